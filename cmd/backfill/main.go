@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/services"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+)
+
+// backfill scelle les documents enregistrés sans preuve (anciens uploads /upload,
+// ingestion sans JWS ou sans ledger) : la preuve JWS manquante est signée et le document
+// inscrit au ledger, dans l'ordre de création. Une preuve existante n'est jamais remplacée
+//
+// Codes de sortie : 0 succès, 1 au moins un document non scellé, 2 erreur d'utilisation
+func main() {
+	os.Exit(run())
+}
+
+// report est le rapport JSON du backfill
+type report struct {
+	DryRun   bool      `json:"dry_run"`
+	Unsealed int       `json:"unsealed"`
+	Sealed   int       `json:"sealed"`
+	Failures []failure `json:"failures,omitempty"`
+}
+
+type failure struct {
+	DocumentID string `json:"document_id"`
+	Error      string `json:"error"`
+}
+
+func run() int {
+	cfg := config.LoadOrDie()
+
+	dryRun := flag.Bool("dry-run", false, "Lister les documents sans preuve sans rien sceller")
+	limit := flag.Int("limit", 0, "Nombre maximal de documents scellés (0 : tous)")
+	batch := flag.Int("batch", 500, "Nombre de documents lus par lot")
+	output := flag.String("output", "", "Fichier de sortie pour le rapport JSON (optionnel)")
+	timeout := flag.Duration("timeout", time.Hour, "Durée maximale du backfill")
+	flag.Parse()
+
+	log := logger.New(cfg.LogLevel)
+	if cfg.DatabaseURL == "" {
+		log.Fatal().Msg("DATABASE_URL not configured")
+	}
+	if *batch <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: -batch must be positive\n")
+		return 2
+	}
+
+	// Preuves configurées : JWS (JWS_ENABLED) et ledger (LEDGER_ENABLED)
+	var signer crypto.Signer
+	if cfg.JWSEnabled {
+		var jwsService *crypto.Service
+		var err error
+		if cfg.JWSKeysDir != "" {
+			jwsService, err = crypto.NewRotatingService(cfg.JWSKeysDir, cfg.JWSKID, time.Duration(cfg.JWSRotationPeriodDays)*24*time.Hour, *log)
+		} else {
+			jwsService, err = crypto.NewService(cfg.JWSPrivateKeyPath, cfg.JWSPublicKeyPath, cfg.JWSKID)
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("JWS service required to sign missing evidence")
+		}
+		signer = crypto.NewLocalSigner(jwsService)
+	}
+	var ledgerService ledger.Service
+	if cfg.LedgerEnabled {
+		ledgerService = ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: cfg.LedgerPerTenantChain, HashVersion: cfg.LedgerHashVersion})
+	}
+	filter := storage.UnsealedFilter{MissingJWS: signer != nil, MissingLedger: ledgerService != nil}
+	if !filter.MissingJWS && !filter.MissingLedger {
+		fmt.Fprintf(os.Stderr, "Error: JWS and ledger are both disabled, nothing to seal\n")
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := storage.NewDB(ctx, cfg.DatabaseURL, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	// Scellement avec un échec de signature bloquant (JWS requis)
+	service := services.NewIngestionService(storage.NewPostgresRepository(db.Pool, log), ledgerService, signer, services.IngestionOptions{
+		JWSRequired: true,
+		Logger:      log,
+	})
+
+	log.Info().
+		Bool("jws", filter.MissingJWS).
+		Bool("ledger", filter.MissingLedger).
+		Bool("dry_run", *dryRun).
+		Msg("Starting evidence backfill")
+
+	rep := report{DryRun: *dryRun}
+	var after *models.Document
+	err = func() error {
+		for {
+			docs, err := db.ListUnsealedDocuments(ctx, filter, after, *batch)
+			if err != nil {
+				return err
+			}
+			for i := range docs {
+				doc := &docs[i]
+				after = doc
+				if *limit > 0 && rep.Unsealed >= *limit {
+					return nil
+				}
+				rep.Unsealed++
+				if *dryRun {
+					fmt.Printf("  - %s %s (créé le %s)\n", doc.ID, doc.Filename, doc.CreatedAt.Format(time.RFC3339))
+					continue
+				}
+				if err := service.Seal(ctx, doc); err != nil {
+					log.Error().Err(err).Str("document_id", doc.ID.String()).Msg("Failed to seal document")
+					rep.Failures = append(rep.Failures, failure{DocumentID: doc.ID.String(), Error: err.Error()})
+					continue
+				}
+				rep.Sealed++
+			}
+			if len(docs) < *batch {
+				return nil
+			}
+		}
+	}()
+
+	fmt.Printf("\n=== Scellement des documents sans preuve ===\n\n")
+	if rep.DryRun {
+		fmt.Printf("Mode: DRY-RUN (aucun scellement)\n")
+	} else {
+		fmt.Printf("Mode: SCELLEMENT\n")
+	}
+	fmt.Printf("Documents sans preuve: %d\n", rep.Unsealed)
+	fmt.Printf("Documents scellés: %d\n", rep.Sealed)
+	if len(rep.Failures) > 0 {
+		fmt.Printf("\nÉchecs (%d):\n", len(rep.Failures))
+		for _, f := range rep.Failures {
+			fmt.Printf("  - %s: %s\n", f.DocumentID, f.Error)
+		}
+	}
+	fmt.Printf("\n")
+
+	if *output != "" {
+		data, err := json.MarshalIndent(rep, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to marshal report: %v\n", err)
+			return 2
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write report: %v\n", err)
+			return 2
+		}
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Evidence backfill interrupted")
+		return 1
+	}
+	if len(rep.Failures) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/closing"
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+)
+
+// closing clôture une période mensuelle (NF525), liste les clôtures ou vérifie
+// hors ligne une archive closing-YYYY-MM.zip
+//
+// Codes de sortie : 0 succès, 1 échec de la clôture ou archive invalide, 2 erreur d'utilisation
+func main() {
+	period := flag.String("period", closing.PeriodOf(time.Now()).Previous().String(), "Période à clôturer (YYYY-MM) [default: mois précédent]")
+	closedBy := flag.String("closed-by", "cli", "Auteur de la clôture (enregistré dans closings.closed_by)")
+	list := flag.Bool("list", false, "Lister les clôtures enregistrées")
+	verifyPath := flag.String("verify", "", "Vérifier hors ligne une archive de clôture (aucun accès à la base)")
+	jwksPath := flag.String("jwks", "", "JWKS de confiance pour -verify (sinon jwks.json de l'archive)")
+	output := flag.String("output", "", "Fichier de sortie pour le rapport JSON (optionnel)")
+	timeout := flag.Duration("timeout", 2*time.Hour, "Durée maximale de la clôture")
+	flag.Parse()
+
+	if *verifyPath != "" {
+		os.Exit(verifyArchive(*verifyPath, *jwksPath, *output))
+	}
+
+	p, err := closing.ParsePeriod(*period)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Charger la configuration
+	cfg := config.LoadOrDie()
+	log := logger.New(cfg.LogLevel)
+
+	if cfg.DatabaseURL == "" {
+		log.Fatal().Msg("DATABASE_URL not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := storage.NewDB(ctx, cfg.DatabaseURL, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+	// Événement period.closed inscrit dans la chaîne configurée
+	db.SetLedgerService(ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: cfg.LedgerPerTenantChain, HashVersion: cfg.LedgerHashVersion}))
+	// Contenu des documents archivés (STORAGE_BACKEND)
+	blobs, err := blobstore.FromConfig(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure document storage")
+	}
+	db.SetBlobStore(blobs)
+	// Déchiffrement du contenu chiffré au repos (DOCUMENT_ENCRYPTION_ENABLED)
+	envelope, err := crypto.EnvelopeFromConfig(cfg, *log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure document encryption")
+	}
+	db.SetEnvelope(envelope)
+
+	if *list {
+		closings, err := db.ListClosings(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to list closings")
+		}
+		fmt.Printf("\n=== Clôtures ===\n\n")
+		for _, c := range closings {
+			fmt.Printf("%s  documents=%d  ledger=%d  merkle=%s  clôturée le %s par %s\n",
+				c.Period, c.DocumentCount, c.LedgerEntryCount, c.MerkleRoot, c.ClosedAt.Format(time.RFC3339), c.ClosedBy)
+		}
+		fmt.Printf("\n%d période(s) clôturée(s)\n\n", len(closings))
+		return
+	}
+
+	// Service JWS : répertoire multi-KID si configuré, sinon clé unique
+	var jwsService *crypto.Service
+	if cfg.JWSKeysDir != "" {
+		jwsService, err = crypto.NewRotatingService(cfg.JWSKeysDir, cfg.JWSKID, time.Duration(cfg.JWSRotationPeriodDays)*24*time.Hour, *log)
+	} else {
+		jwsService, err = crypto.NewService(cfg.JWSPrivateKeyPath, cfg.JWSPublicKeyPath, cfg.JWSKID)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("JWS service required to seal the closing")
+	}
+
+	// Logs d'audit : signatures journalières archivées et événement period_closed
+	var auditLogger *audit.Logger
+	var signer *audit.Signer
+	if cfg.AuditDir != "" {
+		auditLogger, err = audit.NewLogger(audit.Config{
+			AuditDir:      cfg.AuditDir,
+			MaxBuffer:     1000,
+			FlushInterval: 10 * time.Second,
+			Logger:        *log,
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to initialize audit logger, closing without audit hashes")
+		} else {
+			defer auditLogger.Close()
+			signer = audit.NewSigner(auditLogger, jwsService, *log)
+		}
+	}
+
+	closer := closing.NewCloser(db, jwsService, signer, auditLogger, closing.Config{
+		ArchiveDir:     cfg.ClosingDir,
+		PerTenantChain: cfg.LedgerPerTenantChain,
+		Logger:         *log,
+	})
+
+	log.Info().Str("period", p.String()).Msg("Starting period closing")
+	result, err := closer.Close(ctx, p, *closedBy)
+	if errors.Is(err, storage.ErrClosingExists) {
+		fmt.Fprintf(os.Stderr, "Error: period %s is already closed\n", p)
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("period", p.String()).Msg("Closing failed")
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n=== Clôture %s ===\n\n", result.Period)
+	fmt.Printf("Documents: %d\n", result.DocumentCount)
+	fmt.Printf("Entrées ledger: %d\n", result.LedgerEntryCount)
+	fmt.Printf("Racine de Merkle: %s\n", result.MerkleRoot)
+	if result.LastLedgerHash != nil {
+		fmt.Printf("Dernier hash du ledger: %s\n", *result.LastLedgerHash)
+	}
+	fmt.Printf("Archive: %s\n", result.ArchivePath)
+	fmt.Printf("SHA256 de l'archive: %s\n\n", result.ArchiveSHA256)
+
+	if *output != "" {
+		writeJSON(*output, result)
+	}
+}
+
+// verifyArchive vérifie une archive hors ligne et retourne le code de sortie
+func verifyArchive(path, jwksPath, output string) int {
+	var trusted crypto.KeySet
+	if jwksPath != "" {
+		jwksData, err := os.ReadFile(jwksPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to read JWKS: %v\n", err)
+			return 2
+		}
+		trusted, err = crypto.ParseJWKS(jwksData)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	report, err := closing.VerifyArchive(path, trusted)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	fmt.Printf("\n=== Vérification de l'archive de clôture ===\n\n")
+	if m := report.Manifest; m != nil {
+		fmt.Printf("Période: %s\n", m.Period)
+		fmt.Printf("Documents: %d, entrées ledger: %d\n", m.DocumentCount, m.LedgerEntryCount)
+		fmt.Printf("Racine de Merkle: %s\n", m.MerkleRoot)
+	}
+	if report.KeysSource == closing.KeysFromArchive {
+		fmt.Printf("Clés: jwks.json de l'archive (non épinglées, utiliser -jwks pour une vérification de confiance)\n")
+	} else {
+		fmt.Printf("Clés: JWKS de confiance (%s)\n", jwksPath)
+	}
+	fmt.Printf("\n")
+	for _, check := range report.Checks {
+		fmt.Printf("  [%s] %s: %s\n", check.Status, check.Component, check.Message)
+	}
+	fmt.Printf("\nVerdict: %s\n\n", map[bool]string{true: "ARCHIVE VALIDE", false: "ARCHIVE INVALIDE"}[report.Valid])
+
+	if output != "" {
+		writeJSON(output, report)
+	}
+	if !report.Valid {
+		return 1
+	}
+	return 0
+}
+
+func writeJSON(path string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to marshal report: %v\n", err)
+		os.Exit(2)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write report: %v\n", err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/closing"
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+)
+
+// ledgerctl administre le partitionnement du ledger : conversion en ligne en table
+// partitionnée, création des partitions, archivage des partitions anciennes,
+// ré-attachement d'une archive pour audit et vérification hors ligne d'une archive
+//
+// Codes de sortie : 0 succès, 1 échec de l'opération ou archive invalide, 2 erreur d'utilisation
+func main() {
+	cfg := config.LoadOrDie()
+
+	flag.Bool("status", false, "Afficher l'état du partitionnement (action par défaut)")
+	convert := flag.Bool("convert", false, "Convertir en ligne le ledger en table partitionnée (chaîne vérifiée avant et après)")
+	batchSize := flag.Int("batch-size", 10000, "Nombre d'entrées copiées par lot pour -convert")
+	ensure := flag.Bool("ensure", false, "Créer les partitions du mois courant et du mois suivant")
+	archive := flag.String("archive", "", "Archiver la partition d'un mois révolu (YYYY-MM)")
+	archiveExpired := flag.Bool("archive-expired", false, "Archiver les partitions plus anciennes que -retention mois")
+	restore := flag.String("restore", "", "Ré-attacher une partition archivée pour audit (chemin du manifeste)")
+	verifyPath := flag.String("verify-archive", "", "Vérifier hors ligne une archive de partition (chemin du manifeste, aucun accès à la base)")
+	dir := flag.String("dir", cfg.LedgerPartitionArchiveDir, "Répertoire des archives de partitions")
+	retention := flag.Int("retention", cfg.LedgerPartitionRetentionMonths, "Âge en mois révolus au-delà duquel une partition est archivée")
+	output := flag.String("output", "", "Fichier de sortie pour le rapport JSON (optionnel)")
+	timeout := flag.Duration("timeout", 2*time.Hour, "Durée maximale de l'opération")
+	flag.Parse()
+
+	if *verifyPath != "" {
+		os.Exit(verifyArchive(*verifyPath, *output))
+	}
+
+	actions := 0
+	for _, set := range []bool{*convert, *ensure, *archive != "", *archiveExpired, *restore != ""} {
+		if set {
+			actions++
+		}
+	}
+	if actions > 1 {
+		fmt.Fprintf(os.Stderr, "Error: only one of -convert, -ensure, -archive, -archive-expired, -restore can be used\n")
+		os.Exit(2)
+	}
+	var period closing.Period
+	if *archive != "" {
+		var err error
+		if period, err = closing.ParsePeriod(*archive); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	log := logger.New(cfg.LogLevel)
+	if cfg.DatabaseURL == "" {
+		log.Fatal().Msg("DATABASE_URL not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := storage.NewDB(ctx, cfg.DatabaseURL, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	manager := ledger.NewPartitionManager(db.Pool, *log)
+	opts := ledger.LifecycleOptions{
+		ArchiveDir:      *dir,
+		RetentionMonths: *retention,
+		PerTenantChain:  cfg.LedgerPerTenantChain,
+	}
+
+	var result interface{}
+	switch {
+	case *convert:
+		report, err := ledger.ConvertToPartitioned(ctx, db.Pool, ledger.ConvertOptions{
+			PerTenantChain: cfg.LedgerPerTenantChain,
+			BatchSize:      *batchSize,
+		}, *log)
+		if report != nil {
+			printConversion(report)
+			result = report
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Ledger conversion failed")
+			writeReport(*output, result)
+			os.Exit(1)
+		}
+
+	case *ensure:
+		if err := manager.EnsureCurrentPartition(ctx); err != nil {
+			log.Fatal().Err(err).Msg("Failed to create current ledger partition")
+		}
+		if err := manager.EnsureNextPartition(ctx); err != nil {
+			log.Fatal().Err(err).Msg("Failed to create next ledger partition")
+		}
+		fmt.Printf("\nPartitions du mois courant et du mois suivant prêtes\n\n")
+
+	case *archive != "":
+		manifest, err := manager.ArchivePartition(ctx, period.Year, int(period.Month), opts)
+		if err != nil {
+			log.Error().Err(err).Str("period", period.String()).Msg("Partition archiving failed")
+			os.Exit(1)
+		}
+		printManifests("Archivage", manifest)
+		result = manifest
+
+	case *archiveExpired:
+		manifests, err := manager.ArchiveExpired(ctx, opts, time.Now())
+		printManifests("Archivage des partitions expirées", manifests...)
+		result = manifests
+		if err != nil {
+			log.Error().Err(err).Msg("Partition archiving failed")
+			writeReport(*output, result)
+			os.Exit(1)
+		}
+
+	case *restore != "":
+		manifest, err := manager.RestorePartition(ctx, *restore)
+		if err != nil {
+			log.Error().Err(err).Str("manifest", *restore).Msg("Partition restore failed")
+			os.Exit(1)
+		}
+		printManifests("Ré-attachement", manifest)
+		fmt.Printf("Ré-archiver après l'audit : ledgerctl -archive %04d-%02d\n\n",
+			manifest.PeriodStart.Year(), int(manifest.PeriodStart.Month()))
+		result = manifest
+
+	default:
+		st, err := manager.Status(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to get ledger partition status")
+		}
+		printStatus(st)
+		result = st
+	}
+
+	writeReport(*output, result)
+}
+
+// verifyArchive vérifie une archive de partition hors ligne et retourne le code de sortie
+func verifyArchive(path, output string) int {
+	manifest, report, err := ledger.VerifyPartitionArchive(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("\n=== Vérification de l'archive de partition ===\n\n")
+	fmt.Printf("Partition: %s (%s → %s)\n", manifest.Partition,
+		manifest.PeriodStart.Format("2006-01-02"), manifest.PeriodEnd.Format("2006-01-02"))
+	fmt.Printf("Entrées: %d (id %d → %d)\n", manifest.EntryCount, manifest.FirstEntryID, manifest.LastEntryID)
+	fmt.Printf("SHA256 du fichier: %s\n", manifest.SHA256Hex)
+	fmt.Printf("Entrées vérifiées: %d, liens rompus: %d, trous: %d\n",
+		report.EntriesChecked, report.BrokenLinkCount, report.GapCount)
+	fmt.Printf("\nVerdict: %s\n\n", map[bool]string{true: "ARCHIVE VALIDE", false: "ARCHIVE INVALIDE"}[report.Valid])
+
+	if output != "" {
+		writeReport(output, map[string]interface{}{"manifest": manifest, "chain": report})
+	}
+	if !report.Valid {
+		return 1
+	}
+	return 0
+}
+
+func printConversion(report *ledger.ConversionReport) {
+	fmt.Printf("\n=== Conversion du ledger en table partitionnée ===\n\n")
+	if report.Before != nil {
+		fmt.Printf("Chaîne avant: %d entrées, valide=%t\n", report.Before.EntriesChecked, report.Before.Valid)
+	}
+	fmt.Printf("Entrées copiées: %d\n", report.EntriesCopied)
+	fmt.Printf("Partitions: %v\n", report.Partitions)
+	if report.After != nil {
+		fmt.Printf("Chaîne après: %d entrées, valide=%t\n", report.After.EntriesChecked, report.After.Valid)
+	}
+	if !report.CompletedAt.IsZero() {
+		fmt.Printf("Durée: %s\n", report.CompletedAt.Sub(report.StartedAt).Round(time.Millisecond))
+		fmt.Printf("\nL'ancienne table est conservée sous le nom %s (à supprimer après contrôle)\n", ledger.UnpartitionedLedgerTable)
+	}
+	fmt.Printf("\n")
+}
+
+func printManifests(title string, manifests ...*ledger.PartitionArchiveManifest) {
+	fmt.Printf("\n=== %s ===\n\n", title)
+	for _, m := range manifests {
+		fmt.Printf("%s  entrées=%d  fichier=%s  sha256=%s\n", m.Partition, m.EntryCount, m.File, m.SHA256Hex)
+	}
+	fmt.Printf("\n%d partition(s)\n\n", len(manifests))
+}
+
+func printStatus(st *ledger.PartitionStatus) {
+	fmt.Printf("\n=== Partitionnement du ledger ===\n\n")
+	if !st.Partitioned {
+		fmt.Printf("Ledger non partitionné (ledgerctl -convert pour le convertir)\n")
+	} else {
+		fmt.Printf("Partition du mois suivant: %s\n\n", map[bool]string{true: "prête", false: "ABSENTE"}[st.NextPartitionReady])
+		for _, p := range st.Partitions {
+			fmt.Printf("%s  %s  lignes≈%d  taille=%d octets\n", p.Name, p.Bound, p.RowEstimate, p.SizeBytes)
+		}
+	}
+	if len(st.Archives) > 0 {
+		fmt.Printf("\nArchives:\n")
+		for _, a := range st.Archives {
+			state := "archivée"
+			if a.RestoredAt != nil {
+				state = "ré-attachée le " + a.RestoredAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%s  entrées=%d  %s  (%s)\n", a.Partition, a.EntryCount, a.FilePath, state)
+		}
+	}
+	fmt.Printf("\n")
+}
+
+func writeReport(path string, v interface{}) {
+	if path == "" || v == nil {
+		return
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to marshal report: %v\n", err)
+		os.Exit(2)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write report: %v\n", err)
+		os.Exit(2)
+	}
+}
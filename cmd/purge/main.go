@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/retention"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+)
+
+// purge supprime le contenu des documents dont la durée de conservation
+// (RETENTION_POLICIES) est échue, hors blocage légal. Chaque document purgé garde une
+// pierre tombale avec son SHA256 ; la purge est inscrite au ledger et à l'audit
+//
+// Codes de sortie : 0 succès, 1 au moins un document non purgé, 2 erreur d'utilisation
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	cfg := config.LoadOrDie()
+
+	dryRun := flag.Bool("dry-run", false, "Lister les documents échus sans rien purger")
+	limit := flag.Int("limit", 0, "Nombre maximal de documents purgés (0 : tous)")
+	actor := flag.String("actor", "purge", "Auteur de la purge inscrit au ledger et à l'audit")
+	output := flag.String("output", "", "Fichier de sortie pour le rapport JSON (optionnel)")
+	timeout := flag.Duration("timeout", time.Hour, "Durée maximale de la purge")
+	flag.Parse()
+
+	log := logger.New(cfg.LogLevel)
+	if cfg.DatabaseURL == "" {
+		log.Fatal().Msg("DATABASE_URL not configured")
+	}
+
+	policies, err := retention.ParsePolicies(cfg.RetentionPolicies)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if len(policies) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: RETENTION_POLICIES is empty\n")
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := storage.NewDB(ctx, cfg.DatabaseURL, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+	// Événement document.purged inscrit dans la chaîne configurée
+	db.SetLedgerService(ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: cfg.LedgerPerTenantChain, HashVersion: cfg.LedgerHashVersion}))
+	blobs, err := blobstore.FromConfig(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure document storage")
+	}
+	db.SetBlobStore(blobs)
+	envelope, err := crypto.EnvelopeFromConfig(cfg, *log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure document encryption")
+	}
+	db.SetEnvelope(envelope)
+
+	// Événements document_purged
+	var auditLogger *audit.Logger
+	if cfg.AuditDir != "" {
+		auditLogger, err = audit.NewLogger(audit.Config{
+			AuditDir:      cfg.AuditDir,
+			MaxBuffer:     1000,
+			FlushInterval: 10 * time.Second,
+			Logger:        *log,
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to initialize audit logger, purging without audit")
+		} else {
+			defer auditLogger.Close()
+		}
+	}
+
+	purger := retention.NewPurger(db, auditLogger, retention.Config{
+		Policies:  policies,
+		BatchSize: cfg.RetentionPurgeBatch,
+		Logger:    *log,
+	})
+
+	log.Info().
+		Str("policies", cfg.RetentionPolicies).
+		Bool("dry_run", *dryRun).
+		Msg("Starting retention purge")
+
+	report, err := purger.Run(ctx, retention.Options{DryRun: *dryRun, Limit: *limit, Actor: *actor})
+
+	fmt.Printf("\n=== Purge des documents échus ===\n\n")
+	if report.DryRun {
+		fmt.Printf("Mode: DRY-RUN (aucune suppression)\n")
+	} else {
+		fmt.Printf("Mode: PURGE\n")
+	}
+	for _, p := range report.Policies {
+		fmt.Printf("  - %s (créés avant le %s): %d échus, %d purgés\n", p.Policy, p.Cutoff.Format("2006-01-02"), p.Expired, p.Purged)
+	}
+	fmt.Printf("Documents échus: %d\n", report.Expired)
+	fmt.Printf("Documents purgés: %d\n", report.Purged)
+	if len(report.Failures) > 0 {
+		fmt.Printf("\nÉchecs (%d):\n", len(report.Failures))
+		for _, f := range report.Failures {
+			fmt.Printf("  - %s: %s\n", f.DocumentID, f.Error)
+		}
+	}
+	fmt.Printf("\n")
+
+	if *output != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to marshal report: %v\n", err)
+			return 2
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write report: %v\n", err)
+			return 2
+		}
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Retention purge interrupted")
+		return 1
+	}
+	if len(report.Failures) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -8,17 +8,25 @@ import (
 	"os"
 	"time"
 
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
 	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
 	"github.com/doreviateam/dorevia-vault/internal/reconcile"
+	"github.com/doreviateam/dorevia-vault/internal/services"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
 	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/rs/zerolog"
 )
 
 func main() {
 	// Flags
 	dryRun := flag.Bool("dry-run", false, "Mode dry-run : détecte les orphelins sans les supprimer")
-	fix := flag.Bool("fix", false, "Mode fix : supprime les fichiers orphelins et marque les entrées DB")
+	fix := flag.Bool("fix", false, "Mode fix : supprime les fichiers orphelins, marque les entrées DB et scelle les documents sans preuve")
 	output := flag.String("output", "", "Fichier de sortie pour le rapport JSON (optionnel)")
+	migrateLayout := flag.Bool("migrate-layout", false, "Migre le contenu vers la disposition par contenu (ab/cd/<sha256>) ; vérification seule sans --fix")
+	limit := flag.Int("limit", 0, "Nombre maximal de contenus migrés avec --migrate-layout (0 : tous)")
+	timeout := flag.Duration("timeout", 0, "Durée maximale (défaut : 10m, 24h avec --migrate-layout)")
 	flag.Parse()
 
 	// Validation des flags
@@ -50,7 +58,13 @@ func main() {
 	}
 
 	// Initialiser la connexion à la base de données
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	if *timeout <= 0 {
+		*timeout = 10 * time.Minute
+		if *migrateLayout {
+			*timeout = 24 * time.Hour
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
 
 	db, err := storage.NewDB(ctx, cfg.DatabaseURL, log)
@@ -59,13 +73,59 @@ func main() {
 	}
 	defer db.Close()
 
+	blobs, err := blobstore.FromConfig(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure document storage")
+	}
+	db.SetBlobStore(blobs)
+	// Déchiffrement du contenu chiffré au repos (DOCUMENT_ENCRYPTION_ENABLED)
+	envelope, err := crypto.EnvelopeFromConfig(cfg, *log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure document encryption")
+	}
+	db.SetEnvelope(envelope)
+
+	if *migrateLayout {
+		os.Exit(runLayoutMigration(ctx, db, dryRunMode, *limit, *output, log))
+	}
+
 	log.Info().
 		Bool("dry_run", dryRunMode).
 		Str("storage_dir", cfg.StorageDir).
 		Msg("Starting reconciliation")
 
+	// Preuves configurées : vérification des JWS et scellement des documents sans preuve
+	opts := reconcile.Options{
+		StorageDir: cfg.StorageDir,
+		DryRun:     dryRunMode,
+		Filter:     storage.UnsealedFilter{MissingJWS: cfg.JWSEnabled, MissingLedger: cfg.LedgerEnabled},
+	}
+	var signer crypto.Signer
+	if cfg.JWSEnabled {
+		var jwsService *crypto.Service
+		if cfg.JWSKeysDir != "" {
+			jwsService, err = crypto.NewRotatingService(cfg.JWSKeysDir, cfg.JWSKID, time.Duration(cfg.JWSRotationPeriodDays)*24*time.Hour, *log)
+		} else {
+			jwsService, err = crypto.NewService(cfg.JWSPrivateKeyPath, cfg.JWSPublicKeyPath, cfg.JWSKID)
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("JWS service required to verify and sign evidence")
+		}
+		opts.Verifier = jwsService
+		signer = crypto.NewLocalSigner(jwsService)
+	}
+	var ledgerService ledger.Service
+	if cfg.LedgerEnabled {
+		ledgerService = ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: cfg.LedgerPerTenantChain, HashVersion: cfg.LedgerHashVersion})
+	}
+	// Scellement avec un échec de signature bloquant (JWS requis)
+	opts.Sealer = services.NewIngestionService(storage.NewPostgresRepository(db.Pool, log), ledgerService, signer, services.IngestionOptions{
+		JWSRequired: true,
+		Logger:      log,
+	})
+
 	// Exécuter la réconciliation
-	report, err := reconcile.CleanupOrphans(ctx, db, cfg.StorageDir, dryRunMode)
+	report, err := reconcile.Reconcile(ctx, db, opts)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Reconciliation failed")
 	}
@@ -98,10 +158,29 @@ func main() {
 		}
 	}
 
+	printDocumentIssues("Documents sans preuve", report.UnsealedCount, report.UnsealedDocuments)
+
+	fmt.Printf("\nEntrées ledger sans document: %d\n", report.DanglingLedgerCount)
+	for _, issue := range report.DanglingLedgerEntries {
+		fmt.Printf("  - Ledger ID: %d, Document ID: %s (%s)\n", issue.LedgerID, issue.DocumentID, issue.EntryType)
+	}
+
+	printDocumentIssues("ledger_hash incohérents", report.LedgerHashMismatchCount, report.LedgerHashMismatches)
+	printDocumentIssues("Preuves JWS invalides", report.InvalidEvidenceCount, report.InvalidEvidence)
+
+	if len(report.SkippedChecks) > 0 {
+		fmt.Printf("\nVérifications ignorées:\n")
+		for _, check := range report.SkippedChecks {
+			fmt.Printf("  - %s\n", check)
+		}
+	}
+
 	if !dryRunMode {
 		fmt.Printf("\nActions effectuées:\n")
 		fmt.Printf("  - Fichiers supprimés: %d\n", report.FilesDeleted)
 		fmt.Printf("  - Entrées DB marquées: %d\n", report.DBsMarked)
+		fmt.Printf("  - Marqueurs retirés (contenu retrouvé): %d\n", report.DBsRestored)
+		fmt.Printf("  - Documents scellés: %d\n", report.DocumentsSealed)
 	}
 
 	if len(report.Errors) > 0 {
@@ -130,14 +209,80 @@ func main() {
 	}
 
 	// Code de sortie
-	if len(report.OrphanFiles) > 0 || len(report.OrphanDBs) > 0 {
-		if dryRunMode {
-			os.Exit(1) // Code d'erreur si orphelins détectés en dry-run
-		} else {
-			os.Exit(0) // Succès si fix appliqué
+	if dryRunMode && report.HasIssues() {
+		os.Exit(1) // Code d'erreur si anomalies détectées en dry-run
+	}
+	if !dryRunMode && (report.Unrepairable() > 0 || len(report.Errors) > 0) {
+		os.Exit(1) // Anomalies non réparées en mode fix
+	}
+	os.Exit(0)
+}
+
+// printDocumentIssues affiche une catégorie d'anomalies sur les documents (10 premières)
+func printDocumentIssues(title string, count int, issues []reconcile.DocumentIssue) {
+	fmt.Printf("\n%s: %d\n", title, count)
+	for i, issue := range issues {
+		if i == 10 {
+			fmt.Printf("  ... et %d autres\n", count-10)
+			break
+		}
+		sealed := ""
+		if issue.Sealed {
+			sealed = " [scellé]"
 		}
-	} else {
-		os.Exit(0) // Aucun orphelin détecté
+		fmt.Printf("  - Document ID: %s: %s%s\n", issue.DocumentID, issue.Detail, sealed)
 	}
 }
 
+// runLayoutMigration migre le contenu de la disposition par date vers la disposition par
+// contenu : chaque contenu est vérifié contre sha256_hex, écrit une seule fois sous
+// ab/cd/<sha256> (ou rattaché à l'objet déjà présent), puis l'ancien fichier est supprimé
+func runLayoutMigration(ctx context.Context, db *storage.DB, dryRun bool, limit int, output string, log *zerolog.Logger) int {
+	log.Info().Bool("dry_run", dryRun).Msg("Starting storage layout migration")
+
+	report, err := db.MigrateToContentLayout(ctx, storage.BlobMigrationOptions{
+		DryRun:       dryRun,
+		DeleteSource: true,
+		Limit:        limit,
+	})
+	if err != nil && report == nil {
+		log.Error().Err(err).Msg("Storage layout migration failed")
+		return 1
+	}
+
+	fmt.Printf("\n=== Migration de la disposition %s → %s ===\n\n", report.From, report.To)
+	fmt.Printf("Mode: %s\n", map[bool]string{true: "DRY-RUN", false: "FIX"}[report.DryRun])
+	fmt.Printf("Documents concernés: %d\n", report.Candidates)
+	fmt.Printf("Contenus vérifiés (documents): %d\n", report.Verified)
+	fmt.Printf("Documents migrés: %d (dont %d rattachés à un contenu existant)\n", report.Migrated, report.Shared)
+	fmt.Printf("Anciens fichiers supprimés: %d\n", report.Deleted)
+	if len(report.Failures) > 0 {
+		fmt.Printf("\nÉchecs (%d):\n", len(report.Failures))
+		for _, f := range report.Failures {
+			fmt.Printf("  - %s (%s): %s\n", f.DocumentID, f.StoredPath, f.Error)
+		}
+	}
+	fmt.Printf("\n")
+
+	if output != "" {
+		reportJSON, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal report to JSON")
+			return 1
+		}
+		if err := os.WriteFile(output, reportJSON, 0644); err != nil {
+			log.Error().Err(err).Str("output", output).Msg("Failed to write report file")
+			return 1
+		}
+		log.Info().Str("output", output).Msg("Report exported to JSON file")
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Storage layout migration interrupted")
+		return 1
+	}
+	if len(report.Failures) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+)
+
+// rewrap enveloppe à nouveau les clés de données des documents chiffrés par la KEK
+// courante (DOCUMENT_ENCRYPTION_KEK_ID ou KID courant), sans réécrire les contenus.
+// L'ancienne KEK doit rester lisible le temps de la rotation
+//
+// Codes de sortie : 0 succès, 1 au moins une clé non enveloppée, 2 erreur d'utilisation
+func main() {
+	cfg := config.LoadOrDie()
+
+	from := flag.String("from", "", "N'envelopper que les clés de cette version de KEK (défaut : toutes sauf la courante)")
+	dryRun := flag.Bool("dry-run", false, "Compter les clés à envelopper sans rien modifier")
+	limit := flag.Int("limit", 0, "Nombre maximal de clés traitées (0 : toutes)")
+	output := flag.String("output", "", "Fichier de sortie pour le rapport JSON (optionnel)")
+	timeout := flag.Duration("timeout", time.Hour, "Durée maximale de la rotation")
+	flag.Parse()
+
+	log := logger.New(cfg.LogLevel)
+	if cfg.DatabaseURL == "" {
+		log.Fatal().Msg("DATABASE_URL not configured")
+	}
+
+	envelope, err := crypto.EnvelopeFromConfig(cfg, *log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if envelope == nil {
+		fmt.Fprintf(os.Stderr, "Error: DOCUMENT_ENCRYPTION_ENABLED is not set\n")
+		os.Exit(2)
+	}
+	if *from != "" && *from == envelope.KEKVersion() {
+		fmt.Fprintf(os.Stderr, "Error: -from must differ from the current KEK (%s)\n", envelope.KEKVersion())
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := storage.NewDB(ctx, cfg.DatabaseURL, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+	db.SetEnvelope(envelope)
+
+	log.Info().
+		Str("kek_version", envelope.KEKVersion()).
+		Str("from", *from).
+		Bool("dry_run", *dryRun).
+		Msg("Starting document key rewrap")
+
+	report, err := db.RewrapDocumentKeys(ctx, storage.RewrapOptions{
+		DryRun:     *dryRun,
+		KEKVersion: *from,
+		Limit:      *limit,
+	})
+	if err != nil && report == nil {
+		log.Fatal().Err(err).Msg("Document key rewrap failed")
+	}
+
+	fmt.Printf("\n=== Rotation de KEK → %s ===\n\n", report.KEKVersion)
+	if report.DryRun {
+		fmt.Printf("Mode: DRY-RUN (aucune modification)\n")
+	} else {
+		fmt.Printf("Mode: ROTATION\n")
+	}
+	fmt.Printf("Clés concernées: %d\n", report.Keys)
+	fmt.Printf("Clés enveloppées: %d\n", report.Rewrapped)
+	fmt.Printf("Documents mis à jour: %d\n", report.Documents)
+	if len(report.Failures) > 0 {
+		fmt.Printf("\nÉchecs (%d):\n", len(report.Failures))
+		for _, f := range report.Failures {
+			fmt.Printf("  - %s (%d documents): %s\n", f.KEKVersion, f.Documents, f.Error)
+		}
+	}
+	fmt.Printf("\n")
+
+	if *output != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to marshal report: %v\n", err)
+			os.Exit(2)
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write report: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Document key rewrap interrupted")
+		os.Exit(1)
+	}
+	if len(report.Failures) > 0 {
+		os.Exit(1)
+	}
+}
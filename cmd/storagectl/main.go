@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+)
+
+// storagectl migre le contenu des documents d'un backend de stockage à l'autre
+// (fs : STORAGE_DIR, s3 : S3_*). Chaque contenu est vérifié contre sha256_hex avant la
+// copie puis relu dans la destination avant la mise à jour de stored_path ; un document
+// en échec garde sa référence d'origine
+//
+// Codes de sortie : 0 succès, 1 au moins un document non migré, 2 erreur d'utilisation
+func main() {
+	cfg := config.LoadOrDie()
+
+	from := flag.String("from", "fs", "Backend source (fs ou s3)")
+	to := flag.String("to", "s3", "Backend destination (fs ou s3)")
+	dryRun := flag.Bool("dry-run", false, "Vérifier les contenus source sans rien copier")
+	deleteSource := flag.Bool("delete-source", false, "Supprimer le contenu source une fois la copie vérifiée")
+	limit := flag.Int("limit", 0, "Nombre maximal de documents migrés (0 : tous)")
+	output := flag.String("output", "", "Fichier de sortie pour le rapport JSON (optionnel)")
+	timeout := flag.Duration("timeout", 24*time.Hour, "Durée maximale de la migration")
+	flag.Parse()
+
+	if *from == *to {
+		fmt.Fprintf(os.Stderr, "Error: -from and -to must be different backends\n")
+		os.Exit(2)
+	}
+
+	log := logger.New(cfg.LogLevel)
+	if cfg.DatabaseURL == "" {
+		log.Fatal().Msg("DATABASE_URL not configured")
+	}
+
+	blobs, err := blobstore.FromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	source, destination := blobs.Backend(*from), blobs.Backend(*to)
+	if source == nil || destination == nil {
+		fmt.Fprintf(os.Stderr, "Error: backends must be fs or s3 (s3 requires S3_BUCKET)\n")
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := storage.NewDB(ctx, cfg.DatabaseURL, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+	// Contenu chiffré au repos : vérifié après déchiffrement, copié tel quel
+	envelope, err := crypto.EnvelopeFromConfig(cfg, *log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure document encryption")
+	}
+	db.SetEnvelope(envelope)
+
+	log.Info().
+		Str("from", *from).
+		Str("to", *to).
+		Bool("dry_run", *dryRun).
+		Bool("delete_source", *deleteSource).
+		Msg("Starting storage migration")
+
+	report, err := db.MigrateBlobs(ctx, source, destination, storage.BlobMigrationOptions{
+		DryRun:       *dryRun,
+		DeleteSource: *deleteSource,
+		Limit:        *limit,
+	})
+	if err != nil && report == nil {
+		log.Fatal().Err(err).Msg("Storage migration failed")
+	}
+
+	fmt.Printf("\n=== Migration du stockage %s → %s ===\n\n", report.From, report.To)
+	if report.DryRun {
+		fmt.Printf("Mode: DRY-RUN (vérification seule)\n")
+	} else {
+		fmt.Printf("Mode: MIGRATION\n")
+	}
+	fmt.Printf("Documents concernés: %d\n", report.Candidates)
+	fmt.Printf("Contenus vérifiés: %d\n", report.Verified)
+	fmt.Printf("Documents migrés: %d\n", report.Migrated)
+	fmt.Printf("Sources supprimées: %d\n", report.Deleted)
+	if len(report.Failures) > 0 {
+		fmt.Printf("\nÉchecs (%d):\n", len(report.Failures))
+		for _, f := range report.Failures {
+			fmt.Printf("  - %s (%s): %s\n", f.DocumentID, f.StoredPath, f.Error)
+		}
+	}
+	fmt.Printf("\n")
+
+	if *output != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to marshal report: %v\n", err)
+			os.Exit(2)
+		}
+		if err := os.WriteFile(*output, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write report: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Storage migration interrupted")
+		os.Exit(1)
+	}
+	if len(report.Failures) > 0 {
+		os.Exit(1)
+	}
+}
@@ -2,7 +2,8 @@ package main
 
 import (
 	"context"
-	"crypto/rsa"
+	stdcrypto "crypto"
+	"crypto/x509"
 	"fmt"
 	"os"
 	"os/signal"
@@ -11,14 +12,21 @@ import (
 
 	"github.com/doreviateam/dorevia-vault/internal/audit"
 	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/closing"
 	"github.com/doreviateam/dorevia-vault/internal/config"
 	"github.com/doreviateam/dorevia-vault/internal/crypto"
 	"github.com/doreviateam/dorevia-vault/internal/handlers"
 	"github.com/doreviateam/dorevia-vault/internal/ledger"
 	"github.com/doreviateam/dorevia-vault/internal/metrics"
 	"github.com/doreviateam/dorevia-vault/internal/middleware"
+	"github.com/doreviateam/dorevia-vault/internal/reconcile"
+	"github.com/doreviateam/dorevia-vault/internal/retention"
+	"github.com/doreviateam/dorevia-vault/internal/scrub"
 	"github.com/doreviateam/dorevia-vault/internal/services"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/tsp"
+	"github.com/doreviateam/dorevia-vault/internal/upload"
 	"github.com/doreviateam/dorevia-vault/internal/webhooks"
 	"github.com/doreviateam/dorevia-vault/pkg/logger"
 	"github.com/gofiber/fiber/v2"
@@ -55,6 +63,28 @@ func main() {
 			log.Fatal().Err(err).Msg("Failed to connect to database")
 		}
 		defer db.Close()
+		// Chaîne ledger globale ou par tenant (LEDGER_PER_TENANT_CHAIN), format des entrées (LEDGER_HASH_VERSION)
+		db.SetLedgerService(ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: cfg.LedgerPerTenantChain, HashVersion: cfg.LedgerHashVersion}))
+		// Stockage du contenu des documents (STORAGE_BACKEND : fs ou s3)
+		blobs, err := blobstore.FromConfig(cfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to configure document storage")
+		}
+		db.SetBlobStore(blobs)
+		// Disposition des clés (STORAGE_LAYOUT : date ou content)
+		if err := db.SetStorageLayout(cfg.StorageLayout); err != nil {
+			log.Fatal().Err(err).Msg("Failed to configure document storage")
+		}
+		log.Info().Str("storage_backend", blobs.Name()).Str("storage_layout", cfg.StorageLayout).Msg("Document storage configured")
+		// Chiffrement au repos du contenu (DOCUMENT_ENCRYPTION_ENABLED)
+		envelope, err := crypto.EnvelopeFromConfig(cfg, *log)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to configure document encryption")
+		}
+		db.SetEnvelope(envelope)
+		if envelope != nil {
+			log.Info().Str("kek_version", envelope.KEKVersion()).Msg("Document encryption enabled")
+		}
 		log.Info().Msg("PostgreSQL connection established")
 	} else {
 		log.Warn().Msg("DATABASE_URL not configured, database features disabled")
@@ -62,7 +92,19 @@ func main() {
 
 	// Initialisation du service JWS (optionnel)
 	var jwsService *crypto.Service
-	if cfg.JWSEnabled && (cfg.JWSPrivateKeyPath != "" || cfg.JWSPrivateKeyBase64 != "") {
+	if cfg.JWSEnabled && cfg.JWSKeysDir != "" {
+		// Rotation multi-KID : signature avec le KID courant, vérification par kid
+		var err error
+		jwsService, err = crypto.NewRotatingService(cfg.JWSKeysDir, cfg.JWSKID, time.Duration(cfg.JWSRotationPeriodDays)*24*time.Hour, *log)
+		if err != nil {
+			if cfg.JWSRequired {
+				log.Fatal().Err(err).Msg("JWS required but initialization failed")
+			}
+			log.Warn().Err(err).Msg("JWS initialization failed, continuing without JWS")
+		} else {
+			log.Info().Str("kid", jwsService.GetKID()).Str("keys_dir", cfg.JWSKeysDir).Msg("JWS service initialized with key rotation")
+		}
+	} else if cfg.JWSEnabled && (cfg.JWSPrivateKeyPath != "" || cfg.JWSPrivateKeyBase64 != "") {
 		var err error
 		jwsService, err = crypto.NewService(cfg.JWSPrivateKeyPath, cfg.JWSPublicKeyPath, cfg.JWSKID)
 		if err != nil {
@@ -96,25 +138,164 @@ func main() {
 		}
 	}
 
+	// Clôture mensuelle automatique (NF525)
+	stopClosingScheduler := func() {}
+	if cfg.ClosingEnabled {
+		if db == nil || jwsService == nil {
+			log.Warn().Msg("CLOSING_ENABLED=true but database or JWS not configured → automatic closing disabled")
+		} else {
+			var signer *audit.Signer
+			if auditLogger != nil {
+				signer = audit.NewSigner(auditLogger, jwsService, *log)
+			}
+			closer := closing.NewCloser(db, jwsService, signer, auditLogger, closing.Config{
+				ArchiveDir:     cfg.ClosingDir,
+				PerTenantChain: cfg.LedgerPerTenantChain,
+				Logger:         *log,
+			})
+			var closingCtx context.Context
+			closingCtx, stopClosingScheduler = context.WithCancel(context.Background())
+			closing.StartScheduler(closingCtx, closer, cfg.ClosingGraceDays, time.Hour)
+			log.Info().Str("closing_dir", cfg.ClosingDir).Int("grace_days", cfg.ClosingGraceDays).Msg("Monthly closing scheduler started")
+		}
+	}
+
+	// Purge des documents dont la durée de conservation est échue
+	stopRetentionScheduler := func() {}
+	if cfg.RetentionEnabled {
+		policies, err := retention.ParsePolicies(cfg.RetentionPolicies)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid RETENTION_POLICIES")
+		}
+		if db == nil || len(policies) == 0 {
+			log.Warn().Msg("RETENTION_ENABLED=true but database or RETENTION_POLICIES not configured → retention purge disabled")
+		} else {
+			purger := retention.NewPurger(db, auditLogger, retention.Config{
+				Policies:  policies,
+				BatchSize: cfg.RetentionPurgeBatch,
+				Logger:    *log,
+			})
+			var retentionCtx context.Context
+			retentionCtx, stopRetentionScheduler = context.WithCancel(context.Background())
+			retention.StartScheduler(retentionCtx, purger, time.Duration(cfg.RetentionPurgeIntervalHours)*time.Hour)
+			log.Info().Str("policies", cfg.RetentionPolicies).Int("interval_hours", cfg.RetentionPurgeIntervalHours).Msg("Retention purge scheduler started")
+		}
+	}
+
+	// Uploads reprenables des documents volumineux (hors STORAGE_DIR : la réconciliation
+	// ne doit pas traiter les fichiers partiels comme orphelins)
+	var uploadManager *upload.Manager
+	stopUploadCleanup := func() {}
+	if db != nil {
+		var err error
+		uploadManager, err = upload.NewManager(db, upload.Config{
+			Dir:           cfg.UploadSessionsDir,
+			ChunkMaxBytes: int64(cfg.UploadChunkMaxMB) << 20,
+			MaxSizeBytes:  int64(cfg.UploadMaxSizeMB) << 20,
+			TTL:           time.Duration(cfg.UploadSessionTTLHours) * time.Hour,
+			Logger:        *log,
+		})
+		if err != nil {
+			log.Warn().Err(err).Str("dir", cfg.UploadSessionsDir).Msg("Failed to initialize resumable uploads → /api/v1/uploads disabled")
+			uploadManager = nil
+		} else {
+			var uploadCtx context.Context
+			uploadCtx, stopUploadCleanup = context.WithCancel(context.Background())
+			upload.StartCleanupScheduler(uploadCtx, uploadManager, time.Hour)
+		}
+	}
+
+	// Racines de Merkle journalières du ledger (preuves d'inclusion)
+	stopMerkleScheduler := func() {}
+	if cfg.LedgerMerkleEnabled && db != nil && cfg.LedgerEnabled {
+		if jwsService == nil {
+			log.Warn().Msg("LEDGER_MERKLE_ENABLED=true but JWS not configured → ledger Merkle roots disabled")
+		} else {
+			var merkleCtx context.Context
+			merkleCtx, stopMerkleScheduler = context.WithCancel(context.Background())
+			ledger.StartMerkleScheduler(merkleCtx, db.Pool, jwsService, time.Hour, *log)
+			log.Info().Msg("Ledger Merkle root scheduler started")
+		}
+	}
+
+	// Cycle de vie des partitions mensuelles du ledger
+	stopPartitionScheduler := func() {}
+	if cfg.LedgerPartitionEnabled && db != nil && cfg.LedgerEnabled {
+		var partitionCtx context.Context
+		partitionCtx, stopPartitionScheduler = context.WithCancel(context.Background())
+		ledger.StartPartitionScheduler(partitionCtx, ledger.NewPartitionManager(db.Pool, *log), ledger.LifecycleOptions{
+			ArchiveDir:      cfg.LedgerPartitionArchiveDir,
+			RetentionMonths: cfg.LedgerPartitionRetentionMonths,
+			PerTenantChain:  cfg.LedgerPerTenantChain,
+		}, time.Hour, *log)
+		log.Info().Int("retention_months", cfg.LedgerPartitionRetentionMonths).Msg("Ledger partition scheduler started")
+	}
+
+	// Horodatage RFC 3161 de la tête du ledger. Sans TSA_CA_CERT_PATH, le certificat de la
+	// TSA est validé contre le magasin du système (à l'horodatage comme à la vérification)
+	var tsaRoots *x509.CertPool
+	if cfg.TSACACertPath != "" {
+		var err error
+		tsaRoots, err = tsp.LoadCertPool(cfg.TSACACertPath)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", cfg.TSACACertPath).Msg("Failed to load TSA certificates")
+		}
+	}
+	if db != nil {
+		db.SetTSARoots(tsaRoots)
+	}
+	stopAnchorScheduler := func() {}
+	if cfg.TSAURL != "" {
+		if db == nil || !cfg.LedgerEnabled {
+			log.Warn().Msg("TSA_URL set but database or ledger not enabled → ledger timestamping disabled")
+		} else {
+			tsaClient := tsp.NewClient(cfg.TSAURL, time.Duration(cfg.TSATimeoutSeconds)*time.Second, tsaRoots)
+			var anchorCtx context.Context
+			anchorCtx, stopAnchorScheduler = context.WithCancel(context.Background())
+			ledger.StartAnchorScheduler(anchorCtx, db.Pool, tsaClient, cfg.LedgerPerTenantChain,
+				time.Duration(cfg.TSAIntervalMinutes)*time.Minute, *log)
+			log.Info().Str("tsa_url", cfg.TSAURL).Int("interval_minutes", cfg.TSAIntervalMinutes).Msg("Ledger timestamping scheduler started")
+		}
+	}
+
+	// Group commit des documents ingérés (arrêté après le serveur HTTP)
+	stopGroupCommit := func() {}
+
 	// Initialisation de l'authentification (Sprint 5 Phase 5.2)
 	var authService *auth.AuthService
 	var rbacService *auth.RBACService
 	if cfg.AuthEnabled {
-		// Charger la clé publique JWT
-		// Si JWTPublicKeyPath est configuré, on le charge
-		// Sinon, on utilise la clé publique JWS si disponible (pour compatibilité)
-		var jwtPublicKey *rsa.PublicKey
+		// Charger la clé publique JWT (RSA ou ECDSA)
+		// Priorité : AUTH_JWT_PUBLIC_KEY_PATH, sinon clé publique JWS si aucun JWKS n'est configuré
+		var jwtPublicKey stdcrypto.PublicKey
 		if cfg.JWTPublicKeyPath != "" {
-			// TODO: Charger depuis fichier ou Vault
-			// Pour l'instant, on utilise la clé publique JWS si disponible
-			if jwsService != nil {
-				// Note: On devrait avoir une méthode GetPublicKey() dans Service
-				// Pour l'instant, on peut utiliser la même clé que JWS
+			key, err := auth.LoadPublicKeyPEM(cfg.JWTPublicKeyPath)
+			if err != nil {
+				log.Fatal().Err(err).Str("path", cfg.JWTPublicKeyPath).Msg("Failed to load JWT public key")
 			}
-		} else if jwsService != nil && cfg.JWTEnabled {
+			jwtPublicKey = key
+			log.Info().Str("path", cfg.JWTPublicKeyPath).Msg("JWT public key loaded")
+		} else if jwsService != nil && cfg.JWTEnabled && cfg.JWKSURL == "" {
 			// Utiliser la clé publique JWS comme clé publique JWT par défaut
 			// Cela permet d'utiliser les mêmes clés pour JWS et JWT
-			// TODO: Extraire la clé publique depuis jwsService
+			if pk := jwsService.PublicKey(); pk != nil {
+				jwtPublicKey = pk
+				log.Info().Msg("JWT verification uses the JWS public key")
+			}
+		}
+
+		// JWKS distant de l'émetteur (SSO, clés tournantes)
+		var jwks *auth.JWKSCache
+		if cfg.JWKSURL != "" {
+			jwks = auth.NewJWKSCache(cfg.JWKSURL, time.Duration(cfg.JWKSCacheTTLSeconds)*time.Second, nil)
+			refreshCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := jwks.Refresh(refreshCtx); err != nil {
+				// Non bloquant : nouvelle tentative au premier JWT reçu
+				log.Warn().Err(err).Str("url", cfg.JWKSURL).Msg("Failed to fetch JWKS at startup")
+			} else {
+				log.Info().Str("url", cfg.JWKSURL).Msg("JWKS loaded")
+			}
+			cancel()
 		}
 
 		// Créer le service RBAC
@@ -122,11 +303,22 @@ func main() {
 
 		// Créer le service d'authentification
 		authCfg := auth.AuthConfig{
-			JWTPublicKey:  jwtPublicKey,
-			APIKeys:       make(map[string]*auth.APIKey), // TODO: Charger depuis config/DB
-			JWTEnabled:    cfg.JWTEnabled,
-			APIKeyEnabled: cfg.APIKeyEnabled,
-			Logger:        *log,
+			JWTPublicKey:   jwtPublicKey,
+			JWKS:           jwks,
+			JWTIssuer:      cfg.JWTIssuer,
+			JWTAudience:    cfg.JWTAudience,
+			JWTClockSkew:   time.Duration(cfg.JWTClockSkewSeconds) * time.Second,
+			APIKeys:        make(map[string]*auth.APIKey), // Clés statiques (les clés persistées passent par APIKeyStore)
+			JWTEnabled:     cfg.JWTEnabled,
+			APIKeyEnabled:  cfg.APIKeyEnabled,
+			TenantClaim:    cfg.AuthTenantClaim,
+			TenantRequired: cfg.AuthTenantRequired,
+			APIKeyCacheTTL: time.Duration(cfg.APIKeyCacheTTLSeconds) * time.Second,
+			Logger:         *log,
+		}
+		// Clés API persistées en base (table api_keys)
+		if db != nil {
+			authCfg.APIKeyStore = db
 		}
 		authService = auth.NewAuthService(authCfg)
 		log.Info().Msg("Authentication enabled")
@@ -182,6 +374,83 @@ func main() {
 		log.Info().Msg("Webhooks disabled (WEBHOOKS_ENABLED=false)")
 	}
 
+	// Tête signée du ledger (après les webhooks : une réécriture émet error.critical)
+	stopTreeHeadScheduler := func() {}
+	if cfg.LedgerTreeHeadEnabled && db != nil && cfg.LedgerEnabled {
+		if jwsService == nil {
+			log.Warn().Msg("LEDGER_TREE_HEAD_ENABLED=true but JWS not configured → ledger tree heads disabled")
+		} else {
+			var treeHeadCtx context.Context
+			treeHeadCtx, stopTreeHeadScheduler = context.WithCancel(context.Background())
+			ledger.StartTreeHeadScheduler(treeHeadCtx, db.Pool, jwsService, webhookManager,
+				time.Duration(cfg.LedgerTreeHeadIntervalMinutes)*time.Minute, *log)
+			log.Info().Int("interval_minutes", cfg.LedgerTreeHeadIntervalMinutes).Msg("Ledger tree head scheduler started")
+		}
+	}
+
+	// Vérification en arrière-plan du contenu stocké (après les webhooks : une altération
+	// émet error.critical)
+	stopScrubScheduler := func() {}
+	if cfg.ScrubEnabled && db != nil {
+		scrubber := scrub.NewScrubber(db, scrub.Config{
+			BytesPerSecond: int64(cfg.ScrubMaxMBPerSec) << 20,
+			BatchSize:      cfg.ScrubBatch,
+			WebhookManager: webhookManager,
+			Logger:         *log,
+		})
+		var scrubCtx context.Context
+		scrubCtx, stopScrubScheduler = context.WithCancel(context.Background())
+		scrub.StartScheduler(scrubCtx, scrubber, time.Duration(cfg.ScrubIntervalHours)*time.Hour)
+		log.Info().Int("max_mb_per_sec", cfg.ScrubMaxMBPerSec).Int("interval_hours", cfg.ScrubIntervalHours).Msg("Stored content scrub scheduler started")
+	}
+
+	// Service d'ingestion : scellement commun de /upload, /api/v1/invoices, /api/v1/uploads
+	// et /api/v1/pos-tickets (idempotence, JWS + Ledger, audit, webhook)
+	var ingestionService *services.IngestionService
+	if db != nil {
+		// Repository (regroupement des transactions si group commit activé)
+		var repo storage.DocumentRepository = storage.NewDocumentRepository(db, cfg.StorageDir)
+		if cfg.LedgerGroupCommitEnabled {
+			groupCommitRepo := storage.NewGroupCommitRepository(storage.NewDocumentRepository(db, cfg.StorageDir), storage.GroupCommitOptions{
+				Window:   time.Duration(cfg.LedgerGroupCommitWindowMs) * time.Millisecond,
+				MaxBatch: cfg.LedgerGroupCommitMaxBatch,
+			})
+			stopGroupCommit = groupCommitRepo.Close
+			repo = groupCommitRepo
+			log.Info().Int("window_ms", cfg.LedgerGroupCommitWindowMs).Int("max_batch", cfg.LedgerGroupCommitMaxBatch).Msg("Ledger group commit enabled")
+		}
+		var ledgerService ledger.Service
+		if cfg.LedgerEnabled {
+			ledgerService = ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: cfg.LedgerPerTenantChain, HashVersion: cfg.LedgerHashVersion})
+		}
+		// Signer (adaptateur depuis jwsService)
+		var signer crypto.Signer
+		if cfg.JWSEnabled && jwsService != nil {
+			signer = crypto.NewLocalSigner(jwsService)
+		}
+		ingestionService = services.NewIngestionService(repo, ledgerService, signer, services.IngestionOptions{
+			JWSRequired:    cfg.JWSRequired,
+			AuditLogger:    auditLogger,
+			WebhookManager: webhookManager,
+			Logger:         log,
+			SpoolDir:       cfg.UploadSessionsDir,
+		})
+	}
+
+	// JWKS des témoins de confiance (contre-signatures des têtes du ledger)
+	var witnessKeys crypto.KeySet
+	if cfg.LedgerWitnessJWKSPath != "" {
+		jwksData, err := os.ReadFile(cfg.LedgerWitnessJWKSPath)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", cfg.LedgerWitnessJWKSPath).Msg("Failed to read witness JWKS")
+		}
+		witnessKeys, err = crypto.ParseJWKS(jwksData)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", cfg.LedgerWitnessJWKSPath).Msg("Failed to parse witness JWKS")
+		}
+		log.Info().Int("keys", len(witnessKeys)).Msg("Witness JWKS loaded")
+	}
+
 	// Initialisation de l'application Fiber
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -199,6 +468,9 @@ func main() {
 				"error": err.Error(),
 			})
 		},
+		// Corps lus en flux : les documents volumineux ne sont pas chargés en mémoire
+		// (limite des autres routes appliquée par middleware.BodyLimit)
+		StreamRequestBody: true,
 	})
 
 	// Middlewares globaux (ordre important)
@@ -206,17 +478,20 @@ func main() {
 	app.Use(recover.New(recover.Config{
 		EnableStackTrace: true,
 	}))
-	// 2. Helmet : ajoute headers sécurité HTTP (Sprint 3 Phase 2)
+	// 2. BodyLimit : seules les routes d'ingestion reçoivent des corps au-delà de la limite
+	// (avant toute réponse anticipée : un corps en flux non lu impose de fermer la connexion)
+	app.Use(middleware.BodyLimit(fiber.DefaultBodyLimit, "/upload", "/api/v1/invoices", "/api/v1/uploads"))
+	// 3. Helmet : ajoute headers sécurité HTTP (Sprint 3 Phase 2)
 	app.Use(helmet.New())
-	// 3. RequestID : génère ID unique par requête (Sprint 3 Phase 2)
+	// 4. RequestID : génère ID unique par requête (Sprint 3 Phase 2)
 	app.Use(requestid.New())
-	// 4. Logger : peut maintenant utiliser RequestID
+	// 5. Logger : peut maintenant utiliser RequestID
 	app.Use(middleware.Logger(log))
-	// 5. CORS : gère les en-têtes CORS
+	// 6. CORS : gère les en-têtes CORS
 	app.Use(middleware.CORS())
-	// 6. Prometheus : métriques HTTP par route/méthode/code
+	// 7. Prometheus : métriques HTTP par route/méthode/code
 	app.Use(middleware.PrometheusMiddleware())
-	// 7. RateLimit : limite en dernier (après métriques)
+	// 8. RateLimit : limite en dernier (après métriques)
 	app.Use(middleware.RateLimit())
 
 	// Enregistrement des routes de base
@@ -318,38 +593,35 @@ func main() {
 			uploadGroup.Use(auth.AuthMiddleware(authService, *log))
 			uploadGroup.Use(auth.RequirePermission(rbacService, auth.PermissionWriteDocuments, *log))
 		}
-		uploadGroup.Post("", handlers.UploadHandler(db, cfg.StorageDir))
+		uploadGroup.Post("", handlers.UploadHandler(ingestionService))
 
 		// Route Sprint 1 : Endpoint d'ingestion Odoo (permission documents:write)
 		invoicesGroup := apiGroup.Group("/invoices")
 		if rbacService != nil {
 			invoicesGroup.Use(auth.RequirePermission(rbacService, auth.PermissionWriteDocuments, *log))
 		}
-		invoicesGroup.Post("", handlers.InvoicesHandler(db, cfg.StorageDir, jwsService, &cfg, log, auditLogger, webhookManager))
+		invoicesGroup.Post("", handlers.InvoicesHandler(ingestionService, &cfg, log))
 		invoicesGroup.Get("", handlers.GetInvoice) // 405 Method Not Allowed pour GET
 
+		// Uploads reprenables des documents volumineux (permission documents:write)
+		uploadsGroup := apiGroup.Group("/uploads")
+		if rbacService != nil {
+			uploadsGroup.Use(auth.RequirePermission(rbacService, auth.PermissionWriteDocuments, *log))
+		}
+		uploadsGroup.Post("", handlers.UploadCreateHandler(uploadManager, log))
+		uploadsGroup.Head("/:id", handlers.UploadStatusHandler(uploadManager, log))
+		uploadsGroup.Get("/:id", handlers.UploadStatusHandler(uploadManager, log))
+		uploadsGroup.Patch("/:id", handlers.UploadChunkHandler(uploadManager, log))
+		uploadsGroup.Post("/:id/complete", handlers.UploadCompleteHandler(uploadManager, ingestionService, &cfg, log))
+		uploadsGroup.Delete("/:id", handlers.UploadAbortHandler(uploadManager, log))
+
 		// Route Sprint 6 : Endpoint POS tickets (permission documents:write)
 		posTicketsGroup := apiGroup.Group("/pos-tickets")
 		if rbacService != nil {
 			posTicketsGroup.Use(auth.RequirePermission(rbacService, auth.PermissionWriteDocuments, *log))
 		}
-		// Initialiser le service POS si DB et JWS sont disponibles
-		if db != nil && jwsService != nil {
-			// Créer le repository
-			repo := storage.NewPostgresRepository(db.Pool, log)
-			// Créer le service ledger
-			ledgerService := ledger.NewService()
-			// Créer le signer (adaptateur depuis jwsService)
-			signer := crypto.NewLocalSigner(jwsService)
-			// Créer le service POS
-			posTicketsService := services.NewPosTicketsService(repo, ledgerService, signer)
-			// Enregistrer les routes
-			posTicketsGroup.Post("", handlers.PosTicketsHandler(posTicketsService, &cfg, log))
-			posTicketsGroup.Get("", handlers.GetPosTicket) // 405 Method Not Allowed pour GET
-			log.Info().Msg("POS tickets endpoint enabled: /api/v1/pos-tickets")
-		} else {
-			log.Warn().Msg("POS tickets endpoint disabled (requires DB and JWS)")
-		}
+		posTicketsGroup.Post("", handlers.PosTicketsHandler(ingestionService.PosTickets(), &cfg, log))
+		posTicketsGroup.Get("", handlers.GetPosTicket) // 405 Method Not Allowed pour GET
 
 		// Route Sprint 2 : Export ledger (permission ledger:read)
 		ledgerGroup := apiGroup.Group("/ledger")
@@ -357,6 +629,33 @@ func main() {
 			ledgerGroup.Use(auth.RequirePermission(rbacService, auth.PermissionReadLedger, *log))
 		}
 		ledgerGroup.Get("/export", handlers.LedgerExportHandler(db, log))
+		ledgerGroup.Get("/proof/:document_id", handlers.LedgerProofHandler(db, log))
+		ledgerGroup.Get("/tree-heads/latest", handlers.LedgerTreeHeadHandler(db, log))
+		ledgerGroup.Get("/tree-heads/:id", handlers.LedgerTreeHeadHandler(db, log))
+		ledgerGroup.Post("/tree-heads/:id/cosignatures", handlers.LedgerCosignHandler(db, witnessKeys, log))
+		ledgerGroup.Get("/consistency", handlers.LedgerConsistencyHandler(db, log))
+
+		// Bundle de preuve vérifiable hors ligne (permission documents:read)
+		proofGroup := apiGroup.Group("/documents")
+		if rbacService != nil {
+			proofGroup.Use(auth.RequirePermission(rbacService, auth.PermissionReadDocuments, *log))
+		}
+		proofGroup.Get("/:id/proof-bundle", handlers.ProofBundleHandler(db, jwsService, log, auditLogger))
+
+		// Changement de statut d'un document, inscrit dans le ledger (permission documents:write)
+		// Permission portée par la route : un Use sur /documents s'appliquerait aussi aux lectures
+		statusHandlers := []fiber.Handler{}
+		if rbacService != nil {
+			statusHandlers = append(statusHandlers, auth.RequirePermission(rbacService, auth.PermissionWriteDocuments, *log))
+		}
+		apiGroup.Patch("/documents/:id/status", append(statusHandlers, handlers.DocumentStatusHandler(db, log))...)
+
+		// Blocage légal d'un document : ni purge ni suppression (permission documents:legal_hold)
+		legalHoldHandlers := []fiber.Handler{}
+		if rbacService != nil {
+			legalHoldHandlers = append(legalHoldHandlers, auth.RequirePermission(rbacService, auth.PermissionLegalHold, *log))
+		}
+		apiGroup.Put("/documents/:id/legal-hold", append(legalHoldHandlers, handlers.LegalHoldHandler(db, log, auditLogger))...)
 
 		// Route Sprint 3 Phase 3 : Vérification intégrité (permission documents:verify)
 		verifyGroup := apiGroup.Group("/ledger/verify")
@@ -365,7 +664,47 @@ func main() {
 		}
 		verifyGroup.Get("/:document_id", handlers.VerifyHandler(db, jwsService, log, auditLogger, webhookManager))
 
-		log.Info().Msg("Database routes enabled: /dbhealth, /upload, /documents, /documents/:id, /download/:id, /api/v1/invoices, /api/v1/pos-tickets, /api/v1/ledger/export, /api/v1/ledger/verify/:document_id")
+		// Vérification complète de la chaîne du ledger (permission documents:verify)
+		verifyChainGroup := apiGroup.Group("/ledger/verify-chain")
+		if rbacService != nil {
+			verifyChainGroup.Use(auth.RequirePermission(rbacService, auth.PermissionVerifyDocuments, *log))
+		}
+		verifyChainGroup.Get("", handlers.LedgerVerifyChainHandler(db, jwsService, cfg.LedgerPerTenantChain, log, auditLogger))
+
+		// Administration des clés API (permission users:manage)
+		// Uniquement avec authentification : sans elle ces routes seraient ouvertes
+		if authService != nil && rbacService != nil {
+			apiKeysGroup := apiGroup.Group("/admin/api-keys")
+			apiKeysGroup.Use(auth.RequirePermission(rbacService, auth.PermissionManageUsers, *log))
+			apiKeysGroup.Post("", handlers.APIKeysCreateHandler(db, cfg.AuthTenantRequired, log, auditLogger))
+			apiKeysGroup.Get("", handlers.APIKeysListHandler(db, log))
+			apiKeysGroup.Delete("/:key_id", handlers.APIKeysRevokeHandler(db, authService, log, auditLogger))
+			apiKeysGroup.Post("/:key_id/rotate", handlers.APIKeysRotateHandler(db, authService, log, auditLogger))
+			log.Info().Msg("Admin routes enabled: /api/v1/admin/api-keys")
+
+			if jwsService != nil && jwsService.Rotation() != nil {
+				keysGroup := apiGroup.Group("/admin/keys")
+				keysGroup.Use(auth.RequirePermission(rbacService, auth.PermissionManageUsers, *log))
+				keysGroup.Post("/rotate", handlers.KeysRotateHandler(jwsService, db, log, auditLogger))
+				log.Info().Msg("Admin routes enabled: /api/v1/admin/keys/rotate")
+			}
+
+			// Réconciliation complète, rapport signé (permission reconcile:execute)
+			reconcileOpts := reconcile.Options{
+				StorageDir: cfg.StorageDir,
+				Sealer:     ingestionService,
+				Filter:     storage.UnsealedFilter{MissingJWS: cfg.JWSEnabled, MissingLedger: cfg.LedgerEnabled},
+			}
+			if jwsService != nil {
+				reconcileOpts.Verifier = jwsService
+			}
+			reconcileGroup := apiGroup.Group("/admin/reconcile")
+			reconcileGroup.Use(auth.RequirePermission(rbacService, auth.PermissionReconcile, *log))
+			reconcileGroup.Post("", handlers.ReconcileHandler(db, reconcileOpts, jwsService, log, auditLogger))
+			log.Info().Msg("Admin routes enabled: /api/v1/admin/reconcile")
+		}
+
+		log.Info().Msg("Database routes enabled: /dbhealth, /upload, /documents, /documents/:id, /download/:id, /api/v1/invoices, /api/v1/uploads, /api/v1/pos-tickets, /api/v1/ledger/export, /api/v1/ledger/proof/:document_id, /api/v1/ledger/tree-heads, /api/v1/ledger/consistency, /api/v1/ledger/verify/:document_id, /api/v1/ledger/verify-chain, /api/v1/documents/:id/proof-bundle, /api/v1/documents/:id/status, /api/v1/documents/:id/legal-hold")
 	}
 
 	// Gestion de l'arrêt propre avec timeout
@@ -394,11 +733,24 @@ func main() {
 	shCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Arrêter la clôture automatique
+	stopClosingScheduler()
+	stopRetentionScheduler()
+	stopUploadCleanup()
+	stopAnchorScheduler()
+	stopMerkleScheduler()
+	stopPartitionScheduler()
+	stopTreeHeadScheduler()
+	stopScrubScheduler()
+
 	// Arrêter le serveur Fiber
 	if err := app.Shutdown(); err != nil {
 		log.Error().Err(err).Msg("Error during server shutdown")
 	}
 
+	// Valider les derniers lots du group commit
+	stopGroupCommit()
+
 	// Fermer la connexion DB proprement avec timeout
 	if db != nil {
 		done := make(chan struct{})
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/proof"
+	"github.com/doreviateam/dorevia-vault/internal/tsp"
+)
+
+// verifybundle vérifie hors ligne un bundle de preuve exporté par
+// GET /api/v1/documents/:id/proof-bundle (aucun accès au coffre ni à la base)
+//
+// Codes de sortie : 0 bundle valide, 1 bundle invalide, 2 erreur d'utilisation ou de lecture
+func main() {
+	jwksPath := flag.String("jwks", "", "JWKS de confiance obtenu hors bande (recommandé ; sinon jwks.json du bundle)")
+	tsaCAPath := flag.String("tsa-ca", "", "Certificats PEM des autorités d'horodatage de confiance (défaut: magasin du système)")
+	output := flag.String("output", "", "Fichier de sortie pour le rapport JSON (optionnel)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-jwks trusted.json] [-tsa-ca tsa.pem] [-output report.json] proof-<id>.zip\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read bundle: %v\n", err)
+		os.Exit(2)
+	}
+
+	var trusted crypto.KeySet
+	if *jwksPath != "" {
+		jwksData, err := os.ReadFile(*jwksPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to read JWKS: %v\n", err)
+			os.Exit(2)
+		}
+		trusted, err = crypto.ParseJWKS(jwksData)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	var tsaRoots *x509.CertPool
+	if *tsaCAPath != "" {
+		tsaRoots, err = tsp.LoadCertPool(*tsaCAPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	report, err := proof.VerifyBundle(data, trusted, tsaRoots)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Afficher le rapport
+	fmt.Printf("\n=== Vérification du bundle de preuve ===\n\n")
+	if report.DocumentID != "" {
+		fmt.Printf("Document: %s\n", report.DocumentID)
+	}
+	if m := report.Manifest; m != nil {
+		fmt.Printf("Fichier: %s (%s, %d octets)\n", m.Filename, m.ContentKind, m.SizeBytes)
+		fmt.Printf("SHA256: %s\n", m.SHA256Hex)
+		fmt.Printf("Exporté le: %s\n", m.GeneratedAt.Format("2006-01-02T15:04:05Z07:00"))
+		if m.AnchoredAt != nil {
+			fmt.Printf("Horodaté (TSA) le: %s\n", m.AnchoredAt.Format("2006-01-02T15:04:05Z07:00"))
+		}
+	}
+	if report.KeysSource == proof.KeysFromBundle {
+		fmt.Printf("Clés: jwks.json du bundle (non épinglées, utiliser -jwks pour une vérification de confiance)\n")
+	} else {
+		fmt.Printf("Clés: JWKS de confiance (%s)\n", *jwksPath)
+	}
+	fmt.Printf("\n")
+	for _, check := range report.Checks {
+		fmt.Printf("  [%s] %s: %s\n", check.Status, check.Component, check.Message)
+	}
+	fmt.Printf("\nVerdict: %s\n\n", map[bool]string{true: "BUNDLE VALIDE", false: "BUNDLE INVALIDE"}[report.Valid])
+
+	// Exporter le rapport JSON si demandé
+	if *output != "" {
+		reportJSON, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to marshal report: %v\n", err)
+			os.Exit(2)
+		}
+		if err := os.WriteFile(*output, reportJSON, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write report: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	if !report.Valid {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+)
+
+// chainVerdict est le rapport exporté (rapport + verdict signé)
+type chainVerdict struct {
+	*ledger.ChainReport
+	SignedVerdict *string `json:"signed_verdict,omitempty"`
+}
+
+func main() {
+	// Flags
+	from := flag.String("from", "", "Borne basse incluse (RFC3339 ou YYYY-MM-DD, optionnel)")
+	to := flag.String("to", "", "Borne haute (RFC3339 exclue, ou YYYY-MM-DD journée incluse, optionnel)")
+	output := flag.String("output", "", "Fichier de sortie pour le rapport JSON (optionnel)")
+	tenant := flag.String("tenant", "", "Restreindre la vérification à un tenant (optionnel)")
+	sign := flag.Bool("sign", true, "Signer le verdict avec la clé JWS configurée")
+	timeout := flag.Duration("timeout", 30*time.Minute, "Durée maximale de la vérification")
+	flag.Parse()
+
+	fromTime, err := ledger.ParseTimeBound(*from, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	toTime, err := ledger.ParseTimeBound(*to, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Charger la configuration
+	cfg := config.LoadOrDie()
+
+	// Initialiser le logger
+	log := logger.New(cfg.LogLevel)
+
+	if cfg.DatabaseURL == "" {
+		log.Fatal().Msg("DATABASE_URL not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := storage.NewDB(ctx, cfg.DatabaseURL, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	log.Info().Str("from", *from).Str("to", *to).Str("tenant", *tenant).Msg("Starting ledger chain verification")
+
+	report, err := ledger.VerifyChain(ctx, db.Pool, ledger.ChainOptions{
+		From:           fromTime,
+		To:             toTime,
+		Tenant:         *tenant,
+		PerTenantChain: cfg.LedgerPerTenantChain,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Chain verification failed")
+	}
+
+	verdict := &chainVerdict{ChainReport: report}
+	if *sign {
+		jwsService, err := crypto.NewService(cfg.JWSPrivateKeyPath, cfg.JWSPublicKeyPath, cfg.JWSKID)
+		if err != nil {
+			log.Warn().Err(err).Msg("JWS unavailable, verdict will not be signed")
+		} else {
+			jws, err := jwsService.SignReport(ledger.ChainVerdictSubject, report, time.Now())
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to sign verdict")
+			} else {
+				verdict.SignedVerdict = &jws
+			}
+		}
+	}
+
+	// Afficher le rapport
+	fmt.Printf("\n=== Vérification de la chaîne du ledger ===\n\n")
+	fmt.Printf("Timestamp: %s\n", report.CheckedAt.Format(time.RFC3339))
+	fmt.Printf("Fenêtre: %s → %s\n", boundString(report.From), boundString(report.To))
+	if report.Tenant != "" {
+		fmt.Printf("Tenant: %s\n", report.Tenant)
+	}
+	fmt.Printf("Entrées vérifiées: %d\n", report.EntriesChecked)
+	if report.EntriesChecked > 0 && report.HeadHash != "" {
+		fmt.Printf("Entrées: #%d → #%d\n", report.FirstEntryID, report.LastEntryID)
+		fmt.Printf("Hash de tête: %s\n", report.HeadHash)
+	}
+	fmt.Printf("Verdict: %s\n", map[bool]string{true: "CHAÎNE VALIDE", false: "CHAÎNE ROMPUE"}[report.Valid])
+
+	if report.FirstBrokenLink != nil {
+		fmt.Printf("\nPremier maillon rompu: entrée #%d (document %s)\n", report.FirstBrokenLink.EntryID, report.FirstBrokenLink.DocumentID)
+		fmt.Printf("  - %s\n", report.FirstBrokenLink.Message)
+		if report.FirstBrokenLink.Expected != "" {
+			fmt.Printf("  - attendu: %s\n  - stocké:  %s\n", report.FirstBrokenLink.Expected, report.FirstBrokenLink.Actual)
+		}
+	}
+	printIssues("Maillons rompus", report.BrokenLinkCount, report.BrokenLinks)
+	printIssues("Trous", report.GapCount, report.Gaps)
+	printIssues("Bifurcations (forks)", report.ForkCount, report.Forks)
+
+	if verdict.SignedVerdict != nil {
+		fmt.Printf("\nVerdict signé (JWS): %s\n", *verdict.SignedVerdict)
+	}
+	fmt.Printf("\n")
+
+	// Exporter le rapport JSON si demandé
+	if *output != "" {
+		reportJSON, err := json.MarshalIndent(verdict, "", "  ")
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal report to JSON")
+			os.Exit(1)
+		}
+		if err := os.WriteFile(*output, reportJSON, 0644); err != nil {
+			log.Error().Err(err).Str("output", *output).Msg("Failed to write report file")
+			os.Exit(1)
+		}
+		log.Info().Str("output", *output).Msg("Report exported to JSON file")
+	}
+
+	// Code de sortie : 1 si la chaîne est rompue
+	if !report.Valid {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func boundString(t *time.Time) string {
+	if t == nil {
+		return "∞"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func printIssues(title string, count int, issues []ledger.ChainIssue) {
+	if count == 0 {
+		return
+	}
+	fmt.Printf("\n%s: %d\n", title, count)
+	for i, issue := range issues {
+		if i == 10 { // Afficher les 10 premiers
+			fmt.Printf("  ... et %d autres\n", count-10)
+			break
+		}
+		fmt.Printf("  - Entrée #%d (document %s): %s\n", issue.EntryID, issue.DocumentID, issue.Message)
+	}
+}
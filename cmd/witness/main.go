@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/webhooks"
+	"github.com/doreviateam/dorevia-vault/internal/witness"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+)
+
+// witness suit les têtes signées d'un ou plusieurs coffres (GET /api/v1/ledger/tree-heads/latest),
+// vérifie leur cohérence avec la dernière tête témoignée et les contre-signe avec sa propre clé.
+// Le JWKS du témoin (cmd/keygen) est déclaré au coffre via LEDGER_WITNESS_JWKS_PATH.
+// Une réécriture de l'historique émet l'événement webhook error.critical (WEBHOOKS_*)
+//
+// Codes de sortie (-once) : 0 têtes contre-signées, 1 incohérence détectée, 2 erreur
+func main() {
+	vaultsFlag := flag.String("vaults", os.Getenv("WITNESS_VAULTS"), "Coffres suivis, séparés par des virgules : nom=https://vault.example.com ou URL (WITNESS_VAULTS)")
+	apiKey := flag.String("api-key", os.Getenv("WITNESS_API_KEY"), "Clé API du témoin, permission ledger:read (WITNESS_API_KEY)")
+	witnessID := flag.String("id", envOr("WITNESS_ID", hostname()), "Identifiant du témoin (WITNESS_ID)")
+	stateDir := flag.String("state-dir", envOr("WITNESS_STATE_DIR", "/var/lib/dorevia-witness"), "Répertoire des dernières têtes témoignées (WITNESS_STATE_DIR)")
+	privateKey := flag.String("private-key", envOr("WITNESS_PRIVATE_KEY_PATH", "/opt/dorevia-witness/keys/private.pem"), "Clé privée du témoin (WITNESS_PRIVATE_KEY_PATH)")
+	publicKey := flag.String("public-key", envOr("WITNESS_PUBLIC_KEY_PATH", "/opt/dorevia-witness/keys/public.pem"), "Clé publique du témoin (WITNESS_PUBLIC_KEY_PATH)")
+	kid := flag.String("kid", envOr("WITNESS_KID", "witness-key"), "Identifiant de la clé du témoin (WITNESS_KID)")
+	interval := flag.Int("interval", envInt("WITNESS_INTERVAL_MINUTES", 5), "Intervalle de vérification en minutes (WITNESS_INTERVAL_MINUTES)")
+	once := flag.Bool("once", false, "Une seule vérification puis sortie (cron)")
+	flag.Parse()
+
+	cfg := config.LoadOrDie()
+	log := logger.New(cfg.LogLevel)
+
+	vaults, err := witness.ParseVaults(*vaultsFlag, *apiKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	signer, err := crypto.NewService(*privateKey, *publicKey, *kid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load witness key: %v\n", err)
+		os.Exit(2)
+	}
+	if *interval <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: -interval must be positive\n")
+		os.Exit(2)
+	}
+
+	// Alertes error.critical : mêmes webhooks que le coffre
+	var webhookManager *webhooks.Manager
+	if cfg.WebhooksEnabled {
+		queue, err := webhooks.NewQueue(webhooks.QueueConfig{
+			RedisURL:  cfg.WebhooksRedisURL,
+			QueueName: "dorevia:webhooks",
+			Logger:    *log,
+		})
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to initialize webhook queue, alerts disabled")
+		} else {
+			webhookManager = webhooks.NewManager(webhooks.ManagerConfig{
+				Queue: queue,
+				Worker: webhooks.NewWorker(webhooks.WorkerConfig{
+					Queue:     queue,
+					SecretKey: cfg.WebhooksSecretKey,
+					Workers:   cfg.WebhooksWorkers,
+					Logger:    *log,
+				}),
+				WebhookURLs: webhooks.ParseWebhookURLs(cfg.WebhooksURLs),
+				Logger:      *log,
+			})
+			webhookManager.Start(context.Background())
+			defer webhookManager.Stop()
+		}
+	}
+
+	w := witness.New(witness.Config{
+		WitnessID:      *witnessID,
+		Signer:         signer,
+		StateDir:       *stateDir,
+		WebhookManager: webhookManager,
+		Logger:         *log,
+	})
+
+	if *once {
+		code := 0
+		for _, v := range vaults {
+			if _, err := w.Check(context.Background(), v); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", v.Name, err)
+				if errors.Is(err, witness.ErrInconsistent) {
+					code = 1
+				} else if code == 0 {
+					code = 2
+				}
+			}
+		}
+		if webhookManager != nil {
+			webhookManager.Stop()
+		}
+		os.Exit(code)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-quit
+		cancel()
+	}()
+
+	log.Info().
+		Str("witness_id", *witnessID).
+		Int("vaults", len(vaults)).
+		Int("interval_minutes", *interval).
+		Msg("Ledger witness started")
+	w.Run(ctx, vaults, time.Duration(*interval)*time.Minute)
+	log.Info().Msg("Ledger witness stopped")
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "witness"
+	}
+	return name
+}
@@ -22,6 +22,11 @@ const (
 	EventTypeReconciliationRun   EventType = "reconciliation_run"
 	EventTypeVerificationRun    EventType = "verification_run"
 	EventTypeDocumentDownloaded  EventType = "document_downloaded"
+	EventTypeAPIKeyManaged      EventType = "api_key_managed"
+	EventTypeKeyRotated         EventType = "key_rotated"
+	EventTypePeriodClosed       EventType = "period_closed"
+	EventTypeDocumentPurged     EventType = "document_purged"
+	EventTypeLegalHoldChanged   EventType = "legal_hold_changed"
 	EventTypeError              EventType = "error"
 )
 
@@ -41,6 +46,7 @@ type Event struct {
 	DocumentID string                 `json:"document_id,omitempty"`
 	RequestID  string                 `json:"request_id,omitempty"`
 	Source     string                 `json:"source,omitempty"` // sales, purchase, pos, stock, sale, unknown
+	Tenant     string                 `json:"tenant,omitempty"` // Tenant concerné (multi-tenant)
 	Status     EventStatus            `json:"status"`
 	DurationMS int64                  `json:"duration_ms,omitempty"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
@@ -549,7 +549,7 @@ func (g *ReportGenerator) collectLedgerStats(startDate, endDate string) (*Ledger
 	query := `
 		SELECT 
 			COUNT(*) as total_entries,
-			COUNT(CASE WHEN evidence_jws IS NULL OR evidence_jws = '' THEN 1 END) as errors
+			COUNT(CASE WHEN entry_type = 'document.created' AND (evidence_jws IS NULL OR evidence_jws = '') THEN 1 END) as errors
 		FROM ledger
 		WHERE timestamp >= $1 AND timestamp <= $2
 	`
@@ -121,6 +121,27 @@ func (s *Signer) SignDailyLog(date string) (*DailyHash, error) {
 	return dailyHash, nil
 }
 
+// DailyHash retourne la signature journalière d'une date : celle déjà enregistrée,
+// sinon le log du jour est signé. Retourne une erreur os.ErrNotExist s'il n'y a pas de log
+func (s *Signer) DailyHash(date string) (*DailyHash, error) {
+	data, err := os.ReadFile(s.logger.GetSignaturePath(date))
+	if err == nil {
+		var dailyHash DailyHash
+		if err := json.Unmarshal(data, &dailyHash); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal signature: %w", err)
+		}
+		return &dailyHash, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read signature file: %w", err)
+	}
+
+	if _, err := os.Stat(s.logger.GetLogPath(date)); err != nil {
+		return nil, fmt.Errorf("no audit log for %s: %w", date, err)
+	}
+	return s.SignDailyLog(date)
+}
+
 // saveSignature sauvegarde la signature dans un fichier JSON
 func (s *Signer) saveSignature(path string, dailyHash *DailyHash) error {
 	// Créer le répertoire si nécessaire
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// APIKeyPrefix préfixe les clés API générées (identification visuelle et scanners de secrets)
+const APIKeyPrefix = "dvk"
+
+// DefaultAPIKeyCacheTTL est la durée par défaut du cache des clés API persistées
+const DefaultAPIKeyCacheTTL = time.Minute
+
+// APIKeyStore est le stockage persistant des clés API (ex: table PostgreSQL api_keys)
+type APIKeyStore interface {
+	// GetAPIKeyByHash retourne la clé correspondant au hash, nil si inconnue
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	// TouchAPIKey met à jour la date de dernière utilisation
+	TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error
+}
+
+// cachedAPIKey est une entrée du cache mémoire des clés persistées
+type cachedAPIKey struct {
+	key       *APIKey
+	expiresAt time.Time
+}
+
+// GenerateAPIKey génère une nouvelle clé API au format dvk_<key_id>_<secret>
+// Retourne la clé en clair (à afficher une seule fois), son identifiant et son hash
+func GenerateAPIKey() (plaintext, keyID, keyHash string, err error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate key id: %w", err)
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate key secret: %w", err)
+	}
+
+	keyID = hex.EncodeToString(idBytes)
+	plaintext = fmt.Sprintf("%s_%s_%s", APIKeyPrefix, keyID, hex.EncodeToString(secretBytes))
+	return plaintext, keyID, HashAPIKey(plaintext), nil
+}
+
+// lookupAPIKey cherche une clé dans le cache puis dans le stockage persistant
+func (a *AuthService) lookupAPIKey(ctx context.Context, keyHash string) (*APIKey, error) {
+	if a.apiKeyStore == nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	a.apiKeyCacheMu.RLock()
+	cached, ok := a.apiKeyCache[keyHash]
+	a.apiKeyCacheMu.RUnlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.key, nil
+	}
+
+	key, err := a.apiKeyStore.GetAPIKeyByHash(ctx, keyHash)
+	if err != nil {
+		a.log.Error().Err(err).Msg("Failed to lookup API key")
+		return nil, fmt.Errorf("failed to lookup API key: %w", err)
+	}
+	if key == nil {
+		return nil, nil
+	}
+
+	// Dernière utilisation : mise à jour au plus une fois par période de cache
+	if err := a.apiKeyStore.TouchAPIKey(ctx, key.KeyID, now); err != nil {
+		a.log.Warn().Err(err).Str("key_id", key.KeyID).Msg("Failed to update API key last usage")
+	}
+
+	a.apiKeyCacheMu.Lock()
+	a.apiKeyCache[keyHash] = cachedAPIKey{key: key, expiresAt: now.Add(a.apiKeyCacheTTL)}
+	a.apiKeyCacheMu.Unlock()
+
+	return key, nil
+}
+
+// InvalidateAPIKey retire une clé du cache (révocation, rotation)
+func (a *AuthService) InvalidateAPIKey(keyID string) {
+	a.apiKeyCacheMu.Lock()
+	defer a.apiKeyCacheMu.Unlock()
+	for hash, cached := range a.apiKeyCache {
+		if cached.key.KeyID == keyID {
+			delete(a.apiKeyCache, hash)
+		}
+	}
+}
@@ -2,54 +2,116 @@ package auth
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/rs/zerolog"
 )
 
+// DefaultTenantClaim est la claim JWT portant le tenant par défaut
+const DefaultTenantClaim = "tenant"
+
+// ErrTenantRequired est retourné, si le tenant est obligatoire (AuthConfig.TenantRequired),
+// pour une identité sans tenant qui n'est pas administrateur
+var ErrTenantRequired = errors.New("tenant required for non-admin identity")
+
+// DefaultJWTClockSkew est la tolérance d'horloge par défaut pour exp/nbf/iat
+const DefaultJWTClockSkew = 60 * time.Second
+
+// SupportedJWTAlgorithms liste les algorithmes de signature JWT acceptés
+var SupportedJWTAlgorithms = []string{"RS256", "ES256"}
+
 // AuthService gère l'authentification (JWT et API Keys)
 type AuthService struct {
-	jwtPublicKey *rsa.PublicKey // Pour vérification JWT
-	apiKeys      map[string]*APIKey
-	log          zerolog.Logger
-	jwtEnabled   bool
-	apiKeyEnabled bool
+	jwtPublicKey   crypto.PublicKey // Clé statique pour vérification JWT (RSA ou ECDSA)
+	jwks           *JWKSCache       // JWKS distant de l'émetteur (optionnel)
+	jwtIssuer      string
+	jwtAudience    string
+	jwtClockSkew   time.Duration
+	apiKeys        map[string]*APIKey
+	log            zerolog.Logger
+	jwtEnabled     bool
+	apiKeyEnabled  bool
+	tenantClaim    string // Claim JWT portant le tenant (défaut: "tenant")
+	tenantRequired bool   // Identités sans tenant réservées aux administrateurs
+
+	// Clés API persistées (optionnel) + cache mémoire
+	apiKeyStore    APIKeyStore
+	apiKeyCacheTTL time.Duration
+	apiKeyCacheMu  sync.RWMutex
+	apiKeyCache    map[string]cachedAPIKey // key_hash -> clé
 }
 
 // APIKey représente une clé API
 type APIKey struct {
-	KeyID     string
-	KeyHash   string // SHA256 de la clé réelle
-	UserID    string
-	Role      string
-	CreatedAt time.Time
-	ExpiresAt *time.Time // Optionnel
-	IsActive  bool
+	KeyID      string     `json:"key_id"`
+	KeyHash    string     `json:"-"` // SHA256 de la clé réelle (jamais exposé)
+	Name       string     `json:"name,omitempty"`
+	UserID     string     `json:"user_id"`
+	Role       string     `json:"role"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // Optionnel
+	IsActive   bool       `json:"is_active"`
+	Tenant     string     `json:"tenant,omitempty"` // Tenant rattaché à la clé ("" = aucun)
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
 }
 
 // AuthConfig configuration pour AuthService
 type AuthConfig struct {
-	JWTPublicKey  *rsa.PublicKey // Clé publique pour vérification JWT
-	APIKeys       map[string]*APIKey
-	JWTEnabled    bool
-	APIKeyEnabled bool
-	Logger        zerolog.Logger
+	JWTPublicKey   crypto.PublicKey // Clé publique statique pour vérification JWT (RSA ou ECDSA)
+	JWKS           *JWKSCache       // JWKS distant indexé par kid (optionnel)
+	JWTIssuer      string           // Valeur attendue de la claim iss (vide = non vérifiée)
+	JWTAudience    string           // Valeur attendue dans la claim aud (vide = non vérifiée)
+	JWTClockSkew   time.Duration    // Tolérance d'horloge exp/nbf (défaut: 60s)
+	APIKeys        map[string]*APIKey
+	JWTEnabled     bool
+	APIKeyEnabled  bool
+	TenantClaim    string        // Claim JWT portant le tenant (défaut: "tenant")
+	TenantRequired bool          // Multi-tenant : tenant obligatoire hors rôle admin
+	APIKeyStore    APIKeyStore   // Stockage persistant des clés API (optionnel)
+	APIKeyCacheTTL time.Duration // Durée du cache des clés persistées (défaut: 1 min)
+	Logger         zerolog.Logger
 }
 
 // NewAuthService crée un nouveau service d'authentification
 func NewAuthService(cfg AuthConfig) *AuthService {
+	tenantClaim := cfg.TenantClaim
+	if tenantClaim == "" {
+		tenantClaim = DefaultTenantClaim
+	}
+	clockSkew := cfg.JWTClockSkew
+	if clockSkew <= 0 {
+		clockSkew = DefaultJWTClockSkew
+	}
+	cacheTTL := cfg.APIKeyCacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultAPIKeyCacheTTL
+	}
 	return &AuthService{
-		jwtPublicKey:  cfg.JWTPublicKey,
-		apiKeys:       cfg.APIKeys,
-		log:           cfg.Logger,
-		jwtEnabled:    cfg.JWTEnabled,
-		apiKeyEnabled: cfg.APIKeyEnabled,
+		jwtPublicKey:   cfg.JWTPublicKey,
+		jwks:           cfg.JWKS,
+		jwtIssuer:      cfg.JWTIssuer,
+		jwtAudience:    cfg.JWTAudience,
+		jwtClockSkew:   clockSkew,
+		apiKeys:        cfg.APIKeys,
+		log:            cfg.Logger,
+		jwtEnabled:     cfg.JWTEnabled,
+		apiKeyEnabled:  cfg.APIKeyEnabled,
+		tenantClaim:    tenantClaim,
+		tenantRequired: cfg.TenantRequired,
+		apiKeyStore:    cfg.APIKeyStore,
+		apiKeyCacheTTL: cacheTTL,
+		apiKeyCache:    make(map[string]cachedAPIKey),
 	}
 }
 
@@ -68,36 +130,57 @@ func (a *AuthService) Authenticate(ctx context.Context, authHeader string) (*Use
 	scheme := strings.ToLower(parts[0])
 	token := parts[1]
 
+	var userInfo *UserInfo
+	var err error
 	switch scheme {
 	case "bearer":
 		if !a.jwtEnabled {
 			return nil, fmt.Errorf("JWT authentication is not enabled")
 		}
-		return a.authenticateJWT(ctx, token)
+		userInfo, err = a.authenticateJWT(ctx, token)
 	case "apikey":
 		if !a.apiKeyEnabled {
 			return nil, fmt.Errorf("API key authentication is not enabled")
 		}
-		return a.authenticateAPIKey(ctx, token)
+		userInfo, err = a.authenticateAPIKey(ctx, token)
 	default:
 		return nil, fmt.Errorf("unsupported authentication scheme: %s", scheme)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Multi-tenant : seul un administrateur peut ne pas être rattaché à un tenant (accès non
+	// restreint) ; un JWT sans claim tenant ne doit pas ouvrir l'accès à tous les tenants
+	if a.tenantRequired && userInfo.Tenant == "" && userInfo.Role != string(RoleAdmin) {
+		return nil, ErrTenantRequired
+	}
+	return userInfo, nil
 }
 
 // authenticateJWT authentifie avec un JWT
 func (a *AuthService) authenticateJWT(ctx context.Context, tokenString string) (*UserInfo, error) {
-	if a.jwtPublicKey == nil {
+	if a.jwtPublicKey == nil && a.jwks == nil {
 		return nil, fmt.Errorf("JWT public key not configured")
 	}
 
+	// Validation : algorithme, exp obligatoire, nbf/iat avec tolérance, iss/aud si configurés
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(SupportedJWTAlgorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(a.jwtClockSkew),
+	}
+	if a.jwtIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.jwtIssuer))
+	}
+	if a.jwtAudience != "" {
+		opts = append(opts, jwt.WithAudience(a.jwtAudience))
+	}
+
 	// Parser et vérifier le token
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Vérifier l'algorithme
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return a.jwtPublicKey, nil
-	})
+		return a.jwtVerificationKey(ctx, token)
+	}, opts...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify JWT: %w", err)
@@ -118,6 +201,7 @@ func (a *AuthService) authenticateJWT(ctx context.Context, tokenString string) (
 		UserID: getStringClaim(claims, "sub", "user_id"),
 		Role:   getStringClaim(claims, "role"),
 		Email:  getStringClaim(claims, "email"),
+		Tenant: getStringClaim(claims, a.tenantClaim),
 	}
 
 	if userInfo.UserID == "" {
@@ -127,15 +211,55 @@ func (a *AuthService) authenticateJWT(ctx context.Context, tokenString string) (
 	return userInfo, nil
 }
 
+// jwtVerificationKey sélectionne la clé de vérification : JWKS par kid si configuré,
+// sinon clé statique. Le type de clé doit correspondre à l'algorithme annoncé.
+func (a *AuthService) jwtVerificationKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	var key crypto.PublicKey
+	kid, _ := token.Header["kid"].(string)
+	switch {
+	case a.jwks != nil && kid != "":
+		k, err := a.jwks.GetKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	case a.jwtPublicKey != nil:
+		key = a.jwtPublicKey
+	default:
+		return nil, fmt.Errorf("no verification key available (missing kid)")
+	}
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA:
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("key type mismatch for algorithm %v", token.Header["alg"])
+		}
+	case *jwt.SigningMethodECDSA:
+		if _, ok := key.(*ecdsa.PublicKey); !ok {
+			return nil, fmt.Errorf("key type mismatch for algorithm %v", token.Header["alg"])
+		}
+	default:
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key, nil
+}
+
 // authenticateAPIKey authentifie avec une clé API
 func (a *AuthService) authenticateAPIKey(ctx context.Context, apiKey string) (*UserInfo, error) {
 	// Hasher la clé API
-	keyHash := hashAPIKey(apiKey)
+	keyHash := HashAPIKey(apiKey)
 
-	// Chercher la clé dans la map
+	// Chercher la clé dans la map statique, puis dans le stockage persistant
 	key, ok := a.apiKeys[keyHash]
 	if !ok {
-		return nil, fmt.Errorf("invalid API key")
+		var err error
+		key, err = a.lookupAPIKey(ctx, keyHash)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			return nil, fmt.Errorf("invalid API key")
+		}
 	}
 
 	// Vérifier si la clé est active
@@ -152,6 +276,7 @@ func (a *AuthService) authenticateAPIKey(ctx context.Context, apiKey string) (*U
 		UserID: key.UserID,
 		Role:   key.Role,
 		KeyID:  key.KeyID,
+		Tenant: key.Tenant,
 	}, nil
 }
 
@@ -161,6 +286,7 @@ type UserInfo struct {
 	Role   string
 	Email  string
 	KeyID  string // Pour API keys
+	Tenant string // Tenant de l'utilisateur ("" = accès non restreint)
 }
 
 // getStringClaim extrait une claim string avec fallback
@@ -173,9 +299,8 @@ func getStringClaim(claims jwt.MapClaims, keys ...string) string {
 	return ""
 }
 
-// hashAPIKey hash une clé API avec SHA256
-func hashAPIKey(apiKey string) string {
+// HashAPIKey hash une clé API avec SHA256 (forme stockée)
+func HashAPIKey(apiKey string) string {
 	hash := sha256.Sum256([]byte(apiKey))
 	return base64.StdEncoding.EncodeToString(hash[:])
 }
-
@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Valeurs par défaut du cache JWKS
+const (
+	DefaultJWKSCacheTTL       = time.Hour        // rafraîchissement périodique
+	DefaultJWKSMinRefreshWait = 30 * time.Second // délai minimal entre deux rafraîchissements (kid inconnu)
+)
+
+// LoadPublicKeyPEM charge une clé publique de vérification JWT (RSA ou ECDSA) depuis un fichier PEM
+// Formats acceptés : PUBLIC KEY (PKIX), RSA PUBLIC KEY (PKCS#1), CERTIFICATE
+func LoadPublicKeyPEM(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key file: %w", err)
+	}
+	return ParsePublicKeyPEM(data)
+}
+
+// ParsePublicKeyPEM parse une clé publique PEM (RSA ou ECDSA)
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+
+	var key crypto.PublicKey
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		key = parsed
+	case "RSA PUBLIC KEY":
+		parsed, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSA public key: %w", err)
+		}
+		key = parsed
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		key = cert.PublicKey
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T (RSA or ECDSA expected)", key)
+	}
+}
+
+// JWK représente une clé publique au format JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet représente un JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKey convertit la JWK en clé publique Go (*rsa.PublicKey ou *ecdsa.PublicKey)
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64URLInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := decodeBase64URLInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 || e.Int64() < 3 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve: %s", k.Crv)
+		}
+		x, err := decodeBase64URLInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %w", err)
+		}
+		y, err := decodeBase64URLInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("EC point is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
+
+func decodeBase64URLInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty value")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// JWKSCache récupère et met en cache un JWKS distant, indexé par kid
+// Un kid inconnu déclenche un rafraîchissement (limité par minRefreshWait)
+type JWKSCache struct {
+	url            string
+	client         *http.Client
+	ttl            time.Duration
+	minRefreshWait time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time // dernier téléchargement réussi
+	lastAttempt time.Time // dernière tentative (réussie ou non)
+}
+
+// NewJWKSCache crée un cache JWKS pour l'URL donnée
+func NewJWKSCache(url string, ttl time.Duration, client *http.Client) *JWKSCache {
+	if ttl <= 0 {
+		ttl = DefaultJWKSCacheTTL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &JWKSCache{
+		url:            url,
+		client:         client,
+		ttl:            ttl,
+		minRefreshWait: DefaultJWKSMinRefreshWait,
+		keys:           make(map[string]crypto.PublicKey),
+	}
+}
+
+// SetMinRefreshWait ajuste le délai minimal entre deux rafraîchissements
+func (c *JWKSCache) SetMinRefreshWait(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.minRefreshWait = d
+}
+
+// GetKey retourne la clé publique associée au kid, en rafraîchissant le JWKS si nécessaire
+func (c *JWKSCache) GetKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	stale := time.Since(c.fetchedAt) > c.ttl
+	canRefresh := time.Since(c.lastAttempt) >= c.minRefreshWait
+	c.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	// Rafraîchir : cache expiré, ou kid inconnu (rotation côté émetteur)
+	if canRefresh {
+		if err := c.Refresh(ctx); err != nil {
+			if ok {
+				// Clé connue : on tolère l'indisponibilité temporaire du JWKS
+				return key, nil
+			}
+			return nil, err
+		}
+		c.mu.RLock()
+		key, ok = c.keys[kid]
+		c.mu.RUnlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	return key, nil
+}
+
+// Refresh télécharge le JWKS et remplace les clés en cache
+func (c *JWKSCache) Refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	c.mu.Lock()
+	c.lastAttempt = time.Now()
+	c.mu.Unlock()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// Clé non supportée : ignorée sans invalider le reste du JWKS
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
@@ -141,3 +141,25 @@ func GetUserInfo(c *fiber.Ctx) (*UserInfo, error) {
 	return userInfo, nil
 }
 
+
+// GetTenant retourne le tenant de l'utilisateur authentifié
+// Retourne "" si l'authentification est désactivée ou si l'utilisateur n'est rattaché à aucun tenant
+func GetTenant(c *fiber.Ctx) string {
+	userInfo, ok := c.Locals("user").(*UserInfo)
+	if !ok || userInfo == nil {
+		return ""
+	}
+	return userInfo.Tenant
+}
+
+// CanAccessTenant indique si l'utilisateur courant peut accéder à une ressource du tenant donné
+// Un utilisateur sans tenant n'est pas restreint (en multi-tenant, AUTH_TENANT_REQUIRED
+// réserve ce cas aux administrateurs) ; un utilisateur rattaché à un tenant n'accède
+// qu'aux ressources de ce tenant.
+func CanAccessTenant(c *fiber.Ctx, resourceTenant *string) bool {
+	tenant := GetTenant(c)
+	if tenant == "" {
+		return true
+	}
+	return resourceTenant != nil && *resourceTenant == tenant
+}
@@ -26,6 +26,7 @@ const (
 	PermissionVerifyDocuments  Permission = "documents:verify"
 	PermissionReconcile        Permission = "reconcile:execute"
 	PermissionManageUsers      Permission = "users:manage"
+	PermissionLegalHold        Permission = "documents:legal_hold"
 )
 
 // RBACService gère les autorisations basées sur les rôles
@@ -45,6 +46,7 @@ func NewRBACService() *RBACService {
 			PermissionVerifyDocuments,
 			PermissionReconcile,
 			PermissionManageUsers,
+			PermissionLegalHold,
 		},
 		RoleAuditor: {
 			PermissionReadDocuments,
@@ -136,6 +138,11 @@ var EndpointPermission = map[string]Permission{
 	"/api/v1/ledger/export":   PermissionReadLedger,
 	"/audit/export":           PermissionReadAudit,
 	"/api/v1/ledger/verify/:id": PermissionVerifyDocuments,
+	"/api/v1/ledger/verify-chain": PermissionVerifyDocuments,
+	"/api/v1/admin/api-keys":      PermissionManageUsers,
+	"/api/v1/admin/keys/rotate":   PermissionManageUsers,
+	"/api/v1/admin/reconcile":     PermissionReconcile,
+	"/api/v1/documents/:id/proof-bundle": PermissionReadDocuments,
 	"/documents":              PermissionReadDocuments,
 	"/download/:id":           PermissionReadDocuments,
 }
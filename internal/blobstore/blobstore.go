@@ -0,0 +1,299 @@
+// Package blobstore abstrait le stockage du contenu des documents : système de fichiers
+// local ou stockage objet compatible S3 (object-lock / WORM). Chaque objet est désigné
+// par une référence indépendante de l'emplacement physique, conservée dans
+// documents.stored_path :
+//
+//	fs:2025/01/15/<uuid>-facture.pdf      (relative à STORAGE_DIR)
+//	s3://bucket/prefix/2025/01/15/<uuid>-facture.pdf
+//
+// Avec la disposition par contenu (STORAGE_LAYOUT=content), la clé est dérivée du SHA256
+// (ab/cd/abcdef…) : un contenu identique n'est stocké qu'une fois, quel que soit le
+// nombre de documents qui le référencent. Un contenu chiffré garde un objet par document
+// (ab/cd/abcdef…-<uuid>), chacun sous sa propre clé de données.
+//
+// Les chemins absolus des documents stockés avant l'introduction des références
+// restent lisibles par le stockage fichier.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound est retourné quand la référence ne désigne aucun objet
+var ErrNotFound = errors.New("blob not found")
+
+// Dispositions des clés de stockage (STORAGE_LAYOUT)
+const (
+	LayoutDate    = "date"    // YYYY/MM/DD/<uuid>-<filename>
+	LayoutContent = "content" // ab/cd/<sha256>, contenu partagé entre documents
+)
+
+// Modes de rétention object-lock (S3)
+const (
+	RetentionGovernance = "GOVERNANCE"
+	RetentionCompliance = "COMPLIANCE"
+)
+
+// PutOptions précise l'écriture d'un objet
+type PutOptions struct {
+	ContentType string
+	SHA256Hex   string    // condensat du contenu, conservé en métadonnée si le backend le permet
+	RetainUntil time.Time // rétention WORM jusqu'à cette date (zéro : politique par défaut du backend)
+	LegalHold   bool      // blocage légal : aucune suppression tant qu'il est posé
+}
+
+// ObjectInfo décrit un objet stocké
+type ObjectInfo struct {
+	Ref     string    `json:"ref"`
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Store est un backend de stockage du contenu des documents
+type Store interface {
+	// Name identifie le backend ("fs", "s3")
+	Name() string
+	// Owns indique si la référence désigne un objet de ce backend
+	Owns(ref string) bool
+	// Ref retourne la référence qu'aurait l'objet écrit sous la clé
+	Ref(key string) string
+	// Put écrit le contenu sous la clé (écriture atomique) et retourne sa référence
+	Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) (string, error)
+	// Open ouvre le contenu désigné par la référence (ErrNotFound si absent)
+	Open(ctx context.Context, ref string) (io.ReadCloser, error)
+	// Stat décrit l'objet désigné par la référence (ErrNotFound si absent)
+	Stat(ctx context.Context, ref string) (*ObjectInfo, error)
+	// Delete supprime l'objet ; sans effet s'il est absent
+	Delete(ctx context.Context, ref string) error
+	// Walk parcourt tous les objets du backend (hors écritures en cours)
+	Walk(ctx context.Context, fn func(ObjectInfo) error) error
+}
+
+// DateKey retourne la clé d'un document dans l'arborescence par date de stockage
+// (YYYY/MM/DD/<uuid>-<filename>)
+func DateKey(t time.Time, id uuid.UUID, filename string) string {
+	return path.Join(
+		fmt.Sprintf("%d", t.Year()),
+		fmt.Sprintf("%02d", t.Month()),
+		fmt.Sprintf("%02d", t.Day()),
+		fmt.Sprintf("%s-%s", id.String(), SafeFilename(filename)),
+	)
+}
+
+// ContentKey retourne la clé d'un contenu dans la disposition par contenu
+// (ab/cd/<sha256>)
+func ContentKey(sha256Hex string) string {
+	sha256Hex = strings.ToLower(sha256Hex)
+	if len(sha256Hex) < 4 {
+		return sha256Hex
+	}
+	return path.Join(sha256Hex[0:2], sha256Hex[2:4], sha256Hex)
+}
+
+// DocumentContentKey retourne la clé d'un contenu chiffré dans la disposition par contenu
+// (ab/cd/<sha256>-<uuid>) : chiffré par la clé de données du document, l'objet n'est pas
+// partagé et sa suppression avec le document le rend illisible
+func DocumentContentKey(sha256Hex string, id uuid.UUID) string {
+	return ContentKey(sha256Hex) + "-" + id.String()
+}
+
+// IsDocumentContentRef indique si la référence désigne le contenu chiffré du document id
+// dans la disposition par contenu
+func IsDocumentContentRef(ref, sha256Hex string, id uuid.UUID) bool {
+	if sha256Hex == "" {
+		return false
+	}
+	key := DocumentContentKey(sha256Hex, id)
+	if !strings.HasSuffix(ref, key) || len(ref) == len(key) {
+		return false
+	}
+	sep := ref[len(ref)-len(key)-1]
+	return sep == '/' || sep == ':'
+}
+
+// ContentKeySHA256 extrait le SHA256 d'une clé de la disposition par contenu
+func ContentKeySHA256(key string) (string, bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 3 || len(parts[2]) != 64 || !isLowerHex(parts[2]) {
+		return "", false
+	}
+	if parts[0] != parts[2][0:2] || parts[1] != parts[2][2:4] {
+		return "", false
+	}
+	return parts[2], true
+}
+
+// IsContentRef indique si la référence désigne le contenu sha256Hex dans la disposition
+// par contenu (objet potentiellement partagé entre documents)
+func IsContentRef(ref, sha256Hex string) bool {
+	if sha256Hex == "" {
+		return false
+	}
+	key := ContentKey(sha256Hex)
+	if !strings.HasSuffix(ref, key) || len(ref) == len(key) {
+		return false
+	}
+	sep := ref[len(ref)-len(key)-1]
+	return sep == '/' || sep == ':'
+}
+
+// SafeFilename réduit un nom de fichier fourni par l'appelant à un composant de chemin
+// sûr (séparateurs, caractères de contrôle et noms spéciaux remplacés)
+func SafeFilename(filename string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\' || r == ':' || r == '"':
+			return '_'
+		case r < 0x20 || r == 0x7f:
+			return -1
+		}
+		return r
+	}, filename)
+	cleaned = strings.TrimLeft(strings.TrimSpace(cleaned), ".")
+	if cleaned == "" {
+		return "document"
+	}
+	if len(cleaned) > 200 {
+		cleaned = cleaned[len(cleaned)-200:]
+	}
+	return cleaned
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadAll lit entièrement le contenu désigné par la référence
+func ReadAll(ctx context.Context, store Store, ref string) ([]byte, error) {
+	r, err := store.Open(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return data, nil
+}
+
+// Mux écrit dans un backend principal et lit chaque référence dans le backend qui la
+// possède (documents stockés avant une migration de backend)
+type Mux struct {
+	primary Store
+	stores  []Store
+}
+
+// NewMux crée un routeur de backends ; primary reçoit les nouvelles écritures
+func NewMux(primary Store, others ...Store) *Mux {
+	m := &Mux{primary: primary, stores: []Store{primary}}
+	for _, s := range others {
+		if s != nil && s != primary {
+			m.stores = append(m.stores, s)
+		}
+	}
+	return m
+}
+
+// Primary retourne le backend des nouvelles écritures
+func (m *Mux) Primary() Store {
+	return m.primary
+}
+
+// Backend retourne le backend de ce nom (nil si non configuré)
+func (m *Mux) Backend(name string) Store {
+	for _, s := range m.stores {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Name retourne le nom du backend principal
+func (m *Mux) Name() string {
+	return m.primary.Name()
+}
+
+// Owns indique si un des backends possède la référence
+func (m *Mux) Owns(ref string) bool {
+	return m.route(ref) != nil
+}
+
+// Ref retourne la référence de la clé dans le backend principal
+func (m *Mux) Ref(key string) string {
+	return m.primary.Ref(key)
+}
+
+// Put écrit dans le backend principal
+func (m *Mux) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) (string, error) {
+	return m.primary.Put(ctx, key, r, size, opts)
+}
+
+// Open lit dans le backend qui possède la référence
+func (m *Mux) Open(ctx context.Context, ref string) (io.ReadCloser, error) {
+	s, err := m.lookup(ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.Open(ctx, ref)
+}
+
+// Stat décrit l'objet dans le backend qui possède la référence
+func (m *Mux) Stat(ctx context.Context, ref string) (*ObjectInfo, error) {
+	s, err := m.lookup(ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stat(ctx, ref)
+}
+
+// Delete supprime l'objet dans le backend qui possède la référence
+func (m *Mux) Delete(ctx context.Context, ref string) error {
+	s, err := m.lookup(ref)
+	if err != nil {
+		return err
+	}
+	return s.Delete(ctx, ref)
+}
+
+// Walk parcourt les objets de tous les backends
+func (m *Mux) Walk(ctx context.Context, fn func(ObjectInfo) error) error {
+	for _, s := range m.stores {
+		if err := s.Walk(ctx, fn); err != nil {
+			return fmt.Errorf("failed to walk %s store: %w", s.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (m *Mux) route(ref string) Store {
+	for _, s := range m.stores {
+		if s.Owns(ref) {
+			return s
+		}
+	}
+	return nil
+}
+
+func (m *Mux) lookup(ref string) (Store, error) {
+	if s := m.route(ref); s != nil {
+		return s, nil
+	}
+	return nil, fmt.Errorf("no configured storage backend for reference %q", ref)
+}
@@ -0,0 +1,47 @@
+package blobstore
+
+import (
+	"fmt"
+
+	"github.com/doreviateam/dorevia-vault/internal/config"
+)
+
+// FromConfig crée le routeur de stockage : le backend STORAGE_BACKEND reçoit les
+// nouvelles écritures ; le stockage fichier (STORAGE_DIR) et, si S3_BUCKET est défini,
+// le stockage objet restent lisibles pour les documents déjà stockés
+func FromConfig(cfg config.Config) (*Mux, error) {
+	fs := NewFileStore(cfg.StorageDir)
+
+	var s3 *S3Store
+	if cfg.S3Bucket != "" {
+		var err error
+		s3, err = NewS3Store(S3Config{
+			Endpoint:       cfg.S3Endpoint,
+			Region:         cfg.S3Region,
+			Bucket:         cfg.S3Bucket,
+			Prefix:         cfg.S3Prefix,
+			Credentials:    Credentials{AccessKeyID: cfg.S3AccessKeyID, SecretAccessKey: cfg.S3SecretAccessKey},
+			PathStyle:      cfg.S3PathStyle,
+			ObjectLockMode: cfg.S3ObjectLockMode,
+			RetentionDays:  cfg.S3RetentionDays,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch cfg.StorageBackend {
+	case "", "fs":
+		if s3 != nil {
+			return NewMux(fs, s3), nil
+		}
+		return NewMux(fs), nil
+	case "s3":
+		if s3 == nil {
+			return nil, fmt.Errorf("STORAGE_BACKEND=s3 requires S3_BUCKET")
+		}
+		return NewMux(s3, fs), nil
+	default:
+		return nil, fmt.Errorf("invalid STORAGE_BACKEND %q (expected fs or s3)", cfg.StorageBackend)
+	}
+}
@@ -0,0 +1,172 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileScheme préfixe les références du stockage fichier
+const FileScheme = "fs:"
+
+// FileStore stocke les objets sous un répertoire racine (STORAGE_DIR)
+type FileStore struct {
+	root string
+}
+
+// NewFileStore crée un stockage fichier enraciné dans root
+func NewFileStore(root string) *FileStore {
+	return &FileStore{root: root}
+}
+
+// Name retourne "fs"
+func (s *FileStore) Name() string {
+	return "fs"
+}
+
+// Owns reconnaît les références fs: et les chemins absolus historiques
+func (s *FileStore) Owns(ref string) bool {
+	return strings.HasPrefix(ref, FileScheme) || filepath.IsAbs(ref)
+}
+
+// Ref retourne la référence fs: de la clé
+func (s *FileStore) Ref(key string) string {
+	return FileScheme + key
+}
+
+// Put écrit le fichier via un fichier temporaire renommé une fois complet
+func (s *FileStore) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) (string, error) {
+	path, err := s.keyPath(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, r)
+	if err == nil && size >= 0 && written != size {
+		err = fmt.Errorf("wrote %d bytes, expected %d", written, size)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	return s.Ref(key), nil
+}
+
+// Open ouvre le fichier désigné par la référence
+func (s *FileStore) Open(ctx context.Context, ref string) (io.ReadCloser, error) {
+	path, err := s.refPath(ref)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, nil
+}
+
+// Stat décrit le fichier désigné par la référence
+func (s *FileStore) Stat(ctx context.Context, ref string) (*ObjectInfo, error) {
+	path, err := s.refPath(ref)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return &ObjectInfo{Ref: ref, Key: strings.TrimPrefix(ref, FileScheme), Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// Delete supprime le fichier désigné par la référence
+func (s *FileStore) Delete(ctx context.Context, ref string) error {
+	path, err := s.refPath(ref)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// Walk parcourt les fichiers sous la racine (fichiers temporaires ignorés)
+func (s *FileStore) Walk(ctx context.Context, fn func(ObjectInfo) error) error {
+	if s.root == "" {
+		return nil
+	}
+	return filepath.WalkDir(s.root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && path == s.root {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() || filepath.Ext(path) == ".tmp" {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil // Fichier supprimé pendant le parcours
+		}
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		return fn(ObjectInfo{Ref: FileScheme + key, Key: key, Size: info.Size(), ModTime: info.ModTime()})
+	})
+}
+
+func (s *FileStore) refPath(ref string) (string, error) {
+	if filepath.IsAbs(ref) {
+		return ref, nil
+	}
+	if !strings.HasPrefix(ref, FileScheme) {
+		return "", fmt.Errorf("not a filesystem reference: %q", ref)
+	}
+	return s.keyPath(strings.TrimPrefix(ref, FileScheme))
+}
+
+func (s *FileStore) keyPath(key string) (string, error) {
+	if s.root == "" {
+		return "", fmt.Errorf("filesystem store has no root directory")
+	}
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,326 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// S3Scheme préfixe les références du stockage objet
+const S3Scheme = "s3://"
+
+// S3Config configure un stockage objet compatible S3 (AWS, MinIO, Scaleway, OVH...)
+type S3Config struct {
+	Endpoint    string // ex: https://s3.fr-par.scw.cloud, http://minio:9000
+	Region      string
+	Bucket      string
+	Prefix      string // préfixe des clés (ex: "vault/")
+	Credentials Credentials
+	PathStyle   bool // URL https://endpoint/bucket/key (MinIO) plutôt que https://bucket.endpoint/key
+	// Rétention WORM (bucket créé avec object-lock) : mode GOVERNANCE ou COMPLIANCE,
+	// appliquée à chaque objet pendant RetentionDays jours (sauf PutOptions.RetainUntil)
+	ObjectLockMode string
+	RetentionDays  int
+	HTTPClient     *http.Client
+}
+
+// S3Store stocke les objets dans un bucket compatible S3 (requêtes signées SigV4)
+type S3Store struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3Store crée un stockage objet S3
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	switch cfg.ObjectLockMode {
+	case "", RetentionGovernance, RetentionCompliance:
+	default:
+		return nil, fmt.Errorf("invalid S3 object lock mode %q (expected %s or %s)", cfg.ObjectLockMode, RetentionGovernance, RetentionCompliance)
+	}
+	if cfg.Prefix != "" && !strings.HasSuffix(cfg.Prefix, "/") {
+		cfg.Prefix += "/"
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Minute}
+	}
+	return &S3Store{cfg: cfg, endpoint: endpoint, client: client}, nil
+}
+
+// Name retourne "s3"
+func (s *S3Store) Name() string {
+	return "s3"
+}
+
+// Owns reconnaît les références s3:// du bucket configuré
+func (s *S3Store) Owns(ref string) bool {
+	return strings.HasPrefix(ref, s.refPrefix())
+}
+
+// Ref retourne la référence s3:// de la clé (préfixe compris)
+func (s *S3Store) Ref(key string) string {
+	return s.refPrefix() + s.cfg.Prefix + key
+}
+
+// Put envoie l'objet en une requête PUT signée. Le corps est haché (SHA256, MD5) avant
+// l'envoi : relu si le lecteur le permet, sinon copié dans un fichier temporaire
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+
+	body, ok := r.(io.ReadSeeker)
+	if !ok {
+		tmp, err := os.CreateTemp("", "dorevia-blob-*")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if _, err := io.Copy(tmp, r); err != nil {
+			return "", fmt.Errorf("failed to buffer object: %w", err)
+		}
+		body = tmp
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return "", fmt.Errorf("failed to rewind object: %w", err)
+		}
+	}
+	start, err := body.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", fmt.Errorf("failed to seek object: %w", err)
+	}
+	sha, md := sha256.New(), md5.New()
+	length, err := io.Copy(io.MultiWriter(sha, md), body)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash object: %w", err)
+	}
+	if size >= 0 && length != size {
+		return "", fmt.Errorf("object is %d bytes, expected %d", length, size)
+	}
+	if _, err := body.Seek(start, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind object: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(md.Sum(nil)))
+	if opts.ContentType != "" {
+		header.Set("Content-Type", opts.ContentType)
+	}
+	if opts.SHA256Hex != "" {
+		header.Set("X-Amz-Meta-Sha256", opts.SHA256Hex)
+	}
+	retainUntil := opts.RetainUntil
+	if retainUntil.IsZero() && s.cfg.RetentionDays > 0 {
+		retainUntil = time.Now().AddDate(0, 0, s.cfg.RetentionDays)
+	}
+	if s.cfg.ObjectLockMode != "" && !retainUntil.IsZero() {
+		header.Set("X-Amz-Object-Lock-Mode", s.cfg.ObjectLockMode)
+		header.Set("X-Amz-Object-Lock-Retain-Until-Date", retainUntil.UTC().Format(time.RFC3339))
+	}
+	if opts.LegalHold {
+		header.Set("X-Amz-Object-Lock-Legal-Hold", "ON")
+	}
+
+	objectKey := s.cfg.Prefix + key
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(objectKey, nil), io.NopCloser(io.LimitReader(body, length)), length, hex.EncodeToString(sha.Sum(nil)), header)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return s.refPrefix() + objectKey, nil
+}
+
+// Open lit l'objet désigné par la référence
+func (s *S3Store) Open(ctx context.Context, ref string) (io.ReadCloser, error) {
+	objectKey, err := s.objectKey(ref)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(objectKey, nil), nil, 0, EmptyPayloadSHA256, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Stat décrit l'objet désigné par la référence (HEAD)
+func (s *S3Store) Stat(ctx context.Context, ref string) (*ObjectInfo, error) {
+	objectKey, err := s.objectKey(ref)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodHead, s.objectURL(objectKey, nil), nil, 0, EmptyPayloadSHA256, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	info := &ObjectInfo{Ref: ref, Key: strings.TrimPrefix(objectKey, s.cfg.Prefix), Size: resp.ContentLength}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return info, nil
+}
+
+// Delete supprime l'objet. Sur un bucket versionné avec object-lock, seul un marqueur de
+// suppression est posé : la version retenue reste intacte jusqu'à expiration
+func (s *S3Store) Delete(ctx context.Context, ref string) error {
+	objectKey, err := s.objectKey(ref)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(objectKey, nil), nil, 0, EmptyPayloadSHA256, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listBucketResult est la réponse de ListObjectsV2
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// Walk parcourt les objets du préfixe configuré (ListObjectsV2, pages de 1000)
+func (s *S3Store) Walk(ctx context.Context, fn func(ObjectInfo) error) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if s.cfg.Prefix != "" {
+			query.Set("prefix", s.cfg.Prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, s.objectURL("", query), nil, 0, EmptyPayloadSHA256, nil)
+		if err != nil {
+			return err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode bucket listing: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			info := ObjectInfo{
+				Ref:     s.refPrefix() + object.Key,
+				Key:     strings.TrimPrefix(object.Key, s.cfg.Prefix),
+				Size:    object.Size,
+				ModTime: object.LastModified,
+			}
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (s *S3Store) refPrefix() string {
+	return S3Scheme + s.cfg.Bucket + "/"
+}
+
+func (s *S3Store) objectKey(ref string) (string, error) {
+	if !s.Owns(ref) {
+		return "", fmt.Errorf("not a reference of bucket %s: %q", s.cfg.Bucket, ref)
+	}
+	return strings.TrimPrefix(ref, s.refPrefix()), nil
+}
+
+// objectURL construit l'URL d'un objet (ou du bucket si objectKey est vide)
+func (s *S3Store) objectURL(objectKey string, query url.Values) *url.URL {
+	u := *s.endpoint
+	base := strings.TrimRight(u.Path, "/")
+	if s.cfg.PathStyle {
+		u.Path = base + "/" + s.cfg.Bucket + "/" + objectKey
+		u.RawPath = uriEncode(base, false) + "/" + uriEncode(s.cfg.Bucket, true) + "/" + uriEncode(objectKey, false)
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = base + "/" + objectKey
+		u.RawPath = uriEncode(base, false) + "/" + uriEncode(objectKey, false)
+	}
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+	return &u
+}
+
+// do envoie une requête signée ; une réponse 404 retourne ErrNotFound, toute autre
+// réponse hors 2xx une erreur portant le code S3
+func (s *S3Store) do(ctx context.Context, method string, u *url.URL, body io.ReadCloser, length int64, payloadHash string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	req.URL = u
+	if body != nil {
+		req.ContentLength = length
+		if length == 0 {
+			req.Body = http.NoBody
+		}
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	SignV4(req, payloadHash, s.cfg.Credentials, s.cfg.Region, "s3", time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s request failed: %w", method, err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if xml.Unmarshal(data, &s3Err) == nil && s3Err.Code != "" {
+		return nil, fmt.Errorf("S3 %s %s: HTTP %d %s: %s", method, u.Path, resp.StatusCode, s3Err.Code, s3Err.Message)
+	}
+	return nil, fmt.Errorf("S3 %s %s: HTTP %d", method, u.Path, resp.StatusCode)
+}
@@ -0,0 +1,169 @@
+package closing
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+)
+
+// Version du format d'archive de clôture
+const ArchiveVersion = "1"
+
+// SubjectPrefix préfixe la période scellée dans la signature du manifest
+const SubjectPrefix = "closing:"
+
+// Fichiers d'une archive de clôture
+const (
+	FileManifest          = "manifest.json"
+	FileManifestSignature = "manifest.jws" // signature du manifest par la clé courante
+	FileDocuments         = "documents.json"
+	FileLedger            = "ledger.json"
+	FileJWKS              = "jwks.json"
+	documentsDir          = "documents/"
+	payloadsDir           = "payloads/"
+	auditDir              = "audit/"
+)
+
+// Manifest décrit une archive de clôture ; chaque fichier y est référencé par son SHA256,
+// la signature du manifest couvre donc l'ensemble de l'archive
+type Manifest struct {
+	Version          string             `json:"version"`
+	Period           string             `json:"period"`
+	PeriodStart      time.Time          `json:"period_start"`
+	PeriodEnd        time.Time          `json:"period_end"`
+	DocumentCount    int64              `json:"document_count"`
+	LedgerEntryCount int64              `json:"ledger_entry_count"`
+	LedgerPartition  string             `json:"ledger_partition,omitempty"` // partition ledger_YYYY_MM lue, si le ledger est partitionné
+	PerTenantChain   bool               `json:"per_tenant_chain"`
+	ChainAnchors     map[string]*string `json:"chain_anchors,omitempty"` // hash précédant la période, par chaîne
+	LastLedgerHash   *string            `json:"last_ledger_hash,omitempty"`
+	MerkleRoot       string             `json:"merkle_root"`
+	AuditDays        []string           `json:"audit_days"` // jours dont le log d'audit signé est archivé
+	Files            map[string]string  `json:"files"`      // nom -> SHA256
+	SigningKID       string             `json:"signing_kid"`
+	GeneratedAt      time.Time          `json:"generated_at"`
+}
+
+// ArchivedDocument est l'entrée de documents.json
+type ArchivedDocument struct {
+	ID          string    `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	SHA256Hex   string    `json:"sha256_hex"`
+	CreatedAt   time.Time `json:"created_at"`
+	Tenant      *string   `json:"tenant,omitempty"`
+	Source      *string   `json:"source,omitempty"`
+	ContentFile string    `json:"content_file"`
+	EvidenceJWS *string   `json:"evidence_jws,omitempty"`
+	LedgerHash  *string   `json:"ledger_hash,omitempty"`
+}
+
+// LedgerEntry est l'entrée de ledger.json (evidence_jws compris, couvert par le hash v2)
+type LedgerEntry struct {
+	ledger.ChainEntry
+}
+
+// ArchiveWriter écrit une archive de clôture en flux et relève l'empreinte de chaque fichier
+type ArchiveWriter struct {
+	zw    *zip.Writer
+	files map[string]string
+	kids  []string // clés publiques à joindre en plus de la clé de scellement
+}
+
+// NewArchiveWriter crée un writer d'archive sur w
+func NewArchiveWriter(w io.Writer) *ArchiveWriter {
+	return &ArchiveWriter{
+		zw:    zip.NewWriter(w),
+		files: make(map[string]string),
+	}
+}
+
+// AddFile ajoute un fichier à l'archive
+func (a *ArchiveWriter) AddFile(name string, content []byte) error {
+	if _, exists := a.files[name]; exists {
+		return fmt.Errorf("duplicate file in archive: %s", name)
+	}
+	w, err := a.zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", name, err)
+	}
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	a.files[name] = sha256Hex(content)
+	return nil
+}
+
+// AddKID joint la clé publique kid au jwks.json de l'archive
+func (a *ArchiveWriter) AddKID(kid string) {
+	a.kids = append(a.kids, kid)
+}
+
+// AddJSON ajoute un fichier JSON à l'archive
+func (a *ArchiveWriter) AddJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	return a.AddFile(name, data)
+}
+
+// Seal complète le manifest (empreintes, clé), le signe avec la clé courante et
+// termine l'archive. Retourne la signature du manifest
+func (a *ArchiveWriter) Seal(jwsService *crypto.Service, manifest *Manifest) (string, error) {
+	if jwsService == nil {
+		return "", fmt.Errorf("JWS service is required to seal the archive")
+	}
+
+	manifest.Version = ArchiveVersion
+	manifest.SigningKID = jwsService.GetKID()
+	if manifest.GeneratedAt.IsZero() {
+		manifest.GeneratedAt = time.Now().UTC()
+	}
+
+	jwks, err := jwsService.JWKSForKIDs(append([]string{manifest.SigningKID}, a.kids...)...)
+	if err != nil {
+		return "", fmt.Errorf("failed to export JWKS: %w", err)
+	}
+	if err := a.AddFile(FileJWKS, jwks); err != nil {
+		return "", err
+	}
+
+	manifest.Files = make(map[string]string, len(a.files))
+	for name, hash := range a.files {
+		manifest.Files[name] = hash
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	signature, err := jwsService.SignEvidence(SubjectPrefix+manifest.Period, sha256Hex(manifestJSON), manifest.GeneratedAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign manifest: %w", err)
+	}
+	if err := a.AddFile(FileManifest, manifestJSON); err != nil {
+		return "", err
+	}
+	if err := a.AddFile(FileManifestSignature, []byte(signature)); err != nil {
+		return "", err
+	}
+
+	if err := a.zw.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize archive: %w", err)
+	}
+	return signature, nil
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
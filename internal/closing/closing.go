@@ -0,0 +1,382 @@
+// Package closing produit la clôture mensuelle scellée (NF525) : archive ZIP de
+// tous les documents de la période, tranche du ledger, signatures journalières
+// de l'audit et manifest signé, enregistrée dans la table closings.
+package closing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/merkle"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/rs/zerolog"
+)
+
+// ErrPeriodNotEnded est retourné pour une période en cours ou future
+var ErrPeriodNotEnded = errors.New("period has not ended yet")
+
+// Config contient la configuration des clôtures
+type Config struct {
+	ArchiveDir     string // répertoire des archives closing-YYYY-MM.zip
+	PerTenantChain bool   // chaîne du ledger par tenant (LEDGER_PER_TENANT_CHAIN)
+	Logger         zerolog.Logger
+}
+
+// Closer clôture les périodes échues
+type Closer struct {
+	db          *storage.DB
+	jwsService  *crypto.Service
+	signer      *audit.Signer // optionnel : signatures journalières de l'audit
+	auditLogger *audit.Logger // optionnel : événement period_closed
+	cfg         Config
+}
+
+// NewCloser crée un nouveau Closer
+func NewCloser(db *storage.DB, jwsService *crypto.Service, signer *audit.Signer, auditLogger *audit.Logger, cfg Config) *Closer {
+	return &Closer{
+		db:          db,
+		jwsService:  jwsService,
+		signer:      signer,
+		auditLogger: auditLogger,
+		cfg:         cfg,
+	}
+}
+
+// Close clôture une période échue : l'archive est écrite puis la clôture enregistrée.
+// Retourne storage.ErrClosingExists si la période est déjà clôturée
+func (c *Closer) Close(ctx context.Context, period Period, closedBy string) (*models.Closing, error) {
+	if c.jwsService == nil {
+		return nil, fmt.Errorf("JWS service is required to seal the closing")
+	}
+	if time.Now().Before(period.End()) {
+		return nil, fmt.Errorf("%w: %s", ErrPeriodNotEnded, period)
+	}
+	existing, err := c.db.GetClosing(ctx, period.String())
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, storage.ErrClosingExists
+	}
+
+	startTime := time.Now()
+	start, end := period.Start(), period.End()
+
+	// 1. La chaîne doit être intègre sur la période avant d'être figée
+	chain, err := ledger.VerifyChain(ctx, c.db.Pool, ledger.ChainOptions{From: &start, To: &end, PerTenantChain: c.cfg.PerTenantChain})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify ledger chain: %w", err)
+	}
+	if !chain.Valid {
+		return nil, fmt.Errorf("ledger chain is broken for period %s (broken=%d, gaps=%d, forks=%d)",
+			period, chain.BrokenLinkCount, chain.GapCount, chain.ForkCount)
+	}
+
+	// 2. Archive écrite dans un fichier temporaire, renommé une fois scellé
+	if err := os.MkdirAll(c.cfg.ArchiveDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create closings directory: %w", err)
+	}
+	archivePath := filepath.Join(c.cfg.ArchiveDir, fmt.Sprintf("closing-%s.zip", period))
+	tmpPath := archivePath + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	archiveHash := sha256.New()
+	closing, err := c.writeArchive(ctx, period, NewArchiveWriter(io.MultiWriter(file, archiveHash)))
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write archive: %w", closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		return nil, fmt.Errorf("failed to finalize archive: %w", err)
+	}
+
+	closing.ArchivePath = archivePath
+	closing.ArchiveSHA256 = hex.EncodeToString(archiveHash.Sum(nil))
+	closing.ClosedAt = time.Now().UTC()
+	closing.ClosedBy = closedBy
+
+	// 3. Enregistrement : à partir d'ici la période refuse les documents antidatés
+	if err := c.db.InsertClosing(ctx, closing); err != nil {
+		if errors.Is(err, storage.ErrClosingExists) {
+			// Clôture concurrente : l'archive enregistrée est celle de l'autre processus
+			os.Remove(archivePath)
+		}
+		return nil, err
+	}
+
+	// 4. Clôture inscrite dans le ledger (chaîne sans tenant) : la période figée est
+	// référencée par la chaîne elle-même
+	if _, err := c.db.AppendLedgerEvent(ctx, "", ledger.Event{
+		Type: ledger.EntryTypePeriodClosed,
+		Body: ledger.PeriodClosedBody{
+			Period:           closing.Period,
+			DocumentCount:    closing.DocumentCount,
+			LedgerEntryCount: closing.LedgerEntryCount,
+			MerkleRoot:       closing.MerkleRoot,
+			LastLedgerHash:   closing.LastLedgerHash,
+			ArchiveSHA256:    closing.ArchiveSHA256,
+			ClosedBy:         closedBy,
+		},
+	}); err != nil {
+		c.cfg.Logger.Error().Err(err).Str("period", closing.Period).Msg("Failed to record period closing in ledger")
+	}
+
+	c.cfg.Logger.Info().
+		Str("period", closing.Period).
+		Int64("documents", closing.DocumentCount).
+		Int64("ledger_entries", closing.LedgerEntryCount).
+		Str("merkle_root", closing.MerkleRoot).
+		Str("archive", archivePath).
+		Msg("Period closed")
+
+	if c.auditLogger != nil {
+		c.auditLogger.Log(audit.Event{
+			EventType:  audit.EventTypePeriodClosed,
+			Status:     audit.EventStatusSuccess,
+			DurationMS: time.Since(startTime).Milliseconds(),
+			Metadata: map[string]interface{}{
+				"period":           closing.Period,
+				"document_count":   closing.DocumentCount,
+				"ledger_entries":   closing.LedgerEntryCount,
+				"merkle_root":      closing.MerkleRoot,
+				"last_ledger_hash": closing.LastLedgerHash,
+				"archive_sha256":   closing.ArchiveSHA256,
+				"closed_by":        closedBy,
+			},
+		})
+	}
+
+	return closing, nil
+}
+
+// writeArchive écrit le contenu de la période dans l'archive et la scelle
+func (c *Closer) writeArchive(ctx context.Context, period Period, archive *ArchiveWriter) (*models.Closing, error) {
+	manifest := &Manifest{
+		Period:         period.String(),
+		PeriodStart:    period.Start(),
+		PeriodEnd:      period.End(),
+		PerTenantChain: c.cfg.PerTenantChain,
+		AuditDays:      []string{},
+	}
+
+	// 1. Documents et payloads POS
+	documents, root, err := c.archiveDocuments(ctx, period, archive)
+	if err != nil {
+		return nil, err
+	}
+	if err := archive.AddJSON(FileDocuments, documents); err != nil {
+		return nil, err
+	}
+	manifest.DocumentCount = int64(len(documents))
+	manifest.MerkleRoot = hex.EncodeToString(root)
+
+	// 2. Tranche du ledger
+	entries, partition, err := c.loadLedgerSlice(ctx, period)
+	if err != nil {
+		return nil, err
+	}
+	if err := archive.AddJSON(FileLedger, entries); err != nil {
+		return nil, err
+	}
+	manifest.LedgerEntryCount = int64(len(entries))
+	manifest.LedgerPartition = partition
+	manifest.ChainAnchors = make(map[string]*string)
+	for _, e := range entries {
+		key := ""
+		if c.cfg.PerTenantChain && e.Tenant != nil {
+			key = *e.Tenant
+		}
+		if _, ok := manifest.ChainAnchors[key]; !ok {
+			manifest.ChainAnchors[key] = e.PreviousHash
+		}
+	}
+	if len(entries) > 0 {
+		last := entries[len(entries)-1].Hash
+		manifest.LastLedgerHash = &last
+	}
+
+	// 3. Signatures journalières de l'audit
+	if c.signer == nil {
+		c.cfg.Logger.Warn().Str("period", period.String()).Msg("Audit signer not configured, closing without daily audit hashes")
+	} else {
+		for _, day := range period.Days() {
+			dailyHash, err := c.signer.DailyHash(day)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get audit signature for %s: %w", day, err)
+			}
+			if err := archive.AddJSON(auditDir+fmt.Sprintf("audit-%s.log.jws", day), dailyHash); err != nil {
+				return nil, err
+			}
+			// Clé ayant signé le jour (éventuellement retirée depuis) jointe si encore vérifiable
+			if evidence, err := c.jwsService.VerifyEvidence(dailyHash.JWS); err == nil && evidence.KID != "" {
+				archive.AddKID(evidence.KID)
+			}
+			manifest.AuditDays = append(manifest.AuditDays, day)
+		}
+	}
+
+	// 4. Manifest signé
+	signature, err := archive.Seal(c.jwsService, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Closing{
+		Period:           manifest.Period,
+		PeriodStart:      manifest.PeriodStart,
+		PeriodEnd:        manifest.PeriodEnd,
+		DocumentCount:    manifest.DocumentCount,
+		LedgerEntryCount: manifest.LedgerEntryCount,
+		MerkleRoot:       manifest.MerkleRoot,
+		LastLedgerHash:   manifest.LastLedgerHash,
+		ManifestJWS:      signature,
+	}, nil
+}
+
+// archiveDocuments ajoute à l'archive chaque document créé dans la période
+// (fichier original ou payload POS) et retourne l'index et la racine de Merkle
+func (c *Closer) archiveDocuments(ctx context.Context, period Period, archive *ArchiveWriter) ([]ArchivedDocument, []byte, error) {
+	rows, err := c.db.Pool.Query(ctx, `
+		SELECT id, filename, COALESCE(content_type, ''), COALESCE(size_bytes, 0), sha256_hex, stored_path,
+		       created_at, tenant, source, evidence_jws, ledger_hash, payload_json
+		FROM documents
+		WHERE created_at >= $1 AND created_at < $2
+		ORDER BY created_at ASC, id ASC
+	`, period.Start(), period.End())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := []ArchivedDocument{}
+	var tree merkle.Builder
+	for rows.Next() {
+		var doc models.Document
+		if err := rows.Scan(&doc.ID, &doc.Filename, &doc.ContentType, &doc.SizeBytes, &doc.SHA256Hex, &doc.StoredPath,
+			&doc.CreatedAt, &doc.Tenant, &doc.Source, &doc.EvidenceJWS, &doc.LedgerHash, &doc.PayloadJSON); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+
+		archived := ArchivedDocument{
+			ID:          doc.ID.String(),
+			Filename:    doc.Filename,
+			ContentType: doc.ContentType,
+			SizeBytes:   doc.SizeBytes,
+			SHA256Hex:   doc.SHA256Hex,
+			CreatedAt:   doc.CreatedAt.UTC(),
+			Tenant:      doc.Tenant,
+			Source:      doc.Source,
+			EvidenceJWS: doc.EvidenceJWS,
+			LedgerHash:  doc.LedgerHash,
+		}
+
+		// Le contenu est contrôlé avant d'être figé : une clôture ne scelle pas un document altéré
+		var content []byte
+		if doc.StoredPath != "" {
+			content, err = c.db.ReadDocumentContent(ctx, &doc)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read document %s: %w", doc.ID, err)
+			}
+			if actual := sha256Hex(content); actual != doc.SHA256Hex {
+				return nil, nil, fmt.Errorf("document %s does not match its SHA256 (expected %s, got %s)", doc.ID, doc.SHA256Hex, actual)
+			}
+			archived.ContentFile = documentsDir + doc.ID.String() + "-" + safeFilename(doc.Filename)
+		} else {
+			if check := verify.VerifyPosPayload(&doc); check.Status != "ok" {
+				return nil, nil, fmt.Errorf("document %s payload check failed: %s", doc.ID, check.Message)
+			}
+			content = doc.PayloadJSON
+			archived.ContentFile = payloadsDir + doc.ID.String() + ".json"
+		}
+		if err := archive.AddFile(archived.ContentFile, content); err != nil {
+			return nil, nil, err
+		}
+
+		leaf, err := hex.DecodeString(doc.SHA256Hex)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid sha256_hex for document %s: %w", doc.ID, err)
+		}
+		tree.Add(leaf)
+		documents = append(documents, archived)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+
+	return documents, tree.Root(), nil
+}
+
+// loadLedgerSlice lit les entrées du ledger de la période, depuis la partition
+// ledger_YYYY_MM si le ledger est partitionné
+func (c *Closer) loadLedgerSlice(ctx context.Context, period Period) ([]LedgerEntry, string, error) {
+	table := "ledger"
+	partition := ledger.PartitionName(period.Year, int(period.Month))
+	var partitioned bool
+	if err := c.db.Pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, partition).Scan(&partitioned); err != nil {
+		return nil, "", fmt.Errorf("failed to check ledger partition: %w", err)
+	}
+	if partitioned {
+		table = partition
+	} else {
+		partition = ""
+	}
+
+	rows, err := c.db.Pool.Query(ctx, `
+		SELECT l.id, COALESCE(l.document_id::text, ''), l.hash, l.previous_hash, COALESCE(d.sha256_hex, ''),
+		       l.timestamp, l.tenant, l.evidence_jws, l.seq, l.hash_version, l.entry_type, COALESCE(l.body, '')
+		FROM `+table+` l
+		LEFT JOIN documents d ON d.id = l.document_id
+		WHERE l.timestamp >= $1 AND l.timestamp < $2
+		ORDER BY l.seq ASC NULLS FIRST, l.timestamp ASC, l.id ASC
+	`, period.Start(), period.End())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query ledger: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []LedgerEntry{}
+	for rows.Next() {
+		var e LedgerEntry
+		if err := rows.Scan(&e.ID, &e.DocumentID, &e.Hash, &e.PreviousHash, &e.DocumentSHA,
+			&e.Timestamp, &e.Tenant, &e.EvidenceJWS, &e.Seq, &e.HashVersion, &e.EntryType, &e.Body); err != nil {
+			return nil, "", fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating ledger: %w", err)
+	}
+	return entries, partition, nil
+}
+
+// safeFilename neutralise les chemins dans le nom de fichier du document
+func safeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "content"
+	}
+	return name
+}
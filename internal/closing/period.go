@@ -0,0 +1,56 @@
+package closing
+
+import (
+	"fmt"
+	"time"
+)
+
+// Period est un mois calendaire UTC, unité de clôture
+type Period struct {
+	Year  int
+	Month time.Month
+}
+
+// ParsePeriod lit une période au format YYYY-MM
+func ParsePeriod(s string) (Period, error) {
+	t, err := time.Parse("2006-01", s)
+	if err != nil {
+		return Period{}, fmt.Errorf("invalid period %q (expected YYYY-MM)", s)
+	}
+	return Period{Year: t.Year(), Month: t.Month()}, nil
+}
+
+// PeriodOf retourne la période contenant t
+func PeriodOf(t time.Time) Period {
+	t = t.UTC()
+	return Period{Year: t.Year(), Month: t.Month()}
+}
+
+// String retourne la période au format YYYY-MM
+func (p Period) String() string {
+	return fmt.Sprintf("%04d-%02d", p.Year, int(p.Month))
+}
+
+// Start retourne le premier instant de la période
+func (p Period) Start() time.Time {
+	return time.Date(p.Year, p.Month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// End retourne le premier instant de la période suivante (borne exclue)
+func (p Period) End() time.Time {
+	return p.Start().AddDate(0, 1, 0)
+}
+
+// Previous retourne la période précédente
+func (p Period) Previous() Period {
+	return PeriodOf(p.Start().AddDate(0, -1, 0))
+}
+
+// Days retourne les jours de la période au format YYYY-MM-DD
+func (p Period) Days() []string {
+	var days []string
+	for d := p.Start(); d.Before(p.End()); d = d.AddDate(0, 0, 1) {
+		days = append(days, d.Format("2006-01-02"))
+	}
+	return days
+}
@@ -0,0 +1,56 @@
+package closing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+)
+
+// SchedulerClosedBy identifie les clôtures automatiques dans closed_by
+const SchedulerClosedBy = "scheduler"
+
+// StartScheduler clôture automatiquement le mois précédent une fois le délai de grâce
+// écoulé (documents tardifs). La vérification est répétée à chaque intervalle
+func StartScheduler(ctx context.Context, closer *Closer, graceDays int, interval time.Duration) {
+	if interval == 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			closer.closeDuePeriod(ctx, graceDays)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// closeDuePeriod clôture le mois précédent s'il est échu depuis graceDays et encore ouvert
+func (c *Closer) closeDuePeriod(ctx context.Context, graceDays int) {
+	now := time.Now().UTC()
+	period := PeriodOf(now).Previous()
+	if now.Before(period.End().AddDate(0, 0, graceDays)) {
+		return
+	}
+
+	existing, err := c.db.GetClosing(ctx, period.String())
+	if err != nil {
+		c.cfg.Logger.Error().Err(err).Str("period", period.String()).Msg("Failed to check closing")
+		return
+	}
+	if existing != nil {
+		return
+	}
+
+	if _, err := c.Close(ctx, period, SchedulerClosedBy); err != nil && !errors.Is(err, storage.ErrClosingExists) {
+		c.cfg.Logger.Error().Err(err).Str("period", period.String()).Msg("Scheduled closing failed")
+	}
+}
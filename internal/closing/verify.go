@@ -0,0 +1,274 @@
+package closing
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/merkle"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+)
+
+// Origine des clés utilisées pour la vérification
+const (
+	KeysFromArchive = "archive" // jwks.json de l'archive (non épinglé)
+	KeysFromTrusted = "trusted" // JWKS fourni par l'auditeur
+)
+
+// maxIndexFileSize borne la taille des fichiers d'index lus en mémoire
+const maxIndexFileSize = 512 << 20
+
+// Report est le résultat de la vérification hors ligne d'une archive de clôture
+type Report struct {
+	Valid      bool           `json:"valid"`
+	Period     string         `json:"period,omitempty"`
+	KeysSource string         `json:"keys_source"`
+	Checks     []verify.Check `json:"checks"`
+	Errors     []string       `json:"errors,omitempty"`
+	Manifest   *Manifest      `json:"manifest,omitempty"`
+	CheckedAt  time.Time      `json:"checked_at"`
+}
+
+func (r *Report) add(component, status, message string) {
+	check := verify.Check{Component: component, Status: status, Message: message}
+	r.Checks = append(r.Checks, check)
+	if check.Failed() {
+		r.Valid = false
+		r.Errors = append(r.Errors, message)
+	}
+}
+
+// VerifyArchive vérifie une archive de clôture sans accès au coffre : signature du
+// manifest, empreintes des fichiers, racine de Merkle et chaînage de la tranche du ledger.
+// trusted (optionnel) remplace le jwks.json de l'archive par des clés obtenues hors bande
+func VerifyArchive(archivePath string, trusted crypto.KeySet) (*Report, error) {
+	report := &Report{Valid: true, Checks: []verify.Check{}, CheckedAt: time.Now().UTC()}
+
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	manifestJSON, err := readArchiveFile(files, FileManifest)
+	if err != nil {
+		report.add("archive", "missing", err.Error())
+		return report, nil
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		report.add("archive", "error", fmt.Sprintf("Invalid manifest.json: %v", err))
+		return report, nil
+	}
+	report.Manifest = &manifest
+	report.Period = manifest.Period
+
+	// 1. Clés de vérification
+	keys := trusted
+	report.KeysSource = KeysFromTrusted
+	if keys == nil {
+		report.KeysSource = KeysFromArchive
+		jwks, err := readArchiveFile(files, FileJWKS)
+		if err == nil {
+			keys, err = crypto.ParseJWKS(jwks)
+		}
+		if err != nil {
+			report.add("keys", "error", fmt.Sprintf("Invalid jwks.json: %v", err))
+			return report, nil
+		}
+	}
+
+	// 2. Signature du manifest
+	signature, err := readArchiveFile(files, FileManifestSignature)
+	if err != nil {
+		report.add("signature", "missing", err.Error())
+		return report, nil
+	}
+	sealed, err := keys.VerifyEvidence(string(signature))
+	switch {
+	case err != nil:
+		report.add("signature", "error", fmt.Sprintf("Invalid manifest signature: %v", err))
+		return report, nil
+	case sealed.Sha256 != sha256Hex(manifestJSON) || sealed.DocumentID != SubjectPrefix+manifest.Period:
+		report.add("signature", "error", "Manifest signature does not match manifest.json")
+		return report, nil
+	default:
+		report.add("signature", "ok", fmt.Sprintf("Manifest signed with kid=%s at %s", sealed.KID, sealed.Timestamp.UTC().Format(time.RFC3339)))
+	}
+
+	// 3. Empreintes des fichiers (lecture en flux)
+	filesOK := true
+	for name, expected := range manifest.Files {
+		f, ok := files[name]
+		if !ok {
+			report.add("files", "missing", fmt.Sprintf("%s listed in manifest but missing from archive", name))
+			filesOK = false
+			continue
+		}
+		actual, err := hashArchiveFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if actual != expected {
+			report.add("files", "error", fmt.Sprintf("%s does not match its manifest SHA256", name))
+			filesOK = false
+		}
+	}
+	if !filesOK {
+		return report, nil
+	}
+	report.add("files", "ok", fmt.Sprintf("%d files match the manifest", len(manifest.Files)))
+
+	// 4. Racine de Merkle des documents
+	documentsJSON, err := readArchiveFile(files, FileDocuments)
+	if err != nil {
+		report.add("documents", "missing", err.Error())
+		return report, nil
+	}
+	var documents []ArchivedDocument
+	if err := json.Unmarshal(documentsJSON, &documents); err != nil {
+		report.add("documents", "error", fmt.Sprintf("Invalid documents.json: %v", err))
+		return report, nil
+	}
+	documentSHA := make(map[string]string, len(documents))
+	var tree merkle.Builder
+	documentsOK := true
+	for _, doc := range documents {
+		documentSHA[doc.ID] = doc.SHA256Hex
+		leaf, err := hex.DecodeString(doc.SHA256Hex)
+		if err != nil {
+			report.add("documents", "error", fmt.Sprintf("Invalid sha256_hex for document %s", doc.ID))
+			documentsOK = false
+			continue
+		}
+		tree.Add(leaf)
+		// Les fichiers sont couverts par le manifest ; seul le contenu des fichiers originaux est rehashé
+		if _, ok := manifest.Files[doc.ContentFile]; !ok {
+			report.add("documents", "missing", fmt.Sprintf("Content of document %s not in archive", doc.ID))
+			documentsOK = false
+		} else if strings.HasPrefix(doc.ContentFile, documentsDir) && manifest.Files[doc.ContentFile] != doc.SHA256Hex {
+			report.add("documents", "error", fmt.Sprintf("Document %s does not match its SHA256", doc.ID))
+			documentsOK = false
+		}
+	}
+	switch root := hex.EncodeToString(tree.Root()); {
+	case int64(len(documents)) != manifest.DocumentCount:
+		report.add("documents", "error", fmt.Sprintf("Document count mismatch: manifest %d, archive %d", manifest.DocumentCount, len(documents)))
+	case root != manifest.MerkleRoot:
+		report.add("documents", "error", fmt.Sprintf("Merkle root mismatch: manifest %s, recomputed %s", manifest.MerkleRoot, root))
+	case documentsOK:
+		report.add("documents", "ok", fmt.Sprintf("%d documents, Merkle root %s", len(documents), root))
+	}
+
+	// 5. Tranche du ledger
+	ledgerJSON, err := readArchiveFile(files, FileLedger)
+	if err != nil {
+		report.add("ledger", "missing", err.Error())
+		return report, nil
+	}
+	var entries []LedgerEntry
+	if err := json.Unmarshal(ledgerJSON, &entries); err != nil {
+		report.add("ledger", "error", fmt.Sprintf("Invalid ledger.json: %v", err))
+		return report, nil
+	}
+	chain := ledger.NewTenantChainVerifier(manifest.ChainAnchors, manifest.PerTenantChain)
+	for _, e := range entries {
+		if sha, ok := documentSHA[e.DocumentID]; ok && sha != e.DocumentSHA {
+			report.add("ledger", "error", fmt.Sprintf("Ledger entry #%d does not match document %s", e.ID, e.DocumentID))
+		}
+		chain.Add(e.ChainEntry)
+	}
+	chainReport := chain.Report()
+	var lastHash *string
+	if len(entries) > 0 {
+		lastHash = &entries[len(entries)-1].Hash
+	}
+	switch {
+	case int64(len(entries)) != manifest.LedgerEntryCount:
+		report.add("ledger", "error", fmt.Sprintf("Ledger entry count mismatch: manifest %d, archive %d", manifest.LedgerEntryCount, len(entries)))
+	case !sameHash(lastHash, manifest.LastLedgerHash):
+		report.add("ledger", "error", "Last ledger hash does not match manifest")
+	case !chainReport.Valid:
+		report.add("ledger", "error", fmt.Sprintf("Ledger links invalid (broken=%d, gaps=%d, forks=%d)",
+			chainReport.BrokenLinkCount, chainReport.GapCount, chainReport.ForkCount))
+	default:
+		report.add("ledger", "ok", fmt.Sprintf("%d ledger entries recomputed", len(entries)))
+	}
+
+	// 6. Signatures journalières de l'audit (contrôle de la signature, le log n'est pas archivé)
+	for _, day := range manifest.AuditDays {
+		data, err := readArchiveFile(files, auditDir+fmt.Sprintf("audit-%s.log.jws", day))
+		if err != nil {
+			report.add("audit", "missing", err.Error())
+			continue
+		}
+		var dailyHash struct {
+			Hash string `json:"hash"`
+			JWS  string `json:"jws"`
+		}
+		if err := json.Unmarshal(data, &dailyHash); err != nil {
+			report.add("audit", "error", fmt.Sprintf("Invalid audit signature for %s: %v", day, err))
+			continue
+		}
+		if evidence, err := keys.VerifyEvidence(dailyHash.JWS); err != nil || evidence.Sha256 != dailyHash.Hash {
+			report.add("audit", "warn", fmt.Sprintf("Audit signature for %s not verifiable with the archive keys", day))
+		}
+	}
+	if len(manifest.AuditDays) > 0 {
+		report.add("audit", "ok", fmt.Sprintf("%d daily audit signatures archived", len(manifest.AuditDays)))
+	}
+
+	return report, nil
+}
+
+func readArchiveFile(files map[string]*zip.File, name string) ([]byte, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in archive", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxIndexFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > maxIndexFileSize {
+		return nil, fmt.Errorf("file %s exceeds maximum size", name)
+	}
+	return data, nil
+}
+
+func hashArchiveFile(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func sameHash(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -12,6 +12,29 @@ type Config struct {
 	LogLevel    string `env:"LOG_LEVEL" envDefault:"info"`
 	DatabaseURL string `env:"DATABASE_URL" envDefault:""`
 	StorageDir  string `env:"STORAGE_DIR" envDefault:"/opt/dorevia-vault/storage"`
+	// Stockage du contenu des documents : "fs" (STORAGE_DIR) ou "s3" (stockage objet compatible S3)
+	StorageBackend string `env:"STORAGE_BACKEND" envDefault:"fs"`
+	// Disposition des clés : "date" (YYYY/MM/DD/<uuid>-<filename>) ou "content" (ab/cd/<sha256>, dédupliqué)
+	StorageLayout string `env:"STORAGE_LAYOUT" envDefault:"date"`
+	S3Endpoint        string `env:"S3_ENDPOINT" envDefault:""`
+	S3Region          string `env:"S3_REGION" envDefault:"us-east-1"`
+	S3Bucket          string `env:"S3_BUCKET" envDefault:""`
+	S3Prefix          string `env:"S3_PREFIX" envDefault:""`
+	S3AccessKeyID     string `env:"S3_ACCESS_KEY_ID" envDefault:""`
+	S3SecretAccessKey string `env:"S3_SECRET_ACCESS_KEY" envDefault:""`
+	S3PathStyle       bool   `env:"S3_PATH_STYLE" envDefault:"true"`
+	// Rétention WORM des objets (bucket avec object-lock) : GOVERNANCE ou COMPLIANCE
+	S3ObjectLockMode  string `env:"S3_OBJECT_LOCK_MODE" envDefault:""`
+	S3RetentionDays   int    `env:"S3_RETENTION_DAYS" envDefault:"0"`
+	// Chiffrement au repos du contenu : clé de données AES-256-GCM par document, enveloppée par une KEK
+	DocumentEncryptionEnabled bool   `env:"DOCUMENT_ENCRYPTION_ENABLED" envDefault:"false"`
+	DocumentEncryptionKeysDir string `env:"DOCUMENT_ENCRYPTION_KEYS_DIR" envDefault:""` // KEK fichier : <dir>/<kek>/{private,public}.pem
+	DocumentEncryptionKEKID   string `env:"DOCUMENT_ENCRYPTION_KEK_ID" envDefault:""`   // KEK des nouvelles clés (défaut : <dir>/current)
+	// HashiCorp Vault : KEK lues sous VAULT_KEY_PATH/<kek>/{private,public}
+	VaultEnabled bool   `env:"VAULT_ENABLED" envDefault:"false"`
+	VaultAddr    string `env:"VAULT_ADDR" envDefault:""`
+	VaultToken   string `env:"VAULT_TOKEN" envDefault:""`
+	VaultKeyPath string `env:"VAULT_KEY_PATH" envDefault:"secret/data/dorevia/keys"`
 	
 	// Audit Configuration (Sprint 4 Phase 4.2)
 	AuditDir string `env:"AUDIT_DIR" envDefault:"/opt/dorevia-vault/audit"`
@@ -30,19 +53,75 @@ type Config struct {
 	JWSPrivateKeyBase64 string `env:"JWS_PRIVATE_KEY_BASE64" envDefault:""`
 	JWSPublicKeyBase64  string `env:"JWS_PUBLIC_KEY_BASE64" envDefault:""`
 	JWSKID              string `env:"JWS_KID" envDefault:"key-2025-Q1"`
+	// Rotation multi-KID : répertoire <dir>/<kid>/{private,public}.pem (remplace les chemins ci-dessus)
+	JWSKeysDir            string `env:"JWS_KEYS_DIR" envDefault:""`
+	JWSRotationPeriodDays int    `env:"JWS_ROTATION_PERIOD_DAYS" envDefault:"90"`
 	
 	// Ledger Configuration (Sprint 2)
 	LedgerEnabled bool `env:"LEDGER_ENABLED" envDefault:"true"`
+	// Multi-tenant : une chaîne de hash indépendante par tenant. Activé sur un ledger
+	// existant, les chaînes par tenant démarrent sur la tête de la chaîne globale
+	LedgerPerTenantChain bool `env:"LEDGER_PER_TENANT_CHAIN" envDefault:"false"`
+	// Format des nouvelles entrées : 1 (hash du document) ou 2 (hash de tous les champs)
+	LedgerHashVersion int `env:"LEDGER_HASH_VERSION" envDefault:"2"`
+	// Racines de Merkle journalières signées (preuves d'inclusion)
+	LedgerMerkleEnabled bool `env:"LEDGER_MERKLE_ENABLED" envDefault:"true"`
+	// Group commit : documents concurrents regroupés dans une seule transaction ledger
+	LedgerGroupCommitEnabled  bool `env:"LEDGER_GROUP_COMMIT_ENABLED" envDefault:"false"`
+	LedgerGroupCommitWindowMs int  `env:"LEDGER_GROUP_COMMIT_WINDOW_MS" envDefault:"5"`
+	LedgerGroupCommitMaxBatch int  `env:"LEDGER_GROUP_COMMIT_MAX_BATCH" envDefault:"200"`
+	// Cycle de vie des partitions mensuelles (sans effet tant que le ledger n'est pas partitionné, voir ledgerctl)
+	LedgerPartitionEnabled         bool   `env:"LEDGER_PARTITION_ENABLED" envDefault:"true"`
+	LedgerPartitionRetentionMonths int    `env:"LEDGER_PARTITION_RETENTION_MONTHS" envDefault:"0"`
+	LedgerPartitionArchiveDir      string `env:"LEDGER_PARTITION_ARCHIVE_DIR" envDefault:"/opt/dorevia-vault/ledger-archives"`
+	// Tête signée du ledger publiée périodiquement, contre-signée par des témoins externes (cmd/witness)
+	LedgerTreeHeadEnabled         bool   `env:"LEDGER_TREE_HEAD_ENABLED" envDefault:"true"`
+	LedgerTreeHeadIntervalMinutes int    `env:"LEDGER_TREE_HEAD_INTERVAL_MINUTES" envDefault:"5"`
+	LedgerWitnessJWKSPath         string `env:"LEDGER_WITNESS_JWKS_PATH" envDefault:""`
+	// Clôtures mensuelles (NF525) : archive scellée, documents antidatés refusés
+	ClosingEnabled   bool   `env:"CLOSING_ENABLED" envDefault:"false"`
+	ClosingDir       string `env:"CLOSING_DIR" envDefault:"/opt/dorevia-vault/closings"`
+	ClosingGraceDays int    `env:"CLOSING_GRACE_DAYS" envDefault:"5"`
+	// Rétention par source/odoo_model (format: source[/odoo_model]=durée, séparés par des virgules ;
+	// durée en années, mois ou jours : 10y, 6m, 30d ; * : toutes sources). Contenu purgé une fois échu
+	RetentionEnabled            bool   `env:"RETENTION_ENABLED" envDefault:"false"`
+	RetentionPolicies           string `env:"RETENTION_POLICIES" envDefault:""`
+	RetentionPurgeIntervalHours int    `env:"RETENTION_PURGE_INTERVAL_HOURS" envDefault:"24"`
+	RetentionPurgeBatch         int    `env:"RETENTION_PURGE_BATCH" envDefault:"500"`
+	// Vérification en arrière-plan du contenu stocké (SHA256 relu, budget d'I/O en Mo/s ; 0 : illimité)
+	ScrubEnabled       bool `env:"SCRUB_ENABLED" envDefault:"true"`
+	ScrubMaxMBPerSec   int  `env:"SCRUB_MAX_MB_PER_SEC" envDefault:"10"`
+	ScrubIntervalHours int  `env:"SCRUB_INTERVAL_HOURS" envDefault:"168"`
+	ScrubBatch         int  `env:"SCRUB_BATCH" envDefault:"100"`
+	// Horodatage RFC 3161 de la tête du ledger par une autorité d'horodatage (TSA)
+	TSAURL             string `env:"TSA_URL" envDefault:""`
+	TSAIntervalMinutes int    `env:"TSA_INTERVAL_MINUTES" envDefault:"60"`
+	TSATimeoutSeconds  int    `env:"TSA_TIMEOUT_SECONDS" envDefault:"30"`
+	TSACACertPath      string `env:"TSA_CA_CERT_PATH" envDefault:""`
 	
 	// Auth Configuration (Sprint 5 Phase 5.2)
 	AuthEnabled    bool   `env:"AUTH_ENABLED" envDefault:"false"`
 	JWTEnabled     bool   `env:"AUTH_JWT_ENABLED" envDefault:"true"`
 	APIKeyEnabled  bool   `env:"AUTH_APIKEY_ENABLED" envDefault:"true"`
 	JWTPublicKeyPath string `env:"AUTH_JWT_PUBLIC_KEY_PATH" envDefault:""`
+	// JWT émis par un SSO : JWKS distant (clés tournantes par kid) et claims attendues
+	JWKSURL                 string `env:"AUTH_JWKS_URL" envDefault:""`
+	JWKSCacheTTLSeconds     int    `env:"AUTH_JWKS_CACHE_TTL_SECONDS" envDefault:"3600"`
+	JWTIssuer               string `env:"AUTH_JWT_ISSUER" envDefault:""`
+	JWTAudience             string `env:"AUTH_JWT_AUDIENCE" envDefault:""`
+	JWTClockSkewSeconds     int    `env:"AUTH_JWT_CLOCK_SKEW_SECONDS" envDefault:"60"`
+	// Multi-tenant : claim JWT portant le tenant de l'utilisateur
+	AuthTenantClaim string `env:"AUTH_TENANT_CLAIM" envDefault:"tenant"`
+	// Multi-tenant strict : JWT et clés API sans tenant réservés au rôle admin
+	AuthTenantRequired bool `env:"AUTH_TENANT_REQUIRED" envDefault:"false"`
+	// Clés API persistées (table api_keys) : durée du cache mémoire
+	APIKeyCacheTTLSeconds int `env:"AUTH_APIKEY_CACHE_TTL_SECONDS" envDefault:"60"`
 	
 	// Factur-X Validation Configuration (Sprint 5 Phase 5.3)
 	FacturXValidationEnabled  bool `env:"FACTURX_VALIDATION_ENABLED" envDefault:"true"`
 	FacturXValidationRequired bool `env:"FACTURX_VALIDATION_REQUIRED" envDefault:"false"`
+	// Taille maximale validée (validation en mémoire) ; 0 : sans limite
+	FacturXValidationMaxMB int `env:"FACTURX_VALIDATION_MAX_MB" envDefault:"32"`
 	
 	// Webhooks Configuration (Sprint 5 Phase 5.3)
 	WebhooksEnabled    bool   `env:"WEBHOOKS_ENABLED" envDefault:"false"`
@@ -54,6 +133,12 @@ type Config struct {
 	
 	// POS Configuration (Sprint 6)
 	PosTicketMaxSizeBytes int `env:"POS_TICKET_MAX_SIZE_BYTES" envDefault:"65536"` // 64 KB
+
+	// Uploads en flux et reprenables (documents volumineux)
+	UploadMaxSizeMB       int    `env:"UPLOAD_MAX_SIZE_MB" envDefault:"1024"` // 0 : sans limite
+	UploadSessionsDir     string `env:"UPLOAD_SESSIONS_DIR" envDefault:"/opt/dorevia-vault/uploads"` // fichiers temporaires (0700, chiffrés)
+	UploadChunkMaxMB      int    `env:"UPLOAD_CHUNK_MAX_MB" envDefault:"64"`
+	UploadSessionTTLHours int    `env:"UPLOAD_SESSION_TTL_HOURS" envDefault:"24"`
 }
 
 // Load charge la configuration depuis les variables d'environnement
@@ -0,0 +1,335 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Format du contenu chiffré (DVE1) : en-tête "DVE1" + préfixe de nonce (7 octets), puis
+// segments AES-256-GCM de 64 Kio de clair. Le nonce d'un segment est préfixe || compteur
+// (4 octets) || indicateur de dernier segment : un contenu tronqué ou réordonné est refusé
+const (
+	envelopeMagic       = "DVE1"
+	envelopePrefixSize  = 7
+	envelopeSegmentSize = 64 * 1024
+	envelopeTagSize     = 16
+	envelopeHeaderSize  = len(envelopeMagic) + envelopePrefixSize
+	dataKeySize         = 32
+)
+
+// dataKeyLabel lie l'enveloppe RSA-OAEP à son usage
+var dataKeyLabel = []byte("dorevia-vault/document-dek")
+
+// ErrContentDecryption est retourné quand le contenu chiffré est altéré ou la clé incorrecte
+var ErrContentDecryption = errors.New("failed to decrypt document content")
+
+// EncryptedSize retourne la taille du contenu chiffré pour un clair de plaintextSize octets
+func EncryptedSize(plaintextSize int64) int64 {
+	segments := (plaintextSize + envelopeSegmentSize - 1) / envelopeSegmentSize
+	if segments == 0 {
+		segments = 1
+	}
+	return int64(envelopeHeaderSize) + plaintextSize + segments*envelopeTagSize
+}
+
+// Envelope chiffre le contenu des documents par enveloppe : une clé de données AES-256
+// par document, enveloppée (RSA-OAEP SHA-256) par une clé de chiffrement de clés (KEK)
+// du KeyManager. La version de KEK est le KID de la paire RSA
+type Envelope struct {
+	keys  KeyManager
+	kekID string
+
+	mu      sync.Mutex
+	private map[string]*rsa.PrivateKey
+	public  map[string]*rsa.PublicKey
+}
+
+// NewEnvelope crée le chiffrement par enveloppe ; kekID est la KEK des nouvelles clés
+func NewEnvelope(keys KeyManager, kekID string) (*Envelope, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("key manager is required for document encryption")
+	}
+	if kekID == "" {
+		return nil, fmt.Errorf("key encryption key id is required for document encryption")
+	}
+	e := &Envelope{
+		keys:    keys,
+		kekID:   kekID,
+		private: map[string]*rsa.PrivateKey{},
+		public:  map[string]*rsa.PublicKey{},
+	}
+	if _, err := e.publicKey(context.Background(), kekID); err != nil {
+		return nil, fmt.Errorf("failed to load key encryption key %s: %w", kekID, err)
+	}
+	return e, nil
+}
+
+// KEKVersion retourne la version de la KEK des nouvelles clés de données
+func (e *Envelope) KEKVersion() string {
+	return e.kekID
+}
+
+// NewDataKey génère une clé de données et la retourne en clair et enveloppée (base64)
+// par la KEK courante
+func (e *Envelope) NewDataKey(ctx context.Context) (dek []byte, wrapped string, kekVersion string, err error) {
+	dek = make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, "", "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err = e.wrap(ctx, dek, e.kekID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return dek, wrapped, e.kekID, nil
+}
+
+// UnwrapDataKey retrouve la clé de données enveloppée par la KEK kekVersion
+func (e *Envelope) UnwrapDataKey(ctx context.Context, wrapped, kekVersion string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode wrapped data key: %w", err)
+	}
+	privateKey, err := e.privateKey(ctx, kekVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key encryption key %s: %w", kekVersion, err)
+	}
+	dek, err := rsa.DecryptOAEP(sha256.New(), nil, privateKey, ciphertext, dataKeyLabel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with %s: %w", kekVersion, err)
+	}
+	if len(dek) != dataKeySize {
+		return nil, fmt.Errorf("unwrapped data key has %d bytes, expected %d", len(dek), dataKeySize)
+	}
+	return dek, nil
+}
+
+// RewrapDataKey enveloppe à nouveau une clé de données par la KEK courante, sans toucher
+// au contenu qu'elle chiffre
+func (e *Envelope) RewrapDataKey(ctx context.Context, wrapped, kekVersion string) (string, string, error) {
+	dek, err := e.UnwrapDataKey(ctx, wrapped, kekVersion)
+	if err != nil {
+		return "", "", err
+	}
+	rewrapped, err := e.wrap(ctx, dek, e.kekID)
+	if err != nil {
+		return "", "", err
+	}
+	return rewrapped, e.kekID, nil
+}
+
+func (e *Envelope) wrap(ctx context.Context, dek []byte, kekVersion string) (string, error) {
+	publicKey, err := e.publicKey(ctx, kekVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to load key encryption key %s: %w", kekVersion, err)
+	}
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, dek, dataKeyLabel)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func (e *Envelope) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if key, ok := e.public[kid]; ok {
+		return key, nil
+	}
+	key, err := e.keys.GetPublicKey(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	e.public[kid] = key
+	return key, nil
+}
+
+func (e *Envelope) privateKey(ctx context.Context, kid string) (*rsa.PrivateKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if key, ok := e.private[kid]; ok {
+		return key, nil
+	}
+	key, err := e.keys.GetPrivateKey(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	e.private[kid] = key
+	return key, nil
+}
+
+// EncryptContent chiffre un contenu avec une clé de données (format DVE1)
+func EncryptContent(dek, plaintext []byte) ([]byte, error) {
+	r, err := NewEncryptReader(dek, bytes.NewReader(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	out := bytes.NewBuffer(make([]byte, 0, EncryptedSize(int64(len(plaintext)))))
+	if _, err := io.Copy(out, r); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// DecryptContent déchiffre un contenu au format DVE1
+func DecryptContent(dek, ciphertext []byte) ([]byte, error) {
+	r, err := NewDecryptReader(dek, bytes.NewReader(ciphertext))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// NewEncryptReader chiffre au fil de la lecture le contenu lu dans src
+func NewEncryptReader(dek []byte, src io.Reader) (io.Reader, error) {
+	aead, err := newContentAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, envelopeHeaderSize)
+	copy(header, envelopeMagic)
+	if _, err := io.ReadFull(rand.Reader, header[len(envelopeMagic):]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return &encryptReader{
+		segmentStream: segmentStream{aead: aead, prefix: header[len(envelopeMagic):], src: bufio.NewReaderSize(src, envelopeSegmentSize+1)},
+		out:           header,
+		plain:         make([]byte, envelopeSegmentSize),
+		sealed:        make([]byte, 0, envelopeSegmentSize+envelopeTagSize),
+	}, nil
+}
+
+// NewDecryptReader déchiffre au fil de la lecture le contenu DVE1 lu dans src. Un segment
+// altéré, manquant ou réordonné produit ErrContentDecryption
+func NewDecryptReader(dek []byte, src io.Reader) (io.Reader, error) {
+	aead, err := newContentAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, envelopeHeaderSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, fmt.Errorf("%w: missing header", ErrContentDecryption)
+	}
+	if string(header[:len(envelopeMagic)]) != envelopeMagic {
+		return nil, fmt.Errorf("%w: unknown format", ErrContentDecryption)
+	}
+	return &decryptReader{
+		segmentStream: segmentStream{aead: aead, prefix: header[len(envelopeMagic):], src: bufio.NewReaderSize(src, envelopeSegmentSize+envelopeTagSize+1)},
+		sealed:        make([]byte, envelopeSegmentSize+envelopeTagSize),
+	}, nil
+}
+
+func newContentAEAD(dek []byte) (cipher.AEAD, error) {
+	if len(dek) != dataKeySize {
+		return nil, fmt.Errorf("data key has %d bytes, expected %d", len(dek), dataKeySize)
+	}
+	block, err := aes.NewCipher(dek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return aead, nil
+}
+
+// segmentStream porte l'état commun au chiffrement et au déchiffrement par segments
+type segmentStream struct {
+	aead    cipher.AEAD
+	prefix  []byte
+	src     *bufio.Reader
+	counter uint32
+	done    bool
+}
+
+func (s *segmentStream) nonce(last bool) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, s.prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, s.counter)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// readSegment lit jusqu'à len(buf) octets et indique s'il s'agit du dernier segment
+func (s *segmentStream) readSegment(buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(s.src, buf)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		return n, true, nil
+	case err != nil:
+		return n, false, err
+	}
+	if _, err := s.src.Peek(1); err == io.EOF {
+		return n, true, nil
+	} else if err != nil {
+		return n, false, err
+	}
+	return n, false, nil
+}
+
+type encryptReader struct {
+	segmentStream
+	plain  []byte
+	sealed []byte
+	out    []byte
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		n, last, err := r.readSegment(r.plain)
+		if err != nil {
+			return 0, err
+		}
+		r.out = r.aead.Seal(r.sealed[:0], r.nonce(last), r.plain[:n], nil)
+		r.counter++
+		r.done = last
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+type decryptReader struct {
+	segmentStream
+	sealed []byte
+	out    []byte
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		n, last, err := r.readSegment(r.sealed)
+		if err != nil {
+			return 0, err
+		}
+		plain, err := r.aead.Open(r.sealed[:0], r.nonce(last), r.sealed[:n], nil)
+		if err != nil {
+			return 0, ErrContentDecryption
+		}
+		r.out = plain
+		r.counter++
+		r.done = last
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
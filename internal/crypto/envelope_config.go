@@ -0,0 +1,54 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/rs/zerolog"
+)
+
+// EnvelopeFromConfig crée le chiffrement par enveloppe du contenu des documents
+// (DOCUMENT_ENCRYPTION_*) ; nil si le chiffrement est désactivé. Les KEK sont lues dans
+// HashiCorp Vault si VAULT_ENABLED, sinon dans DOCUMENT_ENCRYPTION_KEYS_DIR
+func EnvelopeFromConfig(cfg config.Config, log zerolog.Logger) (*Envelope, error) {
+	if !cfg.DocumentEncryptionEnabled {
+		return nil, nil
+	}
+
+	kekID := cfg.DocumentEncryptionKEKID
+	var keys KeyManager
+	if cfg.VaultEnabled {
+		mountPath, keyPath, _ := strings.Cut(strings.Trim(cfg.VaultKeyPath, "/"), "/")
+		vaultKeys, err := NewVaultKeyManager(VaultConfig{
+			Enabled:   true,
+			Addr:      cfg.VaultAddr,
+			Token:     cfg.VaultToken,
+			MountPath: mountPath,
+			KeyPath:   keyPath,
+			Logger:    log,
+		})
+		if err != nil {
+			return nil, err
+		}
+		keys = vaultKeys
+	} else {
+		if cfg.DocumentEncryptionKeysDir == "" {
+			return nil, fmt.Errorf("DOCUMENT_ENCRYPTION_ENABLED requires DOCUMENT_ENCRYPTION_KEYS_DIR or VAULT_ENABLED")
+		}
+		dirKeys := NewDirKeyManager(cfg.DocumentEncryptionKeysDir, log)
+		if kekID == "" {
+			current, err := dirKeys.LoadCurrentKID(context.Background())
+			if err != nil {
+				return nil, err
+			}
+			kekID = current
+		}
+		keys = dirKeys
+	}
+	if kekID == "" {
+		return nil, fmt.Errorf("DOCUMENT_ENCRYPTION_KEK_ID not configured")
+	}
+	return NewEnvelope(keys, kekID)
+}
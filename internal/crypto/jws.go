@@ -3,8 +3,10 @@ package crypto
 import (
 	"context"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -19,6 +21,7 @@ type Evidence struct {
 	DocumentID string    `json:"document_id"`
 	Sha256     string    `json:"sha256"`
 	Timestamp  time.Time `json:"timestamp"`
+	KID        string    `json:"kid,omitempty"` // Clé de signature (header JWS), renseigné à la vérification
 }
 
 // Service gère les opérations JWS (signature et vérification)
@@ -26,7 +29,8 @@ type Service struct {
 	privateKey *rsa.PrivateKey
 	publicKey  *rsa.PublicKey
 	kid        string
-	keyManager KeyManager // Optionnel : pour support Vault
+	keyManager KeyManager   // Optionnel : pour support Vault
+	rotation   *KeyRotation // Optionnel : rotation multi-KID
 }
 
 // NewService crée un nouveau service JWS
@@ -74,6 +78,69 @@ func NewServiceWithKeyManager(keyManager KeyManager, kid string) (*Service, erro
 	}, nil
 }
 
+// NewServiceWithRotation crée un service JWS multi-KID :
+// signature avec la clé courante de la rotation, vérification par le kid du JWS
+func NewServiceWithRotation(rotation *KeyRotation) (*Service, error) {
+	if rotation == nil {
+		return nil, fmt.Errorf("key rotation is required")
+	}
+	current, err := rotation.GetCurrentKeyPair()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load current key: %w", err)
+	}
+	if current.PrivateKey == nil {
+		return nil, fmt.Errorf("private key not available for current kid %s", current.KID)
+	}
+
+	return &Service{
+		privateKey: current.PrivateKey,
+		publicKey:  current.PublicKey,
+		kid:        current.KID,
+		keyManager: rotation.keyManager,
+		rotation:   rotation,
+	}, nil
+}
+
+// Rotation retourne le gestionnaire de rotation (nil en mode clé unique)
+func (s *Service) Rotation() *KeyRotation {
+	return s.rotation
+}
+
+// signingKey retourne la clé privée et le kid de signature courants
+func (s *Service) signingKey() (*rsa.PrivateKey, string, error) {
+	if s.rotation == nil {
+		return s.privateKey, s.kid, nil
+	}
+	current, err := s.rotation.GetCurrentKeyPair()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get current key: %w", err)
+	}
+	return current.PrivateKey, current.KID, nil
+}
+
+// verificationKey sélectionne la clé publique d'après le kid du JWS
+// En mode rotation, les clés retirées restent utilisables : une preuve scellée
+// avant une rotation doit rester vérifiable. Un JWS sans kid date d'avant la rotation
+// multi-KID : il est vérifié avec la clé la plus ancienne, jamais avec la clé courante
+func (s *Service) verificationKey(token *jwt.Token) (*rsa.PublicKey, error) {
+	if s.rotation == nil {
+		return s.publicKey, nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		legacy, err := s.rotation.GetLegacyKeyPair()
+		if err != nil {
+			return nil, err
+		}
+		return legacy.PublicKey, nil
+	}
+	keyPair, err := s.rotation.GetKeyPair(kid)
+	if err != nil {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+	return keyPair.PublicKey, nil
+}
+
 // loadKeysFromFiles charge les clés depuis les fichiers PEM
 func loadKeysFromFiles(privateKeyPath, publicKeyPath string) (*rsa.PrivateKey, *rsa.PublicKey, error) {
 	// Charger clé privée
@@ -129,7 +196,11 @@ func loadKeysFromEnv() (*rsa.PrivateKey, *rsa.PublicKey, error) {
 
 // SignEvidence signe un triplet (document_id, sha256, timestamp) et retourne un JWS
 func (s *Service) SignEvidence(docID, shaHex string, t time.Time) (string, error) {
-	if s.privateKey == nil {
+	privateKey, kid, err := s.signingKey()
+	if err != nil {
+		return "", err
+	}
+	if privateKey == nil {
 		return "", fmt.Errorf("private key not loaded")
 	}
 
@@ -143,10 +214,10 @@ func (s *Service) SignEvidence(docID, shaHex string, t time.Time) (string, error
 
 	// Créer le token avec header personnalisé (kid)
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	token.Header["kid"] = s.kid
+	token.Header["kid"] = kid
 
 	// Signer
-	jws, err := token.SignedString(s.privateKey)
+	jws, err := token.SignedString(privateKey)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -154,19 +225,35 @@ func (s *Service) SignEvidence(docID, shaHex string, t time.Time) (string, error
 	return jws, nil
 }
 
+// SignReport scelle un rapport JSON (vérification de chaîne, réconciliation...) :
+// le SHA256 du JSON sérialisé est signé comme "document" sous l'identifiant subject
+func (s *Service) SignReport(subject string, report interface{}, t time.Time) (string, error) {
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal report: %w", err)
+	}
+	hash := sha256.Sum256(reportJSON)
+	return s.SignEvidence(subject, hex.EncodeToString(hash[:]), t)
+}
+
 // VerifyEvidence vérifie un JWS et retourne l'Evidence
 func (s *Service) VerifyEvidence(jws string) (*Evidence, error) {
-	if s.publicKey == nil {
+	if s.publicKey == nil && s.rotation == nil {
 		return nil, fmt.Errorf("public key not loaded")
 	}
+	return parseEvidence(jws, s.verificationKey)
+}
 
+// parseEvidence vérifie la signature d'un JWS (RS256) avec la clé retournée par keyFunc
+// et extrait les claims de l'Evidence
+func parseEvidence(jws string, keyFunc func(token *jwt.Token) (*rsa.PublicKey, error)) (*Evidence, error) {
 	// Parser et vérifier le token
 	token, err := jwt.Parse(jws, func(token *jwt.Token) (interface{}, error) {
 		// Vérifier l'algorithme
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return s.publicKey, nil
+		return keyFunc(token)
 	})
 
 	if err != nil {
@@ -204,15 +291,22 @@ func (s *Service) VerifyEvidence(jws string) (*Evidence, error) {
 		return nil, fmt.Errorf("invalid timestamp format: %w", err)
 	}
 
+	kid, _ := token.Header["kid"].(string)
+
 	return &Evidence{
 		DocumentID: docID,
 		Sha256:     sha256,
 		Timestamp:  timestamp,
+		KID:        kid,
 	}, nil
 }
 
 // CurrentJWKS retourne le JWKS (JSON Web Key Set) pour les clés publiques
+// En mode rotation, toutes les clés non expirées sont publiées
 func (s *Service) CurrentJWKS() ([]byte, error) {
+	if s.rotation != nil {
+		return s.rotation.GetJWKS()
+	}
 	if s.publicKey == nil {
 		return nil, fmt.Errorf("public key not loaded")
 	}
@@ -250,7 +344,20 @@ func (s *Service) CurrentJWKS() ([]byte, error) {
 	return json.MarshalIndent(jwks, "", "  ")
 }
 
+// PublicKey retourne la clé publique de vérification courante
+func (s *Service) PublicKey() *rsa.PublicKey {
+	if s.rotation != nil {
+		if current, err := s.rotation.GetCurrentKeyPair(); err == nil {
+			return current.PublicKey
+		}
+	}
+	return s.publicKey
+}
+
 // GetKID retourne le kid (Key ID) actuel
 func (s *Service) GetKID() string {
+	if s.rotation != nil {
+		return s.rotation.GetCurrentKID()
+	}
 	return s.kid
 }
@@ -0,0 +1,300 @@
+package crypto
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Fichiers d'un répertoire de clés multi-KID
+const (
+	keyDirPrivateFile = "private.pem"
+	keyDirPublicFile  = "public.pem"
+	keyDirCurrentFile = "current"    // KID de signature courant
+	keyDirCreatedFile = "created_at" // date de création de la clé (RFC 3339)
+)
+
+// kidPattern restreint les KIDs acceptés (utilisés comme noms de répertoires)
+var kidPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// KeyGenerator est implémenté par les KeyManager capables de créer une nouvelle paire de clés
+type KeyGenerator interface {
+	GenerateKeyPair(ctx context.Context, kid string) error
+}
+
+// CurrentKIDStore persiste le KID de signature courant entre deux redémarrages
+type CurrentKIDStore interface {
+	LoadCurrentKID(ctx context.Context) (string, error)
+	SaveCurrentKID(ctx context.Context, kid string) error
+}
+
+// KeyMetadataProvider fournit la date de création d'une clé (calcul de l'expiration).
+// La date doit être stable d'un redémarrage à l'autre
+type KeyMetadataProvider interface {
+	GetKeyCreatedAt(ctx context.Context, kid string) (time.Time, error)
+}
+
+// DirKeyManager implémente KeyManager avec un répertoire de clés multi-KID :
+//
+//	<dir>/<kid>/private.pem
+//	<dir>/<kid>/public.pem
+//	<dir>/<kid>/created_at   (date de création, enregistrée au premier chargement)
+//	<dir>/current            (KID de signature courant, optionnel)
+//
+// Chaque sous-répertoire est compatible avec la sortie de cmd/keygen (-out <dir>/<kid>)
+type DirKeyManager struct {
+	dir  string
+	bits int
+	log  zerolog.Logger
+}
+
+var (
+	_ KeyManager          = (*DirKeyManager)(nil)
+	_ KeyGenerator        = (*DirKeyManager)(nil)
+	_ CurrentKIDStore     = (*DirKeyManager)(nil)
+	_ KeyMetadataProvider = (*DirKeyManager)(nil)
+	_ KeyMetadataProvider = (*FileKeyManager)(nil)
+)
+
+// NewDirKeyManager crée un DirKeyManager sur le répertoire donné
+func NewDirKeyManager(dir string, log zerolog.Logger) *DirKeyManager {
+	return &DirKeyManager{
+		dir:  dir,
+		bits: 2048,
+		log:  log,
+	}
+}
+
+// ValidateKID vérifie qu'un KID est utilisable comme nom de répertoire
+func ValidateKID(kid string) error {
+	if !kidPattern.MatchString(kid) {
+		return fmt.Errorf("invalid kid %q (allowed: letters, digits, '.', '_', '-')", kid)
+	}
+	return nil
+}
+
+// NewKIDFromTime construit un KID daté (ex: key-20251114T103000Z)
+func NewKIDFromTime(t time.Time) string {
+	return "key-" + t.UTC().Format("20060102T150405Z")
+}
+
+func (d *DirKeyManager) keyPath(kid, file string) (string, error) {
+	if err := ValidateKID(kid); err != nil {
+		return "", err
+	}
+	return filepath.Join(d.dir, kid, file), nil
+}
+
+// GetPrivateKey charge la clé privée du KID
+func (d *DirKeyManager) GetPrivateKey(ctx context.Context, kid string) (*rsa.PrivateKey, error) {
+	privatePath, err := d.keyPath(kid, keyDirPrivateFile)
+	if err != nil {
+		return nil, err
+	}
+	publicPath, _ := d.keyPath(kid, keyDirPublicFile)
+	privateKey, _, err := loadKeysFromFiles(privatePath, publicPath)
+	return privateKey, err
+}
+
+// GetPublicKey charge la clé publique du KID
+// La clé privée n'est pas requise : une clé retirée peut ne conserver que public.pem
+func (d *DirKeyManager) GetPublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	publicPath, err := d.keyPath(kid, keyDirPublicFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(publicPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode public key PEM")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	publicKey, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not RSA")
+	}
+	return publicKey, nil
+}
+
+// ListKIDs liste les sous-répertoires contenant une clé publique
+func (d *DirKeyManager) ListKIDs(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys directory: %w", err)
+	}
+	var kids []string
+	for _, entry := range entries {
+		if !entry.IsDir() || ValidateKID(entry.Name()) != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(d.dir, entry.Name(), keyDirPublicFile)); err != nil {
+			continue
+		}
+		kids = append(kids, entry.Name())
+	}
+	sort.Strings(kids)
+	return kids, nil
+}
+
+// IsAvailable vérifie que le répertoire de clés existe
+func (d *DirKeyManager) IsAvailable(ctx context.Context) bool {
+	info, err := os.Stat(d.dir)
+	return err == nil && info.IsDir()
+}
+
+// GetKeyCreatedAt retourne la date de création de la clé (<kid>/created_at)
+func (d *DirKeyManager) GetKeyCreatedAt(ctx context.Context, kid string) (time.Time, error) {
+	createdPath, err := d.keyPath(kid, keyDirCreatedFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	publicPath, _ := d.keyPath(kid, keyDirPublicFile)
+	return loadKeyCreatedAt(createdPath, publicPath)
+}
+
+// loadKeyCreatedAt lit la date de création enregistrée dans createdPath. Une clé sans date
+// enregistrée (cmd/keygen, clé antérieure à l'enregistrement) reçoit au premier chargement
+// la date de modification de sa clé publique, enregistrée pour ne plus changer ensuite
+// (redémarrage, copie ou restauration des fichiers)
+func loadKeyCreatedAt(createdPath, publicPath string) (time.Time, error) {
+	data, err := os.ReadFile(createdPath)
+	if err == nil {
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid key creation date in %s: %w", createdPath, err)
+		}
+		return t, nil
+	}
+	if !os.IsNotExist(err) {
+		return time.Time{}, fmt.Errorf("failed to read key creation date: %w", err)
+	}
+
+	info, err := os.Stat(publicPath)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to stat public key: %w", err)
+	}
+	createdAt := info.ModTime().UTC()
+	if err := saveKeyCreatedAt(createdPath, createdAt); err != nil {
+		return time.Time{}, err
+	}
+	return createdAt, nil
+}
+
+// saveKeyCreatedAt enregistre la date de création d'une clé
+func saveKeyCreatedAt(createdPath string, t time.Time) error {
+	if err := os.WriteFile(createdPath, []byte(t.UTC().Format(time.RFC3339Nano)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to record key creation date: %w", err)
+	}
+	return nil
+}
+
+// GenerateKeyPair génère une nouvelle paire RSA dans <dir>/<kid>
+// Refuse d'écraser une clé existante
+func (d *DirKeyManager) GenerateKeyPair(ctx context.Context, kid string) error {
+	if err := ValidateKID(kid); err != nil {
+		return err
+	}
+	keyDir := filepath.Join(d.dir, kid)
+	if _, err := os.Stat(keyDir); err == nil {
+		return fmt.Errorf("key %s already exists", kid)
+	}
+	if err := os.MkdirAll(keyDir, 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, d.bits)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	if err := os.WriteFile(filepath.Join(keyDir, keyDirPrivateFile), privatePEM, 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes})
+	if err := os.WriteFile(filepath.Join(keyDir, keyDirPublicFile), publicPEM, 0644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+	if err := saveKeyCreatedAt(filepath.Join(keyDir, keyDirCreatedFile), time.Now()); err != nil {
+		return err
+	}
+
+	d.log.Info().Str("kid", kid).Str("dir", keyDir).Msg("JWS key pair generated")
+	return nil
+}
+
+// LoadCurrentKID lit le KID courant ("" si le fichier n'existe pas)
+func (d *DirKeyManager) LoadCurrentKID(ctx context.Context) (string, error) {
+	data, err := os.ReadFile(filepath.Join(d.dir, keyDirCurrentFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read current kid: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SaveCurrentKID écrit le KID courant (remplacement atomique)
+func (d *DirKeyManager) SaveCurrentKID(ctx context.Context, kid string) error {
+	if err := ValidateKID(kid); err != nil {
+		return err
+	}
+	tmp := filepath.Join(d.dir, keyDirCurrentFile+".tmp")
+	if err := os.WriteFile(tmp, []byte(kid+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write current kid: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(d.dir, keyDirCurrentFile)); err != nil {
+		return fmt.Errorf("failed to write current kid: %w", err)
+	}
+	return nil
+}
+
+// NewRotatingService initialise un service JWS sur un répertoire de clés multi-KID
+// Le KID courant est celui persisté lors de la dernière rotation, sinon defaultKID
+func NewRotatingService(keysDir, defaultKID string, rotationPeriod time.Duration, log zerolog.Logger) (*Service, error) {
+	keyManager := NewDirKeyManager(keysDir, log)
+	if !keyManager.IsAvailable(context.Background()) {
+		return nil, fmt.Errorf("keys directory not found: %s", keysDir)
+	}
+
+	currentKID, err := keyManager.LoadCurrentKID(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if currentKID == "" {
+		currentKID = defaultKID
+	}
+
+	rotation, err := NewKeyRotation(RotationConfig{
+		KeyManager:     keyManager,
+		CurrentKID:     currentKID,
+		RotationPeriod: rotationPeriod,
+		Logger:         log,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewServiceWithRotation(rotation)
+}
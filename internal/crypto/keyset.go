@@ -0,0 +1,121 @@
+package crypto
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySet est un jeu de clés publiques RSA indexé par kid
+// Il permet de vérifier des preuves JWS sans accès au coffre (vérification hors ligne)
+type KeySet map[string]*rsa.PublicKey
+
+// ParseJWKS parse un JWKS (RFC 7517) ; seules les clés RSA de signature sont retenues
+func ParseJWKS(data []byte) (KeySet, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(KeySet, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		nBytes, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil || len(nBytes) == 0 {
+			return nil, fmt.Errorf("invalid modulus for kid %s", jwk.Kid)
+		}
+		eBytes, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(eBytes) == 0 {
+			return nil, fmt.Errorf("invalid exponent for kid %s", jwk.Kid)
+		}
+		e := new(big.Int).SetBytes(eBytes)
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid exponent for kid %s", jwk.Kid)
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: int(e.Int64())}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no RSA signing key found in JWKS")
+	}
+	return keys, nil
+}
+
+// VerifyEvidence vérifie un JWS avec la clé désignée par son kid
+// Un JWS sans kid n'est accepté que si le jeu ne contient qu'une seule clé
+func (ks KeySet) VerifyEvidence(jws string) (*Evidence, error) {
+	return parseEvidence(jws, func(token *jwt.Token) (*rsa.PublicKey, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" && len(ks) == 1 {
+			for _, key := range ks {
+				return key, nil
+			}
+		}
+		key, ok := ks[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id: %s", kid)
+		}
+		return key, nil
+	})
+}
+
+// PeekKID retourne le kid du header d'un JWS sans vérifier la signature
+func PeekKID(jws string) (string, error) {
+	token, _, err := jwt.NewParser().ParseUnverified(jws, jwt.MapClaims{})
+	if err != nil {
+		return "", fmt.Errorf("failed to parse token: %w", err)
+	}
+	kid, _ := token.Header["kid"].(string)
+	return kid, nil
+}
+
+// JWKSForKIDs retourne un JWKS restreint aux clés demandées (KID vides ou inconnus ignorés)
+// Utilisé pour joindre à une preuve la clé valide au moment de la signature
+func (s *Service) JWKSForKIDs(kids ...string) ([]byte, error) {
+	keys := make([]map[string]interface{}, 0, len(kids))
+	seen := make(map[string]bool)
+	for _, kid := range kids {
+		if kid == "" || seen[kid] {
+			continue
+		}
+		seen[kid] = true
+
+		var keyPair *KeyPair
+		if s.rotation != nil {
+			kp, err := s.rotation.GetKeyPair(kid)
+			if err != nil {
+				continue
+			}
+			keyPair = kp
+		} else if kid == s.kid && s.publicKey != nil {
+			keyPair = &KeyPair{KID: s.kid, PublicKey: s.publicKey}
+		} else {
+			continue
+		}
+
+		jwk, err := keyPairToJWK(keyPair)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwk)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no public key available for the requested key ids")
+	}
+	return json.MarshalIndent(map[string]interface{}{"keys": keys}, "", "  ")
+}
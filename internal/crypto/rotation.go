@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,24 +15,24 @@ import (
 
 // KeyRotation gère la rotation des clés avec support multi-KID
 type KeyRotation struct {
-	currentKID      string
-	previousKID     string
+	currentKID       string
+	previousKID      string
 	nextRotationDate time.Time
-	keys            map[string]*KeyPair
-	keyManager      KeyManager
-	log             zerolog.Logger
-	mu              sync.RWMutex
-	rotationPeriod  time.Duration // Durée avant rotation (ex: 90 jours)
+	keys             map[string]*KeyPair
+	keyManager       KeyManager
+	log              zerolog.Logger
+	mu               sync.RWMutex
+	rotationPeriod   time.Duration // Durée avant rotation (ex: 90 jours)
 }
 
 // KeyPair représente une paire de clés avec métadonnées
 type KeyPair struct {
-	KID         string
-	PrivateKey  *rsa.PrivateKey
-	PublicKey   *rsa.PublicKey
-	CreatedAt   time.Time
-	ExpiresAt   time.Time
-	IsActive    bool
+	KID        string
+	PrivateKey *rsa.PrivateKey
+	PublicKey  *rsa.PublicKey
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	IsActive   bool
 }
 
 // RotationConfig configuration pour KeyRotation
@@ -51,7 +52,7 @@ func NewKeyRotation(cfg RotationConfig) (*KeyRotation, error) {
 	ctx := context.Background()
 
 	// Charger la clé actuelle
-	currentKey, err := loadKeyPair(ctx, cfg.KeyManager, cfg.CurrentKID)
+	currentKey, err := loadKeyPair(ctx, cfg.KeyManager, cfg.CurrentKID, cfg.RotationPeriod)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load current key: %w", err)
 	}
@@ -67,18 +68,21 @@ func NewKeyRotation(cfg RotationConfig) (*KeyRotation, error) {
 	keys := make(map[string]*KeyPair)
 	keys[cfg.CurrentKID] = currentKey
 
-	// Trouver la clé précédente (si existe)
+	// Les autres clés sont retirées : elles ne signent plus mais restent
+	// disponibles pour vérifier les preuves scellées avant la rotation
 	var previousKID string
 	for _, kid := range allKIDs {
 		if kid != cfg.CurrentKID {
-			keyPair, err := loadKeyPair(ctx, cfg.KeyManager, kid)
+			keyPair, err := loadKeyPair(ctx, cfg.KeyManager, kid, cfg.RotationPeriod)
 			if err != nil {
 				cfg.Logger.Warn().Err(err).Str("kid", kid).Msg("Failed to load key, skipping")
 				continue
 			}
+			keyPair.IsActive = false
 			keys[kid] = keyPair
-			// La clé précédente est celle qui n'est plus active mais pas expirée
-			if !keyPair.IsActive && time.Now().Before(keyPair.ExpiresAt) {
+			// La clé précédente est la plus récente des clés retirées non expirées
+			if time.Now().Before(keyPair.ExpiresAt) &&
+				(previousKID == "" || keyPair.CreatedAt.After(keys[previousKID].CreatedAt)) {
 				previousKID = kid
 			}
 		}
@@ -87,7 +91,7 @@ func NewKeyRotation(cfg RotationConfig) (*KeyRotation, error) {
 	rotation := &KeyRotation{
 		currentKID:       cfg.CurrentKID,
 		previousKID:      previousKID,
-		nextRotationDate: currentKey.ExpiresAt,
+		nextRotationDate: currentKey.CreatedAt.Add(cfg.RotationPeriod),
 		keys:             keys,
 		keyManager:       cfg.KeyManager,
 		log:              cfg.Logger,
@@ -97,6 +101,7 @@ func NewKeyRotation(cfg RotationConfig) (*KeyRotation, error) {
 	cfg.Logger.Info().
 		Str("current_kid", cfg.CurrentKID).
 		Str("previous_kid", previousKID).
+		Int("keys", len(keys)).
 		Time("next_rotation", rotation.nextRotationDate).
 		Msg("KeyRotation initialized")
 
@@ -104,21 +109,30 @@ func NewKeyRotation(cfg RotationConfig) (*KeyRotation, error) {
 }
 
 // loadKeyPair charge une paire de clés depuis le KeyManager
-func loadKeyPair(ctx context.Context, keyManager KeyManager, kid string) (*KeyPair, error) {
-	privateKey, err := keyManager.GetPrivateKey(ctx, kid)
+// Une clé signe pendant rotationPeriod puis reste publiée (JWKS) une période supplémentaire
+func loadKeyPair(ctx context.Context, keyManager KeyManager, kid string, rotationPeriod time.Duration) (*KeyPair, error) {
+	publicKey, err := keyManager.GetPublicKey(ctx, kid)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load private key: %w", err)
+		return nil, fmt.Errorf("failed to load public key: %w", err)
 	}
 
-	publicKey, err := keyManager.GetPublicKey(ctx, kid)
+	// Clé privée optionnelle : une clé retirée peut n'être conservée que pour la vérification
+	privateKey, err := keyManager.GetPrivateKey(ctx, kid)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load public key: %w", err)
+		privateKey = nil
 	}
 
-	// Pour simplifier, on considère que la clé est active si elle existe
-	// et expire dans rotationPeriod jours
-	createdAt := time.Now() // TODO: récupérer depuis métadonnées Vault si disponible
-	expiresAt := createdAt.Add(90 * 24 * time.Hour) // 90 jours par défaut
+	// Date de création persistée : une date prise au chargement repousserait l'expiration
+	// et la prochaine rotation à chaque redémarrage
+	provider, ok := keyManager.(KeyMetadataProvider)
+	if !ok {
+		return nil, fmt.Errorf("key manager does not record key creation dates (required for rotation)")
+	}
+	createdAt, err := provider.GetKeyCreatedAt(ctx, kid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key creation date: %w", err)
+	}
+	expiresAt := createdAt.Add(2 * rotationPeriod)
 
 	return &KeyPair{
 		KID:        kid,
@@ -144,33 +158,57 @@ func (kr *KeyRotation) GetPreviousKID() string {
 	return kr.previousKID
 }
 
-// GetKeyPair récupère une paire de clés par KID
+// GetKeyPair récupère une paire de clés par KID (y compris retirée ou expirée)
 func (kr *KeyRotation) GetKeyPair(kid string) (*KeyPair, error) {
 	kr.mu.RLock()
-	defer kr.mu.RUnlock()
-
 	keyPair, ok := kr.keys[kid]
-	if !ok {
-		// Essayer de charger depuis le KeyManager
-		ctx := context.Background()
-		keyPair, err := loadKeyPair(ctx, kr.keyManager, kid)
-		if err != nil {
-			return nil, fmt.Errorf("key not found: %s", kid)
-		}
-		// Mettre en cache
-		kr.keys[kid] = keyPair
+	kr.mu.RUnlock()
+	if ok {
 		return keyPair, nil
 	}
 
+	// Essayer de charger depuis le KeyManager (clé ajoutée depuis le démarrage)
+	keyPair, err := loadKeyPair(context.Background(), kr.keyManager, kid, kr.rotationPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("key not found: %s", kid)
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if existing, ok := kr.keys[kid]; ok {
+		return existing, nil
+	}
+	keyPair.IsActive = kid == kr.currentKID
+	kr.keys[kid] = keyPair
 	return keyPair, nil
 }
 
+// GetLegacyKeyPair retourne la clé la plus ancienne : celle des JWS émis sans kid, avant
+// la rotation multi-KID
+func (kr *KeyRotation) GetLegacyKeyPair() (*KeyPair, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	var legacy *KeyPair
+	for _, keyPair := range kr.keys {
+		if legacy == nil || keyPair.CreatedAt.Before(legacy.CreatedAt) ||
+			(keyPair.CreatedAt.Equal(legacy.CreatedAt) && keyPair.KID < legacy.KID) {
+			legacy = keyPair
+		}
+	}
+	if legacy == nil {
+		return nil, fmt.Errorf("no key available")
+	}
+	return legacy, nil
+}
+
 // GetCurrentKeyPair récupère la paire de clés actuelle
 func (kr *KeyRotation) GetCurrentKeyPair() (*KeyPair, error) {
 	return kr.GetKeyPair(kr.GetCurrentKID())
 }
 
-// GetAllActiveKeys retourne toutes les clés actives (actuelle + précédente si valide)
+// GetAllActiveKeys retourne les clés publiables : la clé actuelle (toujours en tête)
+// puis toutes les clés retirées non expirées, de la plus récente à la plus ancienne
 func (kr *KeyRotation) GetAllActiveKeys() []*KeyPair {
 	kr.mu.RLock()
 	defer kr.mu.RUnlock()
@@ -178,19 +216,25 @@ func (kr *KeyRotation) GetAllActiveKeys() []*KeyPair {
 	var activeKeys []*KeyPair
 	now := time.Now()
 
-	// Ajouter la clé actuelle
-	if current, ok := kr.keys[kr.currentKID]; ok && current.IsActive && now.Before(current.ExpiresAt) {
+	// La clé actuelle signe : elle est toujours publiée
+	if current, ok := kr.keys[kr.currentKID]; ok {
 		activeKeys = append(activeKeys, current)
 	}
 
-	// Ajouter la clé précédente si elle n'est pas expirée
-	if kr.previousKID != "" {
-		if previous, ok := kr.keys[kr.previousKID]; ok && now.Before(previous.ExpiresAt) {
-			activeKeys = append(activeKeys, previous)
+	var retired []*KeyPair
+	for kid, keyPair := range kr.keys {
+		if kid != kr.currentKID && now.Before(keyPair.ExpiresAt) {
+			retired = append(retired, keyPair)
 		}
 	}
+	sort.Slice(retired, func(i, j int) bool {
+		if retired[i].CreatedAt.Equal(retired[j].CreatedAt) {
+			return retired[i].KID > retired[j].KID
+		}
+		return retired[i].CreatedAt.After(retired[j].CreatedAt)
+	})
 
-	return activeKeys
+	return append(activeKeys, retired...)
 }
 
 // ShouldRotate vérifie si une rotation est nécessaire
@@ -210,30 +254,44 @@ func (kr *KeyRotation) GetNextRotationDate() time.Time {
 }
 
 // Rotate effectue une rotation vers un nouveau KID
+// Le KID courant est persisté si le KeyManager le permet (CurrentKIDStore)
 func (kr *KeyRotation) Rotate(newKID string) error {
-	kr.mu.Lock()
-	defer kr.mu.Unlock()
-
 	ctx := context.Background()
 
-	// Charger la nouvelle clé
-	newKeyPair, err := loadKeyPair(ctx, kr.keyManager, newKID)
+	// Charger la nouvelle clé (hors verrou : accès disque ou Vault)
+	newKeyPair, err := loadKeyPair(ctx, kr.keyManager, newKID, kr.rotationPeriod)
 	if err != nil {
 		return fmt.Errorf("failed to load new key: %w", err)
 	}
+	if newKeyPair.PrivateKey == nil {
+		return fmt.Errorf("failed to load new key: private key not available for %s", newKID)
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if newKID == kr.currentKID {
+		return fmt.Errorf("key %s is already the current key", newKID)
+	}
+
+	if store, ok := kr.keyManager.(CurrentKIDStore); ok {
+		if err := store.SaveCurrentKID(ctx, newKID); err != nil {
+			return fmt.Errorf("failed to persist current kid: %w", err)
+		}
+	}
 
 	// Mettre à jour les KIDs
 	kr.previousKID = kr.currentKID
 	kr.currentKID = newKID
 
-	// Désactiver l'ancienne clé
+	// Désactiver l'ancienne clé (conservée pour la vérification)
 	if oldKey, ok := kr.keys[kr.previousKID]; ok {
 		oldKey.IsActive = false
 	}
 
 	// Ajouter la nouvelle clé
 	kr.keys[newKID] = newKeyPair
-	kr.nextRotationDate = newKeyPair.ExpiresAt
+	kr.nextRotationDate = newKeyPair.CreatedAt.Add(kr.rotationPeriod)
 
 	kr.log.Info().
 		Str("previous_kid", kr.previousKID).
@@ -244,7 +302,16 @@ func (kr *KeyRotation) Rotate(newKID string) error {
 	return nil
 }
 
-// GetJWKS retourne le JWKS avec toutes les clés actives
+// GenerateKey crée une nouvelle paire de clés via le KeyManager (si supporté)
+func (kr *KeyRotation) GenerateKey(ctx context.Context, kid string) error {
+	generator, ok := kr.keyManager.(KeyGenerator)
+	if !ok {
+		return fmt.Errorf("key manager does not support key generation")
+	}
+	return generator.GenerateKeyPair(ctx, kid)
+}
+
+// GetJWKS retourne le JWKS avec toutes les clés publiables (non expirées)
 func (kr *KeyRotation) GetJWKS() ([]byte, error) {
 	activeKeys := kr.GetAllActiveKeys()
 
@@ -300,4 +367,3 @@ func keyPairToJWK(keyPair *KeyPair) (map[string]interface{}, error) {
 		"e":   eBase64,
 	}, nil
 }
-
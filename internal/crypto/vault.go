@@ -293,6 +293,12 @@ func (f *FileKeyManager) ListKIDs(ctx context.Context) ([]string, error) {
 	return []string{"default"}, nil
 }
 
+// GetKeyCreatedAt retourne la date de création de la clé, enregistrée à côté de la clé
+// publique (<public>.created_at) au premier chargement
+func (f *FileKeyManager) GetKeyCreatedAt(ctx context.Context, kid string) (time.Time, error) {
+	return loadKeyCreatedAt(f.publicKeyPath+"."+keyDirCreatedFile, f.publicKeyPath)
+}
+
 // IsAvailable vérifie si les fichiers existent
 func (f *FileKeyManager) IsAvailable(ctx context.Context) bool {
 	_, err1 := os.Stat(f.privateKeyPath)
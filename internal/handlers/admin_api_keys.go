@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// APIKeyCreateRequest représente le payload de création d'une clé API
+type APIKeyCreateRequest struct {
+	Name      string     `json:"name"`
+	UserID    string     `json:"user_id,omitempty"` // défaut: name
+	Role      string     `json:"role"`
+	Tenant    string     `json:"tenant,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeySecretResponse est retourné à la création et à la rotation :
+// la clé en clair n'est affichée qu'à cette occasion
+type APIKeySecretResponse struct {
+	*auth.APIKey
+	Secret  string `json:"api_key"`
+	Message string `json:"message"`
+}
+
+const apiKeyShownOnceMessage = "Store this API key now: it will not be shown again"
+
+// APIKeysCreateHandler gère l'endpoint POST /api/v1/admin/api-keys
+// tenantRequired (AUTH_TENANT_REQUIRED) : une clé sans tenant est réservée au rôle admin
+func APIKeysCreateHandler(db *storage.DB, tenantRequired bool, log *zerolog.Logger, auditLogger *audit.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+
+		var req APIKeyCreateRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid JSON payload",
+				"details": err.Error(),
+			})
+		}
+		if req.Name == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Missing required field: name",
+			})
+		}
+		if !auth.IsValidRole(req.Role) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid role (admin, auditor, operator, viewer)",
+			})
+		}
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "expires_at must be in the future",
+			})
+		}
+		if req.UserID == "" {
+			req.UserID = req.Name
+		}
+
+		// Multi-tenant : un administrateur de tenant ne crée des clés que pour son tenant
+		if callerTenant := auth.GetTenant(c); callerTenant != "" {
+			if req.Tenant != "" && req.Tenant != callerTenant {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Tenant mismatch with authenticated identity",
+				})
+			}
+			req.Tenant = callerTenant
+		}
+		// Multi-tenant : seule une clé administrateur peut ne pas être rattachée à un tenant
+		if tenantRequired && req.Tenant == "" && req.Role != string(auth.RoleAdmin) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Missing required field: tenant (required for non-admin keys)",
+			})
+		}
+
+		plaintext, keyID, keyHash, err := auth.GenerateAPIKey()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to generate API key")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to generate API key",
+			})
+		}
+
+		key := &auth.APIKey{
+			KeyID:     keyID,
+			KeyHash:   keyHash,
+			Name:      req.Name,
+			UserID:    req.UserID,
+			Role:      req.Role,
+			Tenant:    req.Tenant,
+			CreatedAt: time.Now().UTC(),
+			ExpiresAt: req.ExpiresAt,
+			IsActive:  true,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := db.CreateAPIKey(ctx, key); err != nil {
+			log.Error().Err(err).Msg("Failed to create API key")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to create API key",
+			})
+		}
+
+		logAPIKeyEvent(c, auditLogger, "create", key)
+		log.Info().Str("key_id", key.KeyID).Str("role", key.Role).Str("tenant", key.Tenant).Msg("API key created")
+
+		return c.Status(fiber.StatusCreated).JSON(APIKeySecretResponse{
+			APIKey:  key,
+			Secret:  plaintext,
+			Message: apiKeyShownOnceMessage,
+		})
+	}
+}
+
+// APIKeysListHandler gère l'endpoint GET /api/v1/admin/api-keys
+// Les hash et clés en clair ne sont jamais exposés
+func APIKeysListHandler(db *storage.DB, log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		keys, err := db.ListAPIKeys(ctx, auth.GetTenant(c))
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list API keys")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to list API keys",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"api_keys": keys,
+			"total":    len(keys),
+		})
+	}
+}
+
+// APIKeysRevokeHandler gère l'endpoint DELETE /api/v1/admin/api-keys/:key_id
+func APIKeysRevokeHandler(db *storage.DB, authService *auth.AuthService, log *zerolog.Logger, auditLogger *audit.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		key, status, err := getManagedAPIKey(ctx, c, db)
+		if err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		if err := db.RevokeAPIKey(ctx, key.KeyID); err != nil {
+			log.Error().Err(err).Str("key_id", key.KeyID).Msg("Failed to revoke API key")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to revoke API key",
+			})
+		}
+		if authService != nil {
+			authService.InvalidateAPIKey(key.KeyID)
+		}
+
+		logAPIKeyEvent(c, auditLogger, "revoke", key)
+		log.Info().Str("key_id", key.KeyID).Msg("API key revoked")
+
+		return c.JSON(fiber.Map{
+			"key_id":  key.KeyID,
+			"revoked": true,
+		})
+	}
+}
+
+// APIKeysRotateHandler gère l'endpoint POST /api/v1/admin/api-keys/:key_id/rotate
+// Une nouvelle clé (mêmes rôle, tenant et expiration) remplace l'ancienne, révoquée
+func APIKeysRotateHandler(db *storage.DB, authService *auth.AuthService, log *zerolog.Logger, auditLogger *audit.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		old, status, err := getManagedAPIKey(ctx, c, db)
+		if err != nil {
+			return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+		}
+		if !old.IsActive {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "API key is not active",
+			})
+		}
+
+		plaintext, keyID, keyHash, err := auth.GenerateAPIKey()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to generate API key")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to generate API key",
+			})
+		}
+
+		newKey := &auth.APIKey{
+			KeyID:     keyID,
+			KeyHash:   keyHash,
+			CreatedAt: time.Now().UTC(),
+		}
+		if err := db.RotateAPIKey(ctx, old.KeyID, newKey); err != nil {
+			log.Error().Err(err).Str("key_id", old.KeyID).Msg("Failed to rotate API key")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to rotate API key",
+			})
+		}
+		if authService != nil {
+			authService.InvalidateAPIKey(old.KeyID)
+		}
+
+		logAPIKeyEvent(c, auditLogger, "rotate", newKey, "rotated_from", old.KeyID)
+		log.Info().Str("key_id", newKey.KeyID).Str("rotated_from", old.KeyID).Msg("API key rotated")
+
+		return c.JSON(APIKeySecretResponse{
+			APIKey:  newKey,
+			Secret:  plaintext,
+			Message: apiKeyShownOnceMessage,
+		})
+	}
+}
+
+// getManagedAPIKey charge la clé :key_id en appliquant le périmètre tenant de l'appelant
+func getManagedAPIKey(ctx context.Context, c *fiber.Ctx, db *storage.DB) (*auth.APIKey, int, error) {
+	key, err := db.GetAPIKey(ctx, c.Params("key_id"))
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		return nil, fiber.StatusNotFound, errors.New("API key not found")
+	}
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, errors.New("Failed to retrieve API key")
+	}
+	if callerTenant := auth.GetTenant(c); callerTenant != "" && key.Tenant != callerTenant {
+		return nil, fiber.StatusNotFound, errors.New("API key not found")
+	}
+	return key, 0, nil
+}
+
+// logAPIKeyEvent trace une opération de gestion de clé API dans l'audit
+func logAPIKeyEvent(c *fiber.Ctx, auditLogger *audit.Logger, action string, key *auth.APIKey, extra ...string) {
+	if auditLogger == nil {
+		return
+	}
+	metadata := map[string]interface{}{
+		"action":  action,
+		"key_id":  key.KeyID,
+		"role":    key.Role,
+		"user_id": key.UserID,
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		metadata[extra[i]] = extra[i+1]
+	}
+	if admin, err := auth.GetUserInfo(c); err == nil {
+		metadata["admin_id"] = admin.UserID
+	}
+	auditLogger.Log(audit.Event{
+		EventType: audit.EventTypeAPIKeyManaged,
+		RequestID: c.Get("X-Request-ID"),
+		Tenant:    key.Tenant,
+		Status:    audit.EventStatusSuccess,
+		Metadata:  metadata,
+	})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// KeyRotateRequest représente le payload de rotation de la clé de signature
+// Sans kid, une nouvelle paire de clés est générée (KID daté)
+type KeyRotateRequest struct {
+	KID string `json:"kid,omitempty"`
+}
+
+// KeysRotateHandler gère l'endpoint POST /api/v1/admin/keys/rotate
+// Les clés retirées restent publiées (JWKS) et utilisables pour la vérification
+// La rotation est inscrite dans le ledger (événement key.rotated) si db est configurée
+// La clé signe les preuves de tous les tenants : un appelant restreint à un tenant est refusé
+func KeysRotateHandler(jwsService *crypto.Service, db *storage.DB, log *zerolog.Logger, auditLogger *audit.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if tenant := auth.GetTenant(c); tenant != "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "The signing key is shared by all tenants and requires a key without tenant",
+			})
+		}
+		if jwsService == nil || jwsService.Rotation() == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Key rotation not configured (JWS_KEYS_DIR)",
+			})
+		}
+		rotation := jwsService.Rotation()
+
+		var req KeyRotateRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":   "Invalid JSON payload",
+					"details": err.Error(),
+				})
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		generated := false
+		if req.KID == "" {
+			req.KID = crypto.NewKIDFromTime(time.Now())
+			if err := rotation.GenerateKey(ctx, req.KID); err != nil {
+				log.Error().Err(err).Str("kid", req.KID).Msg("Failed to generate signing key")
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to generate signing key",
+				})
+			}
+			generated = true
+		} else if err := crypto.ValidateKID(req.KID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		previousKID := rotation.GetCurrentKID()
+		if req.KID == previousKID {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Key is already the current signing key",
+			})
+		}
+
+		if err := rotation.Rotate(req.KID); err != nil {
+			log.Error().Err(err).Str("kid", req.KID).Msg("Failed to rotate signing key")
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Failed to rotate signing key",
+				"details": err.Error(),
+			})
+		}
+
+		adminID := ""
+		admin, adminErr := auth.GetUserInfo(c)
+		if adminErr == nil {
+			adminID = admin.UserID
+		}
+
+		// La clé est déjà en service : un échec d'inscription est journalisé sans annuler la rotation
+		if db != nil {
+			_, err := db.AppendLedgerEvent(ctx, "", ledger.Event{
+				Type: ledger.EntryTypeKeyRotated,
+				Body: ledger.KeyRotatedBody{NewKID: req.KID, PreviousKID: previousKID, Generated: generated, Actor: adminID},
+			})
+			if err != nil {
+				log.Error().Err(err).Str("kid", req.KID).Msg("Failed to record key rotation in ledger")
+			}
+		}
+
+		if auditLogger != nil {
+			metadata := map[string]interface{}{
+				"previous_kid": previousKID,
+				"new_kid":      req.KID,
+				"generated":    generated,
+			}
+			if adminErr == nil {
+				metadata["admin_id"] = adminID
+			}
+			auditLogger.Log(audit.Event{
+				EventType: audit.EventTypeKeyRotated,
+				RequestID: c.Get("X-Request-ID"),
+				Status:    audit.EventStatusSuccess,
+				Metadata:  metadata,
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"previous_kid":  previousKID,
+			"current_kid":   req.KID,
+			"generated":     generated,
+			"next_rotation": rotation.GetNextRotationDate(),
+		})
+	}
+}
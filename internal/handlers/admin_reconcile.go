@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/metrics"
+	"github.com/doreviateam/dorevia-vault/internal/reconcile"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// ReconcileRequest représente le payload de réconciliation
+// Sans fix, la réconciliation se limite à la détection (dry-run)
+type ReconcileRequest struct {
+	Fix bool `json:"fix"`
+}
+
+// ReconcileResponse représente la réponse de l'endpoint de réconciliation
+type ReconcileResponse struct {
+	*reconcile.ReconciliationReport
+	SignedReport *string `json:"signed_report,omitempty"` // JWS du rapport (SHA256 du rapport JSON)
+}
+
+// ReconcileHandler gère l'endpoint POST /api/v1/admin/reconcile
+// Réconciliation complète (reconcile.Reconcile) : orphelins, documents sans preuve,
+// entrées du ledger sans document, ledger_hash incohérents et preuves JWS invalides.
+// En mode fix, les documents orphelins sont marqués et les documents sans preuve scellés
+// après coup. Le rapport est signé si le service JWS est disponible.
+// La réconciliation porte sur tous les tenants : un appelant rattaché à un tenant est refusé
+func ReconcileHandler(db *storage.DB, opts reconcile.Options, jwsService *crypto.Service, log *zerolog.Logger, auditLogger *audit.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+		if tenant := auth.GetTenant(c); tenant != "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Reconciliation spans all tenants and requires a key without tenant",
+			})
+		}
+
+		var req ReconcileRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":   "Invalid JSON payload",
+					"details": err.Error(),
+				})
+			}
+		}
+		opts.DryRun = !req.Fix
+
+		// Parcours complet du stockage et du ledger
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+
+		startTime := time.Now()
+		report, err := reconcile.Reconcile(ctx, db, opts)
+		if err != nil {
+			metrics.RecordReconciliationRun("error")
+			log.Error().Err(err).Msg("Reconciliation failed")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Reconciliation failed",
+				"details": err.Error(),
+			})
+		}
+
+		status := audit.EventStatusSuccess
+		if len(report.Errors) > 0 {
+			status = audit.EventStatusError
+		}
+		metrics.RecordReconciliationRun(string(status))
+
+		response := &ReconcileResponse{ReconciliationReport: report}
+		if jwsService != nil {
+			jws, err := jwsService.SignReport(reconcile.ReportSubject, report, time.Now())
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to sign reconciliation report")
+			} else {
+				response.SignedReport = &jws
+			}
+		} else {
+			log.Warn().Msg("JWS service not available, reconciliation report returned unsigned")
+		}
+
+		log.Info().
+			Bool("dry_run", report.DryRun).
+			Int("orphan_files", len(report.OrphanFiles)).
+			Int("orphan_dbs", len(report.OrphanDBs)).
+			Int("unsealed", report.UnsealedCount).
+			Int("sealed", report.DocumentsSealed).
+			Int("dangling_ledger", report.DanglingLedgerCount).
+			Int("ledger_hash_mismatches", report.LedgerHashMismatchCount).
+			Int("invalid_evidence", report.InvalidEvidenceCount).
+			Int("errors", len(report.Errors)).
+			Msg("Reconciliation completed")
+
+		if auditLogger != nil {
+			metadata := map[string]interface{}{
+				"dry_run":                report.DryRun,
+				"orphan_files_found":     len(report.OrphanFiles),
+				"orphan_files_fixed":     report.FilesDeleted,
+				"orphan_dbs_found":       len(report.OrphanDBs),
+				"orphan_dbs_marked":      report.DBsMarked,
+				"unsealed_found":         report.UnsealedCount,
+				"documents_fixed":        report.DocumentsSealed,
+				"dangling_ledger":        report.DanglingLedgerCount,
+				"ledger_hash_mismatches": report.LedgerHashMismatchCount,
+				"invalid_evidence":       report.InvalidEvidenceCount,
+			}
+			if admin, err := auth.GetUserInfo(c); err == nil {
+				metadata["admin_id"] = admin.UserID
+			}
+			auditLogger.Log(audit.Event{
+				EventType:  audit.EventTypeReconciliationRun,
+				RequestID:  c.Get("X-Request-ID"),
+				Status:     status,
+				DurationMS: int64(time.Since(startTime).Milliseconds()),
+				Metadata:   metadata,
+			})
+		}
+
+		return c.JSON(response)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// DocumentStatusRequest représente le payload de changement de statut d'un document
+// Au moins un des deux champs est requis
+type DocumentStatusRequest struct {
+	DispatchStatus *string `json:"dispatch_status,omitempty"` // PENDING|SENT|ACK|REJECTED
+	OdooState      *string `json:"odoo_state,omitempty"`
+}
+
+// DocumentStatusHandler gère l'endpoint PATCH /api/v1/documents/:id/status
+// Chaque champ modifié est inscrit dans le ledger (événement document.status_changed)
+// dans la même transaction que la mise à jour du document
+func DocumentStatusHandler(db *storage.DB, log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid document ID",
+			})
+		}
+
+		var req DocumentStatusRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid JSON payload",
+				"details": err.Error(),
+			})
+		}
+		if req.DispatchStatus == nil && req.OdooState == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "dispatch_status or odoo_state is required",
+			})
+		}
+
+		update := storage.StatusUpdate{
+			DispatchStatus: req.DispatchStatus,
+			OdooState:      req.OdooState,
+			Tenant:         auth.GetTenant(c),
+		}
+		if user, err := auth.GetUserInfo(c); err == nil {
+			update.Actor = user.UserID
+			if update.Actor == "" {
+				update.Actor = user.KeyID
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		doc, err := db.UpdateDocumentStatus(ctx, id, update)
+		var transition storage.ErrInvalidStatusTransition
+		switch {
+		case errors.Is(err, storage.ErrDocumentNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Document not found",
+			})
+		case errors.As(err, &transition):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": transition.Error(),
+			})
+		case err != nil:
+			log.Error().Err(err).Str("document_id", id.String()).Msg("Failed to update document status")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update document status",
+			})
+		}
+
+		return c.JSON(doc)
+	}
+}
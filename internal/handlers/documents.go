@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/doreviateam/dorevia-vault/internal/auth"
 	"github.com/doreviateam/dorevia-vault/internal/models"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
 	"github.com/gofiber/fiber/v2"
@@ -64,6 +65,9 @@ func DocumentsListHandler(db *storage.DB) fiber.Handler {
 			}
 		}
 
+		// Multi-tenant : le périmètre est imposé par l'identité, pas par la requête
+		query.Tenant = auth.GetTenant(c)
+
 		// Récupérer les documents
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -127,6 +131,13 @@ func DocumentByIDHandler(db *storage.DB) fiber.Handler {
 			})
 		}
 
+		// Multi-tenant : un document d'un autre tenant est traité comme inexistant
+		if !auth.CanAccessTenant(c, doc.Tenant) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Document not found",
+			})
+		}
+
 		return c.JSON(doc)
 	}
 }
@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -45,10 +47,31 @@ func DownloadHandler(db *storage.DB) fiber.Handler {
 			})
 		}
 
-		// Vérifier que le fichier existe
-		if _, err := os.Stat(doc.StoredPath); os.IsNotExist(err) {
+		// Multi-tenant : un document d'un autre tenant est traité comme inexistant
+		if !auth.CanAccessTenant(c, doc.Tenant) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "File not found on disk",
+				"error": "Document not found",
+			})
+		}
+
+		// Rétention échue : seule la pierre tombale (métadonnées, SHA256) subsiste
+		if doc.PurgedAt != nil {
+			return c.Status(fiber.StatusGone).JSON(fiber.Map{
+				"error":     "Document content purged",
+				"purged_at": doc.PurgedAt,
+			})
+		}
+
+		// Ouvrir le contenu (système de fichiers ou stockage objet)
+		content, err := db.OpenDocumentContent(context.Background(), doc)
+		if err != nil {
+			if errors.Is(err, blobstore.ErrNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "File not found in storage",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to open document content",
 			})
 		}
 
@@ -64,12 +87,13 @@ func DownloadHandler(db *storage.DB) fiber.Handler {
 
 			// Vérifier If-None-Match pour 304 Not Modified
 			if match := c.Get("If-None-Match"); match == etag {
+				content.Close()
 				return c.SendStatus(fiber.StatusNotModified)
 			}
 		}
 
-		// Envoyer le fichier
-		return c.SendFile(doc.StoredPath)
+		// Envoyer le contenu (fermé par fasthttp en fin d'envoi)
+		return c.SendStream(content, int(doc.SizeBytes))
 	}
 }
 
@@ -1,20 +1,25 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
 	"github.com/doreviateam/dorevia-vault/internal/config"
-	"github.com/doreviateam/dorevia-vault/internal/crypto"
-	"github.com/doreviateam/dorevia-vault/internal/ledger"
-	"github.com/doreviateam/dorevia-vault/internal/metrics"
+	"github.com/doreviateam/dorevia-vault/internal/middleware"
 	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/services"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
 	"github.com/doreviateam/dorevia-vault/internal/validation"
-	"github.com/doreviateam/dorevia-vault/internal/webhooks"
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog"
 )
@@ -41,22 +46,47 @@ type InvoiceResponse struct {
 }
 
 // InvoicesHandler gère l'endpoint POST /api/v1/invoices
-// Intègre JWS + Ledger si configurés
-func InvoicesHandler(db *storage.DB, storageDir string, jwsService *crypto.Service, cfg *config.Config, log *zerolog.Logger, auditLogger *audit.Logger, webhookManager *webhooks.Manager) fiber.Handler {
+// Intègre JWS + Ledger si configurés. Le document est reçu selon le Content-Type :
+//   - application/json : InvoicePayload, fichier en base64 (documents de taille modeste)
+//   - application/pdf : PDF brut lu en flux, métadonnées en paramètres de requête
+//     (source, model, odoo_id, state, pdp_required, meta en JSON)
+//   - multipart/form-data : mêmes champs et fichier dans la partie "file"
+// Un contenu reçu en flux est haché pendant son écriture dans un fichier temporaire
+func InvoicesHandler(service *services.IngestionService, cfg *config.Config, log *zerolog.Logger) fiber.Handler {
+	vault := &invoiceVault{
+		service: service,
+		cfg:     cfg,
+		log:     log,
+	}
 	return func(c *fiber.Ctx) error {
-		if db == nil {
+		if service == nil {
 			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 				"error": "Database not configured",
 			})
 		}
 
-		// Parser le payload JSON
 		var payload InvoicePayload
-		if err := c.BodyParser(&payload); err != nil {
-			log.Error().Err(err).Msg("Failed to parse invoice payload")
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid JSON payload",
-				"details": err.Error(),
+		var content storage.Content
+		var err error
+		switch contentType := strings.ToLower(c.Get(fiber.HeaderContentType)); {
+		case strings.HasPrefix(contentType, fiber.MIMEMultipartForm):
+			payload, content, err = readMultipartInvoice(c, service, cfg)
+		case strings.HasPrefix(contentType, "application/pdf"), strings.HasPrefix(contentType, fiber.MIMEOctetStream):
+			payload, content, err = readRawInvoice(c, service, cfg)
+		default:
+			payload, content, err = readJSONInvoice(c)
+		}
+		if spooled, ok := content.(*storage.SpooledContent); ok {
+			defer spooled.Remove()
+		}
+		if err != nil {
+			var reqErr *invoiceRequestError
+			if errors.As(err, &reqErr) {
+				return c.Status(reqErr.status).JSON(reqErr.body)
+			}
+			log.Error().Err(err).Msg("Failed to read invoice document")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to read invoice document",
 			})
 		}
 
@@ -71,296 +101,367 @@ func InvoicesHandler(db *storage.DB, storageDir string, jwsService *crypto.Servi
 				"error": "Missing required field: model",
 			})
 		}
-		if payload.File == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Missing required field: file",
-			})
-		}
 
-		// Décoder le fichier base64
-		fileContent, err := base64.StdEncoding.DecodeString(payload.File)
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to decode base64 file")
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid base64 file encoding",
-				"details": err.Error(),
-			})
-		}
+		return vault.store(c, payload, content)
+	}
+}
 
-		// Validation Factur-X (Sprint 5 Phase 5.3)
-		var facturXResult *validation.ValidationResult
-		if cfg.FacturXValidationEnabled {
-			validator := validation.NewFacturXValidator(*log)
-			contentType := "application/pdf" // Par défaut, peut être détecté depuis meta
-			if payload.Meta != nil {
-				if ct, ok := payload.Meta["content_type"].(string); ok {
-					contentType = ct
-				}
-			}
-			
-			result, err := validator.Validate(fileContent, contentType)
-			if err != nil {
-				log.Warn().Err(err).Msg("Factur-X validation error")
-			} else {
-				facturXResult = result
-				if !result.Valid {
-					log.Warn().
-						Strs("errors", result.Errors).
-						Msg("Factur-X validation failed")
-					// Retourner erreur si validation requise
-					if cfg.FacturXValidationRequired {
-						return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-							"error": "Factur-X validation failed",
-							"validation_errors": result.Errors,
-							"validation_warnings": result.Warnings,
-						})
-					}
-				} else {
-					log.Info().Msg("Factur-X validation successful")
-				}
-			}
+// invoiceRequestError est une requête de facture invalide (réponse 4xx)
+type invoiceRequestError struct {
+	status int
+	body   fiber.Map
+}
+
+func (e *invoiceRequestError) Error() string {
+	return fmt.Sprintf("invalid invoice request: %v", e.body["error"])
+}
+
+// readJSONInvoice lit le payload JSON, fichier en base64. Un corps au-delà de la limite
+// des corps en mémoire (BodyLimit de Fiber) est refusé : il doit être envoyé en flux
+func readJSONInvoice(c *fiber.Ctx) (InvoicePayload, storage.Content, error) {
+	var payload InvoicePayload
+	if err := middleware.BufferBody(c, c.App().Config().BodyLimit); err != nil {
+		if errors.Is(err, fiber.ErrRequestEntityTooLarge) {
+			return payload, nil, &invoiceRequestError{status: fiber.StatusRequestEntityTooLarge, body: fiber.Map{
+				"error":   "JSON payload too large",
+				"details": "send the document as application/pdf, multipart/form-data or through /api/v1/uploads",
+			}}
 		}
+		return payload, nil, &invoiceRequestError{status: fiber.StatusBadRequest, body: fiber.Map{
+			"error":   "Failed to read request body",
+			"details": err.Error(),
+		}}
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return payload, nil, &invoiceRequestError{status: fiber.StatusBadRequest, body: fiber.Map{
+			"error":   "Invalid JSON payload",
+			"details": err.Error(),
+		}}
+	}
+	if payload.Source == "" || payload.Model == "" {
+		return payload, nil, nil // champ manquant signalé par l'appelant
+	}
+	if payload.File == "" {
+		return payload, nil, &invoiceRequestError{status: fiber.StatusBadRequest, body: fiber.Map{
+			"error": "Missing required field: file",
+		}}
+	}
 
-		// Extraire le nom de fichier depuis meta ou utiliser un nom par défaut
-		filename := "document.pdf"
-		if payload.Meta != nil {
-			if number, ok := payload.Meta["number"].(string); ok && number != "" {
-				filename = fmt.Sprintf("%s.pdf", number)
-			}
+	// Décoder le fichier base64
+	fileContent, err := base64.StdEncoding.DecodeString(payload.File)
+	if err != nil {
+		return payload, nil, &invoiceRequestError{status: fiber.StatusBadRequest, body: fiber.Map{
+			"error":   "Invalid base64 file encoding",
+			"details": err.Error(),
+		}}
+	}
+	payload.File = ""
+	return payload, storage.BytesContent(fileContent), nil
+}
+
+// readRawInvoice lit un PDF brut en flux, métadonnées en paramètres de requête
+func readRawInvoice(c *fiber.Ctx, service *services.IngestionService, cfg *config.Config) (InvoicePayload, storage.Content, error) {
+	payload, err := invoicePayloadFromValues(c.Query)
+	if err != nil {
+		return payload, nil, err
+	}
+
+	var body io.Reader = c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+	content, err := spoolInvoice(body, service, cfg)
+	return payload, content, err
+}
+
+// readMultipartInvoice lit les champs du formulaire et le fichier de la partie "file"
+func readMultipartInvoice(c *fiber.Ctx, service *services.IngestionService, cfg *config.Config) (InvoicePayload, storage.Content, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return InvoicePayload{}, nil, &invoiceRequestError{status: fiber.StatusBadRequest, body: fiber.Map{
+			"error":   "Invalid multipart payload",
+			"details": err.Error(),
+		}}
+	}
+	payload, err := invoicePayloadFromValues(func(key string, _ ...string) string {
+		if values := form.Value[key]; len(values) > 0 {
+			return values[0]
 		}
+		return ""
+	})
+	if err != nil {
+		return payload, nil, err
+	}
 
-		// Construire le document
-		doc := &models.Document{
-			Filename:    filename,
-			ContentType: "application/pdf", // Par défaut, peut être amélioré avec détection MIME
-			SizeBytes:   int64(len(fileContent)),
-			Source:     &payload.Source,
-			OdooModel:    &payload.Model,
-			OdooID:       &payload.OdooID,
-			OdooState:    &payload.State,
-			PDPRequired:  &payload.PDPRequired,
+	files := form.File["file"]
+	if len(files) == 0 {
+		return payload, nil, &invoiceRequestError{status: fiber.StatusBadRequest, body: fiber.Map{
+			"error": "Missing required field: file",
+		}}
+	}
+	src, err := files[0].Open()
+	if err != nil {
+		return payload, nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
+	content, err := spoolInvoice(src, service, cfg)
+	return payload, content, err
+}
+
+// invoicePayloadFromValues construit le payload depuis des champs texte (paramètres de
+// requête ou formulaire)
+func invoicePayloadFromValues(value func(key string, defaultValue ...string) string) (InvoicePayload, error) {
+	payload := InvoicePayload{
+		Source: value("source"),
+		Model:  value("model"),
+		State:  value("state"),
+	}
+	invalid := func(field string, err error) error {
+		return &invoiceRequestError{status: fiber.StatusBadRequest, body: fiber.Map{
+			"error":   "Invalid field: " + field,
+			"details": err.Error(),
+		}}
+	}
+	if s := value("odoo_id"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return payload, invalid("odoo_id", err)
+		}
+		payload.OdooID = id
+	}
+	if s := value("pdp_required"); s != "" {
+		required, err := strconv.ParseBool(s)
+		if err != nil {
+			return payload, invalid("pdp_required", err)
 		}
+		payload.PDPRequired = required
+	}
+	if s := value("meta"); s != "" {
+		if err := json.Unmarshal([]byte(s), &payload.Meta); err != nil {
+			return payload, invalid("meta", err)
+		}
+	}
+	return payload, nil
+}
 
-		// Définir dispatch_status par défaut
-		defaultStatus := "PENDING"
-		doc.DispatchStatus = &defaultStatus
-
-		// Extraire les métadonnées facture
-		// Priorité : métadonnées Factur-X validées > métadonnées payload
-		if facturXResult != nil && facturXResult.Metadata != nil {
-			// Utiliser les métadonnées extraites de Factur-X
-			meta := facturXResult.Metadata
-			doc.InvoiceNumber = &meta.InvoiceNumber
-			doc.InvoiceDate = &meta.InvoiceDate
-			// Note: DueDate n'est pas stocké dans le modèle Document actuellement
-			doc.TotalHT = &meta.TotalHT
-			doc.TotalTTC = &meta.TotalTTC
-			doc.Currency = &meta.Currency
-			doc.SellerVAT = &meta.SellerVAT
-			doc.BuyerVAT = &meta.BuyerVAT
-		} else if payload.Meta != nil {
-			// Fallback vers métadonnées payload si Factur-X non disponible
-			if number, ok := payload.Meta["number"].(string); ok {
-				doc.InvoiceNumber = &number
-			}
-			if dateStr, ok := payload.Meta["invoice_date"].(string); ok {
-				if date, err := time.Parse("2006-01-02", dateStr); err == nil {
-					doc.InvoiceDate = &date
-				}
-			}
-			if totalHT, ok := payload.Meta["total_ht"].(float64); ok {
-				doc.TotalHT = &totalHT
-			}
-			if totalTTC, ok := payload.Meta["total_ttc"].(float64); ok {
-				doc.TotalTTC = &totalTTC
-			}
-			if currency, ok := payload.Meta["currency"].(string); ok {
-				doc.Currency = &currency
-			}
-			if sellerVAT, ok := payload.Meta["seller_vat"].(string); ok {
-				doc.SellerVAT = &sellerVAT
-			}
-			if buyerVAT, ok := payload.Meta["buyer_vat"].(string); ok {
-				doc.BuyerVAT = &buyerVAT
+// spoolInvoice écrit le document reçu en flux dans un fichier temporaire chiffré
+// (UPLOAD_MAX_SIZE_MB)
+func spoolInvoice(r io.Reader, service *services.IngestionService, cfg *config.Config) (storage.Content, error) {
+	maxBytes := int64(cfg.UploadMaxSizeMB) << 20
+	content, err := storage.SpoolContent(r, service.SpoolDir(), maxBytes)
+	if errors.Is(err, storage.ErrContentTooLarge) {
+		return nil, &invoiceRequestError{status: fiber.StatusRequestEntityTooLarge, body: fiber.Map{
+			"error":       "Document too large",
+			"max_size_mb": cfg.UploadMaxSizeMB,
+		}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if content.Size() == 0 {
+		content.Remove()
+		return nil, &invoiceRequestError{status: fiber.StatusBadRequest, body: fiber.Map{
+			"error": "Missing required field: file",
+		}}
+	}
+	return content, nil
+}
+
+// invoiceDocumentIDKey porte l'identifiant du document enregistré (ou déjà présent) par
+// invoiceVault.store, pour l'appelant qui doit le conserver (upload reprenable)
+const invoiceDocumentIDKey = "invoice_document_id"
+
+// invoiceVault regroupe les dépendances de l'enregistrement d'une facture, quel que soit
+// son mode de réception (JSON, flux, upload reprenable)
+type invoiceVault struct {
+	service *services.IngestionService
+	cfg     *config.Config
+	log     *zerolog.Logger
+}
+
+// store enregistre la facture : validation Factur-X puis scellement par le service
+// d'ingestion (clôtures, JWS + Ledger, audit et webhook)
+func (v *invoiceVault) store(c *fiber.Ctx, payload InvoicePayload, content storage.Content) error {
+	cfg, log := v.cfg, v.log
+
+	// Validation Factur-X (Sprint 5 Phase 5.3)
+	// Validation en mémoire : au-delà de FACTURX_VALIDATION_MAX_MB elle est ignorée,
+	// ou la facture refusée si la validation est requise
+	var facturXResult *validation.ValidationResult
+	maxValidationBytes := int64(cfg.FacturXValidationMaxMB) << 20
+	if cfg.FacturXValidationEnabled && maxValidationBytes > 0 && content.Size() > maxValidationBytes {
+		if cfg.FacturXValidationRequired {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error":       "Document too large for Factur-X validation",
+				"max_size_mb": cfg.FacturXValidationMaxMB,
+			})
+		}
+		log.Warn().Int64("size_bytes", content.Size()).Msg("Document too large for Factur-X validation, skipped")
+	} else if cfg.FacturXValidationEnabled {
+		validator := validation.NewFacturXValidator(*log)
+		contentType := "application/pdf" // Par défaut, peut être détecté depuis meta
+		if payload.Meta != nil {
+			if ct, ok := payload.Meta["content_type"].(string); ok {
+				contentType = ct
 			}
 		}
-
-		// Stocker le document avec JWS + Ledger (si configurés)
-		ctx := context.Background()
-		startTime := time.Now() // Sprint 3 Phase 2 : Mesure durée transaction
 		
-		// Utiliser StoreDocumentWithEvidence si JWS ou Ledger activés
-		if (cfg.JWSEnabled && jwsService != nil) || cfg.LedgerEnabled {
-			err = db.StoreDocumentWithEvidence(ctx, doc, fileContent, storageDir, jwsService, cfg.JWSEnabled, cfg.JWSRequired, cfg.LedgerEnabled)
-		} else {
-			// Fallback vers méthode simple (Sprint 1)
-			err = db.StoreDocumentWithTransaction(ctx, doc, fileContent, storageDir)
+		fileContent, err := storage.ReadContent(content)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read document content")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to read document content",
+			})
 		}
-
-		// Mesurer durée transaction (Sprint 3 Phase 2)
-		transactionDuration := time.Since(startTime).Seconds()
-		metrics.RecordTransactionDuration(transactionDuration)
-
-		// Gérer l'idempotence (document déjà existant)
+		result, err := validator.Validate(fileContent, contentType)
 		if err != nil {
-			if docExistsErr, ok := err.(storage.ErrDocumentExists); ok {
-				// Récupérer les informations du document existant
-				existingDoc, err := db.GetDocumentByID(ctx, docExistsErr.ID)
-				if err != nil {
-					log.Error().Err(err).Msg("Failed to retrieve existing document")
-					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-						"error": "Failed to retrieve existing document",
+			log.Warn().Err(err).Msg("Factur-X validation error")
+		} else {
+			facturXResult = result
+			if !result.Valid {
+				log.Warn().
+					Strs("errors", result.Errors).
+					Msg("Factur-X validation failed")
+				// Retourner erreur si validation requise
+				if cfg.FacturXValidationRequired {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+						"error": "Factur-X validation failed",
+						"validation_errors": result.Errors,
+						"validation_warnings": result.Warnings,
 					})
 				}
+			} else {
+				log.Info().Msg("Factur-X validation successful")
+			}
+		}
+	}
 
-				// Document déjà existant - retourner 200 OK avec les infos existantes
-				log.Info().
-					Str("document_id", existingDoc.ID.String()).
-					Str("sha256", existingDoc.SHA256Hex).
-					Msg("Document already exists (idempotence)")
+	// Extraire le nom de fichier depuis meta ou utiliser un nom par défaut
+	// (numéro fourni par l'appelant : réduit à un nom sans séparateur de chemin)
+	filename := "document.pdf"
+	if payload.Meta != nil {
+		if number, ok := payload.Meta["number"].(string); ok && number != "" {
+			filename = fmt.Sprintf("%s.pdf", blobstore.SafeFilename(number))
+		}
+	}
 
-				// Métrique : document idempotent (Sprint 3 Phase 2)
-				source := "unknown"
-				if payload.Source != "" {
-					source = payload.Source
-				}
-				metrics.RecordDocumentVaulted("idempotent", source)
-
-				// Audit : document idempotent (Sprint 4 Phase 4.2)
-				if auditLogger != nil {
-					requestID := c.Get("X-Request-ID")
-					auditLogger.Log(audit.Event{
-						EventType:  audit.EventTypeDocumentVaulted,
-						DocumentID: existingDoc.ID.String(),
-						RequestID:  requestID,
-						Source:     source,
-						Status:     audit.EventStatusIdempotent,
-						DurationMS: int64(time.Since(startTime).Milliseconds()),
-						Metadata: map[string]interface{}{
-							"sha256_hex": existingDoc.SHA256Hex,
-							"odoo_id":    payload.OdooID,
-							"model":      payload.Model,
-						},
-					})
-				}
+	// Construire le document
+	doc := &models.Document{
+		Filename:    filename,
+		ContentType: "application/pdf", // Par défaut, peut être amélioré avec détection MIME
+		SizeBytes:   content.Size(),
+		Source:     &payload.Source,
+		OdooModel:    &payload.Model,
+		OdooID:       &payload.OdooID,
+		OdooState:    &payload.State,
+		PDPRequired:  &payload.PDPRequired,
+	}
 
-				// Vérifier si ledger existe pour document existant (idempotence renforcée)
-				var hasLedger bool
-				if cfg.LedgerEnabled && db != nil {
-					tx, _ := db.Pool.Begin(ctx)
-					if tx != nil {
-						hasLedger, _ = ledger.ExistsByDocumentID(ctx, tx, existingDoc.ID)
-						tx.Rollback(ctx)
-					}
-					// Si document existe mais pas de ledger, compléter le ledger
-					if !hasLedger && jwsService != nil {
-						// TODO: Compléter le ledger si nécessaire (optionnel)
-					}
-				}
+	// Multi-tenant : le document est rattaché au tenant de l'appelant
+	if tenant := auth.GetTenant(c); tenant != "" {
+		doc.Tenant = &tenant
+	}
 
-				return c.Status(fiber.StatusOK).JSON(InvoiceResponse{
-					ID:          existingDoc.ID.String(),
-					SHA256Hex:   existingDoc.SHA256Hex,
-					CreatedAt:   existingDoc.CreatedAt,
-					EvidenceJWS: existingDoc.EvidenceJWS,
-					LedgerHash:  existingDoc.LedgerHash,
-					Message:     "Document already exists",
-				})
-			}
+	// Définir dispatch_status par défaut
+	defaultStatus := "PENDING"
+	doc.DispatchStatus = &defaultStatus
 
-			// Métrique : erreur de stockage (Sprint 3 Phase 2)
-			source := "unknown"
-			if payload.Source != "" {
-				source = payload.Source
-			}
-			metrics.RecordDocumentVaulted("error", source)
-
-			// Audit : erreur de stockage (Sprint 4 Phase 4.2)
-			if auditLogger != nil {
-				requestID := c.Get("X-Request-ID")
-				auditLogger.Log(audit.Event{
-					EventType:  audit.EventTypeDocumentVaulted,
-					RequestID:  requestID,
-					Source:     source,
-					Status:     audit.EventStatusError,
-					DurationMS: int64(time.Since(startTime).Milliseconds()),
-					Metadata: map[string]interface{}{
-						"error": err.Error(),
-						"model": payload.Model,
-					},
-				})
+	// Extraire les métadonnées facture
+	// Priorité : métadonnées Factur-X validées > métadonnées payload
+	if facturXResult != nil && facturXResult.Metadata != nil {
+		// Utiliser les métadonnées extraites de Factur-X
+		meta := facturXResult.Metadata
+		doc.InvoiceNumber = &meta.InvoiceNumber
+		doc.InvoiceDate = &meta.InvoiceDate
+		// Note: DueDate n'est pas stocké dans le modèle Document actuellement
+		doc.TotalHT = &meta.TotalHT
+		doc.TotalTTC = &meta.TotalTTC
+		doc.Currency = &meta.Currency
+		doc.SellerVAT = &meta.SellerVAT
+		doc.BuyerVAT = &meta.BuyerVAT
+	} else if payload.Meta != nil {
+		// Fallback vers métadonnées payload si Factur-X non disponible
+		if number, ok := payload.Meta["number"].(string); ok {
+			doc.InvoiceNumber = &number
+		}
+		if dateStr, ok := payload.Meta["invoice_date"].(string); ok {
+			if date, err := time.Parse("2006-01-02", dateStr); err == nil {
+				doc.InvoiceDate = &date
 			}
-
-			log.Error().Err(err).Msg("Failed to store document")
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to store document",
-				"details": err.Error(),
-			})
 		}
-
-		// Métrique : succès de stockage (Sprint 3 Phase 2)
-		source := "unknown"
-		if payload.Source != "" {
-			source = payload.Source
+		if totalHT, ok := payload.Meta["total_ht"].(float64); ok {
+			doc.TotalHT = &totalHT
 		}
-		metrics.RecordDocumentVaulted("success", source)
-
-		// Audit : succès de stockage (Sprint 4 Phase 4.2)
-		if auditLogger != nil {
-			requestID := c.Get("X-Request-ID")
-			auditLogger.Log(audit.Event{
-				EventType:  audit.EventTypeDocumentVaulted,
-				DocumentID: doc.ID.String(),
-				RequestID:  requestID,
-				Source:     source,
-				Status:     audit.EventStatusSuccess,
-				DurationMS: int64(time.Since(startTime).Milliseconds()),
-				Metadata: map[string]interface{}{
-					"sha256_hex":    doc.SHA256Hex,
-					"filename":      filename,
-					"size_bytes":    len(fileContent),
-					"odoo_id":       payload.OdooID,
-					"model":         payload.Model,
-					"evidence_jws":  doc.EvidenceJWS != nil,
-					"ledger_hash":   doc.LedgerHash != nil,
-				},
-			})
+		if totalTTC, ok := payload.Meta["total_ttc"].(float64); ok {
+			doc.TotalTTC = &totalTTC
 		}
+		if currency, ok := payload.Meta["currency"].(string); ok {
+			doc.Currency = &currency
+		}
+		if sellerVAT, ok := payload.Meta["seller_vat"].(string); ok {
+			doc.SellerVAT = &sellerVAT
+		}
+		if buyerVAT, ok := payload.Meta["buyer_vat"].(string); ok {
+			doc.BuyerVAT = &buyerVAT
+		}
+	}
 
-		// Webhook : document.vaulted (Sprint 5 Phase 5.3)
-		if webhookManager != nil {
-			webhookPayload := map[string]interface{}{
-				"document_id":   doc.ID.String(),
-				"sha256_hex":    doc.SHA256Hex,
-				"filename":      filename,
-				"size_bytes":    len(fileContent),
-				"created_at":    doc.CreatedAt,
-				"evidence_jws":  doc.EvidenceJWS != nil,
-				"ledger_hash":    doc.LedgerHash != nil,
-				"odoo_id":        payload.OdooID,
-				"model":          payload.Model,
-				"source":         source,
-			}
-			if err := webhookManager.EmitEvent(ctx, webhooks.EventTypeDocumentVaulted, doc.ID.String(), webhookPayload); err != nil {
-				log.Warn().Err(err).Msg("Failed to emit webhook event")
-			}
+	// Stocker le document avec JWS + Ledger (si configurés)
+	result, err := v.service.Ingest(context.Background(), services.IngestInput{
+		Document:     doc,
+		Content:      content,
+		BusinessDate: doc.InvoiceDate, // Clôture (NF525) : facture datée d'une période clôturée refusée
+		RequestID:    c.Get("X-Request-ID"),
+		Metadata: map[string]interface{}{
+			"odoo_id": payload.OdooID,
+			"model":   payload.Model,
+		},
+	})
+	if err != nil {
+		var closedErr storage.ErrPeriodClosed
+		if errors.As(err, &closedErr) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":  "Accounting period closed",
+				"period": closedErr.Period,
+			})
 		}
 
-		// Succès - retourner 201 Created
-		log.Info().
-			Str("document_id", doc.ID.String()).
-			Str("sha256", doc.SHA256Hex).
-			Int("odoo_id", payload.OdooID).
-			Msg("Document vaulted successfully")
-
-		return c.Status(fiber.StatusCreated).JSON(InvoiceResponse{
-			ID:          doc.ID.String(),
-			SHA256Hex:   doc.SHA256Hex,
-			CreatedAt:   doc.CreatedAt,
-			EvidenceJWS: doc.EvidenceJWS,
-			LedgerHash:  doc.LedgerHash,
+		log.Error().Err(err).Msg("Failed to store document")
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store document",
+			"details": err.Error(),
+		})
+	}
+
+	// Gérer l'idempotence (document déjà existant) - retourner 200 OK avec les infos existantes
+	if result.Idempotent {
+		existingDoc := result.Document
+		c.Locals(invoiceDocumentIDKey, existingDoc.ID)
+		return c.Status(fiber.StatusOK).JSON(InvoiceResponse{
+			ID:          existingDoc.ID.String(),
+			SHA256Hex:   existingDoc.SHA256Hex,
+			CreatedAt:   existingDoc.CreatedAt,
+			EvidenceJWS: existingDoc.EvidenceJWS,
+			LedgerHash:  existingDoc.LedgerHash,
+			Message:     "Document already exists",
 		})
 	}
+
+	// Succès - retourner 201 Created
+	log.Info().
+		Str("document_id", doc.ID.String()).
+		Str("sha256", doc.SHA256Hex).
+		Int("odoo_id", payload.OdooID).
+		Msg("Document vaulted successfully")
+
+	c.Locals(invoiceDocumentIDKey, doc.ID)
+	return c.Status(fiber.StatusCreated).JSON(InvoiceResponse{
+		ID:          doc.ID.String(),
+		SHA256Hex:   doc.SHA256Hex,
+		CreatedAt:   doc.CreatedAt,
+		EvidenceJWS: doc.EvidenceJWS,
+		LedgerHash:  doc.LedgerHash,
+	})
 }
 
 // GetInvoice gère GET /api/v1/invoices -> 405 Method Not Allowed
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/doreviateam/dorevia-vault/internal/auth"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog"
@@ -16,6 +17,8 @@ import (
 //   - format: "json" (défaut) ou "csv"
 //   - limit:  nombre de lignes (1..10000, défaut 100)
 //   - offset: décalage (défaut 0)
+//
+// Multi-tenant : un appelant rattaché à un tenant n'exporte que ses entrées
 func LedgerExportHandler(db *storage.DB, log *zerolog.Logger) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		if db == nil {
@@ -43,12 +46,13 @@ func LedgerExportHandler(db *storage.DB, log *zerolog.Logger) fiber.Handler {
 		}
 
 		ctx := context.Background()
+		tenant := auth.GetTenant(c)
 
 		switch format {
 		case "json":
 			c.Set("Content-Type", "application/json")
 
-			rows, err := db.ExportLedger(ctx, limit, offset)
+			rows, err := db.ExportLedger(ctx, tenant, limit, offset)
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to export ledger JSON")
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -64,7 +68,7 @@ func LedgerExportHandler(db *storage.DB, log *zerolog.Logger) fiber.Handler {
 			c.Set("Content-Type", "text/csv")
 			c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=ledger_%d_%d.csv", limit, offset))
 
-			rows, err := db.ExportLedger(ctx, limit, offset)
+			rows, err := db.ExportLedger(ctx, tenant, limit, offset)
 			if err != nil {
 				log.Error().Err(err).Msg("Failed to export ledger CSV")
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -75,14 +79,14 @@ func LedgerExportHandler(db *storage.DB, log *zerolog.Logger) fiber.Handler {
 
 			// Écriture CSV minimaliste
 			w := c.Response().BodyWriter()
-			_, _ = w.Write([]byte("id,document_id,hash,previous_hash,seq,timestamp\n"))
+			_, _ = w.Write([]byte("id,document_id,hash,previous_hash,seq,timestamp,entry_type\n"))
 			for _, r := range rows {
 				prev := ""
 				if r.PrevHash != nil {
 					prev = *r.PrevHash
 				}
 				ts := r.Timestamp.UTC().Format(time.RFC3339)
-				line := fmt.Sprintf("%d,%s,%s,%s,%d,%s\n", r.ID, r.DocumentID, r.Hash, prev, r.Seq, ts)
+				line := fmt.Sprintf("%d,%s,%s,%s,%d,%s,%s\n", r.ID, r.DocumentID, r.Hash, prev, r.Seq, ts, r.EntryType)
 				_, _ = w.Write([]byte(line))
 			}
 			return nil
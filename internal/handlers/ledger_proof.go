@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// LedgerProofHandler gère l'endpoint GET /api/v1/ledger/proof/:document_id
+// Retourne la preuve d'inclusion (chemin d'audit Merkle) de l'entrée du ledger du
+// document dans la racine signée de son jour, vérifiable hors ligne
+// (verify.VerifyInclusionProof) avec le JWKS du coffre
+func LedgerProofHandler(db *storage.DB, log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+
+		docID, err := uuid.Parse(c.Params("document_id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid document ID",
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		// Multi-tenant : un document d'un autre tenant est traité comme inexistant
+		doc, err := db.GetDocumentByID(ctx, docID)
+		if err != nil || !auth.CanAccessTenant(c, doc.Tenant) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Document not found",
+			})
+		}
+
+		proof, err := ledger.LoadInclusionProof(ctx, db.Pool, docID)
+		switch {
+		case errors.Is(err, ledger.ErrNoLedgerEntry):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Document has no ledger entry",
+			})
+		case errors.Is(err, ledger.ErrNoMerkleRoot):
+			// La racine du jour est construite une fois le jour (UTC) révolu
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Ledger entry not yet covered by a signed Merkle root",
+			})
+		case err != nil:
+			log.Error().Err(err).Str("document_id", docID.String()).Msg("Failed to build inclusion proof")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to build inclusion proof",
+			})
+		}
+
+		return c.JSON(proof)
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// CosignatureRequest représente le payload de contre-signature d'une tête par un témoin
+type CosignatureRequest struct {
+	WitnessID string `json:"witness_id"`
+	JWS       string `json:"jws"` // JWS (sujet ledger-tree-head) de la tête, clé du témoin
+}
+
+// LedgerTreeHeadHandler gère les endpoints GET /api/v1/ledger/tree-heads/latest et
+// GET /api/v1/ledger/tree-heads/:id : tête signée du ledger et contre-signatures des témoins.
+// La tête couvre toutes les chaînes : elle n'est pas exposée à un appelant rattaché à un tenant
+func LedgerTreeHeadHandler(db *storage.DB, log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+		if auth.GetTenant(c) != "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Tree heads are not available to tenant-restricted callers",
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		var head *ledger.SignedTreeHead
+		var err error
+		if idParam := c.Params("id"); idParam != "" {
+			id, parseErr := strconv.ParseInt(idParam, 10, 64)
+			if parseErr != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid tree head ID",
+				})
+			}
+			head, err = ledger.LoadTreeHead(ctx, db.Pool, id)
+		} else {
+			head, err = ledger.LoadLatestTreeHead(ctx, db.Pool)
+		}
+		if err != nil {
+			return treeHeadError(c, err, log)
+		}
+
+		return c.JSON(head)
+	}
+}
+
+// LedgerConsistencyHandler gère l'endpoint GET /api/v1/ledger/consistency
+// Params:
+//   - from: id de la tête déjà vue par le témoin (requis)
+//   - to:   id de la tête à vérifier (optionnel, dernière tête par défaut)
+//
+// Retourne les entrées (anonymisées) reliant chaque chaîne de from à sa tête dans to
+func LedgerConsistencyHandler(db *storage.DB, log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+		if auth.GetTenant(c) != "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Tree heads are not available to tenant-restricted callers",
+			})
+		}
+
+		fromID, err := strconv.ParseInt(c.Query("from"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "'from' must be a tree head ID",
+			})
+		}
+		var toID int64
+		if c.Query("to") != "" {
+			toID, err = strconv.ParseInt(c.Query("to"), 10, 64)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "'to' must be a tree head ID",
+				})
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		from, err := ledger.LoadTreeHead(ctx, db.Pool, fromID)
+		if err != nil {
+			return treeHeadError(c, err, log)
+		}
+		var to *ledger.SignedTreeHead
+		if toID != 0 {
+			to, err = ledger.LoadTreeHead(ctx, db.Pool, toID)
+		} else {
+			to, err = ledger.LoadLatestTreeHead(ctx, db.Pool)
+		}
+		if err != nil {
+			return treeHeadError(c, err, log)
+		}
+		if to.ID < from.ID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "'from' must not be after 'to'",
+			})
+		}
+
+		proof, err := ledger.BuildConsistencyProof(ctx, db.Pool, from, to)
+		if errors.Is(err, ledger.ErrConsistencyTooLarge) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err != nil {
+			log.Error().Err(err).Int64("from", from.ID).Int64("to", to.ID).Msg("Failed to build ledger consistency proof")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to build ledger consistency proof",
+			})
+		}
+
+		return c.JSON(proof)
+	}
+}
+
+// treeHeadError traduit une erreur de chargement de tête en réponse HTTP
+func treeHeadError(c *fiber.Ctx, err error, log *zerolog.Logger) error {
+	if errors.Is(err, ledger.ErrNoTreeHead) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Tree head not found",
+		})
+	}
+	log.Error().Err(err).Msg("Failed to load ledger tree head")
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to load ledger tree head",
+	})
+}
+
+// LedgerCosignHandler gère l'endpoint POST /api/v1/ledger/tree-heads/:id/cosignatures
+// La contre-signature n'est enregistrée que si elle est vérifiée par une clé du JWKS
+// des témoins de confiance (LEDGER_WITNESS_JWKS_PATH) : c'est elle, et non la permission
+// de l'appelant, qui authentifie le témoin
+func LedgerCosignHandler(db *storage.DB, witnessKeys crypto.KeySet, log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+		if witnessKeys == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Witness keys not configured",
+			})
+		}
+
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid tree head ID",
+			})
+		}
+
+		var req CosignatureRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid JSON payload",
+				"details": err.Error(),
+			})
+		}
+		if req.WitnessID == "" || req.JWS == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "witness_id and jws are required",
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		head, err := ledger.LoadTreeHead(ctx, db.Pool, id)
+		if err != nil {
+			return treeHeadError(c, err, log)
+		}
+
+		evidence, err := verify.VerifyTreeHeadSignature(head.Head, req.JWS, witnessKeys)
+		if err != nil {
+			log.Warn().Err(err).Int64("tree_head_id", id).Str("witness_id", req.WitnessID).Msg("Rejected tree head cosignature")
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":   "Invalid cosignature",
+				"details": err.Error(),
+			})
+		}
+
+		cosignature := ledger.Cosignature{WitnessID: req.WitnessID, KID: evidence.KID, JWS: req.JWS}
+		created, err := ledger.AddCosignature(ctx, db.Pool, id, cosignature)
+		if err != nil {
+			log.Error().Err(err).Int64("tree_head_id", id).Msg("Failed to store tree head cosignature")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to store cosignature",
+			})
+		}
+
+		status := fiber.StatusOK
+		if created {
+			status = fiber.StatusCreated
+			log.Info().Int64("tree_head_id", id).Str("witness_id", req.WitnessID).Str("kid", evidence.KID).Msg("Ledger tree head cosigned")
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"tree_head_id": id,
+			"witness_id":   req.WitnessID,
+			"kid":          evidence.KID,
+		})
+	}
+}
+
+// latestTreeHead retourne la dernière tête signée et ses contre-signatures, jointe aux
+// réponses de vérification ; nil pour un appelant rattaché à un tenant ou sans tête publiée
+func latestTreeHead(ctx context.Context, c *fiber.Ctx, db *storage.DB, log *zerolog.Logger) *ledger.SignedTreeHead {
+	if auth.GetTenant(c) != "" {
+		return nil
+	}
+	head, err := ledger.LoadLatestTreeHead(ctx, db.Pool)
+	if err != nil {
+		if !errors.Is(err, ledger.ErrNoTreeHead) {
+			log.Warn().Err(err).Msg("Failed to load ledger tree head")
+		}
+		return nil
+	}
+	return head
+}
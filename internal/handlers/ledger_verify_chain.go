@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+// ChainVerifyResponse représente la réponse de l'endpoint de vérification de chaîne
+type ChainVerifyResponse struct {
+	*ledger.ChainReport
+	SignedVerdict *string                `json:"signed_verdict,omitempty"` // JWS du verdict (SHA256 du rapport JSON)
+	TreeHead      *ledger.SignedTreeHead `json:"tree_head,omitempty"`      // dernière tête signée et contre-signatures des témoins
+}
+
+// LedgerVerifyChainHandler gère l'endpoint GET /api/v1/ledger/verify-chain
+// Params:
+//   - from: borne basse incluse (RFC3339 ou YYYY-MM-DD, optionnel)
+//   - to:   borne haute exclue (RFC3339, ou YYYY-MM-DD journée incluse, optionnel)
+//
+// Parcourt le ledger (partitions incluses) dans l'ordre, recalcule chaque hash
+// et signe le verdict si le service JWS est disponible.
+// Multi-tenant : un appelant rattaché à un tenant ne voit que ses entrées
+// (chaîne dédiée si perTenantChain, sinon rapport restreint de la chaîne globale)
+func LedgerVerifyChainHandler(db *storage.DB, jwsService *crypto.Service, perTenantChain bool, log *zerolog.Logger, auditLogger *audit.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+
+		from, err := ledger.ParseTimeBound(c.Query("from"), false)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		to, err := ledger.ParseTimeBound(c.Query("to"), true)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if from != nil && to != nil && !from.Before(*to) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "'from' must be before 'to'",
+			})
+		}
+
+		// Parcours complet : timeout plus large que la vérification unitaire
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		startTime := time.Now()
+		tenant := auth.GetTenant(c)
+		report, err := ledger.VerifyChain(ctx, db.Pool, ledger.ChainOptions{
+			From:           from,
+			To:             to,
+			Tenant:         tenant,
+			PerTenantChain: perTenantChain,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to verify ledger chain")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to verify ledger chain",
+				"details": err.Error(),
+			})
+		}
+
+		response := &ChainVerifyResponse{ChainReport: report, TreeHead: latestTreeHead(ctx, c, db, log)}
+		if jwsService != nil {
+			jws, err := jwsService.SignReport(ledger.ChainVerdictSubject, report, time.Now())
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to sign chain verification verdict")
+			} else {
+				response.SignedVerdict = &jws
+			}
+		} else {
+			log.Warn().Msg("JWS service not available, chain verdict returned unsigned")
+		}
+
+		// Audit : vérification de chaîne
+		if auditLogger != nil {
+			status := audit.EventStatusSuccess
+			if !report.Valid {
+				status = audit.EventStatusError
+			}
+			auditLogger.Log(audit.Event{
+				EventType:  audit.EventTypeVerificationRun,
+				RequestID:  c.Get("X-Request-ID"),
+				Tenant:     tenant,
+				Status:     status,
+				DurationMS: int64(time.Since(startTime).Milliseconds()),
+				Metadata: map[string]interface{}{
+					"scope":           "ledger_chain",
+					"valid":           report.Valid,
+					"entries_checked": report.EntriesChecked,
+					"broken_links":    report.BrokenLinkCount,
+					"gaps":            report.GapCount,
+					"forks":           report.ForkCount,
+				},
+			})
+		}
+
+		statusCode := fiber.StatusOK
+		if !report.Valid {
+			statusCode = fiber.StatusConflict // 409 Conflict : chaîne rompue
+		}
+
+		return c.Status(statusCode).JSON(response)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// LegalHoldRequest représente le payload de pose ou de levée d'un blocage légal
+type LegalHoldRequest struct {
+	Hold   *bool  `json:"hold"`
+	Reason string `json:"reason,omitempty"` // requis pour poser un blocage
+}
+
+// LegalHoldHandler gère l'endpoint PUT /api/v1/documents/:id/legal-hold
+// Un document sous blocage légal ne peut être ni purgé ni supprimé
+func LegalHoldHandler(db *storage.DB, log *zerolog.Logger, auditLogger *audit.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		startTime := time.Now()
+
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid document ID",
+			})
+		}
+
+		var req LegalHoldRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid JSON payload",
+				"details": err.Error(),
+			})
+		}
+		if req.Hold == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "hold is required",
+			})
+		}
+		if *req.Hold && req.Reason == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "reason is required to place a legal hold",
+			})
+		}
+
+		update := storage.LegalHoldUpdate{
+			Hold:   *req.Hold,
+			Reason: req.Reason,
+			Tenant: auth.GetTenant(c),
+		}
+		if user, err := auth.GetUserInfo(c); err == nil {
+			update.Actor = user.UserID
+			if update.Actor == "" {
+				update.Actor = user.KeyID
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		doc, err := db.SetLegalHold(ctx, id, update)
+		switch {
+		case errors.Is(err, storage.ErrDocumentNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Document not found",
+			})
+		case errors.Is(err, storage.ErrDocumentPurged):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Document content already purged",
+			})
+		case err != nil:
+			log.Error().Err(err).Str("document_id", id.String()).Msg("Failed to update legal hold")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update legal hold",
+			})
+		}
+
+		log.Info().
+			Str("document_id", id.String()).
+			Bool("legal_hold", update.Hold).
+			Str("actor", update.Actor).
+			Msg("Legal hold updated")
+
+		if auditLogger != nil {
+			event := audit.Event{
+				EventType:  audit.EventTypeLegalHoldChanged,
+				DocumentID: id.String(),
+				RequestID:  c.Get("X-Request-ID"),
+				Tenant:     auth.GetTenant(c),
+				Status:     audit.EventStatusSuccess,
+				DurationMS: time.Since(startTime).Milliseconds(),
+				Metadata: map[string]interface{}{
+					"legal_hold": update.Hold,
+					"reason":     update.Reason,
+					"actor":      update.Actor,
+				},
+			}
+			if doc.Source != nil {
+				event.Source = *doc.Source
+			}
+			auditLogger.Log(event)
+		}
+
+		return c.JSON(doc)
+	}
+}
@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/doreviateam/dorevia-vault/internal/auth"
 	"github.com/doreviateam/dorevia-vault/internal/config"
 	"github.com/doreviateam/dorevia-vault/internal/metrics"
 	"github.com/doreviateam/dorevia-vault/internal/services"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog"
 )
@@ -66,6 +69,16 @@ func PosTicketsHandler(
 			})
 		}
 
+		// Multi-tenant : le tenant authentifié prime sur le payload
+		if authTenant := auth.GetTenant(c); authTenant != "" {
+			if payload.Tenant != "" && payload.Tenant != authTenant {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Tenant mismatch with authenticated identity",
+				})
+			}
+			payload.Tenant = authTenant
+		}
+
 		// Validation
 		if payload.Tenant == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -106,6 +119,7 @@ func PosTicketsHandler(
 			Cashier:      payload.Cashier,
 			Location:     payload.Location,
 			Ticket:       payload.Ticket,
+			RequestID:    c.Get("X-Request-ID"),
 		}
 
 		// Appeler le service
@@ -124,21 +138,23 @@ func PosTicketsHandler(
 				Float64("duration_seconds", duration).
 				Msg("Failed to ingest POS ticket")
 
-			// Métrique d'erreur
-			metrics.RecordDocumentVaulted("error", "pos")
+			// Métrique de durée (statut error enregistré par le service d'ingestion)
 			metrics.RecordDocumentStorageDuration("pos_ingest", duration)
 
+			// Ticket daté d'une période clôturée
+			var closedErr storage.ErrPeriodClosed
+			if errors.As(err, &closedErr) {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error":  "Accounting period closed",
+					"period": closedErr.Period,
+				})
+			}
+
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to ingest POS ticket",
 			})
 		}
 
-		// Déterminer le statut (idempotent si le document existait déjà)
-		status := "success"
-		if result.CreatedAt.Before(startTime.Add(-100 * time.Millisecond)) {
-			// Si le document a été créé avant notre appel, c'est un cas idempotent
-			status = "idempotent"
-		}
 
 		// Logs structurés
 		logEntry := log.Info().
@@ -158,13 +174,12 @@ func PosTicketsHandler(
 
 		logEntry.Msg("POS ticket ingested")
 
-		// Métriques Prometheus
-		metrics.RecordDocumentVaulted(status, "pos")
+		// Métriques Prometheus (statut success/idempotent enregistré par le service d'ingestion)
 		metrics.RecordDocumentStorageDuration("pos_ingest", duration)
 
 		// Retourner la réponse standardisée
 		statusCode := fiber.StatusCreated
-		if status == "idempotent" {
+		if result.Idempotent {
 			// Pour idempotence, on retourne 200 OK au lieu de 201 Created
 			statusCode = fiber.StatusOK
 		}
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/doreviateam/dorevia-vault/internal/auth"
 	"github.com/doreviateam/dorevia-vault/internal/config"
 	"github.com/doreviateam/dorevia-vault/internal/services"
 	"github.com/gofiber/fiber/v2"
@@ -279,6 +280,55 @@ func TestPosTicketsHandler_DefaultSourceSystem(t *testing.T) {
 	service.AssertExpectations(t)
 }
 
+func TestPosTicketsHandler_AuthenticatedTenant(t *testing.T) {
+	app := fiber.New()
+	service := new(MockPosTicketsService)
+	cfg := &config.Config{PosTicketMaxSizeBytes: 65536}
+	log := zerolog.Nop()
+
+	// Simuler un utilisateur authentifié rattaché au tenant "acme"
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &auth.UserInfo{UserID: "user-1", Role: "operator", Tenant: "acme"})
+		return c.Next()
+	})
+	app.Post("/api/v1/pos-tickets", PosTicketsHandler(service, cfg, &log))
+
+	// Tenant du payload différent du tenant authentifié : 403
+	payload := PosTicketPayload{
+		Tenant:      "globex",
+		SourceModel: "pos.order",
+		SourceID:    "POS/001",
+		Ticket:      map[string]interface{}{},
+	}
+	payloadBytes, _ := json.Marshal(payload)
+	req := httptest.NewRequest("POST", "/api/v1/pos-tickets", bytes.NewReader(payloadBytes))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	service.AssertNotCalled(t, "Ingest")
+
+	// Tenant absent du payload : le tenant authentifié est utilisé
+	payload.Tenant = ""
+	payloadBytes, _ = json.Marshal(payload)
+	result := &services.PosTicketResult{
+		ID:        uuid.New(),
+		Tenant:    "acme",
+		SHA256Hex: "test-hash",
+		CreatedAt: time.Now(),
+	}
+	service.On("Ingest", mock.Anything, mock.MatchedBy(func(input services.PosTicketInput) bool {
+		return input.Tenant == "acme"
+	})).Return(result, nil)
+
+	req = httptest.NewRequest("POST", "/api/v1/pos-tickets", bytes.NewReader(payloadBytes))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	service.AssertExpectations(t)
+}
+
 func TestGetPosTicket(t *testing.T) {
 	app := fiber.New()
 	app.Get("/api/v1/pos-tickets", GetPosTicket)
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/proof"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// ProofBundleHandler gère l'endpoint GET /api/v1/documents/:id/proof-bundle
+// Retourne un ZIP signé vérifiable hors ligne (cmd/verifybundle)
+func ProofBundleHandler(db *storage.DB, jwsService *crypto.Service, log *zerolog.Logger, auditLogger *audit.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Database not configured",
+			})
+		}
+		if jwsService == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "JWS service not configured",
+			})
+		}
+
+		docID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid document ID",
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		// Multi-tenant : un document d'un autre tenant est traité comme inexistant
+		doc, err := db.GetDocumentByID(ctx, docID)
+		if err != nil || !auth.CanAccessTenant(c, doc.Tenant) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Document not found",
+			})
+		}
+
+		// Rétention échue : seule la pierre tombale (métadonnées, SHA256) subsiste
+		if doc.PurgedAt != nil {
+			return c.Status(fiber.StatusGone).JSON(fiber.Map{
+				"error":     "Document content purged",
+				"purged_at": doc.PurgedAt,
+			})
+		}
+
+		startTime := time.Now()
+		bundle, err := proof.Build(ctx, db, jwsService, docID)
+		if err != nil {
+			log.Error().Err(err).Str("document_id", docID.String()).Msg("Failed to build proof bundle")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to build proof bundle",
+			})
+		}
+
+		if auditLogger != nil {
+			auditLogger.Log(audit.Event{
+				EventType:  audit.EventTypeDocumentDownloaded,
+				DocumentID: docID.String(),
+				RequestID:  c.Get("X-Request-ID"),
+				Tenant:     auth.GetTenant(c),
+				Status:     audit.EventStatusSuccess,
+				DurationMS: time.Since(startTime).Milliseconds(),
+				Metadata: map[string]interface{}{
+					"proof_bundle": true,
+					"size_bytes":   len(bundle),
+				},
+			})
+		}
+
+		c.Set("Content-Type", "application/zip")
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="proof-%s.zip"`, docID))
+		return c.Send(bundle)
+	}
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/services"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/upload"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// En-têtes du protocole d'upload reprenable
+const (
+	HeaderUploadOffset = "Upload-Offset" // octets déjà reçus / offset du morceau envoyé
+	HeaderUploadLength = "Upload-Length" // taille totale déclarée
+	HeaderChunkSHA256  = "X-Chunk-SHA256"
+)
+
+// UploadSessionRequest représente le payload d'ouverture d'un upload reprenable :
+// taille et SHA256 du document complet, et champs de la facture (voir InvoicePayload)
+type UploadSessionRequest struct {
+	SizeBytes   int64                  `json:"size_bytes"`
+	SHA256Hex   string                 `json:"sha256_hex"`
+	Source      string                 `json:"source"`
+	Model       string                 `json:"model"`
+	OdooID      int                    `json:"odoo_id"`
+	State       string                 `json:"state"`
+	PDPRequired bool                   `json:"pdp_required"`
+	Meta        map[string]interface{} `json:"meta,omitempty"`
+}
+
+// UploadCreateHandler gère l'endpoint POST /api/v1/uploads
+// Ouvre un upload reprenable ; les morceaux sont ensuite envoyés par PATCH
+func UploadCreateHandler(manager *upload.Manager, log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if manager == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Resumable uploads not configured",
+			})
+		}
+
+		var req UploadSessionRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid JSON payload",
+				"details": err.Error(),
+			})
+		}
+		if req.SizeBytes <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Missing required field: size_bytes",
+			})
+		}
+		if req.Source == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Missing required field: source",
+			})
+		}
+		if req.Model == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Missing required field: model",
+			})
+		}
+
+		metadata, err := json.Marshal(InvoicePayload{
+			Source:      req.Source,
+			Model:       req.Model,
+			OdooID:      req.OdooID,
+			State:       req.State,
+			PDPRequired: req.PDPRequired,
+			Meta:        req.Meta,
+		})
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid field: meta",
+				"details": err.Error(),
+			})
+		}
+
+		createReq := upload.CreateRequest{
+			SizeBytes: req.SizeBytes,
+			SHA256Hex: req.SHA256Hex,
+			Metadata:  metadata,
+			Tenant:    auth.GetTenant(c),
+		}
+		if user, err := auth.GetUserInfo(c); err == nil {
+			createReq.CreatedBy = user.UserID
+			if createReq.CreatedBy == "" {
+				createReq.CreatedBy = user.KeyID
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		session, err := manager.Create(ctx, createReq)
+		if err != nil {
+			return uploadErrorResponse(c, log, nil, err)
+		}
+
+		c.Location("/api/v1/uploads/" + session.ID.String())
+		setUploadHeaders(c, session)
+		return c.Status(fiber.StatusCreated).JSON(session)
+	}
+}
+
+// UploadStatusHandler gère les endpoints HEAD et GET /api/v1/uploads/:id
+// Upload-Offset indique l'offset à partir duquel reprendre l'envoi
+func UploadStatusHandler(manager *upload.Manager, log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if manager == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Resumable uploads not configured",
+			})
+		}
+
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid upload ID",
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		session, err := manager.Get(ctx, id, auth.GetTenant(c))
+		if err != nil {
+			return uploadErrorResponse(c, log, nil, err)
+		}
+
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		setUploadHeaders(c, session)
+		return c.JSON(session)
+	}
+}
+
+// UploadChunkHandler gère l'endpoint PATCH /api/v1/uploads/:id
+// Le corps est un morceau brut ; Upload-Offset et X-Chunk-SHA256 sont obligatoires.
+// Un morceau à un autre offset que celui enregistré est refusé (409)
+func UploadChunkHandler(manager *upload.Manager, log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if manager == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Resumable uploads not configured",
+			})
+		}
+
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid upload ID",
+			})
+		}
+		offset, err := strconv.ParseInt(c.Get(HeaderUploadOffset), 10, 64)
+		if err != nil || offset < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Missing or invalid header: " + HeaderUploadOffset,
+			})
+		}
+		chunkSHA256 := c.Get(HeaderChunkSHA256)
+		if chunkSHA256 == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Missing required header: " + HeaderChunkSHA256,
+			})
+		}
+
+		var body io.Reader = c.Context().RequestBodyStream()
+		if body == nil {
+			body = bytes.NewReader(c.Body())
+		}
+
+		// Pas de délai : la durée dépend de la taille du morceau et du débit du client
+		session, err := manager.Append(context.Background(), id, auth.GetTenant(c), offset, chunkSHA256, body)
+		if err != nil {
+			return uploadErrorResponse(c, log, session, err)
+		}
+
+		setUploadHeaders(c, session)
+		return c.JSON(session)
+	}
+}
+
+// UploadCompleteHandler gère l'endpoint POST /api/v1/uploads/:id/complete
+// Vérifie le SHA256 du contenu assemblé puis enregistre la facture comme POST
+// /api/v1/invoices (JWS + Ledger si configurés). En cas d'écart l'upload est remis à zéro
+func UploadCompleteHandler(manager *upload.Manager, service *services.IngestionService, cfg *config.Config, log *zerolog.Logger) fiber.Handler {
+	vault := &invoiceVault{
+		service: service,
+		cfg:     cfg,
+		log:     log,
+	}
+	return func(c *fiber.Ctx) error {
+		if manager == nil || service == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Resumable uploads not configured",
+			})
+		}
+
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid upload ID",
+			})
+		}
+
+		ctx := context.Background()
+		session, content, err := manager.Seal(ctx, id, auth.GetTenant(c))
+		if err != nil {
+			if errors.Is(err, upload.ErrSessionClosed) {
+				// Upload déjà terminé : renvoyer le document enregistré
+				if s, getErr := manager.Get(ctx, id, auth.GetTenant(c)); getErr == nil && s.DocumentID != nil {
+					return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+						"error":       "Upload already completed",
+						"document_id": s.DocumentID.String(),
+					})
+				}
+			}
+			return uploadErrorResponse(c, log, nil, err)
+		}
+
+		var payload InvoicePayload
+		if err := json.Unmarshal(session.Metadata, &payload); err != nil {
+			manager.Release(ctx, id)
+			log.Error().Err(err).Str("upload_id", id.String()).Msg("Invalid upload metadata")
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Invalid upload metadata",
+			})
+		}
+
+		if err := vault.store(c, payload, content); err != nil {
+			manager.Release(ctx, id)
+			return err
+		}
+
+		// Document enregistré (ou déjà présent) : l'upload est terminé, sinon il peut être
+		// finalisé à nouveau
+		documentID, ok := c.Locals(invoiceDocumentIDKey).(uuid.UUID)
+		if !ok {
+			if err := manager.Release(ctx, id); err != nil {
+				log.Warn().Err(err).Str("upload_id", id.String()).Msg("Failed to release upload session")
+			}
+			return nil
+		}
+		if err := manager.Finish(ctx, id, documentID); err != nil {
+			log.Warn().Err(err).Str("upload_id", id.String()).Msg("Failed to complete upload session")
+		}
+		return nil
+	}
+}
+
+// UploadAbortHandler gère l'endpoint DELETE /api/v1/uploads/:id
+func UploadAbortHandler(manager *upload.Manager, log *zerolog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if manager == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Resumable uploads not configured",
+			})
+		}
+
+		id, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid upload ID",
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := manager.Abort(ctx, id, auth.GetTenant(c)); err != nil {
+			return uploadErrorResponse(c, log, nil, err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// setUploadHeaders renseigne l'avancement d'un upload
+func setUploadHeaders(c *fiber.Ctx, session *models.UploadSession) {
+	c.Set(HeaderUploadOffset, strconv.FormatInt(session.OffsetBytes, 10))
+	c.Set(HeaderUploadLength, strconv.FormatInt(session.SizeBytes, 10))
+}
+
+// uploadErrorResponse traduit les erreurs d'upload reprenable. session, si connue,
+// donne l'offset courant au client pour reprendre l'envoi
+func uploadErrorResponse(c *fiber.Ctx, log *zerolog.Logger, session *models.UploadSession, err error) error {
+	if session != nil {
+		setUploadHeaders(c, session)
+	}
+	status := fiber.StatusInternalServerError
+	message := "Upload failed"
+	switch {
+	case errors.Is(err, storage.ErrUploadSessionNotFound):
+		status, message = fiber.StatusNotFound, "Upload not found"
+	case errors.Is(err, upload.ErrInvalidChecksum):
+		status, message = fiber.StatusBadRequest, "Invalid SHA256 checksum"
+	case errors.Is(err, upload.ErrOffsetMismatch):
+		status, message = fiber.StatusConflict, "Upload offset mismatch"
+	case errors.Is(err, upload.ErrSessionClosed):
+		status, message = fiber.StatusConflict, "Upload is no longer open"
+	case errors.Is(err, upload.ErrIncomplete):
+		status, message = fiber.StatusConflict, "Upload is incomplete"
+	case errors.Is(err, upload.ErrChunkChecksum):
+		status, message = fiber.StatusUnprocessableEntity, "Chunk checksum mismatch"
+	case errors.Is(err, upload.ErrHashMismatch):
+		status, message = fiber.StatusUnprocessableEntity, "Document checksum mismatch, upload reset"
+	case errors.Is(err, upload.ErrChunkTooLarge), errors.Is(err, upload.ErrSizeExceeded):
+		status, message = fiber.StatusRequestEntityTooLarge, err.Error()
+	default:
+		log.Error().Err(err).Str("upload_id", c.Params("id")).Msg("Upload failed")
+	}
+
+	body := fiber.Map{"error": message}
+	if session != nil {
+		body["offset"] = session.OffsetBytes
+	}
+	return c.Status(status).JSON(body)
+}
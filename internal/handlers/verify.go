@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/auth"
 	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
 	"github.com/doreviateam/dorevia-vault/internal/verify"
 	"github.com/doreviateam/dorevia-vault/internal/webhooks"
@@ -20,7 +22,8 @@ import (
 // VerifyResponse représente la réponse de l'endpoint de vérification
 type VerifyResponse struct {
 	*verify.VerificationResult
-	SignedProof *string `json:"signed_proof,omitempty"` // JWS signé du résultat si ?signed=true
+	SignedProof *string                `json:"signed_proof,omitempty"` // JWS signé du résultat si ?signed=true
+	TreeHead    *ledger.SignedTreeHead `json:"tree_head,omitempty"`    // dernière tête signée et contre-signatures des témoins
 }
 
 // VerifyHandler gère l'endpoint GET /api/v1/ledger/verify/:document_id
@@ -53,8 +56,19 @@ func VerifyHandler(db *storage.DB, jwsService *crypto.Service, log *zerolog.Logg
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
+		// Multi-tenant : vérification limitée aux documents du tenant appelant
+		tenant := auth.GetTenant(c)
+		if tenant != "" {
+			doc, err := db.GetDocumentByID(ctx, docID)
+			if err != nil || !auth.CanAccessTenant(c, doc.Tenant) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "Document not found",
+				})
+			}
+		}
+
 		startTime := time.Now()
-		result, err := verify.VerifyDocumentIntegrity(ctx, db, docID)
+		result, err := verify.VerifyDocumentIntegrity(ctx, db, jwsService, docID)
 		if err != nil {
 			log.Error().Err(err).Str("document_id", docIDStr).Msg("Failed to verify document integrity")
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -66,6 +80,7 @@ func VerifyHandler(db *storage.DB, jwsService *crypto.Service, log *zerolog.Logg
 		// Construire la réponse
 		response := &VerifyResponse{
 			VerificationResult: result,
+			TreeHead:           latestTreeHead(ctx, c, db, log),
 		}
 
 		// Option ?signed=true : Générer JWS signé du résultat
@@ -111,12 +126,14 @@ func VerifyHandler(db *storage.DB, jwsService *crypto.Service, log *zerolog.Logg
 				EventType:  audit.EventTypeVerificationRun,
 				DocumentID: docIDStr,
 				RequestID:  requestID,
+				Tenant:     tenant,
 				Status:     status,
 				DurationMS: int64(time.Since(startTime).Milliseconds()),
 				Metadata: map[string]interface{}{
 					"valid":        result.Valid,
 					"signed_proof": c.Query("signed") == "true",
 					"checks":       len(result.Checks),
+					"evidence":     evidenceStatus(result),
 				},
 			})
 		}
@@ -145,3 +162,10 @@ func VerifyHandler(db *storage.DB, jwsService *crypto.Service, log *zerolog.Logg
 	}
 }
 
+// evidenceStatus retourne le statut de la preuve JWS ("" si non vérifiée)
+func evidenceStatus(result *verify.VerificationResult) string {
+	if result.Evidence == nil {
+		return ""
+	}
+	return result.Evidence.Status
+}
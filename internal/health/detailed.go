@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/rs/zerolog"
 )
 
 // Status représente le statut d'un composant
@@ -34,6 +36,9 @@ type DetailedHealth struct {
 	Storage   ComponentHealth           `json:"storage"`
 	JWS       ComponentHealth           `json:"jws"`
 	Ledger    ComponentHealth           `json:"ledger"`
+	// Partitionnement mensuel du ledger (état détaillé dans Partitions)
+	LedgerPartitions ComponentHealth         `json:"ledger_partitions"`
+	Partitions       *ledger.PartitionStatus `json:"partitions,omitempty"`
 }
 
 // CheckDetailedHealth vérifie l'état de santé de tous les composants
@@ -64,8 +69,11 @@ func CheckDetailedHealth(
 	// 4. Vérification Ledger
 	health.Ledger = CheckLedger(checkCtx, db)
 
+	// 5. Partitions du ledger
+	health.LedgerPartitions, health.Partitions = CheckLedgerPartitions(checkCtx, db)
+
 	// Déterminer le statut global
-	health.Status = DetermineGlobalStatus(health.Database, health.Storage, health.JWS, health.Ledger)
+	health.Status = DetermineGlobalStatus(health.Database, health.Storage, health.JWS, health.Ledger, health.LedgerPartitions)
 
 	return health
 }
@@ -276,6 +284,52 @@ func CheckLedger(ctx context.Context, db *storage.DB) ComponentHealth {
 	}
 }
 
+// CheckLedgerPartitions vérifie l'état du partitionnement mensuel du ledger
+// (GetPartitionInfo, archives) ; warn si la partition du mois suivant manque
+// Exportée pour les tests unitaires
+func CheckLedgerPartitions(ctx context.Context, db *storage.DB) (ComponentHealth, *ledger.PartitionStatus) {
+	start := time.Now()
+
+	if db == nil || db.Pool == nil {
+		return ComponentHealth{
+			Status:  StatusFail,
+			Message: "Database not configured",
+		}, nil
+	}
+
+	status, err := ledger.NewPartitionManager(db.Pool, zerolog.Nop()).Status(ctx)
+	latency := fmt.Sprintf("%.2f", time.Since(start).Seconds()*1000)
+	if err != nil {
+		return ComponentHealth{
+			Status:  StatusWarn,
+			Message: fmt.Sprintf("Failed to check ledger partitions: %v", err),
+			Latency: &latency,
+		}, nil
+	}
+
+	if !status.Partitioned {
+		return ComponentHealth{
+			Status:  StatusOK,
+			Message: "Ledger not partitioned",
+			Latency: &latency,
+		}, status
+	}
+
+	if !status.NextPartitionReady {
+		return ComponentHealth{
+			Status:  StatusWarn,
+			Message: "Next month ledger partition missing",
+			Latency: &latency,
+		}, status
+	}
+
+	return ComponentHealth{
+		Status:  StatusOK,
+		Message: fmt.Sprintf("Ledger partitioned: %d attached, %d archived", len(status.Partitions), len(status.Archives)),
+		Latency: &latency,
+	}, status
+}
+
 // DetermineGlobalStatus détermine le statut global basé sur les statuts des composants
 // Exportée pour les tests unitaires
 func DetermineGlobalStatus(components ...ComponentHealth) Status {
@@ -0,0 +1,271 @@
+package ledger
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/metrics"
+	"github.com/doreviateam/dorevia-vault/internal/tsp"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// ErrNoAnchor est retourné quand aucun ancrage horodaté ne couvre encore l'entrée
+var ErrNoAnchor = errors.New("ledger entry not yet anchored")
+
+// MaxAnchorPathEntries borne le nombre d'entrées entre un document et son ancrage
+const MaxAnchorPathEntries = 100000
+
+// Anchor est un horodatage RFC 3161 de la tête d'une chaîne du ledger : la TSA atteste
+// qu'à GenTime la chaîne (et donc chaque document qui la précède) existait jusqu'à LedgerHash
+type Anchor struct {
+	ID              int64     `json:"id"`
+	LedgerEntryID   int64     `json:"ledger_entry_id"`
+	LedgerHash      string    `json:"ledger_hash"`
+	LedgerTimestamp time.Time `json:"ledger_timestamp"`
+	Tenant          *string   `json:"tenant,omitempty"` // chaîne du tenant (LEDGER_PER_TENANT_CHAIN)
+	PerTenantChain  bool      `json:"per_tenant_chain"`
+	TSAURL          string    `json:"tsa_url"`
+	Token           []byte    `json:"token"` // TimeStampToken DER, empreinte SHA-256 = LedgerHash décodé
+	GenTime         time.Time `json:"gen_time"`
+	SerialNumber    string    `json:"serial_number"`
+	Policy          string    `json:"policy,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// AnchorProof relie une entrée du ledger au premier ancrage qui la couvre
+type AnchorProof struct {
+	Anchor Anchor       `json:"anchor"`
+	Path   []ChainEntry `json:"path"` // entrées suivant l'entrée du document, jusqu'à l'entrée horodatée incluse
+}
+
+// Timestamper horodate une empreinte SHA-256 auprès d'une TSA (*tsp.Client)
+type Timestamper interface {
+	Timestamp(ctx context.Context, digest []byte) (*tsp.Token, error)
+	URL() string
+}
+
+// Vérification de l'implémentation de l'interface Timestamper
+var _ Timestamper = (*tsp.Client)(nil)
+
+const anchorColumns = `id, ledger_entry_id, ledger_hash, ledger_timestamp, tenant, per_tenant_chain,
+	tsa_url, token, gen_time, serial_number, COALESCE(policy, ''), created_at`
+
+// AnchorHeads horodate la tête de chaque chaîne du ledger qui n'est pas encore ancrée
+func AnchorHeads(ctx context.Context, pool *pgxpool.Pool, stamper Timestamper, perTenantChain bool) ([]*Anchor, error) {
+	headsQuery := `
+		SELECT id, hash, timestamp, NULL::text FROM ledger
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1
+	`
+	if perTenantChain {
+		headsQuery = `
+			SELECT DISTINCT ON (tenant) id, hash, timestamp, tenant FROM ledger
+			ORDER BY tenant, timestamp DESC, id DESC
+		`
+	}
+	rows, err := pool.Query(ctx, headsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger heads: %w", err)
+	}
+	var heads []Anchor
+	for rows.Next() {
+		var head Anchor
+		if err := rows.Scan(&head.LedgerEntryID, &head.LedgerHash, &head.LedgerTimestamp, &head.Tenant); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan ledger head: %w", err)
+		}
+		heads = append(heads, head)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get ledger heads: %w", err)
+	}
+
+	var anchors []*Anchor
+	for i := range heads {
+		head := &heads[i]
+
+		var anchored bool
+		if err := pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM ledger_anchors WHERE ledger_hash = $1)`, head.LedgerHash).Scan(&anchored); err != nil {
+			return anchors, fmt.Errorf("failed to check ledger anchor: %w", err)
+		}
+		if anchored {
+			continue
+		}
+
+		digest, err := hex.DecodeString(head.LedgerHash)
+		if err != nil {
+			return anchors, fmt.Errorf("invalid ledger hash %s: %w", head.LedgerHash, err)
+		}
+		token, err := stamper.Timestamp(ctx, digest)
+		if err != nil {
+			metrics.LedgerAnchors.WithLabelValues("error").Inc()
+			return anchors, fmt.Errorf("failed to timestamp ledger head: %w", err)
+		}
+
+		head.PerTenantChain = perTenantChain
+		head.TSAURL = stamper.URL()
+		head.Token = token.Raw
+		head.GenTime = token.GenTime
+		head.SerialNumber = token.SerialNumber.String()
+		head.Policy = token.Policy.String()
+		inserted, err := insertAnchor(ctx, pool, head)
+		if err != nil {
+			return anchors, err
+		}
+		if inserted {
+			metrics.LedgerAnchors.WithLabelValues("success").Inc()
+			anchors = append(anchors, head)
+		}
+	}
+	return anchors, nil
+}
+
+// insertAnchor enregistre l'ancrage ; false si la même tête est déjà ancrée
+func insertAnchor(ctx context.Context, pool *pgxpool.Pool, a *Anchor) (bool, error) {
+	err := pool.QueryRow(ctx, `
+		INSERT INTO ledger_anchors (ledger_entry_id, ledger_hash, ledger_timestamp, tenant, per_tenant_chain,
+			tsa_url, token, gen_time, serial_number, policy)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (ledger_hash) DO NOTHING
+		RETURNING id, created_at
+	`, a.LedgerEntryID, a.LedgerHash, a.LedgerTimestamp, a.Tenant, a.PerTenantChain,
+		a.TSAURL, a.Token, a.GenTime, a.SerialNumber, a.Policy).Scan(&a.ID, &a.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to insert ledger anchor: %w", err)
+	}
+	return true, nil
+}
+
+// StartAnchorScheduler horodate la tête du ledger dès le démarrage puis à chaque intervalle
+func StartAnchorScheduler(ctx context.Context, pool *pgxpool.Pool, stamper Timestamper, perTenantChain bool, interval time.Duration, log zerolog.Logger) {
+	if interval == 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			anchors, err := AnchorHeads(ctx, pool, stamper, perTenantChain)
+			if err != nil {
+				log.Error().Err(err).Str("tsa_url", stamper.URL()).Msg("Failed to anchor ledger head")
+			}
+			for _, a := range anchors {
+				log.Info().
+					Int64("ledger_entry_id", a.LedgerEntryID).
+					Str("ledger_hash", a.LedgerHash).
+					Time("gen_time", a.GenTime).
+					Msg("Ledger head anchored by timestamp authority")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// HasAnchors indique si le ledger a déjà été horodaté par une TSA
+func HasAnchors(ctx context.Context, pool *pgxpool.Pool) (bool, error) {
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM ledger_anchors)`).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check ledger anchors: %w", err)
+	}
+	return exists, nil
+}
+
+// LoadAnchorProof charge le premier ancrage couvrant l'entrée et les entrées qui
+// l'en séparent dans sa chaîne. ErrNoAnchor si l'entrée n'est pas encore ancrée
+func LoadAnchorProof(ctx context.Context, pool *pgxpool.Pool, entry ChainEntry) (*AnchorProof, error) {
+	var a Anchor
+	err := pool.QueryRow(ctx, `
+		SELECT `+anchorColumns+` FROM ledger_anchors
+		WHERE (ledger_timestamp, ledger_entry_id) >= ($1, $2)
+		  AND (NOT per_tenant_chain OR tenant IS NOT DISTINCT FROM $3)
+		ORDER BY ledger_timestamp, ledger_entry_id
+		LIMIT 1
+	`, entry.Timestamp, entry.ID, entry.Tenant).Scan(&a.ID, &a.LedgerEntryID, &a.LedgerHash, &a.LedgerTimestamp,
+		&a.Tenant, &a.PerTenantChain, &a.TSAURL, &a.Token, &a.GenTime, &a.SerialNumber, &a.Policy, &a.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoAnchor
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ledger anchor: %w", err)
+	}
+
+	rows, err := pool.Query(ctx, proofEntryColumns+`
+		WHERE (l.timestamp, l.id) > ($1, $2)
+		  AND (l.timestamp, l.id) <= ($3, $4)
+		  AND (NOT $5::boolean OR l.tenant IS NOT DISTINCT FROM $6)
+		ORDER BY l.timestamp, l.id
+		LIMIT $7
+	`, entry.Timestamp, entry.ID, a.LedgerTimestamp, a.LedgerEntryID, a.PerTenantChain, a.Tenant, MaxAnchorPathEntries+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load anchor path: %w", err)
+	}
+	defer rows.Close()
+
+	proof := &AnchorProof{Anchor: a, Path: []ChainEntry{}}
+	for rows.Next() {
+		e, err := scanProofEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan anchor path: %w", err)
+		}
+		proof.Path = append(proof.Path, *anonymizeEntry(e))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating anchor path: %w", err)
+	}
+	if len(proof.Path) > MaxAnchorPathEntries {
+		return nil, fmt.Errorf("anchor path exceeds %d entries", MaxAnchorPathEntries)
+	}
+	return proof, nil
+}
+
+// Verify vérifie l'ancrage de l'entrée : signature du jeton de la TSA, empreinte égale
+// au hash horodaté, chaînage de l'entrée jusqu'à ce hash et certificat de TSA émis par
+// roots (nil : magasin de certificats du système)
+func (p *AnchorProof) Verify(entry ChainEntry, roots *x509.CertPool) (*tsp.Token, error) {
+	token, err := tsp.ParseToken(p.Anchor.Token)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := hex.DecodeString(p.Anchor.LedgerHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anchored ledger hash: %w", err)
+	}
+	if err := token.VerifyImprint(crypto.SHA256, digest); err != nil {
+		return nil, err
+	}
+
+	head := entry.Hash
+	if len(p.Path) > 0 {
+		report := VerifyChainEntries(p.Path, &entry.Hash)
+		if !report.Valid {
+			return nil, fmt.Errorf("anchor path broken (broken=%d, gaps=%d, forks=%d)", report.BrokenLinkCount, report.GapCount, report.ForkCount)
+		}
+		head = p.Path[len(p.Path)-1].Hash
+	}
+	if head != p.Anchor.LedgerHash {
+		return nil, fmt.Errorf("anchor path does not lead to the anchored hash")
+	}
+
+	if err := token.VerifyCertificate(roots); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
@@ -2,63 +2,264 @@ package ledger
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
+// GlobalChainID identifie la chaîne globale dans ledger_head
+const GlobalChainID = "global"
+
+// ChainID retourne l'identifiant de la chaîne (ligne de ledger_head) d'un tenant :
+// la chaîne globale, ou la chaîne du tenant si perTenantChain
+func ChainID(tenant string, perTenantChain bool) string {
+	if !perTenantChain {
+		return GlobalChainID
+	}
+	return "tenant:" + tenant
+}
+
+// ChainEpoch marque le passage de la chaîne globale aux chaînes par tenant
+// (LEDGER_PER_TENANT_CHAIN) : les entrées d'id inférieur ou égal à EntryID appartiennent
+// à la chaîne globale, chaque chaîne par tenant démarre sur Hash
+type ChainEpoch struct {
+	EntryID int64   `json:"entry_id"`       // dernière entrée de la chaîne globale (0 : aucune)
+	Hash    *string `json:"hash,omitempty"` // son hash (nil : chaînes par tenant depuis le début)
+}
+
+// rowQuerier est satisfait par *pgxpool.Pool et pgx.Tx
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// LoadChainEpoch lit le passage aux chaînes par tenant (ligne global de ledger_head) ;
+// nil tant qu'aucune chaîne par tenant n'a démarré
+func LoadChainEpoch(ctx context.Context, q rowQuerier) (*ChainEpoch, error) {
+	var epoch ChainEpoch
+	err := q.QueryRow(ctx, `
+		SELECT tenant_epoch_id, tenant_epoch_hash FROM ledger_head
+		WHERE chain_id = $1 AND tenant_epoch_id IS NOT NULL
+	`, GlobalChainID).Scan(&epoch.EntryID, &epoch.Hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger chain epoch: %w", err)
+	}
+	return &epoch, nil
+}
+
 // AppendLedger ajoute une entrée au ledger avec hash chaîné
-// Utilise un verrou exclusif (FOR UPDATE) pour éviter les race conditions
+// La tête de chaîne (ledger_head) est verrouillée jusqu'à la fin de la transaction
 func AppendLedger(ctx context.Context, tx pgx.Tx, docID uuid.UUID, shaHex, jws string) (string, error) {
-	// 1. Récupérer le previous_hash avec verrou exclusif
-	// Le verrou FOR UPDATE empêche les autres transactions de lire/modifier
-	// le dernier enregistrement pendant cette transaction
-	var previousHash *string
-	err := tx.QueryRow(ctx, `
-		SELECT hash FROM ledger 
-		ORDER BY timestamp DESC, id DESC 
-		LIMIT 1 
-		FOR UPDATE
-	`).Scan(&previousHash)
-
-	// 2. Calculer le nouveau hash
-	var newHash string
-	if err == pgx.ErrNoRows || previousHash == nil {
-		// Premier enregistrement : hash = SHA256(sha256_document)
-		hash := sha256.Sum256([]byte(shaHex))
-		newHash = hex.EncodeToString(hash[:])
-	} else if err != nil {
-		return "", fmt.Errorf("failed to get previous hash: %w", err)
-	} else {
-		// Chaînage : hash = SHA256(previous_hash + sha256_document)
-		combined := *previousHash + shaHex
-		hash := sha256.Sum256([]byte(combined))
-		newHash = hex.EncodeToString(hash[:])
+	return AppendLedgerForTenant(ctx, tx, "", false, docID, shaHex, jws)
+}
+
+// AppendLedgerForTenant ajoute une entrée au ledger rattachée à un tenant
+// Si perTenantChain est vrai, le previous_hash est pris dans la chaîne du tenant
+// (une chaîne indépendante par tenant) ; sinon la chaîne globale est utilisée.
+// L'entrée est au format v1 (voir AppendEntry pour le format v2)
+func AppendLedgerForTenant(ctx context.Context, tx pgx.Tx, tenant string, perTenantChain bool, docID uuid.UUID, shaHex, jws string) (string, error) {
+	return AppendEntry(ctx, tx, Options{PerTenantChain: perTenantChain}, tenant, docID, shaHex, jws)
+}
+
+// AppendEntry ajoute une entrée au ledger selon les options du service : chaîne
+// globale ou par tenant, format de hash (opts.HashVersion, v1 par défaut).
+//
+// Le previous_hash et le dernier numéro de séquence sont lus dans la ligne ledger_head
+// de la chaîne (accès par clé primaire, coût constant quelle que soit la taille du
+// ledger, table partitionnée ou non). Son verrou FOR UPDATE sérialise les ajouts d'une
+// même chaîne jusqu'au COMMIT : seq est donc sans trou et l'ordre (timestamp, id)
+// suit l'ordre du chaînage.
+func AppendEntry(ctx context.Context, tx pgx.Tx, opts Options, tenant string, docID uuid.UUID, shaHex, jws string) (string, error) {
+	return appendChained(ctx, tx, opts, tenant, &docID, ChainEntry{
+		DocumentID:  docID.String(),
+		DocumentSHA: shaHex,
+		EvidenceJWS: &jws,
+		EntryType:   EntryTypeDocumentCreated,
+	})
+}
+
+// AppendEvent ajoute un événement typé au ledger (changement de statut, rotation de
+// clé, clôture, purge), chaîné comme un document dans la chaîne du tenant
+func AppendEvent(ctx context.Context, tx pgx.Tx, opts Options, tenant string, event Event) (string, error) {
+	if !IsEventType(event.Type) {
+		return "", fmt.Errorf("unsupported ledger entry type %q", event.Type)
 	}
+	body, err := CanonicalBody(event.Body)
+	if err != nil {
+		return "", err
+	}
+	e := ChainEntry{EntryType: event.Type, Body: body}
+	if event.DocumentID != nil {
+		e.DocumentID = event.DocumentID.String()
+	}
+	return appendChained(ctx, tx, opts, tenant, event.DocumentID, e)
+}
 
-	// 3. Insérer dans le ledger avec ON CONFLICT pour idempotence
-	_, err = tx.Exec(ctx, `
-		INSERT INTO ledger (document_id, hash, previous_hash, evidence_jws)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (document_id, hash) DO NOTHING
-	`, docID, newHash, previousHash, jws)
+// appendChained chaîne l'entrée e à la tête de la chaîne du tenant et l'insère
+func appendChained(ctx context.Context, tx pgx.Tx, opts Options, tenant string, docID *uuid.UUID, e ChainEntry) (string, error) {
+	version := opts.hashVersion()
+	if version != HashVersionV1 && version != HashVersionV2 {
+		return "", fmt.Errorf("unsupported hash version %d", version)
+	}
+	var tenantValue *string
+	if tenant != "" {
+		tenantValue = &tenant
+	}
+	chainID := ChainID(tenant, opts.PerTenantChain)
 
+	// 1. Verrouiller la tête de chaîne
+	seq, previousHash, err := lockHead(ctx, tx, chainID, tenantValue, opts.PerTenantChain)
+	if err != nil {
+		return "", err
+	}
+	seq++
+
+	// 2. Horodatage pris sous le verrou : clock_timestamp() (et non now(), début de
+	// transaction), lu avant l'insertion car couvert par le hash v2
+	var timestamp time.Time
+	if err := tx.QueryRow(ctx, `SELECT clock_timestamp()`).Scan(&timestamp); err != nil {
+		return "", fmt.Errorf("failed to read ledger timestamp: %w", err)
+	}
+
+	// 3. Calculer le nouveau hash (même calcul que la vérification, voir EntryHash)
+	// v1 : premier enregistrement SHA256(sha256_document), sinon SHA256(previous_hash + sha256_document)
+	// v2 : encodage canonique de seq, horodatage, document, JWS et previous_hash
+	e.PreviousHash = previousHash
+	e.Seq = &seq
+	e.Timestamp = timestamp
+	e.HashVersion = version
+	newHash, err := EntryHash(e)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute ledger hash: %w", err)
+	}
+	var body *string
+	if e.isEvent() {
+		body = &e.Body
+	}
+
+	// 4. Insérer dans le ledger avec ON CONFLICT pour idempotence
+	// (sans cible : sur une table partitionnée, l'unicité inclut la clé de partition)
+	var id int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO ledger (document_id, hash, previous_hash, evidence_jws, tenant, seq, timestamp, hash_version, entry_type, body)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT DO NOTHING
+		RETURNING id
+	`, docID, newHash, previousHash, e.EvidenceJWS, tenantValue, seq, timestamp, version, e.EntryType, body).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Entrée déjà présente : la tête n'avance pas
+		return newHash, nil
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to insert into ledger: %w", err)
 	}
 
+	// 5. Avancer la tête de chaîne
+	if _, err := tx.Exec(ctx, `
+		UPDATE ledger_head SET seq = $2, hash = $3, updated_at = now()
+		WHERE chain_id = $1
+	`, chainID, seq, newHash); err != nil {
+		return "", fmt.Errorf("failed to update ledger head: %w", err)
+	}
+
 	return newHash, nil
 }
 
+// lockHead verrouille la ligne ledger_head de la chaîne et retourne son dernier numéro
+// de séquence et son hash (nil si la chaîne est vide). La ligne est créée au premier
+// ajout : la chaîne globale reprend les entrées déjà présentes dans le ledger, une chaîne
+// par tenant démarre après le passage aux chaînes par tenant (voir splitChains)
+func lockHead(ctx context.Context, tx pgx.Tx, chainID string, tenant *string, perTenantChain bool) (int64, *string, error) {
+	var seq int64
+	var hash *string
+	err := tx.QueryRow(ctx, `SELECT seq, hash FROM ledger_head WHERE chain_id = $1 FOR UPDATE`, chainID).Scan(&seq, &hash)
+	if err == nil {
+		return seq, hash, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil, fmt.Errorf("failed to lock ledger head: %w", err)
+	}
+
+	if perTenantChain {
+		epoch, err := splitChains(ctx, tx)
+		if err != nil {
+			return 0, nil, err
+		}
+		// La chaîne du tenant part du hash de la chaîne globale au passage ; seules ses
+		// entrées postérieures (ledger déjà chaîné par tenant, sans seq) sont reprises
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ledger_head (chain_id, seq, hash)
+			SELECT $1, COUNT(*), COALESCE((
+				SELECT hash FROM ledger
+				WHERE tenant IS NOT DISTINCT FROM $2 AND id > $3
+				ORDER BY timestamp DESC, id DESC
+				LIMIT 1
+			), $4)
+			FROM ledger
+			WHERE tenant IS NOT DISTINCT FROM $2 AND id > $3
+			ON CONFLICT (chain_id) DO NOTHING
+		`, chainID, tenant, epoch.EntryID, epoch.Hash); err != nil {
+			return 0, nil, fmt.Errorf("failed to initialize ledger head: %w", err)
+		}
+	} else {
+		// Les entrées antérieures à ledger_head n'ont pas de seq : la séquence reprend après leur nombre
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO ledger_head (chain_id, seq, hash)
+			SELECT $1, COUNT(*), (
+				SELECT hash FROM ledger
+				ORDER BY timestamp DESC, id DESC
+				LIMIT 1
+			)
+			FROM ledger
+			ON CONFLICT (chain_id) DO NOTHING
+		`, chainID); err != nil {
+			return 0, nil, fmt.Errorf("failed to initialize ledger head: %w", err)
+		}
+	}
+
+	if err := tx.QueryRow(ctx, `SELECT seq, hash FROM ledger_head WHERE chain_id = $1 FOR UPDATE`, chainID).Scan(&seq, &hash); err != nil {
+		return 0, nil, fmt.Errorf("failed to lock ledger head: %w", err)
+	}
+	return seq, hash, nil
+}
+
+// splitChains retourne le passage aux chaînes par tenant et l'enregistre au démarrage de
+// la première d'entre elles : la chaîne globale (verrouillée pendant le passage) s'arrête
+// à sa dernière entrée. Sans ce point de passage, les chaînes par tenant bifurqueraient
+// d'entrées chaînées globalement et la vérification signalerait chaque previous_hash
+// historique d'un autre tenant comme un trou
+func splitChains(ctx context.Context, tx pgx.Tx) (*ChainEpoch, error) {
+	if _, _, err := lockHead(ctx, tx, GlobalChainID, nil, false); err != nil {
+		return nil, err
+	}
+	epoch, err := LoadChainEpoch(ctx, tx)
+	if err != nil || epoch != nil {
+		return epoch, err
+	}
+
+	epoch = &ChainEpoch{}
+	if err := tx.QueryRow(ctx, `
+		UPDATE ledger_head
+		SET tenant_epoch_id = COALESCE((SELECT MAX(id) FROM ledger), 0), tenant_epoch_hash = hash
+		WHERE chain_id = $1
+		RETURNING tenant_epoch_id, tenant_epoch_hash
+	`, GlobalChainID).Scan(&epoch.EntryID, &epoch.Hash); err != nil {
+		return nil, fmt.Errorf("failed to record ledger chain epoch: %w", err)
+	}
+	return epoch, nil
+}
+
 // ExistsByDocumentID vérifie si un document existe déjà dans le ledger
 func ExistsByDocumentID(ctx context.Context, tx pgx.Tx, docID uuid.UUID) (bool, error) {
 	var exists bool
 	err := tx.QueryRow(ctx, `
-		SELECT EXISTS(SELECT 1 FROM ledger WHERE document_id = $1)
-	`, docID).Scan(&exists)
+		SELECT EXISTS(SELECT 1 FROM ledger WHERE document_id = $1 AND entry_type = $2)
+	`, docID, EntryTypeDocumentCreated).Scan(&exists)
 
 	if err != nil {
 		return false, fmt.Errorf("failed to check ledger existence: %w", err)
@@ -66,4 +267,3 @@ func ExistsByDocumentID(ctx context.Context, tx pgx.Tx, docID uuid.UUID) (bool,
 
 	return exists, nil
 }
-
@@ -0,0 +1,141 @@
+package ledger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Types d'entrées du ledger (colonne entry_type)
+//
+// document.created est l'entrée historique « document stocké » : son hash porte sur le
+// document (sha256, JWS). Les autres types sont des événements du cycle de vie dont
+// le hash porte sur un corps JSON canonique (EventDigest) ; ils sont chaînés comme les
+// documents, dans la chaîne de leur tenant (NF525 : toute opération est tracée).
+const (
+	EntryTypeDocumentCreated       = "document.created"
+	EntryTypeDocumentStatusChanged = "document.status_changed"
+	EntryTypeKeyRotated            = "key.rotated"
+	EntryTypePeriodClosed          = "period.closed"
+	EntryTypeDocumentPurged        = "document.purged"
+	EntryTypeDocumentSealed        = "document.sealed"
+)
+
+// Event est un événement à inscrire dans le ledger
+type Event struct {
+	Type       string
+	DocumentID *uuid.UUID  // document concerné (nil pour key.rotated, period.closed)
+	Body       interface{} // sérialisé en JSON canonique (CanonicalBody)
+}
+
+// StatusChangedBody est le corps d'un événement document.status_changed
+type StatusChangedBody struct {
+	Field string  `json:"field"` // dispatch_status ou odoo_state
+	From  *string `json:"from"`
+	To    string  `json:"to"`
+	Actor string  `json:"actor,omitempty"`
+}
+
+// KeyRotatedBody est le corps d'un événement key.rotated
+type KeyRotatedBody struct {
+	NewKID      string `json:"new_kid"`
+	PreviousKID string `json:"previous_kid,omitempty"`
+	Generated   bool   `json:"generated"`
+	Actor       string `json:"actor,omitempty"`
+}
+
+// PeriodClosedBody est le corps d'un événement period.closed
+type PeriodClosedBody struct {
+	Period           string  `json:"period"`
+	DocumentCount    int64   `json:"document_count"`
+	LedgerEntryCount int64   `json:"ledger_entry_count"`
+	MerkleRoot       string  `json:"merkle_root"`
+	LastLedgerHash   *string `json:"last_ledger_hash"`
+	ArchiveSHA256    string  `json:"archive_sha256"`
+	ClosedBy         string  `json:"closed_by"`
+}
+
+// DocumentPurgedBody est le corps d'un événement document.purged
+type DocumentPurgedBody struct {
+	SHA256Hex string `json:"sha256_hex"`
+	Reason    string `json:"reason"`
+	Actor     string `json:"actor,omitempty"`
+}
+
+// DocumentSealedBody est le corps d'un événement document.sealed : scellement a posteriori
+// d'un document enregistré sans preuve complète (cmd/backfill, réconciliation)
+type DocumentSealedBody struct {
+	SHA256Hex     string `json:"sha256_hex"`
+	CreatedAt     string `json:"created_at"`            // enregistrement initial du document (RFC 3339)
+	EvidenceAdded bool   `json:"evidence_added"`        // preuve JWS signée lors du scellement
+	LedgerHash    string `json:"ledger_hash,omitempty"` // entrée document.created ajoutée lors du scellement
+}
+
+// IsEventType indique si entryType est un type d'événement connu (hors document.created)
+func IsEventType(entryType string) bool {
+	switch entryType {
+	case EntryTypeDocumentStatusChanged, EntryTypeKeyRotated, EntryTypePeriodClosed, EntryTypeDocumentPurged, EntryTypeDocumentSealed:
+		return true
+	}
+	return false
+}
+
+// CanonicalBody sérialise le corps d'un événement en JSON canonique : clés d'objet
+// triées, sans espace, nombres conservés tels quels. Un corps nil donne "{}"
+func CanonicalBody(v interface{}) (string, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event body: %w", err)
+	}
+	// Passage par interface{} : encoding/json trie les clés des maps
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return "", fmt.Errorf("failed to decode event body: %w", err)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(generic); err != nil {
+		return "", fmt.Errorf("failed to marshal event body: %w", err)
+	}
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
+
+// EventDigest calcule le condensat d'un événement, qui remplace le sha256 du document
+// dans le hash de l'entrée :
+// SHA256("dorevia-ledger-event\n" + entry_type + "\n" + document_id + "\n" + body)
+func EventDigest(entryType, documentID, body string) string {
+	digest := sha256.Sum256([]byte("dorevia-ledger-event\n" + entryType + "\n" + documentID + "\n" + body))
+	return hex.EncodeToString(digest[:])
+}
+
+// isEvent indique si l'entrée est un événement (et non l'enregistrement d'un document)
+func (e ChainEntry) isEvent() bool {
+	return e.EntryType != "" && e.EntryType != EntryTypeDocumentCreated
+}
+
+// entryType retourne le type de l'entrée (document.created pour les entrées antérieures à entry_type)
+func (e ChainEntry) entryType() string {
+	if e.EntryType == "" {
+		return EntryTypeDocumentCreated
+	}
+	return e.EntryType
+}
+
+// eventDigest retourne le condensat de l'événement, ou celui conservé par une entrée
+// anonymisée (corps retiré)
+func (e ChainEntry) eventDigest() string {
+	if e.Body == "" && e.ContentDigest != "" {
+		return e.ContentDigest
+	}
+	return EventDigest(e.EntryType, e.DocumentID, e.Body)
+}
@@ -22,7 +22,7 @@ func ExportLedgerJSON(ctx context.Context, pool *pgxpool.Pool, w io.Writer, limi
 
 	// Requête avec pagination
 	rows, err := pool.Query(ctx, `
-		SELECT id, document_id, hash, previous_hash, timestamp, evidence_jws
+		SELECT id, COALESCE(document_id::text, ''), hash, previous_hash, timestamp, evidence_jws, entry_type, body
 		FROM ledger
 		ORDER BY timestamp ASC, id ASC
 		LIMIT $1 OFFSET $2
@@ -37,11 +37,11 @@ func ExportLedgerJSON(ctx context.Context, pool *pgxpool.Pool, w io.Writer, limi
 	var entries []map[string]interface{}
 	for rows.Next() {
 		var id int
-		var docID string
-		var hash, prevHash, jws *string
+		var docID, entryType string
+		var hash, prevHash, jws, body *string
 		var timestamp string
 
-		if err := rows.Scan(&id, &docID, &hash, &prevHash, &timestamp, &jws); err != nil {
+		if err := rows.Scan(&id, &docID, &hash, &prevHash, &timestamp, &jws, &entryType, &body); err != nil {
 			return fmt.Errorf("failed to scan ledger entry: %w", err)
 		}
 
@@ -52,6 +52,8 @@ func ExportLedgerJSON(ctx context.Context, pool *pgxpool.Pool, w io.Writer, limi
 			"previous_hash": prevHash,
 			"timestamp":     timestamp,
 			"evidence_jws":  jws,
+			"entry_type":    entryType,
+			"body":          body,
 		}
 		entries = append(entries, entry)
 	}
@@ -89,7 +91,8 @@ func ExportLedgerCSV(ctx context.Context, pool *pgxpool.Pool, w io.Writer, limit
 
 	// Requête avec pagination
 	rows, err := pool.Query(ctx, `
-		SELECT id, document_id, hash, previous_hash, timestamp, evidence_jws
+		SELECT id, COALESCE(document_id::text, ''), hash, COALESCE(previous_hash, ''), timestamp,
+		       COALESCE(evidence_jws, ''), entry_type, COALESCE(body, '')
 		FROM ledger
 		ORDER BY timestamp ASC, id ASC
 		LIMIT $1 OFFSET $2
@@ -106,7 +109,7 @@ func ExportLedgerCSV(ctx context.Context, pool *pgxpool.Pool, w io.Writer, limit
 
 	// En-têtes CSV
 	if err := writer.Write([]string{
-		"id", "document_id", "hash", "previous_hash", "timestamp", "evidence_jws",
+		"id", "document_id", "hash", "previous_hash", "timestamp", "evidence_jws", "entry_type", "body",
 	}); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
@@ -114,10 +117,10 @@ func ExportLedgerCSV(ctx context.Context, pool *pgxpool.Pool, w io.Writer, limit
 	// Lignes de données
 	for rows.Next() {
 		var id int
-		var docID, hash, prevHash, jws string
+		var docID, hash, prevHash, jws, entryType, body string
 		var timestamp string
 
-		if err := rows.Scan(&id, &docID, &hash, &prevHash, &timestamp, &jws); err != nil {
+		if err := rows.Scan(&id, &docID, &hash, &prevHash, &timestamp, &jws, &entryType, &body); err != nil {
 			return fmt.Errorf("failed to scan ledger entry: %w", err)
 		}
 
@@ -128,6 +131,8 @@ func ExportLedgerCSV(ctx context.Context, pool *pgxpool.Pool, w io.Writer, limit
 			prevHash,
 			timestamp,
 			jws,
+			entryType,
+			body,
 		}
 
 		if err := writer.Write(record); err != nil {
@@ -0,0 +1,321 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/merkle"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// MerkleRootSubjectPrefix préfixe l'identifiant scellé dans le JWS d'une racine de Merkle
+const MerkleRootSubjectPrefix = "ledger-merkle-root:"
+
+// MerkleBatchLayout est le format des lots journaliers (jour UTC)
+const MerkleBatchLayout = "2006-01-02"
+
+// ErrNoMerkleRoot est retourné quand aucune racine signée ne couvre encore l'entrée
+var ErrNoMerkleRoot = errors.New("ledger entry not yet covered by a signed Merkle root")
+
+// MerkleRoot est la racine signée de l'arbre de Merkle (RFC 6962) des entrées du
+// ledger d'un jour UTC, dans l'ordre (timestamp, id). C'est le contenu scellé par le JWS
+type MerkleRoot struct {
+	Batch          string    `json:"batch"` // jour UTC (YYYY-MM-DD)
+	TreeSize       int64     `json:"tree_size"`
+	RootHash       string    `json:"root_hash"`
+	FirstEntryID   int64     `json:"first_entry_id"`
+	LastEntryID    int64     `json:"last_entry_id"`
+	FirstTimestamp time.Time `json:"first_timestamp"`
+	LastTimestamp  time.Time `json:"last_timestamp"`
+}
+
+// SignedMerkleRoot est une racine enregistrée dans ledger_merkle_roots avec son JWS
+type SignedMerkleRoot struct {
+	Root      MerkleRoot `json:"root"`
+	JWS       string     `json:"jws"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// InclusionProof prouve qu'un document figure dans le ledger : chemin d'audit de sa
+// feuille jusqu'à une racine signée, sans le reste du ledger
+type InclusionProof struct {
+	DocumentID    string     `json:"document_id"`
+	DocumentSHA   string     `json:"sha256_hex"`
+	LedgerHash    string     `json:"ledger_hash"`
+	LedgerEntryID int64      `json:"ledger_entry_id"`
+	LeafIndex     int64      `json:"leaf_index"`
+	AuditPath     []string   `json:"audit_path"` // hashes frères (hex), de la feuille vers la racine
+	Root          MerkleRoot `json:"root"`
+	RootJWS       string     `json:"root_jws"`
+}
+
+// ReportSigner scelle un rapport JSON (*crypto.Service)
+type ReportSigner interface {
+	SignReport(subject string, report interface{}, t time.Time) (string, error)
+}
+
+// MerkleLeaf retourne les données d'une feuille : document_id (16 octets) ||
+// SHA256 du document (32 octets) || hash de l'entrée du ledger (32 octets)
+func MerkleLeaf(documentID, shaHex, ledgerHash string) ([]byte, error) {
+	id, err := uuid.Parse(documentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document_id %q: %w", documentID, err)
+	}
+	sha, err := hex.DecodeString(shaHex)
+	if err != nil || len(sha) != sha256.Size {
+		return nil, fmt.Errorf("invalid document sha256 %q", shaHex)
+	}
+	hash, err := hex.DecodeString(ledgerHash)
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("invalid ledger hash %q", ledgerHash)
+	}
+	leaf := make([]byte, 0, len(id)+len(sha)+len(hash))
+	leaf = append(leaf, id[:]...)
+	leaf = append(leaf, sha...)
+	return append(leaf, hash...), nil
+}
+
+// entryLeaf retourne la feuille d'une entrée du ledger ; pour un événement, le
+// condensat de l'événement tient lieu de SHA256 du document et l'identifiant nul
+// celui d'un document absent
+func entryLeaf(e *ChainEntry) ([]byte, error) {
+	if !e.isEvent() {
+		return MerkleLeaf(e.DocumentID, e.DocumentSHA, e.Hash)
+	}
+	documentID := e.DocumentID
+	if documentID == "" {
+		documentID = uuid.Nil.String()
+	}
+	return MerkleLeaf(documentID, e.eventDigest(), e.Hash)
+}
+
+// BuildMerkleRoots construit et signe la racine de chaque jour UTC révolu (avant before)
+// dont les entrées du ledger n'ont pas encore de racine
+func BuildMerkleRoots(ctx context.Context, pool *pgxpool.Pool, signer ReportSigner, before time.Time) ([]*SignedMerkleRoot, error) {
+	end := before.UTC().Truncate(24 * time.Hour)
+
+	// Reprise après la dernière racine (le premier passage couvre tout l'historique)
+	var since time.Time
+	var last *time.Time
+	if err := pool.QueryRow(ctx, `SELECT max(batch_date)::timestamp FROM ledger_merkle_roots`).Scan(&last); err != nil {
+		return nil, fmt.Errorf("failed to get last Merkle root: %w", err)
+	}
+	if last != nil {
+		since = last.UTC().AddDate(0, 0, 1)
+	} else {
+		var first *time.Time
+		if err := pool.QueryRow(ctx, `SELECT min(timestamp) FROM ledger`).Scan(&first); err != nil {
+			return nil, fmt.Errorf("failed to get first ledger entry: %w", err)
+		}
+		if first == nil {
+			return nil, nil
+		}
+		since = first.UTC().Truncate(24 * time.Hour)
+	}
+
+	var roots []*SignedMerkleRoot
+	for day := since; day.Before(end); day = day.AddDate(0, 0, 1) {
+		root, err := BuildMerkleRoot(ctx, pool, signer, day)
+		if err != nil {
+			return roots, err
+		}
+		if root != nil {
+			roots = append(roots, root)
+		}
+	}
+	return roots, nil
+}
+
+// BuildMerkleRoot construit, signe et enregistre la racine du jour day ; nil si le
+// jour n'a aucune entrée ou si sa racine existe déjà
+func BuildMerkleRoot(ctx context.Context, pool *pgxpool.Pool, signer ReportSigner, day time.Time) (*SignedMerkleRoot, error) {
+	day = day.UTC().Truncate(24 * time.Hour)
+	rows, err := pool.Query(ctx, proofEntryColumns+`
+		WHERE l.timestamp >= $1 AND l.timestamp < $2
+		ORDER BY l.timestamp, l.id
+	`, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	root := MerkleRoot{Batch: day.Format(MerkleBatchLayout)}
+	var tree merkle.Builder
+	for rows.Next() {
+		e, err := scanProofEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		leaf, err := entryLeaf(e)
+		if err != nil {
+			return nil, fmt.Errorf("ledger entry %d: %w", e.ID, err)
+		}
+		tree.Add(leaf)
+		if root.FirstEntryID == 0 {
+			root.FirstEntryID = e.ID
+			root.FirstTimestamp = e.Timestamp.UTC()
+		}
+		root.LastEntryID = e.ID
+		root.LastTimestamp = e.Timestamp.UTC()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ledger entries: %w", err)
+	}
+	if tree.Size() == 0 {
+		return nil, nil
+	}
+	root.TreeSize = int64(tree.Size())
+	root.RootHash = hex.EncodeToString(tree.Root())
+
+	now := time.Now().UTC()
+	jws, err := signer.SignReport(MerkleRootSubjectPrefix+root.Batch, root, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign Merkle root: %w", err)
+	}
+
+	signed := &SignedMerkleRoot{Root: root, JWS: jws}
+	err = pool.QueryRow(ctx, `
+		INSERT INTO ledger_merkle_roots (batch_date, tree_size, root_hash, first_entry_id, last_entry_id,
+			first_timestamp, last_timestamp, leaf_hashes, root_jws)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (batch_date) DO NOTHING
+		RETURNING created_at
+	`, day, root.TreeSize, root.RootHash, root.FirstEntryID, root.LastEntryID,
+		root.FirstTimestamp, root.LastTimestamp, bytes.Join(tree.LeafHashes(), nil), jws).Scan(&signed.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert Merkle root: %w", err)
+	}
+	return signed, nil
+}
+
+// StartMerkleScheduler construit les racines des jours révolus dès le démarrage puis à chaque intervalle
+func StartMerkleScheduler(ctx context.Context, pool *pgxpool.Pool, signer ReportSigner, interval time.Duration, log zerolog.Logger) {
+	if interval == 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			roots, err := BuildMerkleRoots(ctx, pool, signer, time.Now())
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to build ledger Merkle roots")
+			}
+			for _, r := range roots {
+				log.Info().
+					Str("batch", r.Root.Batch).
+					Int64("tree_size", r.Root.TreeSize).
+					Str("root_hash", r.Root.RootHash).
+					Msg("Ledger Merkle root signed")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// LoadInclusionProof calcule la preuve d'inclusion de l'entrée du ledger d'un document
+// dans la racine signée de son jour. ErrNoMerkleRoot si la racine n'est pas encore construite
+func LoadInclusionProof(ctx context.Context, pool *pgxpool.Pool, docID uuid.UUID) (*InclusionProof, error) {
+	entry, err := LoadEntry(ctx, pool, docID)
+	if err != nil {
+		return nil, err
+	}
+
+	var root MerkleRoot
+	var batch time.Time
+	var leafHashes []byte
+	var jws string
+	err = pool.QueryRow(ctx, `
+		SELECT batch_date::timestamp, tree_size, root_hash, first_entry_id, last_entry_id,
+		       first_timestamp, last_timestamp, leaf_hashes, root_jws
+		FROM ledger_merkle_roots
+		WHERE batch_date = ($1::timestamptz AT TIME ZONE 'UTC')::date
+	`, entry.Timestamp).Scan(&batch, &root.TreeSize, &root.RootHash, &root.FirstEntryID, &root.LastEntryID,
+		&root.FirstTimestamp, &root.LastTimestamp, &leafHashes, &jws)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoMerkleRoot
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Merkle root: %w", err)
+	}
+	root.Batch = batch.Format(MerkleBatchLayout)
+	root.FirstTimestamp = root.FirstTimestamp.UTC()
+	root.LastTimestamp = root.LastTimestamp.UTC()
+	if int64(len(leafHashes)) != root.TreeSize*sha256.Size {
+		return nil, fmt.Errorf("corrupted Merkle leaves for batch %s", root.Batch)
+	}
+
+	leaf, err := MerkleLeaf(entry.DocumentID, entry.DocumentSHA, entry.Hash)
+	if err != nil {
+		return nil, err
+	}
+	leafHash := merkle.LeafHash(leaf)
+	hashes := make([][]byte, root.TreeSize)
+	index := -1
+	for i := range hashes {
+		hashes[i] = leafHashes[i*sha256.Size : (i+1)*sha256.Size]
+		if index < 0 && bytes.Equal(hashes[i], leafHash) {
+			index = i
+		}
+	}
+	// Entrée validée après la construction de la racine de son jour
+	if index < 0 {
+		return nil, ErrNoMerkleRoot
+	}
+
+	path, err := merkle.InclusionProof(hashes, index)
+	if err != nil {
+		return nil, err
+	}
+	proof := &InclusionProof{
+		DocumentID:    entry.DocumentID,
+		DocumentSHA:   entry.DocumentSHA,
+		LedgerHash:    entry.Hash,
+		LedgerEntryID: entry.ID,
+		LeafIndex:     int64(index),
+		AuditPath:     make([]string, len(path)),
+		Root:          root,
+		RootJWS:       jws,
+	}
+	for i, h := range path {
+		proof.AuditPath[i] = hex.EncodeToString(h)
+	}
+	return proof, nil
+}
+
+// Verify vérifie le chemin d'audit de la preuve jusqu'à sa racine (hors signature)
+func (p *InclusionProof) Verify() error {
+	leaf, err := MerkleLeaf(p.DocumentID, p.DocumentSHA, p.LedgerHash)
+	if err != nil {
+		return err
+	}
+	root, err := hex.DecodeString(p.Root.RootHash)
+	if err != nil {
+		return fmt.Errorf("invalid Merkle root hash: %w", err)
+	}
+	path := make([][]byte, len(p.AuditPath))
+	for i, h := range p.AuditPath {
+		if path[i], err = hex.DecodeString(h); err != nil {
+			return fmt.Errorf("invalid audit path hash %d: %w", i, err)
+		}
+	}
+	return merkle.VerifyInclusion(merkle.LeafHash(leaf), p.LeafIndex, p.Root.TreeSize, path, root)
+}
@@ -2,8 +2,6 @@ package ledger
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -28,7 +26,13 @@ func NewPartitionManager(pool *pgxpool.Pool, log zerolog.Logger) *PartitionManag
 }
 
 // EnsurePartition crée une partition pour un mois donné si elle n'existe pas
+// Le ledger doit être partitionné (voir ConvertToPartitioned)
 func (p *PartitionManager) EnsurePartition(ctx context.Context, year int, month int) error {
+	return p.ensurePartitionOf(ctx, "ledger", year, month)
+}
+
+// ensurePartitionOf crée la partition mensuelle ledger_YYYY_MM de la table parent
+func (p *PartitionManager) ensurePartitionOf(ctx context.Context, parent string, year int, month int) error {
 	partitionName := p.getPartitionName(year, month)
 	
 	// Vérifier si la partition existe déjà
@@ -51,14 +55,13 @@ func (p *PartitionManager) EnsurePartition(ctx context.Context, year int, month
 		return nil
 	}
 	
-	// Créer la partition
-	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-	endDate := startDate.AddDate(0, 1, 0) // Premier jour du mois suivant
+	// Créer la partition (bornes en UTC : timestamp est un TIMESTAMPTZ)
+	startDate, endDate := PartitionBounds(year, month)
 	
 	createSQL := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s PARTITION OF ledger
+		CREATE TABLE IF NOT EXISTS %s PARTITION OF %s
 		FOR VALUES FROM ('%s') TO ('%s')
-	`, partitionName, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	`, partitionName, parent, startDate.Format(time.RFC3339), endDate.Format(time.RFC3339))
 	
 	_, err = p.pool.Exec(ctx, createSQL)
 	if err != nil {
@@ -74,15 +77,22 @@ func (p *PartitionManager) EnsurePartition(ctx context.Context, year int, month
 	return nil
 }
 
+// PartitionBounds retourne les bornes [début, fin) en UTC de la partition d'un mois
+func PartitionBounds(year int, month int) (time.Time, time.Time) {
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
 // EnsureCurrentPartition crée la partition pour le mois actuel
 func (p *PartitionManager) EnsureCurrentPartition(ctx context.Context) error {
-	now := time.Now()
+	now := time.Now().UTC()
 	return p.EnsurePartition(ctx, now.Year(), int(now.Month()))
 }
 
 // EnsureNextPartition crée la partition pour le mois suivant
 func (p *PartitionManager) EnsureNextPartition(ctx context.Context) error {
-	nextMonth := time.Now().AddDate(0, 1, 0)
+	now := time.Now().UTC()
+	nextMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
 	return p.EnsurePartition(ctx, nextMonth.Year(), int(nextMonth.Month()))
 }
 
@@ -135,20 +145,28 @@ func (p *PartitionManager) MigrateExistingData(ctx context.Context) error {
 
 // getPartitionName retourne le nom de la partition pour un mois donné
 func (p *PartitionManager) getPartitionName(year int, month int) string {
+	return PartitionName(year, month)
+}
+
+// PartitionName retourne le nom de la partition mensuelle du ledger (ledger_YYYY_MM)
+func PartitionName(year int, month int) string {
 	return fmt.Sprintf("ledger_%d_%02d", year, month)
 }
 
-// GetPartitionInfo retourne les informations sur les partitions existantes
+// GetPartitionInfo retourne les informations sur les partitions attachées au ledger
+// (liste vide si le ledger n'est pas partitionné)
 func (p *PartitionManager) GetPartitionInfo(ctx context.Context) ([]PartitionInfo, error) {
 	rows, err := p.pool.Query(ctx, `
 		SELECT 
-			relname as partition_name,
-			pg_size_pretty(pg_total_relation_size(relname::regclass)) as size,
-			(SELECT COUNT(*) FROM pg_inherits WHERE inhrelid = rel.oid) as is_partition
-		FROM pg_class rel
-		WHERE relname LIKE 'ledger_%' 
-		AND relkind = 'r'
-		ORDER BY relname ASC
+			rel.relname as partition_name,
+			pg_size_pretty(pg_total_relation_size(rel.oid)) as size,
+			pg_total_relation_size(rel.oid) as size_bytes,
+			GREATEST(rel.reltuples, 0)::bigint as row_estimate,
+			pg_get_expr(rel.relpartbound, rel.oid) as bound
+		FROM pg_inherits inh
+		JOIN pg_class rel ON rel.oid = inh.inhrelid
+		WHERE inh.inhparent = to_regclass('ledger')
+		ORDER BY rel.relname ASC
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query partitions: %w", err)
@@ -157,8 +175,8 @@ func (p *PartitionManager) GetPartitionInfo(ctx context.Context) ([]PartitionInf
 	
 	var partitions []PartitionInfo
 	for rows.Next() {
-		var info PartitionInfo
-		if err := rows.Scan(&info.Name, &info.Size, &info.IsPartition); err != nil {
+		info := PartitionInfo{IsPartition: true}
+		if err := rows.Scan(&info.Name, &info.Size, &info.SizeBytes, &info.RowEstimate, &info.Bound); err != nil {
 			return nil, fmt.Errorf("failed to scan partition info: %w", err)
 		}
 		partitions = append(partitions, info)
@@ -173,113 +191,51 @@ func (p *PartitionManager) GetPartitionInfo(ctx context.Context) ([]PartitionInf
 
 // PartitionInfo contient les informations sur une partition
 type PartitionInfo struct {
-	Name        string
-	Size        string
-	IsPartition bool
+	Name        string `json:"name"`
+	Size        string `json:"size"`
+	SizeBytes   int64  `json:"size_bytes"`
+	RowEstimate int64  `json:"row_estimate"` // estimation (statistiques ANALYZE)
+	Bound       string `json:"bound"`
+	IsPartition bool   `json:"-"`
 }
 
-// SetupPartitionedLedger configure le ledger avec partitionnement
-// Cette fonction doit être appelée une fois pour initialiser le partitionnement
-func SetupPartitionedLedger(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger) error {
-	manager := NewPartitionManager(pool, log)
-	
-	// 1. Vérifier si le ledger est déjà partitionné
-	var isPartitioned bool
+// IsPartitioned indique si la table ledger est partitionnée
+func (p *PartitionManager) IsPartitioned(ctx context.Context) (bool, error) {
+	return isLedgerPartitioned(ctx, p.pool)
+}
+
+// isLedgerPartitioned indique si la table ledger est une table partitionnée
+func isLedgerPartitioned(ctx context.Context, pool *pgxpool.Pool) (bool, error) {
+	var partitioned bool
 	err := pool.QueryRow(ctx, `
 		SELECT EXISTS (
-			SELECT 1 FROM pg_inherits 
-			WHERE inhparent = 'ledger'::regclass
+			SELECT 1 FROM pg_partitioned_table
+			WHERE partrelid = to_regclass('ledger')
 		)
-	`).Scan(&isPartitioned)
-	
+	`).Scan(&partitioned)
 	if err != nil {
-		return fmt.Errorf("failed to check partition status: %w", err)
+		return false, fmt.Errorf("failed to check partition status: %w", err)
 	}
+	return partitioned, nil
+}
+
+// SetupPartitionedLedger configure le ledger avec partitionnement
+// Conversion en ligne (ConvertToPartitioned) si le ledger n'est pas encore partitionné,
+// puis création des partitions du mois actuel et suivant
+func SetupPartitionedLedger(ctx context.Context, pool *pgxpool.Pool, log zerolog.Logger) error {
+	manager := NewPartitionManager(pool, log)
 	
-	if isPartitioned {
-		log.Info().Msg("Ledger is already partitioned")
-		// Migrer les données existantes si nécessaire
-		return manager.MigrateExistingData(ctx)
-	}
-	
-	// 2. Convertir la table ledger en table partitionnée
-	// Note: Cette opération nécessite que la table soit vide ou utilise ALTER TABLE
-	log.Info().Msg("Converting ledger to partitioned table")
-	
-	// Créer une nouvelle table partitionnée
-	_, err = pool.Exec(ctx, `
-		CREATE TABLE IF NOT EXISTS ledger_new (
-			id SERIAL,
-			document_id UUID NOT NULL,
-			hash TEXT NOT NULL,
-			previous_hash TEXT,
-			timestamp TIMESTAMP NOT NULL DEFAULT NOW(),
-			evidence_jws TEXT,
-			PRIMARY KEY (id, timestamp),
-			UNIQUE (document_id, hash)
-		) PARTITION BY RANGE (timestamp)
-	`)
+	isPartitioned, err := manager.IsPartitioned(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create partitioned ledger: %w", err)
+		return err
 	}
 	
-	// Migrer les données (si la table existe déjà)
-	var tableExists bool
-	err = pool.QueryRow(ctx, `
-		SELECT EXISTS (
-			SELECT 1 FROM information_schema.tables 
-			WHERE table_name = 'ledger'
-		)
-	`).Scan(&tableExists)
-	
-	if err == nil && tableExists {
-		// Migrer les données vers la nouvelle table partitionnée
-		log.Info().Msg("Migrating existing ledger data")
-		
-		// Créer les partitions pour les mois existants
-		if err := manager.MigrateExistingData(ctx); err != nil {
-			return fmt.Errorf("failed to migrate data: %w", err)
-		}
-		
-		// Copier les données (dans une transaction)
-		tx, err := pool.Begin(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to begin transaction: %w", err)
-		}
-		defer tx.Rollback(ctx)
-		
-		_, err = tx.Exec(ctx, `
-			INSERT INTO ledger_new (document_id, hash, previous_hash, timestamp, evidence_jws)
-			SELECT document_id, hash, previous_hash, timestamp, evidence_jws
-			FROM ledger
-		`)
-		if err != nil {
-			return fmt.Errorf("failed to copy data: %w", err)
-		}
-		
-		// Remplacer l'ancienne table
-		_, err = tx.Exec(ctx, `
-			DROP TABLE IF EXISTS ledger CASCADE;
-			ALTER TABLE ledger_new RENAME TO ledger;
-		`)
-		if err != nil {
-			return fmt.Errorf("failed to replace table: %w", err)
-		}
-		
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("failed to commit migration: %w", err)
-		}
-		
-		log.Info().Msg("Ledger migration completed")
-	} else {
-		// Pas de données existantes, renommer directement
-		_, err = pool.Exec(ctx, `ALTER TABLE ledger_new RENAME TO ledger`)
-		if err != nil {
-			return fmt.Errorf("failed to rename table: %w", err)
-		}
+	if isPartitioned {
+		log.Info().Msg("Ledger is already partitioned")
+	} else if _, err := ConvertToPartitioned(ctx, pool, ConvertOptions{}, log); err != nil {
+		return err
 	}
 	
-	// 3. Créer les partitions pour le mois actuel et suivant
 	if err := manager.EnsureCurrentPartition(ctx); err != nil {
 		return fmt.Errorf("failed to create current partition: %w", err)
 	}
@@ -293,56 +249,8 @@ func SetupPartitionedLedger(ctx context.Context, pool *pgxpool.Pool, log zerolog
 }
 
 // AppendLedgerPartitioned ajoute une entrée au ledger partitionné
-// Cette fonction est une version optimisée de AppendLedger pour les tables partitionnées
+// Deprecated: AppendLedger lit la tête de chaîne dans ledger_head et fonctionne
+// à l'identique sur une table partitionnée ou non
 func AppendLedgerPartitioned(ctx context.Context, tx pgx.Tx, docID uuid.UUID, shaHex, jws string) (string, error) {
-	// Utiliser la même logique que AppendLedger mais optimisée pour les partitions
-	// La partition sera automatiquement sélectionnée par PostgreSQL basée sur timestamp
-	
-	// 1. Récupérer le previous_hash avec verrou exclusif
-	// Pour les tables partitionnées, on peut optimiser en cherchant dans la partition actuelle
-	var previousHash *string
-	err := tx.QueryRow(ctx, `
-		SELECT hash FROM ledger 
-		WHERE timestamp >= DATE_TRUNC('month', NOW())
-		ORDER BY timestamp DESC, id DESC 
-		LIMIT 1 
-		FOR UPDATE
-	`).Scan(&previousHash)
-	
-	// Si pas trouvé dans le mois actuel, chercher dans toutes les partitions
-	if err == pgx.ErrNoRows {
-		err = tx.QueryRow(ctx, `
-			SELECT hash FROM ledger 
-			ORDER BY timestamp DESC, id DESC 
-			LIMIT 1 
-			FOR UPDATE
-		`).Scan(&previousHash)
-	}
-	
-	// 2. Calculer le nouveau hash (même logique que AppendLedger)
-	var newHash string
-	if err == pgx.ErrNoRows || previousHash == nil {
-		hash := sha256.Sum256([]byte(shaHex))
-		newHash = hex.EncodeToString(hash[:])
-	} else if err != nil {
-		return "", fmt.Errorf("failed to get previous hash: %w", err)
-	} else {
-		combined := *previousHash + shaHex
-		hash := sha256.Sum256([]byte(combined))
-		newHash = hex.EncodeToString(hash[:])
-	}
-	
-	// 3. Insérer dans le ledger (PostgreSQL sélectionnera automatiquement la bonne partition)
-	_, err = tx.Exec(ctx, `
-		INSERT INTO ledger (document_id, hash, previous_hash, evidence_jws, timestamp)
-		VALUES ($1, $2, $3, $4, NOW())
-		ON CONFLICT (document_id, hash) DO NOTHING
-	`, docID, newHash, previousHash, jws)
-	
-	if err != nil {
-		return "", fmt.Errorf("failed to insert into ledger: %w", err)
-	}
-	
-	return newHash, nil
+	return AppendLedger(ctx, tx, docID, shaHex, jws)
 }
-
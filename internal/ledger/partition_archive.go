@@ -0,0 +1,717 @@
+package ledger
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/metrics"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// ErrPartitionNotArchived est retourné à la restauration d'une partition sans archive enregistrée
+var ErrPartitionNotArchived = errors.New("partition not archived")
+
+// ArchivedEntry est une entrée du ledger dans un fichier d'archive de partition
+// (une ligne JSON par entrée, sha256 du document et JWS inclus pour la vérification hors ligne)
+type ArchivedEntry struct {
+	ChainEntry
+}
+
+// PartitionArchiveManifest décrit le fichier d'archive d'une partition mensuelle
+// (<partition>.manifest.json à côté de <partition>.jsonl.gz)
+type PartitionArchiveManifest struct {
+	Partition      string            `json:"partition"`
+	PeriodStart    time.Time         `json:"period_start"`
+	PeriodEnd      time.Time         `json:"period_end"`
+	File           string            `json:"file"`
+	SHA256Hex      string            `json:"sha256_hex"` // sha256 du fichier compressé
+	EntryCount     int64             `json:"entry_count"`
+	FirstEntryID   int64             `json:"first_entry_id,omitempty"`
+	LastEntryID    int64             `json:"last_entry_id,omitempty"`
+	HeadHash       string            `json:"head_hash,omitempty"` // dernier hash (ordre du ledger)
+	TenantHeads    map[string]string `json:"tenant_heads"`        // dernier hash par tenant ("" hors tenant)
+	PerTenantChain bool              `json:"per_tenant_chain"`
+	ArchivedAt     time.Time         `json:"archived_at"`
+}
+
+// PartitionArchive est une partition archivée enregistrée dans ledger_partition_archives
+type PartitionArchive struct {
+	Partition   string     `json:"partition"`
+	PeriodStart time.Time  `json:"period_start"`
+	PeriodEnd   time.Time  `json:"period_end"`
+	FilePath    string     `json:"file_path"`
+	SHA256Hex   string     `json:"sha256_hex"`
+	EntryCount  int64      `json:"entry_count"`
+	ArchivedAt  time.Time  `json:"archived_at"`
+	RestoredAt  *time.Time `json:"restored_at,omitempty"` // ré-attachée pour audit
+}
+
+// PartitionStatus est l'état du partitionnement du ledger
+type PartitionStatus struct {
+	Partitioned        bool               `json:"partitioned"`
+	NextPartitionReady bool               `json:"next_partition_ready"`
+	Partitions         []PartitionInfo    `json:"partitions"`
+	Archives           []PartitionArchive `json:"archives"`
+}
+
+// LifecycleOptions configure le cycle de vie des partitions
+type LifecycleOptions struct {
+	// ArchiveDir : répertoire des fichiers d'archive
+	ArchiveDir string
+	// RetentionMonths : âge (en mois révolus) au-delà duquel une partition est archivée (0 : jamais)
+	RetentionMonths int
+	// PerTenantChain : chaînes par tenant (têtes de chaîne des archives)
+	PerTenantChain bool
+}
+
+// PartitionArchivePaths retourne les chemins du fichier d'archive et du manifeste d'une partition
+func PartitionArchivePaths(dir, partition string) (string, string) {
+	return filepath.Join(dir, partition+".jsonl.gz"), filepath.Join(dir, partition+".manifest.json")
+}
+
+// ArchivePartition exporte la partition d'un mois révolu dans un fichier compressé
+// accompagné de son manifeste, vérifie le fichier écrit, puis détache et supprime
+// la partition. Les partitions plus anciennes doivent avoir été archivées avant :
+// les entrées restantes du ledger se chaînent ainsi sur la tête de la dernière archive.
+func (p *PartitionManager) ArchivePartition(ctx context.Context, year, month int, opts LifecycleOptions) (*PartitionArchiveManifest, error) {
+	manifest, err := p.archivePartition(ctx, year, month, opts)
+	if err != nil {
+		metrics.LedgerPartitionArchives.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	metrics.LedgerPartitionArchives.WithLabelValues("success").Inc()
+	p.log.Info().
+		Str("partition", manifest.Partition).
+		Int64("entries", manifest.EntryCount).
+		Str("file", manifest.File).
+		Str("sha256", manifest.SHA256Hex).
+		Msg("Ledger partition archived")
+	return manifest, nil
+}
+
+func (p *PartitionManager) archivePartition(ctx context.Context, year, month int, opts LifecycleOptions) (*PartitionArchiveManifest, error) {
+	partition := PartitionName(year, month)
+	start, end := PartitionBounds(year, month)
+	now := time.Now().UTC()
+	if end.After(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)) {
+		return nil, fmt.Errorf("partition %s is not in a past month", partition)
+	}
+	if opts.ArchiveDir == "" {
+		return nil, fmt.Errorf("archive directory not configured")
+	}
+
+	var attached bool
+	if err := p.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_inherits
+			WHERE inhparent = to_regclass('ledger') AND inhrelid = to_regclass($1)
+		)
+	`, partition).Scan(&attached); err != nil {
+		return nil, fmt.Errorf("failed to check partition %s: %w", partition, err)
+	}
+	if !attached {
+		return nil, fmt.Errorf("partition %s is not attached to the ledger", partition)
+	}
+	var older bool
+	if err := p.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM ledger WHERE timestamp < $1)`, start).Scan(&older); err != nil {
+		return nil, fmt.Errorf("failed to check older ledger entries: %w", err)
+	}
+	if older {
+		return nil, fmt.Errorf("ledger entries older than %s are still attached, archive older partitions first", partition)
+	}
+
+	if err := os.MkdirAll(opts.ArchiveDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	archivePath, manifestPath := PartitionArchivePaths(opts.ArchiveDir, partition)
+
+	// 1. Export (la partition d'un mois révolu ne reçoit plus d'ajouts)
+	manifest, err := p.exportPartition(ctx, partition, archivePath)
+	if err != nil {
+		return nil, err
+	}
+	manifest.PeriodStart = start
+	manifest.PeriodEnd = end
+	manifest.PerTenantChain = opts.PerTenantChain
+	manifest.ArchivedAt = time.Now().UTC()
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal archive manifest: %w", err)
+	}
+	if err := writeFileSync(manifestPath, manifestJSON); err != nil {
+		return nil, err
+	}
+
+	// 2. Relecture du fichier écrit avant toute suppression
+	verified, report, err := VerifyPartitionArchive(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("archive verification failed: %w", err)
+	}
+	if !report.Valid {
+		return nil, fmt.Errorf("partition %s chain is broken, archive kept but partition not detached", partition)
+	}
+
+	// 3. Détachement et suppression de la partition
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE ledger DETACH PARTITION %s`, partition)); err != nil {
+		return nil, fmt.Errorf("failed to detach partition %s: %w", partition, err)
+	}
+	var count int64
+	if err := tx.QueryRow(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, partition)).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count partition %s: %w", partition, err)
+	}
+	if count != verified.EntryCount {
+		return nil, fmt.Errorf("partition %s changed during archiving (%d != %d entries)", partition, count, verified.EntryCount)
+	}
+	tenantHeads, err := json.Marshal(verified.TenantHeads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tenant heads: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO ledger_partition_archives (partition_name, period_start, period_end, file_path,
+			sha256_hex, entry_count, head_hash, tenant_heads, manifest, archived_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (partition_name) DO UPDATE SET
+			file_path = EXCLUDED.file_path, sha256_hex = EXCLUDED.sha256_hex,
+			entry_count = EXCLUDED.entry_count, head_hash = EXCLUDED.head_hash,
+			tenant_heads = EXCLUDED.tenant_heads, manifest = EXCLUDED.manifest,
+			archived_at = EXCLUDED.archived_at, restored_at = NULL
+	`, partition, start, end, archivePath, verified.SHA256Hex, verified.EntryCount,
+		nullableHash(verified.HeadHash), tenantHeads, manifestJSON, verified.ArchivedAt); err != nil {
+		return nil, fmt.Errorf("failed to record partition archive: %w", err)
+	}
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`DROP TABLE %s`, partition)); err != nil {
+		return nil, fmt.Errorf("failed to drop partition %s: %w", partition, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit partition archive: %w", err)
+	}
+	return verified, nil
+}
+
+// exportPartition écrit les entrées de la partition dans le fichier d'archive
+// (JSON lines compressé) et retourne le manifeste correspondant
+func (p *PartitionManager) exportPartition(ctx context.Context, partition, archivePath string) (*PartitionArchiveManifest, error) {
+	tmpPath := archivePath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer os.Remove(tmpPath)
+	defer file.Close()
+
+	hasher := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(file, hasher))
+	encoder := json.NewEncoder(gz)
+
+	rows, err := p.pool.Query(ctx, `
+		SELECT l.id, COALESCE(l.document_id::text, ''), l.hash, l.previous_hash,
+		       COALESCE(d.sha256_hex, ''), l.timestamp, l.tenant, l.seq, l.evidence_jws, l.hash_version,
+		       l.entry_type, COALESCE(l.body, '')
+		FROM `+partition+` l
+		LEFT JOIN documents d ON d.id = l.document_id
+		ORDER BY l.seq ASC NULLS FIRST, l.timestamp ASC, l.id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query partition %s: %w", partition, err)
+	}
+	defer rows.Close()
+
+	manifest := &PartitionArchiveManifest{
+		Partition:   partition,
+		File:        filepath.Base(archivePath),
+		TenantHeads: map[string]string{},
+	}
+	for rows.Next() {
+		var e ArchivedEntry
+		if err := rows.Scan(&e.ID, &e.DocumentID, &e.Hash, &e.PreviousHash, &e.DocumentSHA,
+			&e.Timestamp, &e.Tenant, &e.Seq, &e.EvidenceJWS, &e.HashVersion, &e.EntryType, &e.Body); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		if err := encoder.Encode(e); err != nil {
+			return nil, fmt.Errorf("failed to write archive entry: %w", err)
+		}
+		manifest.addEntry(e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating partition %s: %w", partition, err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress archive: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync archive file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close archive file: %w", err)
+	}
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		return nil, fmt.Errorf("failed to move archive file: %w", err)
+	}
+	manifest.SHA256Hex = hex.EncodeToString(hasher.Sum(nil))
+	return manifest, nil
+}
+
+// addEntry met à jour les compteurs et têtes de chaîne du manifeste
+func (m *PartitionArchiveManifest) addEntry(e ArchivedEntry) {
+	if m.EntryCount == 0 {
+		m.FirstEntryID = e.ID
+	}
+	m.EntryCount++
+	m.LastEntryID = e.ID
+	m.HeadHash = e.Hash
+	m.TenantHeads[derefHash(e.Tenant)] = e.Hash
+}
+
+// VerifyPartitionArchive vérifie hors ligne une archive de partition : empreinte du
+// fichier, nombre d'entrées, têtes de chaîne et chaînage interne (hash recalculés)
+func VerifyPartitionArchive(manifestPath string) (*PartitionArchiveManifest, *ChainReport, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read archive manifest: %w", err)
+	}
+	var manifest PartitionArchiveManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid archive manifest: %w", err)
+	}
+
+	recomputed := &PartitionArchiveManifest{TenantHeads: map[string]string{}}
+	verifier := NewTenantChainVerifier(nil, manifest.PerTenantChain)
+	archivePath := filepath.Join(filepath.Dir(manifestPath), manifest.File)
+	sum, err := readPartitionArchive(archivePath, func(e ArchivedEntry) error {
+		// Les archives ne contiennent qu'une tranche : chaque chaîne part du
+		// previous_hash de sa première entrée
+		key := ""
+		if manifest.PerTenantChain {
+			key = derefHash(e.Tenant)
+		}
+		if _, started := verifier.chains[key]; !started {
+			verifier.anchors[key] = e.PreviousHash
+		}
+		verifier.Add(e.ChainEntry)
+		recomputed.addEntry(e)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case sum != manifest.SHA256Hex:
+		return nil, nil, fmt.Errorf("archive %s sha256 does not match manifest", manifest.File)
+	case recomputed.EntryCount != manifest.EntryCount:
+		return nil, nil, fmt.Errorf("archive %s entry count does not match manifest", manifest.File)
+	case recomputed.FirstEntryID != manifest.FirstEntryID || recomputed.LastEntryID != manifest.LastEntryID:
+		return nil, nil, fmt.Errorf("archive %s entry ids do not match manifest", manifest.File)
+	case recomputed.HeadHash != manifest.HeadHash || !sameHeads(recomputed.TenantHeads, manifest.TenantHeads):
+		return nil, nil, fmt.Errorf("archive %s chain heads do not match manifest", manifest.File)
+	}
+	return &manifest, verifier.Report(), nil
+}
+
+// readPartitionArchive lit les entrées d'un fichier d'archive et retourne son sha256
+func readPartitionArchive(path string, fn func(ArchivedEntry) error) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open archive file: %w", err)
+	}
+	defer file.Close()
+
+	// Tous les octets lus passent par tee : l'empreinte couvre le fichier entier
+	hasher := sha256.New()
+	tee := io.TeeReader(file, hasher)
+	gz, err := gzip.NewReader(tee)
+	if err != nil {
+		return "", fmt.Errorf("invalid archive file: %w", err)
+	}
+	decoder := json.NewDecoder(gz)
+	for {
+		var e ArchivedEntry
+		if err := decoder.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("invalid archive entry: %w", err)
+		}
+		if err := fn(e); err != nil {
+			return "", err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("invalid archive file: %w", err)
+	}
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return "", fmt.Errorf("failed to read archive file: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// RestorePartition ré-attache au ledger une partition archivée, pour audit.
+// L'archive doit correspondre à celle enregistrée (sha256) et être intègre ;
+// la partition peut ensuite être de nouveau archivée (ArchivePartition)
+func (p *PartitionManager) RestorePartition(ctx context.Context, manifestPath string) (*PartitionArchiveManifest, error) {
+	manifest, report, err := VerifyPartitionArchive(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	if !report.Valid {
+		return nil, fmt.Errorf("archive %s chain is broken", manifest.File)
+	}
+
+	var recorded string
+	err = p.pool.QueryRow(ctx, `SELECT sha256_hex FROM ledger_partition_archives WHERE partition_name = $1`, manifest.Partition).Scan(&recorded)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPartitionNotArchived
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get partition archive: %w", err)
+	}
+	if recorded != manifest.SHA256Hex {
+		return nil, fmt.Errorf("archive %s does not match the recorded archive sha256", manifest.File)
+	}
+	expectedPartition := PartitionName(manifest.PeriodStart.Year(), int(manifest.PeriodStart.Month()))
+	if manifest.Partition != expectedPartition {
+		return nil, fmt.Errorf("invalid partition name %q in manifest", manifest.Partition)
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`CREATE TABLE %s (LIKE ledger INCLUDING DEFAULTS)`, manifest.Partition)); err != nil {
+		return nil, fmt.Errorf("failed to create partition %s: %w", manifest.Partition, err)
+	}
+
+	// Réimport en flux depuis le fichier (déjà vérifié)
+	entries := make(chan ArchivedEntry, 256)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(entries)
+		_, err := readPartitionArchive(filepath.Join(filepath.Dir(manifestPath), manifest.File), func(e ArchivedEntry) error {
+			select {
+			case entries <- e:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		readErr <- err
+	}()
+	copied, err := tx.CopyFrom(ctx, pgx.Identifier{manifest.Partition},
+		[]string{"id", "document_id", "hash", "previous_hash", "timestamp", "evidence_jws", "tenant", "seq", "hash_version", "entry_type", "body"},
+		pgx.CopyFromFunc(func() ([]any, error) {
+			e, ok := <-entries
+			if !ok {
+				return nil, nil
+			}
+			var documentID, body *string
+			if e.DocumentID != "" {
+				documentID = &e.DocumentID
+			}
+			if e.isEvent() {
+				body = &e.Body
+			}
+			return []any{e.ID, documentID, e.Hash, e.PreviousHash, e.Timestamp, e.EvidenceJWS, e.Tenant, e.Seq, int16(e.version()),
+				e.entryType(), body}, nil
+		}))
+	if err != nil {
+		for range entries {
+		}
+		return nil, fmt.Errorf("failed to import partition %s: %w", manifest.Partition, err)
+	}
+	if err := <-readErr; err != nil {
+		return nil, err
+	}
+	if copied != manifest.EntryCount {
+		return nil, fmt.Errorf("partition %s import count mismatch (%d != %d)", manifest.Partition, copied, manifest.EntryCount)
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE ledger ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`,
+		manifest.Partition, manifest.PeriodStart.UTC().Format(time.RFC3339), manifest.PeriodEnd.UTC().Format(time.RFC3339))); err != nil {
+		return nil, fmt.Errorf("failed to attach partition %s: %w", manifest.Partition, err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE ledger_partition_archives SET restored_at = now() WHERE partition_name = $1`, manifest.Partition); err != nil {
+		return nil, fmt.Errorf("failed to record partition restore: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit partition restore: %w", err)
+	}
+
+	p.log.Info().Str("partition", manifest.Partition).Int64("entries", copied).Msg("Ledger partition restored")
+	return manifest, nil
+}
+
+// ArchiveExpired archive, de la plus ancienne à la plus récente, les partitions plus
+// anciennes que opts.RetentionMonths mois révolus. Les partitions ré-attachées pour
+// audit ne sont pas archivées automatiquement
+func (p *PartitionManager) ArchiveExpired(ctx context.Context, opts LifecycleOptions, now time.Time) ([]*PartitionArchiveManifest, error) {
+	if opts.RetentionMonths <= 0 {
+		return nil, nil
+	}
+	now = now.UTC()
+	cutoff := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -opts.RetentionMonths, 0)
+
+	partitions, err := p.GetPartitionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	restored := make(map[string]bool)
+	archives, err := p.ListArchives(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range archives {
+		if a.RestoredAt != nil {
+			restored[a.Partition] = true
+		}
+	}
+
+	var months []time.Time
+	for _, info := range partitions {
+		var year, month int
+		if _, err := fmt.Sscanf(info.Name, "ledger_%4d_%2d", &year, &month); err != nil || PartitionName(year, month) != info.Name {
+			continue
+		}
+		start, end := PartitionBounds(year, month)
+		if end.After(cutoff) || restored[info.Name] {
+			continue
+		}
+		months = append(months, start)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+
+	var manifests []*PartitionArchiveManifest
+	for _, m := range months {
+		manifest, err := p.ArchivePartition(ctx, m.Year(), int(m.Month()), opts)
+		if err != nil {
+			return manifests, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+// ListArchives retourne les partitions archivées, de la plus ancienne à la plus récente
+func (p *PartitionManager) ListArchives(ctx context.Context) ([]PartitionArchive, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT partition_name, period_start, period_end, file_path, sha256_hex, entry_count, archived_at, restored_at
+		FROM ledger_partition_archives
+		ORDER BY period_start ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query partition archives: %w", err)
+	}
+	defer rows.Close()
+
+	archives := []PartitionArchive{}
+	for rows.Next() {
+		var a PartitionArchive
+		if err := rows.Scan(&a.Partition, &a.PeriodStart, &a.PeriodEnd, &a.FilePath, &a.SHA256Hex,
+			&a.EntryCount, &a.ArchivedAt, &a.RestoredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan partition archive: %w", err)
+		}
+		archives = append(archives, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating partition archives: %w", err)
+	}
+	return archives, nil
+}
+
+// Status retourne l'état du partitionnement et met à jour les métriques correspondantes
+func (p *PartitionManager) Status(ctx context.Context) (*PartitionStatus, error) {
+	partitioned, err := p.IsPartitioned(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &PartitionStatus{Partitioned: partitioned, Partitions: []PartitionInfo{}}
+	if partitioned {
+		if status.Partitions, err = p.GetPartitionInfo(ctx); err != nil {
+			return nil, err
+		}
+		now := time.Now().UTC()
+		next := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+		nextName := PartitionName(next.Year(), int(next.Month()))
+		for _, info := range status.Partitions {
+			if info.Name == nextName {
+				status.NextPartitionReady = true
+			}
+		}
+	}
+	if status.Archives, err = p.ListArchives(ctx); err != nil {
+		return nil, err
+	}
+
+	archived, restored := 0, 0
+	for _, a := range status.Archives {
+		if a.RestoredAt != nil {
+			restored++
+		} else {
+			archived++
+		}
+	}
+	if partitioned {
+		metrics.LedgerPartitioned.Set(1)
+	} else {
+		metrics.LedgerPartitioned.Set(0)
+	}
+	metrics.LedgerPartitions.WithLabelValues("attached").Set(float64(len(status.Partitions)))
+	metrics.LedgerPartitions.WithLabelValues("archived").Set(float64(archived))
+	metrics.LedgerPartitions.WithLabelValues("restored").Set(float64(restored))
+	return status, nil
+}
+
+// StartPartitionScheduler démarre la maintenance périodique des partitions du ledger :
+// création des partitions du mois courant et suivant, archivage des partitions
+// expirées (opts.RetentionMonths). Sans effet tant que le ledger n'est pas partitionné
+func StartPartitionScheduler(ctx context.Context, manager *PartitionManager, opts LifecycleOptions, interval time.Duration, log zerolog.Logger) {
+	if interval == 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			runPartitionMaintenance(ctx, manager, opts, log)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runPartitionMaintenance exécute un passage de maintenance des partitions
+func runPartitionMaintenance(ctx context.Context, manager *PartitionManager, opts LifecycleOptions, log zerolog.Logger) {
+	defer func() {
+		if _, err := manager.Status(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to get ledger partition status")
+		}
+	}()
+
+	partitioned, err := manager.IsPartitioned(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check ledger partitioning")
+		return
+	}
+	if !partitioned {
+		log.Debug().Msg("Ledger not partitioned, partition maintenance skipped (see ledgerctl -convert)")
+		return
+	}
+
+	if err := manager.EnsureCurrentPartition(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to create current ledger partition")
+	}
+	if err := manager.EnsureNextPartition(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to create next ledger partition")
+	}
+	if _, err := manager.ArchiveExpired(ctx, opts, time.Now()); err != nil {
+		log.Error().Err(err).Msg("Failed to archive expired ledger partitions")
+	}
+}
+
+// archiveAnchors retourne les têtes de chaîne des partitions archivées (détachées)
+// antérieures aux entrées du ledger : ancrage des premières entrées restantes,
+// par tenant si perTenant
+func archiveAnchors(ctx context.Context, pool *pgxpool.Pool, perTenant bool) (map[string]*string, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT head_hash, tenant_heads FROM ledger_partition_archives
+		WHERE restored_at IS NULL
+		  AND period_end <= COALESCE((SELECT MIN(timestamp) FROM ledger), 'infinity')
+		ORDER BY period_start ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query partition archives: %w", err)
+	}
+	defer rows.Close()
+
+	anchors := make(map[string]*string)
+	for rows.Next() {
+		var head *string
+		var tenantHeads map[string]string
+		if err := rows.Scan(&head, &tenantHeads); err != nil {
+			return nil, fmt.Errorf("failed to scan partition archive: %w", err)
+		}
+		if !perTenant {
+			if head != nil {
+				anchors[""] = head
+			}
+			continue
+		}
+		for tenant, h := range tenantHeads {
+			h := h
+			anchors[tenant] = &h
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating partition archives: %w", err)
+	}
+	return anchors, nil
+}
+
+// writeFileSync écrit un fichier de façon atomique (fichier temporaire, fsync, renommage)
+func writeFileSync(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Base(path), err)
+	}
+	defer os.Remove(tmpPath)
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync %s: %w", filepath.Base(path), err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to move %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func sameHeads(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func nullableHash(h string) *string {
+	if h == "" {
+		return nil
+	}
+	return &h
+}
@@ -0,0 +1,297 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// ErrLedgerPartitioned est retourné par ConvertToPartitioned si le ledger est déjà partitionné
+var ErrLedgerPartitioned = errors.New("ledger is already partitioned")
+
+// UnpartitionedLedgerTable est le nom de l'ancienne table, conservée après conversion
+// pour retour arrière (à supprimer manuellement une fois la conversion validée)
+const UnpartitionedLedgerTable = "ledger_unpartitioned"
+
+// convertCatchUpMargin couvre les transactions en cours pendant la copie (timeout 30s)
+const convertCatchUpMargin = 5 * time.Minute
+
+// ledgerColumns sont les colonnes copiées lors de la conversion
+const ledgerColumns = "id, document_id, hash, previous_hash, timestamp, evidence_jws, tenant, seq, hash_version, entry_type, body"
+
+// ledgerIndexes sont les index du ledger (voir migrations Sprint 2 et tenant), recréés
+// sur la table partitionnée sous un nom temporaire puis renommés lors de la bascule
+var ledgerIndexes = []struct{ name, columns string }{
+	{"idx_ledger_document_id", "document_id"},
+	{"idx_ledger_timestamp", "timestamp DESC"},
+	{"idx_ledger_hash", "hash"},
+	{"idx_ledger_prev_hash", "previous_hash"},
+	{"idx_ledger_ts_id_desc", "timestamp DESC, id DESC"},
+	{"idx_ledger_tenant_ts_id_desc", "tenant, timestamp DESC, id DESC"},
+}
+
+// ConvertOptions configure la conversion du ledger en table partitionnée
+type ConvertOptions struct {
+	// PerTenantChain : chaînes par tenant (LEDGER_PER_TENANT_CHAIN) pour la vérification
+	PerTenantChain bool
+	// BatchSize : nombre d'entrées copiées par lot hors verrou (défaut 10000)
+	BatchSize int
+}
+
+// ConversionReport est le compte rendu d'une conversion
+type ConversionReport struct {
+	Before        *ChainReport `json:"before"`
+	After         *ChainReport `json:"after"`
+	EntriesCopied int64        `json:"entries_copied"`
+	Partitions    []string     `json:"partitions"`
+	StartedAt     time.Time    `json:"started_at"`
+	CompletedAt   time.Time    `json:"completed_at"`
+}
+
+// ConvertToPartitioned convertit en ligne la table ledger en table partitionnée par mois
+//
+//  1. la chaîne est vérifiée : une chaîne rompue n'est pas convertie
+//  2. ledger_new (partitionnée, partitions ledger_YYYY_MM du premier mois au mois
+//     suivant) est remplie par lots d'identifiants, sans bloquer les ajouts
+//  3. bascule dans une transaction courte : verrou EXCLUSIVE (lectures autorisées,
+//     ajouts en attente), rattrapage des entrées arrivées entre-temps, contrôle du
+//     nombre d'entrées, échange des noms ; les identifiants et la séquence sont conservés
+//  4. la chaîne est revérifiée sur la table partitionnée
+//
+// L'ancienne table est conservée sous le nom ledger_unpartitioned.
+func ConvertToPartitioned(ctx context.Context, pool *pgxpool.Pool, opts ConvertOptions, log zerolog.Logger) (*ConversionReport, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 10000
+	}
+	report := &ConversionReport{StartedAt: time.Now().UTC()}
+
+	partitioned, err := isLedgerPartitioned(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	if partitioned {
+		return nil, ErrLedgerPartitioned
+	}
+	var leftover bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, UnpartitionedLedgerTable).Scan(&leftover); err != nil {
+		return nil, fmt.Errorf("failed to check previous conversion: %w", err)
+	}
+	if leftover {
+		return nil, fmt.Errorf("table %s already exists (previous conversion), drop it first", UnpartitionedLedgerTable)
+	}
+
+	// 1. Vérification avant conversion
+	log.Info().Msg("Verifying ledger chain before conversion")
+	report.Before, err = VerifyChain(ctx, pool, ChainOptions{PerTenantChain: opts.PerTenantChain})
+	if err != nil {
+		return nil, err
+	}
+	if !report.Before.Valid {
+		return report, fmt.Errorf("ledger chain is broken (%d broken links, %d gaps, %d forks), conversion aborted",
+			report.Before.BrokenLinkCount, report.Before.GapCount, report.Before.ForkCount)
+	}
+
+	// 2. Table partitionnée et copie en ligne
+	var sequence string
+	if err := pool.QueryRow(ctx, `SELECT pg_get_serial_sequence('ledger', 'id')`).Scan(&sequence); err != nil {
+		return nil, fmt.Errorf("failed to get ledger id sequence: %w", err)
+	}
+	if _, err := pool.Exec(ctx, `DROP TABLE IF EXISTS ledger_new CASCADE`); err != nil {
+		return nil, fmt.Errorf("failed to drop leftover ledger_new: %w", err)
+	}
+	if _, err := pool.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE ledger_new (
+			id INTEGER NOT NULL DEFAULT nextval('%s'::regclass),
+			document_id UUID,
+			hash TEXT NOT NULL,
+			previous_hash TEXT,
+			timestamp TIMESTAMPTZ NOT NULL DEFAULT now(),
+			evidence_jws TEXT,
+			tenant TEXT,
+			seq BIGINT,
+			hash_version SMALLINT NOT NULL DEFAULT 1,
+			entry_type TEXT NOT NULL DEFAULT 'document.created',
+			body TEXT,
+			CONSTRAINT ledger_new_pkey PRIMARY KEY (id, timestamp),
+			CONSTRAINT ledger_new_doc_hash_key UNIQUE (document_id, hash, timestamp),
+			CONSTRAINT ledger_new_document_id_fkey FOREIGN KEY (document_id) REFERENCES documents(id) ON DELETE RESTRICT
+		) PARTITION BY RANGE (timestamp)
+	`, strings.ReplaceAll(sequence, "'", "''"))); err != nil {
+		return nil, fmt.Errorf("failed to create partitioned ledger: %w", err)
+	}
+
+	manager := NewPartitionManager(pool, log)
+	var first *time.Time
+	if err := pool.QueryRow(ctx, `SELECT MIN(timestamp) FROM ledger`).Scan(&first); err != nil {
+		return nil, fmt.Errorf("failed to get first ledger timestamp: %w", err)
+	}
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if first != nil {
+		f := first.UTC()
+		month = time.Date(f.Year(), f.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	last := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	for ; !month.After(last); month = month.AddDate(0, 1, 0) {
+		if err := manager.ensurePartitionOf(ctx, "ledger_new", month.Year(), int(month.Month())); err != nil {
+			return nil, err
+		}
+		report.Partitions = append(report.Partitions, PartitionName(month.Year(), int(month.Month())))
+	}
+
+	copyStart := time.Now()
+	var minID, maxID int64
+	if err := pool.QueryRow(ctx, `SELECT COALESCE(MIN(id), 0), COALESCE(MAX(id), 0) FROM ledger`).Scan(&minID, &maxID); err != nil {
+		return nil, fmt.Errorf("failed to get ledger id range: %w", err)
+	}
+	for lo := minID; lo <= maxID && maxID > 0; lo += int64(opts.BatchSize) {
+		tag, err := pool.Exec(ctx, `
+			INSERT INTO ledger_new (`+ledgerColumns+`)
+			SELECT `+ledgerColumns+` FROM ledger
+			WHERE id >= $1 AND id < $2
+		`, lo, lo+int64(opts.BatchSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy ledger entries: %w", err)
+		}
+		report.EntriesCopied += tag.RowsAffected()
+	}
+	log.Info().Int64("entries", report.EntriesCopied).Msg("Ledger entries copied to partitioned table")
+
+	for _, idx := range ledgerIndexes {
+		if _, err := pool.Exec(ctx, fmt.Sprintf(`CREATE INDEX %s ON ledger_new(%s)`, tempIndexName(idx.name), idx.columns)); err != nil {
+			return nil, fmt.Errorf("failed to create index %s: %w", idx.name, err)
+		}
+	}
+
+	// 3. Bascule
+	copied, err := switchToPartitioned(ctx, pool, sequence, copyStart.Add(-convertCatchUpMargin), maxID)
+	if err != nil {
+		return nil, err
+	}
+	report.EntriesCopied += copied
+	log.Info().Int64("caught_up", copied).Msg("Ledger switched to partitioned table")
+
+	// 4. Vérification après conversion
+	report.After, err = VerifyChain(ctx, pool, ChainOptions{PerTenantChain: opts.PerTenantChain})
+	if err != nil {
+		return report, err
+	}
+	report.CompletedAt = time.Now().UTC()
+	if !report.After.Valid || report.After.EntriesChecked < report.Before.EntriesChecked {
+		return report, fmt.Errorf("ledger chain verification failed after conversion (previous table kept as %s)", UnpartitionedLedgerTable)
+	}
+	return report, nil
+}
+
+// switchToPartitioned rattrape les entrées arrivées pendant la copie et échange les
+// tables sous verrou ; retourne le nombre d'entrées rattrapées
+func switchToPartitioned(ctx context.Context, pool *pgxpool.Pool, sequence string, since time.Time, copiedMaxID int64) (int64, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SET LOCAL lock_timeout = '30s'`); err != nil {
+		return 0, fmt.Errorf("failed to set lock timeout: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `LOCK TABLE ledger IN EXCLUSIVE MODE`); err != nil {
+		return 0, fmt.Errorf("failed to lock ledger: %w", err)
+	}
+
+	// Entrées validées après la copie (identifiant supérieur, ou transaction en cours pendant la copie)
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO ledger_new (`+ledgerColumns+`)
+		SELECT `+ledgerColumns+` FROM ledger l
+		WHERE (l.id > $1 OR l.timestamp >= $2)
+		  AND NOT EXISTS (SELECT 1 FROM ledger_new n WHERE n.id = l.id AND n.timestamp = l.timestamp)
+	`, copiedMaxID, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy remaining ledger entries: %w", err)
+	}
+
+	var oldCount, newCount int64
+	if err := tx.QueryRow(ctx, `SELECT (SELECT COUNT(*) FROM ledger), (SELECT COUNT(*) FROM ledger_new)`).Scan(&oldCount, &newCount); err != nil {
+		return 0, fmt.Errorf("failed to count ledger entries: %w", err)
+	}
+	if oldCount != newCount {
+		return 0, fmt.Errorf("ledger entry count mismatch after copy (%d != %d)", oldCount, newCount)
+	}
+
+	if err := renameLedgerObjects(ctx, tx, sequence); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit ledger switch: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// renameLedgerObjects renomme l'ancienne table et ses index (suffixe _unpartitioned),
+// puis donne à la table partitionnée les noms canoniques des migrations
+func renameLedgerObjects(ctx context.Context, tx pgx.Tx, sequence string) error {
+	rows, err := tx.Query(ctx, `SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = 'ledger'`)
+	if err != nil {
+		return fmt.Errorf("failed to list ledger indexes: %w", err)
+	}
+	var oldIndexes []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan ledger index: %w", err)
+		}
+		oldIndexes = append(oldIndexes, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list ledger indexes: %w", err)
+	}
+
+	statements := []string{
+		fmt.Sprintf(`ALTER TABLE ledger RENAME TO %s`, UnpartitionedLedgerTable),
+		fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN id DROP DEFAULT`, UnpartitionedLedgerTable),
+	}
+	for _, name := range oldIndexes {
+		statements = append(statements, fmt.Sprintf(`ALTER INDEX %s RENAME TO %s`,
+			pgx.Identifier{name}.Sanitize(), pgx.Identifier{unpartitionedName(name)}.Sanitize()))
+	}
+	statements = append(statements,
+		`ALTER TABLE ledger_new RENAME TO ledger`,
+		`ALTER INDEX ledger_new_pkey RENAME TO ledger_pkey`,
+		`ALTER INDEX ledger_new_doc_hash_key RENAME TO uq_ledger_doc_hash`,
+		`ALTER TABLE ledger RENAME CONSTRAINT ledger_new_document_id_fkey TO ledger_document_id_fkey`,
+		fmt.Sprintf(`ALTER SEQUENCE %s OWNED BY ledger.id`, sequence),
+	)
+	for _, idx := range ledgerIndexes {
+		statements = append(statements, fmt.Sprintf(`ALTER INDEX %s RENAME TO %s`, tempIndexName(idx.name), idx.name))
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to switch ledger tables (%s): %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// tempIndexName retourne le nom temporaire d'un index de ledger_new
+func tempIndexName(name string) string {
+	return strings.Replace(name, "idx_ledger_", "idx_ledger_new_", 1)
+}
+
+// unpartitionedName suffixe le nom d'un index de l'ancienne table (63 caractères max)
+func unpartitionedName(name string) string {
+	const suffix = "_unpartitioned"
+	if len(name)+len(suffix) > 63 {
+		name = name[:63-len(suffix)]
+	}
+	return name + suffix
+}
@@ -0,0 +1,138 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrNoLedgerEntry est retourné quand un document n'a pas d'entrée dans le ledger
+var ErrNoLedgerEntry = errors.New("no ledger entry for document")
+
+// ProofEntries regroupe l'entrée d'un document et ses voisines immédiates dans la
+// chaîne : de quoi recalculer les deux maillons qui l'encadrent sans accès au ledger.
+// Les voisines ne portent ni document_id, ni tenant, ni JWS (elles peuvent appartenir à un autre tenant)
+type ProofEntries struct {
+	Previous *ChainEntry `json:"previous,omitempty"`
+	Entry    ChainEntry  `json:"entry"`
+	Next     *ChainEntry `json:"next,omitempty"`
+}
+
+const proofEntryColumns = `
+	SELECT l.id, COALESCE(l.document_id::text, ''), l.hash, l.previous_hash, COALESCE(d.sha256_hex, ''), l.timestamp, l.tenant, l.seq,
+	       l.hash_version, l.evidence_jws, l.entry_type, COALESCE(l.body, '')
+	FROM ledger l
+	LEFT JOIN documents d ON d.id = l.document_id
+`
+
+// LoadEntry charge l'entrée du ledger d'un document (document.created, hors événements)
+func LoadEntry(ctx context.Context, pool *pgxpool.Pool, docID uuid.UUID) (*ChainEntry, error) {
+	entry, err := scanProofEntry(pool.QueryRow(ctx, proofEntryColumns+`
+		WHERE l.document_id = $1 AND l.entry_type = $2
+		ORDER BY l.id
+		LIMIT 1
+	`, docID, EntryTypeDocumentCreated))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoLedgerEntry
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ledger entry: %w", err)
+	}
+	return entry, nil
+}
+
+// LoadProofEntries charge l'entrée du document, celle qu'elle référence (previous_hash)
+// et celle qui la référence
+func LoadProofEntries(ctx context.Context, pool *pgxpool.Pool, docID uuid.UUID) (*ProofEntries, error) {
+	entry, err := LoadEntry(ctx, pool, docID)
+	if err != nil {
+		return nil, err
+	}
+	proof := &ProofEntries{Entry: *entry}
+
+	if entry.PreviousHash != nil {
+		previous, err := scanProofEntry(pool.QueryRow(ctx, proofEntryColumns+`
+			WHERE l.hash = $1
+			ORDER BY l.id
+			LIMIT 1
+		`, *entry.PreviousHash))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to load previous ledger entry: %w", err)
+		}
+		proof.Previous = anonymizeEntry(previous)
+	}
+
+	next, err := scanProofEntry(pool.QueryRow(ctx, proofEntryColumns+`
+		WHERE l.previous_hash = $1
+		ORDER BY l.id
+		LIMIT 1
+	`, entry.Hash))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to load next ledger entry: %w", err)
+	}
+	proof.Next = anonymizeEntry(next)
+
+	return proof, nil
+}
+
+func scanProofEntry(row pgx.Row) (*ChainEntry, error) {
+	var e ChainEntry
+	if err := row.Scan(&e.ID, &e.DocumentID, &e.Hash, &e.PreviousHash, &e.DocumentSHA, &e.Timestamp, &e.Tenant, &e.Seq,
+		&e.HashVersion, &e.EvidenceJWS, &e.EntryType, &e.Body); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// anonymizeEntry retire d'une entrée voisine ce qui identifie son document ;
+// une entrée v2 garde le condensat de son contenu, un événement celui de son corps,
+// pour le recalcul de son hash
+func anonymizeEntry(e *ChainEntry) *ChainEntry {
+	if e == nil {
+		return nil
+	}
+	if e.isEvent() {
+		e.ContentDigest = e.eventDigest()
+		e.Body = ""
+	} else if e.HashVersion == HashVersionV2 && e.DocumentSHA != "" {
+		e.ContentDigest = ContentDigestV2(e.DocumentID, e.DocumentSHA, e.EvidenceJWS)
+	}
+	e.DocumentID = ""
+	e.Tenant = nil
+	e.EvidenceJWS = nil
+	return e
+}
+
+// Verify recalcule les hash des entrées et vérifie leur chaînage
+func (p *ProofEntries) Verify() *ChainReport {
+	entries := make([]ChainEntry, 0, 3)
+	anchor := p.Entry.PreviousHash
+	if p.Previous != nil {
+		entries = append(entries, *p.Previous)
+		anchor = p.Previous.PreviousHash
+	}
+	entries = append(entries, p.Entry)
+	if p.Next != nil {
+		entries = append(entries, *p.Next)
+	}
+
+	report := VerifyChainEntries(entries, anchor)
+
+	// L'entrée référencée par previous_hash doit être fournie
+	if p.Entry.PreviousHash != nil && p.Previous == nil {
+		report.Valid = false
+		report.GapCount++
+		report.Gaps = append(report.Gaps, ChainIssue{
+			Type:       ChainIssueGap,
+			EntryID:    p.Entry.ID,
+			DocumentID: p.Entry.DocumentID,
+			Expected:   *p.Entry.PreviousHash,
+			Message:    "previous entry referenced by previous_hash is missing",
+		})
+	}
+	return report
+}
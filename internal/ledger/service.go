@@ -12,7 +12,12 @@ import (
 type Service interface {
 	// Append ajoute une entrée au ledger avec hash chaîné
 	// Prend une transaction en paramètre pour garantir l'atomicité
-	Append(ctx context.Context, tx pgx.Tx, docID uuid.UUID, shaHex, jws string) (string, error)
+	// tenant rattache l'entrée à un tenant ("" = aucun)
+	Append(ctx context.Context, tx pgx.Tx, tenant string, docID uuid.UUID, shaHex, jws string) (string, error)
+
+	// AppendEvent ajoute un événement typé (document.status_changed, key.rotated,
+	// period.closed, document.purged, document.sealed) à la chaîne du tenant
+	AppendEvent(ctx context.Context, tx pgx.Tx, tenant string, event Event) (string, error)
 
 	// ExistsByDocumentID vérifie si un document existe dans le ledger
 	ExistsByDocumentID(ctx context.Context, tx pgx.Tx, docID uuid.UUID) (bool, error)
@@ -7,17 +7,45 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+// Options configure le service ledger
+type Options struct {
+	// PerTenantChain : une chaîne de hash indépendante par tenant
+	PerTenantChain bool
+	// HashVersion : format des nouvelles entrées (HashVersionV1 par défaut, HashVersionV2)
+	HashVersion int
+}
+
+// hashVersion retourne le format des nouvelles entrées
+func (o Options) hashVersion() int {
+	if o.HashVersion == 0 {
+		return HashVersionV1
+	}
+	return o.HashVersion
+}
+
 // DefaultService implémente Service avec la logique existante
-type DefaultService struct{}
+type DefaultService struct {
+	opts Options
+}
 
 // NewService crée un nouveau service ledger par défaut
 func NewService() Service {
 	return &DefaultService{}
 }
 
+// NewServiceWithOptions crée un service ledger configuré
+func NewServiceWithOptions(opts Options) Service {
+	return &DefaultService{opts: opts}
+}
+
 // Append ajoute une entrée au ledger avec hash chaîné
-func (s *DefaultService) Append(ctx context.Context, tx pgx.Tx, docID uuid.UUID, shaHex, jws string) (string, error) {
-	return AppendLedger(ctx, tx, docID, shaHex, jws)
+func (s *DefaultService) Append(ctx context.Context, tx pgx.Tx, tenant string, docID uuid.UUID, shaHex, jws string) (string, error) {
+	return AppendEntry(ctx, tx, s.opts, tenant, docID, shaHex, jws)
+}
+
+// AppendEvent ajoute un événement typé au ledger
+func (s *DefaultService) AppendEvent(ctx context.Context, tx pgx.Tx, tenant string, event Event) (string, error) {
+	return AppendEvent(ctx, tx, s.opts, tenant, event)
 }
 
 // ExistsByDocumentID vérifie si un document existe dans le ledger
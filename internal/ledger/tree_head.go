@@ -0,0 +1,461 @@
+package ledger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/webhooks"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// TreeHeadSubject est l'identifiant scellé dans le JWS d'une tête de ledger, par le
+// coffre comme par les témoins qui la contre-signent
+const TreeHeadSubject = "ledger-tree-head"
+
+// MaxConsistencyEntries limite le nombre d'entrées d'une preuve de cohérence
+const MaxConsistencyEntries = 100000
+
+// ErrNoTreeHead est retourné quand aucune tête signée n'est encore publiée
+var ErrNoTreeHead = errors.New("no signed ledger tree head")
+
+// ErrConsistencyTooLarge est retourné quand la preuve de cohérence dépasse MaxConsistencyEntries
+var ErrConsistencyTooLarge = fmt.Errorf("consistency proof exceeds %d entries", MaxConsistencyEntries)
+
+// ChainHead est la tête d'une chaîne du ledger (ligne de ledger_head)
+type ChainHead struct {
+	ChainID string `json:"chain_id"`
+	Seq     int64  `json:"seq"`
+	Hash    string `json:"hash"` // "" pour une chaîne vide
+}
+
+// TreeHead est la tête du ledger publiée périodiquement : taille (somme des seq des
+// chaînes), hash des têtes de chaîne et horodatage. C'est le contenu scellé par le JWS
+type TreeHead struct {
+	TreeSize  int64       `json:"tree_size"`
+	HeadHash  string      `json:"head_hash"`
+	Chains    []ChainHead `json:"chains"` // triées par chain_id
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// Cosignature est la contre-signature d'une tête par un témoin (même sujet, même contenu)
+type Cosignature struct {
+	WitnessID string    `json:"witness_id"`
+	KID       string    `json:"kid"`
+	JWS       string    `json:"jws"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SignedTreeHead est une tête enregistrée dans ledger_tree_heads avec son JWS et
+// les contre-signatures des témoins
+type SignedTreeHead struct {
+	ID           int64         `json:"id"`
+	Head         TreeHead      `json:"head"`
+	JWS          string        `json:"jws"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Cosignatures []Cosignature `json:"cosignatures"`
+}
+
+// ComputeTreeHeadHash calcule le hash des têtes de chaîne, indépendamment de leur ordre :
+// SHA256("dorevia-tree-head/v1\n" + pour chaque chaîne triée "chain_id seq hash\n")
+func ComputeTreeHeadHash(chains []ChainHead) string {
+	sorted := append([]ChainHead(nil), chains...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ChainID < sorted[j].ChainID })
+
+	canonical := "dorevia-tree-head/v1\n"
+	for _, c := range sorted {
+		canonical += c.ChainID + " " + strconv.FormatInt(c.Seq, 10) + " " + c.Hash + "\n"
+	}
+	hash := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(hash[:])
+}
+
+// NewTreeHead construit la tête du ledger à partir des têtes de chaîne ; l'horodatage
+// est tronqué à la microseconde (précision PostgreSQL) pour être relu à l'identique
+func NewTreeHead(chains []ChainHead, t time.Time) TreeHead {
+	sorted := append([]ChainHead{}, chains...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ChainID < sorted[j].ChainID })
+
+	head := TreeHead{
+		HeadHash:  ComputeTreeHeadHash(sorted),
+		Chains:    sorted,
+		Timestamp: t.UTC().Truncate(time.Microsecond),
+	}
+	for _, c := range sorted {
+		head.TreeSize += c.Seq
+	}
+	return head
+}
+
+// LoadChainHeads lit les têtes de chaîne (ledger_head)
+func LoadChainHeads(ctx context.Context, pool *pgxpool.Pool) ([]ChainHead, error) {
+	rows, err := pool.Query(ctx, `SELECT chain_id, seq, COALESCE(hash, '') FROM ledger_head ORDER BY chain_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledger heads: %w", err)
+	}
+	defer rows.Close()
+
+	var chains []ChainHead
+	for rows.Next() {
+		var c ChainHead
+		if err := rows.Scan(&c.ChainID, &c.Seq, &c.Hash); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger head: %w", err)
+		}
+		chains = append(chains, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ledger heads: %w", err)
+	}
+	return chains, nil
+}
+
+// PublishTreeHead signe et enregistre la tête courante du ledger ; nil si le ledger
+// est vide ou n'a pas bougé depuis la dernière tête publiée
+func PublishTreeHead(ctx context.Context, pool *pgxpool.Pool, signer ReportSigner, t time.Time) (*SignedTreeHead, error) {
+	chains, err := LoadChainHeads(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	if len(chains) == 0 {
+		return nil, nil
+	}
+	head := NewTreeHead(chains, t)
+
+	last, err := LoadLatestTreeHead(ctx, pool)
+	if err != nil && !errors.Is(err, ErrNoTreeHead) {
+		return nil, err
+	}
+	if last != nil && last.Head.HeadHash == head.HeadHash {
+		return nil, nil
+	}
+
+	jws, err := signer.SignReport(TreeHeadSubject, head, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign tree head: %w", err)
+	}
+	chainsJSON, err := json.Marshal(head.Chains)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal chain heads: %w", err)
+	}
+
+	signed := &SignedTreeHead{Head: head, JWS: jws, Cosignatures: []Cosignature{}}
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO ledger_tree_heads (tree_size, head_hash, chains, timestamp, jws)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, head.TreeSize, head.HeadHash, chainsJSON, head.Timestamp, jws).Scan(&signed.ID, &signed.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to insert tree head: %w", err)
+	}
+	return signed, nil
+}
+
+const treeHeadColumns = `SELECT id, tree_size, head_hash, chains, timestamp, jws, created_at FROM ledger_tree_heads`
+
+// LoadTreeHead charge la tête id et ses contre-signatures ; ErrNoTreeHead si elle n'existe pas
+func LoadTreeHead(ctx context.Context, pool *pgxpool.Pool, id int64) (*SignedTreeHead, error) {
+	return loadTreeHead(ctx, pool, treeHeadColumns+` WHERE id = $1`, id)
+}
+
+// LoadLatestTreeHead charge la dernière tête publiée et ses contre-signatures
+func LoadLatestTreeHead(ctx context.Context, pool *pgxpool.Pool) (*SignedTreeHead, error) {
+	return loadTreeHead(ctx, pool, treeHeadColumns+` ORDER BY id DESC LIMIT 1`)
+}
+
+func loadTreeHead(ctx context.Context, pool *pgxpool.Pool, query string, args ...interface{}) (*SignedTreeHead, error) {
+	var signed SignedTreeHead
+	var chainsJSON []byte
+	err := pool.QueryRow(ctx, query, args...).Scan(&signed.ID, &signed.Head.TreeSize, &signed.Head.HeadHash, &chainsJSON,
+		&signed.Head.Timestamp, &signed.JWS, &signed.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoTreeHead
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tree head: %w", err)
+	}
+	if err := json.Unmarshal(chainsJSON, &signed.Head.Chains); err != nil {
+		return nil, fmt.Errorf("failed to decode chain heads: %w", err)
+	}
+	signed.Head.Timestamp = signed.Head.Timestamp.UTC()
+
+	rows, err := pool.Query(ctx, `
+		SELECT witness_id, kid, jws, created_at FROM ledger_tree_head_cosignatures
+		WHERE tree_head_id = $1
+		ORDER BY id
+	`, signed.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cosignatures: %w", err)
+	}
+	defer rows.Close()
+
+	signed.Cosignatures = []Cosignature{}
+	for rows.Next() {
+		var c Cosignature
+		if err := rows.Scan(&c.WitnessID, &c.KID, &c.JWS, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cosignature: %w", err)
+		}
+		signed.Cosignatures = append(signed.Cosignatures, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cosignatures: %w", err)
+	}
+	return &signed, nil
+}
+
+// AddCosignature enregistre la contre-signature d'un témoin (une par clé et par tête) ;
+// false si ce témoin a déjà contre-signé la tête. La signature doit avoir été vérifiée
+func AddCosignature(ctx context.Context, pool *pgxpool.Pool, treeHeadID int64, c Cosignature) (bool, error) {
+	tag, err := pool.Exec(ctx, `
+		INSERT INTO ledger_tree_head_cosignatures (tree_head_id, witness_id, kid, jws)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tree_head_id, kid) DO NOTHING
+	`, treeHeadID, c.WitnessID, c.KID, c.JWS)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert cosignature: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ChainConsistency relie la tête d'une chaîne dans l'ancienne tête du ledger à sa tête
+// dans la nouvelle : entrées FromSeq+1..ToSeq, anonymisées
+type ChainConsistency struct {
+	ChainID  string       `json:"chain_id"`
+	FromSeq  int64        `json:"from_seq"`
+	FromHash string       `json:"from_hash"`
+	ToSeq    int64        `json:"to_seq"`
+	ToHash   string       `json:"to_hash"`
+	Entries  []ChainEntry `json:"entries"`
+}
+
+// ConsistencyProof prouve que la tête To prolonge la tête From sans réécriture :
+// chaque chaîne de From se poursuit, maillon par maillon, jusqu'à sa tête dans To
+type ConsistencyProof struct {
+	From   int64              `json:"from"`
+	To     int64              `json:"to"`
+	Chains []ChainConsistency `json:"chains"`
+}
+
+// BuildConsistencyProof charge, pour chaque chaîne de from, les entrées ajoutées
+// jusqu'à to. Les entrées ne portent ni document_id, ni tenant, ni JWS
+func BuildConsistencyProof(ctx context.Context, pool *pgxpool.Pool, from, to *SignedTreeHead) (*ConsistencyProof, error) {
+	proof := &ConsistencyProof{From: from.ID, To: to.ID, Chains: []ChainConsistency{}}
+	newHeads := make(map[string]ChainHead, len(to.Head.Chains))
+	for _, c := range to.Head.Chains {
+		newHeads[c.ChainID] = c
+	}
+
+	total := int64(0)
+	for _, old := range from.Head.Chains {
+		head, ok := newHeads[old.ChainID]
+		if !ok {
+			continue
+		}
+		chain := ChainConsistency{
+			ChainID:  old.ChainID,
+			FromSeq:  old.Seq,
+			FromHash: old.Hash,
+			ToSeq:    head.Seq,
+			ToHash:   head.Hash,
+			Entries:  []ChainEntry{},
+		}
+		if head.Seq > old.Seq {
+			total += head.Seq - old.Seq
+			if total > MaxConsistencyEntries {
+				return nil, ErrConsistencyTooLarge
+			}
+			entries, err := loadChainRange(ctx, pool, old.ChainID, old.Seq, head.Seq)
+			if err != nil {
+				return nil, err
+			}
+			chain.Entries = entries
+		}
+		proof.Chains = append(proof.Chains, chain)
+	}
+	return proof, nil
+}
+
+// loadChainRange charge les entrées de seq ]from, to] d'une chaîne (chain_id de ledger_head) ;
+// le passage aux chaînes par tenant sépare les entrées de la chaîne globale de celles des tenants
+func loadChainRange(ctx context.Context, pool *pgxpool.Pool, chainID string, from, to int64) ([]ChainEntry, error) {
+	var tenant *string
+	perTenant := chainID != GlobalChainID
+	if perTenant {
+		t := chainID[len("tenant:"):]
+		if t != "" {
+			tenant = &t
+		}
+	}
+	epoch, err := LoadChainEpoch(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	var epochID *int64
+	if epoch != nil {
+		epochID = &epoch.EntryID
+	}
+
+	rows, err := pool.Query(ctx, proofEntryColumns+`
+		WHERE l.seq > $1 AND l.seq <= $2
+		  AND (NOT $3::boolean OR l.tenant IS NOT DISTINCT FROM $4)
+		  AND ($5::bigint IS NULL OR ($3::boolean AND l.id > $5) OR (NOT $3::boolean AND l.id <= $5))
+		ORDER BY l.seq, l.id
+	`, from, to, perTenant, tenant, epochID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledger entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []ChainEntry{}
+	for rows.Next() {
+		e, err := scanProofEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, *anonymizeEntry(e))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ledger entries: %w", err)
+	}
+	return entries, nil
+}
+
+// Verify vérifie que la tête next prolonge la tête prev : chaque chaîne de prev figure
+// dans next avec un seq au moins égal, et les entrées de la preuve, dont le hash est
+// recalculé, relient sans trou sa tête dans prev à sa tête dans next. Une chaîne
+// absente de prev n'a pas d'historique témoigné et n'est pas vérifiée
+func (p *ConsistencyProof) Verify(prev, next TreeHead) error {
+	if ComputeTreeHeadHash(prev.Chains) != prev.HeadHash {
+		return fmt.Errorf("previous tree head hash does not match its chains")
+	}
+	if ComputeTreeHeadHash(next.Chains) != next.HeadHash {
+		return fmt.Errorf("new tree head hash does not match its chains")
+	}
+
+	newHeads := make(map[string]ChainHead, len(next.Chains))
+	for _, c := range next.Chains {
+		newHeads[c.ChainID] = c
+	}
+	proofs := make(map[string]ChainConsistency, len(p.Chains))
+	for _, c := range p.Chains {
+		proofs[c.ChainID] = c
+	}
+
+	for _, old := range prev.Chains {
+		head, ok := newHeads[old.ChainID]
+		if !ok {
+			return fmt.Errorf("chain %s missing from new tree head", old.ChainID)
+		}
+		if head.Seq < old.Seq {
+			return fmt.Errorf("chain %s rolled back from seq %d to %d", old.ChainID, old.Seq, head.Seq)
+		}
+		if head.Seq == old.Seq {
+			if head.Hash != old.Hash {
+				return fmt.Errorf("chain %s rewritten at seq %d", old.ChainID, old.Seq)
+			}
+			continue
+		}
+
+		chain, ok := proofs[old.ChainID]
+		if !ok {
+			return fmt.Errorf("chain %s: no consistency entries", old.ChainID)
+		}
+		if int64(len(chain.Entries)) != head.Seq-old.Seq {
+			return fmt.Errorf("chain %s: %d entries provided, %d expected", old.ChainID, len(chain.Entries), head.Seq-old.Seq)
+		}
+		for i, e := range chain.Entries {
+			if e.Seq == nil || *e.Seq != old.Seq+int64(i)+1 {
+				return fmt.Errorf("chain %s: entry %d out of sequence", old.ChainID, e.ID)
+			}
+		}
+
+		var anchor *string
+		if old.Hash != "" {
+			h := old.Hash
+			anchor = &h
+		}
+		report := VerifyChainEntries(chain.Entries, anchor)
+		if !report.Valid {
+			return fmt.Errorf("chain %s does not extend previous head (%d broken links, %d gaps, %d forks)",
+				old.ChainID, report.BrokenLinkCount, report.GapCount, report.ForkCount)
+		}
+		if report.HeadHash != head.Hash {
+			return fmt.Errorf("chain %s: last entry does not match new head", old.ChainID)
+		}
+	}
+	return nil
+}
+
+// StartTreeHeadScheduler publie la tête signée du ledger toutes les interval (exécution
+// immédiate au démarrage), et vérifie sa cohérence avec la tête précédente : une
+// réécriture de l'historique émet l'événement webhook error.critical
+func StartTreeHeadScheduler(ctx context.Context, pool *pgxpool.Pool, signer ReportSigner, webhookManager *webhooks.Manager, interval time.Duration, log zerolog.Logger) {
+	if interval == 0 {
+		interval = 5 * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			publishAndCheck(ctx, pool, signer, webhookManager, log)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func publishAndCheck(ctx context.Context, pool *pgxpool.Pool, signer ReportSigner, webhookManager *webhooks.Manager, log zerolog.Logger) {
+	previous, err := LoadLatestTreeHead(ctx, pool)
+	if err != nil && !errors.Is(err, ErrNoTreeHead) {
+		log.Error().Err(err).Msg("Failed to load ledger tree head")
+		return
+	}
+
+	signed, err := PublishTreeHead(ctx, pool, signer, time.Now())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to publish ledger tree head")
+		return
+	}
+	if signed == nil {
+		return
+	}
+	log.Info().
+		Int64("id", signed.ID).
+		Int64("tree_size", signed.Head.TreeSize).
+		Str("head_hash", signed.Head.HeadHash).
+		Msg("Ledger tree head signed")
+
+	if previous == nil {
+		return
+	}
+	proof, err := BuildConsistencyProof(ctx, pool, previous, signed)
+	if err != nil {
+		log.Error().Err(err).Int64("from", previous.ID).Int64("to", signed.ID).Msg("Failed to build ledger consistency proof")
+		return
+	}
+	if err := proof.Verify(previous.Head, signed.Head); err != nil {
+		log.Error().Err(err).Int64("from", previous.ID).Int64("to", signed.ID).Msg("Ledger history rewritten since previous tree head")
+		if webhookManager != nil {
+			if err := webhookManager.EmitEvent(ctx, webhooks.EventTypeErrorCritical, "", map[string]interface{}{
+				"source": "ledger_tree_head",
+				"error":  err.Error(),
+				"from":   previous.ID,
+				"to":     signed.ID,
+			}); err != nil {
+				log.Error().Err(err).Msg("Failed to emit error.critical webhook")
+			}
+		}
+	}
+}
@@ -0,0 +1,714 @@
+package ledger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Types d'anomalies détectées lors de la vérification de chaîne
+const (
+	ChainIssueBrokenLink       = "broken_link"       // hash recalculé différent du hash stocké
+	ChainIssueGap              = "gap"               // previous_hash ne pointe pas sur l'entrée précédente
+	ChainIssueFork             = "fork"              // plusieurs entrées partagent le même previous_hash
+	ChainIssueMissingDocument  = "missing_document"  // sha256 du document introuvable (recalcul impossible)
+	ChainIssueSequenceGap      = "sequence_gap"      // numéro de séquence non consécutif dans la chaîne
+	ChainIssueVersionDowngrade = "version_downgrade" // entrée d'un format antérieur à celui de l'entrée précédente
+)
+
+// Versions du format des entrées du ledger (colonne hash_version)
+const (
+	// HashVersionV1 : hash = SHA256(previous_hash + sha256_document)
+	HashVersionV1 = 1
+	// HashVersionV2 : hash sur l'encodage canonique de tous les champs de l'entrée (ComputeHashV2)
+	HashVersionV2 = 2
+)
+
+// ErrMissingDocumentSHA est retourné quand le hash d'une entrée ne peut pas être
+// recalculé faute de sha256 du document
+var ErrMissingDocumentSHA = errors.New("document sha256 not found, hash cannot be recomputed")
+
+// ChainVerdictSubject est l'identifiant scellé dans le JWS du verdict de chaîne
+const ChainVerdictSubject = "ledger-chain"
+
+// MaxReportedChainIssues limite le nombre d'anomalies détaillées par catégorie
+const MaxReportedChainIssues = 100
+
+// ForkWindow est le nombre de previous_hash récents mémorisés pour détecter les
+// bifurcations : la mémoire de la vérification reste bornée quelle que soit la taille du
+// ledger. Une entrée qui référence un previous_hash plus ancien est signalée comme trou
+const ForkWindow = 4096
+
+// ChainEntry représente une entrée du ledger relue pour la vérification de chaîne
+type ChainEntry struct {
+	ID           int64     `json:"id"`
+	DocumentID   string    `json:"document_id"`
+	Hash         string    `json:"hash"`
+	PreviousHash *string   `json:"previous_hash,omitempty"`
+	DocumentSHA  string    `json:"sha256_hex"` // sha256 du document (jointure documents)
+	Timestamp    time.Time `json:"timestamp"`
+	Tenant       *string   `json:"tenant,omitempty"`
+	Seq          *int64    `json:"seq,omitempty"`          // numéro de séquence dans la chaîne (ledger_head)
+	HashVersion  int       `json:"hash_version,omitempty"` // format de l'entrée (0 ou 1 : v1)
+	EvidenceJWS  *string   `json:"evidence_jws,omitempty"`
+	EntryType    string    `json:"entry_type,omitempty"` // type d'entrée ("" : document.created)
+	Body         string    `json:"body,omitempty"`       // corps JSON canonique d'un événement
+	// ContentDigest remplace document_id, sha256 et JWS d'une entrée v2 anonymisée (preuves),
+	// ou document_id et corps d'un événement anonymisé
+	ContentDigest string `json:"content_digest,omitempty"`
+}
+
+// ChainIssue décrit une anomalie de chaînage
+type ChainIssue struct {
+	Type       string `json:"type"`
+	EntryID    int64  `json:"entry_id"`
+	DocumentID string `json:"document_id,omitempty"`
+	Tenant     string `json:"tenant,omitempty"`
+	Expected   string `json:"expected,omitempty"`
+	Actual     string `json:"actual,omitempty"`
+	Message    string `json:"message"`
+}
+
+// ChainReport est le verdict de vérification complète de la chaîne
+type ChainReport struct {
+	Valid  bool       `json:"valid"`
+	From   *time.Time `json:"from,omitempty"`
+	To     *time.Time `json:"to,omitempty"`
+	Tenant string     `json:"tenant,omitempty"` // rapport restreint à un tenant
+	// EpochEntryID : dernière entrée de la chaîne globale avant les chaînes par tenant
+	// (les rapports d'un tenant ne couvrent que sa chaîne, postérieure)
+	EpochEntryID    *int64       `json:"epoch_entry_id,omitempty"`
+	EntriesChecked  int          `json:"entries_checked"`
+	FirstEntryID    int64        `json:"first_entry_id,omitempty"`
+	LastEntryID     int64        `json:"last_entry_id,omitempty"`
+	AnchorHash      *string      `json:"anchor_hash,omitempty"` // hash de l'entrée précédant la fenêtre
+	HeadHash        string       `json:"head_hash,omitempty"`   // dernier hash de la fenêtre
+	FirstBrokenLink *ChainIssue  `json:"first_broken_link,omitempty"`
+	BrokenLinks     []ChainIssue `json:"broken_links"`
+	Gaps            []ChainIssue `json:"gaps"`
+	Forks           []ChainIssue `json:"forks"`
+	BrokenLinkCount int          `json:"broken_link_count"`
+	GapCount        int          `json:"gap_count"`
+	ForkCount       int          `json:"fork_count"`
+	CheckedAt       time.Time    `json:"checked_at"`
+}
+
+// ComputeHash calcule le hash chaîné d'une entrée du ledger
+//   - premier enregistrement : SHA256(sha256_document)
+//   - sinon : SHA256(previous_hash + sha256_document)
+func ComputeHash(previousHash *string, shaHex string) string {
+	combined := shaHex
+	if previousHash != nil {
+		combined = *previousHash + shaHex
+	}
+	hash := sha256.Sum256([]byte(combined))
+	return hex.EncodeToString(hash[:])
+}
+
+// ContentDigestV2 calcule le condensat du contenu d'une entrée v2 :
+// SHA256("dorevia-ledger-content/v2\n" + document_id + "\n" + sha256_document + "\n" + SHA256(evidence_jws))
+func ContentDigestV2(documentID, shaHex string, evidenceJWS *string) string {
+	jwsHash := sha256.Sum256([]byte(derefHash(evidenceJWS)))
+	content := sha256.Sum256([]byte("dorevia-ledger-content/v2\n" + documentID + "\n" + shaHex + "\n" + hex.EncodeToString(jwsHash[:])))
+	return hex.EncodeToString(content[:])
+}
+
+// ComputeHashV2 calcule le hash d'une entrée v2 sur l'encodage canonique de ses champs :
+// SHA256("dorevia-ledger/v2\n" + seq + "\n" + timestamp + "\n" + content_digest + "\n" + previous_hash)
+//
+// Le timestamp est encodé en UTC à la microseconde (précision PostgreSQL), previous_hash
+// est vide pour la première entrée. Modifier le document, le JWS, l'horodatage ou l'ordre
+// (seq) d'une entrée rompt son hash ; le condensat du contenu permet de vérifier une
+// entrée voisine sans exposer son document ni son JWS.
+func ComputeHashV2(previousHash *string, seq int64, timestamp time.Time, contentDigest string) string {
+	canonical := "dorevia-ledger/v2\n" +
+		strconv.FormatInt(seq, 10) + "\n" +
+		timestamp.UTC().Format("2006-01-02T15:04:05.000000Z") + "\n" +
+		contentDigest + "\n" +
+		derefHash(previousHash)
+	hash := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(hash[:])
+}
+
+// EntryHash recalcule le hash d'une entrée selon son format (hash_version). Pour un
+// événement, le condensat de l'événement (EventDigest) tient lieu de sha256 du document
+// (v1) ou de condensat du contenu (v2)
+func EntryHash(e ChainEntry) (string, error) {
+	if e.isEvent() {
+		if !IsEventType(e.EntryType) {
+			return "", fmt.Errorf("unsupported entry type %q", e.EntryType)
+		}
+		switch e.HashVersion {
+		case 0, HashVersionV1:
+			return ComputeHash(e.PreviousHash, e.eventDigest()), nil
+		case HashVersionV2:
+			if e.Seq == nil {
+				return "", fmt.Errorf("v2 entry without sequence number")
+			}
+			return ComputeHashV2(e.PreviousHash, *e.Seq, e.Timestamp, e.eventDigest()), nil
+		default:
+			return "", fmt.Errorf("unsupported hash version %d", e.HashVersion)
+		}
+	}
+
+	switch e.HashVersion {
+	case 0, HashVersionV1:
+		if e.DocumentSHA == "" {
+			return "", ErrMissingDocumentSHA
+		}
+		return ComputeHash(e.PreviousHash, e.DocumentSHA), nil
+	case HashVersionV2:
+		if e.Seq == nil {
+			return "", fmt.Errorf("v2 entry without sequence number")
+		}
+		content := e.ContentDigest
+		if e.DocumentID != "" || content == "" {
+			if e.DocumentSHA == "" {
+				return "", ErrMissingDocumentSHA
+			}
+			content = ContentDigestV2(e.DocumentID, e.DocumentSHA, e.EvidenceJWS)
+		}
+		return ComputeHashV2(e.PreviousHash, *e.Seq, e.Timestamp, content), nil
+	default:
+		return "", fmt.Errorf("unsupported hash version %d", e.HashVersion)
+	}
+}
+
+// version retourne le format de l'entrée (v1 pour les entrées antérieures à hash_version)
+func (e ChainEntry) version() int {
+	if e.HashVersion == 0 {
+		return HashVersionV1
+	}
+	return e.HashVersion
+}
+
+// ChainVerifier vérifie une séquence d'entrées fournies dans l'ordre de la chaîne
+// (seq, puis timestamp ASC, id ASC pour les entrées sans seq). Les entrées sont traitées au fil de l'eau pour
+// permettre la vérification de gros volumes sans tout charger en mémoire.
+type ChainVerifier struct {
+	report      *ChainReport
+	expected    *string          // hash attendu en previous_hash de la prochaine entrée
+	prevOwners  map[string]int64 // previous_hash -> id de la première entrée qui le référence (ForkWindow derniers)
+	prevRing    []string         // previous_hash de prevOwners dans l'ordre d'insertion
+	prevNext    int              // prochaine position à remplacer dans prevRing
+	lastSeq     *int64           // dernier numéro de séquence rencontré
+	lastVersion int              // format de l'entrée précédente
+	initialized bool
+}
+
+// NewChainVerifier crée un vérificateur ; anchor est le hash de l'entrée
+// précédant la fenêtre vérifiée (nil si la fenêtre commence au début du ledger)
+func NewChainVerifier(anchor *string) *ChainVerifier {
+	return &ChainVerifier{
+		report: &ChainReport{
+			AnchorHash:  anchor,
+			BrokenLinks: []ChainIssue{},
+			Gaps:        []ChainIssue{},
+			Forks:       []ChainIssue{},
+		},
+		expected:   anchor,
+		prevOwners: make(map[string]int64),
+	}
+}
+
+// Add vérifie l'entrée suivante de la chaîne
+func (v *ChainVerifier) Add(e ChainEntry) {
+	r := v.report
+	r.EntriesChecked++
+	if !v.initialized {
+		r.FirstEntryID = e.ID
+		v.initialized = true
+	}
+	r.LastEntryID = e.ID
+	r.HeadHash = e.Hash
+	tenant := derefHash(e.Tenant)
+
+	// 1. Recalcul du hash selon le format de l'entrée
+	recomputed, err := EntryHash(e)
+	switch {
+	case errors.Is(err, ErrMissingDocumentSHA):
+		v.addBrokenLink(ChainIssue{
+			Type:       ChainIssueMissingDocument,
+			EntryID:    e.ID,
+			DocumentID: e.DocumentID,
+			Tenant:     tenant,
+			Message:    err.Error(),
+		})
+	case err != nil:
+		v.addBrokenLink(ChainIssue{
+			Type:       ChainIssueBrokenLink,
+			EntryID:    e.ID,
+			DocumentID: e.DocumentID,
+			Tenant:     tenant,
+			Actual:     e.Hash,
+			Message:    err.Error(),
+		})
+	case recomputed != e.Hash:
+		v.addBrokenLink(ChainIssue{
+			Type:       ChainIssueBrokenLink,
+			EntryID:    e.ID,
+			DocumentID: e.DocumentID,
+			Tenant:     tenant,
+			Expected:   recomputed,
+			Actual:     e.Hash,
+			Message:    "recomputed hash does not match stored hash",
+		})
+	}
+
+	// Un format ne peut pas être suivi d'un format antérieur (réécriture en v1 d'une chaîne v2)
+	if e.version() < v.lastVersion {
+		v.addBrokenLink(ChainIssue{
+			Type:       ChainIssueVersionDowngrade,
+			EntryID:    e.ID,
+			DocumentID: e.DocumentID,
+			Tenant:     tenant,
+			Expected:   fmt.Sprintf("v%d", v.lastVersion),
+			Actual:     fmt.Sprintf("v%d", e.version()),
+			Message:    "hash version lower than preceding entry",
+		})
+	} else {
+		v.lastVersion = e.version()
+	}
+
+	// 2. Fork : previous_hash déjà référencé par une autre entrée
+	prevKey := ""
+	if e.PreviousHash != nil {
+		prevKey = *e.PreviousHash
+	}
+	if ownerID, seen := v.prevOwners[prevKey]; seen {
+		r.ForkCount++
+		if len(r.Forks) < MaxReportedChainIssues {
+			r.Forks = append(r.Forks, ChainIssue{
+				Type:       ChainIssueFork,
+				EntryID:    e.ID,
+				DocumentID: e.DocumentID,
+				Tenant:     tenant,
+				Actual:     prevKey,
+				Message:    fmt.Sprintf("previous_hash already referenced by entry %d", ownerID),
+			})
+		}
+	} else {
+		v.rememberPrevious(prevKey, e.ID)
+
+		// 3. Trou : previous_hash ne correspond pas au hash de l'entrée précédente
+		if !sameHash(e.PreviousHash, v.expected) {
+			r.GapCount++
+			if len(r.Gaps) < MaxReportedChainIssues {
+				r.Gaps = append(r.Gaps, ChainIssue{
+					Type:       ChainIssueGap,
+					EntryID:    e.ID,
+					DocumentID: e.DocumentID,
+					Tenant:     tenant,
+					Expected:   derefHash(v.expected),
+					Actual:     prevKey,
+					Message:    "previous_hash does not match preceding entry",
+				})
+			}
+		}
+	}
+
+	// 4. Séquence : les entrées numérotées d'une chaîne se suivent sans trou
+	if e.Seq != nil {
+		if v.lastSeq != nil && *e.Seq != *v.lastSeq+1 {
+			r.GapCount++
+			if len(r.Gaps) < MaxReportedChainIssues {
+				r.Gaps = append(r.Gaps, ChainIssue{
+					Type:       ChainIssueSequenceGap,
+					EntryID:    e.ID,
+					DocumentID: e.DocumentID,
+					Tenant:     tenant,
+					Expected:   fmt.Sprintf("%d", *v.lastSeq+1),
+					Actual:     fmt.Sprintf("%d", *e.Seq),
+					Message:    "sequence number does not follow preceding entry",
+				})
+			}
+		}
+		seq := *e.Seq
+		v.lastSeq = &seq
+	}
+
+	hash := e.Hash
+	v.expected = &hash
+}
+
+// Report retourne le verdict final
+func (v *ChainVerifier) Report() *ChainReport {
+	r := v.report
+	r.Valid = r.BrokenLinkCount == 0 && r.GapCount == 0 && r.ForkCount == 0
+	r.CheckedAt = time.Now().UTC()
+	return r
+}
+
+// rememberPrevious mémorise le previous_hash d'une entrée, en oubliant le plus ancien
+// au-delà de ForkWindow
+func (v *ChainVerifier) rememberPrevious(prevKey string, id int64) {
+	if len(v.prevRing) < ForkWindow {
+		v.prevRing = append(v.prevRing, prevKey)
+	} else {
+		delete(v.prevOwners, v.prevRing[v.prevNext])
+		v.prevRing[v.prevNext] = prevKey
+		v.prevNext = (v.prevNext + 1) % ForkWindow
+	}
+	v.prevOwners[prevKey] = id
+}
+
+func (v *ChainVerifier) addBrokenLink(issue ChainIssue) {
+	r := v.report
+	r.BrokenLinkCount++
+	if r.FirstBrokenLink == nil {
+		first := issue
+		r.FirstBrokenLink = &first
+	}
+	if len(r.BrokenLinks) < MaxReportedChainIssues {
+		r.BrokenLinks = append(r.BrokenLinks, issue)
+	}
+}
+
+func sameHash(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func derefHash(h *string) string {
+	if h == nil {
+		return ""
+	}
+	return *h
+}
+
+// VerifyChainEntries vérifie une séquence ordonnée d'entrées (sans DB)
+func VerifyChainEntries(entries []ChainEntry, anchor *string) *ChainReport {
+	v := NewChainVerifier(anchor)
+	for _, e := range entries {
+		v.Add(e)
+	}
+	return v.Report()
+}
+
+// ChainOptions délimite la vérification de chaîne
+type ChainOptions struct {
+	From *time.Time // borne basse incluse (optionnelle)
+	To   *time.Time // borne haute exclue (optionnelle)
+
+	// Tenant restreint le rapport aux entrées du tenant ("" = ledger complet)
+	Tenant string
+	// PerTenantChain indique que chaque tenant possède sa propre chaîne :
+	// seule la chaîne du tenant est alors relue. Sinon la chaîne globale est
+	// vérifiée et le rapport est expurgé des hash des autres tenants.
+	PerTenantChain bool
+}
+
+// VerifyChain relit le ledger dans l'ordre (table parente : inclut les partitions)
+// et vérifie la chaîne complète, éventuellement restreinte à [from, to)
+func VerifyChain(ctx context.Context, pool *pgxpool.Pool, opts ChainOptions) (*ChainReport, error) {
+	// Passage aux chaînes par tenant : tant qu'aucune n'a démarré, le ledger ne
+	// contient que la chaîne globale
+	var epoch *ChainEpoch
+	if opts.PerTenantChain {
+		var err error
+		if epoch, err = LoadChainEpoch(ctx, pool); err != nil {
+			return nil, err
+		}
+		if epoch == nil {
+			opts.PerTenantChain = false
+		}
+	}
+	var epochID int64
+	if epoch != nil {
+		epochID = epoch.EntryID
+	}
+
+	// Filtre de chaîne : uniquement en mode chaîne par tenant (entrées postérieures au passage)
+	var chainTenant *string
+	if opts.Tenant != "" && opts.PerTenantChain {
+		chainTenant = &opts.Tenant
+	}
+
+	// Hash d'ancrage : dernière entrée de chaque chaîne avant la fenêtre
+	anchors := make(map[string]*string)
+	var globalAnchor *string
+	if opts.From != nil {
+		anchorQuery := `
+			SELECT '', hash FROM ledger
+			WHERE timestamp < $1
+			  AND ($2::text IS NULL OR tenant = $2)
+			ORDER BY timestamp DESC, id DESC
+			LIMIT 1
+		`
+		args := []any{*opts.From, chainTenant}
+		if opts.PerTenantChain {
+			anchorQuery = `
+				SELECT DISTINCT ON (tenant) COALESCE(tenant, ''), hash FROM ledger
+				WHERE timestamp < $1
+				  AND ($2::text IS NULL OR tenant = $2)
+				  AND id > $3
+				ORDER BY tenant, timestamp DESC, id DESC
+			`
+			args = append(args, epochID)
+		}
+		rows, err := pool.Query(ctx, anchorQuery, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get anchor hash: %w", err)
+		}
+		for rows.Next() {
+			var key, h string
+			if err := rows.Scan(&key, &h); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan anchor hash: %w", err)
+			}
+			anchors[key] = &h
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get anchor hash: %w", err)
+		}
+
+		// Chaîne globale antérieure au passage
+		if opts.PerTenantChain && chainTenant == nil && epochID > 0 {
+			err := pool.QueryRow(ctx, `
+				SELECT hash FROM ledger
+				WHERE timestamp < $1 AND id <= $2
+				ORDER BY timestamp DESC, id DESC
+				LIMIT 1
+			`, *opts.From, epochID).Scan(&globalAnchor)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return nil, fmt.Errorf("failed to get anchor hash: %w", err)
+			}
+		}
+	}
+
+	// Partitions archivées : les premières entrées restantes se chaînent sur leurs têtes
+	archived, err := archiveAnchors(ctx, pool, opts.PerTenantChain)
+	if err != nil {
+		return nil, err
+	}
+	for key, h := range archived {
+		if _, ok := anchors[key]; !ok {
+			anchors[key] = h
+		}
+	}
+
+	// Ordre de chaque chaîne : seq (indépendant de l'horloge), les entrées antérieures
+	// à ledger_head (sans seq) en tête dans l'ordre (timestamp, id)
+	rows, err := pool.Query(ctx, `
+		SELECT l.id, COALESCE(l.document_id::text, ''), l.hash, l.previous_hash,
+		       COALESCE(d.sha256_hex, ''), l.timestamp, l.tenant, l.seq, l.hash_version, l.evidence_jws,
+		       l.entry_type, COALESCE(l.body, '')
+		FROM ledger l
+		LEFT JOIN documents d ON d.id = l.document_id
+		WHERE ($1::timestamptz IS NULL OR l.timestamp >= $1)
+		  AND ($2::timestamptz IS NULL OR l.timestamp < $2)
+		  AND ($3::text IS NULL OR (l.tenant = $3 AND l.id > $4))
+		ORDER BY l.seq ASC NULLS FIRST, l.timestamp ASC, l.id ASC
+	`, opts.From, opts.To, chainTenant, epochID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledger: %w", err)
+	}
+	defer rows.Close()
+
+	v := NewTenantChainVerifier(anchors, opts.PerTenantChain)
+	if opts.PerTenantChain {
+		v.SplitAt(*epoch, globalAnchor)
+	}
+	for rows.Next() {
+		var e ChainEntry
+		if err := rows.Scan(&e.ID, &e.DocumentID, &e.Hash, &e.PreviousHash, &e.DocumentSHA, &e.Timestamp, &e.Tenant, &e.Seq,
+			&e.HashVersion, &e.EvidenceJWS, &e.EntryType, &e.Body); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		v.Add(e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ledger: %w", err)
+	}
+
+	report := v.Report()
+	report.From = opts.From
+	report.To = opts.To
+	if opts.PerTenantChain {
+		report.EpochEntryID = &epochID
+	}
+	if opts.Tenant != "" {
+		report.Tenant = opts.Tenant
+		if !opts.PerTenantChain {
+			report.RestrictToTenant(opts.Tenant)
+		}
+	}
+	return report, nil
+}
+
+// TenantChainVerifier répartit les entrées entre les chaînes du ledger :
+// une chaîne unique, ou une chaîne par tenant (LEDGER_PER_TENANT_CHAIN)
+type TenantChainVerifier struct {
+	perTenant bool
+	anchors   map[string]*string
+	chains    map[string]*ChainVerifier
+	order     []string
+
+	epoch        *ChainEpoch    // passage aux chaînes par tenant (SplitAt)
+	globalAnchor *string        // ancrage de la chaîne globale antérieure au passage
+	global       *ChainVerifier // chaîne globale antérieure au passage
+}
+
+// NewTenantChainVerifier crée un vérificateur multi-chaînes ; anchors associe
+// à chaque tenant ("" pour la chaîne globale) le hash précédant la fenêtre
+func NewTenantChainVerifier(anchors map[string]*string, perTenant bool) *TenantChainVerifier {
+	if anchors == nil {
+		anchors = make(map[string]*string)
+	}
+	return &TenantChainVerifier{
+		perTenant: perTenant,
+		anchors:   anchors,
+		chains:    make(map[string]*ChainVerifier),
+	}
+}
+
+// SplitAt indique le passage aux chaînes par tenant : les entrées d'id inférieur ou égal
+// à epoch.EntryID sont vérifiées dans la chaîne globale (ancrée sur globalAnchor), une
+// chaîne par tenant sans ancrage démarre sur epoch.Hash
+func (m *TenantChainVerifier) SplitAt(epoch ChainEpoch, globalAnchor *string) {
+	m.epoch = &epoch
+	m.globalAnchor = globalAnchor
+}
+
+// Add route l'entrée vers la chaîne de son tenant
+func (m *TenantChainVerifier) Add(e ChainEntry) {
+	if m.perTenant && m.epoch != nil && e.ID <= m.epoch.EntryID {
+		if m.global == nil {
+			m.global = NewChainVerifier(m.globalAnchor)
+		}
+		m.global.Add(e)
+		return
+	}
+
+	key := ""
+	if m.perTenant {
+		key = derefHash(e.Tenant)
+	}
+	v, ok := m.chains[key]
+	if !ok {
+		anchor, anchored := m.anchors[key]
+		if !anchored && m.epoch != nil {
+			anchor = m.epoch.Hash
+		}
+		v = NewChainVerifier(anchor)
+		m.chains[key] = v
+		m.order = append(m.order, key)
+	}
+	v.Add(e)
+}
+
+// Report fusionne les verdicts de chaque chaîne
+func (m *TenantChainVerifier) Report() *ChainReport {
+	var chains []*ChainVerifier
+	if m.global != nil {
+		chains = append(chains, m.global)
+	}
+	for _, key := range m.order {
+		chains = append(chains, m.chains[key])
+	}
+	if len(chains) == 0 {
+		return NewChainVerifier(m.anchors[""]).Report()
+	}
+	if len(chains) == 1 {
+		return chains[0].Report()
+	}
+
+	// Plusieurs chaînes : pas d'ancrage ni de tête uniques
+	merged := NewChainVerifier(nil).report
+	for _, chain := range chains {
+		r := chain.Report()
+		merged.EntriesChecked += r.EntriesChecked
+		if merged.FirstEntryID == 0 || r.FirstEntryID < merged.FirstEntryID {
+			merged.FirstEntryID = r.FirstEntryID
+		}
+		if r.LastEntryID > merged.LastEntryID {
+			merged.LastEntryID = r.LastEntryID
+		}
+		if r.FirstBrokenLink != nil && (merged.FirstBrokenLink == nil || r.FirstBrokenLink.EntryID < merged.FirstBrokenLink.EntryID) {
+			merged.FirstBrokenLink = r.FirstBrokenLink
+		}
+		merged.BrokenLinks = appendIssues(merged.BrokenLinks, r.BrokenLinks)
+		merged.Gaps = appendIssues(merged.Gaps, r.Gaps)
+		merged.Forks = appendIssues(merged.Forks, r.Forks)
+		merged.BrokenLinkCount += r.BrokenLinkCount
+		merged.GapCount += r.GapCount
+		merged.ForkCount += r.ForkCount
+	}
+	merged.Valid = merged.BrokenLinkCount == 0 && merged.GapCount == 0 && merged.ForkCount == 0
+	merged.CheckedAt = time.Now().UTC()
+	return merged
+}
+
+func appendIssues(dst, src []ChainIssue) []ChainIssue {
+	for _, issue := range src {
+		if len(dst) >= MaxReportedChainIssues {
+			break
+		}
+		dst = append(dst, issue)
+	}
+	return dst
+}
+
+// RestrictToTenant expurge un rapport de chaîne globale de tout hash
+// appartenant à un autre tenant. Le verdict et les compteurs restent globaux.
+func (r *ChainReport) RestrictToTenant(tenant string) {
+	r.Tenant = tenant
+	r.AnchorHash = nil
+	r.HeadHash = ""
+	r.FirstEntryID = 0
+	r.LastEntryID = 0
+	if r.FirstBrokenLink != nil && r.FirstBrokenLink.Tenant != tenant {
+		r.FirstBrokenLink = nil
+	}
+	r.BrokenLinks = filterIssues(r.BrokenLinks, tenant)
+	r.Gaps = filterIssues(r.Gaps, tenant)
+	r.Forks = filterIssues(r.Forks, tenant)
+	// Les hash attendus (gap) et previous_hash (fork) pointent sur des entrées
+	// potentiellement étrangères : ils ne sont pas exposés
+	for i := range r.Gaps {
+		r.Gaps[i].Expected = ""
+		r.Gaps[i].Actual = ""
+	}
+	for i := range r.Forks {
+		r.Forks[i].Actual = ""
+	}
+}
+
+func filterIssues(issues []ChainIssue, tenant string) []ChainIssue {
+	out := []ChainIssue{}
+	for _, issue := range issues {
+		if issue.Tenant == tenant {
+			out = append(out, issue)
+		}
+	}
+	return out
+}
+
+// ParseTimeBound parse une borne de fenêtre (RFC3339 ou YYYY-MM-DD)
+// Pour une borne haute au format date, la journée entière est incluse.
+func ParseTimeBound(value string, upper bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time bound %q (expected RFC3339 or YYYY-MM-DD)", value)
+	}
+	if upper {
+		t = t.Add(24 * time.Hour)
+	}
+	return &t, nil
+}
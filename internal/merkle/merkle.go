@@ -0,0 +1,157 @@
+// Package merkle implémente les arbres de Merkle au format RFC 6962
+// (Certificate Transparency) : feuilles et nœuds préfixés pour empêcher
+// qu'un nœud interne soit présenté comme une feuille.
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+)
+
+// Préfixes de domaine RFC 6962
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// LeafHash retourne le hash d'une feuille : SHA256(0x00 || data)
+func LeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// NodeHash retourne le hash d'un nœud interne : SHA256(0x01 || left || right)
+func NodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// EmptyRoot retourne la racine d'un arbre vide : SHA256("")
+func EmptyRoot() []byte {
+	sum := sha256.Sum256(nil)
+	return sum[:]
+}
+
+// Root calcule la racine de l'arbre des feuilles données (dans l'ordre)
+func Root(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		return EmptyRoot()
+	}
+	hashes := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		hashes[i] = LeafHash(leaf)
+	}
+	return rootOfHashes(hashes)
+}
+
+// rootOfHashes calcule la racine de feuilles déjà hashées (MTH, RFC 6962 §2.1)
+func rootOfHashes(hashes [][]byte) []byte {
+	if len(hashes) == 1 {
+		return hashes[0]
+	}
+	k := splitPoint(len(hashes))
+	return NodeHash(rootOfHashes(hashes[:k]), rootOfHashes(hashes[k:]))
+}
+
+// splitPoint retourne la plus grande puissance de 2 strictement inférieure à n
+func splitPoint(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// Builder accumule des feuilles pour calculer la racine en fin de flux
+type Builder struct {
+	hashes [][]byte
+}
+
+// Add ajoute une feuille
+func (b *Builder) Add(data []byte) {
+	b.hashes = append(b.hashes, LeafHash(data))
+}
+
+// Size retourne le nombre de feuilles
+func (b *Builder) Size() int {
+	return len(b.hashes)
+}
+
+// Root retourne la racine des feuilles ajoutées
+func (b *Builder) Root() []byte {
+	if len(b.hashes) == 0 {
+		return EmptyRoot()
+	}
+	return rootOfHashes(b.hashes)
+}
+
+// Proof retourne le chemin d'audit de la feuille index (RFC 6962 §2.1.1)
+func (b *Builder) Proof(index int) ([][]byte, error) {
+	return InclusionProof(b.hashes, index)
+}
+
+// InclusionProof retourne le chemin d'audit de la feuille index parmi des feuilles
+// déjà hashées (LeafHash) : hashes frères, de la feuille vers la racine
+func InclusionProof(hashes [][]byte, index int) ([][]byte, error) {
+	if index < 0 || index >= len(hashes) {
+		return nil, fmt.Errorf("leaf index %d out of range (tree size %d)", index, len(hashes))
+	}
+	return auditPath(hashes, index), nil
+}
+
+// auditPath calcule PATH(m, D[n]) (RFC 6962 §2.1.1)
+func auditPath(hashes [][]byte, m int) [][]byte {
+	if len(hashes) <= 1 {
+		return nil
+	}
+	k := splitPoint(len(hashes))
+	if m < k {
+		return append(auditPath(hashes[:k], m), rootOfHashes(hashes[k:]))
+	}
+	return append(auditPath(hashes[k:], m-k), rootOfHashes(hashes[:k]))
+}
+
+// VerifyInclusion vérifie que leafHash est la feuille index d'un arbre de size feuilles
+// de racine root, à partir de son chemin d'audit (RFC 9162 §2.1.3.2)
+func VerifyInclusion(leafHash []byte, index, size int64, path [][]byte, root []byte) error {
+	if index < 0 || index >= size {
+		return fmt.Errorf("leaf index %d out of range (tree size %d)", index, size)
+	}
+
+	fn, sn := index, size-1
+	r := leafHash
+	for _, p := range path {
+		if sn == 0 {
+			return fmt.Errorf("audit path too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			r = NodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = NodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return fmt.Errorf("audit path too short")
+	}
+	if !bytes.Equal(r, root) {
+		return fmt.Errorf("audit path does not lead to the Merkle root")
+	}
+	return nil
+}
+
+// LeafHashes retourne les hashes des feuilles ajoutées (LeafHash), dans l'ordre
+func (b *Builder) LeafHashes() [][]byte {
+	return b.hashes
+}
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -64,6 +66,58 @@ var (
 		[]string{"status"},
 	)
 
+	// LedgerAnchors compte les horodatages RFC 3161 de la tête du ledger
+	// Labels:
+	//   - status: "success" | "error"
+	LedgerAnchors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ledger_anchors_total",
+			Help: "Nombre total d'ancrages horodatés (TSA) du ledger par statut",
+		},
+		[]string{"status"},
+	)
+
+	// LedgerPartitionArchives compte les partitions du ledger détachées et archivées
+	// Labels:
+	//   - status: "success" | "error"
+	LedgerPartitionArchives = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ledger_partition_archives_total",
+			Help: "Nombre total d'archivages de partitions du ledger par statut",
+		},
+		[]string{"status"},
+	)
+
+	// ScrubDocuments compte les documents relus par la vérification en arrière-plan
+	// Labels:
+	//   - result: "ok" | "corrupted" | "error"
+	ScrubDocuments = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scrub_documents_total",
+			Help: "Nombre total de documents relus par la vérification en arrière-plan par résultat",
+		},
+		[]string{"result"},
+	)
+
+	// ScrubBytes compte les octets relus par la vérification en arrière-plan
+	ScrubBytes = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "scrub_bytes_total",
+			Help: "Nombre total d'octets relus par la vérification en arrière-plan",
+		},
+	)
+
+	// ScrubCorruptions compte les altérations nouvellement détectées
+	// Labels:
+	//   - kind: "sha256_mismatch" | "missing" | "unreadable" | "payload_mismatch"
+	ScrubCorruptions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scrub_corruptions_total",
+			Help: "Nombre total d'altérations du contenu stocké détectées par type",
+		},
+		[]string{"kind"},
+	)
+
 	// ============================================
 	// HISTOGRAMMES - Durées d'opérations
 	// ============================================
@@ -111,6 +165,27 @@ var (
 		},
 	)
 
+	// LedgerGroupCommitBatchSize mesure le nombre de documents par transaction groupée
+	// Buckets: 1, 2, 4, ..., 512 documents
+	LedgerGroupCommitBatchSize = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "ledger_group_commit_batch_size",
+			Help:    "Nombre de documents par transaction groupée (group commit)",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10), // 1 à 512
+		},
+	)
+
+	// LedgerGroupCommitWait mesure la latence ajoutée par le regroupement
+	// (attente entre la soumission d'un document et le début de sa transaction)
+	// Buckets: 0.0005, 0.001, 0.002, ... secondes
+	LedgerGroupCommitWait = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "ledger_group_commit_wait_seconds",
+			Help:    "Latence ajoutée par le group commit en secondes",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 12), // 0.5ms à ~1s
+		},
+	)
+
 	// ============================================
 	// GAUGES - Valeurs instantanées
 	// ============================================
@@ -131,6 +206,42 @@ var (
 		},
 	)
 
+	// LedgerPartitioned vaut 1 si la table ledger est partitionnée par mois, 0 sinon
+	LedgerPartitioned = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ledger_partitioned",
+			Help: "1 si le ledger est partitionné par mois, 0 sinon",
+		},
+	)
+
+	// LedgerPartitions mesure le nombre de partitions du ledger par état
+	// Labels:
+	//   - state: "attached" | "archived" | "restored"
+	LedgerPartitions = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ledger_partitions",
+			Help: "Nombre de partitions mensuelles du ledger par état",
+		},
+		[]string{"state"},
+	)
+
+	// ScrubOpenCorruptions mesure le nombre d'altérations non résolues (alerte si > 0)
+	ScrubOpenCorruptions = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "scrub_corruptions_open",
+			Help: "Nombre d'altérations du contenu stocké non résolues",
+		},
+	)
+
+	// ScrubLastPassCompleted mesure la date de fin de la dernière passe complète
+	// (timestamp Unix, 0 avant la première passe)
+	ScrubLastPassCompleted = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "scrub_last_pass_completed_timestamp_seconds",
+			Help: "Date de fin de la dernière passe complète de vérification (timestamp Unix)",
+		},
+	)
+
 	// ActiveConnections mesure le nombre de connexions actives à la base de données
 	ActiveConnections = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -185,6 +296,37 @@ func RecordTransactionDuration(durationSeconds float64) {
 	TransactionDuration.Observe(durationSeconds)
 }
 
+// RecordGroupCommitBatch enregistre la taille d'une transaction groupée
+func RecordGroupCommitBatch(size int) {
+	LedgerGroupCommitBatchSize.Observe(float64(size))
+}
+
+// RecordGroupCommitWait enregistre la latence ajoutée par le group commit
+func RecordGroupCommitWait(durationSeconds float64) {
+	LedgerGroupCommitWait.Observe(durationSeconds)
+}
+
+// RecordScrubDocument enregistre un document relu par la vérification en arrière-plan
+func RecordScrubDocument(result string, bytes int64) {
+	ScrubDocuments.WithLabelValues(result).Inc()
+	ScrubBytes.Add(float64(bytes))
+}
+
+// RecordScrubCorruption enregistre une altération nouvellement détectée
+func RecordScrubCorruption(kind string) {
+	ScrubCorruptions.WithLabelValues(kind).Inc()
+}
+
+// UpdateScrubOpenCorruptions met à jour le nombre d'altérations non résolues
+func UpdateScrubOpenCorruptions(count int) {
+	ScrubOpenCorruptions.Set(float64(count))
+}
+
+// UpdateScrubLastPassCompleted met à jour la date de fin de la dernière passe complète
+func UpdateScrubLastPassCompleted(t time.Time) {
+	ScrubLastPassCompleted.Set(float64(t.Unix()))
+}
+
 // UpdateLedgerSize met à jour la taille du ledger
 func UpdateLedgerSize(size int64) {
 	LedgerSize.Set(float64(size))
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimit limite la taille des corps de requête hors routes de réception en flux.
+// Avec StreamRequestBody, les corps au-delà de la limite de Fiber ne sont plus refusés
+// mais lus en flux : seules les routes préfixées par streamed peuvent les recevoir,
+// les autres gardent la limite (413). Un corps en flux qui n'a pas été lu jusqu'au bout
+// (requête refusée) reste sur la connexion : elle est fermée après la réponse
+func BodyLimit(limit int, streamed ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path := c.Path()
+		for _, prefix := range streamed {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				err := c.Next()
+				if c.Request().IsBodyStream() && (err != nil || c.Response().StatusCode() >= fiber.StatusBadRequest) {
+					c.Context().SetConnectionClose()
+				}
+				return err
+			}
+		}
+
+		err := BufferBody(c, limit)
+		if errors.Is(err, fiber.ErrRequestEntityTooLarge) {
+			c.Context().SetConnectionClose()
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": "Request body too large",
+			})
+		}
+		if err != nil {
+			c.Context().SetConnectionClose()
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Failed to read request body",
+				"details": err.Error(),
+			})
+		}
+		return c.Next()
+	}
+}
+
+// BufferBody lit en mémoire un corps reçu en flux, dans la limite de limit octets
+// (fiber.ErrRequestEntityTooLarge au-delà). Sans flux, le corps est déjà en mémoire
+// (ou, formulaire multipart, déjà lu dans des fichiers temporaires)
+func BufferBody(c *fiber.Ctx, limit int) error {
+	if contentLength := c.Request().Header.ContentLength(); contentLength > limit {
+		return fiber.ErrRequestEntityTooLarge
+	}
+	if !c.Request().IsBodyStream() {
+		return nil
+	}
+
+	// Lecture bornée : la taille peut être inconnue (transfert par morceaux)
+	body, err := io.ReadAll(io.LimitReader(c.Context().RequestBodyStream(), int64(limit)+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > limit {
+		return fiber.ErrRequestEntityTooLarge
+	}
+	c.Request().SetBody(body)
+	return nil
+}
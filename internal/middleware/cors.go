@@ -9,8 +9,9 @@ import (
 func CORS() fiber.Handler {
 	return cors.New(cors.Config{
 		AllowOrigins:     "*",
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+		AllowMethods:     "GET,HEAD,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,Upload-Offset,X-Chunk-SHA256",
+		ExposeHeaders:    "Location,Upload-Offset,Upload-Length",
 		AllowCredentials: false, // Fix: Cannot use wildcard "*" with AllowCredentials=true (Fiber v2.52.9 security)
 	})
 }
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Closing représente la clôture mensuelle d'une période (NF525)
+type Closing struct {
+	Period           string    `json:"period"` // YYYY-MM
+	PeriodStart      time.Time `json:"period_start"`
+	PeriodEnd        time.Time `json:"period_end"` // exclue
+	DocumentCount    int64     `json:"document_count"`
+	LedgerEntryCount int64     `json:"ledger_entry_count"`
+	MerkleRoot       string    `json:"merkle_root"`                // racine RFC 6962 des SHA256 des documents
+	LastLedgerHash   *string   `json:"last_ledger_hash,omitempty"` // dernier maillon de la période
+	ArchivePath      string    `json:"archive_path"`
+	ArchiveSHA256    string    `json:"archive_sha256"`
+	ManifestJWS      string    `json:"manifest_jws"`
+	ClosedAt         time.Time `json:"closed_at"`
+	ClosedBy         string    `json:"closed_by"`
+}
@@ -46,6 +46,22 @@ type Document struct {
 	PosSession   *string                `json:"pos_session,omitempty" db:"pos_session"`
 	Cashier      *string                `json:"cashier,omitempty" db:"cashier"`
 	Location     *string                `json:"location,omitempty" db:"location"`
+
+	// Multi-tenant - NULL pour les documents hors tenant
+	Tenant *string `json:"tenant,omitempty" db:"tenant"`
+
+	// Chiffrement au repos - NULL pour les contenus stockés en clair
+	WrappedKey *string `json:"-" db:"dek_wrapped"`                     // Clé de données enveloppée par la KEK (base64)
+	KEKVersion *string `json:"kek_version,omitempty" db:"kek_version"` // Version (KID) de la KEK
+
+	// Rétention - un document sous blocage légal n'est jamais purgé ; un document purgé
+	// ne garde que ses métadonnées et son SHA256 (pierre tombale)
+	LegalHold   bool       `json:"legal_hold" db:"legal_hold"`
+	PurgedAt    *time.Time `json:"purged_at,omitempty" db:"purged_at"`
+	PurgeReason *string    `json:"purge_reason,omitempty" db:"purge_reason"`
+
+	// Réconciliation - contenu introuvable lors de la dernière réconciliation
+	OrphanedAt *time.Time `json:"orphaned_at,omitempty" db:"orphaned_at"`
 }
 
 // DocumentListResponse représente la réponse pour la liste de documents
@@ -70,5 +86,6 @@ type DocumentQuery struct {
 	Type      string
 	DateFrom  *time.Time
 	DateTo    *time.Time
+	Tenant    string // Restreint la liste au tenant (vide = pas de restriction)
 }
 
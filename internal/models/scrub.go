@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types d'altération détectés par la vérification en arrière-plan (scrub)
+const (
+	ScrubCorruptionMismatch   = "sha256_mismatch"  // contenu relu, SHA256 différent de sha256_hex
+	ScrubCorruptionMissing    = "missing"          // objet absent du stockage
+	ScrubCorruptionUnreadable = "unreadable"       // contenu chiffré altéré (déchiffrement impossible)
+	ScrubCorruptionPayload    = "payload_mismatch" // payload POS invalide ou SHA256 différent
+)
+
+// ScrubState est l'avancement de la vérification en arrière-plan. Le curseur
+// (created_at, id) désigne le dernier document vérifié de la passe en cours ; il est
+// vide entre deux passes
+type ScrubState struct {
+	CursorCreatedAt     *time.Time `json:"cursor_created_at,omitempty"`
+	CursorID            *uuid.UUID `json:"cursor_id,omitempty"`
+	PassStartedAt       *time.Time `json:"pass_started_at,omitempty"`
+	PassesCompleted     int64      `json:"passes_completed"`
+	LastPassCompletedAt *time.Time `json:"last_pass_completed_at,omitempty"`
+}
+
+// Cursor retourne le dernier document vérifié de la passe en cours (nil : début de passe)
+func (s *ScrubState) Cursor() *Document {
+	if s.CursorCreatedAt == nil || s.CursorID == nil {
+		return nil
+	}
+	return &Document{ID: *s.CursorID, CreatedAt: *s.CursorCreatedAt}
+}
+
+// ScrubCorruption est une altération du contenu stocké d'un document. Une altération
+// est levée (ResolvedAt) quand une passe suivante relit un contenu conforme, par exemple
+// après restauration depuis une sauvegarde
+type ScrubCorruption struct {
+	DocumentID  uuid.UUID  `json:"document_id"`
+	Tenant      *string    `json:"tenant,omitempty"`
+	Kind        string     `json:"kind"`
+	StoredPath  string     `json:"stored_path,omitempty"`
+	ExpectedSHA string     `json:"expected_sha256"`
+	ActualSHA   *string    `json:"actual_sha256,omitempty"`
+	Detail      string     `json:"detail,omitempty"`
+	DetectedAt  time.Time  `json:"detected_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuts d'un upload reprenable
+const (
+	UploadStatusOpen      = "open"      // morceaux attendus
+	UploadStatusSealing   = "sealing"   // contenu complet, document en cours d'enregistrement
+	UploadStatusCompleted = "completed" // document enregistré (DocumentID)
+)
+
+// UploadSession est un upload reprenable : le contenu est reçu par morceaux dans des
+// fichiers temporaires, puis enregistré comme facture une fois son SHA256 vérifié
+type UploadSession struct {
+	ID          uuid.UUID       `json:"id"`
+	Tenant      *string         `json:"tenant,omitempty"`
+	SizeBytes   int64           `json:"size_bytes"`
+	SHA256Hex   string          `json:"sha256_hex"` // SHA256 attendu du contenu assemblé
+	OffsetBytes int64           `json:"offset"`     // octets reçus
+	Status      string          `json:"status"`
+	Metadata    json.RawMessage `json:"metadata"` // champs de la facture (source, model, odoo_id, meta...)
+	DocumentID  *uuid.UUID      `json:"document_id,omitempty"`
+	CreatedBy   *string         `json:"created_by,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+
+	// Clé de données enveloppée des morceaux reçus (chiffrement au repos activé)
+	WrappedKey *string `json:"-"`
+	KEKVersion *string `json:"-"`
+}
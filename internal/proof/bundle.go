@@ -0,0 +1,275 @@
+package proof
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/google/uuid"
+)
+
+// Version du format de bundle de preuve
+const BundleVersion = "1"
+
+// SubjectPrefix préfixe le document_id scellé dans la signature du manifest
+const SubjectPrefix = "proof-bundle:"
+
+// Fichiers d'un bundle de preuve
+const (
+	FileManifest          = "manifest.json"
+	FileManifestSignature = "manifest.jws" // signature du manifest par la clé courante
+	FileEvidence          = "evidence.jws"
+	FileLedger            = "ledger.json"
+	FileJWKS              = "jwks.json"
+	FilePayload           = "payload.json" // ticket POS stocké en base
+	FileAnchor            = "anchor.json"  // horodatage RFC 3161 couvrant l'entrée du ledger
+	documentDir           = "document/"
+)
+
+// Types de contenu
+const (
+	ContentFile       = "file"
+	ContentPosPayload = "pos_payload"
+)
+
+// Manifest décrit le contenu d'un bundle ; chaque fichier y est référencé par son SHA256,
+// la signature du manifest couvre donc l'ensemble du bundle
+type Manifest struct {
+	Version      string                     `json:"version"`
+	DocumentID   string                     `json:"document_id"`
+	Filename     string                     `json:"filename"`
+	ContentType  string                     `json:"content_type"`
+	SizeBytes    int64                      `json:"size_bytes"`
+	SHA256Hex    string                     `json:"sha256_hex"`
+	Tenant       *string                    `json:"tenant,omitempty"`
+	Source       *string                    `json:"source,omitempty"`
+	CreatedAt    time.Time                  `json:"created_at"`
+	ContentKind  string                     `json:"content_kind"` // "file" ou "pos_payload"
+	ContentFile  string                     `json:"content_file"`
+	EvidenceKID  string                     `json:"evidence_kid,omitempty"` // clé ayant scellé le document
+	LedgerHash   *string                    `json:"ledger_hash,omitempty"`
+	AnchoredAt   *time.Time                 `json:"anchored_at,omitempty"`  // date certifiée par la TSA
+	Files        map[string]string          `json:"files"`                  // nom -> SHA256
+	Verification *verify.VerificationResult `json:"verification,omitempty"` // vérification en ligne à l'export
+	SigningKID   string                     `json:"signing_kid"`            // clé signant le manifest
+	GeneratedAt  time.Time                  `json:"generated_at"`
+}
+
+// Contents regroupe les éléments d'un bundle avant scellement
+type Contents struct {
+	Document     *models.Document
+	Content      []byte                     // fichier original, ou payload_json (document sans fichier)
+	Ledger       *ledger.ProofEntries       // nil si le document n'est pas dans le ledger
+	Anchor       *ledger.AnchorProof        // nil si l'entrée n'est pas encore horodatée
+	Verification *verify.VerificationResult // optionnel
+}
+
+// Build construit le bundle ZIP de preuve d'un document, vérifiable hors ligne
+func Build(ctx context.Context, db *storage.DB, jwsService *crypto.Service, docID uuid.UUID) ([]byte, error) {
+	if jwsService == nil {
+		return nil, fmt.Errorf("JWS service is required to sign the bundle")
+	}
+
+	doc, err := db.GetDocumentByID(ctx, docID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	if doc.PurgedAt != nil {
+		return nil, storage.ErrDocumentPurged
+	}
+	contents := Contents{Document: doc}
+
+	// Vérification en ligne jointe au manifest (constat au moment de l'export)
+	contents.Verification, err = verify.VerifyDocumentIntegrity(ctx, db, jwsService, docID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify document: %w", err)
+	}
+
+	// Contenu : fichier original ou payload POS
+	if doc.StoredPath != "" {
+		contents.Content, err = db.ReadDocumentContent(ctx, doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document file: %w", err)
+		}
+	} else {
+		contents.Content, err = db.GetDocumentPayload(ctx, docID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document payload: %w", err)
+		}
+		if len(contents.Content) == 0 {
+			return nil, fmt.Errorf("document has neither stored file nor payload")
+		}
+	}
+
+	// Entrée du ledger et voisines (absentes si ledger désactivé)
+	contents.Ledger, err = ledger.LoadProofEntries(ctx, db.Pool, docID)
+	if err != nil && !errors.Is(err, ledger.ErrNoLedgerEntry) {
+		return nil, err
+	}
+
+	// Horodatage RFC 3161 couvrant l'entrée (absent si pas encore ancrée)
+	if contents.Ledger != nil {
+		contents.Anchor, err = ledger.LoadAnchorProof(ctx, db.Pool, contents.Ledger.Entry)
+		if err != nil && !errors.Is(err, ledger.ErrNoAnchor) {
+			return nil, err
+		}
+	}
+
+	return Seal(jwsService, contents)
+}
+
+// Seal assemble le bundle et signe son manifest avec la clé courante
+func Seal(jwsService *crypto.Service, contents Contents) ([]byte, error) {
+	doc := contents.Document
+	files := make(map[string][]byte)
+	manifest := &Manifest{
+		Version:      BundleVersion,
+		DocumentID:   doc.ID.String(),
+		Filename:     doc.Filename,
+		ContentType:  doc.ContentType,
+		SizeBytes:    doc.SizeBytes,
+		SHA256Hex:    doc.SHA256Hex,
+		Tenant:       doc.Tenant,
+		Source:       doc.Source,
+		CreatedAt:    doc.CreatedAt.UTC(),
+		LedgerHash:   doc.LedgerHash,
+		Files:        make(map[string]string),
+		Verification: contents.Verification,
+		SigningKID:   jwsService.GetKID(),
+		GeneratedAt:  time.Now().UTC(),
+	}
+
+	// 1. Contenu
+	if doc.StoredPath != "" {
+		manifest.ContentKind = ContentFile
+		manifest.ContentFile = documentDir + safeFilename(doc.Filename)
+	} else {
+		manifest.ContentKind = ContentPosPayload
+		manifest.ContentFile = FilePayload
+	}
+	files[manifest.ContentFile] = contents.Content
+
+	// 2. Preuve JWS (absente pour un document stocké en mode dégradé)
+	if doc.EvidenceJWS != nil && *doc.EvidenceJWS != "" {
+		files[FileEvidence] = []byte(*doc.EvidenceJWS)
+		if kid, err := crypto.PeekKID(*doc.EvidenceJWS); err == nil {
+			manifest.EvidenceKID = kid
+		}
+	}
+
+	// 3. Maillons du ledger
+	if contents.Ledger != nil {
+		ledgerJSON, err := json.MarshalIndent(contents.Ledger, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal ledger entries: %w", err)
+		}
+		files[FileLedger] = ledgerJSON
+	}
+	if contents.Anchor != nil {
+		anchorJSON, err := json.MarshalIndent(contents.Anchor, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal ledger anchor: %w", err)
+		}
+		files[FileAnchor] = anchorJSON
+		anchoredAt := contents.Anchor.Anchor.GenTime.UTC()
+		manifest.AnchoredAt = &anchoredAt
+	}
+
+	// 4. Clés publiques : clé de scellement du document et clé signant le manifest
+	jwks, err := jwsService.JWKSForKIDs(manifest.EvidenceKID, manifest.SigningKID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export JWKS: %w", err)
+	}
+	files[FileJWKS] = jwks
+
+	for name, content := range files {
+		manifest.Files[name] = sha256Hex(content)
+	}
+
+	// 5. Manifest signé par la clé courante
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	signature, err := jwsService.SignEvidence(SubjectPrefix+manifest.DocumentID, sha256Hex(manifestJSON), manifest.GeneratedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign manifest: %w", err)
+	}
+	files[FileManifest] = manifestJSON
+	files[FileManifestSignature] = []byte(signature)
+
+	return writeZip(files)
+}
+
+// writeZip écrit les fichiers dans un ZIP (ordre déterministe, manifest en tête)
+func writeZip(files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if name != FileManifest && name != FileManifestSignature {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = append([]string{FileManifest, FileManifestSignature}, names...)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to bundle: %w", name, err)
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, fmt.Errorf("failed to write %s to bundle: %w", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// safeFilename neutralise les chemins dans le nom de fichier du document
+func safeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "content"
+	}
+	return name
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// documentFromManifest reconstruit le document décrit par le manifest (vérification hors ligne)
+func documentFromManifest(m *Manifest, evidence []byte) *models.Document {
+	id, _ := uuid.Parse(m.DocumentID)
+	doc := &models.Document{
+		ID:        id,
+		Filename:  m.Filename,
+		SizeBytes: m.SizeBytes,
+		SHA256Hex: m.SHA256Hex,
+		Tenant:    m.Tenant,
+	}
+	if len(evidence) > 0 {
+		jws := string(evidence)
+		doc.EvidenceJWS = &jws
+	}
+	return doc
+}
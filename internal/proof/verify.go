@@ -0,0 +1,211 @@
+package proof
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+)
+
+// Origine des clés utilisées pour la vérification
+const (
+	KeysFromBundle  = "bundle"  // jwks.json du bundle (non épinglé)
+	KeysFromTrusted = "trusted" // JWKS fourni par l'auditeur
+)
+
+// maxBundleFileSize borne la taille décompressée d'un fichier du bundle
+const maxBundleFileSize = 512 << 20
+
+// Report est le résultat de la vérification hors ligne d'un bundle
+type Report struct {
+	Valid      bool           `json:"valid"`
+	DocumentID string         `json:"document_id,omitempty"`
+	KeysSource string         `json:"keys_source"`
+	Checks     []verify.Check `json:"checks"`
+	Errors     []string       `json:"errors,omitempty"`
+	Manifest   *Manifest      `json:"manifest,omitempty"`
+	CheckedAt  time.Time      `json:"checked_at"`
+}
+
+func (r *Report) add(component, status, message string) {
+	check := verify.Check{Component: component, Status: status, Message: message}
+	r.Checks = append(r.Checks, check)
+	if check.Failed() {
+		r.Valid = false
+		r.Errors = append(r.Errors, message)
+	}
+}
+
+// VerifyBundle vérifie un bundle de preuve sans accès au coffre :
+// signature du manifest, empreintes des fichiers, contenu, preuve JWS, maillons du ledger
+// et horodatage RFC 3161.
+// trusted (optionnel) remplace le jwks.json du bundle par des clés obtenues hors bande ;
+// tsaRoots valide le certificat de la TSA (nil : magasin de certificats du système)
+func VerifyBundle(data []byte, trusted crypto.KeySet, tsaRoots *x509.CertPool) (*Report, error) {
+	report := &Report{Valid: true, Checks: []verify.Check{}, CheckedAt: time.Now().UTC()}
+
+	files, err := readZip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestJSON, ok := files[FileManifest]
+	if !ok {
+		report.add("bundle", "missing", "manifest.json not found in bundle")
+		return report, nil
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		report.add("bundle", "error", fmt.Sprintf("Invalid manifest.json: %v", err))
+		return report, nil
+	}
+	report.Manifest = &manifest
+	report.DocumentID = manifest.DocumentID
+
+	// 1. Clés de vérification
+	keys := trusted
+	report.KeysSource = KeysFromTrusted
+	if keys == nil {
+		report.KeysSource = KeysFromBundle
+		keys, err = crypto.ParseJWKS(files[FileJWKS])
+		if err != nil {
+			report.add("keys", "error", fmt.Sprintf("Invalid jwks.json: %v", err))
+			return report, nil
+		}
+	}
+
+	// 2. Signature du manifest (couvre tous les fichiers via leurs empreintes)
+	signature, ok := files[FileManifestSignature]
+	if !ok {
+		report.add("signature", "missing", "manifest.jws not found in bundle")
+		return report, nil
+	}
+	sealed, err := keys.VerifyEvidence(string(signature))
+	switch {
+	case err != nil:
+		report.add("signature", "error", fmt.Sprintf("Invalid manifest signature: %v", err))
+		return report, nil
+	case sealed.Sha256 != sha256Hex(manifestJSON) || sealed.DocumentID != SubjectPrefix+manifest.DocumentID:
+		report.add("signature", "error", "Manifest signature does not match manifest.json")
+		return report, nil
+	default:
+		report.add("signature", "ok", fmt.Sprintf("Manifest signed with kid=%s at %s", sealed.KID, sealed.Timestamp.UTC().Format(time.RFC3339)))
+	}
+
+	// 3. Empreintes des fichiers listés
+	filesOK := true
+	for name, expected := range manifest.Files {
+		content, ok := files[name]
+		if !ok {
+			report.add("files", "missing", fmt.Sprintf("%s listed in manifest but missing from bundle", name))
+			filesOK = false
+		} else if sha256Hex(content) != expected {
+			report.add("files", "error", fmt.Sprintf("%s does not match its manifest SHA256", name))
+			filesOK = false
+		}
+	}
+	if !filesOK {
+		return report, nil
+	}
+	report.add("files", "ok", fmt.Sprintf("%d files match the manifest", len(manifest.Files)))
+
+	// 4. Contenu du document
+	doc := documentFromManifest(&manifest, files[FileEvidence])
+	switch manifest.ContentKind {
+	case ContentFile:
+		content := files[manifest.ContentFile]
+		if actual := sha256Hex(content); actual != manifest.SHA256Hex {
+			report.add("file", "error", fmt.Sprintf("SHA256 mismatch: expected %s, got %s", manifest.SHA256Hex, actual))
+		} else {
+			report.add("file", "ok", fmt.Sprintf("File %s, size=%d, SHA256=%s", manifest.ContentFile, len(content), manifest.SHA256Hex))
+		}
+	case ContentPosPayload:
+		doc.PayloadJSON = files[manifest.ContentFile]
+		check := verify.VerifyPosPayload(doc)
+		report.add(check.Component, check.Status, check.Message)
+	default:
+		report.add("file", "error", fmt.Sprintf("Unknown content kind: %s", manifest.ContentKind))
+	}
+
+	// 5. Preuve JWS du document
+	check, _ := verify.VerifyEvidenceJWS(keys, doc)
+	report.add(check.Component, check.Status, check.Message)
+
+	// 6. Maillons du ledger
+	ledgerJSON, ok := files[FileLedger]
+	if !ok {
+		report.add("ledger", "warn", "No ledger entry in bundle (ledger may be disabled)")
+		return report, nil
+	}
+	var entries ledger.ProofEntries
+	if err := json.Unmarshal(ledgerJSON, &entries); err != nil {
+		report.add("ledger", "error", fmt.Sprintf("Invalid ledger.json: %v", err))
+		return report, nil
+	}
+	ledgerOK := false
+	switch chain := entries.Verify(); {
+	case entries.Entry.DocumentID != manifest.DocumentID || entries.Entry.DocumentSHA != manifest.SHA256Hex:
+		report.add("ledger", "error", "Ledger entry does not belong to this document")
+	case manifest.LedgerHash != nil && entries.Entry.Hash != *manifest.LedgerHash:
+		report.add("ledger", "error", "Ledger hash does not match document ledger_hash")
+	case !chain.Valid:
+		report.add("ledger", "error", fmt.Sprintf("Ledger links invalid (broken=%d, gaps=%d)", chain.BrokenLinkCount, chain.GapCount))
+	default:
+		ledgerOK = true
+		report.add("ledger", "ok", fmt.Sprintf("Ledger entry #%d and %d neighbouring entries recomputed, hash=%s", entries.Entry.ID, chain.EntriesChecked-1, entries.Entry.Hash))
+	}
+
+	// 7. Horodatage RFC 3161 de l'entrée du ledger
+	anchorJSON, ok := files[FileAnchor]
+	if !ok {
+		report.add("timestamp", "warn", "No timestamp anchor in bundle (ledger entry not yet anchored)")
+		return report, nil
+	}
+	if !ledgerOK {
+		return report, nil
+	}
+	var anchor ledger.AnchorProof
+	if err := json.Unmarshal(anchorJSON, &anchor); err != nil {
+		report.add("timestamp", "error", fmt.Sprintf("Invalid anchor.json: %v", err))
+		return report, nil
+	}
+	check, _ = verify.CheckAnchor(&anchor, entries.Entry, tsaRoots)
+	report.add(check.Component, check.Status, check.Message)
+
+	return report, nil
+}
+
+// readZip lit les fichiers du bundle en mémoire
+func readZip(data []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	files := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, maxBundleFileSize+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		if len(content) > maxBundleFileSize {
+			return nil, fmt.Errorf("file %s exceeds maximum size", f.Name)
+		}
+		files[f.Name] = content
+	}
+	return files, nil
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -19,6 +19,7 @@ type OrphanFile struct {
 	Path      string `json:"path"`
 	SizeBytes int64  `json:"size_bytes"`
 	SHA256Hex string `json:"sha256_hex,omitempty"`
+	Shared    bool   `json:"shared,omitempty"` // contenu de la disposition par contenu (ab/cd/<sha256>)
 }
 
 // OrphanDB représente une entrée DB sans fichier
@@ -31,18 +32,33 @@ type OrphanDB struct {
 
 // ReconciliationReport représente le rapport de réconciliation
 type ReconciliationReport struct {
-	Timestamp      time.Time    `json:"timestamp"`
-	DryRun         bool         `json:"dry_run"`
-	OrphanFiles    []OrphanFile `json:"orphan_files"`    // Fichiers sans DB
-	OrphanDBs      []OrphanDB   `json:"orphan_dbs"`      // DB sans fichiers
-	FilesDeleted   int          `json:"files_deleted"`    // Nombre de fichiers supprimés (si fix)
-	DBsMarked      int          `json:"dbs_marked"`      // Nombre d'entrées DB marquées (si fix)
-	Errors         []string     `json:"errors,omitempty"` // Erreurs rencontrées
+	Timestamp    time.Time    `json:"timestamp"`
+	DryRun       bool         `json:"dry_run"`
+	OrphanFiles  []OrphanFile `json:"orphan_files"`     // Fichiers sans DB
+	OrphanDBs    []OrphanDB   `json:"orphan_dbs"`       // DB sans fichiers
+	FilesDeleted int          `json:"files_deleted"`    // Nombre de fichiers supprimés (si fix)
+	DBsMarked    int          `json:"dbs_marked"`       // Nombre d'entrées DB marquées (si fix)
+	DBsRestored  int          `json:"dbs_restored"`     // Nombre de marqueurs retirés, contenu retrouvé (si fix)
+	Errors       []string     `json:"errors,omitempty"` // Erreurs rencontrées
+
+	// Recoupement avec le ledger (Reconcile)
+	UnsealedDocuments       []DocumentIssue `json:"unsealed_documents"` // Documents sans preuve
+	UnsealedCount           int             `json:"unsealed_count"`
+	DocumentsSealed         int             `json:"documents_sealed"`        // Documents scellés après coup (si fix)
+	DanglingLedgerEntries   []LedgerIssue   `json:"dangling_ledger_entries"` // Entrées du ledger sans document
+	DanglingLedgerCount     int             `json:"dangling_ledger_count"`
+	LedgerHashMismatches    []DocumentIssue `json:"ledger_hash_mismatches"` // documents.ledger_hash incohérent
+	LedgerHashMismatchCount int             `json:"ledger_hash_mismatch_count"`
+	InvalidEvidence         []DocumentIssue `json:"invalid_evidence"` // Preuves JWS invalides
+	InvalidEvidenceCount    int             `json:"invalid_evidence_count"`
+	SkippedChecks           []string        `json:"skipped_checks,omitempty"` // Vérifications non exécutées
 }
 
 // CleanupOrphans détecte et corrige les fichiers orphelins
-// - Fichiers sans DB : fichiers dans storage sans entrée correspondante
-// - DB sans fichiers : entrées DB dont le fichier n'existe pas
+// - Fichiers sans DB : objets du stockage (BlobStore, à défaut storageDir) sans entrée correspondante ;
+//   un contenu partagé (disposition par contenu) n'est orphelin qu'une fois sans aucune référence
+// - DB sans fichiers : entrées DB dont le contenu n'existe pas ; en mode fix elles sont
+//   marquées (orphaned_at) et le marqueur est retiré quand le contenu est retrouvé
 func CleanupOrphans(
 	ctx context.Context,
 	db *storage.DB,
@@ -55,14 +71,21 @@ func CleanupOrphans(
 		OrphanFiles: []OrphanFile{},
 		OrphanDBs:   []OrphanDB{},
 		Errors:      []string{},
+
+		UnsealedDocuments:     []DocumentIssue{},
+		DanglingLedgerEntries: []LedgerIssue{},
+		LedgerHashMismatches:  []DocumentIssue{},
+		InvalidEvidence:       []DocumentIssue{},
 	}
 
 	if db == nil {
 		return nil, fmt.Errorf("database not configured")
 	}
 
+	blobs := db.BlobStoreFor(storageDir)
+
 	// 1. Scanner storage pour trouver fichiers sans DB
-	orphanFiles, err := findOrphanFiles(ctx, db, storageDir)
+	orphanFiles, err := findOrphanFiles(ctx, db, blobs)
 	if err != nil {
 		report.Errors = append(report.Errors, fmt.Sprintf("Failed to scan storage: %v", err))
 	} else {
@@ -70,7 +93,7 @@ func CleanupOrphans(
 	}
 
 	// 2. Scanner DB pour trouver entrées sans fichiers
-	orphanDBs, err := findOrphanDBs(ctx, db)
+	orphanDBs, restored, err := findOrphanDBs(ctx, db, blobs, report)
 	if err != nil {
 		report.Errors = append(report.Errors, fmt.Sprintf("Failed to scan database: %v", err))
 	} else {
@@ -81,60 +104,102 @@ func CleanupOrphans(
 	if !dryRun {
 		// Supprimer fichiers orphelins
 		for _, orphan := range report.OrphanFiles {
-			if err := os.Remove(orphan.Path); err != nil {
+			if orphan.Shared {
+				// Revérifié sous verrou : un document a pu le référencer depuis le parcours
+				deleted, err := db.DeleteUnreferencedBlob(ctx, blobs, orphan.Path, orphan.SHA256Hex)
+				if err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("Failed to delete orphan file %s: %v", orphan.Path, err))
+				} else if deleted {
+					report.FilesDeleted++
+				}
+				continue
+			}
+			if err := blobs.Delete(ctx, orphan.Path); err != nil {
 				report.Errors = append(report.Errors, fmt.Sprintf("Failed to delete orphan file %s: %v", orphan.Path, err))
 			} else {
 				report.FilesDeleted++
 			}
 		}
 
-		// Marquer entrées DB orphelines (optionnel : ajouter un champ "orphaned" ou supprimer)
-		// Pour l'instant, on ne fait que les détecter (suppression DB à implémenter si nécessaire)
-		// TODO: Ajouter un champ "orphaned_at" dans documents ou supprimer directement
-		report.DBsMarked = len(report.OrphanDBs)
+		// Marquer entrées DB orphelines (orphaned_at) : l'entrée et sa preuve sont conservées
+		orphanIDs := make([]uuid.UUID, 0, len(report.OrphanDBs))
+		for _, orphan := range report.OrphanDBs {
+			orphanIDs = append(orphanIDs, uuid.MustParse(orphan.DocumentID))
+		}
+		marked, err := db.MarkDocumentsOrphaned(ctx, orphanIDs)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("Failed to mark orphan documents: %v", err))
+		}
+		report.DBsMarked = int(marked)
+
+		// Contenu retrouvé (restauration) : retirer le marqueur
+		cleared, err := db.ClearDocumentsOrphaned(ctx, restored)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("Failed to clear orphan markers: %v", err))
+		}
+		report.DBsRestored = int(cleared)
 	}
 
 	return report, nil
 }
 
-// findOrphanFiles scanne le répertoire storage et trouve les fichiers sans entrée DB
-func findOrphanFiles(ctx context.Context, db *storage.DB, storageDir string) ([]OrphanFile, error) {
+// findOrphanFiles parcourt le stockage et trouve les objets sans entrée DB
+func findOrphanFiles(ctx context.Context, db *storage.DB, blobs blobstore.Store) ([]OrphanFile, error) {
 	var orphans []OrphanFile
 
-	// Scanner récursivement le répertoire storage
-	err := filepath.Walk(storageDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err // Continuer malgré les erreurs
+	err := blobs.Walk(ctx, func(object blobstore.ObjectInfo) error {
+		// Contenu partagé : orphelin s'il n'est plus référencé par aucun document
+		if sha256Hex, ok := blobstore.ContentKeySHA256(object.Key); ok {
+			var referenced bool
+			err := db.Pool.QueryRow(ctx, `
+				SELECT EXISTS (SELECT 1 FROM documents WHERE stored_path = $1)
+					OR EXISTS (SELECT 1 FROM blob_refs WHERE ref = $1 AND ref_count > 0)
+			`, object.Ref).Scan(&referenced)
+			if err != nil {
+				return fmt.Errorf("failed to check file in DB: %w", err)
+			}
+			if !referenced {
+				orphans = append(orphans, OrphanFile{
+					Path:      object.Ref,
+					SizeBytes: object.Size,
+					SHA256Hex: sha256Hex,
+					Shared:    true,
+				})
+			}
+			return nil
 		}
 
-		// Ignorer les répertoires et fichiers temporaires
-		if info.IsDir() {
-			return nil
+		// Objet référencé par un document (contenu chiffré compris : son SHA256 porte sur le clair)
+		var referenced bool
+		if err := db.Pool.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM documents WHERE stored_path = $1)
+		`, object.Ref).Scan(&referenced); err != nil {
+			return fmt.Errorf("failed to check file in DB: %w", err)
 		}
-		if filepath.Ext(path) == ".tmp" {
+		if referenced {
 			return nil
 		}
 
-		// Lire le fichier pour calculer SHA256
-		content, err := os.ReadFile(path)
+		// Lire l'objet pour calculer SHA256
+		content, err := blobstore.ReadAll(ctx, blobs, object.Ref)
 		if err != nil {
-			return nil // Ignorer les fichiers non lisibles
+			return nil // Ignorer les objets non lisibles
 		}
 
 		hash := sha256.Sum256(content)
 		sha256Hex := hex.EncodeToString(hash[:])
 
-		// Vérifier si ce SHA256 existe en DB
+		// Vérifier si ce SHA256 existe en DB (hors pierres tombales : contenu purgé)
 		var docID uuid.UUID
 		err = db.Pool.QueryRow(ctx, `
-			SELECT id FROM documents WHERE sha256_hex = $1 LIMIT 1
+			SELECT id FROM documents WHERE sha256_hex = $1 AND purged_at IS NULL LIMIT 1
 		`, sha256Hex).Scan(&docID)
 
 		if err == pgx.ErrNoRows {
-			// Fichier sans entrée DB → orphelin
+			// Objet sans entrée DB → orphelin
 			orphans = append(orphans, OrphanFile{
-				Path:      path,
-				SizeBytes: info.Size(),
+				Path:      object.Ref,
+				SizeBytes: object.Size,
 				SHA256Hex: sha256Hex,
 			})
 		} else if err != nil {
@@ -147,45 +212,64 @@ func findOrphanFiles(ctx context.Context, db *storage.DB, storageDir string) ([]
 	return orphans, err
 }
 
-// findOrphanDBs trouve les entrées DB dont le fichier n'existe pas
-func findOrphanDBs(ctx context.Context, db *storage.DB) ([]OrphanDB, error) {
+// findOrphanDBs trouve les entrées DB dont le contenu n'existe pas, ainsi que les entrées
+// marquées orphelines dont le contenu a été retrouvé ; une référence illisible (backend non
+// configuré, stockage injoignable) est signalée dans le rapport
+func findOrphanDBs(ctx context.Context, db *storage.DB, blobs blobstore.Store, report *ReconciliationReport) ([]OrphanDB, []uuid.UUID, error) {
 	var orphans []OrphanDB
+	var restored []uuid.UUID
 
 	// Récupérer toutes les entrées avec stored_path
 	rows, err := db.Pool.Query(ctx, `
-		SELECT id, filename, stored_path, sha256_hex
+		SELECT id, filename, stored_path, sha256_hex, orphaned_at IS NOT NULL
 		FROM documents
 		WHERE stored_path IS NOT NULL AND stored_path != ''
 	`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query documents: %w", err)
+		return nil, nil, fmt.Errorf("failed to query documents: %w", err)
 	}
 	defer rows.Close()
 
+	// Un contenu partagé par plusieurs documents n'est vérifié qu'une fois
+	checked := map[string]error{}
+
 	for rows.Next() {
 		var docID uuid.UUID
 		var filename, storedPath, sha256Hex string
+		var orphaned bool
 
-		if err := rows.Scan(&docID, &filename, &storedPath, &sha256Hex); err != nil {
-			return nil, fmt.Errorf("failed to scan document: %w", err)
+		if err := rows.Scan(&docID, &filename, &storedPath, &sha256Hex, &orphaned); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan document: %w", err)
 		}
 
-		// Vérifier si le fichier existe
-		if _, err := os.Stat(storedPath); os.IsNotExist(err) {
-			// Fichier n'existe pas → orphelin DB
+		// Vérifier si le contenu existe
+		shared := blobstore.IsContentRef(storedPath, sha256Hex)
+		err, ok := checked[storedPath]
+		if !ok {
+			_, err = blobs.Stat(ctx, storedPath)
+			if shared {
+				checked[storedPath] = err
+			}
+		}
+		if errors.Is(err, blobstore.ErrNotFound) {
+			// Contenu absent → orphelin DB
 			orphans = append(orphans, OrphanDB{
 				DocumentID: docID.String(),
 				Filename:   filename,
 				StoredPath: storedPath,
 				SHA256Hex:  sha256Hex,
 			})
+		} else if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("Failed to check content of document %s: %v", docID, err))
+		} else if orphaned {
+			restored = append(restored, docID)
 		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating documents: %w", err)
+		return nil, nil, fmt.Errorf("error iterating documents: %w", err)
 	}
 
-	return orphans, nil
+	return orphans, restored, nil
 }
 
@@ -0,0 +1,313 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/google/uuid"
+)
+
+// ReportSubject est l'identifiant scellé dans le JWS du rapport de réconciliation
+const ReportSubject = "reconciliation"
+
+// MaxReportedIssues limite le nombre d'anomalies détaillées par catégorie ; les compteurs
+// portent sur toutes les anomalies
+const MaxReportedIssues = 100
+
+// batchSize est le nombre de documents relus par lot
+const batchSize = 500
+
+// DocumentIssue représente un document dont la preuve est absente ou incohérente
+type DocumentIssue struct {
+	DocumentID string  `json:"document_id"`
+	Tenant     *string `json:"tenant,omitempty"`
+	SHA256Hex  string  `json:"sha256_hex,omitempty"`
+	LedgerHash *string `json:"ledger_hash,omitempty"`
+	Detail     string  `json:"detail,omitempty"`
+	Sealed     bool    `json:"sealed,omitempty"` // scellé après coup (si fix)
+}
+
+// LedgerIssue représente une entrée du ledger dont le document n'existe pas
+type LedgerIssue struct {
+	LedgerID   int64  `json:"ledger_id"`
+	Hash       string `json:"hash"`
+	DocumentID string `json:"document_id"`
+	EntryType  string `json:"entry_type"`
+}
+
+// Sealer scelle après coup un document enregistré sans preuve (services.IngestionService)
+type Sealer interface {
+	Seal(ctx context.Context, doc *models.Document) error
+}
+
+// Options configure une réconciliation complète
+type Options struct {
+	StorageDir string
+	DryRun     bool
+	// Verifier vérifie les preuves JWS (nil : vérification ignorée)
+	Verifier verify.EvidenceVerifier
+	// Sealer scelle les documents sans preuve en mode fix (nil : détection seule)
+	Sealer Sealer
+	// Filter désigne les preuves attendues (JWS et/ou ledger activés)
+	Filter storage.UnsealedFilter
+}
+
+// HasIssues indique si la réconciliation a détecté au moins une anomalie
+func (r *ReconciliationReport) HasIssues() bool {
+	return len(r.OrphanFiles) > 0 || len(r.OrphanDBs) > 0 || r.UnsealedCount > 0 ||
+		r.DanglingLedgerCount > 0 || r.LedgerHashMismatchCount > 0 || r.InvalidEvidenceCount > 0
+}
+
+// Unrepairable retourne le nombre d'anomalies qui subsistent après réparation : documents
+// non scellés, entrées du ledger sans document, ledger_hash incohérents et preuves JWS
+// invalides (ces trois dernières catégories ne sont jamais réparées automatiquement)
+func (r *ReconciliationReport) Unrepairable() int {
+	return r.UnsealedCount - r.DocumentsSealed + r.DanglingLedgerCount + r.LedgerHashMismatchCount + r.InvalidEvidenceCount
+}
+
+// Reconcile exécute la réconciliation complète : fichiers et entrées DB orphelins
+// (CleanupOrphans), puis recoupement avec le ledger :
+//   - documents sans preuve (sans evidence_jws ou sans entrée au ledger selon opts.Filter) ;
+//     en mode fix ils sont scellés après coup (événement document.sealed)
+//   - entrées du ledger dont le document n'existe pas
+//   - documents.ledger_hash ne désignant pas l'entrée document.created du document
+//   - preuves evidence_jws invalides (signature, document_id ou sha256)
+//
+// Les anomalies sont détaillées dans la limite de MaxReportedIssues par catégorie ; une
+// vérification en échec est signalée dans Errors sans interrompre les suivantes
+func Reconcile(ctx context.Context, db *storage.DB, opts Options) (*ReconciliationReport, error) {
+	report, err := CleanupOrphans(ctx, db, opts.StorageDir, opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Filter.MissingJWS || opts.Filter.MissingLedger {
+		if err := checkUnsealed(ctx, db, opts, report); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("Failed to check unsealed documents: %v", err))
+		}
+	} else {
+		report.SkippedChecks = append(report.SkippedChecks, "unsealed_documents (JWS and ledger disabled)")
+	}
+
+	if err := checkDanglingLedger(ctx, db, report); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("Failed to check ledger entries: %v", err))
+	}
+
+	if err := checkLedgerHashes(ctx, db, report); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("Failed to check ledger hashes: %v", err))
+	}
+
+	if opts.Verifier != nil {
+		if err := checkEvidence(ctx, db, opts.Verifier, report); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("Failed to check evidence: %v", err))
+		}
+	} else {
+		report.SkippedChecks = append(report.SkippedChecks, "invalid_evidence (JWS service not configured)")
+	}
+
+	return report, nil
+}
+
+// checkUnsealed liste les documents sans preuve et, en mode fix, les scelle
+func checkUnsealed(ctx context.Context, db *storage.DB, opts Options, report *ReconciliationReport) error {
+	var after *models.Document
+	for {
+		docs, err := db.ListUnsealedDocuments(ctx, opts.Filter, after, batchSize)
+		if err != nil {
+			return err
+		}
+		for i := range docs {
+			doc := &docs[i]
+			after = &models.Document{ID: doc.ID, CreatedAt: doc.CreatedAt}
+
+			issue := DocumentIssue{
+				DocumentID: doc.ID.String(),
+				Tenant:     doc.Tenant,
+				SHA256Hex:  doc.SHA256Hex,
+				Detail:     unsealedDetail(doc, opts.Filter),
+			}
+			report.UnsealedCount++
+
+			if !opts.DryRun && opts.Sealer != nil {
+				if err := opts.Sealer.Seal(ctx, doc); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("Failed to seal document %s: %v", doc.ID, err))
+				} else {
+					issue.Sealed = true
+					issue.LedgerHash = doc.LedgerHash
+					report.DocumentsSealed++
+				}
+			}
+
+			if len(report.UnsealedDocuments) < MaxReportedIssues {
+				report.UnsealedDocuments = append(report.UnsealedDocuments, issue)
+			}
+		}
+		if len(docs) < batchSize {
+			return nil
+		}
+	}
+}
+
+// unsealedDetail décrit la preuve manquante d'un document
+func unsealedDetail(doc *models.Document, filter storage.UnsealedFilter) string {
+	missingJWS := filter.MissingJWS && doc.EvidenceJWS == nil
+	missingLedger := filter.MissingLedger && doc.LedgerHash == nil
+	switch {
+	case missingJWS && missingLedger:
+		return "no evidence_jws and no ledger entry"
+	case missingJWS:
+		return "no evidence_jws"
+	default:
+		return "no ledger entry"
+	}
+}
+
+// checkDanglingLedger trouve les entrées du ledger désignant un document inexistant
+func checkDanglingLedger(ctx context.Context, db *storage.DB, report *ReconciliationReport) error {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT l.id, l.hash, l.document_id, l.entry_type
+		FROM ledger l
+		WHERE l.document_id IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM documents d WHERE d.id = l.document_id)
+		ORDER BY l.id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query ledger: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var issue LedgerIssue
+		var documentID uuid.UUID
+		if err := rows.Scan(&issue.LedgerID, &issue.Hash, &documentID, &issue.EntryType); err != nil {
+			return fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		issue.DocumentID = documentID.String()
+		report.DanglingLedgerCount++
+		if len(report.DanglingLedgerEntries) < MaxReportedIssues {
+			report.DanglingLedgerEntries = append(report.DanglingLedgerEntries, issue)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating ledger entries: %w", err)
+	}
+	return nil
+}
+
+// checkLedgerHashes trouve les documents dont ledger_hash ne désigne pas leur entrée
+// document.created (entrée absente, d'un autre document ou d'un autre type)
+func checkLedgerHashes(ctx context.Context, db *storage.DB, report *ReconciliationReport) error {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT d.id, d.tenant, d.sha256_hex, d.ledger_hash, l.document_id, l.entry_type
+		FROM documents d
+		LEFT JOIN LATERAL (
+			SELECT document_id, entry_type FROM ledger WHERE hash = d.ledger_hash ORDER BY id LIMIT 1
+		) l ON true
+		WHERE d.ledger_hash IS NOT NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM ledger m
+			WHERE m.hash = d.ledger_hash AND m.document_id = d.id AND m.entry_type IN ('', $1)
+		  )
+		ORDER BY d.created_at, d.id
+	`, ledger.EntryTypeDocumentCreated)
+	if err != nil {
+		return fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var ledgerDocID *uuid.UUID
+		var entryType *string
+		issue := DocumentIssue{}
+		if err := rows.Scan(&id, &issue.Tenant, &issue.SHA256Hex, &issue.LedgerHash, &ledgerDocID, &entryType); err != nil {
+			return fmt.Errorf("failed to scan document: %w", err)
+		}
+		issue.DocumentID = id.String()
+		switch {
+		case entryType == nil:
+			issue.Detail = "ledger entry not found"
+		case ledgerDocID == nil || *ledgerDocID != id:
+			other := "none"
+			if ledgerDocID != nil {
+				other = ledgerDocID.String()
+			}
+			issue.Detail = fmt.Sprintf("ledger entry belongs to document %s", other)
+		default:
+			issue.Detail = fmt.Sprintf("ledger entry is a %s entry", *entryType)
+		}
+		report.LedgerHashMismatchCount++
+		if len(report.LedgerHashMismatches) < MaxReportedIssues {
+			report.LedgerHashMismatches = append(report.LedgerHashMismatches, issue)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating documents: %w", err)
+	}
+	return nil
+}
+
+// checkEvidence vérifie la preuve JWS de chaque document qui en porte une
+func checkEvidence(ctx context.Context, db *storage.DB, verifier verify.EvidenceVerifier, report *ReconciliationReport) error {
+	var after *models.Document
+	for {
+		args := []interface{}{batchSize}
+		query := `
+			SELECT id, tenant, sha256_hex, evidence_jws, created_at
+			FROM documents
+			WHERE evidence_jws IS NOT NULL`
+		if after != nil {
+			args = append(args, after.CreatedAt, after.ID)
+			query += " AND (created_at, id) > ($2, $3)"
+		}
+		query += " ORDER BY created_at, id LIMIT $1"
+
+		docs, err := func() ([]models.Document, error) {
+			rows, err := db.Pool.Query(ctx, query, args...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to query documents: %w", err)
+			}
+			defer rows.Close()
+			var docs []models.Document
+			for rows.Next() {
+				var doc models.Document
+				if err := rows.Scan(&doc.ID, &doc.Tenant, &doc.SHA256Hex, &doc.EvidenceJWS, &doc.CreatedAt); err != nil {
+					return nil, fmt.Errorf("failed to scan document: %w", err)
+				}
+				docs = append(docs, doc)
+			}
+			if err := rows.Err(); err != nil {
+				return nil, fmt.Errorf("error iterating documents: %w", err)
+			}
+			return docs, nil
+		}()
+		if err != nil {
+			return err
+		}
+
+		for i := range docs {
+			doc := &docs[i]
+			after = doc
+			check, _ := verify.VerifyEvidenceJWS(verifier, doc)
+			if check.Status != "error" {
+				continue
+			}
+			report.InvalidEvidenceCount++
+			if len(report.InvalidEvidence) < MaxReportedIssues {
+				report.InvalidEvidence = append(report.InvalidEvidence, DocumentIssue{
+					DocumentID: doc.ID.String(),
+					Tenant:     doc.Tenant,
+					SHA256Hex:  doc.SHA256Hex,
+					Detail:     check.Message,
+				})
+			}
+		}
+		if len(docs) < batchSize {
+			return nil
+		}
+	}
+}
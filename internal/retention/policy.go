@@ -0,0 +1,165 @@
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+)
+
+// AnySource désigne dans RETENTION_POLICIES la règle applicable à toutes les sources
+const AnySource = "*"
+
+// Policy est une durée de conservation applicable aux documents d'une source et,
+// optionnellement, d'un modèle Odoo (champ vide : tous)
+type Policy struct {
+	Source    string
+	OdooModel string
+	Years     int
+	Months    int
+	Days      int
+}
+
+// ParsePolicies lit RETENTION_POLICIES : règles source[/odoo_model]=durée séparées par des
+// virgules, durée en années (10y), mois (6m) ou jours (30d), * pour toutes les sources.
+// Exemple : sales/account.move=10y,pos=6y,*=10y. Les règles sont triées de la plus
+// précise à la plus générale
+func ParsePolicies(spec string) ([]Policy, error) {
+	var policies []Policy
+	seen := map[string]bool{}
+	for _, rule := range strings.Split(spec, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		selector, duration, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid retention policy %q (expected source[/odoo_model]=duration)", rule)
+		}
+		var p Policy
+		p.Source, p.OdooModel, _ = strings.Cut(strings.TrimSpace(selector), "/")
+		p.Source = strings.TrimSpace(p.Source)
+		p.OdooModel = strings.TrimSpace(p.OdooModel)
+		switch p.Source {
+		case "":
+			return nil, fmt.Errorf("invalid retention policy %q: missing source (%s for all sources)", rule, AnySource)
+		case AnySource:
+			p.Source = ""
+		}
+		if err := p.parseDuration(strings.TrimSpace(duration)); err != nil {
+			return nil, fmt.Errorf("invalid retention policy %q: %w", rule, err)
+		}
+		if seen[p.Selector()] {
+			return nil, fmt.Errorf("duplicate retention policy for %s", p.Selector())
+		}
+		seen[p.Selector()] = true
+		policies = append(policies, p)
+	}
+
+	sort.SliceStable(policies, func(i, j int) bool {
+		return policies[i].specificity() > policies[j].specificity()
+	})
+	return policies, nil
+}
+
+func (p *Policy) parseDuration(s string) error {
+	if len(s) < 2 {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	switch s[len(s)-1] {
+	case 'y':
+		p.Years = n
+	case 'm':
+		p.Months = n
+	case 'd':
+		p.Days = n
+	default:
+		return fmt.Errorf("invalid duration unit in %q (expected y, m or d)", s)
+	}
+	return nil
+}
+
+// Selector retourne la partie source[/odoo_model] de la règle
+func (p Policy) Selector() string {
+	source := p.Source
+	if source == "" {
+		source = AnySource
+	}
+	if p.OdooModel != "" {
+		return source + "/" + p.OdooModel
+	}
+	return source
+}
+
+// String retourne la règle au format de RETENTION_POLICIES
+func (p Policy) String() string {
+	switch {
+	case p.Years > 0:
+		return fmt.Sprintf("%s=%dy", p.Selector(), p.Years)
+	case p.Months > 0:
+		return fmt.Sprintf("%s=%dm", p.Selector(), p.Months)
+	default:
+		return fmt.Sprintf("%s=%dd", p.Selector(), p.Days)
+	}
+}
+
+// Cutoff retourne la date de création avant laquelle un document est échu à now
+func (p Policy) Cutoff(now time.Time) time.Time {
+	return now.AddDate(-p.Years, -p.Months, -p.Days)
+}
+
+// Matches indique si la règle s'applique à un document de cette source et de ce modèle
+func (p Policy) Matches(source, odooModel string) bool {
+	return (p.Source == "" || p.Source == source) && (p.OdooModel == "" || p.OdooModel == odooModel)
+}
+
+// specificity classe les règles : source et modèle, source, modèle, toutes sources
+func (p Policy) specificity() int {
+	n := 0
+	if p.Source != "" {
+		n += 2
+	}
+	if p.OdooModel != "" {
+		n++
+	}
+	return n
+}
+
+// overlaps indique si un document peut relever des deux règles
+func (p Policy) overlaps(other Policy) bool {
+	return (p.Source == "" || other.Source == "" || p.Source == other.Source) &&
+		(p.OdooModel == "" || other.OdooModel == "" || p.OdooModel == other.OdooModel)
+}
+
+func (p Policy) match() storage.RetentionMatch {
+	return storage.RetentionMatch{Source: p.Source, OdooModel: p.OdooModel}
+}
+
+// PolicyFor retourne la règle la plus précise applicable au document (nil : conservé
+// indéfiniment). policies doit être trié par ParsePolicies
+func PolicyFor(policies []Policy, source, odooModel string) *Policy {
+	for i := range policies {
+		if policies[i].Matches(source, odooModel) {
+			return &policies[i]
+		}
+	}
+	return nil
+}
+
+// exclusions retourne les règles plus précises qui prennent le pas sur policies[i]
+func exclusions(policies []Policy, i int) []storage.RetentionMatch {
+	var exclude []storage.RetentionMatch
+	for j, other := range policies {
+		if j != i && other.specificity() > policies[i].specificity() && other.overlaps(policies[i]) {
+			exclude = append(exclude, other.match())
+		}
+	}
+	return exclude
+}
@@ -0,0 +1,213 @@
+// Package retention applique les durées de conservation par source et modèle Odoo :
+// une fois la durée échue, le contenu d'un document est purgé et seule une pierre
+// tombale (métadonnées, SHA256, preuve) est conservée. Les documents sous blocage
+// légal ne sont jamais purgés ; chaque purge est inscrite au ledger et à l'audit.
+package retention
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// SchedulerActor identifie les purges automatiques dans le ledger et l'audit
+const SchedulerActor = "scheduler"
+
+// Config contient la configuration des purges
+type Config struct {
+	Policies  []Policy // triées par ParsePolicies
+	BatchSize int      // documents listés par requête (défaut 500)
+	Logger    zerolog.Logger
+}
+
+// Purger purge les documents dont la durée de conservation est échue
+type Purger struct {
+	db          *storage.DB
+	auditLogger *audit.Logger // optionnel : événement document_purged
+	cfg         Config
+}
+
+// NewPurger crée un nouveau Purger
+func NewPurger(db *storage.DB, auditLogger *audit.Logger, cfg Config) *Purger {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return &Purger{
+		db:          db,
+		auditLogger: auditLogger,
+		cfg:         cfg,
+	}
+}
+
+// Options contrôle une passe de purge
+type Options struct {
+	DryRun bool   // lister les documents échus sans rien purger
+	Limit  int    // nombre maximal de documents purgés (0 : tous)
+	Actor  string // auteur de la purge (ledger, audit)
+}
+
+// PolicyReport résume une règle de rétention
+type PolicyReport struct {
+	Policy  string    `json:"policy"`
+	Cutoff  time.Time `json:"cutoff"`
+	Expired int       `json:"expired"`
+	Purged  int       `json:"purged"`
+}
+
+// Failure décrit un document non purgé
+type Failure struct {
+	DocumentID string `json:"document_id"`
+	Error      string `json:"error"`
+}
+
+// Report résume une passe de purge
+type Report struct {
+	Timestamp string         `json:"timestamp"`
+	DryRun    bool           `json:"dry_run"`
+	Policies  []PolicyReport `json:"policies"`
+	Expired   int            `json:"expired"`
+	Purged    int            `json:"purged"`
+	Failures  []Failure      `json:"failures,omitempty"`
+}
+
+// Run purge les documents échus, règle par règle. Un document relève de la règle la plus
+// précise qui lui est applicable. Une erreur n'est retournée qu'en cas d'échec de la
+// recherche ; les documents non purgés figurent dans Failures
+func (p *Purger) Run(ctx context.Context, opts Options) (*Report, error) {
+	now := time.Now().UTC()
+	report := &Report{
+		Timestamp: now.Format(time.RFC3339),
+		DryRun:    opts.DryRun,
+	}
+
+	for i, policy := range p.cfg.Policies {
+		pr := PolicyReport{Policy: policy.String(), Cutoff: policy.Cutoff(now)}
+		exclude := exclusions(p.cfg.Policies, i)
+
+		// Parcours par curseur : un document non purgé n'est pas relu, et un lot composé
+		// uniquement d'échecs n'interrompt pas la passe
+		var cursor *storage.RetentionCursor
+		for {
+			if opts.Limit > 0 && report.Purged >= opts.Limit {
+				break
+			}
+			batch := p.cfg.BatchSize
+			if opts.DryRun {
+				batch = 0
+			}
+			docs, err := p.db.ListExpiredDocuments(ctx, policy.match(), exclude, pr.Cutoff, cursor, batch)
+			if err != nil {
+				report.Policies = append(report.Policies, pr)
+				return report, err
+			}
+			if opts.DryRun {
+				pr.Expired = len(docs)
+				break
+			}
+
+			for _, doc := range docs {
+				if opts.Limit > 0 && report.Purged >= opts.Limit {
+					break
+				}
+				cursor = doc.Cursor()
+				pr.Expired++
+				if err := p.purge(ctx, doc.ID, doc.SHA256Hex, doc.Tenant, doc.Source, policy, opts.Actor); err != nil {
+					// Blocage légal posé entre la recherche et la purge : document conservé
+					if !errors.Is(err, storage.ErrLegalHold) && !errors.Is(err, storage.ErrDocumentPurged) {
+						report.Failures = append(report.Failures, Failure{DocumentID: doc.ID.String(), Error: err.Error()})
+					}
+					continue
+				}
+				pr.Purged++
+				report.Purged++
+			}
+			if len(docs) < p.cfg.BatchSize {
+				break
+			}
+			if ctx.Err() != nil {
+				report.Policies = append(report.Policies, pr)
+				return report, ctx.Err()
+			}
+		}
+
+		report.Expired += pr.Expired
+		report.Policies = append(report.Policies, pr)
+	}
+
+	return report, nil
+}
+
+// purge purge un document et l'inscrit à l'audit
+func (p *Purger) purge(ctx context.Context, id uuid.UUID, sha256Hex string, tenant, source *string, policy Policy, actor string) error {
+	startTime := time.Now()
+	reason := "retention " + policy.String()
+
+	result, err := p.db.PurgeDocument(ctx, id, reason, actor)
+	if err != nil {
+		return err
+	}
+
+	p.cfg.Logger.Info().
+		Str("document_id", id.String()).
+		Str("policy", policy.String()).
+		Bool("content_deleted", result.ContentDeleted).
+		Msg("Document purged")
+
+	if p.auditLogger != nil {
+		event := audit.Event{
+			EventType:  audit.EventTypeDocumentPurged,
+			DocumentID: id.String(),
+			Status:     audit.EventStatusSuccess,
+			DurationMS: time.Since(startTime).Milliseconds(),
+			Metadata: map[string]interface{}{
+				"sha256_hex":      sha256Hex,
+				"policy":          policy.String(),
+				"ledger_hash":     result.LedgerHash,
+				"content_deleted": result.ContentDeleted,
+				"actor":           actor,
+			},
+		}
+		if tenant != nil {
+			event.Tenant = *tenant
+		}
+		if source != nil {
+			event.Source = *source
+		}
+		p.auditLogger.Log(event)
+	}
+	return nil
+}
+
+// StartScheduler purge les documents échus à chaque intervalle
+func StartScheduler(ctx context.Context, purger *Purger, interval time.Duration) {
+	if interval == 0 {
+		interval = 24 * time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			report, err := purger.Run(ctx, Options{Actor: SchedulerActor})
+			if err != nil && ctx.Err() == nil {
+				purger.cfg.Logger.Error().Err(err).Msg("Scheduled purge failed")
+			} else if report != nil && (report.Purged > 0 || len(report.Failures) > 0) {
+				purger.cfg.Logger.Info().
+					Int("purged", report.Purged).
+					Int("failures", len(report.Failures)).
+					Msg("Scheduled purge completed")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
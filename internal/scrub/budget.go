@@ -0,0 +1,70 @@
+package scrub
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// maxChunk borne les lectures pour que l'attente imposée par le budget reste régulière
+const maxChunk = 64 << 10
+
+// budget limite le débit de lecture de la vérification (octets par seconde) : après
+// chaque lecture, l'appelant attend que le volume lu depuis le début de la passe tienne
+// dans le budget
+type budget struct {
+	bytesPerSecond int64 // 0 : illimité
+	start          time.Time
+	used           int64
+}
+
+func newBudget(bytesPerSecond int64) *budget {
+	return &budget{bytesPerSecond: bytesPerSecond, start: time.Now()}
+}
+
+// wait décompte n octets lus et attend si le budget est dépassé
+func (b *budget) wait(ctx context.Context, n int) error {
+	if b.bytesPerSecond <= 0 || n <= 0 {
+		return nil
+	}
+	b.used += int64(n)
+	due := time.Duration(float64(b.used) / float64(b.bytesPerSecond) * float64(time.Second))
+	delay := due - time.Since(b.start)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// chunk retourne la taille maximale d'une lecture
+func (b *budget) chunk() int {
+	if b.bytesPerSecond > 0 && b.bytesPerSecond < maxChunk {
+		return int(b.bytesPerSecond)
+	}
+	return maxChunk
+}
+
+// throttledReader lit r dans la limite du budget
+type throttledReader struct {
+	ctx    context.Context
+	r      io.Reader
+	budget *budget
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if chunk := t.budget.chunk(); len(p) > chunk {
+		p = p[:chunk]
+	}
+	n, err := t.r.Read(p)
+	if werr := t.budget.wait(t.ctx, n); werr != nil {
+		return n, werr
+	}
+	return n, err
+}
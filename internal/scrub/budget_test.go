@@ -0,0 +1,52 @@
+package scrub
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottledReader_RespectsBudget(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 3000)
+	b := newBudget(10000) // 3000 octets : au moins 300 ms
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, &throttledReader{ctx: context.Background(), r: bytes.NewReader(data), budget: b})
+
+	require.NoError(t, err)
+	assert.EqualValues(t, len(data), n)
+	assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+}
+
+func TestThrottledReader_Unlimited(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 1<<20)
+	b := newBudget(0)
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, &throttledReader{ctx: context.Background(), r: bytes.NewReader(data), budget: b})
+
+	require.NoError(t, err)
+	assert.EqualValues(t, len(data), n)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestThrottledReader_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := newBudget(1) // 1 octet/s : la première lecture attend
+
+	_, err := io.Copy(io.Discard, &throttledReader{ctx: ctx, r: bytes.NewReader([]byte("contenu")), budget: b})
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBudget_Chunk(t *testing.T) {
+	assert.Equal(t, maxChunk, newBudget(0).chunk())
+	assert.Equal(t, maxChunk, newBudget(10<<20).chunk())
+	assert.Equal(t, 1000, newBudget(1000).chunk())
+}
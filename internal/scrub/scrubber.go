@@ -0,0 +1,356 @@
+// Package scrub vérifie en arrière-plan l'intégrité du contenu stocké : chaque fichier
+// ou objet est relu (déchiffré s'il est chiffré) et son SHA256 comparé à sha256_hex, le
+// hash du payload des tickets POS est recalculé. La lecture est limitée par un budget
+// d'I/O et une passe interrompue reprend au curseur persisté. Les altérations sont
+// enregistrées (scrub_corruptions), exportées en métriques et signalées par un webhook
+// error.critical.
+package scrub
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/metrics"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/doreviateam/dorevia-vault/internal/webhooks"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// retryDelay sépare deux tentatives après l'échec d'une passe (base indisponible...)
+const retryDelay = 5 * time.Minute
+
+// Config contient la configuration de la vérification
+type Config struct {
+	BytesPerSecond int64             // budget de lecture (0 : illimité)
+	BatchSize      int               // documents lus par requête, curseur enregistré après chaque lot (défaut 100)
+	WebhookManager *webhooks.Manager // alertes error.critical (optionnel)
+	Logger         zerolog.Logger
+}
+
+// Scrubber relit le contenu stocké des documents et détecte les altérations
+type Scrubber struct {
+	db  *storage.DB
+	cfg Config
+}
+
+// NewScrubber crée un nouveau Scrubber
+func NewScrubber(db *storage.DB, cfg Config) *Scrubber {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &Scrubber{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// Failure décrit un document non vérifié (stockage indisponible...) ; il est relu à la
+// passe suivante
+type Failure struct {
+	DocumentID string `json:"document_id"`
+	Error      string `json:"error"`
+}
+
+// Report résume l'exécution d'une passe
+type Report struct {
+	Timestamp      string    `json:"timestamp"`
+	Resumed        bool      `json:"resumed"`   // reprise d'une passe interrompue
+	Completed      bool      `json:"completed"` // passe terminée, curseur remis à zéro
+	Scanned        int       `json:"scanned"`
+	Bytes          int64     `json:"bytes"`
+	Corrupted      int       `json:"corrupted"`       // documents altérés (nouveaux ou déjà connus)
+	NewCorruptions int       `json:"new_corruptions"` // altérations signalées par cette exécution
+	Resolved       int64     `json:"resolved"`        // altérations levées (contenu relu conforme)
+	Failures       []Failure `json:"failures,omitempty"`
+}
+
+// Run poursuit la passe en cours depuis le curseur persisté jusqu'au dernier document,
+// puis la termine. Le curseur est enregistré après chaque lot, et à l'annulation de ctx
+// pour les documents déjà vérifiés. Une erreur n'est retournée qu'en cas d'échec de la
+// base ; les documents non vérifiés figurent dans Failures
+func (s *Scrubber) Run(ctx context.Context) (*Report, error) {
+	report := &Report{Timestamp: time.Now().UTC().Format(time.RFC3339)}
+
+	state, err := s.db.GetScrubState(ctx)
+	if err != nil {
+		return report, err
+	}
+	after := state.Cursor()
+	report.Resumed = after != nil
+	b := newBudget(s.cfg.BytesPerSecond)
+
+	for {
+		docs, err := s.db.ListScrubDocuments(ctx, after, s.cfg.BatchSize)
+		if err != nil {
+			return report, err
+		}
+
+		var healthy []uuid.UUID
+		var last *models.Document
+		for i := range docs {
+			doc := &docs[i]
+			corruption, n, err := s.check(ctx, b, doc)
+			if ctx.Err() != nil {
+				break
+			}
+			report.Scanned++
+			report.Bytes += n
+			switch {
+			case err != nil:
+				metrics.RecordScrubDocument("error", n)
+				s.cfg.Logger.Warn().Err(err).Str("document_id", doc.ID.String()).Msg("Failed to scrub document")
+				report.Failures = append(report.Failures, Failure{DocumentID: doc.ID.String(), Error: err.Error()})
+			case corruption != nil:
+				metrics.RecordScrubDocument("corrupted", n)
+				report.Corrupted++
+				opened, err := s.record(ctx, corruption)
+				if err != nil {
+					if cerr := s.checkpoint(ctx, last, healthy, report); cerr != nil {
+						s.cfg.Logger.Error().Err(cerr).Msg("Failed to save scrub cursor")
+					}
+					return report, err
+				}
+				if opened {
+					report.NewCorruptions++
+				}
+			default:
+				metrics.RecordScrubDocument("ok", n)
+				healthy = append(healthy, doc.ID)
+			}
+			last = doc
+		}
+
+		if err := s.checkpoint(ctx, last, healthy, report); err != nil {
+			return report, err
+		}
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		if len(docs) < s.cfg.BatchSize {
+			break
+		}
+		after = last
+	}
+
+	state, err = s.db.CompleteScrubPass(ctx)
+	if err != nil {
+		return report, err
+	}
+	metrics.UpdateScrubLastPassCompleted(*state.LastPassCompletedAt)
+	report.Completed = true
+	return report, nil
+}
+
+// check relit le contenu d'un document. Retourne l'altération détectée, le nombre
+// d'octets relus, ou une erreur si le contenu n'a pas pu être vérifié
+func (s *Scrubber) check(ctx context.Context, b *budget, doc *models.Document) (*models.ScrubCorruption, int64, error) {
+	// Ticket POS : payload stocké en base, hash recalculé
+	if doc.StoredPath == "" {
+		if len(doc.PayloadJSON) == 0 {
+			return corruption(doc, models.ScrubCorruptionMissing, "No stored_path nor payload_json in database", nil), 0, nil
+		}
+		n := int64(len(doc.PayloadJSON))
+		if err := b.wait(ctx, len(doc.PayloadJSON)); err != nil {
+			return nil, 0, err
+		}
+		if check := verify.VerifyPosPayload(doc); check.Status != "ok" {
+			return corruption(doc, models.ScrubCorruptionPayload, check.Message, nil), n, nil
+		}
+		return nil, n, nil
+	}
+
+	r, err := s.db.OpenDocumentContent(ctx, doc)
+	if err != nil {
+		return classify(doc, err, 0)
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(hash, &throttledReader{ctx: ctx, r: r, budget: b})
+	if err != nil {
+		return classify(doc, err, n)
+	}
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if actual != doc.SHA256Hex {
+		detail := fmt.Sprintf("SHA256 mismatch: expected %s, got %s (%d bytes read)", doc.SHA256Hex, actual, n)
+		return corruption(doc, models.ScrubCorruptionMismatch, detail, &actual), n, nil
+	}
+	return nil, n, nil
+}
+
+// classify distingue une altération (objet absent, contenu chiffré altéré) d'une erreur
+// transitoire du stockage
+func classify(doc *models.Document, err error, n int64) (*models.ScrubCorruption, int64, error) {
+	switch {
+	case errors.Is(err, blobstore.ErrNotFound):
+		return corruption(doc, models.ScrubCorruptionMissing, fmt.Sprintf("File not found: %s", doc.StoredPath), nil), n, nil
+	case errors.Is(err, crypto.ErrContentDecryption):
+		return corruption(doc, models.ScrubCorruptionUnreadable, err.Error(), nil), n, nil
+	default:
+		return nil, n, fmt.Errorf("failed to read content: %w", err)
+	}
+}
+
+func corruption(doc *models.Document, kind, detail string, actual *string) *models.ScrubCorruption {
+	return &models.ScrubCorruption{
+		DocumentID:  doc.ID,
+		Tenant:      doc.Tenant,
+		Kind:        kind,
+		StoredPath:  doc.StoredPath,
+		ExpectedSHA: doc.SHA256Hex,
+		ActualSHA:   actual,
+		Detail:      detail,
+	}
+}
+
+// record enregistre une altération ; une nouvelle altération est comptée et signalée
+// (error.critical). Retourne true si l'altération est nouvelle
+func (s *Scrubber) record(ctx context.Context, c *models.ScrubCorruption) (bool, error) {
+	opened, err := s.db.RecordScrubCorruption(ctx, c)
+	if err != nil {
+		return false, err
+	}
+	if !opened {
+		s.cfg.Logger.Warn().Str("document_id", c.DocumentID.String()).Str("kind", c.Kind).Msg("Stored content still corrupted")
+		return false, nil
+	}
+
+	s.cfg.Logger.Error().
+		Str("document_id", c.DocumentID.String()).
+		Str("kind", c.Kind).
+		Str("stored_path", c.StoredPath).
+		Str("detail", c.Detail).
+		Msg("Stored content corruption detected")
+	metrics.RecordScrubCorruption(c.Kind)
+
+	if s.cfg.WebhookManager != nil {
+		payload := map[string]interface{}{
+			"source":          "scrub",
+			"kind":            c.Kind,
+			"stored_path":     c.StoredPath,
+			"expected_sha256": c.ExpectedSHA,
+			"detail":          c.Detail,
+			"detected_at":     c.DetectedAt.UTC().Format(time.RFC3339),
+		}
+		if c.ActualSHA != nil {
+			payload["actual_sha256"] = *c.ActualSHA
+		}
+		if c.Tenant != nil {
+			payload["tenant"] = *c.Tenant
+		}
+		if err := s.cfg.WebhookManager.EmitEvent(ctx, webhooks.EventTypeErrorCritical, c.DocumentID.String(), payload); err != nil {
+			s.cfg.Logger.Error().Err(err).Msg("Failed to emit error.critical webhook")
+		}
+	}
+	return true, nil
+}
+
+// checkpoint lève les altérations des documents relus conformes et enregistre le
+// curseur, y compris après l'annulation de ctx (arrêt du serveur)
+func (s *Scrubber) checkpoint(ctx context.Context, last *models.Document, healthy []uuid.UUID, report *Report) error {
+	if last == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	resolved, err := s.db.ResolveScrubCorruptions(ctx, healthy)
+	if err != nil {
+		return err
+	}
+	if resolved > 0 {
+		s.cfg.Logger.Info().Int64("resolved", resolved).Msg("Stored content corruptions resolved")
+		report.Resolved += resolved
+	}
+	if err := s.db.SaveScrubCursor(ctx, last); err != nil {
+		return err
+	}
+	return s.updateOpenCorruptions(ctx)
+}
+
+// updateOpenCorruptions met à jour la gauge des altérations non résolues
+func (s *Scrubber) updateOpenCorruptions(ctx context.Context) error {
+	count, err := s.db.CountOpenScrubCorruptions(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.UpdateScrubOpenCorruptions(count)
+	return nil
+}
+
+// untilNextPass retourne le délai avant la prochaine exécution : immédiat si une passe
+// est en cours ou n'a jamais eu lieu, sinon interval après la fin de la dernière passe.
+// Les gauges sont initialisées depuis l'état persisté
+func (s *Scrubber) untilNextPass(ctx context.Context, interval time.Duration) (time.Duration, error) {
+	state, err := s.db.GetScrubState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.updateOpenCorruptions(ctx); err != nil {
+		return 0, err
+	}
+	if state.LastPassCompletedAt != nil {
+		metrics.UpdateScrubLastPassCompleted(*state.LastPassCompletedAt)
+	}
+	if state.Cursor() != nil || state.LastPassCompletedAt == nil {
+		return 0, nil
+	}
+	return time.Until(state.LastPassCompletedAt.Add(interval)), nil
+}
+
+// StartScheduler enchaîne les passes de vérification : une passe interrompue (arrêt,
+// redémarrage) reprend au curseur persisté, une nouvelle passe commence interval après
+// la fin de la précédente
+func StartScheduler(ctx context.Context, scrubber *Scrubber, interval time.Duration) {
+	if interval == 0 {
+		interval = 7 * 24 * time.Hour
+	}
+
+	go func() {
+		for {
+			delay, err := scrubber.untilNextPass(ctx, interval)
+			if err == nil && delay <= 0 {
+				var report *Report
+				report, err = scrubber.Run(ctx)
+				if err == nil {
+					scrubber.cfg.Logger.Info().
+						Bool("resumed", report.Resumed).
+						Int("scanned", report.Scanned).
+						Int64("bytes", report.Bytes).
+						Int("corrupted", report.Corrupted).
+						Int("failures", len(report.Failures)).
+						Msg("Scrub pass completed")
+				}
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				scrubber.cfg.Logger.Error().Err(err).Msg("Scheduled scrub failed")
+				delay = retryDelay
+			}
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/metrics"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/webhooks"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// IngestionService scelle tous les documents entrants (/upload, /api/v1/invoices,
+// /api/v1/uploads, /api/v1/pos-tickets) : idempotence par SHA256, clôtures, preuve JWS,
+// ledger, métriques, audit et webhook document.vaulted
+type IngestionService struct {
+	repo   storage.DocumentRepository
+	ledger ledger.Service // nil : ledger désactivé
+	signer crypto.Signer  // nil : JWS désactivé
+	opts   IngestionOptions
+	log    zerolog.Logger
+}
+
+// NewIngestionService crée le service d'ingestion. Les documents accompagnés d'un fichier
+// exigent un repository qui stocke le contenu (storage.NewDocumentRepository)
+func NewIngestionService(
+	repo storage.DocumentRepository,
+	ledger ledger.Service,
+	signer crypto.Signer,
+	opts IngestionOptions,
+) *IngestionService {
+	log := zerolog.Nop()
+	if opts.Logger != nil {
+		log = *opts.Logger
+	}
+	return &IngestionService{
+		repo:   repo,
+		ledger: ledger,
+		signer: signer,
+		opts:   opts,
+		log:    log,
+	}
+}
+
+// SpoolDir retourne le répertoire des contenus reçus en flux avant leur enregistrement
+// ("" : répertoire temporaire du système)
+func (s *IngestionService) SpoolDir() string {
+	return s.opts.SpoolDir
+}
+
+// PosTickets retourne le service POS adossé à ce service d'ingestion
+func (s *IngestionService) PosTickets() *PosTicketsService {
+	return &PosTicketsService{ingestion: s}
+}
+
+// Ingest enregistre le document avec sa preuve, ou retourne le document existant de même
+// SHA256 (Idempotent). Une période clôturée est signalée par storage.ErrPeriodClosed
+func (s *IngestionService) Ingest(ctx context.Context, input IngestInput) (*IngestResult, error) {
+	startTime := time.Now()
+	doc := input.Document
+	source := "unknown"
+	if doc.Source != nil && *doc.Source != "" {
+		source = *doc.Source
+	}
+	tenant := ""
+	if doc.Tenant != nil {
+		tenant = *doc.Tenant
+	}
+
+	sha256Hex := input.SHA256Hex
+	if input.Content != nil {
+		sha256Hex = input.Content.SHA256Hex()
+		doc.SizeBytes = input.Content.Size()
+	}
+
+	// 1. Idempotence (par sha256, dans le périmètre du tenant)
+	existingDoc, err := s.repo.GetDocumentBySHA256(ctx, tenant, sha256Hex)
+	if err != nil {
+		return nil, s.fail(input, source, tenant, startTime, fmt.Errorf("failed to check existing document: %w", err))
+	}
+	if existingDoc != nil {
+		metrics.RecordDocumentVaulted("idempotent", source)
+		s.audit(input, source, tenant, startTime, existingDoc.ID.String(), audit.EventStatusIdempotent, map[string]interface{}{
+			"sha256_hex": existingDoc.SHA256Hex,
+		})
+		s.log.Info().
+			Str("document_id", existingDoc.ID.String()).
+			Str("sha256", existingDoc.SHA256Hex).
+			Msg("Document already exists (idempotence)")
+		return &IngestResult{Document: existingDoc, Idempotent: true}, nil
+	}
+
+	// 2. Clôture (NF525) : un document daté d'une période clôturée est refusé
+	if guard, ok := s.repo.(storage.PeriodGuard); ok && input.BusinessDate != nil {
+		if err := guard.CheckPeriodOpen(ctx, *input.BusinessDate); err != nil {
+			return nil, s.fail(input, source, tenant, startTime, err)
+		}
+	}
+
+	// 3. Identité du document et preuve JWS
+	now := time.Now()
+	doc.ID = uuid.New()
+	doc.SHA256Hex = sha256Hex
+	doc.CreatedAt = now
+
+	evidenceJWS, err := s.sign(ctx, doc.ID, sha256Hex, now)
+	if err != nil {
+		return nil, s.fail(input, source, tenant, startTime, fmt.Errorf("sign evidence: %w", err))
+	}
+	if evidenceJWS != "" {
+		doc.EvidenceJWS = &evidenceJWS
+	}
+
+	// 4. Insertion (contenu, document, ledger) via le repository
+	insertStartTime := time.Now()
+	if input.Content != nil {
+		contentRepo, ok := s.repo.(storage.ContentRepository)
+		if !ok {
+			err = storage.ErrContentStorageNotConfigured
+		} else {
+			err = contentRepo.InsertContentWithEvidence(ctx, doc, input.Content, evidenceJWS, s.ledger)
+		}
+		metrics.RecordDocumentStorageDuration("store", time.Since(insertStartTime).Seconds())
+	} else {
+		err = s.repo.InsertDocumentWithEvidence(ctx, doc, evidenceJWS, s.ledger)
+	}
+	metrics.RecordTransactionDuration(time.Since(insertStartTime).Seconds())
+	if err != nil {
+		return nil, s.fail(input, source, tenant, startTime, fmt.Errorf("insert document: %w", err))
+	}
+	if doc.LedgerHash != nil {
+		metrics.LedgerEntries.Inc()
+	}
+
+	// 5. Métriques, audit et webhook
+	metrics.RecordDocumentVaulted("success", source)
+	s.audit(input, source, tenant, startTime, doc.ID.String(), audit.EventStatusSuccess, map[string]interface{}{
+		"sha256_hex":   doc.SHA256Hex,
+		"filename":     doc.Filename,
+		"size_bytes":   doc.SizeBytes,
+		"evidence_jws": doc.EvidenceJWS != nil,
+		"ledger_hash":  doc.LedgerHash != nil,
+	})
+	if s.opts.WebhookManager != nil {
+		webhookPayload := map[string]interface{}{
+			"document_id":  doc.ID.String(),
+			"sha256_hex":   doc.SHA256Hex,
+			"filename":     doc.Filename,
+			"size_bytes":   doc.SizeBytes,
+			"created_at":   doc.CreatedAt,
+			"evidence_jws": doc.EvidenceJWS != nil,
+			"ledger_hash":  doc.LedgerHash != nil,
+			"source":       source,
+		}
+		for k, v := range input.Metadata {
+			webhookPayload[k] = v
+		}
+		if err := s.opts.WebhookManager.EmitEvent(ctx, webhooks.EventTypeDocumentVaulted, doc.ID.String(), webhookPayload); err != nil {
+			s.log.Warn().Err(err).Msg("Failed to emit webhook event")
+		}
+	}
+
+	s.log.Info().
+		Str("document_id", doc.ID.String()).
+		Str("sha256", doc.SHA256Hex).
+		Str("source", source).
+		Bool("jws_generated", evidenceJWS != "").
+		Bool("ledger_appended", doc.LedgerHash != nil).
+		Msg("Document vaulted successfully")
+
+	return &IngestResult{Document: doc}, nil
+}
+
+// sign signe la preuve du document ("" si JWS désactivé ou, hors JWS_REQUIRED, en échec)
+func (s *IngestionService) sign(ctx context.Context, docID uuid.UUID, sha256Hex string, timestamp time.Time) (string, error) {
+	if s.signer == nil {
+		return "", nil
+	}
+
+	evidenceBytes, err := json.Marshal(crypto.EvidencePayload{
+		DocumentID: docID.String(),
+		Sha256:     sha256Hex,
+		Timestamp:  timestamp.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal evidence payload: %w", err)
+	}
+
+	signStartTime := time.Now()
+	signature, err := s.signer.SignPayload(ctx, evidenceBytes)
+	metrics.RecordJWSSignatureDuration(time.Since(signStartTime).Seconds())
+	if err != nil {
+		metrics.RecordJWSSignature("error")
+		if s.opts.JWSRequired {
+			return "", err
+		}
+		// Mode dégradé : continuer sans JWS
+		metrics.RecordJWSSignature("degraded")
+		s.log.Warn().Err(err).Msg("JWS generation failed, continuing without evidence")
+		return "", nil
+	}
+	metrics.RecordJWSSignature("success")
+	return signature.JWS, nil
+}
+
+// fail enregistre l'échec d'une ingestion (métrique, audit) et retourne err
+func (s *IngestionService) fail(input IngestInput, source, tenant string, startTime time.Time, err error) error {
+	metrics.RecordDocumentVaulted("error", source)
+	s.audit(input, source, tenant, startTime, "", audit.EventStatusError, map[string]interface{}{
+		"error": err.Error(),
+	})
+	return err
+}
+
+// audit journalise l'événement document_vaulted (si l'audit est configuré)
+func (s *IngestionService) audit(input IngestInput, source, tenant string, startTime time.Time, documentID string, status audit.EventStatus, metadata map[string]interface{}) {
+	if s.opts.AuditLogger == nil {
+		return
+	}
+	for k, v := range input.Metadata {
+		metadata[k] = v
+	}
+	s.opts.AuditLogger.Log(audit.Event{
+		EventType:  audit.EventTypeDocumentVaulted,
+		DocumentID: documentID,
+		RequestID:  input.RequestID,
+		Tenant:     tenant,
+		Source:     source,
+		Status:     status,
+		DurationMS: time.Since(startTime).Milliseconds(),
+		Metadata:   metadata,
+	})
+}
+
+// Seal scelle après coup un document enregistré sans preuve (cmd/backfill) : la preuve JWS
+// manquante est signée et le document inscrit au ledger s'il n'y figure pas
+func (s *IngestionService) Seal(ctx context.Context, doc *models.Document) error {
+	sealer, ok := s.repo.(storage.DocumentSealer)
+	if !ok {
+		return fmt.Errorf("repository does not support sealing")
+	}
+
+	var evidenceJWS string
+	if doc.EvidenceJWS == nil {
+		var err error
+		if evidenceJWS, err = s.sign(ctx, doc.ID, doc.SHA256Hex, time.Now()); err != nil {
+			return fmt.Errorf("sign evidence: %w", err)
+		}
+	}
+
+	hadLedgerHash := doc.LedgerHash != nil
+	if err := sealer.SealDocument(ctx, doc, evidenceJWS, s.ledger); err != nil {
+		return fmt.Errorf("seal document: %w", err)
+	}
+	if !hadLedgerHash && doc.LedgerHash != nil {
+		metrics.LedgerEntries.Inc()
+	}
+
+	s.log.Info().
+		Str("document_id", doc.ID.String()).
+		Str("sha256", doc.SHA256Hex).
+		Bool("evidence_jws", doc.EvidenceJWS != nil).
+		Bool("ledger_hash", doc.LedgerHash != nil).
+		Msg("Document sealed")
+	return nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockContentRepository est un mock pour un repository qui stocke le contenu
+// (storage.ContentRepository, storage.PeriodGuard, storage.DocumentSealer)
+type MockContentRepository struct {
+	MockDocumentRepository
+}
+
+func (m *MockContentRepository) InsertContentWithEvidence(
+	ctx context.Context,
+	doc *models.Document,
+	content storage.Content,
+	evidenceJWS string,
+	ledgerService ledger.Service,
+) error {
+	args := m.Called(ctx, doc, content, evidenceJWS, ledgerService)
+	return args.Error(0)
+}
+
+func (m *MockContentRepository) CheckPeriodOpen(ctx context.Context, date time.Time) error {
+	args := m.Called(ctx, date)
+	return args.Error(0)
+}
+
+func (m *MockContentRepository) SealDocument(ctx context.Context, doc *models.Document, evidenceJWS string, ledgerService ledger.Service) error {
+	args := m.Called(ctx, doc, evidenceJWS, ledgerService)
+	return args.Error(0)
+}
+
+func TestIngestionService_Ingest_Content(t *testing.T) {
+	repo := new(MockContentRepository)
+	ledgerSvc := new(MockLedgerService)
+	signer := new(MockSigner)
+	service := NewIngestionService(repo, ledgerSvc, signer, IngestionOptions{JWSRequired: true})
+
+	ctx := context.Background()
+	content := storage.BytesContent([]byte("%PDF-1.4 facture"))
+	source := "sales"
+	tenant := "acme"
+	doc := &models.Document{Filename: "facture.pdf", ContentType: "application/pdf", Source: &source, Tenant: &tenant}
+
+	repo.On("GetDocumentBySHA256", ctx, tenant, content.SHA256Hex()).Return(nil, nil)
+	signer.On("SignPayload", ctx, mock.AnythingOfType("[]uint8")).Return(&crypto.Signature{JWS: "jws", KID: "kid"}, nil)
+	repo.On("InsertContentWithEvidence", ctx, doc, content, "jws", ledgerSvc).Return(nil)
+
+	result, err := service.Ingest(ctx, IngestInput{Document: doc, Content: content})
+
+	require.NoError(t, err)
+	assert.False(t, result.Idempotent)
+	assert.Same(t, doc, result.Document)
+	assert.NotEqual(t, uuid.Nil, doc.ID)
+	assert.Equal(t, content.SHA256Hex(), doc.SHA256Hex)
+	assert.Equal(t, content.Size(), doc.SizeBytes)
+	require.NotNil(t, doc.EvidenceJWS)
+	assert.Equal(t, "jws", *doc.EvidenceJWS)
+	repo.AssertNotCalled(t, "InsertDocumentWithEvidence")
+	repo.AssertExpectations(t)
+}
+
+func TestIngestionService_Ingest_Idempotence(t *testing.T) {
+	repo := new(MockContentRepository)
+	signer := new(MockSigner)
+	service := NewIngestionService(repo, nil, signer, IngestionOptions{JWSRequired: true})
+
+	ctx := context.Background()
+	content := storage.BytesContent([]byte("déjà scellé"))
+	existingDoc := &models.Document{ID: uuid.New(), SHA256Hex: content.SHA256Hex()}
+	repo.On("GetDocumentBySHA256", ctx, "", content.SHA256Hex()).Return(existingDoc, nil)
+
+	result, err := service.Ingest(ctx, IngestInput{Document: &models.Document{}, Content: content})
+
+	require.NoError(t, err)
+	assert.True(t, result.Idempotent)
+	assert.Equal(t, existingDoc.ID, result.Document.ID)
+	signer.AssertNotCalled(t, "SignPayload")
+	repo.AssertNotCalled(t, "InsertContentWithEvidence")
+}
+
+func TestIngestionService_Ingest_PeriodClosed(t *testing.T) {
+	repo := new(MockContentRepository)
+	signer := new(MockSigner)
+	service := NewIngestionService(repo, nil, signer, IngestionOptions{JWSRequired: true})
+
+	ctx := context.Background()
+	date := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	repo.On("GetDocumentBySHA256", ctx, "", mock.AnythingOfType("string")).Return(nil, nil)
+	repo.On("CheckPeriodOpen", ctx, date).Return(storage.ErrPeriodClosed{Period: "2024-03"})
+
+	_, err := service.Ingest(ctx, IngestInput{
+		Document:     &models.Document{},
+		Content:      storage.BytesContent([]byte("facture de mars")),
+		BusinessDate: &date,
+	})
+
+	var closedErr storage.ErrPeriodClosed
+	require.True(t, errors.As(err, &closedErr))
+	assert.Equal(t, "2024-03", closedErr.Period)
+	signer.AssertNotCalled(t, "SignPayload")
+}
+
+func TestIngestionService_Ingest_JWSDegraded(t *testing.T) {
+	repo := new(MockContentRepository)
+	signer := new(MockSigner)
+	service := NewIngestionService(repo, nil, signer, IngestionOptions{JWSRequired: false})
+
+	ctx := context.Background()
+	repo.On("GetDocumentBySHA256", ctx, "", mock.AnythingOfType("string")).Return(nil, nil)
+	signer.On("SignPayload", ctx, mock.AnythingOfType("[]uint8")).Return(nil, errors.New("signer error"))
+	repo.On("InsertContentWithEvidence", ctx, mock.AnythingOfType("*models.Document"), mock.Anything, "", nil).Return(nil)
+
+	result, err := service.Ingest(ctx, IngestInput{Document: &models.Document{}, Content: storage.BytesContent([]byte("upload"))})
+
+	require.NoError(t, err)
+	assert.Nil(t, result.Document.EvidenceJWS)
+	repo.AssertExpectations(t)
+}
+
+func TestIngestionService_Ingest_ContentWithoutContentRepository(t *testing.T) {
+	repo := new(MockDocumentRepository)
+	service := NewIngestionService(repo, nil, nil, IngestionOptions{})
+
+	ctx := context.Background()
+	repo.On("GetDocumentBySHA256", ctx, "", mock.AnythingOfType("string")).Return(nil, nil)
+
+	_, err := service.Ingest(ctx, IngestInput{Document: &models.Document{}, Content: storage.BytesContent([]byte("upload"))})
+
+	assert.ErrorIs(t, err, storage.ErrContentStorageNotConfigured)
+	repo.AssertNotCalled(t, "InsertDocumentWithEvidence")
+}
+
+func TestIngestionService_Seal(t *testing.T) {
+	repo := new(MockContentRepository)
+	ledgerSvc := new(MockLedgerService)
+	signer := new(MockSigner)
+	service := NewIngestionService(repo, ledgerSvc, signer, IngestionOptions{JWSRequired: true})
+
+	ctx := context.Background()
+	doc := &models.Document{ID: uuid.New(), SHA256Hex: "hash"}
+	signer.On("SignPayload", ctx, mock.AnythingOfType("[]uint8")).Return(&crypto.Signature{JWS: "jws", KID: "kid"}, nil)
+	repo.On("SealDocument", ctx, doc, "jws", ledgerSvc).Return(nil)
+
+	require.NoError(t, service.Seal(ctx, doc))
+	repo.AssertExpectations(t)
+
+	// Preuve JWS déjà présente : seul le ledger est complété
+	sealed := &models.Document{ID: uuid.New(), SHA256Hex: "hash", EvidenceJWS: stringPtr("existing-jws")}
+	repo.On("SealDocument", ctx, sealed, "", ledgerSvc).Return(nil)
+
+	require.NoError(t, service.Seal(ctx, sealed))
+	signer.AssertNumberOfCalls(t, "SignPayload", 1)
+}
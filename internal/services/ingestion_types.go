@@ -0,0 +1,42 @@
+package services
+
+import (
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/webhooks"
+	"github.com/rs/zerolog"
+)
+
+// IngestionOptions configure le service d'ingestion
+type IngestionOptions struct {
+	JWSRequired    bool              // JWS_REQUIRED : un échec de signature refuse le document
+	AuditLogger    *audit.Logger     // Optionnel
+	WebhookManager *webhooks.Manager // Optionnel (document.vaulted)
+	Logger         *zerolog.Logger   // Optionnel
+	SpoolDir       string            // Répertoire privé des contenus reçus en flux (UPLOAD_SESSIONS_DIR)
+}
+
+// IngestInput représente un document à ingérer
+type IngestInput struct {
+	// Document porte les métadonnées ; ID, SHA256Hex, CreatedAt, EvidenceJWS et
+	// LedgerHash sont fixés par le service
+	Document *models.Document
+	// Content est le fichier du document ; nil pour un document sans fichier (payload_json)
+	Content storage.Content
+	// SHA256Hex est l'empreinte d'idempotence d'un document sans fichier
+	SHA256Hex string
+	// BusinessDate est la date métier contrôlée contre les périodes clôturées (optionnelle)
+	BusinessDate *time.Time
+	RequestID    string
+	// Metadata complète l'événement d'audit et le webhook document.vaulted
+	Metadata map[string]interface{}
+}
+
+// IngestResult représente le résultat d'une ingestion
+type IngestResult struct {
+	Document   *models.Document
+	Idempotent bool // document déjà présent (même SHA256 dans le périmètre du tenant)
+}
@@ -23,26 +23,21 @@ type PosTicketsServiceInterface interface {
 
 // PosTicketsService gère l'ingestion des tickets POS
 // Sprint 6 - Phase 3 : Service métier avec interfaces abstraites
+// Le scellement (idempotence, JWS, ledger, audit, webhook) est celui d'IngestionService
 type PosTicketsService struct {
-	repo   storage.DocumentRepository // Interface, pas *storage.DB
-	ledger ledger.Service              // Interface
-	signer crypto.Signer
+	ingestion *IngestionService
 }
 
 // Vérifier que PosTicketsService implémente PosTicketsServiceInterface
 var _ PosTicketsServiceInterface = (*PosTicketsService)(nil)
 
-// NewPosTicketsService crée un nouveau service POS
+// NewPosTicketsService crée un nouveau service POS (JWS requis)
 func NewPosTicketsService(
 	repo storage.DocumentRepository,
 	ledger ledger.Service,
 	signer crypto.Signer,
 ) *PosTicketsService {
-	return &PosTicketsService{
-		repo:   repo,
-		ledger: ledger,
-		signer: signer,
-	}
+	return NewIngestionService(repo, ledger, signer, IngestionOptions{JWSRequired: true}).PosTickets()
 }
 
 // PosTicketResult représente le résultat de l'ingestion d'un ticket POS
@@ -53,52 +48,59 @@ type PosTicketResult struct {
 	LedgerHash  *string
 	EvidenceJWS *string
 	CreatedAt   time.Time
+	Idempotent  bool // ticket déjà ingéré (même hash canonique)
 }
 
-// Ingest ingère un ticket POS avec idempotence métier stricte
-// Hash basé sur ticket + source_id + pos_session (Option A)
-func (s *PosTicketsService) Ingest(ctx context.Context, input PosTicketInput) (*PosTicketResult, error) {
-	// 1. Construire le hash input pour idempotence métier stricte (Option A)
+// PosTicketDate retourne la date métier du ticket (date_order Odoo), nil si absente ou illisible
+func PosTicketDate(ticket map[string]interface{}) *time.Time {
+	value, ok := ticket["date_order"].(string)
+	if !ok || value == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return &date
+		}
+	}
+	return nil
+}
+
+// ComputePosTicketHash calcule le SHA256 d'un ticket POS sur l'entrée canonique
+// {ticket, source_id, pos_session} : utilisé à l'ingestion (idempotence) et à la
+// vérification d'intégrité (recalcul depuis payload_json)
+func ComputePosTicketHash(ticket map[string]interface{}, sourceID string, posSession *string) (string, error) {
 	// Hash basé sur ticket + source_id + pos_session (plus stable)
 	hashInput := map[string]interface{}{
-		"ticket":      input.Ticket,
-		"source_id":   input.SourceID,
-		"pos_session": input.PosSession,
+		"ticket":      ticket,
+		"source_id":   sourceID,
+		"pos_session": posSession,
 	}
 
-	// 2. Marshal et canonicaliser le hash input
+	// Marshal et canonicaliser le hash input
 	hashInputBytes, err := json.Marshal(hashInput)
 	if err != nil {
-		return nil, fmt.Errorf("marshal hash input: %w", err)
+		return "", fmt.Errorf("marshal hash input: %w", err)
 	}
 
 	canonicalBytes, err := utils.CanonicalizeJSON(hashInputBytes)
 	if err != nil {
-		return nil, fmt.Errorf("canonicalize JSON: %w", err)
+		return "", fmt.Errorf("canonicalize JSON: %w", err)
 	}
 
-	// 3. Calculer SHA256 pour idempotence
 	hash := sha256.Sum256(canonicalBytes)
-	sha256Hex := hex.EncodeToString(hash[:])
+	return hex.EncodeToString(hash[:]), nil
+}
 
-	// 4. Vérifier idempotence (par sha256)
-	existingDoc, err := s.repo.GetDocumentBySHA256(ctx, sha256Hex)
+// Ingest ingère un ticket POS avec idempotence métier stricte
+// Hash basé sur ticket + source_id + pos_session (Option A)
+func (s *PosTicketsService) Ingest(ctx context.Context, input PosTicketInput) (*PosTicketResult, error) {
+	// 1-3. Hash canonique ticket + source_id + pos_session (idempotence métier stricte, Option A)
+	sha256Hex, err := ComputePosTicketHash(input.Ticket, input.SourceID, input.PosSession)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing document: %w", err)
-	}
-	if existingDoc != nil {
-		// Document déjà existant (idempotence)
-		return &PosTicketResult{
-			ID:          existingDoc.ID,
-			Tenant:      input.Tenant,
-			SHA256Hex:   existingDoc.SHA256Hex,
-			LedgerHash:  existingDoc.LedgerHash,
-			EvidenceJWS: existingDoc.EvidenceJWS,
-			CreatedAt:   existingDoc.CreatedAt,
-		}, nil
+		return nil, err
 	}
 
-	// 5. Marshal le payload complet pour stockage
+	// 4. Marshal le payload complet pour stockage
 	fullPayload := map[string]interface{}{
 		"tenant":        input.Tenant,
 		"source_system": input.SourceSystem,
@@ -121,22 +123,17 @@ func (s *PosTicketsService) Ingest(ctx context.Context, input PosTicketInput) (*
 		return nil, fmt.Errorf("canonicalize full payload: %w", err)
 	}
 
-	// 6. Créer le document
-	docID := uuid.New()
+	// 5. Créer le document (ID, SHA256 et date fixés par le service d'ingestion)
 	source := "pos"
-	now := time.Now()
-
 	doc := &models.Document{
-		ID:          docID,
-		Filename:    fmt.Sprintf("pos-ticket-%s.json", input.SourceID),
-		ContentType: "application/json",
-		SizeBytes:   int64(len(fullCanonicalBytes)),
-		SHA256Hex:   sha256Hex, // Hash pour idempotence (basé sur ticket + source_id + session)
-		StoredPath:  "",        // Pas de fichier, stockage en DB uniquement
-		CreatedAt:   now,
-		Source:      &source,
-		OdooModel:   &input.SourceModel,
+		Filename:     fmt.Sprintf("pos-ticket-%s.json", input.SourceID),
+		ContentType:  "application/json",
+		SizeBytes:    int64(len(fullCanonicalBytes)),
+		StoredPath:   "", // Pas de fichier, stockage en DB uniquement
+		Source:       &source,
+		OdooModel:    &input.SourceModel,
 		SourceIDText: &input.SourceID, // Stocker l'ID textuel
+		Tenant:       &input.Tenant,
 		// OdooID reste NULL pour les tickets POS (on utilise source_id_text)
 		PayloadJSON: fullCanonicalBytes, // JSON complet canonicalisé pour stockage
 		Currency:    input.Currency,
@@ -147,43 +144,48 @@ func (s *PosTicketsService) Ingest(ctx context.Context, input PosTicketInput) (*
 		Location:    input.Location,
 	}
 
-	// 7. Construire le payload Evidence et signer
-	evidencePayload := crypto.EvidencePayload{
-		DocumentID: docID.String(),
-		Sha256:     sha256Hex,
-		Timestamp:  now.UTC().Format(time.RFC3339),
-	}
-	evidenceBytes, err := json.Marshal(evidencePayload)
-	if err != nil {
-		return nil, fmt.Errorf("marshal evidence payload: %w", err)
-	}
-
-	signature, err := s.signer.SignPayload(ctx, evidenceBytes)
+	// 6. Idempotence (par sha256, dans le périmètre du tenant), clôture, signature et
+	// insertion avec evidence via le repository
+	result, err := s.ingestion.Ingest(ctx, IngestInput{
+		Document:     doc,
+		SHA256Hex:    sha256Hex, // Hash pour idempotence (basé sur ticket + source_id + session)
+		BusinessDate: PosTicketDate(input.Ticket),
+		RequestID:    input.RequestID,
+		Metadata: map[string]interface{}{
+			"source_id": input.SourceID,
+			"model":     input.SourceModel,
+		},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("sign evidence: %w", err)
+		return nil, err
 	}
-	evidenceJWS := signature.JWS
 
-	// 8. Insérer le document avec evidence via le repository
-	// Le repository gère la transaction, l'insertion, l'ajout au ledger et la mise à jour
-	err = s.repo.InsertDocumentWithEvidence(ctx, doc, evidenceJWS, s.ledger)
-	if err != nil {
-		return nil, fmt.Errorf("insert document: %w", err)
+	if result.Idempotent {
+		// Document déjà existant (idempotence)
+		existingDoc := result.Document
+		return &PosTicketResult{
+			ID:          existingDoc.ID,
+			Tenant:      input.Tenant,
+			SHA256Hex:   existingDoc.SHA256Hex,
+			LedgerHash:  existingDoc.LedgerHash,
+			EvidenceJWS: existingDoc.EvidenceJWS,
+			CreatedAt:   existingDoc.CreatedAt,
+			Idempotent:  true,
+		}, nil
 	}
 
-	// 9. Récupérer le ledger_hash depuis le document (mis à jour par le repository)
+	// 7. Récupérer le ledger_hash depuis le document (mis à jour par le repository)
 	ledgerHash := ""
 	if doc.LedgerHash != nil {
 		ledgerHash = *doc.LedgerHash
 	}
 
 	return &PosTicketResult{
-		ID:          docID,
+		ID:          doc.ID,
 		Tenant:      input.Tenant,
-		SHA256Hex:   sha256Hex,
+		SHA256Hex:   doc.SHA256Hex,
 		LedgerHash:  &ledgerHash,
-		EvidenceJWS: &evidenceJWS,
-		CreatedAt:   now,
+		EvidenceJWS: doc.EvidenceJWS,
+		CreatedAt:   doc.CreatedAt,
 	}, nil
 }
-
@@ -21,8 +21,8 @@ type MockDocumentRepository struct {
 	mock.Mock
 }
 
-func (m *MockDocumentRepository) GetDocumentBySHA256(ctx context.Context, sha256 string) (*models.Document, error) {
-	args := m.Called(ctx, sha256)
+func (m *MockDocumentRepository) GetDocumentBySHA256(ctx context.Context, tenant, sha256 string) (*models.Document, error) {
+	args := m.Called(ctx, tenant, sha256)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -44,8 +44,13 @@ type MockLedgerService struct {
 	mock.Mock
 }
 
-func (m *MockLedgerService) Append(ctx context.Context, tx pgx.Tx, docID uuid.UUID, shaHex, jws string) (string, error) {
-	args := m.Called(ctx, tx, docID, shaHex, jws)
+func (m *MockLedgerService) Append(ctx context.Context, tx pgx.Tx, tenant string, docID uuid.UUID, shaHex, jws string) (string, error) {
+	args := m.Called(ctx, tx, tenant, docID, shaHex, jws)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockLedgerService) AppendEvent(ctx context.Context, tx pgx.Tx, tenant string, event ledger.Event) (string, error) {
+	args := m.Called(ctx, tx, tenant, event)
 	return args.String(0), args.Error(1)
 }
 
@@ -100,7 +105,7 @@ func TestPosTicketsService_Ingest_Success(t *testing.T) {
 	ctx := context.Background()
 
 	// Mock : document n'existe pas encore
-	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string")).Return(nil, nil)
+	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil, nil)
 
 	// Mock : signature réussie
 	signature := &crypto.Signature{
@@ -153,7 +158,7 @@ func TestPosTicketsService_Ingest_Idempotence(t *testing.T) {
 	existingDoc.EvidenceJWS = stringPtr("existing-jws")
 	existingDoc.LedgerHash = stringPtr("existing-ledger-hash")
 
-	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string")).Return(existingDoc, nil)
+	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(existingDoc, nil)
 
 	result, err := service.Ingest(ctx, input)
 
@@ -190,7 +195,7 @@ func TestPosTicketsService_Ingest_Idempotence_MetadataChange(t *testing.T) {
 	ctx := context.Background()
 
 	// Mock : document n'existe pas
-	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string")).Return(nil, nil).Once()
+	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil, nil).Once()
 	signature := &crypto.Signature{JWS: "jws-1", KID: "kid-1"}
 	signer.On("SignPayload", ctx, mock.AnythingOfType("[]uint8")).Return(signature, nil).Once()
 	repo.On("InsertDocumentWithEvidence", ctx, mock.AnythingOfType("*models.Document"), "jws-1", ledgerSvc).Return(nil).Once()
@@ -212,7 +217,7 @@ func TestPosTicketsService_Ingest_Idempotence_MetadataChange(t *testing.T) {
 	existingDoc.EvidenceJWS = result1.EvidenceJWS
 	existingDoc.LedgerHash = result1.LedgerHash
 
-	repo.On("GetDocumentBySHA256", ctx, hash1).Return(existingDoc, nil).Once()
+	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string"), hash1).Return(existingDoc, nil).Once()
 
 	result2, err2 := service.Ingest(ctx, input2)
 	require.NoError(t, err2)
@@ -241,7 +246,7 @@ func TestPosTicketsService_Ingest_LedgerError(t *testing.T) {
 
 	ctx := context.Background()
 
-	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string")).Return(nil, nil)
+	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil, nil)
 	signature := &crypto.Signature{JWS: "test-jws", KID: "test-kid"}
 	signer.On("SignPayload", ctx, mock.AnythingOfType("[]uint8")).Return(signature, nil)
 
@@ -273,7 +278,7 @@ func TestPosTicketsService_Ingest_SignerError(t *testing.T) {
 
 	ctx := context.Background()
 
-	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string")).Return(nil, nil)
+	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil, nil)
 
 	// Mock : erreur lors de la signature
 	signer.On("SignPayload", ctx, mock.AnythingOfType("[]uint8")).
@@ -304,7 +309,7 @@ func TestPosTicketsService_Ingest_RepositoryError(t *testing.T) {
 	ctx := context.Background()
 
 	// Mock : erreur lors de la vérification d'existence
-	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string")).
+	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("string")).
 		Return(nil, errors.New("repository error"))
 
 	result, err := service.Ingest(ctx, input)
@@ -348,7 +353,7 @@ func TestPosTicketsService_Canonicalization(t *testing.T) {
 	ctx := context.Background()
 
 	// Premier appel
-	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string")).Return(nil, nil).Once()
+	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil, nil).Once()
 	signature := &crypto.Signature{JWS: "jws", KID: "kid"}
 	signer.On("SignPayload", ctx, mock.AnythingOfType("[]uint8")).Return(signature, nil).Once()
 	repo.On("InsertDocumentWithEvidence", ctx, mock.AnythingOfType("*models.Document"), "jws", ledgerSvc).Return(nil).Once()
@@ -366,7 +371,7 @@ func TestPosTicketsService_Canonicalization(t *testing.T) {
 	existingDoc.EvidenceJWS = result1.EvidenceJWS
 	existingDoc.LedgerHash = result1.LedgerHash
 
-	repo.On("GetDocumentBySHA256", ctx, hash1).Return(existingDoc, nil).Once()
+	repo.On("GetDocumentBySHA256", ctx, mock.AnythingOfType("string"), hash1).Return(existingDoc, nil).Once()
 
 	result2, err2 := service.Ingest(ctx, input2)
 	require.NoError(t, err2)
@@ -15,5 +15,6 @@ type PosTicketInput struct {
 	Cashier      *string                // Optionnel
 	Location     *string                // Optionnel
 	Ticket       map[string]interface{} // Obligatoire (JSON brut du ticket)
+	RequestID    string                 // Optionnel (X-Request-ID, audit)
 }
 
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrAPIKeyNotFound est retourné quand la clé API demandée n'existe pas
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// Vérification de l'implémentation de l'interface auth.APIKeyStore
+var _ auth.APIKeyStore = (*DB)(nil)
+
+const apiKeyColumns = `key_id, key_hash, COALESCE(name, ''), user_id, role, COALESCE(tenant, ''),
+	created_at, expires_at, is_active, last_used_at, revoked_at`
+
+// migrateAPIKeys crée la table des clés API
+func (db *DB) migrateAPIKeys(ctx context.Context) error {
+	migrationSQL := `
+		CREATE TABLE IF NOT EXISTS api_keys (
+			key_id       TEXT PRIMARY KEY,
+			key_hash     TEXT NOT NULL UNIQUE,
+			name         TEXT,
+			user_id      TEXT NOT NULL,
+			role         TEXT NOT NULL,
+			tenant       TEXT,
+			created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+			expires_at   TIMESTAMPTZ,
+			is_active    BOOLEAN NOT NULL DEFAULT true,
+			last_used_at TIMESTAMPTZ,
+			revoked_at   TIMESTAMPTZ,
+			rotated_from TEXT REFERENCES api_keys(key_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_api_keys_tenant ON api_keys(tenant);
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply api_keys migration: %w", err)
+	}
+
+	db.log.Debug().Msg("API keys migration applied successfully")
+	return nil
+}
+
+// CreateAPIKey enregistre une nouvelle clé API (seul le hash est stocké)
+func (db *DB) CreateAPIKey(ctx context.Context, key *auth.APIKey) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO api_keys (key_id, key_hash, name, user_id, role, tenant, created_at, expires_at, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, key.KeyID, key.KeyHash, nullableString(key.Name), key.UserID, key.Role, nullableString(key.Tenant),
+		key.CreatedAt, key.ExpiresAt, key.IsActive)
+	if err != nil {
+		return fmt.Errorf("failed to insert api key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash retourne la clé correspondant au hash, nil si inconnue
+func (db *DB) GetAPIKeyByHash(ctx context.Context, keyHash string) (*auth.APIKey, error) {
+	row := db.Pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, keyHash)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+// GetAPIKey retourne une clé par son identifiant
+func (db *DB) GetAPIKey(ctx context.Context, keyID string) (*auth.APIKey, error) {
+	row := db.Pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_id = $1`, keyID)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+// TouchAPIKey met à jour la date de dernière utilisation
+func (db *DB) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error {
+	if _, err := db.Pool.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE key_id = $1`, keyID, usedAt); err != nil {
+		return fmt.Errorf("failed to update api key last usage: %w", err)
+	}
+	return nil
+}
+
+// ListAPIKeys liste les clés API (tenant vide = toutes les clés)
+func (db *DB) ListAPIKeys(ctx context.Context, tenant string) ([]*auth.APIKey, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys
+		WHERE ($1::text = '' OR tenant = $1)
+		ORDER BY created_at DESC
+	`, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*auth.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey désactive une clé API
+func (db *DB) RevokeAPIKey(ctx context.Context, keyID string) error {
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE api_keys SET is_active = false, revoked_at = COALESCE(revoked_at, now())
+		WHERE key_id = $1
+	`, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// RotateAPIKey remplace une clé active par une nouvelle clé aux mêmes attributs
+// (utilisateur, rôle, tenant, expiration) et révoque l'ancienne dans la même transaction
+func (db *DB) RotateAPIKey(ctx context.Context, oldKeyID string, newKey *auth.APIKey) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	row := tx.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_id = $1 FOR UPDATE`, oldKeyID)
+	old, err := scanAPIKey(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get api key: %w", err)
+	}
+	if !old.IsActive {
+		return fmt.Errorf("api key %s is not active", oldKeyID)
+	}
+
+	newKey.Name = old.Name
+	newKey.UserID = old.UserID
+	newKey.Role = old.Role
+	newKey.Tenant = old.Tenant
+	newKey.ExpiresAt = old.ExpiresAt
+	newKey.IsActive = true
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO api_keys (key_id, key_hash, name, user_id, role, tenant, created_at, expires_at, is_active, rotated_from)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, true, $9)
+	`, newKey.KeyID, newKey.KeyHash, nullableString(newKey.Name), newKey.UserID, newKey.Role,
+		nullableString(newKey.Tenant), newKey.CreatedAt, newKey.ExpiresAt, oldKeyID); err != nil {
+		return fmt.Errorf("failed to insert rotated api key: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE api_keys SET is_active = false, revoked_at = now() WHERE key_id = $1
+	`, oldKeyID); err != nil {
+		return fmt.Errorf("failed to revoke previous api key: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func scanAPIKey(row pgx.Row) (*auth.APIKey, error) {
+	var key auth.APIKey
+	if err := row.Scan(&key.KeyID, &key.KeyHash, &key.Name, &key.UserID, &key.Role, &key.Tenant,
+		&key.CreatedAt, &key.ExpiresAt, &key.IsActive, &key.LastUsedAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
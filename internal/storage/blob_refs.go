@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// migrateBlobRefs crée le comptage des références aux contenus partagés (disposition par contenu)
+func (db *DB) migrateBlobRefs(ctx context.Context) error {
+	migrationSQL := `
+		CREATE TABLE IF NOT EXISTS blob_refs (
+			ref        TEXT PRIMARY KEY,
+			sha256_hex TEXT NOT NULL,
+			size_bytes BIGINT NOT NULL,
+			ref_count  INTEGER NOT NULL CHECK (ref_count >= 0),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		-- Recherche des documents référençant un contenu (partage, orphelins, migrations)
+		CREATE INDEX IF NOT EXISTS idx_documents_stored_path ON documents(stored_path);
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply blob_refs migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Blob refs migration applied successfully")
+	return nil
+}
+
+// SetStorageLayout définit la disposition des clés des nouveaux contenus (STORAGE_LAYOUT)
+func (db *DB) SetStorageLayout(layout string) error {
+	switch layout {
+	case "", blobstore.LayoutDate:
+		db.layout = blobstore.LayoutDate
+	case blobstore.LayoutContent:
+		db.layout = blobstore.LayoutContent
+	default:
+		return fmt.Errorf("invalid STORAGE_LAYOUT %q (expected %s or %s)", layout, blobstore.LayoutDate, blobstore.LayoutContent)
+	}
+	return nil
+}
+
+// PutDocumentContent écrit le contenu d'un nouveau document, chiffré au fil de l'écriture
+// si le chiffrement est activé. Disposition par contenu : la clé est dérivée du SHA256 et
+// un contenu déjà stocké n'est pas réécrit ; disposition par date :
+// YYYY/MM/DD/<uuid>-<filename>. Un contenu chiffré n'est jamais partagé : chaque document
+// a sa clé de données, et la purge d'un document détruit son contenu quel que soit le
+// nombre de documents de même SHA256
+func (db *DB) PutDocumentContent(ctx context.Context, blobs blobstore.Store, docID uuid.UUID, now time.Time, filename, contentType string, content Content) (*StoredContent, error) {
+	sha256Hex := content.SHA256Hex()
+	opts := blobstore.PutOptions{ContentType: contentType, SHA256Hex: sha256Hex}
+	if db.layout != blobstore.LayoutContent || db.envelope != nil {
+		stored, err := db.sealContent(ctx)
+		if err != nil {
+			return nil, err
+		}
+		key := blobstore.DateKey(now, docID, filename)
+		if db.layout == blobstore.LayoutContent {
+			key = blobstore.DocumentContentKey(sha256Hex, docID)
+		}
+		if stored.Ref, err = putContent(ctx, blobs, key, content, stored.dek, opts); err != nil {
+			return nil, err
+		}
+		return stored, nil
+	}
+
+	key := blobstore.ContentKey(sha256Hex)
+	stored := &StoredContent{Ref: blobs.Ref(key)}
+
+	// Verrou du comptage : un enregistrement concurrent du même contenu attend la fin de
+	// l'écriture et reprend sa clé de données
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO blob_refs (ref, sha256_hex, size_bytes, ref_count)
+		VALUES ($1, $2, $3, 0)
+		ON CONFLICT (ref) DO UPDATE SET ref_count = blob_refs.ref_count
+		RETURNING dek_wrapped, kek_version
+	`, stored.Ref, sha256Hex, content.Size()).Scan(&stored.WrappedKey, &stored.KEKVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock content: %w", err)
+	}
+
+	if _, err := blobs.Stat(ctx, stored.Ref); err == nil {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return stored, nil
+	} else if !errors.Is(err, blobstore.ErrNotFound) {
+		return nil, fmt.Errorf("failed to check stored content: %w", err)
+	}
+
+	sealed, err := db.sealContent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Ref = stored.Ref
+	if _, err := putContent(ctx, blobs, key, content, sealed.dek, opts); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE blob_refs SET dek_wrapped = $2, kek_version = $3 WHERE ref = $1`, sealed.Ref, sealed.WrappedKey, sealed.KEKVersion); err != nil {
+		return nil, fmt.Errorf("failed to store content key: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return sealed, nil
+}
+
+// RetainDocumentContent compte, dans la transaction du document et avant son insertion,
+// une référence de plus au contenu s'il est partagé (disposition par contenu) ; stored
+// reçoit la clé de données du contenu partagé. Au premier référencement, le contenu est
+// réécrit s'il a été supprimé comme orphelin entre son écriture et la prise du verrou
+func (db *DB) RetainDocumentContent(ctx context.Context, tx pgx.Tx, blobs blobstore.Store, stored *StoredContent, content Content) error {
+	sha256Hex := content.SHA256Hex()
+	if !blobstore.IsContentRef(stored.Ref, sha256Hex) {
+		return nil
+	}
+
+	var refCount int
+	var wrapped, kekVersion *string
+	err := tx.QueryRow(ctx, `
+		INSERT INTO blob_refs (ref, sha256_hex, size_bytes, ref_count, dek_wrapped, kek_version)
+		VALUES ($1, $2, $3, 1, $4, $5)
+		ON CONFLICT (ref) DO UPDATE SET ref_count = blob_refs.ref_count + 1
+		RETURNING ref_count, dek_wrapped, kek_version
+	`, stored.Ref, sha256Hex, content.Size(), stored.WrappedKey, stored.KEKVersion).Scan(&refCount, &wrapped, &kekVersion)
+	if err != nil {
+		return fmt.Errorf("failed to retain content: %w", err)
+	}
+	if derefString(wrapped) != derefString(stored.WrappedKey) {
+		// Contenu réécrit par un enregistrement concurrent avec sa propre clé
+		stored.WrappedKey, stored.KEKVersion, stored.dek = wrapped, kekVersion, nil
+	}
+	if refCount > 1 {
+		return nil
+	}
+
+	if _, err := blobs.Stat(ctx, stored.Ref); err == nil {
+		return nil
+	} else if !errors.Is(err, blobstore.ErrNotFound) {
+		return fmt.Errorf("failed to check stored content: %w", err)
+	}
+	dek := stored.dek
+	if dek == nil {
+		if dek, err = db.dataKey(ctx, stored.WrappedKey, stored.KEKVersion); err != nil {
+			return err
+		}
+	}
+	if _, err := putContent(ctx, blobs, blobstore.ContentKey(sha256Hex), content, dek, blobstore.PutOptions{SHA256Hex: sha256Hex}); err != nil {
+		return fmt.Errorf("failed to rewrite content: %w", err)
+	}
+	return nil
+}
+
+// DiscardDocumentContent supprime le contenu d'un document dont l'enregistrement a échoué.
+// Un contenu partagé est conservé (un enregistrement concurrent peut le référencer) ;
+// resté sans référence, il est supprimé par cmd/reconcile
+func (db *DB) DiscardDocumentContent(ctx context.Context, blobs blobstore.Store, ref, sha256Hex string) {
+	if blobstore.IsContentRef(ref, sha256Hex) {
+		return
+	}
+	if err := blobs.Delete(ctx, ref); err != nil {
+		db.log.Warn().Err(err).Str("stored_path", ref).Msg("Failed to delete content of failed document")
+	}
+}
+
+// DeleteUnreferencedBlob supprime un contenu partagé qu'aucun document ne référence.
+// La suppression a lieu sous le verrou de son comptage : un enregistrement concurrent
+// attend sa fin puis réécrit le contenu. Retourne false si le contenu est référencé
+func (db *DB) DeleteUnreferencedBlob(ctx context.Context, blobs blobstore.Store, ref, sha256Hex string) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var refCount int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO blob_refs (ref, sha256_hex, size_bytes, ref_count)
+		VALUES ($1, $2, 0, 0)
+		ON CONFLICT (ref) DO UPDATE SET ref_count = blob_refs.ref_count
+		RETURNING ref_count
+	`, ref, sha256Hex).Scan(&refCount)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock content: %w", err)
+	}
+
+	var referenced bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM documents WHERE stored_path = $1)`, ref).Scan(&referenced); err != nil {
+		return false, fmt.Errorf("failed to check content references: %w", err)
+	}
+	if refCount > 0 || referenced {
+		return false, nil
+	}
+
+	if err := blobs.Delete(ctx, ref); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM blob_refs WHERE ref = $1`, ref); err != nil {
+		return false, fmt.Errorf("failed to delete content refs: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// switchBlobRef fait pointer les documents de oldRef vers newRef, chiffré par la clé de
+// données key, et reporte leur comptage si newRef est un contenu partagé ; retourne le
+// nombre de documents mis à jour
+func (db *DB) switchBlobRef(ctx context.Context, oldRef, newRef, sha256Hex string, size int64, key contentKey) (int64, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Verrou du comptage source : pas d'enregistrement concurrent sur l'ancienne référence
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM blob_refs WHERE ref = $1 FOR UPDATE`, oldRef); err != nil {
+		return 0, fmt.Errorf("failed to lock content: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE documents SET stored_path = $1, dek_wrapped = $3, kek_version = $4
+		WHERE stored_path = $2
+	`, newRef, oldRef, key.wrapped, key.kekVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update stored_path: %w", err)
+	}
+	moved := tag.RowsAffected()
+	if moved == 0 {
+		return 0, nil
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM blob_refs WHERE ref = $1`, oldRef); err != nil {
+		return 0, fmt.Errorf("failed to delete content refs: %w", err)
+	}
+	if blobstore.IsContentRef(newRef, sha256Hex) {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO blob_refs (ref, sha256_hex, size_bytes, ref_count, dek_wrapped, kek_version)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (ref) DO UPDATE SET ref_count = blob_refs.ref_count + EXCLUDED.ref_count
+		`, newRef, sha256Hex, size, moved, key.wrapped, key.kekVersion)
+		if err != nil {
+			return 0, fmt.Errorf("failed to retain content: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return moved, nil
+}
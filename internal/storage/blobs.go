@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+)
+
+// SetBlobStore définit le stockage du contenu des documents (STORAGE_BACKEND)
+func (db *DB) SetBlobStore(store blobstore.Store) {
+	if store != nil {
+		db.blobs = store
+	}
+}
+
+// BlobStore retourne le stockage du contenu des documents. Sans SetBlobStore, seuls les
+// chemins absolus historiques sont lisibles
+func (db *DB) BlobStore() blobstore.Store {
+	if db.blobs != nil {
+		return db.blobs
+	}
+	return blobstore.NewFileStore("")
+}
+
+// BlobStoreFor retourne le stockage configuré ou, à défaut, un stockage fichier enraciné
+// dans storageDir (chemins d'écriture recevant STORAGE_DIR)
+func (db *DB) BlobStoreFor(storageDir string) blobstore.Store {
+	if db.blobs != nil {
+		return db.blobs
+	}
+	return blobstore.NewFileStore(storageDir)
+}
+
+// OpenDocumentContent ouvre le contenu stocké d'un document (stored_path), déchiffré s'il
+// est chiffré ; blobstore.ErrNotFound si l'objet n'existe plus
+func (db *DB) OpenDocumentContent(ctx context.Context, doc *models.Document) (io.ReadCloser, error) {
+	if doc.StoredPath == "" {
+		return nil, fmt.Errorf("document %s has no stored content", doc.ID)
+	}
+	if err := db.loadDocumentKey(ctx, doc); err != nil {
+		return nil, err
+	}
+	return db.openContent(ctx, db.BlobStore(), doc.StoredPath, doc.WrappedKey, doc.KEKVersion)
+}
+
+// ReadDocumentContent lit entièrement le contenu stocké d'un document
+func (db *DB) ReadDocumentContent(ctx context.Context, doc *models.Document) ([]byte, error) {
+	r, err := db.OpenDocumentContent(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document content: %w", err)
+	}
+	return content, nil
+}
+
+// BlobMigrationOptions configure la migration du contenu entre deux backends ou dispositions
+type BlobMigrationOptions struct {
+	DryRun       bool // vérifie les contenus source sans rien copier
+	DeleteSource bool // supprime l'objet source une fois la copie vérifiée et la référence mise à jour
+	Limit        int  // nombre maximal de contenus traités (0 : tous)
+}
+
+// BlobMigrationFailure décrit un document non migré
+type BlobMigrationFailure struct {
+	DocumentID string `json:"document_id"`
+	StoredPath string `json:"stored_path"`
+	Error      string `json:"error"`
+}
+
+// BlobMigrationReport est le rapport d'une migration de backend ou de disposition
+type BlobMigrationReport struct {
+	Timestamp  time.Time              `json:"timestamp"`
+	From       string                 `json:"from"`
+	To         string                 `json:"to"`
+	DryRun     bool                   `json:"dry_run"`
+	Candidates int                    `json:"candidates"` // documents concernés
+	Verified   int                    `json:"verified"`   // documents dont le contenu a été vérifié
+	Migrated   int                    `json:"migrated"`   // documents dont la référence a été mise à jour
+	Shared     int                    `json:"shared"`     // documents rattachés à un contenu déjà présent dans la destination
+	Deleted    int                    `json:"deleted"`    // objets source supprimés
+	Failures   []BlobMigrationFailure `json:"failures"`
+}
+
+// blobGroup réunit les documents qui référencent un même contenu
+type blobGroup struct {
+	ref  string
+	docs []models.Document
+}
+
+// MigrateBlobs déplace le contenu des documents stockés dans from vers to en conservant
+// sa disposition. Chaque copie est relue et son SHA256 comparé à sha256_hex avant la mise
+// à jour de stored_path ; un contenu source altéré n'est pas copié
+func (db *DB) MigrateBlobs(ctx context.Context, from, to blobstore.Store, opts BlobMigrationOptions) (*BlobMigrationReport, error) {
+	groups, err := db.loadBlobGroups(ctx, func(doc models.Document) bool {
+		return from.Owns(doc.StoredPath) && !to.Owns(doc.StoredPath)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db.migrateBlobGroups(ctx, groups, from, to, from.Name(), to.Name(), func(g blobGroup) string {
+		doc := g.docs[0]
+		if blobstore.IsContentRef(g.ref, doc.SHA256Hex) {
+			return blobstore.ContentKey(doc.SHA256Hex)
+		}
+		if blobstore.IsDocumentContentRef(g.ref, doc.SHA256Hex, doc.ID) {
+			return blobstore.DocumentContentKey(doc.SHA256Hex, doc.ID)
+		}
+		return blobstore.DateKey(doc.CreatedAt, doc.ID, doc.Filename)
+	}, opts)
+}
+
+// MigrateToContentLayout réécrit le contenu des documents de la disposition par date
+// (ou des chemins absolus historiques) vers la disposition par contenu, dans le backend
+// principal. Les documents de même contenu en clair partagent ensuite un seul objet ; un
+// contenu chiffré garde son objet et sa clé de données
+func (db *DB) MigrateToContentLayout(ctx context.Context, opts BlobMigrationOptions) (*BlobMigrationReport, error) {
+	blobs := db.BlobStore()
+	groups, err := db.loadBlobGroups(ctx, func(doc models.Document) bool {
+		return !blobstore.IsContentRef(doc.StoredPath, doc.SHA256Hex) &&
+			!blobstore.IsDocumentContentRef(doc.StoredPath, doc.SHA256Hex, doc.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db.migrateBlobGroups(ctx, groups, blobs, blobs, blobstore.LayoutDate, blobstore.LayoutContent, func(g blobGroup) string {
+		doc := g.docs[0]
+		if doc.WrappedKey != nil {
+			return blobstore.DocumentContentKey(doc.SHA256Hex, doc.ID)
+		}
+		return blobstore.ContentKey(doc.SHA256Hex)
+	}, opts)
+}
+
+// loadBlobGroups charge les documents retenus par keep, regroupés par référence
+func (db *DB) loadBlobGroups(ctx context.Context, keep func(models.Document) bool) ([]blobGroup, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT id, filename, COALESCE(content_type, ''), COALESCE(size_bytes, 0), sha256_hex, stored_path, created_at,
+		       dek_wrapped, kek_version
+		FROM documents
+		WHERE stored_path IS NOT NULL AND stored_path != ''
+		ORDER BY created_at ASC, id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []blobGroup
+	index := map[string]int{}
+	for rows.Next() {
+		var doc models.Document
+		if err := rows.Scan(&doc.ID, &doc.Filename, &doc.ContentType, &doc.SizeBytes, &doc.SHA256Hex, &doc.StoredPath, &doc.CreatedAt,
+			&doc.WrappedKey, &doc.KEKVersion); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		if !keep(doc) {
+			continue
+		}
+		if i, ok := index[doc.StoredPath]; ok {
+			groups[i].docs = append(groups[i].docs, doc)
+			continue
+		}
+		index[doc.StoredPath] = len(groups)
+		groups = append(groups, blobGroup{ref: doc.StoredPath, docs: []models.Document{doc}})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+	return groups, nil
+}
+
+func (db *DB) migrateBlobGroups(ctx context.Context, groups []blobGroup, from, to blobstore.Store, fromName, toName string, keyFor func(blobGroup) string, opts BlobMigrationOptions) (*BlobMigrationReport, error) {
+	report := &BlobMigrationReport{
+		Timestamp: time.Now().UTC(),
+		From:      fromName,
+		To:        toName,
+		DryRun:    opts.DryRun,
+		Failures:  []BlobMigrationFailure{},
+	}
+	if opts.Limit > 0 && len(groups) > opts.Limit {
+		groups = groups[:opts.Limit]
+	}
+	for _, g := range groups {
+		report.Candidates += len(g.docs)
+	}
+
+	for _, g := range groups {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		deleted, err := db.migrateBlob(ctx, g, from, to, keyFor(g), opts, report)
+		if err != nil {
+			for _, doc := range g.docs {
+				report.Failures = append(report.Failures, BlobMigrationFailure{DocumentID: doc.ID.String(), StoredPath: g.ref, Error: err.Error()})
+			}
+			db.log.Error().Err(err).Str("stored_path", g.ref).Int("documents", len(g.docs)).Msg("Failed to migrate document content")
+			continue
+		}
+		if deleted {
+			report.Deleted++
+		}
+	}
+	return report, nil
+}
+
+// migrateBlob copie et vérifie le contenu partagé par les documents du groupe ; retourne
+// true si la source a été supprimée
+func (db *DB) migrateBlob(ctx context.Context, g blobGroup, from, to blobstore.Store, key string, opts BlobMigrationOptions, report *BlobMigrationReport) (bool, error) {
+	doc := g.docs[0]
+	contentKey := documentContentKey(doc)
+	for _, other := range g.docs[1:] {
+		if other.SHA256Hex != doc.SHA256Hex {
+			return false, fmt.Errorf("documents sharing this content have different SHA256 (%s, %s)", doc.SHA256Hex, other.SHA256Hex)
+		}
+		if documentContentKey(other) != contentKey {
+			return false, fmt.Errorf("documents sharing this content have different data keys")
+		}
+	}
+
+	if opts.DryRun {
+		if err := db.verifyBlob(ctx, from, g.ref, doc.SHA256Hex, contentKey); err != nil {
+			return false, err
+		}
+		report.Verified += len(g.docs)
+		return false, nil
+	}
+
+	// Un contenu partagé déjà présent dans la destination n'est pas recopié, ni supprimé en
+	// cas d'échec (d'autres documents peuvent le référencer)
+	ref := to.Ref(key)
+	shared := blobstore.IsContentRef(ref, doc.SHA256Hex)
+	reused := false
+	if shared {
+		if _, err := to.Stat(ctx, ref); err == nil {
+			if contentKey, err = db.blobRefKey(ctx, ref); err != nil {
+				return false, err
+			}
+			if err := db.verifyBlob(ctx, to, ref, doc.SHA256Hex, contentKey); err != nil {
+				return false, err
+			}
+			reused = true
+		} else if !errors.Is(err, blobstore.ErrNotFound) {
+			return false, fmt.Errorf("failed to check destination: %w", err)
+		}
+	}
+	discard := func() {
+		if !shared {
+			to.Delete(ctx, ref)
+		}
+	}
+
+	if !reused {
+		// Un contenu chiffré est copié tel quel : sa clé de données ne change pas
+		src, err := from.Open(ctx, g.ref)
+		if err != nil {
+			return false, fmt.Errorf("failed to open source: %w", err)
+		}
+		hash := sha256.New()
+		size := doc.SizeBytes
+		if contentKey.wrapped != nil {
+			size = crypto.EncryptedSize(doc.SizeBytes)
+		}
+		ref, err = to.Put(ctx, key, io.TeeReader(src, hash), size, blobstore.PutOptions{ContentType: doc.ContentType, SHA256Hex: doc.SHA256Hex})
+		src.Close()
+		if err != nil {
+			return false, fmt.Errorf("failed to write destination: %w", err)
+		}
+		if actual := hex.EncodeToString(hash.Sum(nil)); contentKey.wrapped == nil && actual != doc.SHA256Hex {
+			discard()
+			return false, fmt.Errorf("source does not match its SHA256 (expected %s, got %s)", doc.SHA256Hex, actual)
+		}
+
+		// Relecture de la copie avant de basculer la référence
+		if err := db.verifyBlob(ctx, to, ref, doc.SHA256Hex, contentKey); err != nil {
+			discard()
+			return false, err
+		}
+	}
+	report.Verified += len(g.docs)
+
+	moved, err := db.switchBlobRef(ctx, g.ref, ref, doc.SHA256Hex, doc.SizeBytes, contentKey)
+	if err != nil {
+		discard()
+		return false, err
+	}
+	if moved == 0 {
+		discard()
+		return false, fmt.Errorf("document changed during migration")
+	}
+	report.Migrated += int(moved)
+	if reused {
+		report.Shared += int(moved)
+	}
+	db.log.Info().Str("from", g.ref).Str("to", ref).Int64("documents", moved).Msg("Document content migrated")
+
+	if !opts.DeleteSource || ref == g.ref {
+		return false, nil
+	}
+	if blobstore.IsContentRef(g.ref, doc.SHA256Hex) {
+		deleted, err := db.DeleteUnreferencedBlob(ctx, from, g.ref, doc.SHA256Hex)
+		if err != nil {
+			db.log.Warn().Err(err).Str("stored_path", g.ref).Msg("Failed to delete migrated source content")
+		}
+		return deleted, nil
+	}
+	if err := from.Delete(ctx, g.ref); err != nil {
+		db.log.Warn().Err(err).Str("stored_path", g.ref).Msg("Failed to delete migrated source content")
+		return false, nil
+	}
+	return true, nil
+}
+
+// verifyBlob relit un objet, déchiffré par key s'il est chiffré, et compare son SHA256
+func (db *DB) verifyBlob(ctx context.Context, store blobstore.Store, ref, expected string, key contentKey) error {
+	r, err := db.openContent(ctx, store, ref, key.wrapped, key.kekVersion)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", ref, err)
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return fmt.Errorf("failed to read %s: %w", ref, err)
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return fmt.Errorf("%s does not match SHA256 (expected %s, got %s)", ref, expected, actual)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrClosingExists est retourné quand la période est déjà clôturée
+var ErrClosingExists = errors.New("period already closed")
+
+// ErrPeriodClosed est retourné quand un document est daté d'une période clôturée
+type ErrPeriodClosed struct {
+	Period string
+}
+
+func (e ErrPeriodClosed) Error() string {
+	return fmt.Sprintf("accounting period %s is closed", e.Period)
+}
+
+// PeriodGuard refuse les documents datés d'une période clôturée
+// (implémentée par DB et PostgresRepository, optionnelle pour les autres repositories)
+type PeriodGuard interface {
+	CheckPeriodOpen(ctx context.Context, date time.Time) error
+}
+
+// Vérification de l'implémentation de l'interface PeriodGuard
+var (
+	_ PeriodGuard = (*DB)(nil)
+	_ PeriodGuard = (*PostgresRepository)(nil)
+)
+
+const closingColumns = `period, period_start, period_end, document_count, ledger_entry_count,
+	merkle_root, last_ledger_hash, archive_path, archive_sha256, manifest_jws, closed_at, closed_by`
+
+// migrateClosings crée la table des clôtures mensuelles
+func (db *DB) migrateClosings(ctx context.Context) error {
+	migrationSQL := `
+		CREATE TABLE IF NOT EXISTS closings (
+			period             TEXT PRIMARY KEY,
+			period_start       TIMESTAMPTZ NOT NULL,
+			period_end         TIMESTAMPTZ NOT NULL,
+			document_count     BIGINT NOT NULL,
+			ledger_entry_count BIGINT NOT NULL,
+			merkle_root        TEXT NOT NULL,
+			last_ledger_hash   TEXT,
+			archive_path       TEXT NOT NULL,
+			archive_sha256     TEXT NOT NULL,
+			manifest_jws       TEXT NOT NULL,
+			closed_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+			closed_by          TEXT NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_closings_range ON closings(period_start, period_end);
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply closings migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Closings migration applied successfully")
+	return nil
+}
+
+// InsertClosing enregistre une clôture ; ErrClosingExists si la période est déjà clôturée
+func (db *DB) InsertClosing(ctx context.Context, closing *models.Closing) error {
+	tag, err := db.Pool.Exec(ctx, `
+		INSERT INTO closings (`+closingColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (period) DO NOTHING
+	`, closing.Period, closing.PeriodStart, closing.PeriodEnd, closing.DocumentCount, closing.LedgerEntryCount,
+		closing.MerkleRoot, closing.LastLedgerHash, closing.ArchivePath, closing.ArchiveSHA256, closing.ManifestJWS,
+		closing.ClosedAt, closing.ClosedBy)
+	if err != nil {
+		return fmt.Errorf("failed to insert closing: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrClosingExists
+	}
+	return nil
+}
+
+// GetClosing retourne la clôture d'une période (YYYY-MM), nil si la période est ouverte
+func (db *DB) GetClosing(ctx context.Context, period string) (*models.Closing, error) {
+	row := db.Pool.QueryRow(ctx, `SELECT `+closingColumns+` FROM closings WHERE period = $1`, period)
+	closing, err := scanClosing(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get closing: %w", err)
+	}
+	return closing, nil
+}
+
+// ListClosings retourne les clôtures, de la plus récente à la plus ancienne
+func (db *DB) ListClosings(ctx context.Context) ([]*models.Closing, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+closingColumns+` FROM closings ORDER BY period_start DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list closings: %w", err)
+	}
+	defer rows.Close()
+
+	closings := []*models.Closing{}
+	for rows.Next() {
+		closing, err := scanClosing(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan closing: %w", err)
+		}
+		closings = append(closings, closing)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating closings: %w", err)
+	}
+	return closings, nil
+}
+
+// CheckPeriodOpen retourne ErrPeriodClosed si la date appartient à une période clôturée
+func (db *DB) CheckPeriodOpen(ctx context.Context, date time.Time) error {
+	return checkPeriodOpen(ctx, db.Pool, date)
+}
+
+// CheckPeriodOpen retourne ErrPeriodClosed si la date appartient à une période clôturée
+func (r *PostgresRepository) CheckPeriodOpen(ctx context.Context, date time.Time) error {
+	return checkPeriodOpen(ctx, r.pool, date)
+}
+
+func checkPeriodOpen(ctx context.Context, pool *pgxpool.Pool, date time.Time) error {
+	var period string
+	err := pool.QueryRow(ctx, `
+		SELECT period FROM closings
+		WHERE period_start <= $1 AND period_end > $1
+		LIMIT 1
+	`, date).Scan(&period)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check closed periods: %w", err)
+	}
+	return ErrPeriodClosed{Period: period}
+}
+
+func scanClosing(row pgx.Row) (*models.Closing, error) {
+	var c models.Closing
+	if err := row.Scan(&c.Period, &c.PeriodStart, &c.PeriodEnd, &c.DocumentCount, &c.LedgerEntryCount,
+		&c.MerkleRoot, &c.LastLedgerHash, &c.ArchivePath, &c.ArchiveSHA256, &c.ManifestJWS,
+		&c.ClosedAt, &c.ClosedBy); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+)
+
+// ErrContentTooLarge est retourné quand un contenu reçu en flux dépasse la taille maximale
+var ErrContentTooLarge = errors.New("content exceeds maximum size")
+
+// Content est le contenu d'un document à enregistrer. Il est relu à chaque écriture
+// (stockage, réécriture d'un contenu partagé supprimé entre-temps) plutôt que conservé
+// en mémoire : un contenu reçu en flux reste dans un fichier temporaire chiffré
+type Content interface {
+	Open() (io.ReadCloser, error)
+	Size() int64
+	SHA256Hex() string
+}
+
+// BytesContent retourne un contenu déjà en mémoire
+func BytesContent(data []byte) Content {
+	hash := sha256.Sum256(data)
+	return &bytesContent{data: data, sha256Hex: hex.EncodeToString(hash[:])}
+}
+
+type bytesContent struct {
+	data      []byte
+	sha256Hex string
+}
+
+// bytesReader garde Seek : le stockage S3 n'a pas à recopier le contenu pour le hacher
+type bytesReader struct {
+	*bytes.Reader
+}
+
+func (bytesReader) Close() error { return nil }
+
+func (c *bytesContent) Open() (io.ReadCloser, error) {
+	return bytesReader{bytes.NewReader(c.data)}, nil
+}
+
+func (c *bytesContent) Size() int64       { return int64(len(c.data)) }
+func (c *bytesContent) SHA256Hex() string { return c.sha256Hex }
+
+// ReadContent lit entièrement un contenu (validations qui travaillent en mémoire)
+func ReadContent(content Content) ([]byte, error) {
+	if c, ok := content.(*bytesContent); ok {
+		return c.data, nil
+	}
+	r, err := content.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// SpooledContent est un contenu reçu en flux, écrit dans un fichier temporaire pendant
+// le calcul de son SHA256. Le fichier est chiffré par une clé éphémère gardée en mémoire :
+// le clair n'est jamais écrit sur disque, et un fichier laissé par un arrêt du processus
+// est illisible. Remove supprime le fichier une fois le document enregistré
+type SpooledContent struct {
+	path      string
+	size      int64
+	sha256Hex string
+	dek       []byte
+}
+
+// SpoolContent écrit r dans un fichier temporaire chiffré de dir en calculant son SHA256.
+// dir est un répertoire privé (UPLOAD_SESSIONS_DIR), créé en 0700 s'il n'existe pas ;
+// "" : répertoire temporaire du système. maxBytes > 0 limite la taille (ErrContentTooLarge)
+func SpoolContent(r io.Reader, dir string, maxBytes int64) (*SpooledContent, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create spool directory: %w", err)
+		}
+	}
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("failed to generate spool key: %w", err)
+	}
+	f, err := os.CreateTemp(dir, SpoolFilePrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	hash := sha256.New()
+	src := r
+	if maxBytes > 0 {
+		src = io.LimitReader(r, maxBytes+1)
+	}
+	var size sizeCounter
+	sealed, err := crypto.NewEncryptReader(dek, io.TeeReader(src, io.MultiWriter(hash, &size)))
+	if err == nil {
+		_, err = io.Copy(f, sealed)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && maxBytes > 0 && int64(size) > maxBytes {
+		err = ErrContentTooLarge
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		if errors.Is(err, ErrContentTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to spool content: %w", err)
+	}
+
+	return &SpooledContent{path: f.Name(), size: int64(size), sha256Hex: hex.EncodeToString(hash.Sum(nil)), dek: dek}, nil
+}
+
+// sizeCounter compte les octets écrits
+type sizeCounter int64
+
+func (c *sizeCounter) Write(p []byte) (int, error) {
+	*c += sizeCounter(len(p))
+	return len(p), nil
+}
+
+// SpoolFilePrefix préfixe les fichiers temporaires des contenus reçus en flux
+const SpoolFilePrefix = "dorevia-upload-"
+
+func (c *SpooledContent) Open() (io.ReadCloser, error) {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := crypto.NewDecryptReader(c.dek, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return readCloser{Reader: plain, Closer: f}, nil
+}
+
+func (c *SpooledContent) Size() int64       { return c.size }
+func (c *SpooledContent) SHA256Hex() string { return c.sha256Hex }
+
+// Remove supprime le fichier temporaire
+func (c *SpooledContent) Remove() {
+	os.Remove(c.path)
+}
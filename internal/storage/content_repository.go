@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrContentStorageNotConfigured est retourné par un repository sans stockage du contenu
+// (NewPostgresRepository) pour un document accompagné d'un fichier
+var ErrContentStorageNotConfigured = errors.New("content storage not configured")
+
+// ContentRepository est implémenté par les repositories qui stockent aussi le contenu
+// des documents (factures, uploads). Les tickets POS n'ont pas de fichier
+type ContentRepository interface {
+	// InsertContentWithEvidence écrit content (BlobStore) puis insère le document comme
+	// InsertDocumentWithEvidence ; le contenu est supprimé si la transaction échoue
+	InsertContentWithEvidence(
+		ctx context.Context,
+		doc *models.Document,
+		content Content,
+		evidenceJWS string,
+		ledgerService ledger.Service,
+	) error
+}
+
+// DocumentSealer est implémenté par les repositories qui scellent après coup un document
+// enregistré sans preuve (cmd/backfill, réconciliation)
+type DocumentSealer interface {
+	// SealDocument enregistre evidenceJWS et inscrit le document au ledger s'il n'y est
+	// pas, puis inscrit le scellement (document.sealed). Une preuve déjà présente n'est
+	// jamais remplacée
+	SealDocument(ctx context.Context, doc *models.Document, evidenceJWS string, ledgerService ledger.Service) error
+}
+
+// Vérification de l'implémentation des interfaces
+var (
+	_ ContentRepository = (*PostgresRepository)(nil)
+	_ DocumentSealer    = (*PostgresRepository)(nil)
+)
+
+// NewDocumentRepository crée un repository PostgreSQL qui stocke aussi le contenu des
+// documents (BlobStore de db, ou stockage fichier dans storageDir)
+func NewDocumentRepository(db *DB, storageDir string) *PostgresRepository {
+	return &PostgresRepository{
+		pool:       db.Pool,
+		log:        db.log,
+		db:         db,
+		storageDir: storageDir,
+	}
+}
+
+// InsertContentWithEvidence écrit le contenu avant la transaction (hors délai de
+// transaction : un document volumineux peut prendre du temps), puis insère le document,
+// sa référence au contenu partagé et son entrée ledger dans une même transaction
+func (r *PostgresRepository) InsertContentWithEvidence(
+	ctx context.Context,
+	doc *models.Document,
+	content Content,
+	evidenceJWS string,
+	ledgerService ledger.Service,
+) error {
+	if r.db == nil {
+		return ErrContentStorageNotConfigured
+	}
+
+	blobs := r.db.BlobStoreFor(r.storageDir)
+	stored, err := r.db.PutDocumentContent(ctx, blobs, doc.ID, doc.CreatedAt, doc.Filename, doc.ContentType, content)
+	if err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+	doc.SizeBytes = content.Size()
+	doc.SHA256Hex = content.SHA256Hex()
+	doc.StoredPath = stored.Ref
+	doc.WrappedKey = stored.WrappedKey
+	doc.KEKVersion = stored.KEKVersion
+
+	// Contenu supprimé si la transaction échoue (un contenu resté orphelin après un arrêt
+	// brutal est détecté par cmd/reconcile)
+	if err := r.insertContentTx(ctx, doc, blobs, stored, content, evidenceJWS, ledgerService); err != nil {
+		r.db.DiscardDocumentContent(context.Background(), blobs, stored.Ref, doc.SHA256Hex)
+		return err
+	}
+
+	r.log.Info().
+		Str("document_id", doc.ID.String()).
+		Str("sha256", doc.SHA256Hex).
+		Bool("jws_generated", evidenceJWS != "").
+		Bool("ledger_appended", doc.LedgerHash != nil).
+		Msg("Document stored with evidence via repository")
+
+	return nil
+}
+
+func (r *PostgresRepository) insertContentTx(
+	ctx context.Context,
+	doc *models.Document,
+	blobs blobstore.Store,
+	stored *StoredContent,
+	content Content,
+	evidenceJWS string,
+	ledgerService ledger.Service,
+) error {
+	txCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.Begin(txCtx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(txCtx)
+
+	// Référence au contenu partagé (disposition par contenu) : fixe sa clé de données
+	if err := r.db.RetainDocumentContent(txCtx, tx, blobs, stored, content); err != nil {
+		return err
+	}
+	if err := insertDocumentWithEvidenceTx(txCtx, tx, doc, evidenceJWS, ledgerService); err != nil {
+		return err
+	}
+	if err := tx.Commit(txCtx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SealDocument scelle un document enregistré sans preuve : evidence_jws et ledger_hash
+// manquants sont complétés sous verrou de la ligne. Un document déjà inscrit au ledger
+// n'y est pas inscrit une seconde fois. Le scellement a posteriori est inscrit dans la
+// même transaction par un événement document.sealed, distinct de l'entrée
+// document.created dont le hash est conservé dans ledger_hash
+func (r *PostgresRepository) SealDocument(ctx context.Context, doc *models.Document, evidenceJWS string, ledgerService ledger.Service) error {
+	txCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.Begin(txCtx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(txCtx)
+
+	var currentJWS, currentLedgerHash *string
+	var createdAt time.Time
+	err = tx.QueryRow(txCtx, `
+		SELECT evidence_jws, ledger_hash, created_at FROM documents WHERE id = $1 FOR UPDATE
+	`, doc.ID).Scan(&currentJWS, &currentLedgerHash, &createdAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrDocumentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock document: %w", err)
+	}
+
+	evidenceAdded := evidenceJWS != ""
+	if currentJWS != nil && *currentJWS != "" {
+		evidenceJWS = *currentJWS
+		evidenceAdded = false
+	}
+	ledgerHash := ""
+	if currentLedgerHash != nil {
+		ledgerHash = *currentLedgerHash
+	}
+
+	appendedHash := ""
+	if ledgerHash == "" && ledgerService != nil {
+		exists, err := ledgerService.ExistsByDocumentID(txCtx, tx, doc.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check ledger: %w", err)
+		}
+		if exists {
+			// Inscrit sans que ledger_hash ait été enregistré : reprendre la première entrée
+			err = tx.QueryRow(txCtx, `
+				SELECT hash FROM ledger WHERE document_id = $1 ORDER BY timestamp, id LIMIT 1
+			`, doc.ID).Scan(&ledgerHash)
+		} else {
+			ledgerHash, err = ledgerService.Append(txCtx, tx, derefString(doc.Tenant), doc.ID, doc.SHA256Hex, evidenceJWS)
+			appendedHash = ledgerHash
+		}
+		if err != nil {
+			return fmt.Errorf("failed to append to ledger: %w", err)
+		}
+	}
+
+	// Scellement a posteriori : événement distinct de l'enregistrement du document
+	if ledgerService != nil && (evidenceAdded || appendedHash != "") {
+		_, err = ledgerService.AppendEvent(txCtx, tx, derefString(doc.Tenant), ledger.Event{
+			Type:       ledger.EntryTypeDocumentSealed,
+			DocumentID: &doc.ID,
+			Body: ledger.DocumentSealedBody{
+				SHA256Hex:     doc.SHA256Hex,
+				CreatedAt:     createdAt.UTC().Format(time.RFC3339),
+				EvidenceAdded: evidenceAdded,
+				LedgerHash:    appendedHash,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to append seal event to ledger: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(txCtx, `
+		UPDATE documents SET evidence_jws = NULLIF($2, ''), ledger_hash = NULLIF($3, '') WHERE id = $1
+	`, doc.ID, evidenceJWS, ledgerHash)
+	if err != nil {
+		return fmt.Errorf("failed to update evidence: %w", err)
+	}
+	if err := tx.Commit(txCtx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if evidenceJWS != "" {
+		doc.EvidenceJWS = &evidenceJWS
+	}
+	if ledgerHash != "" {
+		doc.LedgerHash = &ledgerHash
+	}
+	return nil
+}
+
+// UnsealedFilter sélectionne les documents enregistrés sans preuve complète
+type UnsealedFilter struct {
+	MissingJWS    bool // sans evidence_jws
+	MissingLedger bool // sans ledger_hash
+}
+
+// ListUnsealedDocuments liste, par date de création, les documents non purgés sans preuve
+// selon filter, après after (pagination par curseur, nil : depuis le début)
+func (db *DB) ListUnsealedDocuments(ctx context.Context, filter UnsealedFilter, after *models.Document, limit int) ([]models.Document, error) {
+	var missing []string
+	if filter.MissingJWS {
+		missing = append(missing, "evidence_jws IS NULL")
+	}
+	if filter.MissingLedger {
+		missing = append(missing, "ledger_hash IS NULL")
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{}
+	where := []string{"purged_at IS NULL", "(" + strings.Join(missing, " OR ") + ")"}
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where = append(where, "(created_at, id) > ($1, $2)")
+	}
+	query := `
+		SELECT id, filename, sha256_hex, created_at, source, tenant, evidence_jws, ledger_hash
+		FROM documents
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY created_at ASC, id ASC`
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unsealed documents: %w", err)
+	}
+	defer rows.Close()
+
+	var documents []models.Document
+	for rows.Next() {
+		var doc models.Document
+		if err := rows.Scan(&doc.ID, &doc.Filename, &doc.SHA256Hex, &doc.CreatedAt, &doc.Source, &doc.Tenant, &doc.EvidenceJWS, &doc.LedgerHash); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		documents = append(documents, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+	return documents, nil
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Statuts de transmission d'un document (colonne dispatch_status)
+const (
+	DispatchStatusPending  = "PENDING"
+	DispatchStatusSent     = "SENT"
+	DispatchStatusAck      = "ACK"
+	DispatchStatusRejected = "REJECTED"
+)
+
+// dispatchTransitions liste les transitions autorisées de dispatch_status
+// (un document rejeté peut être renvoyé, un accusé de réception est définitif)
+var dispatchTransitions = map[string][]string{
+	DispatchStatusPending:  {DispatchStatusSent},
+	DispatchStatusSent:     {DispatchStatusAck, DispatchStatusRejected},
+	DispatchStatusRejected: {DispatchStatusSent},
+}
+
+// ErrDocumentNotFound est retourné quand le document n'existe pas (ou appartient à un autre tenant)
+var ErrDocumentNotFound = errors.New("document not found")
+
+// ErrInvalidStatusTransition est retourné pour une transition de dispatch_status non autorisée
+type ErrInvalidStatusTransition struct {
+	From string
+	To   string
+}
+
+func (e ErrInvalidStatusTransition) Error() string {
+	return fmt.Sprintf("invalid dispatch_status transition %s -> %s", e.From, e.To)
+}
+
+// ValidDispatchTransition indique si dispatch_status peut passer de from à to
+func ValidDispatchTransition(from, to string) bool {
+	for _, allowed := range dispatchTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusUpdate décrit un changement de statut d'un document (champs nil : inchangés)
+type StatusUpdate struct {
+	DispatchStatus *string
+	OdooState      *string
+	Actor          string // auteur du changement, inscrit dans l'événement
+	Tenant         string // tenant de l'appelant ("" = accès non restreint)
+}
+
+// UpdateDocumentStatus modifie dispatch_status et/ou odoo_state d'un document et inscrit
+// un événement document.status_changed par champ modifié, dans la même transaction :
+// un statut ne change jamais hors de la chaîne du ledger
+func (db *DB) UpdateDocumentStatus(ctx context.Context, id uuid.UUID, update StatusUpdate) (*models.Document, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var dispatchStatus, odooState, tenant *string
+	err = tx.QueryRow(ctx, `
+		SELECT dispatch_status, odoo_state, tenant FROM documents WHERE id = $1 FOR UPDATE
+	`, id).Scan(&dispatchStatus, &odooState, &tenant)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock document: %w", err)
+	}
+	if update.Tenant != "" && (tenant == nil || *tenant != update.Tenant) {
+		return nil, ErrDocumentNotFound
+	}
+	docTenant := ""
+	if tenant != nil {
+		docTenant = *tenant
+	}
+
+	if update.DispatchStatus != nil && !sameStatus(dispatchStatus, update.DispatchStatus) {
+		from := DispatchStatusPending
+		if dispatchStatus != nil {
+			from = *dispatchStatus
+		}
+		if !ValidDispatchTransition(from, *update.DispatchStatus) {
+			return nil, ErrInvalidStatusTransition{From: from, To: *update.DispatchStatus}
+		}
+		if _, err := tx.Exec(ctx, `UPDATE documents SET dispatch_status = $2 WHERE id = $1`, id, *update.DispatchStatus); err != nil {
+			return nil, fmt.Errorf("failed to update dispatch_status: %w", err)
+		}
+		if err := db.appendStatusChanged(ctx, tx, docTenant, id, "dispatch_status", dispatchStatus, *update.DispatchStatus, update.Actor); err != nil {
+			return nil, err
+		}
+	}
+
+	if update.OdooState != nil && !sameStatus(odooState, update.OdooState) {
+		if _, err := tx.Exec(ctx, `UPDATE documents SET odoo_state = $2 WHERE id = $1`, id, *update.OdooState); err != nil {
+			return nil, fmt.Errorf("failed to update odoo_state: %w", err)
+		}
+		if err := db.appendStatusChanged(ctx, tx, docTenant, id, "odoo_state", odooState, *update.OdooState, update.Actor); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return db.GetDocumentByID(ctx, id)
+}
+
+func (db *DB) appendStatusChanged(ctx context.Context, tx pgx.Tx, tenant string, id uuid.UUID, field string, from *string, to, actor string) error {
+	_, err := db.ledger.AppendEvent(ctx, tx, tenant, ledger.Event{
+		Type:       ledger.EntryTypeDocumentStatusChanged,
+		DocumentID: &id,
+		Body:       ledger.StatusChangedBody{Field: field, From: from, To: to, Actor: actor},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append status change to ledger: %w", err)
+	}
+	return nil
+}
+
+// AppendLedgerEvent inscrit un événement dans le ledger, dans sa propre transaction
+func (db *DB) AppendLedgerEvent(ctx context.Context, tenant string, event ledger.Event) (string, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	hash, err := db.ledger.AppendEvent(ctx, tx, tenant, event)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return hash, nil
+}
+
+func sameStatus(current, next *string) bool {
+	return current != nil && next != nil && *current == *next
+}
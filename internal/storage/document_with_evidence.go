@@ -2,15 +2,10 @@ package storage
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/doreviateam/dorevia-vault/internal/crypto"
-	"github.com/doreviateam/dorevia-vault/internal/ledger"
 	"github.com/doreviateam/dorevia-vault/internal/metrics"
 	"github.com/doreviateam/dorevia-vault/internal/models"
 	"github.com/google/uuid"
@@ -18,7 +13,7 @@ import (
 )
 
 // StoreDocumentWithEvidence stocke un document avec JWS + Ledger (Sprint 2)
-// Flux complet : fichier → DB → JWS → Ledger → UPDATE evidence
+// Flux complet : contenu (BlobStore) → DB → JWS → Ledger → UPDATE evidence
 // Sprint 3 : Ajout timeout transaction (30s par défaut) + métriques Prometheus
 func (db *DB) StoreDocumentWithEvidence(
 	ctx context.Context,
@@ -27,6 +22,19 @@ func (db *DB) StoreDocumentWithEvidence(
 	storageDir string,
 	jwsService *crypto.Service,
 	jwsEnabled, jwsRequired, ledgerEnabled bool,
+) error {
+	return db.StoreContentWithEvidence(ctx, doc, BytesContent(content), storageDir, jwsService, jwsEnabled, jwsRequired, ledgerEnabled)
+}
+
+// StoreContentWithEvidence stocke comme StoreDocumentWithEvidence un contenu éventuellement
+// reçu en flux (SpoolContent), sans le charger en mémoire
+func (db *DB) StoreContentWithEvidence(
+	ctx context.Context,
+	doc *models.Document,
+	content Content,
+	storageDir string,
+	jwsService *crypto.Service,
+	jwsEnabled, jwsRequired, ledgerEnabled bool,
 ) error {
 	// Sprint 3 : Ajouter timeout transaction (30s)
 	transactionTimeout := 30 * time.Second
@@ -40,13 +48,14 @@ func (db *DB) StoreDocumentWithEvidence(
 		metrics.RecordDocumentStorageDuration("store", storageDuration)
 	}()
 
-	// 1. Calculer hash avant transaction
-	hash := sha256.Sum256(content)
-	sha256Hex := hex.EncodeToString(hash[:])
+	// 1. Hash calculé à la réception du contenu
+	sha256Hex := content.SHA256Hex()
+	doc.SizeBytes = content.Size()
 
 	// 2. Vérifier idempotence (SELECT avant transaction)
 	var existingID uuid.UUID
-	err := db.Pool.QueryRow(txCtx, "SELECT id FROM documents WHERE sha256_hex = $1 LIMIT 1", sha256Hex).Scan(&existingID)
+	// Multi-tenant : idempotence évaluée dans le périmètre du tenant
+	err := db.Pool.QueryRow(txCtx, "SELECT id FROM documents WHERE sha256_hex = $1 AND tenant IS NOT DISTINCT FROM $2 LIMIT 1", sha256Hex, doc.Tenant).Scan(&existingID)
 	if err == nil {
 		// Document déjà existant
 		doc.ID = existingID
@@ -57,56 +66,53 @@ func (db *DB) StoreDocumentWithEvidence(
 		return fmt.Errorf("failed to check existing document: %w", err)
 	}
 
-	// 3. Générer UUID et chemin
+	// 3. Générer UUID et clé de stockage
 	docID := uuid.New()
 	now := time.Now()
-	datePath := filepath.Join(
-		storageDir,
-		fmt.Sprintf("%d", now.Year()),
-		fmt.Sprintf("%02d", now.Month()),
-		fmt.Sprintf("%02d", now.Day()),
-	)
-
-	if err := os.MkdirAll(datePath, 0755); err != nil {
-		return fmt.Errorf("failed to create storage directory: %w", err)
-	}
+	blobs := db.BlobStoreFor(storageDir)
 
-	// 4. Créer chemin temporaire puis final
-	tmpPath := filepath.Join(datePath, fmt.Sprintf("%s-%s.tmp", docID.String(), doc.Filename))
-	finalPath := filepath.Join(datePath, fmt.Sprintf("%s-%s", docID.String(), doc.Filename))
+	// 4. Stocker le contenu avant la transaction ; supprimé si elle échoue
+	// (un contenu resté orphelin après un arrêt brutal est détecté par cmd/reconcile)
+	stored, err := db.PutDocumentContent(txCtx, blobs, docID, now, doc.Filename, doc.ContentType, content)
+	if err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+	storedPath := stored.Ref
 
 	// 5. BEGIN transaction (avec timeout)
 	tx, err := db.Pool.Begin(txCtx)
 	if err != nil {
+		db.DiscardDocumentContent(ctx, blobs, storedPath, sha256Hex)
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(txCtx)
 
-	// 6. Stocker fichier sur disque (fichier temporaire)
-	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
-		return fmt.Errorf("failed to save file: %w", err)
+	// Référence au contenu partagé (disposition par contenu) : fixe sa clé de données
+	if err := db.RetainDocumentContent(txCtx, tx, blobs, stored, content); err != nil {
+		db.DiscardDocumentContent(ctx, blobs, storedPath, sha256Hex)
+		return err
 	}
 
-	// 7. INSERT dans documents (sans evidence_jws et ledger_hash pour l'instant)
+	// 6. INSERT dans documents (sans evidence_jws et ledger_hash pour l'instant)
 	_, err = tx.Exec(txCtx, `
 		INSERT INTO documents (
 			id, filename, content_type, size_bytes, sha256_hex, stored_path,
 			source, odoo_model, odoo_id, odoo_state, pdp_required, dispatch_status,
 			invoice_number, invoice_date, total_ht, total_ttc, currency, seller_vat, buyer_vat,
-			evidence_jws, ledger_hash
+			evidence_jws, ledger_hash, tenant, dek_wrapped, kek_version
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
-	`, docID, doc.Filename, doc.ContentType, doc.SizeBytes, sha256Hex, finalPath,
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
+	`, docID, doc.Filename, doc.ContentType, doc.SizeBytes, sha256Hex, storedPath,
 		doc.Source, doc.OdooModel, doc.OdooID, doc.OdooState, doc.PDPRequired, doc.DispatchStatus,
 		doc.InvoiceNumber, doc.InvoiceDate, doc.TotalHT, doc.TotalTTC, doc.Currency, doc.SellerVAT, doc.BuyerVAT,
-		nil, nil) // evidence_jws et ledger_hash seront mis à jour après
+		nil, nil, doc.Tenant, stored.WrappedKey, stored.KEKVersion) // evidence_jws et ledger_hash seront mis à jour après
 
 	if err != nil {
-		os.Remove(tmpPath)
+		db.DiscardDocumentContent(ctx, blobs, storedPath, sha256Hex)
 		return fmt.Errorf("failed to insert document: %w", err)
 	}
 
-	// 8. Générer JWS (hors transaction mais rapide)
+	// 7. Générer JWS (hors transaction mais rapide)
 	var jws string
 	if jwsEnabled && jwsService != nil {
 		jwsStartTime := time.Now() // Sprint 3 Phase 2 : Mesure durée JWS
@@ -119,7 +125,7 @@ func (db *DB) StoreDocumentWithEvidence(
 			metrics.RecordJWSSignatureDuration(jwsDuration)
 			
 			if jwsRequired {
-				os.Remove(tmpPath)
+				db.DiscardDocumentContent(ctx, blobs, storedPath, sha256Hex)
 				return fmt.Errorf("JWS required but generation failed: %w", err)
 			}
 			// Mode dégradé : continuer sans JWS
@@ -132,17 +138,17 @@ func (db *DB) StoreDocumentWithEvidence(
 		}
 	}
 
-	// 9. AppendLedger (dans transaction avec verrou)
+	// 8. AppendLedger (dans transaction avec verrou)
 	var ledgerHash string
 	if ledgerEnabled {
 		ledgerStartTime := time.Now() // Sprint 3 Phase 2 : Mesure durée ledger
-		ledgerHash, err = ledger.AppendLedger(txCtx, tx, docID, sha256Hex, jws)
+		ledgerHash, err = db.ledger.Append(txCtx, tx, derefString(doc.Tenant), docID, sha256Hex, jws)
 		ledgerDuration := time.Since(ledgerStartTime).Seconds()
 		
 		if err != nil {
 			// Métrique : erreur ledger (Sprint 4 Phase 4.1)
 			metrics.RecordLedgerAppendError()
-			os.Remove(tmpPath)
+			db.DiscardDocumentContent(ctx, blobs, storedPath, sha256Hex)
 			return fmt.Errorf("failed to append to ledger: %w", err)
 		}
 		
@@ -151,7 +157,7 @@ func (db *DB) StoreDocumentWithEvidence(
 		metrics.LedgerEntries.Inc() // Incrémenter compteur ledger
 	}
 
-	// 10. UPDATE documents avec evidence_jws et ledger_hash
+	// 9. UPDATE documents avec evidence_jws et ledger_hash
 	if jws != "" || ledgerHash != "" {
 		_, err = tx.Exec(txCtx, `
 			UPDATE documents 
@@ -159,31 +165,23 @@ func (db *DB) StoreDocumentWithEvidence(
 			WHERE id = $3
 		`, jws, ledgerHash, docID)
 		if err != nil {
-			os.Remove(tmpPath)
+			db.DiscardDocumentContent(ctx, blobs, storedPath, sha256Hex)
 			return fmt.Errorf("failed to update evidence: %w", err)
 		}
 	}
 
-	// 11. COMMIT (avec timeout)
+	// 10. COMMIT (avec timeout)
 	if err := tx.Commit(txCtx); err != nil {
-		os.Remove(tmpPath)
+		db.DiscardDocumentContent(ctx, blobs, storedPath, sha256Hex)
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	// 12. Déplacer fichier temporaire vers final (après COMMIT réussi)
-	if err := os.Rename(tmpPath, finalPath); err != nil {
-		db.log.Error().
-			Err(err).
-			Str("tmp_path", tmpPath).
-			Str("final_path", finalPath).
-			Msg("Failed to move file after commit - manual cleanup required")
-		return fmt.Errorf("failed to move file after commit: %w", err)
-	}
-
 	// Mettre à jour le document avec les valeurs finales
 	doc.ID = docID
 	doc.SHA256Hex = sha256Hex
-	doc.StoredPath = finalPath
+	doc.StoredPath = storedPath
+	doc.WrappedKey = stored.WrappedKey
+	doc.KEKVersion = stored.KEKVersion
 	doc.CreatedAt = now
 	if jws != "" {
 		doc.EvidenceJWS = &jws
@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// migrateDocumentEncryption ajoute la clé de données enveloppée des contenus chiffrés
+func (db *DB) migrateDocumentEncryption(ctx context.Context) error {
+	migrationSQL := `
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS dek_wrapped TEXT;
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS kek_version TEXT;
+		ALTER TABLE blob_refs ADD COLUMN IF NOT EXISTS dek_wrapped TEXT;
+		ALTER TABLE blob_refs ADD COLUMN IF NOT EXISTS kek_version TEXT;
+
+		-- Rotation des KEK : clés restant à envelopper par la nouvelle version
+		CREATE INDEX IF NOT EXISTS idx_documents_kek_version ON documents(kek_version) WHERE kek_version IS NOT NULL;
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply document encryption migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Document encryption migration applied successfully")
+	return nil
+}
+
+// SetEnvelope active le chiffrement au repos des nouveaux contenus (DOCUMENT_ENCRYPTION_ENABLED)
+// et le déchiffrement des contenus chiffrés
+func (db *DB) SetEnvelope(envelope *crypto.Envelope) {
+	if envelope != nil {
+		db.envelope = envelope
+	}
+}
+
+// StoredContent est le contenu écrit pour un nouveau document : sa référence et, s'il est
+// chiffré, sa clé de données enveloppée (colonnes dek_wrapped et kek_version)
+type StoredContent struct {
+	Ref        string
+	WrappedKey *string
+	KEKVersion *string
+	dek        []byte // clé de données en clair, si elle vient d'être générée
+}
+
+// dataKey retourne la clé de données en clair du contenu (nil s'il est en clair)
+func (db *DB) dataKey(ctx context.Context, wrapped, kekVersion *string) ([]byte, error) {
+	if wrapped == nil {
+		return nil, nil
+	}
+	if db.envelope == nil {
+		return nil, fmt.Errorf("content is encrypted with %s but document encryption is not configured", derefString(kekVersion))
+	}
+	return db.envelope.UnwrapDataKey(ctx, *wrapped, derefString(kekVersion))
+}
+
+// sealContent génère la clé de données d'un nouveau contenu si le chiffrement est activé
+func (db *DB) sealContent(ctx context.Context) (*StoredContent, error) {
+	if db.envelope == nil {
+		return &StoredContent{}, nil
+	}
+	dek, wrapped, kekVersion, err := db.envelope.NewDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &StoredContent{WrappedKey: &wrapped, KEKVersion: &kekVersion, dek: dek}, nil
+}
+
+// putContent écrit le contenu sous key, chiffré au fil de l'écriture par dek (nil : en clair)
+func putContent(ctx context.Context, blobs blobstore.Store, key string, content Content, dek []byte, opts blobstore.PutOptions) (string, error) {
+	r, err := content.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open content: %w", err)
+	}
+	defer r.Close()
+
+	var body io.Reader = r
+	size := content.Size()
+	if dek != nil {
+		if body, err = crypto.NewEncryptReader(dek, r); err != nil {
+			return "", fmt.Errorf("failed to encrypt content: %w", err)
+		}
+		size = crypto.EncryptedSize(size)
+	}
+	return blobs.Put(ctx, key, body, size, opts)
+}
+
+// contentKey est la clé de données enveloppée d'un contenu (wrapped nil : contenu en clair)
+type contentKey struct {
+	wrapped, kekVersion *string
+}
+
+func documentContentKey(doc models.Document) contentKey {
+	return contentKey{wrapped: doc.WrappedKey, kekVersion: doc.KEKVersion}
+}
+
+// blobRefKey retourne la clé de données d'un contenu partagé (blob_refs)
+func (db *DB) blobRefKey(ctx context.Context, ref string) (contentKey, error) {
+	var key contentKey
+	err := db.Pool.QueryRow(ctx, `SELECT dek_wrapped, kek_version FROM blob_refs WHERE ref = $1`, ref).Scan(&key.wrapped, &key.kekVersion)
+	if err != nil && err != pgx.ErrNoRows {
+		return key, fmt.Errorf("failed to load content key: %w", err)
+	}
+	return key, nil
+}
+
+// loadDocumentKey charge la clé de données enveloppée du document depuis la base (elle a pu
+// être enveloppée à nouveau depuis le chargement du document)
+func (db *DB) loadDocumentKey(ctx context.Context, doc *models.Document) error {
+	err := db.Pool.QueryRow(ctx, `SELECT dek_wrapped, kek_version FROM documents WHERE id = $1`, doc.ID).Scan(&doc.WrappedKey, &doc.KEKVersion)
+	if err == pgx.ErrNoRows {
+		return nil // Document non enregistré : contenu en clair
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load document key: %w", err)
+	}
+	return nil
+}
+
+// openContent ouvre un objet et le déchiffre au fil de la lecture s'il est chiffré
+func (db *DB) openContent(ctx context.Context, store blobstore.Store, ref string, wrapped, kekVersion *string) (io.ReadCloser, error) {
+	dek, err := db.dataKey(ctx, wrapped, kekVersion)
+	if err != nil {
+		return nil, err
+	}
+	r, err := store.Open(ctx, ref)
+	if err != nil || dek == nil {
+		return r, err
+	}
+	plain, err := crypto.NewDecryptReader(dek, r)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return readCloser{Reader: plain, Closer: r}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// ContentSize retourne la taille attendue de l'objet stocké d'un document (contenu chiffré
+// compris)
+func (db *DB) ContentSize(ctx context.Context, doc *models.Document) (int64, error) {
+	if err := db.loadDocumentKey(ctx, doc); err != nil {
+		return 0, err
+	}
+	if doc.WrappedKey != nil {
+		return crypto.EncryptedSize(doc.SizeBytes), nil
+	}
+	return doc.SizeBytes, nil
+}
+
+// RewrapOptions configure l'enveloppement des clés de données par la KEK courante
+type RewrapOptions struct {
+	DryRun     bool   // compte les clés sans les modifier
+	KEKVersion string // n'envelopper que les clés de cette version (vide : toutes sauf la courante)
+	Limit      int    // nombre maximal de clés traitées (0 : toutes)
+}
+
+// RewrapFailure décrit une clé non enveloppée
+type RewrapFailure struct {
+	KEKVersion string `json:"kek_version"`
+	Documents  int    `json:"documents"`
+	Error      string `json:"error"`
+}
+
+// RewrapReport est le rapport d'un enveloppement des clés par la KEK courante
+type RewrapReport struct {
+	Timestamp  time.Time       `json:"timestamp"`
+	KEKVersion string          `json:"kek_version"` // KEK cible
+	DryRun     bool            `json:"dry_run"`
+	Keys       int             `json:"keys"`      // clés de données concernées
+	Rewrapped  int             `json:"rewrapped"` // clés enveloppées à nouveau
+	Documents  int             `json:"documents"` // documents mis à jour
+	Failures   []RewrapFailure `json:"failures"`
+}
+
+// RewrapDocumentKeys enveloppe à nouveau les clés de données par la KEK courante, sans
+// réécrire les contenus. Une clé partagée (disposition par contenu) est mise à jour pour
+// tous ses documents dans une même transaction
+func (db *DB) RewrapDocumentKeys(ctx context.Context, opts RewrapOptions) (*RewrapReport, error) {
+	if db.envelope == nil {
+		return nil, fmt.Errorf("document encryption is not configured")
+	}
+	target := db.envelope.KEKVersion()
+	report := &RewrapReport{
+		Timestamp:  time.Now().UTC(),
+		KEKVersion: target,
+		DryRun:     opts.DryRun,
+		Failures:   []RewrapFailure{},
+	}
+
+	rows, err := db.Pool.Query(ctx, `
+		SELECT dek_wrapped, kek_version, COUNT(*)
+		FROM documents
+		WHERE dek_wrapped IS NOT NULL AND kek_version != $1 AND ($2 = '' OR kek_version = $2)
+		GROUP BY dek_wrapped, kek_version
+		ORDER BY kek_version, MIN(created_at)
+	`, target, opts.KEKVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document keys: %w", err)
+	}
+	type wrappedKey struct {
+		wrapped, kekVersion string
+		documents           int
+	}
+	var keys []wrappedKey
+	for rows.Next() {
+		var k wrappedKey
+		if err := rows.Scan(&k.wrapped, &k.kekVersion, &k.documents); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan document key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating document keys: %w", err)
+	}
+	if opts.Limit > 0 && len(keys) > opts.Limit {
+		keys = keys[:opts.Limit]
+	}
+	report.Keys = len(keys)
+	if opts.DryRun {
+		for _, k := range keys {
+			report.Documents += k.documents
+		}
+		return report, nil
+	}
+
+	for _, k := range keys {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		updated, err := db.rewrapKey(ctx, k.wrapped, k.kekVersion)
+		if err != nil {
+			report.Failures = append(report.Failures, RewrapFailure{KEKVersion: k.kekVersion, Documents: k.documents, Error: err.Error()})
+			db.log.Error().Err(err).Str("kek_version", k.kekVersion).Msg("Failed to rewrap document key")
+			continue
+		}
+		report.Rewrapped++
+		report.Documents += int(updated)
+	}
+
+	db.log.Info().
+		Str("kek_version", target).
+		Int("rewrapped", report.Rewrapped).
+		Int("documents", report.Documents).
+		Msg("Document keys rewrapped")
+	return report, nil
+}
+
+// rewrapKey remplace une clé enveloppée dans les documents et le contenu partagé qui la portent
+func (db *DB) rewrapKey(ctx context.Context, wrapped, kekVersion string) (int64, error) {
+	rewrapped, target, err := db.envelope.RewrapDataKey(ctx, wrapped, kekVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Contenu partagé d'abord : verrou sur son comptage (enregistrements concurrents)
+	if _, err := tx.Exec(ctx, `
+		UPDATE blob_refs SET dek_wrapped = $1, kek_version = $2
+		WHERE dek_wrapped = $3 AND kek_version = $4
+	`, rewrapped, target, wrapped, kekVersion); err != nil {
+		return 0, fmt.Errorf("failed to update content key: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE documents SET dek_wrapped = $1, kek_version = $2
+		WHERE dek_wrapped = $3 AND kek_version = $4
+	`, rewrapped, target, wrapped, kekVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update document key: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
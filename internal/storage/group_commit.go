@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/metrics"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrGroupCommitClosed est retourné pour un document soumis après l'arrêt du group commit
+var ErrGroupCommitClosed = errors.New("group commit closed")
+
+// GroupCommitOptions configure le regroupement des insertions
+type GroupCommitOptions struct {
+	// Window : durée de collecte d'un lot après son premier document
+	Window time.Duration
+	// MaxBatch : nombre maximal de documents par transaction
+	MaxBatch int
+}
+
+// GroupCommitRepository regroupe les InsertDocumentWithEvidence concurrents dans une
+// seule transaction (un BEGIN/COMMIT et un verrou ledger_head par lot au lieu d'un par
+// document). Chaque document garde son evidence_jws et son ledger_hash, et chaque
+// appelant reste bloqué jusqu'au COMMIT du lot qui contient son document.
+//
+// Chaque document est inséré sous un SAVEPOINT : l'échec de l'un (doublon, contrainte)
+// n'est retourné qu'à son appelant, les autres documents du lot sont validés. Si la
+// transaction du lot elle-même échoue (BEGIN, SAVEPOINT, COMMIT), ses documents sont
+// réinsérés un à un dans leur propre transaction, sauf ceux déjà en base (COMMIT
+// validé malgré l'erreur retournée).
+// Les autres méthodes (GetDocumentBySHA256, CheckPeriodOpen...) sont celles du
+// PostgresRepository sous-jacent.
+type GroupCommitRepository struct {
+	*PostgresRepository
+	opts     GroupCommitOptions
+	requests chan *groupCommitRequest
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// groupCommitRequest est un document en attente d'insertion
+type groupCommitRequest struct {
+	doc           *models.Document
+	evidenceJWS   string
+	ledgerService ledger.Service
+	submittedAt   time.Time
+	result        chan error
+}
+
+// Vérification de l'implémentation de l'interface DocumentRepository
+var _ DocumentRepository = (*GroupCommitRepository)(nil)
+
+// NewGroupCommitRepository crée un repository à insertions groupées et démarre sa
+// goroutine de collecte (arrêtée par Close)
+func NewGroupCommitRepository(repo *PostgresRepository, opts GroupCommitOptions) *GroupCommitRepository {
+	if opts.Window <= 0 {
+		opts.Window = 5 * time.Millisecond
+	}
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = 200
+	}
+	r := &GroupCommitRepository{
+		PostgresRepository: repo,
+		opts:               opts,
+		requests:           make(chan *groupCommitRequest),
+		stop:               make(chan struct{}),
+		done:               make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// InsertDocumentWithEvidence soumet le document au prochain lot et attend son COMMIT
+func (r *GroupCommitRepository) InsertDocumentWithEvidence(
+	ctx context.Context,
+	doc *models.Document,
+	evidenceJWS string,
+	ledgerService ledger.Service,
+) error {
+	req := &groupCommitRequest{
+		doc:           doc,
+		evidenceJWS:   evidenceJWS,
+		ledgerService: ledgerService,
+		submittedAt:   time.Now(),
+		result:        make(chan error, 1),
+	}
+
+	select {
+	case r.requests <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.stop:
+		return ErrGroupCommitClosed
+	}
+
+	// Une fois soumis, le document peut être validé : on attend toujours le verdict du lot
+	return <-req.result
+}
+
+// Close arrête la collecte après le lot en cours (les documents déjà soumis sont traités)
+func (r *GroupCommitRepository) Close() {
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+}
+
+// run collecte les lots : le premier document ouvre une fenêtre de opts.Window,
+// le lot part à l'expiration de la fenêtre ou dès qu'il atteint opts.MaxBatch
+func (r *GroupCommitRepository) run() {
+	defer close(r.done)
+	for {
+		var batch []*groupCommitRequest
+		select {
+		case req := <-r.requests:
+			batch = append(batch, req)
+		case <-r.stop:
+			return
+		}
+
+		timer := time.NewTimer(r.opts.Window)
+	collect:
+		for len(batch) < r.opts.MaxBatch {
+			select {
+			case req := <-r.requests:
+				batch = append(batch, req)
+			case <-timer.C:
+				break collect
+			case <-r.stop:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		r.commitBatch(batch)
+	}
+}
+
+// commitBatch insère un lot dans une seule transaction et répond à chaque appelant
+func (r *GroupCommitRepository) commitBatch(batch []*groupCommitRequest) {
+	start := time.Now()
+	metrics.RecordGroupCommitBatch(len(batch))
+	for _, req := range batch {
+		metrics.RecordGroupCommitWait(start.Sub(req.submittedAt).Seconds())
+	}
+
+	errs := make([]error, len(batch))
+	err := r.insertBatch(batch, errs)
+	if err != nil {
+		// Transaction du lot en échec : chaque document sans erreur propre est réinséré
+		// seul, pour qu'une erreur de lot ne soit retournée qu'aux documents concernés
+		r.log.Warn().Err(err).Int("batch_size", len(batch)).Msg("Group commit failed, retrying documents one by one")
+		r.retryBatch(batch, errs)
+	}
+	for i, req := range batch {
+		req.result <- errs[i]
+	}
+
+	if err != nil {
+		return
+	}
+	r.log.Debug().
+		Int("batch_size", len(batch)).
+		Dur("duration", time.Since(start)).
+		Msg("Group commit done")
+}
+
+// retryBatch insère un à un, chacun dans sa propre transaction, les documents d'un lot
+// en échec qui n'ont pas d'erreur propre ; errs reçoit le résultat de chaque tentative
+func (r *GroupCommitRepository) retryBatch(batch []*groupCommitRequest, errs []error) {
+	failed := 0
+	for i, req := range batch {
+		if errs[i] != nil {
+			continue
+		}
+		// Erreur ambiguë au COMMIT : le lot a pu être validé, le document n'est alors pas
+		// réinséré et l'appelant reçoit le ledger_hash et l'evidence stockés
+		stored, err := r.storedEvidence(context.Background(), req.doc)
+		if err != nil {
+			errs[i] = err
+			failed++
+			r.log.Error().Err(err).Str("document_id", req.doc.ID.String()).Msg("Group commit retry failed")
+			continue
+		}
+		if stored {
+			continue
+		}
+		// Le hash ledger du lot annulé n'a jamais été validé
+		req.doc.LedgerHash = nil
+		req.doc.EvidenceJWS = nil
+		errs[i] = r.PostgresRepository.InsertDocumentWithEvidence(context.Background(), req.doc, req.evidenceJWS, req.ledgerService)
+		if errs[i] != nil {
+			failed++
+			r.log.Error().Err(errs[i]).Str("document_id", req.doc.ID.String()).Msg("Group commit retry failed")
+		}
+	}
+	if failed > 0 {
+		r.log.Error().Int("batch_size", len(batch)).Int("failed", failed).Msg("Group commit failed")
+	}
+}
+
+// storedEvidence indique si le document est déjà en base (lot validé malgré l'erreur) ;
+// le cas échéant, doc reçoit l'evidence_jws et le ledger_hash stockés
+func (r *GroupCommitRepository) storedEvidence(ctx context.Context, doc *models.Document) (bool, error) {
+	var evidenceJWS, ledgerHash *string
+	err := r.pool.QueryRow(ctx, `
+		SELECT evidence_jws, ledger_hash FROM documents WHERE id = $1
+	`, doc.ID).Scan(&evidenceJWS, &ledgerHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check stored document: %w", err)
+	}
+	doc.EvidenceJWS = evidenceJWS
+	doc.LedgerHash = ledgerHash
+	return true, nil
+}
+
+// insertBatch exécute la transaction du lot ; errs reçoit les erreurs propres à
+// chaque document, l'erreur retournée concerne la transaction entière
+func (r *GroupCommitRepository) insertBatch(batch []*groupCommitRequest, errs []error) error {
+	// Timeout transaction (30s), indépendant des requêtes HTTP des appelants
+	txCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.Begin(txCtx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(txCtx)
+
+	committed := 0
+	for i, req := range batch {
+		savepoint, err := tx.Begin(txCtx)
+		if err != nil {
+			return fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		if err := insertDocumentWithEvidenceTx(txCtx, savepoint, req.doc, req.evidenceJWS, req.ledgerService); err != nil {
+			// Savepoint impossible à annuler : l'erreur peut venir de la transaction du lot,
+			// le document sera réinséré seul
+			if rbErr := savepoint.Rollback(txCtx); rbErr != nil {
+				return fmt.Errorf("failed to rollback savepoint: %w", rbErr)
+			}
+			errs[i] = err
+			continue
+		}
+		if err := savepoint.Commit(txCtx); err != nil {
+			return fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		committed++
+	}
+
+	if committed == 0 {
+		return nil
+	}
+	if err := tx.Commit(txCtx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
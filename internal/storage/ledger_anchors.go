@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrateLedgerAnchors crée la table des ancrages horodatés (RFC 3161) du ledger
+func (db *DB) migrateLedgerAnchors(ctx context.Context) error {
+	migrationSQL := `
+		CREATE TABLE IF NOT EXISTS ledger_anchors (
+			id               BIGSERIAL PRIMARY KEY,
+			ledger_entry_id  BIGINT NOT NULL,
+			ledger_hash      TEXT NOT NULL,
+			ledger_timestamp TIMESTAMPTZ NOT NULL,
+			tenant           TEXT,
+			per_tenant_chain BOOLEAN NOT NULL DEFAULT false,
+			tsa_url          TEXT NOT NULL,
+			token            BYTEA NOT NULL,
+			gen_time         TIMESTAMPTZ NOT NULL,
+			serial_number    TEXT NOT NULL,
+			policy           TEXT,
+			created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS uq_ledger_anchors_hash ON ledger_anchors(ledger_hash);
+		CREATE INDEX IF NOT EXISTS idx_ledger_anchors_position ON ledger_anchors(ledger_timestamp, ledger_entry_id);
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply ledger_anchors migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Ledger anchors migration applied successfully")
+	return nil
+}
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrateLedgerEntryTypes ajoute le type d'entrée et le corps des événements du ledger
+// (entrées existantes : document.created)
+func (db *DB) migrateLedgerEntryTypes(ctx context.Context) error {
+	migrationSQL := `
+		ALTER TABLE ledger ADD COLUMN IF NOT EXISTS entry_type TEXT NOT NULL DEFAULT 'document.created';
+		ALTER TABLE ledger ADD COLUMN IF NOT EXISTS body TEXT;
+		ALTER TABLE ledger ALTER COLUMN document_id DROP NOT NULL;
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply ledger entry_type migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Ledger entry types migration applied successfully")
+	return nil
+}
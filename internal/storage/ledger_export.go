@@ -13,24 +13,33 @@ type LedgerRow struct {
 	PrevHash   *string   `json:"prev_hash,omitempty"`
 	Seq        int64     `json:"seq"`        // si tu n’as pas de seq, remplace par ID
 	Timestamp  time.Time `json:"timestamp"`  // <— fix: time.Time
+	Tenant     *string   `json:"tenant,omitempty"`
+	HashVersion int       `json:"hash_version"` // format du hash (1 ou 2)
+	EntryType  string    `json:"entry_type"`     // document.created ou type d'événement
+	Body       *string   `json:"body,omitempty"` // corps JSON canonique d'un événement
 }
 
-// ExportLedger lit une page du ledger
-func (db *DB) ExportLedger(ctx context.Context, limit, offset int) ([]LedgerRow, error) {
+// ExportLedger lit une page du ledger (tenant vide = toutes les entrées)
+func (db *DB) ExportLedger(ctx context.Context, tenant string, limit, offset int) ([]LedgerRow, error) {
 	const q = `
 		SELECT
 			id,               -- SERIAL
-			document_id,      -- UUID
+			COALESCE(document_id::text, ''), -- UUID (NULL pour un événement sans document)
 			hash,
 			previous_hash,
 			id AS seq,        -- ou une vraie colonne seq si tu en as une
-			timestamp
+			timestamp,
+			tenant,
+			hash_version,
+			entry_type,
+			body
 		FROM ledger
+		WHERE ($3::text = '' OR tenant = $3)
 		ORDER BY id
 		LIMIT $1 OFFSET $2;
 	`
 
-	rows, err := db.Pool.Query(ctx, q, limit, offset)
+	rows, err := db.Pool.Query(ctx, q, limit, offset, tenant)
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +48,7 @@ func (db *DB) ExportLedger(ctx context.Context, limit, offset int) ([]LedgerRow,
 	out := make([]LedgerRow, 0, limit)
 	for rows.Next() {
 		var r LedgerRow
-		if err := rows.Scan(&r.ID, &r.DocumentID, &r.Hash, &r.PrevHash, &r.Seq, &r.Timestamp); err != nil {
+		if err := rows.Scan(&r.ID, &r.DocumentID, &r.Hash, &r.PrevHash, &r.Seq, &r.Timestamp, &r.Tenant, &r.HashVersion, &r.EntryType, &r.Body); err != nil {
 			return nil, err
 		}
 		out = append(out, r)
@@ -47,11 +56,11 @@ func (db *DB) ExportLedger(ctx context.Context, limit, offset int) ([]LedgerRow,
 	return out, rows.Err()
 }
 
-// CountLedger renvoie le total pour paginer
-func (db *DB) CountLedger(ctx context.Context) (int, error) {
-	const q = `SELECT count(*) FROM ledger`
+// CountLedger renvoie le total pour paginer (tenant vide = toutes les entrées)
+func (db *DB) CountLedger(ctx context.Context, tenant string) (int, error) {
+	const q = `SELECT count(*) FROM ledger WHERE ($1::text = '' OR tenant = $1)`
 	var n int
-	if err := db.Pool.QueryRow(ctx, q).Scan(&n); err != nil {
+	if err := db.Pool.QueryRow(ctx, q, tenant).Scan(&n); err != nil {
 		return 0, err
 	}
 	return n, nil
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrateLedgerHashVersion ajoute la version du format de hash des entrées du ledger
+// (entrées existantes en v1)
+func (db *DB) migrateLedgerHashVersion(ctx context.Context) error {
+	migrationSQL := `
+		ALTER TABLE ledger ADD COLUMN IF NOT EXISTS hash_version SMALLINT NOT NULL DEFAULT 1;
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply ledger hash_version migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Ledger hash version migration applied successfully")
+	return nil
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrateLedgerHead crée la table des têtes de chaîne du ledger et la colonne seq
+func (db *DB) migrateLedgerHead(ctx context.Context) error {
+	migrationSQL := `
+		CREATE TABLE IF NOT EXISTS ledger_head (
+			chain_id   TEXT PRIMARY KEY,
+			seq        BIGINT NOT NULL,
+			hash       TEXT,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		ALTER TABLE ledger ADD COLUMN IF NOT EXISTS seq BIGINT;
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply ledger_head migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Ledger head migration applied successfully")
+	return nil
+}
+
+// migrateLedgerChainEpoch ajoute le passage de la chaîne globale aux chaînes par tenant
+// (ligne global de ledger_head). Un ledger déjà chaîné par tenant est marqué comme
+// chaîné par tenant depuis son début
+func (db *DB) migrateLedgerChainEpoch(ctx context.Context) error {
+	migrationSQL := `
+		ALTER TABLE ledger_head ADD COLUMN IF NOT EXISTS tenant_epoch_id BIGINT;
+		ALTER TABLE ledger_head ADD COLUMN IF NOT EXISTS tenant_epoch_hash TEXT;
+
+		INSERT INTO ledger_head (chain_id, seq, hash, tenant_epoch_id)
+		SELECT 'global', 0, NULL, 0
+		WHERE EXISTS (SELECT 1 FROM ledger_head WHERE chain_id LIKE 'tenant:%')
+		ON CONFLICT (chain_id) DO UPDATE SET tenant_epoch_id = 0
+		WHERE ledger_head.tenant_epoch_id IS NULL;
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply ledger chain epoch migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Ledger chain epoch migration applied successfully")
+	return nil
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrateLedgerMerkleRoots crée la table des racines de Merkle journalières du ledger
+func (db *DB) migrateLedgerMerkleRoots(ctx context.Context) error {
+	migrationSQL := `
+		CREATE TABLE IF NOT EXISTS ledger_merkle_roots (
+			id              BIGSERIAL PRIMARY KEY,
+			batch_date      DATE NOT NULL,
+			tree_size       BIGINT NOT NULL,
+			root_hash       TEXT NOT NULL,
+			first_entry_id  BIGINT NOT NULL,
+			last_entry_id   BIGINT NOT NULL,
+			first_timestamp TIMESTAMPTZ NOT NULL,
+			last_timestamp  TIMESTAMPTZ NOT NULL,
+			leaf_hashes     BYTEA NOT NULL,
+			root_jws        TEXT NOT NULL,
+			created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS uq_ledger_merkle_roots_batch ON ledger_merkle_roots(batch_date);
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply ledger_merkle_roots migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Ledger Merkle roots migration applied successfully")
+	return nil
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrateLedgerPartitionArchives crée la table des partitions du ledger archivées
+func (db *DB) migrateLedgerPartitionArchives(ctx context.Context) error {
+	migrationSQL := `
+		CREATE TABLE IF NOT EXISTS ledger_partition_archives (
+			partition_name TEXT PRIMARY KEY,
+			period_start   TIMESTAMPTZ NOT NULL,
+			period_end     TIMESTAMPTZ NOT NULL,
+			file_path      TEXT NOT NULL,
+			sha256_hex     TEXT NOT NULL,
+			entry_count    BIGINT NOT NULL,
+			head_hash      TEXT,
+			tenant_heads   JSONB NOT NULL DEFAULT '{}',
+			manifest       JSONB NOT NULL,
+			archived_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+			restored_at    TIMESTAMPTZ
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_ledger_partition_archives_period ON ledger_partition_archives(period_start);
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply ledger_partition_archives migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Ledger partition archives migration applied successfully")
+	return nil
+}
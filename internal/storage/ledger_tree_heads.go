@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrateLedgerTreeHeads crée les tables des têtes de ledger signées et de leurs
+// contre-signatures par les témoins
+func (db *DB) migrateLedgerTreeHeads(ctx context.Context) error {
+	migrationSQL := `
+		CREATE TABLE IF NOT EXISTS ledger_tree_heads (
+			id         BIGSERIAL PRIMARY KEY,
+			tree_size  BIGINT NOT NULL,
+			head_hash  TEXT NOT NULL,
+			chains     JSONB NOT NULL,
+			timestamp  TIMESTAMPTZ NOT NULL,
+			jws        TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		CREATE TABLE IF NOT EXISTS ledger_tree_head_cosignatures (
+			id           BIGSERIAL PRIMARY KEY,
+			tree_head_id BIGINT NOT NULL REFERENCES ledger_tree_heads(id),
+			witness_id   TEXT NOT NULL,
+			kid          TEXT NOT NULL,
+			jws          TEXT NOT NULL,
+			created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS uq_ledger_tree_head_cosignatures ON ledger_tree_head_cosignatures(tree_head_id, kid);
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply ledger_tree_heads migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Ledger tree heads migration applied successfully")
+	return nil
+}
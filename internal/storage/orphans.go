@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// migrateDocumentOrphans ajoute le marqueur des documents dont le contenu est introuvable
+// (réconciliation)
+func (db *DB) migrateDocumentOrphans(ctx context.Context) error {
+	migrationSQL := `
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS orphaned_at TIMESTAMPTZ;
+
+		-- Documents marqués orphelins
+		CREATE INDEX IF NOT EXISTS idx_documents_orphaned_at ON documents(orphaned_at)
+			WHERE orphaned_at IS NOT NULL;
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply document orphans migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Document orphans migration applied successfully")
+	return nil
+}
+
+// MarkDocumentsOrphaned marque les documents dont le contenu est introuvable. Un document
+// déjà marqué garde sa date de marquage ; retourne le nombre de documents marqués
+func (db *DB) MarkDocumentsOrphaned(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE documents SET orphaned_at = now()
+		WHERE id = ANY($1) AND orphaned_at IS NULL
+	`, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark orphaned documents: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClearDocumentsOrphaned retire le marqueur des documents dont le contenu a été retrouvé
+// (restauration) ; retourne le nombre de documents concernés
+func (db *DB) ClearDocumentsOrphaned(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE documents SET orphaned_at = NULL
+		WHERE id = ANY($1) AND orphaned_at IS NOT NULL
+	`, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear orphaned documents: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
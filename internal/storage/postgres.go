@@ -2,13 +2,13 @@ package storage
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"crypto/x509"
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
 	"github.com/doreviateam/dorevia-vault/internal/models"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -18,8 +18,13 @@ import (
 
 // DB représente le pool de connexions PostgreSQL
 type DB struct {
-	Pool *pgxpool.Pool
-	log  *zerolog.Logger
+	Pool     *pgxpool.Pool
+	log      *zerolog.Logger
+	ledger   ledger.Service   // Service ledger utilisé par StoreDocumentWithEvidence
+	blobs    blobstore.Store  // Stockage du contenu des documents (SetBlobStore)
+	layout   string           // Disposition des clés des nouveaux contenus (SetStorageLayout)
+	envelope *crypto.Envelope // Chiffrement au repos du contenu (SetEnvelope)
+	tsaRoots *x509.CertPool   // Autorités de confiance des TSA (SetTSARoots)
 }
 
 // NewDB crée une nouvelle connexion à PostgreSQL
@@ -39,8 +44,9 @@ func NewDB(ctx context.Context, databaseURL string, log *zerolog.Logger) (*DB, e
 	}
 
 	db := &DB{
-		Pool: pool,
-		log:  log,
+		Pool:   pool,
+		log:    log,
+		ledger: ledger.NewService(),
 	}
 
 	// Migration automatique
@@ -93,6 +99,101 @@ func (db *DB) migrate(ctx context.Context) error {
 		return fmt.Errorf("failed to apply Sprint 6 migration: %w", err)
 	}
 
+	// Migration multi-tenant : colonne tenant sur documents et ledger
+	if err := db.migrateTenant(ctx); err != nil {
+		return fmt.Errorf("failed to apply tenant migration: %w", err)
+	}
+
+	// Migration clés API persistées
+	if err := db.migrateAPIKeys(ctx); err != nil {
+		return fmt.Errorf("failed to apply api_keys migration: %w", err)
+	}
+
+	// Migration clôtures mensuelles (NF525)
+	if err := db.migrateClosings(ctx); err != nil {
+		return fmt.Errorf("failed to apply closings migration: %w", err)
+	}
+
+	// Migration ancrages horodatés du ledger (RFC 3161)
+	if err := db.migrateLedgerAnchors(ctx); err != nil {
+		return fmt.Errorf("failed to apply ledger_anchors migration: %w", err)
+	}
+
+	// Migration racines de Merkle du ledger (preuves d'inclusion)
+	if err := db.migrateLedgerMerkleRoots(ctx); err != nil {
+		return fmt.Errorf("failed to apply ledger_merkle_roots migration: %w", err)
+	}
+
+	// Migration tête de chaîne du ledger (ajouts sans balayage de la table)
+	if err := db.migrateLedgerHead(ctx); err != nil {
+		return fmt.Errorf("failed to apply ledger_head migration: %w", err)
+	}
+
+	// Migration archives des partitions du ledger (cycle de vie des partitions)
+	if err := db.migrateLedgerPartitionArchives(ctx); err != nil {
+		return fmt.Errorf("failed to apply ledger_partition_archives migration: %w", err)
+	}
+
+	// Migration format v2 des entrées du ledger (hash de tous les champs)
+	if err := db.migrateLedgerHashVersion(ctx); err != nil {
+		return fmt.Errorf("failed to apply ledger hash_version migration: %w", err)
+	}
+
+	// Migration entrées typées du ledger (événements du cycle de vie des documents)
+	if err := db.migrateLedgerEntryTypes(ctx); err != nil {
+		return fmt.Errorf("failed to apply ledger entry_type migration: %w", err)
+	}
+
+	// Migration têtes de ledger signées et contre-signatures des témoins
+	if err := db.migrateLedgerTreeHeads(ctx); err != nil {
+		return fmt.Errorf("failed to apply ledger_tree_heads migration: %w", err)
+	}
+
+	// Migration comptage des contenus partagés (disposition par contenu)
+	if err := db.migrateBlobRefs(ctx); err != nil {
+		return fmt.Errorf("failed to apply blob_refs migration: %w", err)
+	}
+
+	// Migration chiffrement au repos du contenu (clés de données enveloppées)
+	if err := db.migrateDocumentEncryption(ctx); err != nil {
+		return fmt.Errorf("failed to apply document encryption migration: %w", err)
+	}
+
+	// Migration rétention : blocage légal, pierres tombales, ledger sans suppression en cascade
+	if err := db.migrateRetention(ctx); err != nil {
+		return fmt.Errorf("failed to apply retention migration: %w", err)
+	}
+
+	// Migration uploads reprenables
+	if err := db.migrateUploadSessions(ctx); err != nil {
+		return fmt.Errorf("failed to apply upload sessions migration: %w", err)
+	}
+
+	// Migration vérification en arrière-plan : curseur de reprise et altérations détectées
+	if err := db.migrateScrub(ctx); err != nil {
+		return fmt.Errorf("failed to apply scrub migration: %w", err)
+	}
+
+	// Migration marqueur des documents orphelins (réconciliation)
+	if err := db.migrateDocumentOrphans(ctx); err != nil {
+		return fmt.Errorf("failed to apply document orphans migration: %w", err)
+	}
+
+	// Migration passage aux chaînes par tenant (entrées antérieures dans la chaîne globale)
+	if err := db.migrateLedgerChainEpoch(ctx); err != nil {
+		return fmt.Errorf("failed to apply ledger chain epoch migration: %w", err)
+	}
+
+	// Migration clés de données des uploads reprenables (morceaux chiffrés)
+	if err := db.migrateUploadSessionKeys(ctx); err != nil {
+		return fmt.Errorf("failed to apply upload session keys migration: %w", err)
+	}
+
+	// Migration date de rétention (date de facture, à défaut date de création)
+	if err := db.migrateRetentionDate(ctx); err != nil {
+		return fmt.Errorf("failed to apply retention date migration: %w", err)
+	}
+
 	db.log.Debug().Msg("Database migrations applied successfully")
 	return nil
 }
@@ -222,6 +323,59 @@ func (db *DB) migrateSprint6(ctx context.Context) error {
 	return nil
 }
 
+// migrateTenant applique la migration multi-tenant (colonne tenant)
+func (db *DB) migrateTenant(ctx context.Context) error {
+	migrationSQL := `
+		-- Preuves d'intégrité (présentes dans 003_add_odoo_fields.sql, absentes des migrations Go)
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS evidence_jws TEXT;
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS ledger_hash TEXT;
+
+		-- Tenant propriétaire (NULL pour les documents hors tenant)
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS tenant TEXT;
+		ALTER TABLE ledger ADD COLUMN IF NOT EXISTS tenant TEXT;
+
+		-- Reprise : les tickets POS portaient déjà le tenant dans payload_json
+		UPDATE documents SET tenant = payload_json->>'tenant'
+		WHERE tenant IS NULL AND source = 'pos' AND payload_json ? 'tenant';
+		UPDATE ledger l SET tenant = d.tenant
+		FROM documents d
+		WHERE l.document_id = d.id AND l.tenant IS NULL AND d.tenant IS NOT NULL;
+
+		-- Index pour filtrage par tenant
+		CREATE INDEX IF NOT EXISTS idx_documents_tenant ON documents(tenant);
+		CREATE INDEX IF NOT EXISTS idx_documents_tenant_sha256 ON documents(tenant, sha256_hex);
+
+		-- Index pour SELECT previous_hash par chaîne de tenant
+		CREATE INDEX IF NOT EXISTS idx_ledger_tenant_ts_id_desc ON ledger(tenant, timestamp DESC, id DESC);
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply tenant migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Tenant migration applied successfully")
+	return nil
+}
+
+// SetLedgerService remplace le service ledger utilisé lors du stockage
+// (ex: chaîne par tenant configurée via LEDGER_PER_TENANT_CHAIN)
+func (db *DB) SetLedgerService(service ledger.Service) {
+	if service != nil {
+		db.ledger = service
+	}
+}
+
+// SetTSARoots définit les autorités de confiance des certificats de TSA (TSA_CA_CERT_PATH)
+// utilisées pour vérifier les ancrages horodatés
+func (db *DB) SetTSARoots(roots *x509.CertPool) {
+	db.tsaRoots = roots
+}
+
+// TSARoots retourne les autorités de confiance des TSA (nil : magasin du système)
+func (db *DB) TSARoots() *x509.CertPool {
+	return db.tsaRoots
+}
+
 // Close ferme le pool de connexions
 func (db *DB) Close() {
 	if db.Pool != nil {
@@ -246,13 +400,19 @@ func (e ErrDocumentExists) Error() string {
 // StoreDocumentWithTransaction stocke un document avec transaction atomique
 // Pattern Transaction Outbox : garantit la cohérence fichier ↔ DB
 func (db *DB) StoreDocumentWithTransaction(ctx context.Context, doc *models.Document, content []byte, storageDir string) error {
-	// 1. Calculer hash avant transaction
-	hash := sha256.Sum256(content)
-	sha256Hex := hex.EncodeToString(hash[:])
+	return db.StoreContentWithTransaction(ctx, doc, BytesContent(content), storageDir)
+}
+
+// StoreContentWithTransaction stocke comme StoreDocumentWithTransaction un contenu
+// éventuellement reçu en flux (SpoolContent), sans le charger en mémoire
+func (db *DB) StoreContentWithTransaction(ctx context.Context, doc *models.Document, content Content, storageDir string) error {
+	// 1. Hash calculé à la réception du contenu
+	sha256Hex := content.SHA256Hex()
+	doc.SizeBytes = content.Size()
 
 	// 2. Vérifier idempotence (SELECT avant transaction)
 	var existingID uuid.UUID
-	err := db.Pool.QueryRow(ctx, "SELECT id FROM documents WHERE sha256_hex = $1 LIMIT 1", sha256Hex).Scan(&existingID)
+	err := db.Pool.QueryRow(ctx, "SELECT id FROM documents WHERE sha256_hex = $1 AND tenant IS NOT DISTINCT FROM $2 LIMIT 1", sha256Hex, doc.Tenant).Scan(&existingID)
 	if err == nil {
 		// Document déjà existant
 		doc.ID = existingID
@@ -263,76 +423,65 @@ func (db *DB) StoreDocumentWithTransaction(ctx context.Context, doc *models.Docu
 		return fmt.Errorf("failed to check existing document: %w", err)
 	}
 
-	// 3. Générer UUID et chemin
+	// 3. Générer UUID et clé de stockage
 	docID := uuid.New()
 	now := time.Now()
-	datePath := filepath.Join(
-		storageDir,
-		fmt.Sprintf("%d", now.Year()),
-		fmt.Sprintf("%02d", now.Month()),
-		fmt.Sprintf("%02d", now.Day()),
-	)
+	blobs := db.BlobStoreFor(storageDir)
 
-	if err := os.MkdirAll(datePath, 0755); err != nil {
-		return fmt.Errorf("failed to create storage directory: %w", err)
+	// 4. Stocker le contenu avant la transaction ; supprimé si elle échoue
+	// (un contenu resté orphelin après un arrêt brutal est détecté par cmd/reconcile)
+	stored, err := db.PutDocumentContent(ctx, blobs, docID, now, doc.Filename, doc.ContentType, content)
+	if err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
 	}
-
-	// 4. Créer chemin temporaire puis final (déplacement après COMMIT)
-	tmpPath := filepath.Join(datePath, fmt.Sprintf("%s-%s.tmp", docID.String(), doc.Filename))
-	finalPath := filepath.Join(datePath, fmt.Sprintf("%s-%s", docID.String(), doc.Filename))
+	storedPath := stored.Ref
 
 	// 5. BEGIN transaction
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
+		db.DiscardDocumentContent(ctx, blobs, storedPath, sha256Hex)
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	// 6. Stocker fichier sur disque (fichier temporaire)
-	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
-		return fmt.Errorf("failed to save file: %w", err)
+	// 6. Référence au contenu partagé (disposition par contenu) : fixe sa clé de données
+	if err := db.RetainDocumentContent(ctx, tx, blobs, stored, content); err != nil {
+		db.DiscardDocumentContent(ctx, blobs, storedPath, sha256Hex)
+		return err
 	}
 
-	// 7. INSERT dans documents (utiliser finalPath pour stored_path)
+	// 7. INSERT dans documents (stored_path = référence du contenu)
 	_, err = tx.Exec(ctx, `
 		INSERT INTO documents (
 			id, filename, content_type, size_bytes, sha256_hex, stored_path,
 			source, odoo_model, odoo_id, odoo_state, pdp_required, dispatch_status,
-			invoice_number, invoice_date, total_ht, total_ttc, currency, seller_vat, buyer_vat
+			invoice_number, invoice_date, total_ht, total_ttc, currency, seller_vat, buyer_vat,
+			tenant, dek_wrapped, kek_version
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
-	`, docID, doc.Filename, doc.ContentType, doc.SizeBytes, sha256Hex, finalPath,
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
+	`, docID, doc.Filename, doc.ContentType, doc.SizeBytes, sha256Hex, storedPath,
 		doc.Source, doc.OdooModel, doc.OdooID, doc.OdooState, doc.PDPRequired, doc.DispatchStatus,
-		doc.InvoiceNumber, doc.InvoiceDate, doc.TotalHT, doc.TotalTTC, doc.Currency, doc.SellerVAT, doc.BuyerVAT)
+		doc.InvoiceNumber, doc.InvoiceDate, doc.TotalHT, doc.TotalTTC, doc.Currency, doc.SellerVAT, doc.BuyerVAT,
+		doc.Tenant, stored.WrappedKey, stored.KEKVersion)
 
 	if err != nil {
-		// Nettoyage fichier temporaire en cas d'erreur
-		os.Remove(tmpPath)
+		// Nettoyage du contenu en cas d'erreur
+		db.DiscardDocumentContent(ctx, blobs, storedPath, sha256Hex)
 		return fmt.Errorf("failed to insert document: %w", err)
 	}
 
 	// 8. COMMIT
 	if err := tx.Commit(ctx); err != nil {
-		os.Remove(tmpPath) // Nettoyage fichier temporaire
+		db.DiscardDocumentContent(ctx, blobs, storedPath, sha256Hex)
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	// 9. Déplacer fichier temporaire vers final (après COMMIT réussi)
-	if err := os.Rename(tmpPath, finalPath); err != nil {
-		// Si le move échoue après COMMIT, on a une incohérence
-		// On log l'erreur mais on ne rollback pas (déjà commité)
-		db.log.Error().
-			Err(err).
-			Str("tmp_path", tmpPath).
-			Str("final_path", finalPath).
-			Msg("Failed to move file after commit - manual cleanup required")
-		return fmt.Errorf("failed to move file after commit: %w", err)
-	}
-
 	// Mettre à jour le document avec les valeurs finales
 	doc.ID = docID
 	doc.SHA256Hex = sha256Hex
-	doc.StoredPath = finalPath
+	doc.StoredPath = storedPath
+	doc.WrappedKey = stored.WrappedKey
+	doc.KEKVersion = stored.KEKVersion
 	doc.CreatedAt = now
 
 	db.log.Info().
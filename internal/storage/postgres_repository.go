@@ -15,8 +15,10 @@ import (
 
 // PostgresRepository implémente DocumentRepository pour PostgreSQL
 type PostgresRepository struct {
-	pool *pgxpool.Pool
-	log  *zerolog.Logger
+	pool       *pgxpool.Pool
+	log        *zerolog.Logger
+	db         *DB    // stockage du contenu (NewDocumentRepository), nil : documents sans fichier
+	storageDir string // STORAGE_DIR du stockage fichier par défaut
 }
 
 // NewPostgresRepository crée un nouveau repository PostgreSQL
@@ -28,7 +30,7 @@ func NewPostgresRepository(pool *pgxpool.Pool, log *zerolog.Logger) *PostgresRep
 }
 
 // GetDocumentBySHA256 récupère un document par son hash SHA256
-func (r *PostgresRepository) GetDocumentBySHA256(ctx context.Context, sha256Hex string) (*models.Document, error) {
+func (r *PostgresRepository) GetDocumentBySHA256(ctx context.Context, tenant, sha256Hex string) (*models.Document, error) {
 	var doc models.Document
 	var payloadJSON []byte
 
@@ -38,11 +40,12 @@ func (r *PostgresRepository) GetDocumentBySHA256(ctx context.Context, sha256Hex
 			source, odoo_model, odoo_id, odoo_state, pdp_required, dispatch_status,
 			invoice_number, invoice_date, total_ht, total_ttc, currency, seller_vat, buyer_vat,
 			evidence_jws, ledger_hash,
-			source_id_text, payload_json, pos_session, cashier, location
+			source_id_text, payload_json, pos_session, cashier, location,
+			tenant
 		FROM documents
-		WHERE sha256_hex = $1
+		WHERE sha256_hex = $1 AND tenant IS NOT DISTINCT FROM $2
 		LIMIT 1
-	`, sha256Hex).Scan(
+	`, sha256Hex, nullableString(tenant)).Scan(
 		&doc.ID,
 		&doc.Filename,
 		&doc.ContentType,
@@ -71,6 +74,7 @@ func (r *PostgresRepository) GetDocumentBySHA256(ctx context.Context, sha256Hex
 		&doc.PosSession,
 		&doc.Cashier,
 		&doc.Location,
+		&doc.Tenant,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -112,21 +116,49 @@ func (r *PostgresRepository) InsertDocumentWithEvidence(
 	}
 	defer tx.Rollback(txCtx)
 
+	if err := insertDocumentWithEvidenceTx(txCtx, tx, doc, evidenceJWS, ledgerService); err != nil {
+		return err
+	}
+
+	// COMMIT
+	if err := tx.Commit(txCtx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.log.Info().
+		Str("document_id", doc.ID.String()).
+		Str("sha256", doc.SHA256Hex).
+		Bool("jws_generated", evidenceJWS != "").
+		Bool("ledger_appended", doc.LedgerHash != nil).
+		Msg("Document inserted with evidence via repository")
+
+	return nil
+}
+
+// insertDocumentWithEvidenceTx insère le document, l'ajoute au ledger et enregistre
+// evidence_jws et ledger_hash dans la transaction tx (sans COMMIT)
+func insertDocumentWithEvidenceTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	doc *models.Document,
+	evidenceJWS string,
+	ledgerService ledger.Service,
+) error {
 	// 1. INSERT dans documents (sans evidence_jws et ledger_hash pour l'instant)
-	_, err = tx.Exec(txCtx, `
+	_, err := tx.Exec(ctx, `
 		INSERT INTO documents (
 			id, filename, content_type, size_bytes, sha256_hex, stored_path,
 			source, odoo_model, odoo_id, odoo_state, pdp_required, dispatch_status,
 			invoice_number, invoice_date, total_ht, total_ttc, currency, seller_vat, buyer_vat,
 			source_id_text, payload_json, pos_session, cashier, location,
-			created_at
+			created_at, tenant, dek_wrapped, kek_version
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28)
 	`, doc.ID, doc.Filename, doc.ContentType, doc.SizeBytes, doc.SHA256Hex, doc.StoredPath,
 		doc.Source, doc.OdooModel, doc.OdooID, doc.OdooState, doc.PDPRequired, doc.DispatchStatus,
 		doc.InvoiceNumber, doc.InvoiceDate, doc.TotalHT, doc.TotalTTC, doc.Currency, doc.SellerVAT, doc.BuyerVAT,
 		doc.SourceIDText, doc.PayloadJSON, doc.PosSession, doc.Cashier, doc.Location,
-		doc.CreatedAt)
+		doc.CreatedAt, doc.Tenant, doc.WrappedKey, doc.KEKVersion)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert document: %w", err)
@@ -135,7 +167,7 @@ func (r *PostgresRepository) InsertDocumentWithEvidence(
 	// 2. Ajouter au ledger (via interface)
 	var ledgerHash string
 	if ledgerService != nil {
-		ledgerHash, err = ledgerService.Append(txCtx, tx, doc.ID, doc.SHA256Hex, evidenceJWS)
+		ledgerHash, err = ledgerService.Append(ctx, tx, derefString(doc.Tenant), doc.ID, doc.SHA256Hex, evidenceJWS)
 		if err != nil {
 			return fmt.Errorf("failed to append to ledger: %w", err)
 		}
@@ -143,7 +175,7 @@ func (r *PostgresRepository) InsertDocumentWithEvidence(
 
 	// 3. UPDATE documents avec evidence_jws et ledger_hash
 	if evidenceJWS != "" || ledgerHash != "" {
-		_, err = tx.Exec(txCtx, `
+		_, err = tx.Exec(ctx, `
 			UPDATE documents 
 			SET evidence_jws = $1, ledger_hash = $2
 			WHERE id = $3
@@ -161,18 +193,22 @@ func (r *PostgresRepository) InsertDocumentWithEvidence(
 		}
 	}
 
-	// 4. COMMIT
-	if err := tx.Commit(txCtx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
+	return nil
+}
 
-	r.log.Info().
-		Str("document_id", doc.ID.String()).
-		Str("sha256", doc.SHA256Hex).
-		Bool("jws_generated", evidenceJWS != "").
-		Bool("ledger_appended", ledgerHash != "").
-		Msg("Document inserted with evidence via repository")
 
-	return nil
+// nullableString convertit une chaîne vide en NULL SQL
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
 
+// derefString retourne la valeur pointée ou ""
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
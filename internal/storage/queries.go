@@ -45,6 +45,13 @@ func (db *DB) ListDocuments(ctx context.Context, query models.DocumentQuery) ([]
 		argIndex++
 	}
 
+	// Filtre par tenant (isolation multi-tenant)
+	if query.Tenant != "" {
+		whereClauses = append(whereClauses, fmt.Sprintf("tenant = $%d", argIndex))
+		args = append(args, query.Tenant)
+		argIndex++
+	}
+
 	whereSQL := strings.Join(whereClauses, " AND ")
 
 	// Compter le total
@@ -70,7 +77,7 @@ func (db *DB) ListDocuments(ctx context.Context, query models.DocumentQuery) ([]
 	}
 
 	selectSQL := fmt.Sprintf(`
-		SELECT id, filename, content_type, size_bytes, sha256_hex, stored_path, created_at
+		SELECT id, filename, content_type, size_bytes, sha256_hex, stored_path, created_at, tenant
 		FROM documents
 		WHERE %s
 		ORDER BY created_at DESC
@@ -96,6 +103,7 @@ func (db *DB) ListDocuments(ctx context.Context, query models.DocumentQuery) ([]
 			&doc.SHA256Hex,
 			&doc.StoredPath,
 			&doc.CreatedAt,
+			&doc.Tenant,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan document: %w", err)
@@ -117,7 +125,7 @@ func (db *DB) GetDocumentByID(ctx context.Context, id uuid.UUID) (*models.Docume
 		SELECT id, filename, content_type, size_bytes, sha256_hex, stored_path, created_at,
 		       source, odoo_model, odoo_id, odoo_state, pdp_required, dispatch_status,
 		       invoice_number, invoice_date, total_ht, total_ttc, currency, seller_vat, buyer_vat,
-		       evidence_jws, ledger_hash
+		       evidence_jws, ledger_hash, tenant, legal_hold, purged_at, purge_reason, orphaned_at
 		FROM documents
 		WHERE id = $1
 	`, id).Scan(
@@ -143,6 +151,11 @@ func (db *DB) GetDocumentByID(ctx context.Context, id uuid.UUID) (*models.Docume
 		&doc.BuyerVAT,
 		&doc.EvidenceJWS,
 		&doc.LedgerHash,
+		&doc.Tenant,
+		&doc.LegalHold,
+		&doc.PurgedAt,
+		&doc.PurgeReason,
+		&doc.OrphanedAt,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -155,6 +168,22 @@ func (db *DB) GetDocumentByID(ctx context.Context, id uuid.UUID) (*models.Docume
 	return &doc, nil
 }
 
+// GetDocumentPayload récupère le payload JSON canonique d'un document stocké en base
+// (tickets POS) ; nil si le document n'a pas de payload
+func (db *DB) GetDocumentPayload(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	var payload []byte
+	err := db.Pool.QueryRow(ctx, `
+		SELECT payload_json FROM documents WHERE id = $1
+	`, id).Scan(&payload)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("document not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document payload: %w", err)
+	}
+	return payload, nil
+}
+
 // CalculatePages calcule le nombre de pages total
 func CalculatePages(total, limit int) int {
 	if limit <= 0 {
@@ -11,7 +11,8 @@ import (
 // Interface pour abstraction de la couche de stockage (Sprint 6)
 type DocumentRepository interface {
 	// GetDocumentBySHA256 récupère un document par son hash SHA256
-	GetDocumentBySHA256(ctx context.Context, sha256 string) (*models.Document, error)
+	// L'idempotence est évaluée dans le périmètre du tenant ("" = documents hors tenant)
+	GetDocumentBySHA256(ctx context.Context, tenant, sha256 string) (*models.Document, error)
 
 	// InsertDocumentWithEvidence insère un document avec evidence JWS et ledger hash
 	// Gère la transaction en interne, inclut l'ajout au ledger
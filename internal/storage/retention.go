@@ -0,0 +1,331 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrLegalHold est retourné quand un document sous blocage légal devrait être purgé
+var ErrLegalHold = errors.New("document is under legal hold")
+
+// ErrDocumentPurged est retourné quand le contenu d'un document a été purgé (rétention échue)
+var ErrDocumentPurged = errors.New("document content has been purged")
+
+// migrateRetention ajoute le blocage légal et la pierre tombale des documents purgés, et
+// remplace la suppression en cascade des entrées du ledger par une clé étrangère restrictive
+func (db *DB) migrateRetention(ctx context.Context) error {
+	migrationSQL := `
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold_reason TEXT;
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold_by TEXT;
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold_at TIMESTAMPTZ;
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS purged_at TIMESTAMPTZ;
+		ALTER TABLE documents ADD COLUMN IF NOT EXISTS purge_reason TEXT;
+
+		-- Blocage légal : ni suppression ni purge
+		CREATE OR REPLACE FUNCTION documents_legal_hold_guard() RETURNS trigger AS $$
+		BEGIN
+			IF OLD.legal_hold AND (TG_OP = 'DELETE' OR (NEW.purged_at IS NOT NULL AND OLD.purged_at IS NULL)) THEN
+				RAISE EXCEPTION 'document % is under legal hold', OLD.id;
+			END IF;
+			IF TG_OP = 'DELETE' THEN
+				RETURN OLD;
+			END IF;
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS trg_documents_legal_hold ON documents;
+		CREATE TRIGGER trg_documents_legal_hold BEFORE UPDATE OR DELETE ON documents
+			FOR EACH ROW EXECUTE FUNCTION documents_legal_hold_guard();
+
+		-- Le ledger ne perd jamais d'entrée : suppression d'un document référencé refusée
+		DO $$
+		DECLARE
+			fk RECORD;
+		BEGIN
+			FOR fk IN
+				SELECT conname FROM pg_constraint
+				WHERE conrelid = 'ledger'::regclass AND contype = 'f'
+				  AND confrelid = 'documents'::regclass AND confdeltype <> 'r'
+				  AND conparentid = 0
+			LOOP
+				EXECUTE format('ALTER TABLE ledger DROP CONSTRAINT %I', fk.conname);
+				EXECUTE format('ALTER TABLE ledger ADD CONSTRAINT %I FOREIGN KEY (document_id) REFERENCES documents(id) ON DELETE RESTRICT', fk.conname);
+			END LOOP;
+		END $$;
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply retention migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Retention migration applied successfully")
+	return nil
+}
+
+// LegalHoldUpdate décrit la pose ou la levée d'un blocage légal
+type LegalHoldUpdate struct {
+	Hold   bool
+	Reason string
+	Actor  string // auteur du changement
+	Tenant string // tenant de l'appelant ("" = accès non restreint)
+}
+
+// SetLegalHold pose ou lève le blocage légal d'un document. Un document purgé ne peut
+// plus être bloqué
+func (db *DB) SetLegalHold(ctx context.Context, id uuid.UUID, update LegalHoldUpdate) (*models.Document, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var tenant *string
+	var purgedAt *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT tenant, purged_at FROM documents WHERE id = $1 FOR UPDATE
+	`, id).Scan(&tenant, &purgedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock document: %w", err)
+	}
+	if update.Tenant != "" && (tenant == nil || *tenant != update.Tenant) {
+		return nil, ErrDocumentNotFound
+	}
+	if purgedAt != nil && update.Hold {
+		return nil, ErrDocumentPurged
+	}
+
+	if update.Hold {
+		_, err = tx.Exec(ctx, `
+			UPDATE documents SET legal_hold = true, legal_hold_reason = $2, legal_hold_by = $3, legal_hold_at = now()
+			WHERE id = $1
+		`, id, nullIfEmpty(update.Reason), nullIfEmpty(update.Actor))
+	} else {
+		_, err = tx.Exec(ctx, `
+			UPDATE documents SET legal_hold = false, legal_hold_reason = NULL, legal_hold_by = NULL, legal_hold_at = NULL
+			WHERE id = $1
+		`, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update legal hold: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return db.GetDocumentByID(ctx, id)
+}
+
+// RetentionMatch sélectionne les documents d'une règle de rétention (champ vide : tous)
+type RetentionMatch struct {
+	Source    string
+	OdooModel string
+}
+
+func (m RetentionMatch) condition(args *[]interface{}) string {
+	var conds []string
+	if m.Source != "" {
+		*args = append(*args, m.Source)
+		conds = append(conds, fmt.Sprintf("COALESCE(source, '') = $%d", len(*args)))
+	}
+	if m.OdooModel != "" {
+		*args = append(*args, m.OdooModel)
+		conds = append(conds, fmt.Sprintf("COALESCE(odoo_model, '') = $%d", len(*args)))
+	}
+	if len(conds) == 0 {
+		return "true"
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// retentionDateExpr est la date à partir de laquelle court la conservation d'un document :
+// la date de la facture, à défaut la date de réception (UTC). Expression immuable, indexée
+// par idx_documents_retention_date
+const retentionDateExpr = `COALESCE(invoice_date::timestamp, created_at AT TIME ZONE 'UTC')`
+
+// migrateRetentionDate indexe les documents à purger sur leur date de rétention
+// (date de facture, à défaut date de création) et leur id (pagination par curseur)
+func (db *DB) migrateRetentionDate(ctx context.Context) error {
+	migrationSQL := `
+		DROP INDEX IF EXISTS idx_documents_retention;
+		CREATE INDEX IF NOT EXISTS idx_documents_retention_date
+			ON documents ((` + retentionDateExpr + `), id)
+			WHERE purged_at IS NULL AND NOT legal_hold;
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply retention date migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Retention date migration applied successfully")
+	return nil
+}
+
+// ExpiredDocument est un document dont la durée de conservation est échue
+type ExpiredDocument struct {
+	models.Document
+	RetentionDate time.Time // date de facture, à défaut date de création
+}
+
+// RetentionCursor reprend la recherche des documents échus après un document déjà vu
+type RetentionCursor struct {
+	RetentionDate time.Time
+	ID            uuid.UUID
+}
+
+// Cursor retourne le curseur qui reprend la recherche après ce document
+func (d ExpiredDocument) Cursor() *RetentionCursor {
+	return &RetentionCursor{RetentionDate: d.RetentionDate, ID: d.ID}
+}
+
+// ListExpiredDocuments liste, par date de rétention croissante, les documents de match
+// dont la date de facture (à défaut la date de création) précède before, ni purgés ni sous
+// blocage légal ; exclude écarte les documents relevant d'une règle plus précise. after
+// reprend la liste après un document déjà vu (nil : depuis le début). limit 0 : tous
+func (db *DB) ListExpiredDocuments(ctx context.Context, match RetentionMatch, exclude []RetentionMatch, before time.Time, after *RetentionCursor, limit int) ([]ExpiredDocument, error) {
+	args := []interface{}{before}
+	where := []string{"purged_at IS NULL", "NOT legal_hold", retentionDateExpr + " < ($1::timestamptz AT TIME ZONE 'UTC')", match.condition(&args)}
+	for _, ex := range exclude {
+		where = append(where, "NOT ("+ex.condition(&args)+")")
+	}
+	if after != nil {
+		args = append(args, after.RetentionDate, after.ID)
+		where = append(where, fmt.Sprintf("(%s, id) > ($%d::timestamptz AT TIME ZONE 'UTC', $%d)", retentionDateExpr, len(args)-1, len(args)))
+	}
+	query := `
+		SELECT id, filename, COALESCE(size_bytes, 0), sha256_hex, stored_path, created_at, source, odoo_model, tenant,
+		       ` + retentionDateExpr + ` AT TIME ZONE 'UTC'
+		FROM documents
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY ` + retentionDateExpr + ` ASC, id ASC`
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired documents: %w", err)
+	}
+	defer rows.Close()
+
+	var documents []ExpiredDocument
+	for rows.Next() {
+		var doc ExpiredDocument
+		if err := rows.Scan(&doc.ID, &doc.Filename, &doc.SizeBytes, &doc.SHA256Hex, &doc.StoredPath, &doc.CreatedAt, &doc.Source, &doc.OdooModel, &doc.Tenant, &doc.RetentionDate); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		documents = append(documents, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+	return documents, nil
+}
+
+// PurgeResult décrit la purge d'un document
+type PurgeResult struct {
+	LedgerHash     string // entrée document.purged
+	ContentDeleted bool   // objet supprimé (un contenu partagé encore référencé est conservé)
+}
+
+// PurgeDocument supprime le contenu d'un document et n'en garde qu'une pierre tombale
+// (métadonnées, SHA256, preuve JWS). La purge est inscrite dans le ledger
+// (document.purged) dans la même transaction ; le contenu est supprimé ensuite : un échec
+// laisse un objet orphelin détecté par cmd/reconcile
+func (db *DB) PurgeDocument(ctx context.Context, id uuid.UUID, reason, actor string) (*PurgeResult, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var tenant *string
+	var sha256Hex, storedPath string
+	var legalHold bool
+	var purgedAt *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT tenant, sha256_hex, COALESCE(stored_path, ''), legal_hold, purged_at
+		FROM documents WHERE id = $1 FOR UPDATE
+	`, id).Scan(&tenant, &sha256Hex, &storedPath, &legalHold, &purgedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock document: %w", err)
+	}
+	if legalHold {
+		return nil, ErrLegalHold
+	}
+	if purgedAt != nil {
+		return nil, ErrDocumentPurged
+	}
+
+	result := &PurgeResult{}
+	result.LedgerHash, err = db.ledger.AppendEvent(ctx, tx, derefString(tenant), ledger.Event{
+		Type:       ledger.EntryTypeDocumentPurged,
+		DocumentID: &id,
+		Body:       ledger.DocumentPurgedBody{SHA256Hex: sha256Hex, Reason: reason, Actor: actor},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to append purge to ledger: %w", err)
+	}
+
+	// Pierre tombale : contenu, payload et clé de données retirés
+	if _, err := tx.Exec(ctx, `
+		UPDATE documents
+		SET purged_at = now(), purge_reason = $2, stored_path = '', payload_json = NULL,
+		    dek_wrapped = NULL, kek_version = NULL
+		WHERE id = $1
+	`, id, nullIfEmpty(reason)); err != nil {
+		return nil, fmt.Errorf("failed to purge document: %w", err)
+	}
+
+	shared := storedPath != "" && blobstore.IsContentRef(storedPath, sha256Hex)
+	if shared {
+		if _, err := tx.Exec(ctx, `
+			UPDATE blob_refs SET ref_count = GREATEST(ref_count - 1, 0) WHERE ref = $1
+		`, storedPath); err != nil {
+			return nil, fmt.Errorf("failed to release content: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if storedPath == "" {
+		return result, nil
+	}
+	blobs := db.BlobStore()
+	if shared {
+		result.ContentDeleted, err = db.DeleteUnreferencedBlob(ctx, blobs, storedPath, sha256Hex)
+	} else {
+		err = blobs.Delete(ctx, storedPath)
+		result.ContentDeleted = err == nil
+	}
+	if err != nil {
+		db.log.Warn().Err(err).Str("document_id", id.String()).Str("stored_path", storedPath).Msg("Failed to delete purged content")
+	}
+	return result, nil
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// migrateScrub crée l'avancement de la vérification en arrière-plan et le registre des
+// altérations détectées
+func (db *DB) migrateScrub(ctx context.Context) error {
+	migrationSQL := `
+		CREATE TABLE IF NOT EXISTS scrub_state (
+			id                     SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+			cursor_created_at      TIMESTAMPTZ,
+			cursor_id              UUID,
+			pass_started_at        TIMESTAMPTZ,
+			passes_completed       BIGINT NOT NULL DEFAULT 0,
+			last_pass_completed_at TIMESTAMPTZ,
+			updated_at             TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+
+		CREATE TABLE IF NOT EXISTS scrub_corruptions (
+			document_id     UUID PRIMARY KEY REFERENCES documents(id) ON DELETE CASCADE,
+			tenant          TEXT,
+			kind            TEXT NOT NULL,
+			stored_path     TEXT,
+			expected_sha256 TEXT NOT NULL,
+			actual_sha256   TEXT,
+			detail          TEXT,
+			detected_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+			last_seen_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+			resolved_at     TIMESTAMPTZ
+		);
+
+		-- Altérations non résolues (gauge, alertes)
+		CREATE INDEX IF NOT EXISTS idx_scrub_corruptions_open ON scrub_corruptions(detected_at)
+			WHERE resolved_at IS NULL;
+
+		-- Parcours des documents par curseur (created_at, id)
+		CREATE INDEX IF NOT EXISTS idx_documents_scrub ON documents(created_at, id)
+			WHERE purged_at IS NULL;
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply scrub migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Scrub migration applied successfully")
+	return nil
+}
+
+// GetScrubState retourne l'avancement de la vérification en arrière-plan (état vide
+// avant la première passe)
+func (db *DB) GetScrubState(ctx context.Context) (*models.ScrubState, error) {
+	var s models.ScrubState
+	err := db.Pool.QueryRow(ctx, `
+		SELECT cursor_created_at, cursor_id, pass_started_at, passes_completed, last_pass_completed_at
+		FROM scrub_state WHERE id = 1
+	`).Scan(&s.CursorCreatedAt, &s.CursorID, &s.PassStartedAt, &s.PassesCompleted, &s.LastPassCompletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scrub state: %w", err)
+	}
+	return &s, nil
+}
+
+// SaveScrubCursor enregistre le dernier document vérifié de la passe en cours, qui
+// reprend à ce document après un redémarrage
+func (db *DB) SaveScrubCursor(ctx context.Context, last *models.Document) error {
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO scrub_state (id, cursor_created_at, cursor_id, pass_started_at)
+		VALUES (1, $1, $2, now())
+		ON CONFLICT (id) DO UPDATE SET
+			cursor_created_at = EXCLUDED.cursor_created_at,
+			cursor_id = EXCLUDED.cursor_id,
+			pass_started_at = COALESCE(scrub_state.pass_started_at, now()),
+			updated_at = now()
+	`, last.CreatedAt, last.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save scrub cursor: %w", err)
+	}
+	return nil
+}
+
+// CompleteScrubPass termine la passe en cours : le curseur est vidé et la passe suivante
+// reprend au premier document
+func (db *DB) CompleteScrubPass(ctx context.Context) (*models.ScrubState, error) {
+	var s models.ScrubState
+	err := db.Pool.QueryRow(ctx, `
+		INSERT INTO scrub_state (id, passes_completed, last_pass_completed_at)
+		VALUES (1, 1, now())
+		ON CONFLICT (id) DO UPDATE SET
+			cursor_created_at = NULL,
+			cursor_id = NULL,
+			pass_started_at = NULL,
+			passes_completed = scrub_state.passes_completed + 1,
+			last_pass_completed_at = now(),
+			updated_at = now()
+		RETURNING passes_completed, last_pass_completed_at
+	`).Scan(&s.PassesCompleted, &s.LastPassCompletedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete scrub pass: %w", err)
+	}
+	return &s, nil
+}
+
+// ListScrubDocuments liste les documents à vérifier après after (nil : depuis le début),
+// par ordre de création. Les documents purgés (rétention échue) n'ont plus de contenu et
+// sont ignorés ; le payload n'est chargé que pour les documents sans contenu stocké (POS)
+func (db *DB) ListScrubDocuments(ctx context.Context, after *models.Document, limit int) ([]models.Document, error) {
+	args := []interface{}{}
+	where := []string{"purged_at IS NULL"}
+	if after != nil {
+		args = append(args, after.CreatedAt, after.ID)
+		where = append(where, "(created_at, id) > ($1, $2)")
+	}
+	query := `
+		SELECT id, filename, COALESCE(size_bytes, 0), sha256_hex, COALESCE(stored_path, ''), created_at, source, tenant,
+			CASE WHEN COALESCE(stored_path, '') = '' THEN payload_json END
+		FROM documents
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY created_at ASC, id ASC`
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents to scrub: %w", err)
+	}
+	defer rows.Close()
+
+	var documents []models.Document
+	for rows.Next() {
+		var doc models.Document
+		if err := rows.Scan(&doc.ID, &doc.Filename, &doc.SizeBytes, &doc.SHA256Hex, &doc.StoredPath, &doc.CreatedAt, &doc.Source, &doc.Tenant, &doc.PayloadJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		documents = append(documents, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+	return documents, nil
+}
+
+// RecordScrubCorruption enregistre l'altération d'un document. Retourne true si
+// l'altération est nouvelle (première détection, ou nouvelle détection après résolution),
+// false si elle était déjà ouverte
+func (db *DB) RecordScrubCorruption(ctx context.Context, c *models.ScrubCorruption) (bool, error) {
+	var opened bool
+	err := db.Pool.QueryRow(ctx, `
+		WITH previous AS (
+			SELECT resolved_at IS NULL AS was_open FROM scrub_corruptions WHERE document_id = $1
+		)
+		INSERT INTO scrub_corruptions (document_id, tenant, kind, stored_path, expected_sha256, actual_sha256, detail)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (document_id) DO UPDATE SET
+			kind = EXCLUDED.kind,
+			stored_path = EXCLUDED.stored_path,
+			expected_sha256 = EXCLUDED.expected_sha256,
+			actual_sha256 = EXCLUDED.actual_sha256,
+			detail = EXCLUDED.detail,
+			detected_at = CASE WHEN scrub_corruptions.resolved_at IS NULL THEN scrub_corruptions.detected_at ELSE now() END,
+			last_seen_at = now(),
+			resolved_at = NULL
+		RETURNING NOT COALESCE((SELECT was_open FROM previous), false), detected_at, last_seen_at
+	`, c.DocumentID, c.Tenant, c.Kind, c.StoredPath, c.ExpectedSHA, c.ActualSHA, c.Detail).Scan(&opened, &c.DetectedAt, &c.LastSeenAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to record scrub corruption: %w", err)
+	}
+	c.ResolvedAt = nil
+	return opened, nil
+}
+
+// ResolveScrubCorruptions lève les altérations ouvertes des documents relus conformes.
+// Retourne le nombre d'altérations levées
+func (db *DB) ResolveScrubCorruptions(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	tag, err := db.Pool.Exec(ctx, `
+		UPDATE scrub_corruptions SET resolved_at = now()
+		WHERE document_id = ANY($1) AND resolved_at IS NULL
+	`, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve scrub corruptions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CountOpenScrubCorruptions compte les altérations non résolues des documents non purgés
+func (db *DB) CountOpenScrubCorruptions(ctx context.Context) (int, error) {
+	var count int
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM scrub_corruptions c
+		JOIN documents d ON d.id = c.document_id
+		WHERE c.resolved_at IS NULL AND d.purged_at IS NULL
+	`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count scrub corruptions: %w", err)
+	}
+	return count, nil
+}
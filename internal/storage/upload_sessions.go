@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUploadSessionNotFound est retourné quand un upload reprenable n'existe pas (ou a expiré)
+var ErrUploadSessionNotFound = errors.New("upload session not found")
+
+// migrateUploadSessions crée la table des uploads reprenables
+func (db *DB) migrateUploadSessions(ctx context.Context) error {
+	migrationSQL := `
+		CREATE TABLE IF NOT EXISTS upload_sessions (
+			id           UUID PRIMARY KEY,
+			tenant       TEXT,
+			size_bytes   BIGINT NOT NULL CHECK (size_bytes > 0),
+			sha256_hex   TEXT NOT NULL,
+			offset_bytes BIGINT NOT NULL DEFAULT 0 CHECK (offset_bytes >= 0 AND offset_bytes <= size_bytes),
+			status       TEXT NOT NULL DEFAULT 'open',
+			metadata     JSONB NOT NULL DEFAULT '{}',
+			document_id  UUID,
+			created_by   TEXT,
+			created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+			updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+			expires_at   TIMESTAMPTZ NOT NULL
+		);
+
+		-- Purge des uploads abandonnés
+		CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires_at ON upload_sessions(expires_at);
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply upload_sessions migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Upload sessions migration applied successfully")
+	return nil
+}
+
+// migrateUploadSessionKeys ajoute la clé de données des morceaux d'un upload reprenable
+func (db *DB) migrateUploadSessionKeys(ctx context.Context) error {
+	migrationSQL := `
+		ALTER TABLE upload_sessions ADD COLUMN IF NOT EXISTS dek_wrapped TEXT;
+		ALTER TABLE upload_sessions ADD COLUMN IF NOT EXISTS kek_version TEXT;
+	`
+
+	if _, err := db.Pool.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("failed to apply upload session keys migration: %w", err)
+	}
+
+	db.log.Debug().Msg("Upload session keys migration applied successfully")
+	return nil
+}
+
+const uploadSessionColumns = `id, tenant, size_bytes, sha256_hex, offset_bytes, status, metadata,
+	document_id, created_by, created_at, updated_at, expires_at, dek_wrapped, kek_version`
+
+func scanUploadSession(row pgx.Row) (*models.UploadSession, error) {
+	var s models.UploadSession
+	err := row.Scan(&s.ID, &s.Tenant, &s.SizeBytes, &s.SHA256Hex, &s.OffsetBytes, &s.Status, &s.Metadata,
+		&s.DocumentID, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt, &s.ExpiresAt, &s.WrappedKey, &s.KEKVersion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUploadSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan upload session: %w", err)
+	}
+	return &s, nil
+}
+
+// CreateUploadSession enregistre un nouvel upload reprenable
+func (db *DB) CreateUploadSession(ctx context.Context, s *models.UploadSession) error {
+	if s.Status == "" {
+		s.Status = models.UploadStatusOpen
+	}
+	err := db.Pool.QueryRow(ctx, `
+		INSERT INTO upload_sessions (id, tenant, size_bytes, sha256_hex, status, metadata, created_by, expires_at,
+		                             dek_wrapped, kek_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING created_at, updated_at
+	`, s.ID, s.Tenant, s.SizeBytes, s.SHA256Hex, s.Status, s.Metadata, s.CreatedBy, s.ExpiresAt,
+		s.WrappedKey, s.KEKVersion).Scan(&s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create upload session: %w", err)
+	}
+	return nil
+}
+
+// SealUploadSession génère la clé de données des morceaux d'un nouvel upload si le
+// chiffrement est activé (s reçoit la clé enveloppée) ; retourne nil sinon
+func (db *DB) SealUploadSession(ctx context.Context, s *models.UploadSession) ([]byte, error) {
+	sealed, err := db.sealContent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.WrappedKey, s.KEKVersion = sealed.WrappedKey, sealed.KEKVersion
+	return sealed.dek, nil
+}
+
+// UploadSessionKey retourne la clé de données des morceaux d'un upload (nil : en clair)
+func (db *DB) UploadSessionKey(ctx context.Context, s *models.UploadSession) ([]byte, error) {
+	return db.dataKey(ctx, s.WrappedKey, s.KEKVersion)
+}
+
+// GetUploadSession retourne un upload reprenable non expiré. tenant non vide restreint
+// la recherche aux uploads de ce tenant
+func (db *DB) GetUploadSession(ctx context.Context, id uuid.UUID, tenant string) (*models.UploadSession, error) {
+	return scanUploadSession(db.Pool.QueryRow(ctx, `
+		SELECT `+uploadSessionColumns+` FROM upload_sessions
+		WHERE id = $1 AND expires_at > now() AND ($2 = '' OR tenant = $2)
+	`, id, tenant))
+}
+
+// UpdateUploadSession verrouille un upload reprenable le temps de fn, puis enregistre son
+// avancement (offset, statut, document, expiration). Une erreur de fn annule la mise à jour
+func (db *DB) UpdateUploadSession(ctx context.Context, id uuid.UUID, tenant string, fn func(s *models.UploadSession) error) (*models.UploadSession, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	s, err := scanUploadSession(tx.QueryRow(ctx, `
+		SELECT `+uploadSessionColumns+` FROM upload_sessions
+		WHERE id = $1 AND expires_at > now() AND ($2 = '' OR tenant = $2)
+		FOR UPDATE
+	`, id, tenant))
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(s); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE upload_sessions
+		SET offset_bytes = $2, status = $3, document_id = $4, expires_at = $5, updated_at = now()
+		WHERE id = $1
+		RETURNING updated_at
+	`, id, s.OffsetBytes, s.Status, s.DocumentID, s.ExpiresAt).Scan(&s.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update upload session: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s, nil
+}
+
+// DeleteUploadSession supprime un upload reprenable (tenant non vide : uploads de ce tenant)
+func (db *DB) DeleteUploadSession(ctx context.Context, id uuid.UUID, tenant string) error {
+	tag, err := db.Pool.Exec(ctx, `
+		DELETE FROM upload_sessions WHERE id = $1 AND ($2 = '' OR tenant = $2)
+	`, id, tenant)
+	if err != nil {
+		return fmt.Errorf("failed to delete upload session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUploadSessionNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredUploadSessions supprime les uploads reprenables expirés avant before et
+// retourne leurs identifiants (fichiers temporaires à supprimer)
+func (db *DB) DeleteExpiredUploadSessions(ctx context.Context, before time.Time) ([]uuid.UUID, error) {
+	rows, err := db.Pool.Query(ctx, `DELETE FROM upload_sessions WHERE expires_at <= $1 RETURNING id`, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete expired upload sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan upload session: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating upload sessions: %w", err)
+	}
+	return ids, nil
+}
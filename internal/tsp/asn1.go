@@ -0,0 +1,157 @@
+package tsp
+
+import (
+	"crypto"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Identifiants d'objets utilisés par RFC 3161 et CMS (RFC 5652)
+var (
+	OIDSignedData         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	OIDContentTypeTSTInfo = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	OIDAttributeContent   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	OIDAttributeDigest    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// HashOID retourne l'OID d'un algorithme de hachage supporté
+func HashOID(h crypto.Hash) (asn1.ObjectIdentifier, error) {
+	switch h {
+	case crypto.SHA256:
+		return oidSHA256, nil
+	case crypto.SHA384:
+		return oidSHA384, nil
+	case crypto.SHA512:
+		return oidSHA512, nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm: %v", h)
+}
+
+func hashFromOID(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, nil
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, nil
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported hash algorithm: %v", oid)
+}
+
+// messageImprint (RFC 3161 §2.4.1)
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// timeStampReq (RFC 3161 §2.4.1)
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional"`
+	Extensions     asn1.RawValue         `asn1:"optional,tag:0"`
+}
+
+// pkiStatusInfo (RFC 3161 §2.4.2)
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+// timeStampResp (RFC 3161 §2.4.2)
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+// contentInfo (RFC 5652 §3)
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+// signedData (RFC 5652 §5.1)
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+// signerInfo (RFC 5652 §5.3)
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// tstInfo (RFC 3161 §2.4.2) ; genTime est lu brut : les TSA y mettent souvent
+// des fractions de seconde que encoding/asn1 refuse
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        asn1.RawValue
+	Accuracy       accuracy      `asn1:"optional"`
+	Ordering       bool          `asn1:"optional"`
+	Nonce          *big.Int      `asn1:"optional"`
+	TSA            asn1.RawValue `asn1:"optional,tag:0"`
+	Extensions     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// parseGeneralizedTime lit un GeneralizedTime DER (YYYYMMDDHHMMSS[.fff]Z)
+func parseGeneralizedTime(raw asn1.RawValue) (time.Time, error) {
+	if raw.Class != asn1.ClassUniversal || raw.Tag != asn1.TagGeneralizedTime {
+		return time.Time{}, fmt.Errorf("genTime is not a GeneralizedTime")
+	}
+	value := string(raw.Bytes)
+	if !strings.HasSuffix(value, "Z") {
+		return time.Time{}, fmt.Errorf("genTime must be expressed in UTC: %q", value)
+	}
+	// time.Parse accepte une fraction de seconde après le champ secondes
+	t, err := time.Parse("20060102150405Z", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid genTime %q: %w", value, err)
+	}
+	return t.UTC(), nil
+}
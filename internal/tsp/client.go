@@ -0,0 +1,201 @@
+// Package tsp implémente l'horodatage RFC 3161 (Time-Stamp Protocol) : requête
+// auprès d'une autorité d'horodatage (TSA) et vérification des jetons retournés
+package tsp
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Types MIME du protocole sur HTTP (RFC 3161 §3.4)
+const (
+	ContentTypeQuery = "application/timestamp-query"
+	ContentTypeReply = "application/timestamp-reply"
+)
+
+// maxResponseSize borne la taille d'une réponse de la TSA
+const maxResponseSize = 1 << 20
+
+// Statuts PKIStatus (RFC 3161 §2.4.2)
+const (
+	StatusGranted         = 0
+	StatusGrantedWithMods = 1
+	StatusRejection       = 2
+)
+
+// Request est une requête d'horodatage (TimeStampReq)
+type Request struct {
+	HashAlgorithm crypto.Hash
+	HashedMessage []byte
+	Nonce         *big.Int
+	CertReq       bool // demander le certificat de la TSA dans le jeton
+}
+
+// Marshal encode la requête en DER
+func (r *Request) Marshal() ([]byte, error) {
+	oid, err := HashOID(r.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue},
+			HashedMessage: r.HashedMessage,
+		},
+		Nonce:   r.Nonce,
+		CertReq: r.CertReq,
+	})
+}
+
+// ParseRequest décode une requête d'horodatage DER
+func ParseRequest(der []byte) (*Request, error) {
+	var req timeStampReq
+	if _, err := asn1.Unmarshal(der, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp request: %w", err)
+	}
+	hashAlg, err := hashFromOID(req.MessageImprint.HashAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &Request{
+		HashAlgorithm: hashAlg,
+		HashedMessage: req.MessageImprint.HashedMessage,
+		Nonce:         req.Nonce,
+		CertReq:       req.CertReq,
+	}, nil
+}
+
+// Client interroge une autorité d'horodatage RFC 3161 sur HTTP
+type Client struct {
+	url        string
+	httpClient *http.Client
+	roots      *x509.CertPool
+}
+
+// NewClient crée un client pour la TSA url. roots restreint les certificats de TSA
+// acceptés ; nil : magasin de certificats du système
+func NewClient(url string, timeout time.Duration, roots *x509.CertPool) *Client {
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	return &Client{
+		url:        url,
+		httpClient: &http.Client{Timeout: timeout},
+		roots:      roots,
+	}
+}
+
+// URL retourne l'URL de la TSA
+func (c *Client) URL() string {
+	return c.url
+}
+
+// Timestamp fait horodater l'empreinte SHA-256 digest par la TSA et retourne le jeton vérifié
+func (c *Client) Timestamp(ctx context.Context, digest []byte) (*Token, error) {
+	if len(digest) != crypto.SHA256.Size() {
+		return nil, fmt.Errorf("invalid SHA-256 digest length: %d", len(digest))
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	req := &Request{HashAlgorithm: crypto.SHA256, HashedMessage: digest, Nonce: nonce, CertReq: true}
+	body, err := req.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode timestamp request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create timestamp request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", ContentTypeQuery)
+	httpReq.Header.Set("Accept", ContentTypeReply)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to contact timestamp authority: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("timestamp authority returned HTTP %d", resp.StatusCode)
+	}
+	reply, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read timestamp response: %w", err)
+	}
+
+	token, err := ParseResponse(reply)
+	if err != nil {
+		return nil, err
+	}
+	if err := token.VerifyImprint(crypto.SHA256, digest); err != nil {
+		return nil, err
+	}
+	if token.Nonce == nil || token.Nonce.Cmp(nonce) != 0 {
+		return nil, fmt.Errorf("timestamp response nonce does not match the request")
+	}
+	if err := token.VerifyCertificate(c.roots); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ParseResponse décode une réponse TimeStampResp et retourne le jeton vérifié
+func ParseResponse(der []byte) (*Token, error) {
+	var resp timeStampResp
+	if _, err := asn1.Unmarshal(der, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp response: %w", err)
+	}
+	if status := resp.Status.Status; status != StatusGranted && status != StatusGrantedWithMods {
+		return nil, fmt.Errorf("timestamp request rejected (status %d): %s", status, strings.Join(resp.Status.StatusString, "; "))
+	}
+	if len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, fmt.Errorf("timestamp response has no token")
+	}
+	return ParseToken(resp.TimeStampToken.FullBytes)
+}
+
+// LoadCertPool charge les certificats PEM de confiance des TSA
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TSA certificates: %w", err)
+	}
+	pool := x509.NewCertPool()
+	count := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse TSA certificate: %w", err)
+		}
+		pool.AddCert(cert)
+		count++
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,236 @@
+package tsp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ErrImprintMismatch est retourné quand le jeton ne couvre pas l'empreinte attendue
+var ErrImprintMismatch = errors.New("timestamp token does not cover the expected digest")
+
+// Token est un jeton d'horodatage RFC 3161 (TimeStampToken) dont la signature CMS a été vérifiée
+type Token struct {
+	Raw           []byte                // DER du ContentInfo (SignedData)
+	GenTime       time.Time             // date certifiée par la TSA
+	SerialNumber  *big.Int              // numéro de série attribué par la TSA
+	Policy        asn1.ObjectIdentifier // politique d'horodatage de la TSA
+	HashAlgorithm crypto.Hash
+	HashedMessage []byte
+	Nonce         *big.Int
+	Certificates  []*x509.Certificate // certificats joints au jeton
+	Signer        *x509.Certificate   // certificat ayant signé le jeton
+}
+
+// ParseToken décode un TimeStampToken et vérifie sa signature CMS avec le certificat
+// de la TSA joint au jeton. La confiance dans ce certificat est vérifiée séparément
+// (VerifyCertificate)
+func ParseToken(der []byte) (*Token, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp token: %w", err)
+	} else if len(rest) > 0 {
+		return nil, fmt.Errorf("trailing data after timestamp token")
+	}
+	if !ci.ContentType.Equal(OIDSignedData) {
+		return nil, fmt.Errorf("timestamp token is not a CMS SignedData")
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("failed to parse SignedData: %w", err)
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(OIDContentTypeTSTInfo) {
+		return nil, fmt.Errorf("timestamp token does not contain a TSTInfo")
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("timestamp token must have exactly one signer, got %d", len(sd.SignerInfos))
+	}
+
+	var info tstInfo
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse TSTInfo: %w", err)
+	}
+	genTime, err := parseGeneralizedTime(info.GenTime)
+	if err != nil {
+		return nil, err
+	}
+	hashAlg, err := hashFromOID(info.MessageImprint.HashAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &Token{
+		Raw:           der,
+		GenTime:       genTime,
+		SerialNumber:  info.SerialNumber,
+		Policy:        info.Policy,
+		HashAlgorithm: hashAlg,
+		HashedMessage: info.MessageImprint.HashedMessage,
+		Nonce:         info.Nonce,
+	}
+
+	if len(sd.Certificates.Bytes) > 0 {
+		token.Certificates, err = x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse token certificates: %w", err)
+		}
+	}
+
+	si := sd.SignerInfos[0]
+	token.Signer, err = findSigner(si.SID, token.Certificates)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignerInfo(si, sd.EncapContentInfo.EContent, token.Signer); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// VerifyImprint vérifie que le jeton horodate l'empreinte digest calculée avec h
+func (t *Token) VerifyImprint(h crypto.Hash, digest []byte) error {
+	if t.HashAlgorithm != h || !bytes.Equal(t.HashedMessage, digest) {
+		return ErrImprintMismatch
+	}
+	return nil
+}
+
+// VerifyCertificate vérifie que le certificat signataire est un certificat d'horodatage
+// (extended key usage timeStamping) valide à la date du jeton et émis par roots.
+// roots nil : magasin de certificats du système
+func (t *Token) VerifyCertificate(roots *x509.CertPool) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range t.Certificates {
+		if cert != t.Signer {
+			intermediates.AddCert(cert)
+		}
+	}
+	_, err := t.Signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   t.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	if err != nil {
+		return fmt.Errorf("untrusted timestamp authority certificate: %w", err)
+	}
+	return nil
+}
+
+// findSigner retrouve le certificat désigné par le SignerIdentifier
+func findSigner(sid asn1.RawValue, certs []*x509.Certificate) (*x509.Certificate, error) {
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("signer certificate not included in timestamp token")
+	}
+
+	// subjectKeyIdentifier [0] IMPLICIT OCTET STRING
+	if sid.Class == asn1.ClassContextSpecific && sid.Tag == 0 {
+		for _, cert := range certs {
+			if bytes.Equal(cert.SubjectKeyId, sid.Bytes) {
+				return cert, nil
+			}
+		}
+		return nil, fmt.Errorf("signer certificate not found in timestamp token")
+	}
+
+	var ias issuerAndSerialNumber
+	if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+		return nil, fmt.Errorf("failed to parse signer identifier: %w", err)
+	}
+	for _, cert := range certs {
+		if cert.SerialNumber.Cmp(ias.SerialNumber) == 0 && bytes.Equal(cert.RawIssuer, ias.Issuer.FullBytes) {
+			return cert, nil
+		}
+	}
+	return nil, fmt.Errorf("signer certificate not found in timestamp token")
+}
+
+// verifySignerInfo vérifie les attributs signés (type de contenu, empreinte du TSTInfo)
+// puis la signature de la TSA
+func verifySignerInfo(si signerInfo, content []byte, signer *x509.Certificate) error {
+	hashAlg, err := hashFromOID(si.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	signed := content
+	if len(si.SignedAttrs.FullBytes) > 0 {
+		var contentType asn1.ObjectIdentifier
+		var messageDigest []byte
+		rest := si.SignedAttrs.Bytes
+		for len(rest) > 0 {
+			var attr attribute
+			rest, err = asn1.Unmarshal(rest, &attr)
+			if err != nil {
+				return fmt.Errorf("failed to parse signed attributes: %w", err)
+			}
+			switch {
+			case attr.Type.Equal(OIDAttributeContent):
+				if _, err := asn1.Unmarshal(attr.Values.Bytes, &contentType); err != nil {
+					return fmt.Errorf("invalid content-type attribute: %w", err)
+				}
+			case attr.Type.Equal(OIDAttributeDigest):
+				if _, err := asn1.Unmarshal(attr.Values.Bytes, &messageDigest); err != nil {
+					return fmt.Errorf("invalid message-digest attribute: %w", err)
+				}
+			}
+		}
+		if !contentType.Equal(OIDContentTypeTSTInfo) {
+			return fmt.Errorf("signed content-type attribute is not TSTInfo")
+		}
+		h := hashAlg.New()
+		h.Write(content)
+		if !bytes.Equal(h.Sum(nil), messageDigest) {
+			return fmt.Errorf("TSTInfo does not match the signed message digest")
+		}
+
+		// La signature porte sur l'encodage DER en SET OF (et non [0] IMPLICIT)
+		signed = append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	}
+
+	algo, err := signatureAlgorithm(signer, hashAlg)
+	if err != nil {
+		return err
+	}
+	if err := signer.CheckSignature(algo, signed, si.Signature); err != nil {
+		return fmt.Errorf("invalid timestamp token signature: %w", err)
+	}
+	return nil
+}
+
+// signatureAlgorithm déduit l'algorithme de signature de la clé du signataire
+func signatureAlgorithm(signer *x509.Certificate, h crypto.Hash) (x509.SignatureAlgorithm, error) {
+	switch signer.PublicKey.(type) {
+	case *rsa.PublicKey:
+		switch h {
+		case crypto.SHA256:
+			return x509.SHA256WithRSA, nil
+		case crypto.SHA384:
+			return x509.SHA384WithRSA, nil
+		case crypto.SHA512:
+			return x509.SHA512WithRSA, nil
+		}
+	case *ecdsa.PublicKey:
+		switch h {
+		case crypto.SHA256:
+			return x509.ECDSAWithSHA256, nil
+		case crypto.SHA384:
+			return x509.ECDSAWithSHA384, nil
+		case crypto.SHA512:
+			return x509.ECDSAWithSHA512, nil
+		}
+	case ed25519.PublicKey:
+		return x509.PureEd25519, nil
+	}
+	return x509.UnknownSignatureAlgorithm, fmt.Errorf("unsupported timestamp signature key %T with %v", signer.PublicKey, h)
+}
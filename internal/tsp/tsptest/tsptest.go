@@ -0,0 +1,326 @@
+// Package tsptest fournit une autorité d'horodatage RFC 3161 locale pour les tests :
+// une AC et un certificat d'horodatage éphémères, et un serveur HTTP qui signe les requêtes
+package tsptest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/tsp"
+)
+
+// Policy est la politique d'horodatage annoncée par la TSA de test
+var Policy = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1, 1}
+
+var (
+	oidSHA256          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// Authority est une TSA de test : elle émet des jetons signés par un certificat
+// d'horodatage (extended key usage timeStamping) émis par sa propre AC
+type Authority struct {
+	CA   *x509.Certificate
+	Cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+
+	mu       sync.Mutex
+	serial   int64
+	requests int
+	reject   bool
+}
+
+// Server est une TSA de test exposée en HTTP (httptest)
+type Server struct {
+	*httptest.Server
+	*Authority
+}
+
+// NewAuthority crée une TSA de test avec une AC éphémère
+func NewAuthority() (*Authority, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	now := time.Now()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test TSA Root CA"},
+		NotBefore:             now.Add(-24 * time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TSA key: %w", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test TSA"},
+		NotBefore:    now.Add(-24 * time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TSA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authority{CA: ca, Cert: cert, key: key}, nil
+}
+
+// NewServer démarre une TSA de test ; à fermer avec Close
+func NewServer() (*Server, error) {
+	authority, err := NewAuthority()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{Authority: authority}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s, nil
+}
+
+// Roots retourne un pool contenant l'AC de la TSA
+func (a *Authority) Roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(a.CA)
+	return pool
+}
+
+// SetReject fait rejeter (PKIStatus rejection) les requêtes suivantes
+func (a *Authority) SetReject(reject bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reject = reject
+}
+
+// Requests retourne le nombre de requêtes reçues
+func (a *Authority) Requests() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.requests
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != tsp.ContentTypeQuery {
+		http.Error(w, "expected a timestamp query", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := tsp.ParseRequest(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests++
+	reject := s.reject
+	s.mu.Unlock()
+
+	var resp []byte
+	if reject {
+		resp, err = asn1.Marshal(timeStampResp{Status: pkiStatusInfo{Status: tsp.StatusRejection, StatusString: []string{"request rejected by test TSA"}}})
+	} else {
+		var token []byte
+		token, err = s.Issue(req, time.Now())
+		if err == nil {
+			resp, err = asn1.Marshal(timeStampResp{Status: pkiStatusInfo{Status: tsp.StatusGranted}, TimeStampToken: asn1.RawValue{FullBytes: token}})
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", tsp.ContentTypeReply)
+	w.Write(resp)
+}
+
+// Issue émet un TimeStampToken (DER) pour la requête, daté de genTime
+func (a *Authority) Issue(req *tsp.Request, genTime time.Time) ([]byte, error) {
+	hashOID, err := tsp.HashOID(req.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.serial++
+	serial := big.NewInt(a.serial)
+	a.mu.Unlock()
+
+	info, err := asn1.Marshal(tstInfo{
+		Version: 1,
+		Policy:  Policy,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: hashOID, Parameters: asn1.NullRawValue},
+			HashedMessage: req.HashedMessage,
+		},
+		SerialNumber: serial,
+		GenTime:      genTime.UTC().Truncate(time.Second),
+		Nonce:        req.Nonce,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode TSTInfo: %w", err)
+	}
+
+	// Attributs signés : type de contenu et empreinte du TSTInfo (SET OF trié, DER)
+	infoDigest := sha256.Sum256(info)
+	contentType, err := marshalAttribute(tsp.OIDAttributeContent, tsp.OIDContentTypeTSTInfo)
+	if err != nil {
+		return nil, err
+	}
+	messageDigest, err := marshalAttribute(tsp.OIDAttributeDigest, infoDigest[:])
+	if err != nil {
+		return nil, err
+	}
+	attrs := [][]byte{contentType, messageDigest}
+	sort.Slice(attrs, func(i, j int) bool { return bytes.Compare(attrs[i], attrs[j]) < 0 })
+	attrsContent := bytes.Join(attrs, nil)
+
+	signedAttrs, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: attrsContent})
+	if err != nil {
+		return nil, err
+	}
+	attrsDigest := sha256.Sum256(signedAttrs)
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, attrsDigest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign timestamp token: %w", err)
+	}
+
+	sid, err := asn1.Marshal(issuerAndSerialNumber{Issuer: asn1.RawValue{FullBytes: a.Cert.RawIssuer}, SerialNumber: a.Cert.SerialNumber})
+	if err != nil {
+		return nil, err
+	}
+
+	sd := signedData{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidSHA256}},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: tsp.OIDContentTypeTSTInfo, EContent: info},
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                asn1.RawValue{FullBytes: sid},
+			DigestAlgorithm:    pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attrsContent},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256},
+			Signature:          signature,
+		}},
+	}
+	if req.CertReq {
+		sd.Certificates = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: a.Cert.Raw}
+	}
+	sdDER, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SignedData: %w", err)
+	}
+
+	// encoding/asn1 n'applique pas le tag explicite à un RawValue : [0] est construit ici
+	return asn1.Marshal(contentInfo{
+		ContentType: tsp.OIDSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sdDER},
+	})
+}
+
+func marshalAttribute(oid asn1.ObjectIdentifier, value interface{}) ([]byte, error) {
+	valueDER, err := asn1.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(attribute{Type: oid, Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: valueDER}})
+}
+
+// Structures ASN.1 côté émetteur (RFC 3161, RFC 5652)
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+	Nonce          *big.Int  `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
@@ -0,0 +1,604 @@
+// Package upload gère les uploads reprenables des documents volumineux : le contenu est
+// reçu par morceaux vérifiés (SHA256 de chaque morceau) dans un répertoire privé, la
+// reprise se fait depuis l'offset enregistré, et le document n'est scellé qu'une fois le
+// SHA256 du contenu assemblé vérifié. Chiffrement au repos activé, chaque morceau est
+// chiffré par la clé de données de l'upload, enveloppée par la KEK : le contenu n'est
+// jamais écrit en clair.
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// ErrInvalidChecksum est retourné pour un SHA256 qui n'est pas en hexadécimal (64 caractères)
+	ErrInvalidChecksum = errors.New("invalid sha256 checksum")
+	// ErrOffsetMismatch est retourné quand un morceau ne commence pas à l'offset enregistré
+	ErrOffsetMismatch = errors.New("chunk offset does not match upload offset")
+	// ErrChunkChecksum est retourné quand le SHA256 d'un morceau ne correspond pas
+	ErrChunkChecksum = errors.New("chunk checksum mismatch")
+	// ErrChunkTooLarge est retourné pour un morceau au-delà de UPLOAD_CHUNK_MAX_MB
+	ErrChunkTooLarge = errors.New("chunk exceeds maximum size")
+	// ErrSizeExceeded est retourné quand le contenu dépasse la taille déclarée ou autorisée
+	ErrSizeExceeded = errors.New("upload exceeds declared size")
+	// ErrIncomplete est retourné par Seal tant que tout le contenu n'a pas été reçu
+	ErrIncomplete = errors.New("upload is incomplete")
+	// ErrHashMismatch est retourné par Seal quand le SHA256 du contenu assemblé ne correspond
+	// pas : l'upload est remis à zéro
+	ErrHashMismatch = errors.New("assembled content checksum mismatch")
+	// ErrSessionClosed est retourné pour un upload en cours d'enregistrement ou terminé
+	ErrSessionClosed = errors.New("upload is no longer open")
+)
+
+// Config contient la configuration des uploads reprenables
+type Config struct {
+	Dir           string        // répertoire privé (0700) des fichiers temporaires (hors STORAGE_DIR)
+	ChunkMaxBytes int64         // taille maximale d'un morceau (0 : illimitée)
+	MaxSizeBytes  int64         // taille maximale d'un document (0 : illimitée)
+	TTL           time.Duration // durée de vie d'un upload sans activité (défaut 24h)
+	Logger        zerolog.Logger
+}
+
+// Manager gère les uploads reprenables
+type Manager struct {
+	db  *storage.DB
+	cfg Config
+}
+
+// NewManager crée un nouveau Manager et son répertoire de fichiers temporaires,
+// accessible au seul compte du service
+func NewManager(db *storage.DB, cfg Config) (*Manager, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("upload sessions directory is required")
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create upload sessions directory: %w", err)
+	}
+	if err := os.Chmod(cfg.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to restrict upload sessions directory: %w", err)
+	}
+	return &Manager{db: db, cfg: cfg}, nil
+}
+
+// CreateRequest décrit un upload reprenable à ouvrir
+type CreateRequest struct {
+	SizeBytes int64
+	SHA256Hex string          // SHA256 attendu du contenu assemblé
+	Metadata  json.RawMessage // champs de la facture
+	Tenant    string
+	CreatedBy string
+}
+
+// Create ouvre un upload reprenable
+func (m *Manager) Create(ctx context.Context, req CreateRequest) (*models.UploadSession, error) {
+	sha256Hex, err := normalizeChecksum(req.SHA256Hex)
+	if err != nil {
+		return nil, err
+	}
+	if req.SizeBytes <= 0 {
+		return nil, fmt.Errorf("upload size must be positive")
+	}
+	if m.cfg.MaxSizeBytes > 0 && req.SizeBytes > m.cfg.MaxSizeBytes {
+		return nil, ErrSizeExceeded
+	}
+
+	s := &models.UploadSession{
+		ID:        uuid.New(),
+		SizeBytes: req.SizeBytes,
+		SHA256Hex: sha256Hex,
+		Status:    models.UploadStatusOpen,
+		Metadata:  req.Metadata,
+		ExpiresAt: time.Now().Add(m.cfg.TTL),
+	}
+	if len(s.Metadata) == 0 {
+		s.Metadata = json.RawMessage(`{}`)
+	}
+	if req.Tenant != "" {
+		s.Tenant = &req.Tenant
+	}
+	if req.CreatedBy != "" {
+		s.CreatedBy = &req.CreatedBy
+	}
+
+	if _, err := m.db.SealUploadSession(ctx, s); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(m.partsDir(s.ID), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	if err := m.db.CreateUploadSession(ctx, s); err != nil {
+		os.RemoveAll(m.partsDir(s.ID))
+		return nil, err
+	}
+
+	m.cfg.Logger.Info().
+		Str("upload_id", s.ID.String()).
+		Int64("size_bytes", s.SizeBytes).
+		Msg("Upload session created")
+	return s, nil
+}
+
+// Get retourne un upload reprenable (tenant non vide : uploads de ce tenant)
+func (m *Manager) Get(ctx context.Context, id uuid.UUID, tenant string) (*models.UploadSession, error) {
+	return m.db.GetUploadSession(ctx, id, tenant)
+}
+
+// Append ajoute un morceau à l'offset donné. Le morceau est d'abord reçu et haché dans un
+// fichier temporaire (sans verrou), puis recopié à la suite du contenu sous le verrou de
+// l'upload : un morceau interrompu ou refusé ne modifie pas l'offset enregistré
+func (m *Manager) Append(ctx context.Context, id uuid.UUID, tenant string, offset int64, chunkSHA256 string, r io.Reader) (*models.UploadSession, error) {
+	expected, err := normalizeChecksum(chunkSHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	// Vérification préalable : inutile de recevoir un morceau qui sera refusé
+	s, err := m.db.GetUploadSession(ctx, id, tenant)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAppend(s, offset, 0); err != nil {
+		return s, err
+	}
+
+	chunk, err := storage.SpoolContent(r, m.cfg.Dir, m.cfg.ChunkMaxBytes)
+	if errors.Is(err, storage.ErrContentTooLarge) {
+		return s, ErrChunkTooLarge
+	}
+	if err != nil {
+		return s, err
+	}
+	defer chunk.Remove()
+	if chunk.SHA256Hex() != expected {
+		return s, ErrChunkChecksum
+	}
+
+	var current *models.UploadSession
+	updated, err := m.db.UpdateUploadSession(ctx, id, tenant, func(s *models.UploadSession) error {
+		current = s
+		if err := checkAppend(s, offset, chunk.Size()); err != nil {
+			return err
+		}
+		if err := m.writeChunk(ctx, s, offset, chunk); err != nil {
+			return err
+		}
+		s.OffsetBytes = offset + chunk.Size()
+		s.ExpiresAt = time.Now().Add(m.cfg.TTL)
+		return nil
+	})
+	if err != nil {
+		if current == nil {
+			current = s
+		}
+		return current, err
+	}
+	return updated, nil
+}
+
+// checkAppend vérifie qu'un morceau de size octets peut être ajouté à offset
+func checkAppend(s *models.UploadSession, offset, size int64) error {
+	if s.Status != models.UploadStatusOpen {
+		return ErrSessionClosed
+	}
+	if offset != s.OffsetBytes {
+		return ErrOffsetMismatch
+	}
+	if offset+size > s.SizeBytes {
+		return ErrSizeExceeded
+	}
+	return nil
+}
+
+// writeChunk recopie le morceau, chiffré par la clé de l'upload, dans le fichier de son
+// offset. Les morceaux à partir de offset (reste d'un morceau précédent interrompu) sont
+// d'abord supprimés
+func (m *Manager) writeChunk(ctx context.Context, s *models.UploadSession, offset int64, chunk *storage.SpooledContent) error {
+	dek, err := m.db.UploadSessionKey(ctx, s)
+	if err != nil {
+		return err
+	}
+	parts, err := m.listParts(s.ID)
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if p.offset >= offset {
+			if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove stale chunk: %w", err)
+			}
+		}
+	}
+
+	src, err := chunk.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open chunk: %w", err)
+	}
+	defer src.Close()
+	var body io.Reader = src
+	if dek != nil {
+		if body, err = crypto.NewEncryptReader(dek, src); err != nil {
+			return fmt.Errorf("failed to encrypt chunk: %w", err)
+		}
+	}
+
+	f, err := os.CreateTemp(m.partsDir(s.ID), "chunk-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create chunk file: %w", err)
+	}
+	_, err = io.Copy(f, body)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), m.partPath(s.ID, offset))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write chunk: %w", err)
+	}
+	return nil
+}
+
+// Seal vérifie le contenu assemblé et passe l'upload en cours d'enregistrement. Le contenu
+// retourné reste la propriété de l'upload : l'appelant termine par Finish (document
+// enregistré) ou Release (échec, l'upload peut être finalisé à nouveau). Si le SHA256 ne
+// correspond pas, l'upload est remis à zéro et ErrHashMismatch retourné
+func (m *Manager) Seal(ctx context.Context, id uuid.UUID, tenant string) (*models.UploadSession, storage.Content, error) {
+	s, err := m.db.UpdateUploadSession(ctx, id, tenant, func(s *models.UploadSession) error {
+		if s.Status != models.UploadStatusOpen {
+			return ErrSessionClosed
+		}
+		if s.OffsetBytes != s.SizeBytes {
+			return ErrIncomplete
+		}
+		s.Status = models.UploadStatusSealing
+		s.ExpiresAt = time.Now().Add(m.cfg.TTL)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Hachage hors verrou : l'upload est protégé par son statut
+	content, err := m.openContent(ctx, s)
+	if err != nil {
+		m.Release(ctx, id)
+		return nil, nil, err
+	}
+	sha256Hex, size, err := hashContent(content)
+	if err != nil {
+		m.Release(ctx, id)
+		return nil, nil, err
+	}
+	if size != s.SizeBytes || sha256Hex != s.SHA256Hex {
+		m.cfg.Logger.Warn().
+			Str("upload_id", id.String()).
+			Str("expected_sha256", s.SHA256Hex).
+			Str("sha256", sha256Hex).
+			Msg("Upload checksum mismatch, upload reset")
+		if err := m.reset(ctx, id); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrHashMismatch
+	}
+
+	content.sha256Hex = sha256Hex
+	return s, content, nil
+}
+
+// reset remet à zéro un upload dont le contenu assemblé est invalide
+func (m *Manager) reset(ctx context.Context, id uuid.UUID) error {
+	_, err := m.db.UpdateUploadSession(ctx, id, "", func(s *models.UploadSession) error {
+		parts, err := m.listParts(id)
+		if err != nil {
+			return err
+		}
+		for _, p := range parts {
+			if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove chunk: %w", err)
+			}
+		}
+		s.OffsetBytes = 0
+		s.Status = models.UploadStatusOpen
+		return nil
+	})
+	return err
+}
+
+// Finish termine un upload scellé : le document est enregistré, les morceaux supprimés
+func (m *Manager) Finish(ctx context.Context, id uuid.UUID, documentID uuid.UUID) error {
+	_, err := m.db.UpdateUploadSession(ctx, id, "", func(s *models.UploadSession) error {
+		s.Status = models.UploadStatusCompleted
+		s.DocumentID = &documentID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	os.RemoveAll(m.partsDir(id))
+
+	m.cfg.Logger.Info().
+		Str("upload_id", id.String()).
+		Str("document_id", documentID.String()).
+		Msg("Upload session completed")
+	return nil
+}
+
+// Release rouvre un upload scellé dont l'enregistrement a échoué
+func (m *Manager) Release(ctx context.Context, id uuid.UUID) error {
+	_, err := m.db.UpdateUploadSession(ctx, id, "", func(s *models.UploadSession) error {
+		if s.Status == models.UploadStatusSealing {
+			s.Status = models.UploadStatusOpen
+		}
+		return nil
+	})
+	return err
+}
+
+// Abort abandonne un upload et supprime ses morceaux. Un upload en cours d'enregistrement
+// ne peut pas être abandonné
+func (m *Manager) Abort(ctx context.Context, id uuid.UUID, tenant string) error {
+	s, err := m.db.GetUploadSession(ctx, id, tenant)
+	if err != nil {
+		return err
+	}
+	if s.Status == models.UploadStatusSealing {
+		return ErrSessionClosed
+	}
+	if err := m.db.DeleteUploadSession(ctx, id, tenant); err != nil {
+		return err
+	}
+	os.RemoveAll(m.partsDir(id))
+	return nil
+}
+
+// CleanupExpired supprime les uploads expirés et leurs morceaux, puis les fichiers
+// abandonnés du répertoire, inchangés depuis la durée de vie d'un upload : morceaux d'un
+// upload qui n'existe plus (arrêt du processus pendant une suppression) et fichiers
+// temporaires d'un contenu reçu en flux
+func (m *Manager) CleanupExpired(ctx context.Context) (int, error) {
+	ids, err := m.db.DeleteExpiredUploadSessions(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err := os.RemoveAll(m.partsDir(id)); err != nil {
+			m.cfg.Logger.Warn().Err(err).Str("upload_id", id.String()).Msg("Failed to remove expired upload files")
+		}
+	}
+	if err := m.removeAbandoned(ctx); err != nil {
+		m.cfg.Logger.Warn().Err(err).Msg("Failed to remove abandoned upload files")
+	}
+	return len(ids), nil
+}
+
+// removeAbandoned supprime les fichiers anciens du répertoire qui n'appartiennent à aucun upload
+func (m *Manager) removeAbandoned(ctx context.Context) error {
+	entries, err := os.ReadDir(m.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read upload sessions directory: %w", err)
+	}
+	for _, entry := range entries {
+		// Un fichier récent peut appartenir à un upload ou à une requête en cours
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < m.cfg.TTL {
+			continue
+		}
+		if id, err := uuid.Parse(entry.Name()); err == nil && entry.IsDir() {
+			if _, err := m.db.GetUploadSession(ctx, id, ""); !errors.Is(err, storage.ErrUploadSessionNotFound) {
+				continue
+			}
+		}
+		path := filepath.Join(m.cfg.Dir, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		m.cfg.Logger.Info().Str("path", path).Msg("Abandoned upload file removed")
+	}
+	return nil
+}
+
+// StartCleanupScheduler supprime les uploads expirés à chaque intervalle
+func StartCleanupScheduler(ctx context.Context, m *Manager, interval time.Duration) {
+	if interval == 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				count, err := m.CleanupExpired(ctx)
+				if err != nil && ctx.Err() == nil {
+					m.cfg.Logger.Error().Err(err).Msg("Upload sessions cleanup failed")
+				} else if count > 0 {
+					m.cfg.Logger.Info().Int("count", count).Msg("Expired upload sessions removed")
+				}
+			}
+		}
+	}()
+}
+
+// partsDir est le répertoire des morceaux d'un upload
+func (m *Manager) partsDir(id uuid.UUID) string {
+	return filepath.Join(m.cfg.Dir, id.String())
+}
+
+// partPath est le fichier du morceau reçu à offset
+func (m *Manager) partPath(id uuid.UUID, offset int64) string {
+	return filepath.Join(m.partsDir(id), fmt.Sprintf("%020d.part", offset))
+}
+
+// part est un morceau enregistré
+type part struct {
+	offset int64
+	path   string
+}
+
+// listParts retourne les morceaux d'un upload par offset croissant
+func (m *Manager) listParts(id uuid.UUID) ([]part, error) {
+	entries, err := os.ReadDir(m.partsDir(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload directory: %w", err)
+	}
+	var parts []part
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".part")
+		if !ok {
+			continue
+		}
+		offset, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, part{offset: offset, path: filepath.Join(m.partsDir(id), entry.Name())})
+	}
+	// Noms à largeur fixe : l'ordre de ReadDir est celui des offsets
+	return parts, nil
+}
+
+// normalizeChecksum valide un SHA256 hexadécimal et le met en minuscules
+func normalizeChecksum(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) != sha256.Size*2 {
+		return "", ErrInvalidChecksum
+	}
+	if _, err := hex.DecodeString(s); err != nil {
+		return "", ErrInvalidChecksum
+	}
+	return s, nil
+}
+
+// hashContent calcule le SHA256 et la taille d'un contenu
+func hashContent(content storage.Content) (string, int64, error) {
+	r, err := content.Open()
+	if err != nil {
+		return "", 0, err
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, r)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash upload content: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// openContent retourne le contenu assemblé des morceaux reçus jusqu'à l'offset enregistré
+func (m *Manager) openContent(ctx context.Context, s *models.UploadSession) (*partsContent, error) {
+	dek, err := m.db.UploadSessionKey(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := m.listParts(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	content := &partsContent{dek: dek, size: s.OffsetBytes}
+	for _, p := range parts {
+		if p.offset < s.OffsetBytes {
+			content.paths = append(content.paths, p.path)
+		}
+	}
+	return content, nil
+}
+
+// partsContent est le contenu d'un upload : ses morceaux lus et déchiffrés à la suite
+type partsContent struct {
+	paths     []string
+	dek       []byte
+	size      int64
+	sha256Hex string
+}
+
+func (c *partsContent) Open() (io.ReadCloser, error) {
+	return &partsReader{paths: c.paths, dek: c.dek}, nil
+}
+
+func (c *partsContent) Size() int64       { return c.size }
+func (c *partsContent) SHA256Hex() string { return c.sha256Hex }
+
+// partsReader ouvre chaque morceau au fil de la lecture
+type partsReader struct {
+	paths []string
+	dek   []byte
+	file  *os.File
+	cur   io.Reader
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.paths) == 0 {
+				return 0, io.EOF
+			}
+			if err := r.next(); err != nil {
+				return 0, err
+			}
+		}
+		n, err := r.cur.Read(p)
+		if err == io.EOF {
+			r.file.Close()
+			r.file, r.cur = nil, nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *partsReader) next() error {
+	f, err := os.Open(r.paths[0])
+	if err != nil {
+		return fmt.Errorf("failed to open chunk: %w", err)
+	}
+	r.paths = r.paths[1:]
+	r.file, r.cur = f, f
+	if r.dek != nil {
+		if r.cur, err = crypto.NewDecryptReader(r.dek, f); err != nil {
+			f.Close()
+			r.file, r.cur = nil, nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *partsReader) Close() error {
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}
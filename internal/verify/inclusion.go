@@ -0,0 +1,36 @@
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+)
+
+// VerifyInclusionProof vérifie hors ligne une preuve d'inclusion retournée par
+// GET /api/v1/ledger/proof/:document_id : signature de la racine (JWS) puis chemin
+// d'audit de la feuille du document jusqu'à cette racine.
+// keys : crypto.KeySet (JWKS obtenu hors bande) ou *crypto.Service
+func VerifyInclusionProof(p *ledger.InclusionProof, keys EvidenceVerifier) (*crypto.Evidence, error) {
+	evidence, err := keys.VerifyEvidence(p.RootJWS)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Merkle root signature: %w", err)
+	}
+
+	rootJSON, err := json.Marshal(p.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Merkle root: %w", err)
+	}
+	hash := sha256.Sum256(rootJSON)
+	if evidence.DocumentID != ledger.MerkleRootSubjectPrefix+p.Root.Batch || evidence.Sha256 != hex.EncodeToString(hash[:]) {
+		return nil, fmt.Errorf("Merkle root signature does not match the root")
+	}
+
+	if err := p.Verify(); err != nil {
+		return nil, fmt.Errorf("invalid inclusion proof: %w", err)
+	}
+	return evidence, nil
+}
@@ -4,12 +4,19 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"time"
 
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
 	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/services"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/utils"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
@@ -21,20 +28,39 @@ type VerificationResult struct {
 	Checks     []Check  `json:"checks"`               // Détails des vérifications
 	Errors     []string `json:"errors,omitempty"`    // Erreurs rencontrées
 	Timestamp  string   `json:"timestamp"`           // Timestamp de la vérification
+	Evidence   *EvidenceInfo `json:"evidence,omitempty"` // Détails de la preuve JWS
+	Anchor     *AnchorInfo   `json:"anchor,omitempty"`   // Horodatage RFC 3161 du ledger
 }
 
-// Check représente une vérification individuelle
+// EvidenceInfo décrit la preuve JWS vérifiée
+type EvidenceInfo struct {
+	Status   string `json:"status"`              // "ok", "error", "degraded", "unverified"
+	KID      string `json:"kid,omitempty"`       // Clé de signature
+	SignedAt string `json:"signed_at,omitempty"` // Horodatage signé (claim timestamp)
+}
+
+// Check représente une vérification individuelle. Seuls "error" et "missing" sont des
+// échecs (Failed) ; "warn", "degraded" et "purged" (contenu supprimé à l'échéance de la
+// rétention, purge inscrite au ledger) n'invalident pas le résultat
 type Check struct {
-	Component string `json:"component"` // "file", "database", "ledger"
-	Status    string `json:"status"`    // "ok", "error", "missing"
+	Component string `json:"component"` // "file", "payload", "database", "jws", "ledger", "timestamp"
+	Status    string `json:"status"`    // "ok", "error", "missing", "warn", "degraded", "purged"
 	Message   string `json:"message"`   // Message détaillé
 }
 
+// Failed indique si la vérification a échoué. Un statut inconnu n'est pas un échec : le
+// champ valid du résultat fait foi
+func (c Check) Failed() bool {
+	return c.Status == "error" || c.Status == "missing"
+}
+
 // VerifyDocumentIntegrity vérifie l'intégrité complète d'un document
-// Vérifie la cohérence entre fichier, base de données et ledger
+// Vérifie la cohérence entre fichier, base de données, preuve JWS et ledger
+// jwsService peut être nil : la signature n'est alors pas vérifiée (statut "warn")
 func VerifyDocumentIntegrity(
 	ctx context.Context,
 	db *storage.DB,
+	jwsService *crypto.Service,
 	docID uuid.UUID,
 ) (*VerificationResult, error) {
 	result := &VerificationResult{
@@ -68,76 +94,60 @@ func VerifyDocumentIntegrity(
 		Message:   fmt.Sprintf("Document found: %s", doc.Filename),
 	})
 
-	// 2. Vérifier fichier sur disque
-	if doc.StoredPath == "" {
-		result.Checks = append(result.Checks, Check{
-			Component: "file",
-			Status:    "missing",
-			Message:   "No stored_path in database",
-		})
-		result.Valid = false
-		result.Errors = append(result.Errors, "No stored_path in database")
-		return result, nil
+	// 2. Vérifier le contenu : fichier sur disque, ou payload JSON en base (tickets POS)
+	var contentOK bool
+	if doc.StoredPath == "" && doc.PurgedAt == nil {
+		payload, err := db.GetDocumentPayload(ctx, docID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query document payload: %w", err)
+		}
+		doc.PayloadJSON = payload
 	}
-
-	// Vérifier existence du fichier
-	fileInfo, err := os.Stat(doc.StoredPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			result.Checks = append(result.Checks, Check{
-				Component: "file",
-				Status:    "missing",
-				Message:   fmt.Sprintf("File not found: %s", doc.StoredPath),
-			})
+	switch {
+	case doc.PurgedAt != nil:
+		// Pierre tombale : le contenu a été purgé, la preuve et le ledger restent vérifiables
+		purgeCheck, err := verifyPurgeRecord(ctx, db, doc)
+		if err != nil {
+			return nil, err
+		}
+		result.Checks = append(result.Checks, purgeCheck)
+		if purgeCheck.Failed() {
 			result.Valid = false
-			result.Errors = append(result.Errors, fmt.Sprintf("File not found: %s", doc.StoredPath))
-			return result, nil
+			result.Errors = append(result.Errors, purgeCheck.Message)
+		}
+		contentOK = !purgeCheck.Failed()
+	case doc.StoredPath == "" && len(doc.PayloadJSON) > 0:
+		payloadCheck := VerifyPosPayload(doc)
+		result.Checks = append(result.Checks, payloadCheck)
+		if payloadCheck.Status != "ok" {
+			result.Valid = false
+			result.Errors = append(result.Errors, payloadCheck.Message)
+		}
+		contentOK = payloadCheck.Status == "ok"
+	default:
+		contentOK, err = verifyStoredFile(ctx, db, doc, result)
+		if err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("failed to stat file: %w", err)
 	}
-
-	// Vérifier taille du fichier
-	if fileInfo.Size() != doc.SizeBytes {
-		result.Checks = append(result.Checks, Check{
-			Component: "file",
-			Status:    "error",
-			Message:   fmt.Sprintf("Size mismatch: expected %d, got %d", doc.SizeBytes, fileInfo.Size()),
-		})
-		result.Valid = false
-		result.Errors = append(result.Errors, fmt.Sprintf("File size mismatch: expected %d, got %d", doc.SizeBytes, fileInfo.Size()))
+	if !contentOK {
 		return result, nil
 	}
 
-	// Lire le fichier et calculer SHA256
-	fileContent, err := os.ReadFile(doc.StoredPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+	// 3. Vérifier la preuve JWS (signature, claims, kid)
+	var verifier EvidenceVerifier
+	if jwsService != nil {
+		verifier = jwsService
 	}
-
-	// Calculer SHA256 du fichier
-	hash := sha256.Sum256(fileContent)
-	calculatedSHA256 := hex.EncodeToString(hash[:])
-
-	// Vérifier cohérence SHA256
-	if calculatedSHA256 != doc.SHA256Hex {
-		result.Checks = append(result.Checks, Check{
-			Component: "file",
-			Status:    "error",
-			Message:   fmt.Sprintf("SHA256 mismatch: expected %s, got %s", doc.SHA256Hex, calculatedSHA256),
-		})
+	jwsCheck, evidence := VerifyEvidenceJWS(verifier, doc)
+	result.Checks = append(result.Checks, jwsCheck)
+	result.Evidence = evidence
+	if jwsCheck.Status == "error" {
 		result.Valid = false
-		result.Errors = append(result.Errors, fmt.Sprintf("SHA256 mismatch: file may have been tampered"))
-		return result, nil
+		result.Errors = append(result.Errors, jwsCheck.Message)
 	}
 
-	// Fichier OK
-	result.Checks = append(result.Checks, Check{
-		Component: "file",
-		Status:    "ok",
-		Message:   fmt.Sprintf("File exists, size=%d, SHA256=%s", fileInfo.Size(), calculatedSHA256),
-	})
-
-	// 3. Vérifier présence dans le ledger (si ledger activé)
+	// 4. Vérifier présence dans le ledger (si ledger activé)
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
@@ -149,17 +159,28 @@ func VerifyDocumentIntegrity(
 		return nil, fmt.Errorf("failed to check ledger: %w", err)
 	}
 
+	ledgerOK := false
 	if hasLedger {
 		// Vérifier que le ledger_hash dans documents correspond à une entrée ledger
 		if doc.LedgerHash != nil {
 			// Vérifier que l'entrée ledger existe avec ce hash
-			var ledgerHash string
+			var ledgerHash, ledgerJWS string
 			err = tx.QueryRow(ctx, `
-				SELECT hash FROM ledger 
+				SELECT hash, COALESCE(evidence_jws, '') FROM ledger 
 				WHERE document_id = $1 AND hash = $2
-			`, docID, *doc.LedgerHash).Scan(&ledgerHash)
+			`, docID, *doc.LedgerHash).Scan(&ledgerHash, &ledgerJWS)
 
-			if err == nil {
+			if err == nil && ledgerJWS != derefEvidence(doc) {
+				// La preuve copiée dans le ledger au scellement doit être identique
+				result.Checks = append(result.Checks, Check{
+					Component: "ledger",
+					Status:    "error",
+					Message:   "Evidence JWS differs from the one recorded in the ledger",
+				})
+				result.Valid = false
+				result.Errors = append(result.Errors, "Evidence JWS differs from ledger entry")
+			} else if err == nil {
+				ledgerOK = true
 				result.Checks = append(result.Checks, Check{
 					Component: "ledger",
 					Status:    "ok",
@@ -193,6 +214,262 @@ func VerifyDocumentIntegrity(
 
 	tx.Rollback(ctx) // Rollback car on ne fait que lire
 
+	// 5. Vérifier l'ancrage horodaté (RFC 3161) de l'entrée du ledger
+	if ledgerOK {
+		anchorCheck, anchor, err := verifyLedgerAnchor(ctx, db.Pool, docID, db.TSARoots())
+		if err != nil {
+			return nil, fmt.Errorf("failed to check ledger anchor: %w", err)
+		}
+		if anchorCheck != nil {
+			result.Checks = append(result.Checks, *anchorCheck)
+			result.Anchor = anchor
+			if anchorCheck.Status == "error" {
+				result.Valid = false
+				result.Errors = append(result.Errors, anchorCheck.Message)
+			}
+		}
+	}
+
 	return result, nil
 }
 
+// verifyPurgeRecord vérifie qu'une pierre tombale correspond à une purge inscrite au ledger :
+// purged_at seul ne prouve rien (une écriture en base masquerait un contenu altéré), il faut
+// une entrée document.purged du document portant son SHA256
+func verifyPurgeRecord(ctx context.Context, db *storage.DB, doc *models.Document) (Check, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT COALESCE(body, '') FROM ledger WHERE document_id = $1 AND entry_type = $2
+	`, doc.ID, ledger.EntryTypeDocumentPurged)
+	if err != nil {
+		return Check{}, fmt.Errorf("failed to query purge ledger entry: %w", err)
+	}
+	defer rows.Close()
+
+	found := false
+	for rows.Next() {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			return Check{}, fmt.Errorf("failed to scan purge ledger entry: %w", err)
+		}
+		found = true
+		var purged ledger.DocumentPurgedBody
+		if json.Unmarshal([]byte(body), &purged) == nil && purged.SHA256Hex == doc.SHA256Hex {
+			return Check{
+				Component: "file",
+				Status:    "purged",
+				Message:   fmt.Sprintf("Content purged at %s (retention expired)", doc.PurgedAt.UTC().Format(time.RFC3339)),
+			}, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return Check{}, fmt.Errorf("error iterating purge ledger entries: %w", err)
+	}
+
+	message := "Document marked as purged but no document.purged entry in the ledger"
+	if found {
+		message = "Document marked as purged but the document.purged ledger entry does not match its SHA256"
+	}
+	return Check{Component: "file", Status: "error", Message: message}, nil
+}
+
+// EvidenceVerifier vérifie une preuve JWS : *crypto.Service (en ligne) ou
+// crypto.KeySet (hors ligne, clés d'un JWKS)
+type EvidenceVerifier interface {
+	VerifyEvidence(jws string) (*crypto.Evidence, error)
+}
+
+// VerifyEvidenceJWS vérifie la preuve JWS d'un document : signature (clé désignée
+// par le kid) et correspondance des claims document_id et sha256 avec la ligne en base.
+// Un document sans preuve a été stocké en mode dégradé (échec de signature toléré)
+func VerifyEvidenceJWS(jwsService EvidenceVerifier, doc *models.Document) (Check, *EvidenceInfo) {
+	if derefEvidence(doc) == "" {
+		return Check{
+			Component: "jws",
+			Status:    "degraded",
+			Message:   "No JWS evidence (document stored in degraded mode)",
+		}, &EvidenceInfo{Status: "degraded"}
+	}
+
+	if jwsService == nil {
+		return Check{
+			Component: "jws",
+			Status:    "warn",
+			Message:   "JWS service not configured, evidence signature not verified",
+		}, &EvidenceInfo{Status: "unverified"}
+	}
+
+	evidence, err := jwsService.VerifyEvidence(*doc.EvidenceJWS)
+	if err != nil {
+		return Check{
+			Component: "jws",
+			Status:    "error",
+			Message:   fmt.Sprintf("Invalid JWS signature: %v", err),
+		}, &EvidenceInfo{Status: "error"}
+	}
+
+	info := &EvidenceInfo{
+		Status:   "error",
+		KID:      evidence.KID,
+		SignedAt: evidence.Timestamp.UTC().Format(time.RFC3339),
+	}
+
+	// Une preuve valide mais appartenant à un autre document trahit un échange de preuves
+	if evidence.DocumentID != doc.ID.String() {
+		return Check{
+			Component: "jws",
+			Status:    "error",
+			Message:   fmt.Sprintf("JWS document_id mismatch: evidence signed for %s", evidence.DocumentID),
+		}, info
+	}
+	if evidence.Sha256 != doc.SHA256Hex {
+		return Check{
+			Component: "jws",
+			Status:    "error",
+			Message:   fmt.Sprintf("JWS sha256 mismatch: evidence signed for %s", evidence.Sha256),
+		}, info
+	}
+
+	info.Status = "ok"
+	return Check{
+		Component: "jws",
+		Status:    "ok",
+		Message:   fmt.Sprintf("Evidence signed with kid=%s at %s", evidence.KID, info.SignedAt),
+	}, info
+}
+
+// derefEvidence retourne la preuve JWS du document ("" si absente)
+func derefEvidence(doc *models.Document) string {
+	if doc.EvidenceJWS == nil {
+		return ""
+	}
+	return *doc.EvidenceJWS
+}
+
+// verifyStoredFile vérifie le contenu stocké (existence, taille, SHA256), quel que soit le backend
+// Retourne false si une incohérence a été ajoutée au résultat
+func verifyStoredFile(ctx context.Context, db *storage.DB, doc *models.Document, result *VerificationResult) (bool, error) {
+	if doc.StoredPath == "" {
+		result.Checks = append(result.Checks, Check{
+			Component: "file",
+			Status:    "missing",
+			Message:   "No stored_path in database",
+		})
+		result.Valid = false
+		result.Errors = append(result.Errors, "No stored_path in database")
+		return false, nil
+	}
+
+	// Vérifier existence du fichier
+	fileInfo, err := db.BlobStore().Stat(ctx, doc.StoredPath)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			result.Checks = append(result.Checks, Check{
+				Component: "file",
+				Status:    "missing",
+				Message:   fmt.Sprintf("File not found: %s", doc.StoredPath),
+			})
+			result.Valid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("File not found: %s", doc.StoredPath))
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Vérifier taille du fichier (contenu chiffré : taille du format chiffré)
+	expectedSize, err := db.ContentSize(ctx, doc)
+	if err != nil {
+		return false, err
+	}
+	if fileInfo.Size != expectedSize {
+		result.Checks = append(result.Checks, Check{
+			Component: "file",
+			Status:    "error",
+			Message:   fmt.Sprintf("Size mismatch: expected %d, got %d", expectedSize, fileInfo.Size),
+		})
+		result.Valid = false
+		result.Errors = append(result.Errors, fmt.Sprintf("File size mismatch: expected %d, got %d", expectedSize, fileInfo.Size))
+		return false, nil
+	}
+
+	// Lire le contenu et calculer SHA256
+	content, err := db.OpenDocumentContent(ctx, doc)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer content.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+	calculatedSHA256 := hex.EncodeToString(hash.Sum(nil))
+
+	// Vérifier cohérence SHA256
+	if calculatedSHA256 != doc.SHA256Hex {
+		result.Checks = append(result.Checks, Check{
+			Component: "file",
+			Status:    "error",
+			Message:   fmt.Sprintf("SHA256 mismatch: expected %s, got %s", doc.SHA256Hex, calculatedSHA256),
+		})
+		result.Valid = false
+		result.Errors = append(result.Errors, fmt.Sprintf("SHA256 mismatch: file may have been tampered"))
+		return false, nil
+	}
+
+	// Fichier OK
+	result.Checks = append(result.Checks, Check{
+		Component: "file",
+		Status:    "ok",
+		Message:   fmt.Sprintf("File exists, size=%d, SHA256=%s", fileInfo.Size, calculatedSHA256),
+	})
+	return true, nil
+}
+
+// VerifyPosPayload vérifie un document stocké en base sans fichier (ticket POS) :
+// le payload_json est re-canonicalisé, l'entrée {ticket, source_id, pos_session}
+// reconstruite et son SHA256 comparé à sha256_hex
+func VerifyPosPayload(doc *models.Document) Check {
+	canonical, err := utils.CanonicalizeJSON(doc.PayloadJSON)
+	if err != nil {
+		return Check{
+			Component: "payload",
+			Status:    "error",
+			Message:   fmt.Sprintf("Invalid payload_json: %v", err),
+		}
+	}
+
+	var payload struct {
+		Ticket     map[string]interface{} `json:"ticket"`
+		SourceID   string                 `json:"source_id"`
+		PosSession *string                `json:"pos_session"`
+	}
+	if err := json.Unmarshal(canonical, &payload); err != nil || payload.Ticket == nil || payload.SourceID == "" {
+		return Check{
+			Component: "payload",
+			Status:    "error",
+			Message:   "Invalid payload_json: ticket and source_id are required",
+		}
+	}
+
+	calculatedSHA256, err := services.ComputePosTicketHash(payload.Ticket, payload.SourceID, payload.PosSession)
+	if err != nil {
+		return Check{
+			Component: "payload",
+			Status:    "error",
+			Message:   fmt.Sprintf("Failed to hash payload: %v", err),
+		}
+	}
+
+	if calculatedSHA256 != doc.SHA256Hex {
+		return Check{
+			Component: "payload",
+			Status:    "error",
+			Message:   fmt.Sprintf("SHA256 mismatch: expected %s, got %s (payload may have been tampered)", doc.SHA256Hex, calculatedSHA256),
+		}
+	}
+
+	return Check{
+		Component: "payload",
+		Status:    "ok",
+		Message:   fmt.Sprintf("Payload stored in database, source_id=%s, SHA256=%s", payload.SourceID, calculatedSHA256),
+	}
+}
@@ -0,0 +1,41 @@
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+)
+
+// VerifyTreeHeadSignature vérifie un JWS scellant une tête du ledger : signature du
+// coffre (JWKS du coffre) ou contre-signature d'un témoin (JWKS des témoins).
+// keys : crypto.KeySet (JWKS obtenu hors bande) ou *crypto.Service
+func VerifyTreeHeadSignature(head ledger.TreeHead, jws string, keys EvidenceVerifier) (*crypto.Evidence, error) {
+	evidence, err := keys.VerifyEvidence(jws)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tree head signature: %w", err)
+	}
+
+	headJSON, err := json.Marshal(head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tree head: %w", err)
+	}
+	hash := sha256.Sum256(headJSON)
+	if evidence.DocumentID != ledger.TreeHeadSubject || evidence.Sha256 != hex.EncodeToString(hash[:]) {
+		return nil, fmt.Errorf("tree head signature does not match the head")
+	}
+	if ledger.ComputeTreeHeadHash(head.Chains) != head.HeadHash {
+		return nil, fmt.Errorf("tree head hash does not match its chains")
+	}
+	size := int64(0)
+	for _, c := range head.Chains {
+		size += c.Seq
+	}
+	if size != head.TreeSize {
+		return nil, fmt.Errorf("tree size does not match its chains")
+	}
+	return evidence, nil
+}
@@ -0,0 +1,307 @@
+// Package witness implémente le témoin externe du ledger : il suit les têtes signées
+// publiées par un ou plusieurs coffres, vérifie que chaque nouvelle tête prolonge la
+// précédente (aucune réécriture de l'historique) puis la contre-signe
+package witness
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/doreviateam/dorevia-vault/internal/webhooks"
+	"github.com/rs/zerolog"
+)
+
+// maxResponseSize borne la taille d'une réponse du coffre (preuve de cohérence incluse)
+const maxResponseSize = 256 << 20
+
+// ErrInconsistent est retourné quand un coffre présente une tête qui ne prolonge pas la
+// dernière tête témoignée (fork, retour arrière ou réécriture de l'historique)
+var ErrInconsistent = errors.New("ledger history inconsistent with witnessed tree head")
+
+// Vault est un coffre suivi par le témoin
+type Vault struct {
+	Name   string // identifiant du coffre (fichier d'état)
+	URL    string // URL de base (ex: https://vault.example.com)
+	APIKey string // clé API (permission ledger:read), optionnelle
+}
+
+// State est la dernière tête témoignée d'un coffre
+type State struct {
+	TreeHeadID int64           `json:"tree_head_id"`
+	Head       ledger.TreeHead `json:"head"`
+	JWS        string          `json:"jws"`
+	CheckedAt  time.Time       `json:"checked_at"`
+}
+
+// Config configure le témoin
+type Config struct {
+	WitnessID      string              // identifiant du témoin, transmis avec ses contre-signatures
+	Signer         ledger.ReportSigner // clé du témoin (*crypto.Service)
+	StateDir       string              // répertoire des dernières têtes témoignées (<vault>.json)
+	HTTPClient     *http.Client
+	WebhookManager *webhooks.Manager // alertes error.critical (optionnel)
+	Logger         zerolog.Logger
+}
+
+// Witness suit et contre-signe les têtes signées des coffres
+type Witness struct {
+	cfg    Config
+	client *http.Client
+}
+
+// New crée un témoin
+func New(cfg Config) *Witness {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: time.Minute}
+	}
+	return &Witness{cfg: cfg, client: client}
+}
+
+var vaultNameSanitizer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ParseVaults parse une liste de coffres séparés par des virgules, "nom=url" ou "url"
+// (le nom est alors l'hôte de l'URL)
+func ParseVaults(value, apiKey string) ([]Vault, error) {
+	var vaults []Vault
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, rawURL := "", item
+		if i := strings.Index(item, "="); i > 0 && !strings.Contains(item[:i], "/") {
+			name, rawURL = item[:i], item[i+1:]
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid vault URL %q", rawURL)
+		}
+		if name == "" {
+			name = u.Host
+		}
+		name = vaultNameSanitizer.ReplaceAllString(name, "_")
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate vault name %q", name)
+		}
+		seen[name] = true
+		vaults = append(vaults, Vault{Name: name, URL: strings.TrimRight(rawURL, "/"), APIKey: apiKey})
+	}
+	if len(vaults) == 0 {
+		return nil, fmt.Errorf("no vault configured")
+	}
+	return vaults, nil
+}
+
+// Run vérifie les coffres toutes les interval (exécution immédiate) jusqu'à l'annulation de ctx
+func (w *Witness) Run(ctx context.Context, vaults []Vault, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, v := range vaults {
+			if _, err := w.Check(ctx, v); err != nil && !errors.Is(err, ErrInconsistent) {
+				w.cfg.Logger.Error().Err(err).Str("vault", v.Name).Msg("Failed to check vault tree head")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check récupère la dernière tête du coffre, vérifie sa signature et sa cohérence avec
+// la dernière tête témoignée, la contre-signe et l'enregistre comme nouvelle référence.
+// Une incohérence (ErrInconsistent) émet l'événement webhook error.critical ; la
+// référence n'avance pas tant qu'elle persiste
+func (w *Witness) Check(ctx context.Context, v Vault) (*ledger.SignedTreeHead, error) {
+	log := w.cfg.Logger.With().Str("vault", v.Name).Logger()
+
+	jwks, err := w.get(ctx, v, "/jwks.json")
+	if err != nil {
+		return nil, err
+	}
+	vaultKeys, err := crypto.ParseJWKS(jwks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vault JWKS: %w", err)
+	}
+
+	var latest ledger.SignedTreeHead
+	if err := w.getJSON(ctx, v, "/api/v1/ledger/tree-heads/latest", &latest); err != nil {
+		return nil, err
+	}
+	if _, err := verify.VerifyTreeHeadSignature(latest.Head, latest.JWS, vaultKeys); err != nil {
+		return nil, w.inconsistent(ctx, v, &latest, err)
+	}
+
+	state, err := w.loadState(v)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		switch {
+		case latest.ID < state.TreeHeadID:
+			return nil, w.inconsistent(ctx, v, &latest, fmt.Errorf("latest tree head %d precedes witnessed head %d", latest.ID, state.TreeHeadID))
+		case latest.ID == state.TreeHeadID:
+			if latest.Head.HeadHash != state.Head.HeadHash || latest.Head.TreeSize != state.Head.TreeSize {
+				return nil, w.inconsistent(ctx, v, &latest, fmt.Errorf("tree head %d differs from witnessed head", latest.ID))
+			}
+			// Tête déjà contre-signée (l'état n'est enregistré qu'après l'envoi)
+			return &latest, nil
+		default:
+			var proof ledger.ConsistencyProof
+			path := "/api/v1/ledger/consistency?from=" + strconv.FormatInt(state.TreeHeadID, 10) + "&to=" + strconv.FormatInt(latest.ID, 10)
+			if err := w.getJSON(ctx, v, path, &proof); err != nil {
+				return nil, err
+			}
+			if proof.From != state.TreeHeadID || proof.To != latest.ID {
+				return nil, w.inconsistent(ctx, v, &latest, fmt.Errorf("consistency proof covers %d..%d", proof.From, proof.To))
+			}
+			// La tête de référence est celle conservée par le témoin, non celle relue dans le coffre
+			if err := proof.Verify(state.Head, latest.Head); err != nil {
+				return nil, w.inconsistent(ctx, v, &latest, err)
+			}
+		}
+	}
+
+	jws, err := w.cfg.Signer.SignReport(ledger.TreeHeadSubject, latest.Head, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to cosign tree head: %w", err)
+	}
+	body, err := json.Marshal(map[string]string{"witness_id": w.cfg.WitnessID, "jws": jws})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cosignature: %w", err)
+	}
+	if _, err := w.do(ctx, v, http.MethodPost, "/api/v1/ledger/tree-heads/"+strconv.FormatInt(latest.ID, 10)+"/cosignatures", body); err != nil {
+		return nil, err
+	}
+
+	if err := w.saveState(v, &State{TreeHeadID: latest.ID, Head: latest.Head, JWS: latest.JWS, CheckedAt: time.Now().UTC()}); err != nil {
+		return nil, err
+	}
+	log.Info().Int64("tree_head_id", latest.ID).Int64("tree_size", latest.Head.TreeSize).Msg("Vault tree head cosigned")
+	return &latest, nil
+}
+
+// inconsistent journalise et alerte (error.critical) puis retourne ErrInconsistent
+func (w *Witness) inconsistent(ctx context.Context, v Vault, head *ledger.SignedTreeHead, cause error) error {
+	w.cfg.Logger.Error().Err(cause).Str("vault", v.Name).Int64("tree_head_id", head.ID).Msg("Vault ledger history inconsistent with witnessed tree head")
+	if w.cfg.WebhookManager != nil {
+		if err := w.cfg.WebhookManager.EmitEvent(ctx, webhooks.EventTypeErrorCritical, "", map[string]interface{}{
+			"source":       "ledger_witness",
+			"witness_id":   w.cfg.WitnessID,
+			"vault":        v.Name,
+			"vault_url":    v.URL,
+			"tree_head_id": head.ID,
+			"error":        cause.Error(),
+		}); err != nil {
+			w.cfg.Logger.Error().Err(err).Msg("Failed to emit error.critical webhook")
+		}
+	}
+	return fmt.Errorf("%w: %v", ErrInconsistent, cause)
+}
+
+func (w *Witness) getJSON(ctx context.Context, v Vault, path string, out interface{}) error {
+	data, err := w.get(ctx, v, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+func (w *Witness) get(ctx context.Context, v Vault, path string) ([]byte, error) {
+	return w.do(ctx, v, http.MethodGet, path, nil)
+}
+
+func (w *Witness) do(ctx context.Context, v Vault, method, path string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.URL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if v.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+v.APIKey)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s: HTTP %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+func (w *Witness) statePath(v Vault) string {
+	return filepath.Join(w.cfg.StateDir, v.Name+".json")
+}
+
+// loadState lit la dernière tête témoignée du coffre ; nil au premier passage
+func (w *Witness) loadState(v Vault) (*State, error) {
+	data, err := os.ReadFile(w.statePath(v))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read witness state: %w", err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode witness state: %w", err)
+	}
+	return &state, nil
+}
+
+// saveState enregistre la tête témoignée (écriture atomique par renommage)
+func (w *Witness) saveState(v Vault, state *State) error {
+	if err := os.MkdirAll(w.cfg.StateDir, 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal witness state: %w", err)
+	}
+	tmp := w.statePath(v) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write witness state: %w", err)
+	}
+	if err := os.Rename(tmp, w.statePath(v)); err != nil {
+		return fmt.Errorf("failed to write witness state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+-- Migration 006: Isolation multi-tenant
+-- Date: 2026-10
+-- Description: Ajoute la colonne tenant aux documents et au ledger
+
+-- Tenant propriétaire (NULL pour les documents hors tenant)
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS tenant TEXT;
+ALTER TABLE ledger ADD COLUMN IF NOT EXISTS tenant TEXT;
+
+-- Reprise : les tickets POS portaient déjà le tenant dans payload_json
+UPDATE documents SET tenant = payload_json->>'tenant'
+WHERE tenant IS NULL AND source = 'pos' AND payload_json ? 'tenant';
+
+UPDATE ledger l SET tenant = d.tenant
+FROM documents d
+WHERE l.document_id = d.id AND l.tenant IS NULL AND d.tenant IS NOT NULL;
+
+-- Index pour filtrage par tenant
+CREATE INDEX IF NOT EXISTS idx_documents_tenant ON documents(tenant);
+CREATE INDEX IF NOT EXISTS idx_documents_tenant_sha256 ON documents(tenant, sha256_hex);
+
+-- Index pour SELECT previous_hash par chaîne de tenant (LEDGER_PER_TENANT_CHAIN)
+CREATE INDEX IF NOT EXISTS idx_ledger_tenant_ts_id_desc ON ledger(tenant, timestamp DESC, id DESC);
@@ -0,0 +1,23 @@
+-- Migration 007: Clés API persistées
+-- Date: 2025-02
+-- Description: Table des clés API (hash SHA256 uniquement, clé en clair jamais stockée)
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    key_id       TEXT PRIMARY KEY,
+    key_hash     TEXT NOT NULL UNIQUE,
+    name         TEXT,
+    user_id      TEXT NOT NULL,
+    role         TEXT NOT NULL,
+    tenant       TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ,
+    is_active    BOOLEAN NOT NULL DEFAULT true,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ,
+    rotated_from TEXT REFERENCES api_keys(key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant ON api_keys(tenant);
+
+COMMENT ON TABLE api_keys IS 'Clés API (hash SHA256 base64) pour les connecteurs (Odoo, POS)';
+COMMENT ON COLUMN api_keys.rotated_from IS 'Clé remplacée lors d''une rotation';
@@ -0,0 +1,23 @@
+-- Migration 008: Clôtures mensuelles
+-- Date: 2026-10
+-- Description: Périodes clôturées (NF525) ; archive scellée, racine de Merkle et dernier maillon du ledger
+
+CREATE TABLE IF NOT EXISTS closings (
+    period             TEXT PRIMARY KEY,
+    period_start       TIMESTAMPTZ NOT NULL,
+    period_end         TIMESTAMPTZ NOT NULL,
+    document_count     BIGINT NOT NULL,
+    ledger_entry_count BIGINT NOT NULL,
+    merkle_root        TEXT NOT NULL,
+    last_ledger_hash   TEXT,
+    archive_path       TEXT NOT NULL,
+    archive_sha256     TEXT NOT NULL,
+    manifest_jws       TEXT NOT NULL,
+    closed_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    closed_by          TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_closings_range ON closings(period_start, period_end);
+
+COMMENT ON TABLE closings IS 'Clôtures mensuelles : les documents datés d''une période clôturée sont refusés';
+COMMENT ON COLUMN closings.merkle_root IS 'Racine RFC 6962 des SHA256 des documents de la période (ordre created_at, id)';
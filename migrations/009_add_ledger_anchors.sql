@@ -0,0 +1,24 @@
+-- Migration 009: Ancrages horodatés du ledger
+-- Date: 2026-10
+-- Description: Jetons d'horodatage RFC 3161 (TSA) obtenus sur la tête de la chaîne du ledger
+
+CREATE TABLE IF NOT EXISTS ledger_anchors (
+    id               BIGSERIAL PRIMARY KEY,
+    ledger_entry_id  BIGINT NOT NULL,
+    ledger_hash      TEXT NOT NULL,
+    ledger_timestamp TIMESTAMPTZ NOT NULL,
+    tenant           TEXT,
+    per_tenant_chain BOOLEAN NOT NULL DEFAULT false,
+    tsa_url          TEXT NOT NULL,
+    token            BYTEA NOT NULL,
+    gen_time         TIMESTAMPTZ NOT NULL,
+    serial_number    TEXT NOT NULL,
+    policy           TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_ledger_anchors_hash ON ledger_anchors(ledger_hash);
+CREATE INDEX IF NOT EXISTS idx_ledger_anchors_position ON ledger_anchors(ledger_timestamp, ledger_entry_id);
+
+COMMENT ON TABLE ledger_anchors IS 'Ancrages RFC 3161 : une TSA atteste l''existence de la chaîne du ledger jusqu''à ledger_hash à gen_time';
+COMMENT ON COLUMN ledger_anchors.token IS 'TimeStampToken DER (CMS SignedData) ; empreinte SHA-256 = ledger_hash décodé';
@@ -0,0 +1,23 @@
+-- Migration 010: Racines de Merkle du ledger
+-- Date: 2026-10
+-- Description: Arbre de Merkle journalier (RFC 6962) des entrées du ledger, racine signée (JWS) pour les preuves d'inclusion
+
+CREATE TABLE IF NOT EXISTS ledger_merkle_roots (
+    id              BIGSERIAL PRIMARY KEY,
+    batch_date      DATE NOT NULL,
+    tree_size       BIGINT NOT NULL,
+    root_hash       TEXT NOT NULL,
+    first_entry_id  BIGINT NOT NULL,
+    last_entry_id   BIGINT NOT NULL,
+    first_timestamp TIMESTAMPTZ NOT NULL,
+    last_timestamp  TIMESTAMPTZ NOT NULL,
+    leaf_hashes     BYTEA NOT NULL,
+    root_jws        TEXT NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_ledger_merkle_roots_batch ON ledger_merkle_roots(batch_date);
+
+COMMENT ON TABLE ledger_merkle_roots IS 'Racines de Merkle journalières (UTC) des entrées du ledger, ordre (timestamp, id)';
+COMMENT ON COLUMN ledger_merkle_roots.leaf_hashes IS 'Hashes des feuilles (32 octets chacun, dans l''ordre) pour le calcul des chemins d''audit';
+COMMENT ON COLUMN ledger_merkle_roots.root_jws IS 'JWS (crypto.Service.SignReport) de la racine, sujet ledger-merkle-root:YYYY-MM-DD';
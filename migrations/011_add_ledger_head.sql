@@ -0,0 +1,16 @@
+-- Migration 011: Tête de chaîne du ledger
+-- Date: 2026-10
+-- Description: Ligne ledger_head par chaîne (verrou unique des ajouts) et numéro de séquence sans trou
+
+CREATE TABLE IF NOT EXISTS ledger_head (
+    chain_id   TEXT PRIMARY KEY,
+    seq        BIGINT NOT NULL,
+    hash       TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Numéro de séquence dans la chaîne (NULL pour les entrées antérieures à ledger_head)
+ALTER TABLE ledger ADD COLUMN IF NOT EXISTS seq BIGINT;
+
+COMMENT ON TABLE ledger_head IS 'Dernier maillon de chaque chaîne du ledger : global, ou tenant:<tenant> (LEDGER_PER_TENANT_CHAIN)';
+COMMENT ON COLUMN ledger_head.seq IS 'Numéro de séquence de la dernière entrée ; la ligne est initialisée au premier ajout depuis le ledger existant';
@@ -0,0 +1,24 @@
+-- Migration 012: Archives des partitions du ledger
+-- Date: 2026-10
+-- Description: Partitions mensuelles détachées du ledger, archivées en fichiers compressés avec manifeste
+
+CREATE TABLE IF NOT EXISTS ledger_partition_archives (
+    partition_name TEXT PRIMARY KEY,
+    period_start   TIMESTAMPTZ NOT NULL,
+    period_end     TIMESTAMPTZ NOT NULL,
+    file_path      TEXT NOT NULL,
+    sha256_hex     TEXT NOT NULL,
+    entry_count    BIGINT NOT NULL,
+    head_hash      TEXT,
+    tenant_heads   JSONB NOT NULL DEFAULT '{}',
+    manifest       JSONB NOT NULL,
+    archived_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    restored_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_partition_archives_period ON ledger_partition_archives(period_start);
+
+COMMENT ON TABLE ledger_partition_archives IS 'Partitions ledger_YYYY_MM détachées et archivées (ledgerctl, LEDGER_PARTITION_RETENTION_MONTHS)';
+COMMENT ON COLUMN ledger_partition_archives.head_hash IS 'Dernier hash de la partition (chaîne globale) : ancrage de la vérification des entrées suivantes';
+COMMENT ON COLUMN ledger_partition_archives.tenant_heads IS 'Dernier hash de chaque tenant dans la partition (LEDGER_PER_TENANT_CHAIN)';
+COMMENT ON COLUMN ledger_partition_archives.restored_at IS 'Date de ré-attachement pour audit (NULL : partition détachée)';
@@ -0,0 +1,8 @@
+-- Migration 013: Format v2 des entrées du ledger
+-- Date: 2026-10
+-- Description: Version du format de hash de chaque entrée (v1 et v2 coexistent dans une même chaîne)
+
+-- Entrées existantes : v1, hash = SHA256(previous_hash + sha256_document)
+ALTER TABLE ledger ADD COLUMN IF NOT EXISTS hash_version SMALLINT NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN ledger.hash_version IS 'Format du hash : 1 = SHA256(previous_hash + sha256_document), 2 = encodage canonique de seq, timestamp, document_id, sha256, evidence_jws et previous_hash (LEDGER_HASH_VERSION)';
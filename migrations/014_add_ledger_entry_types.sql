@@ -0,0 +1,15 @@
+-- Migration 014: Entrées typées du ledger
+-- Date: 2026-10
+-- Description: Événements du cycle de vie des documents chaînés dans le ledger (NF525)
+
+-- Entrées existantes : enregistrement d'un document
+ALTER TABLE ledger ADD COLUMN IF NOT EXISTS entry_type TEXT NOT NULL DEFAULT 'document.created';
+
+-- Corps JSON canonique d'un événement, conservé tel quel (TEXT et non JSONB : le hash porte sur ces octets)
+ALTER TABLE ledger ADD COLUMN IF NOT EXISTS body TEXT;
+
+-- Événements sans document (key.rotated, period.closed)
+ALTER TABLE ledger ALTER COLUMN document_id DROP NOT NULL;
+
+COMMENT ON COLUMN ledger.entry_type IS 'Type d''entrée : document.created, document.status_changed, key.rotated, period.closed, document.purged';
+COMMENT ON COLUMN ledger.body IS 'Corps JSON canonique d''un événement (NULL pour document.created)';
@@ -0,0 +1,30 @@
+-- Migration 015: Têtes de ledger signées et contre-signatures des témoins
+-- Date: 2026-10
+-- Description: Tête signée (taille, hash, horodatage) publiée périodiquement ; des témoins
+-- externes (cmd/witness) vérifient sa cohérence avec la précédente et la contre-signent
+
+CREATE TABLE IF NOT EXISTS ledger_tree_heads (
+    id         BIGSERIAL PRIMARY KEY,
+    tree_size  BIGINT NOT NULL,
+    head_hash  TEXT NOT NULL,
+    chains     JSONB NOT NULL,
+    timestamp  TIMESTAMPTZ NOT NULL,
+    jws        TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS ledger_tree_head_cosignatures (
+    id           BIGSERIAL PRIMARY KEY,
+    tree_head_id BIGINT NOT NULL REFERENCES ledger_tree_heads(id),
+    witness_id   TEXT NOT NULL,
+    kid          TEXT NOT NULL,
+    jws          TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_ledger_tree_head_cosignatures ON ledger_tree_head_cosignatures(tree_head_id, kid);
+
+COMMENT ON TABLE ledger_tree_heads IS 'Têtes signées du ledger : taille (somme des seq), hash des têtes de chaîne (ledger_head), horodatage';
+COMMENT ON COLUMN ledger_tree_heads.chains IS 'Têtes de chaîne (chain_id, seq, hash) couvertes par head_hash';
+COMMENT ON COLUMN ledger_tree_heads.jws IS 'JWS (crypto.Service.SignReport) de la tête, sujet ledger-tree-head';
+COMMENT ON TABLE ledger_tree_head_cosignatures IS 'Contre-signatures des témoins, vérifiées avec LEDGER_WITNESS_JWKS_PATH à l''enregistrement';
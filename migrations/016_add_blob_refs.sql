@@ -0,0 +1,18 @@
+-- Migration 016: Disposition par contenu du stockage (déduplication)
+-- Date: 2026-10
+-- Description: Avec STORAGE_LAYOUT=content, le contenu est stocké sous ab/cd/<sha256> et
+-- partagé entre documents ; blob_refs compte les documents qui référencent chaque contenu
+
+CREATE TABLE IF NOT EXISTS blob_refs (
+    ref        TEXT PRIMARY KEY,
+    sha256_hex TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    ref_count  INTEGER NOT NULL CHECK (ref_count >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_documents_stored_path ON documents(stored_path);
+
+COMMENT ON TABLE blob_refs IS 'Contenus partagés (disposition par contenu) et nombre de documents qui les référencent';
+COMMENT ON COLUMN blob_refs.ref IS 'Référence du contenu (fs:ab/cd/<sha256>, s3://bucket/prefix/ab/cd/<sha256>), égale à documents.stored_path';
+COMMENT ON COLUMN blob_refs.ref_count IS 'Documents référençant le contenu ; supprimé par cmd/reconcile une fois à zéro';
@@ -0,0 +1,16 @@
+-- Migration 017: Chiffrement au repos du contenu des documents
+-- Date: 2026-10
+-- Description: Avec DOCUMENT_ENCRYPTION_ENABLED, chaque contenu est chiffré (AES-256-GCM)
+-- par une clé de données enveloppée par une KEK du KeyManager (fichiers ou HashiCorp Vault).
+-- sha256_hex et les preuves portent toujours sur le contenu en clair
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS dek_wrapped TEXT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS kek_version TEXT;
+ALTER TABLE blob_refs ADD COLUMN IF NOT EXISTS dek_wrapped TEXT;
+ALTER TABLE blob_refs ADD COLUMN IF NOT EXISTS kek_version TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_documents_kek_version ON documents(kek_version) WHERE kek_version IS NOT NULL;
+
+COMMENT ON COLUMN documents.dek_wrapped IS 'Clé de données du contenu, enveloppée (RSA-OAEP SHA-256, base64) ; NULL si le contenu est en clair';
+COMMENT ON COLUMN documents.kek_version IS 'Version (KID) de la KEK qui enveloppe dek_wrapped ; cmd/rewrap l''enveloppe par la KEK courante';
+COMMENT ON COLUMN blob_refs.dek_wrapped IS 'Clé de données du contenu partagé, reprise par chaque document qui le référence';
@@ -0,0 +1,54 @@
+-- Migration 018: Rétention, blocage légal et purge auditée
+-- Date: 2026-10
+-- Description: Durées de conservation par source et modèle Odoo (RETENTION_POLICIES).
+-- Une fois la durée échue, le contenu est supprimé et le document devient une pierre
+-- tombale (métadonnées, SHA256, preuve JWS). Un blocage légal interdit purge et suppression.
+-- Les entrées du ledger ne sont plus supprimées en cascade avec le document
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold_reason TEXT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold_by TEXT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS legal_hold_at TIMESTAMPTZ;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS purged_at TIMESTAMPTZ;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS purge_reason TEXT;
+
+-- Recherche des documents échus
+CREATE INDEX IF NOT EXISTS idx_documents_retention ON documents(created_at)
+    WHERE purged_at IS NULL AND NOT legal_hold;
+
+-- Blocage légal : ni suppression ni purge
+CREATE OR REPLACE FUNCTION documents_legal_hold_guard() RETURNS trigger AS $$
+BEGIN
+    IF OLD.legal_hold AND (TG_OP = 'DELETE' OR (NEW.purged_at IS NOT NULL AND OLD.purged_at IS NULL)) THEN
+        RAISE EXCEPTION 'document % is under legal hold', OLD.id;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_documents_legal_hold ON documents;
+CREATE TRIGGER trg_documents_legal_hold BEFORE UPDATE OR DELETE ON documents
+    FOR EACH ROW EXECUTE FUNCTION documents_legal_hold_guard();
+
+-- Le ledger ne perd jamais d'entrée : ON DELETE CASCADE remplacé par ON DELETE RESTRICT
+DO $$
+DECLARE
+    fk RECORD;
+BEGIN
+    FOR fk IN
+        SELECT conname FROM pg_constraint
+        WHERE conrelid = 'ledger'::regclass AND contype = 'f'
+          AND confrelid = 'documents'::regclass AND confdeltype <> 'r'
+          AND conparentid = 0
+    LOOP
+        EXECUTE format('ALTER TABLE ledger DROP CONSTRAINT %I', fk.conname);
+        EXECUTE format('ALTER TABLE ledger ADD CONSTRAINT %I FOREIGN KEY (document_id) REFERENCES documents(id) ON DELETE RESTRICT', fk.conname);
+    END LOOP;
+END $$;
+
+COMMENT ON COLUMN documents.legal_hold IS 'Blocage légal : le document ne peut être ni purgé ni supprimé';
+COMMENT ON COLUMN documents.purged_at IS 'Date de purge du contenu (rétention échue) ; la ligne reste comme pierre tombale avec sha256_hex';
+COMMENT ON COLUMN documents.purge_reason IS 'Règle de rétention appliquée, ex. retention sales/account.move=10y';
@@ -0,0 +1,23 @@
+-- Migration 019: Uploads reprenables des documents volumineux
+-- Date: 2026-10
+-- Description: Le contenu est reçu par morceaux (SHA256 de chaque morceau vérifié) dans
+-- un fichier temporaire de UPLOAD_SESSIONS_DIR. La reprise se fait depuis offset_bytes ;
+-- le document n'est scellé qu'une fois le SHA256 du contenu assemblé vérifié
+
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id           UUID PRIMARY KEY,
+    tenant       TEXT,
+    size_bytes   BIGINT NOT NULL CHECK (size_bytes > 0),
+    sha256_hex   TEXT NOT NULL,
+    offset_bytes BIGINT NOT NULL DEFAULT 0 CHECK (offset_bytes >= 0 AND offset_bytes <= size_bytes),
+    status       TEXT NOT NULL DEFAULT 'open',  -- open | sealing | completed
+    metadata     JSONB NOT NULL DEFAULT '{}',   -- champs de la facture
+    document_id  UUID,                          -- document enregistré (completed)
+    created_by   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+-- Purge des uploads abandonnés
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires_at ON upload_sessions(expires_at);
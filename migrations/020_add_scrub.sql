@@ -0,0 +1,36 @@
+-- Migration 020: Vérification en arrière-plan du contenu stocké (scrub)
+-- Date: 2026-10
+-- Description: Chaque contenu stocké (fichier, objet, payload POS) est relu et son SHA256
+-- comparé à sha256_hex, à débit limité. Le curseur (created_at, id) permet de reprendre la
+-- passe en cours après un redémarrage ; les altérations détectées sont enregistrées
+
+CREATE TABLE IF NOT EXISTS scrub_state (
+    id                     SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),  -- ligne unique
+    cursor_created_at      TIMESTAMPTZ,  -- dernier document vérifié de la passe en cours
+    cursor_id              UUID,
+    pass_started_at        TIMESTAMPTZ,
+    passes_completed       BIGINT NOT NULL DEFAULT 0,
+    last_pass_completed_at TIMESTAMPTZ,
+    updated_at             TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS scrub_corruptions (
+    document_id     UUID PRIMARY KEY REFERENCES documents(id) ON DELETE CASCADE,
+    tenant          TEXT,
+    kind            TEXT NOT NULL,  -- sha256_mismatch | missing | unreadable | payload_mismatch
+    stored_path     TEXT,
+    expected_sha256 TEXT NOT NULL,
+    actual_sha256   TEXT,           -- SHA256 relu (sha256_mismatch)
+    detail          TEXT,
+    detected_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at     TIMESTAMPTZ     -- contenu relu conforme (restauration)
+);
+
+-- Altérations non résolues (gauge, alertes)
+CREATE INDEX IF NOT EXISTS idx_scrub_corruptions_open ON scrub_corruptions(detected_at)
+    WHERE resolved_at IS NULL;
+
+-- Parcours des documents par curseur (created_at, id)
+CREATE INDEX IF NOT EXISTS idx_documents_scrub ON documents(created_at, id)
+    WHERE purged_at IS NULL;
@@ -0,0 +1,11 @@
+-- Migration 021: Marqueur des documents orphelins
+-- Date: 2026-10
+-- Description: La réconciliation (cmd/reconcile --fix, POST /api/v1/admin/reconcile)
+-- marque les documents dont le contenu est introuvable au lieu de les supprimer ; le
+-- marqueur est retiré quand le contenu est retrouvé (restauration)
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS orphaned_at TIMESTAMPTZ;
+
+-- Documents marqués orphelins
+CREATE INDEX IF NOT EXISTS idx_documents_orphaned_at ON documents(orphaned_at)
+    WHERE orphaned_at IS NOT NULL;
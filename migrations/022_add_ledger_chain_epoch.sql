@@ -0,0 +1,20 @@
+-- Migration 022: Passage aux chaînes par tenant
+-- Date: 2026-10
+-- Description: La ligne 'global' de ledger_head enregistre la dernière entrée de la chaîne
+-- globale au démarrage de la première chaîne par tenant (LEDGER_PER_TENANT_CHAIN) : les
+-- entrées d'id inférieur ou égal restent vérifiées dans la chaîne globale, les chaînes
+-- par tenant démarrent sur son hash
+
+ALTER TABLE ledger_head ADD COLUMN IF NOT EXISTS tenant_epoch_id BIGINT;
+ALTER TABLE ledger_head ADD COLUMN IF NOT EXISTS tenant_epoch_hash TEXT;
+
+-- Ledger déjà chaîné par tenant avant cette migration : les chaînes par tenant partent
+-- du début du ledger
+INSERT INTO ledger_head (chain_id, seq, hash, tenant_epoch_id)
+SELECT 'global', 0, NULL, 0
+WHERE EXISTS (SELECT 1 FROM ledger_head WHERE chain_id LIKE 'tenant:%')
+ON CONFLICT (chain_id) DO UPDATE SET tenant_epoch_id = 0
+WHERE ledger_head.tenant_epoch_id IS NULL;
+
+COMMENT ON COLUMN ledger_head.tenant_epoch_id IS 'Ligne global : id de la dernière entrée de la chaîne globale avant les chaînes par tenant (0 : aucune)';
+COMMENT ON COLUMN ledger_head.tenant_epoch_hash IS 'Ligne global : hash de cette entrée, previous_hash de la première entrée de chaque chaîne par tenant';
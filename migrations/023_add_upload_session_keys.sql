@@ -0,0 +1,10 @@
+-- Migration 023: Chiffrement des morceaux des uploads reprenables
+-- Date: 2026-10
+-- Description: Clé de données enveloppée (DOCUMENT_ENCRYPTION_ENABLED) des morceaux reçus
+-- par un upload reprenable : le contenu n'est jamais écrit en clair dans UPLOAD_SESSIONS_DIR
+
+ALTER TABLE upload_sessions ADD COLUMN IF NOT EXISTS dek_wrapped TEXT;
+ALTER TABLE upload_sessions ADD COLUMN IF NOT EXISTS kek_version TEXT;
+
+COMMENT ON COLUMN upload_sessions.dek_wrapped IS 'Clé de données des morceaux, enveloppée par la KEK (NULL : morceaux en clair)';
+COMMENT ON COLUMN upload_sessions.kek_version IS 'Version (KID) de la KEK qui enveloppe dek_wrapped';
@@ -0,0 +1,11 @@
+-- Migration 024: Date de rétention des documents
+-- Date: 2026-10
+-- Description: La conservation court à partir de la date de la facture (invoice_date), à
+-- défaut de la date de réception (created_at, UTC). L'index remplace idx_documents_retention
+-- et porte aussi l'id : la purge parcourt les documents échus par curseur
+
+DROP INDEX IF EXISTS idx_documents_retention;
+
+CREATE INDEX IF NOT EXISTS idx_documents_retention_date
+    ON documents ((COALESCE(invoice_date::timestamp, created_at AT TIME ZONE 'UTC')), id)
+    WHERE purged_at IS NULL AND NOT legal_hold;
@@ -0,0 +1,73 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAPIKeys_Lifecycle teste création, authentification, rotation et révocation en base
+func TestAPIKeys_Lifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	plaintext, keyID, keyHash, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+	key := &auth.APIKey{
+		KeyID:     keyID,
+		KeyHash:   keyHash,
+		Name:      "odoo-test",
+		UserID:    "odoo-test",
+		Role:      "operator",
+		Tenant:    "test-tenant",
+		CreatedAt: time.Now().UTC(),
+		IsActive:  true,
+	}
+	require.NoError(t, db.CreateAPIKey(ctx, key))
+	defer db.Pool.Exec(ctx, "DELETE FROM api_keys WHERE tenant = 'test-tenant'")
+
+	service := auth.NewAuthService(auth.AuthConfig{
+		APIKeyEnabled:  true,
+		APIKeyStore:    db,
+		APIKeyCacheTTL: time.Millisecond,
+		Logger:         *logger.New("error"),
+	})
+
+	userInfo, err := service.Authenticate(ctx, "apikey "+plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, "test-tenant", userInfo.Tenant)
+
+	stored, err := db.GetAPIKey(ctx, keyID)
+	require.NoError(t, err)
+	assert.NotNil(t, stored.LastUsedAt)
+
+	// Rotation : l'ancienne clé est révoquée, la nouvelle hérite des attributs
+	newPlaintext, newKeyID, newKeyHash, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+	rotated := &auth.APIKey{KeyID: newKeyID, KeyHash: newKeyHash, CreatedAt: time.Now().UTC()}
+	require.NoError(t, db.RotateAPIKey(ctx, keyID, rotated))
+	assert.Equal(t, "operator", rotated.Role)
+	assert.Equal(t, "test-tenant", rotated.Tenant)
+
+	service.InvalidateAPIKey(keyID)
+	_, err = service.Authenticate(ctx, "apikey "+plaintext)
+	assert.Error(t, err)
+	_, err = service.Authenticate(ctx, "apikey "+newPlaintext)
+	require.NoError(t, err)
+
+	// Révocation
+	require.NoError(t, db.RevokeAPIKey(ctx, newKeyID))
+	service.InvalidateAPIKey(newKeyID)
+	_, err = service.Authenticate(ctx, "apikey "+newPlaintext)
+	assert.Error(t, err)
+
+	keys, err := db.ListAPIKeys(ctx, "test-tenant")
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+}
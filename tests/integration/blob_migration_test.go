@@ -0,0 +1,176 @@
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryS3 est un stockage objet minimal (PUT, GET, HEAD, DELETE) pour la migration
+type memoryS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (m *memoryS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		m.objects[r.URL.Path], _ = io.ReadAll(r.Body)
+	case http.MethodGet, http.MethodHead:
+		body, ok := m.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(m.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// TestMigrateBlobs teste la migration fs → s3 : contenus vérifiés, références mises à
+// jour, document altéré laissé en place
+func TestMigrateBlobs(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	s3Server := httptest.NewServer(&memoryS3{objects: map[string][]byte{}})
+	defer s3Server.Close()
+	s3, err := blobstore.NewS3Store(blobstore.S3Config{Endpoint: s3Server.URL, Bucket: "archive", PathStyle: true})
+	require.NoError(t, err)
+	fs := blobstore.NewFileStore(t.TempDir())
+	db.SetBlobStore(blobstore.NewMux(fs, s3))
+
+	createdAt := time.Date(2001, 4, 2, 10, 0, 0, 0, time.UTC)
+	insert := func(content, stored string) (uuid.UUID, string) {
+		docID := uuid.New()
+		sum := sha256.Sum256([]byte(content))
+		ref, err := fs.Put(ctx, blobstore.DateKey(createdAt, docID, "facture.pdf"), strings.NewReader(stored), int64(len(stored)), blobstore.PutOptions{})
+		require.NoError(t, err)
+		_, err = db.Pool.Exec(ctx, `
+			INSERT INTO documents (id, filename, content_type, size_bytes, sha256_hex, stored_path, created_at)
+			VALUES ($1, 'facture.pdf', 'application/pdf', $2, $3, $4, $5)
+		`, docID, len(content), hex.EncodeToString(sum[:]), ref, createdAt)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Pool.Exec(ctx, "DELETE FROM documents WHERE id = $1", docID) })
+		return docID, ref
+	}
+	intactID, intactRef := insert("%PDF-1.4 facture intacte", "%PDF-1.4 facture intacte")
+	alteredID, alteredRef := insert("%PDF-1.4 facture modifiée", "%PDF-1.4 facture MODIFIÉE")
+
+	// Dry-run : rien n'est copié, l'altération est détectée
+	report, err := db.MigrateBlobs(ctx, fs, s3, storage.BlobMigrationOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Migrated)
+	require.NotEmpty(t, report.Failures)
+
+	report, err = db.MigrateBlobs(ctx, fs, s3, storage.BlobMigrationOptions{DeleteSource: true})
+	require.NoError(t, err)
+
+	doc, err := db.GetDocumentByID(ctx, intactID)
+	require.NoError(t, err)
+	assert.True(t, s3.Owns(doc.StoredPath), doc.StoredPath)
+	content, err := db.ReadDocumentContent(ctx, doc)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4 facture intacte", string(content))
+	_, err = fs.Stat(ctx, intactRef)
+	assert.ErrorIs(t, err, blobstore.ErrNotFound, "source deleted after verified copy")
+
+	doc, err = db.GetDocumentByID(ctx, alteredID)
+	require.NoError(t, err)
+	assert.Equal(t, alteredRef, doc.StoredPath, "altered content keeps its reference")
+	var failed []string
+	for _, f := range report.Failures {
+		failed = append(failed, f.DocumentID)
+	}
+	assert.Contains(t, failed, alteredID.String())
+	assert.NotContains(t, failed, intactID.String())
+}
+
+// TestContentLayout teste la disposition par contenu : un contenu identique reçu pour
+// deux tenants n'est stocké qu'une fois, les contenus par date sont migrés et un contenu
+// partagé n'est orphelin qu'une fois sans référence
+func TestContentLayout(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	fs := blobstore.NewFileStore(t.TempDir())
+	db.SetBlobStore(blobstore.NewMux(fs))
+	content := []byte("%PDF-1.4 facture partagée " + uuid.NewString())
+	sum := sha256.Sum256(content)
+	sha256Hex := hex.EncodeToString(sum[:])
+	t.Cleanup(func() { db.Pool.Exec(ctx, "DELETE FROM blob_refs WHERE sha256_hex = $1", sha256Hex) })
+
+	store := func(tenant string) *models.Document {
+		doc := &models.Document{Filename: "../../facture.pdf", ContentType: "application/pdf", SizeBytes: int64(len(content)), Tenant: &tenant}
+		require.NoError(t, db.StoreDocumentWithTransaction(ctx, doc, content, ""))
+		t.Cleanup(func() { db.Pool.Exec(ctx, "DELETE FROM documents WHERE id = $1", doc.ID) })
+		return doc
+	}
+
+	// Disposition par date : un objet par document, nom de fichier neutralisé
+	dated := store("tenant-date-" + uuid.NewString())
+	assert.False(t, blobstore.IsContentRef(dated.StoredPath, sha256Hex))
+	assert.NotContains(t, dated.StoredPath, "../")
+
+	require.NoError(t, db.SetStorageLayout(blobstore.LayoutContent))
+	a := store("tenant-a-" + uuid.NewString())
+	b := store("tenant-b-" + uuid.NewString())
+	assert.Equal(t, fs.Ref(blobstore.ContentKey(sha256Hex)), a.StoredPath)
+	assert.Equal(t, a.StoredPath, b.StoredPath)
+
+	refCount := func() int {
+		var n int
+		require.NoError(t, db.Pool.QueryRow(ctx, "SELECT ref_count FROM blob_refs WHERE ref = $1", a.StoredPath).Scan(&n))
+		return n
+	}
+	assert.Equal(t, 2, refCount())
+
+	// Migration de la disposition : le document par date rejoint le contenu partagé
+	report, err := db.MigrateToContentLayout(ctx, storage.BlobMigrationOptions{DeleteSource: true})
+	require.NoError(t, err)
+	assert.Empty(t, report.Failures)
+	assert.GreaterOrEqual(t, report.Shared, 1)
+	doc, err := db.GetDocumentByID(ctx, dated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, a.StoredPath, doc.StoredPath)
+	assert.Equal(t, 3, refCount())
+	_, err = fs.Stat(ctx, dated.StoredPath)
+	assert.ErrorIs(t, err, blobstore.ErrNotFound)
+
+	// Contenu référencé : jamais supprimé comme orphelin
+	deleted, err := db.DeleteUnreferencedBlob(ctx, fs, a.StoredPath, sha256Hex)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	_, err = db.Pool.Exec(ctx, "DELETE FROM ledger WHERE document_id IN (SELECT id FROM documents WHERE stored_path = $1)", a.StoredPath)
+	require.NoError(t, err)
+	_, err = db.Pool.Exec(ctx, "DELETE FROM documents WHERE stored_path = $1", a.StoredPath)
+	require.NoError(t, err)
+	_, err = db.Pool.Exec(ctx, "UPDATE blob_refs SET ref_count = 0 WHERE ref = $1", a.StoredPath)
+	require.NoError(t, err)
+	deleted, err = db.DeleteUnreferencedBlob(ctx, fs, a.StoredPath, sha256Hex)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	_, err = fs.Stat(ctx, a.StoredPath)
+	assert.ErrorIs(t, err, blobstore.ErrNotFound)
+}
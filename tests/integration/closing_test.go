@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/closing"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClosing_CloseAndVerify teste la clôture d'une période, la vérification hors
+// ligne de l'archive et le refus des documents antidatés
+func TestClosing_CloseAndVerify(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+
+	// Période improbable pour ne pas interférer avec les autres tests
+	period, err := closing.ParsePeriod("2001-03")
+	require.NoError(t, err)
+	createdAt := time.Date(2001, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	cleanup := func() {
+		db.Pool.Exec(ctx, "DELETE FROM closings WHERE period = $1", period.String())
+		db.Pool.Exec(ctx, "DELETE FROM ledger WHERE timestamp >= $1 AND timestamp < $2", period.Start(), period.End())
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE created_at >= $1 AND created_at < $2", period.Start(), period.End())
+	}
+	cleanup()
+	defer cleanup()
+
+	content := []byte("%PDF-1.4 facture de mars 2001")
+	hash := sha256.Sum256(content)
+	shaHex := hex.EncodeToString(hash[:])
+	storedPath := filepath.Join(t.TempDir(), "facture.pdf")
+	require.NoError(t, os.WriteFile(storedPath, content, 0644))
+
+	docID := uuid.New()
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO documents (id, filename, content_type, size_bytes, sha256_hex, stored_path, created_at)
+		VALUES ($1, 'facture.pdf', 'application/pdf', $2, $3, $4, $5)
+	`, docID, len(content), shaHex, storedPath, createdAt)
+	require.NoError(t, err)
+
+	ledgerHash := ledger.ComputeHash(nil, shaHex)
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO ledger (document_id, hash, previous_hash, timestamp)
+		VALUES ($1, $2, NULL, $3)
+	`, docID, ledgerHash, createdAt)
+	require.NoError(t, err)
+
+	closer := closing.NewCloser(db, jwsService, nil, nil, closing.Config{
+		ArchiveDir: t.TempDir(),
+		Logger:     *logger.New("error"),
+	})
+
+	result, err := closer.Close(ctx, period, "test")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.DocumentCount)
+	assert.Equal(t, int64(1), result.LedgerEntryCount)
+	require.NotNil(t, result.LastLedgerHash)
+	assert.Equal(t, ledgerHash, *result.LastLedgerHash)
+
+	// L'archive se vérifie hors ligne
+	report, err := closing.VerifyArchive(result.ArchivePath, nil)
+	require.NoError(t, err)
+	assert.True(t, report.Valid, report.Errors)
+	assert.Equal(t, result.MerkleRoot, report.Manifest.MerkleRoot)
+
+	// Une seconde clôture est refusée
+	_, err = closer.Close(ctx, period, "test")
+	assert.True(t, errors.Is(err, storage.ErrClosingExists))
+
+	// Un document daté de la période clôturée est refusé
+	err = db.CheckPeriodOpen(ctx, time.Date(2001, 3, 31, 23, 0, 0, 0, time.UTC))
+	var closed storage.ErrPeriodClosed
+	require.True(t, errors.As(err, &closed))
+	assert.Equal(t, period.String(), closed.Period)
+	assert.NoError(t, db.CheckPeriodOpen(ctx, period.End()))
+
+	// Une période en cours ne peut pas être clôturée
+	_, err = closer.Close(ctx, closing.PeriodOf(time.Now()), "test")
+	assert.True(t, errors.Is(err, closing.ErrPeriodNotEnded))
+}
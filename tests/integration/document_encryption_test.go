@@ -0,0 +1,125 @@
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDocumentEncryption teste le chiffrement au repos : objet chiffré, lecture
+// transparente, une clé de données par document (même contenu compris), purge d'un
+// document sans effet sur les autres et rotation de KEK sans réécriture
+func TestDocumentEncryption(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	keys := crypto.NewDirKeyManager(t.TempDir(), zerolog.Nop())
+	require.NoError(t, keys.GenerateKeyPair(ctx, "kek-1"))
+	envelope, err := crypto.NewEnvelope(keys, "kek-1")
+	require.NoError(t, err)
+	db.SetEnvelope(envelope)
+
+	fs := blobstore.NewFileStore(t.TempDir())
+	db.SetBlobStore(blobstore.NewMux(fs))
+	content := []byte("%PDF-1.4 facture chiffrée " + uuid.NewString())
+	sum := sha256.Sum256(content)
+	sha256Hex := hex.EncodeToString(sum[:])
+	t.Cleanup(func() { db.Pool.Exec(ctx, "DELETE FROM blob_refs WHERE sha256_hex = $1", sha256Hex) })
+
+	store := func(tenant string) *models.Document {
+		doc := &models.Document{Filename: "facture.pdf", ContentType: "application/pdf", SizeBytes: int64(len(content)), Tenant: &tenant}
+		require.NoError(t, db.StoreDocumentWithTransaction(ctx, doc, content, ""))
+		t.Cleanup(func() { db.Pool.Exec(ctx, "DELETE FROM documents WHERE id = $1", doc.ID) })
+		return doc
+	}
+
+	dated := store("tenant-date-" + uuid.NewString())
+	require.NotNil(t, dated.WrappedKey)
+	assert.Equal(t, "kek-1", *dated.KEKVersion)
+	assert.Equal(t, sha256Hex, dated.SHA256Hex, "SHA256 of plaintext")
+
+	raw, err := blobstore.ReadAll(ctx, fs, dated.StoredPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "facture chiffrée")
+	assert.Equal(t, crypto.EncryptedSize(int64(len(content))), int64(len(raw)))
+
+	doc, err := db.GetDocumentByID(ctx, dated.ID)
+	require.NoError(t, err)
+	plain, err := db.ReadDocumentContent(ctx, doc)
+	require.NoError(t, err)
+	assert.Equal(t, content, plain)
+	size, err := db.ContentSize(ctx, doc)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(raw)), size)
+
+	// Disposition par contenu : un objet et une clé de données par document chiffré
+	require.NoError(t, db.SetStorageLayout(blobstore.LayoutContent))
+	a := store("tenant-a-" + uuid.NewString())
+	b := store("tenant-b-" + uuid.NewString())
+	assert.NotEqual(t, a.StoredPath, b.StoredPath)
+	assert.NotEqual(t, *a.WrappedKey, *b.WrappedKey)
+	assert.True(t, blobstore.IsDocumentContentRef(a.StoredPath, sha256Hex, a.ID))
+	assert.False(t, blobstore.IsContentRef(a.StoredPath, sha256Hex))
+	var shared int
+	require.NoError(t, db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM blob_refs WHERE sha256_hex = $1", sha256Hex).Scan(&shared))
+	assert.Zero(t, shared)
+
+	// Migration de la disposition : le document par date garde sa clé de données
+	report, err := db.MigrateToContentLayout(ctx, storage.BlobMigrationOptions{DeleteSource: true})
+	require.NoError(t, err)
+	assert.Empty(t, report.Failures)
+	doc, err = db.GetDocumentByID(ctx, dated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fs.Ref(blobstore.DocumentContentKey(sha256Hex, dated.ID)), doc.StoredPath)
+	assert.Equal(t, *dated.WrappedKey, *doc.WrappedKey)
+	plain, err = db.ReadDocumentContent(ctx, doc)
+	require.NoError(t, err)
+	assert.Equal(t, content, plain)
+
+	// Purge d'un document : son objet est supprimé, les autres documents restent lisibles
+	purge, err := db.PurgeDocument(ctx, b.ID, "test", "test")
+	require.NoError(t, err)
+	assert.True(t, purge.ContentDeleted)
+	_, err = fs.Stat(ctx, b.StoredPath)
+	assert.ErrorIs(t, err, blobstore.ErrNotFound)
+	doc, err = db.GetDocumentByID(ctx, a.ID)
+	require.NoError(t, err)
+	plain, err = db.ReadDocumentContent(ctx, doc)
+	require.NoError(t, err)
+	assert.Equal(t, content, plain)
+
+	// Rotation de KEK : clés enveloppées à nouveau, contenu inchangé
+	require.NoError(t, keys.GenerateKeyPair(ctx, "kek-2"))
+	rotated, err := crypto.NewEnvelope(keys, "kek-2")
+	require.NoError(t, err)
+	db.SetEnvelope(rotated)
+	rewrap, err := db.RewrapDocumentKeys(ctx, storage.RewrapOptions{KEKVersion: "kek-1"})
+	require.NoError(t, err)
+	assert.Empty(t, rewrap.Failures)
+	assert.GreaterOrEqual(t, rewrap.Documents, 2)
+
+	after, err := blobstore.ReadAll(ctx, fs, a.StoredPath)
+	require.NoError(t, err)
+	before, err := db.GetDocumentByID(ctx, a.ID)
+	require.NoError(t, err)
+	plain, err = db.ReadDocumentContent(ctx, before)
+	require.NoError(t, err)
+	assert.Equal(t, content, plain)
+	assert.Equal(t, "kek-2", *before.KEKVersion)
+	assert.Equal(t, crypto.EncryptedSize(int64(len(content))), int64(len(after)))
+
+	moved, err := db.GetDocumentByID(ctx, dated.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "kek-2", *moved.KEKVersion)
+}
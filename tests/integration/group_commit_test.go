@@ -0,0 +1,296 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/metrics"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/services"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGroupCommitTestService crée un service d'ingestion POS à insertions groupées
+func newGroupCommitTestService(db *storage.DB, jwsService *crypto.Service) (*services.PosTicketsService, *storage.GroupCommitRepository) {
+	repo := storage.NewGroupCommitRepository(storage.NewPostgresRepository(db.Pool, logger.New("error")), storage.GroupCommitOptions{
+		Window:   5 * time.Millisecond,
+		MaxBatch: 50,
+	})
+	ledgerService := ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: true})
+	return services.NewPosTicketsService(repo, ledgerService, crypto.NewLocalSigner(jwsService)), repo
+}
+
+// groupCommitBatches retourne le nombre de transactions groupées observées
+func groupCommitBatches() uint64 {
+	m := &dto.Metric{}
+	if err := metrics.LedgerGroupCommitBatchSize.Write(m); err != nil {
+		return 0
+	}
+	return m.Histogram.GetSampleCount()
+}
+
+// TestGroupCommit_ConcurrentIngest teste que des tickets concurrents regroupés gardent
+// chacun leur ledger_hash et leur evidence_jws, dans une chaîne valide et sans trou
+func TestGroupCommit_ConcurrentIngest(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+
+	tenant := "group-" + uuid.NewString()
+	defer cleanupHeadTenant(db, tenant)
+	service, repo := newGroupCommitTestService(db, jwsService)
+	defer repo.Close()
+
+	const tickets = 100
+	batchesBefore := groupCommitBatches()
+	results := make([]*services.PosTicketResult, tickets)
+	var wg sync.WaitGroup
+	for i := 0; i < tickets; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := service.Ingest(ctx, headTestTicket(tenant, int64(i)))
+			assert.NoError(t, err)
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+
+	// Moins de transactions que de tickets
+	batches := groupCommitBatches() - batchesBefore
+	assert.Less(t, batches, uint64(tickets))
+
+	// Réponse propre à chaque ticket, persistée
+	hashes := make(map[string]bool)
+	for _, result := range results {
+		require.NotNil(t, result)
+		require.NotNil(t, result.LedgerHash)
+		require.NotNil(t, result.EvidenceJWS)
+		assert.False(t, hashes[*result.LedgerHash], "ledger_hash dupliqué")
+		hashes[*result.LedgerHash] = true
+
+		var ledgerHash, evidenceJWS string
+		require.NoError(t, db.Pool.QueryRow(ctx, `SELECT ledger_hash, evidence_jws FROM documents WHERE id = $1`, result.ID).Scan(&ledgerHash, &evidenceJWS))
+		assert.Equal(t, *result.LedgerHash, ledgerHash)
+		assert.Equal(t, *result.EvidenceJWS, evidenceJWS)
+	}
+
+	report, err := ledger.VerifyChain(ctx, db.Pool, ledger.ChainOptions{Tenant: tenant, PerTenantChain: true})
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, tickets, report.EntriesChecked)
+	assert.Zero(t, report.GapCount)
+
+	// Un ticket déjà ingéré reste idempotent
+	again, err := service.Ingest(ctx, headTestTicket(tenant, 0))
+	require.NoError(t, err)
+	assert.Equal(t, results[0].ID, again.ID)
+}
+
+// failingBatchLedger ferme le savepoint du premier document qu'il reçoit : la transaction
+// du lot échoue alors au RELEASE SAVEPOINT, hors de toute erreur propre au document
+type failingBatchLedger struct {
+	ledger.Service
+	failed int32
+}
+
+func (l *failingBatchLedger) Append(ctx context.Context, tx pgx.Tx, tenant string, docID uuid.UUID, shaHex, jws string) (string, error) {
+	if atomic.CompareAndSwapInt32(&l.failed, 0, 1) {
+		if err := tx.Commit(ctx); err != nil {
+			return "", err
+		}
+	}
+	return l.Service.Append(ctx, tx, tenant, docID, shaHex, jws)
+}
+
+// TestGroupCommit_RetriesFailedBatch teste qu'après l'échec de la transaction d'un lot,
+// ses tickets sont réinsérés un à un au lieu d'être tous rejetés
+func TestGroupCommit_RetriesFailedBatch(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+
+	tenant := "group-retry-" + uuid.NewString()
+	defer cleanupHeadTenant(db, tenant)
+	repo := storage.NewGroupCommitRepository(storage.NewPostgresRepository(db.Pool, logger.New("error")), storage.GroupCommitOptions{
+		Window:   50 * time.Millisecond,
+		MaxBatch: 50,
+	})
+	defer repo.Close()
+	ledgerService := &failingBatchLedger{Service: ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: true})}
+	service := services.NewPosTicketsService(repo, ledgerService, crypto.NewLocalSigner(jwsService))
+
+	const tickets = 10
+	results := make([]*services.PosTicketResult, tickets)
+	var wg sync.WaitGroup
+	for i := 0; i < tickets; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := service.Ingest(ctx, headTestTicket(tenant, int64(i)))
+			assert.NoError(t, err)
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ledgerService.failed))
+
+	for _, result := range results {
+		require.NotNil(t, result)
+		require.NotNil(t, result.LedgerHash)
+		var ledgerHash string
+		require.NoError(t, db.Pool.QueryRow(ctx, `SELECT ledger_hash FROM documents WHERE id = $1`, result.ID).Scan(&ledgerHash))
+		assert.Equal(t, *result.LedgerHash, ledgerHash)
+	}
+
+	report, err := ledger.VerifyChain(ctx, db.Pool, ledger.ChainOptions{Tenant: tenant, PerTenantChain: true})
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, tickets, report.EntriesChecked)
+	assert.Zero(t, report.GapCount)
+}
+
+// committedElsewhereLedger simule un COMMIT ambigu : au premier Append, les autres
+// documents du lot sont validés dans leur propre transaction, puis la transaction du lot
+// échoue comme avec failingBatchLedger
+type committedElsewhereLedger struct {
+	ledger.Service
+	repo   *storage.PostgresRepository
+	docs   []*models.Document
+	failed int32
+}
+
+func (l *committedElsewhereLedger) Append(ctx context.Context, tx pgx.Tx, tenant string, docID uuid.UUID, shaHex, jws string) (string, error) {
+	if atomic.CompareAndSwapInt32(&l.failed, 0, 1) {
+		for _, doc := range l.docs {
+			if doc.ID == docID {
+				continue
+			}
+			stored := *doc
+			if err := l.repo.InsertDocumentWithEvidence(ctx, &stored, "stored-jws", l.Service); err != nil {
+				return "", err
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return "", err
+		}
+	}
+	return l.Service.Append(ctx, tx, tenant, docID, shaHex, jws)
+}
+
+// TestGroupCommit_RetrySkipsStoredDocuments teste qu'un document déjà en base après
+// l'échec d'un lot n'est pas réinséré : l'appelant reçoit le ledger_hash et l'evidence stockés
+func TestGroupCommit_RetrySkipsStoredDocuments(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	tenant := "group-stored-" + uuid.NewString()
+	defer cleanupHeadTenant(db, tenant)
+	postgresRepo := storage.NewPostgresRepository(db.Pool, logger.New("error"))
+	repo := storage.NewGroupCommitRepository(postgresRepo, storage.GroupCommitOptions{
+		Window:   time.Second,
+		MaxBatch: 2,
+	})
+	defer repo.Close()
+
+	docs := make([]*models.Document, 2)
+	for i := range docs {
+		content := []byte(fmt.Sprintf("group commit stored %s %d", tenant, i))
+		docs[i] = &models.Document{
+			ID:          uuid.New(),
+			Filename:    fmt.Sprintf("stored-%d.pdf", i),
+			ContentType: "application/pdf",
+			SizeBytes:   int64(len(content)),
+			SHA256Hex:   sha256Of(content),
+			StoredPath:  fmt.Sprintf("/tmp/stored-%d.pdf", i),
+			CreatedAt:   time.Now(),
+			Tenant:      &tenant,
+		}
+	}
+	ledgerService := &committedElsewhereLedger{
+		Service: ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: true}),
+		repo:    postgresRepo,
+		docs:    docs,
+	}
+
+	var wg sync.WaitGroup
+	for _, doc := range docs {
+		wg.Add(1)
+		go func(doc *models.Document) {
+			defer wg.Done()
+			assert.NoError(t, repo.InsertDocumentWithEvidence(ctx, doc, "batch-jws", ledgerService))
+		}(doc)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ledgerService.failed))
+
+	// Un document validé hors du lot et un document réinséré
+	evidences := map[string]int{}
+	for _, doc := range docs {
+		require.NotNil(t, doc.LedgerHash)
+		require.NotNil(t, doc.EvidenceJWS)
+		var ledgerHash, evidenceJWS string
+		require.NoError(t, db.Pool.QueryRow(ctx, `SELECT ledger_hash, evidence_jws FROM documents WHERE id = $1`, doc.ID).Scan(&ledgerHash, &evidenceJWS))
+		assert.Equal(t, ledgerHash, *doc.LedgerHash)
+		assert.Equal(t, evidenceJWS, *doc.EvidenceJWS)
+		evidences[evidenceJWS]++
+	}
+	assert.Equal(t, map[string]int{"stored-jws": 1, "batch-jws": 1}, evidences)
+
+	report, err := ledger.VerifyChain(ctx, db.Pool, ledger.ChainOptions{Tenant: tenant, PerTenantChain: true})
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, len(docs), report.EntriesChecked)
+}
+
+// BenchmarkPosTickets_IngestGroupCommit mesure le débit d'ingestion de tickets POS
+// concurrents avec group commit (à comparer à BenchmarkPosTickets_IngestConcurrent)
+func BenchmarkPosTickets_IngestGroupCommit(b *testing.B) {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		b.Skip("TEST_DATABASE_URL not set, skipping integration benchmark")
+	}
+	ctx := context.Background()
+	db, err := storage.NewDB(ctx, dbURL, logger.New("error"))
+	require.NoError(b, err)
+	defer db.Close()
+
+	jwsService, err := crypto.NewService("/tmp/test_private_key.pem", "/tmp/test_public_key.pem", "test-kid")
+	if err != nil {
+		b.Skipf("JWS service not available: %v", err)
+	}
+
+	tenant := "bench-" + uuid.NewString()
+	defer cleanupHeadTenant(db, tenant)
+	service, repo := newGroupCommitTestService(db, jwsService)
+	defer repo.Close()
+
+	var counter int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := service.Ingest(ctx, headTestTicket(tenant, atomic.AddInt64(&counter, 1))); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "tickets/s")
+}
@@ -0,0 +1,222 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/doreviateam/dorevia-vault/internal/audit"
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/handlers"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/services"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUploadHandler_Ingestion teste /upload à travers le service d'ingestion : preuve JWS
+// vérifiable, entrée du ledger et événement d'audit document_vaulted
+func TestUploadHandler_Ingestion(t *testing.T) {
+	db := setupTestDB(t)
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+	tenant := "upload-" + uuid.NewString()[:8]
+	t.Cleanup(func() {
+		db.Pool.Exec(ctx, "DELETE FROM ledger WHERE document_id IN (SELECT id FROM documents WHERE tenant = $1)", tenant)
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE tenant = $1", tenant)
+		db.Close()
+	})
+	db.SetBlobStore(blobstore.NewMux(blobstore.NewFileStore(t.TempDir())))
+
+	log := logger.New("error")
+	auditDir := t.TempDir()
+	auditLogger, err := audit.NewLogger(audit.Config{AuditDir: auditDir, Logger: *log})
+	require.NoError(t, err)
+	defer auditLogger.Close()
+
+	service := services.NewIngestionService(storage.NewDocumentRepository(db, ""), ledger.NewService(), crypto.NewLocalSigner(jwsService), services.IngestionOptions{
+		JWSRequired: true,
+		AuditLogger: auditLogger,
+		Logger:      log,
+		SpoolDir:    t.TempDir(),
+	})
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &auth.UserInfo{Role: "operator", Tenant: tenant})
+		return c.Next()
+	})
+	app.Post("/upload", handlers.UploadHandler(service))
+
+	data := []byte("%PDF-1.4 facture " + uuid.NewString())
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "facture.pdf")
+	part.Write(data)
+	writer.Close()
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-Request-ID", "req-"+tenant)
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var result map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	docID := result["id"].(string)
+
+	// Preuve JWS : signée pour ce document et ce contenu
+	jws, _ := result["evidence_jws"].(string)
+	require.NotEmpty(t, jws)
+	evidence, err := jwsService.VerifyEvidence(jws)
+	require.NoError(t, err)
+	assert.Equal(t, docID, evidence.DocumentID)
+	assert.Equal(t, sha256Of(data), evidence.Sha256)
+
+	// Entrée du ledger : celle référencée par le document
+	var ledgerHash, entryTenant string
+	require.NoError(t, db.Pool.QueryRow(ctx, `
+		SELECT hash, tenant FROM ledger WHERE document_id = $1
+	`, docID).Scan(&ledgerHash, &entryTenant))
+	assert.Equal(t, result["ledger_hash"], ledgerHash)
+	assert.Equal(t, tenant, entryTenant)
+
+	// Événement d'audit du document enregistré
+	require.NoError(t, auditLogger.Flush())
+	logs, err := filepath.Glob(filepath.Join(auditDir, "logs", "audit-*.log"))
+	require.NoError(t, err)
+	var vaulted *audit.Event
+	for _, path := range logs {
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		for _, line := range strings.Split(strings.TrimSpace(string(raw)), "\n") {
+			var event audit.Event
+			require.NoError(t, json.Unmarshal([]byte(line), &event))
+			if event.DocumentID == docID {
+				vaulted = &event
+			}
+		}
+	}
+	require.NotNil(t, vaulted, "audit event for document %s", docID)
+	assert.Equal(t, audit.EventTypeDocumentVaulted, vaulted.EventType)
+	assert.Equal(t, audit.EventStatusSuccess, vaulted.Status)
+	assert.Equal(t, tenant, vaulted.Tenant)
+	assert.Equal(t, "req-"+tenant, vaulted.RequestID)
+}
+
+// TestUpload_Sealed teste le scellement des documents reçus par /upload (JWS + Ledger) et
+// le scellement après coup d'un document enregistré sans preuve (cmd/backfill), inscrit
+// au ledger par un événement document.sealed
+func TestUpload_Sealed(t *testing.T) {
+	db := setupTestDB(t)
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+	tenant := "ingestion-" + uuid.NewString()[:8]
+	t.Cleanup(func() {
+		db.Pool.Exec(ctx, "DELETE FROM ledger WHERE document_id IN (SELECT id FROM documents WHERE tenant = $1)", tenant)
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE tenant = $1", tenant)
+		db.Close()
+	})
+	db.SetBlobStore(blobstore.NewMux(blobstore.NewFileStore(t.TempDir())))
+
+	log := logger.New("error")
+	ledgerService := ledger.NewService()
+	service := services.NewIngestionService(storage.NewDocumentRepository(db, ""), ledgerService, crypto.NewLocalSigner(jwsService), services.IngestionOptions{
+		JWSRequired: true,
+		Logger:      log,
+	})
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &auth.UserInfo{Tenant: tenant})
+		return c.Next()
+	})
+	app.Post("/upload", handlers.UploadHandler(service))
+
+	upload := func(data []byte) (int, map[string]interface{}) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "scan.pdf")
+		part.Write(data)
+		writer.Close()
+		req := httptest.NewRequest("POST", "/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		var result map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, result
+	}
+
+	data := []byte("%PDF-1.4 " + uuid.NewString())
+	status, result := upload(data)
+	require.Equal(t, fiber.StatusCreated, status)
+	assert.NotEmpty(t, result["evidence_jws"])
+	assert.NotEmpty(t, result["ledger_hash"])
+
+	docID := uuid.MustParse(result["id"].(string))
+	doc, err := db.GetDocumentByID(ctx, docID)
+	require.NoError(t, err)
+	require.NotNil(t, doc.EvidenceJWS)
+	require.NotNil(t, doc.LedgerHash)
+	stored, err := db.ReadDocumentContent(ctx, doc)
+	require.NoError(t, err)
+	assert.Equal(t, data, stored)
+
+	// Même contenu : document existant, pas de nouvelle entrée ledger
+	status, result = upload(data)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, docID.String(), result["id"])
+	var entries int
+	require.NoError(t, db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM ledger WHERE document_id = $1", docID).Scan(&entries))
+	assert.Equal(t, 1, entries)
+
+	// Document enregistré sans preuve (ancien /upload) : scellé par le backfill
+	unsealedID := uuid.New()
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO documents (id, filename, content_type, size_bytes, sha256_hex, stored_path, tenant)
+		VALUES ($1, 'ancien.pdf', 'application/pdf', 4, $2, '', $3)
+	`, unsealedID, sha256Of([]byte(unsealedID.String())), tenant)
+	require.NoError(t, err)
+
+	unsealed, err := db.ListUnsealedDocuments(ctx, storage.UnsealedFilter{MissingJWS: true, MissingLedger: true}, nil, 0)
+	require.NoError(t, err)
+	found := false
+	for i := range unsealed {
+		if unsealed[i].ID == unsealedID {
+			found = true
+			require.NoError(t, service.Seal(ctx, &unsealed[i]))
+		}
+		assert.NotEqual(t, docID, unsealed[i].ID, "sealed upload listed as unsealed")
+	}
+	require.True(t, found)
+
+	doc, err = db.GetDocumentByID(ctx, unsealedID)
+	require.NoError(t, err)
+	require.NotNil(t, doc.EvidenceJWS)
+	require.NotNil(t, doc.LedgerHash)
+
+	// Scellement a posteriori inscrit par un événement distinct de document.created
+	var sealedBody string
+	require.NoError(t, db.Pool.QueryRow(ctx, `
+		SELECT body FROM ledger WHERE document_id = $1 AND entry_type = $2
+	`, unsealedID, ledger.EntryTypeDocumentSealed).Scan(&sealedBody))
+	assert.Contains(t, sealedBody, `"evidence_added":true`)
+	assert.Contains(t, sealedBody, `"ledger_hash":"`+*doc.LedgerHash+`"`)
+
+	// Déjà scellé : ni nouvelle signature ni nouvelle entrée ledger
+	require.NoError(t, service.Seal(ctx, doc))
+	require.NoError(t, db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM ledger WHERE document_id = $1", unsealedID).Scan(&entries))
+	assert.Equal(t, 2, entries)
+}
@@ -0,0 +1,94 @@
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/tsp"
+	"github.com/doreviateam/dorevia-vault/internal/tsp/tsptest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLedgerAnchor_AnchorHeads teste l'horodatage de la tête du ledger auprès de la
+// TSA locale et la preuve d'ancrage d'une entrée antérieure
+func TestLedgerAnchor_AnchorHeads(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	server, err := tsptest.NewServer()
+	require.NoError(t, err)
+	defer server.Close()
+	client := tsp.NewClient(server.URL, 5*time.Second, server.Roots())
+
+	// Entrées datées dans le futur pour constituer la tête de la chaîne globale
+	base := time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC)
+	var docIDs []uuid.UUID
+	cleanup := func() {
+		db.Pool.Exec(ctx, "DELETE FROM ledger_anchors WHERE ledger_timestamp >= $1", base)
+		db.Pool.Exec(ctx, "DELETE FROM ledger WHERE timestamp >= $1", base)
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE created_at >= $1", base)
+	}
+	cleanup()
+	defer cleanup()
+
+	var previous *string
+	for i := 0; i < 3; i++ {
+		docID := uuid.New()
+		docIDs = append(docIDs, docID)
+		hash := sha256.Sum256([]byte(docID.String()))
+		shaHex := hex.EncodeToString(hash[:])
+		createdAt := base.Add(time.Duration(i) * time.Minute)
+
+		_, err := db.Pool.Exec(ctx, `
+			INSERT INTO documents (id, filename, content_type, size_bytes, sha256_hex, stored_path, created_at)
+			VALUES ($1, 'ancre.pdf', 'application/pdf', 1, $2, '/tmp/ancre.pdf', $3)
+		`, docID, shaHex, createdAt)
+		require.NoError(t, err)
+
+		ledgerHash := ledger.ComputeHash(previous, shaHex)
+		_, err = db.Pool.Exec(ctx, `
+			INSERT INTO ledger (document_id, hash, previous_hash, timestamp)
+			VALUES ($1, $2, $3, $4)
+		`, docID, ledgerHash, previous, createdAt)
+		require.NoError(t, err)
+		previous = &ledgerHash
+	}
+
+	anchors, err := ledger.AnchorHeads(ctx, db.Pool, client, false)
+	require.NoError(t, err)
+	require.Len(t, anchors, 1)
+	assert.Equal(t, *previous, anchors[0].LedgerHash)
+	assert.Equal(t, server.URL, anchors[0].TSAURL)
+	assert.Equal(t, 1, server.Requests())
+
+	// La tête déjà ancrée n'est pas horodatée une seconde fois
+	anchors, err = ledger.AnchorHeads(ctx, db.Pool, client, false)
+	require.NoError(t, err)
+	assert.Empty(t, anchors)
+	assert.Equal(t, 1, server.Requests())
+
+	// La première entrée est couverte par l'ancrage de la tête
+	entry, err := ledger.LoadEntry(ctx, db.Pool, docIDs[0])
+	require.NoError(t, err)
+	anchorProof, err := ledger.LoadAnchorProof(ctx, db.Pool, *entry)
+	require.NoError(t, err)
+	assert.Len(t, anchorProof.Path, 2)
+
+	token, err := anchorProof.Verify(*entry, server.Roots())
+	require.NoError(t, err)
+	assert.Equal(t, server.Cert.Raw, token.Signer.Raw)
+
+	// Une entrée postérieure à l'ancrage n'est pas encore couverte
+	_, err = db.Pool.Exec(ctx, "DELETE FROM ledger_anchors WHERE ledger_timestamp >= $1", base)
+	require.NoError(t, err)
+	_, err = ledger.LoadAnchorProof(ctx, db.Pool, *entry)
+	assert.True(t, errors.Is(err, ledger.ErrNoAnchor))
+}
@@ -0,0 +1,88 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/services"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLedgerEvents_StatusChanges teste l'inscription des changements de statut dans la
+// chaîne du tenant et la vérification d'une chaîne mêlant documents et événements
+func TestLedgerEvents_StatusChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+
+	tenant := "events-" + uuid.NewString()
+	defer cleanupHeadTenant(db, tenant)
+	ledgerService := ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: true, HashVersion: ledger.HashVersionV2})
+	db.SetLedgerService(ledgerService)
+	repo := storage.NewPostgresRepository(db.Pool, logger.New("error"))
+	service := services.NewPosTicketsService(repo, ledgerService, crypto.NewLocalSigner(jwsService))
+
+	result, err := service.Ingest(ctx, headTestTicket(tenant, 1))
+	require.NoError(t, err)
+
+	// PENDING → SENT → ACK, puis odoo_state
+	sent, ack, paid := storage.DispatchStatusSent, storage.DispatchStatusAck, "paid"
+	_, err = db.UpdateDocumentStatus(ctx, result.ID, storage.StatusUpdate{DispatchStatus: &sent, Actor: "test"})
+	require.NoError(t, err)
+	doc, err := db.UpdateDocumentStatus(ctx, result.ID, storage.StatusUpdate{DispatchStatus: &ack, OdooState: &paid})
+	require.NoError(t, err)
+	assert.Equal(t, ack, *doc.DispatchStatus)
+	assert.Equal(t, paid, *doc.OdooState)
+
+	// Transition refusée et document d'un autre tenant : rien n'est inscrit
+	_, err = db.UpdateDocumentStatus(ctx, result.ID, storage.StatusUpdate{DispatchStatus: &sent})
+	var transition storage.ErrInvalidStatusTransition
+	assert.True(t, errors.As(err, &transition))
+	_, err = db.UpdateDocumentStatus(ctx, result.ID, storage.StatusUpdate{OdooState: &paid, Tenant: "other"})
+	assert.True(t, errors.Is(err, storage.ErrDocumentNotFound))
+
+	var created, changed int
+	require.NoError(t, db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE entry_type = $2), COUNT(*) FILTER (WHERE entry_type = $3)
+		FROM ledger WHERE tenant = $1
+	`, tenant, ledger.EntryTypeDocumentCreated, ledger.EntryTypeDocumentStatusChanged).Scan(&created, &changed))
+	assert.Equal(t, 1, created)
+	assert.Equal(t, 3, changed)
+
+	// Événement sans document dans la chaîne du tenant
+	_, err = db.AppendLedgerEvent(ctx, tenant, ledger.Event{
+		Type: ledger.EntryTypeKeyRotated,
+		Body: ledger.KeyRotatedBody{NewKID: "key-test"},
+	})
+	require.NoError(t, err)
+
+	opts := ledger.ChainOptions{Tenant: tenant, PerTenantChain: true}
+	report, err := ledger.VerifyChain(ctx, db.Pool, opts)
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, 5, report.EntriesChecked)
+
+	// La preuve du document ignore les événements qui le référencent
+	entry, err := ledger.LoadEntry(ctx, db.Pool, result.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ledger.EntryTypeDocumentCreated, entry.EntryType)
+
+	// Réécriture du corps d'un événement : détectée
+	_, err = db.Pool.Exec(ctx, `
+		UPDATE ledger SET body = replace(body, 'paid', 'cancel')
+		WHERE tenant = $1 AND entry_type = $2 AND body LIKE '%paid%'
+	`, tenant, ledger.EntryTypeDocumentStatusChanged)
+	require.NoError(t, err)
+	report, err = ledger.VerifyChain(ctx, db.Pool, opts)
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.Equal(t, 1, report.BrokenLinkCount)
+}
@@ -0,0 +1,75 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/services"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLedgerHashVersion_MixedChain teste une chaîne dont les premières entrées sont v1
+// et les suivantes v2, puis la détection d'une réécriture d'horodatage et de JWS
+func TestLedgerHashVersion_MixedChain(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+
+	tenant := "hashv2-" + uuid.NewString()
+	defer cleanupHeadTenant(db, tenant)
+	repo := storage.NewPostgresRepository(db.Pool, logger.New("error"))
+	newService := func(version int) *services.PosTicketsService {
+		ledgerService := ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: true, HashVersion: version})
+		return services.NewPosTicketsService(repo, ledgerService, crypto.NewLocalSigner(jwsService))
+	}
+
+	v1, v2 := newService(ledger.HashVersionV1), newService(ledger.HashVersionV2)
+	for i := int64(0); i < 6; i++ {
+		service := v1
+		if i >= 3 {
+			service = v2
+		}
+		_, err := service.Ingest(ctx, headTestTicket(tenant, i))
+		require.NoError(t, err)
+	}
+
+	var v1Count, v2Count int
+	require.NoError(t, db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE hash_version = 1), COUNT(*) FILTER (WHERE hash_version = 2)
+		FROM ledger WHERE tenant = $1
+	`, tenant).Scan(&v1Count, &v2Count))
+	assert.Equal(t, 3, v1Count)
+	assert.Equal(t, 3, v2Count)
+
+	opts := ledger.ChainOptions{Tenant: tenant, PerTenantChain: true}
+	report, err := ledger.VerifyChain(ctx, db.Pool, opts)
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, 6, report.EntriesChecked)
+
+	// Réécriture de l'horodatage d'une entrée v2 : détectée
+	_, err = db.Pool.Exec(ctx, `
+		UPDATE ledger SET timestamp = timestamp - interval '1 second'
+		WHERE tenant = $1 AND seq = 5
+	`, tenant)
+	require.NoError(t, err)
+	report, err = ledger.VerifyChain(ctx, db.Pool, opts)
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	require.NotNil(t, report.FirstBrokenLink)
+	assert.Equal(t, ledger.ChainIssueBrokenLink, report.FirstBrokenLink.Type)
+
+	// Réécriture du JWS d'une autre entrée v2 : détectée également
+	_, err = db.Pool.Exec(ctx, `UPDATE ledger SET evidence_jws = 'forged' WHERE tenant = $1 AND seq = 6`, tenant)
+	require.NoError(t, err)
+	report, err = ledger.VerifyChain(ctx, db.Pool, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.BrokenLinkCount)
+}
@@ -0,0 +1,211 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/services"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHeadTestService crée un service d'ingestion POS sur une chaîne par tenant
+func newHeadTestService(db *storage.DB, jwsService *crypto.Service) *services.PosTicketsService {
+	repo := storage.NewPostgresRepository(db.Pool, logger.New("error"))
+	ledgerService := ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: true})
+	return services.NewPosTicketsService(repo, ledgerService, crypto.NewLocalSigner(jwsService))
+}
+
+// headTestTicket construit un ticket POS distinct pour l'index i
+func headTestTicket(tenant string, i int64) services.PosTicketInput {
+	return services.PosTicketInput{
+		Tenant:      tenant,
+		SourceModel: "pos.order",
+		SourceID:    fmt.Sprintf("POS/HEAD/%d", i),
+		Ticket: map[string]interface{}{
+			"lines": []interface{}{
+				map[string]interface{}{"product": "Item", "quantity": 1, "price": float64(i)},
+			},
+		},
+	}
+}
+
+// cleanupHeadTenant supprime les données d'un tenant de test
+func cleanupHeadTenant(db *storage.DB, tenant string) {
+	ctx := context.Background()
+	db.Pool.Exec(ctx, "DELETE FROM ledger WHERE tenant = $1", tenant)
+	db.Pool.Exec(ctx, "DELETE FROM documents WHERE tenant = $1", tenant)
+	db.Pool.Exec(ctx, "DELETE FROM ledger_head WHERE chain_id = $1", ledger.ChainID(tenant, true))
+}
+
+// TestLedgerHead_ConcurrentIngest teste que des ingestions concurrentes produisent une
+// chaîne sans fork et une séquence sans trou
+func TestLedgerHead_ConcurrentIngest(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+
+	tenant := "head-" + uuid.NewString()
+	defer cleanupHeadTenant(db, tenant)
+	service := newHeadTestService(db, jwsService)
+
+	const workers, perWorker = 8, 10
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := service.Ingest(ctx, headTestTicket(tenant, int64(w*perWorker+i))); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// Séquence 1..N sans trou, dans l'ordre de la chaîne
+	rows, err := db.Pool.Query(ctx, `SELECT seq FROM ledger WHERE tenant = $1 ORDER BY timestamp, id`, tenant)
+	require.NoError(t, err)
+	var expected int64
+	for rows.Next() {
+		var seq int64
+		require.NoError(t, rows.Scan(&seq))
+		expected++
+		assert.Equal(t, expected, seq)
+	}
+	rows.Close()
+	assert.Equal(t, int64(workers*perWorker), expected)
+
+	var headSeq int64
+	require.NoError(t, db.Pool.QueryRow(ctx, `SELECT seq FROM ledger_head WHERE chain_id = $1`, ledger.ChainID(tenant, true)).Scan(&headSeq))
+	assert.Equal(t, expected, headSeq)
+
+	report, err := ledger.VerifyChain(ctx, db.Pool, ledger.ChainOptions{Tenant: tenant, PerTenantChain: true})
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, workers*perWorker, report.EntriesChecked)
+	assert.Zero(t, report.ForkCount)
+	assert.Zero(t, report.GapCount)
+}
+
+// TestLedgerHead_SwitchToPerTenantChain teste l'activation de LEDGER_PER_TENANT_CHAIN sur
+// un ledger déjà peuplé : les chaînes par tenant démarrent sur la tête de la chaîne
+// globale et la vérification complète reste valide
+func TestLedgerHead_SwitchToPerTenantChain(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+
+	acme, globex := "switch-"+uuid.NewString(), "switch-"+uuid.NewString()
+	defer cleanupHeadTenant(db, acme)
+	defer cleanupHeadTenant(db, globex)
+
+	// Ledger jamais séparé : la chaîne globale reprend les entrées existantes
+	_, err := db.Pool.Exec(ctx, `DELETE FROM ledger_head`)
+	require.NoError(t, err)
+	var from time.Time
+	require.NoError(t, db.Pool.QueryRow(ctx, `SELECT clock_timestamp()`).Scan(&from))
+
+	repo := storage.NewPostgresRepository(db.Pool, logger.New("error"))
+	globalService := services.NewPosTicketsService(repo, ledger.NewServiceWithOptions(ledger.Options{}), crypto.NewLocalSigner(jwsService))
+	perTenantService := newHeadTestService(db, jwsService)
+	tenants := []string{acme, globex}
+	for i := 0; i < 4; i++ {
+		_, err := globalService.Ingest(ctx, headTestTicket(tenants[i%2], int64(i)))
+		require.NoError(t, err)
+	}
+	epoch, err := ledger.LoadChainEpoch(ctx, db.Pool)
+	require.NoError(t, err)
+	assert.Nil(t, epoch)
+
+	// Activation du flag
+	for i := 4; i < 8; i++ {
+		_, err := perTenantService.Ingest(ctx, headTestTicket(tenants[i%2], int64(i)))
+		require.NoError(t, err)
+	}
+	epoch, err = ledger.LoadChainEpoch(ctx, db.Pool)
+	require.NoError(t, err)
+	require.NotNil(t, epoch)
+	require.NotNil(t, epoch.Hash)
+
+	// Chaque chaîne par tenant démarre sur la tête de la chaîne globale
+	for _, tenant := range tenants {
+		var previousHash string
+		var seq int64
+		require.NoError(t, db.Pool.QueryRow(ctx, `
+			SELECT previous_hash, seq FROM ledger
+			WHERE tenant = $1 AND id > $2
+			ORDER BY seq LIMIT 1
+		`, tenant, epoch.EntryID).Scan(&previousHash, &seq))
+		assert.Equal(t, *epoch.Hash, previousHash)
+		assert.Equal(t, int64(1), seq)
+
+		report, err := ledger.VerifyChain(ctx, db.Pool, ledger.ChainOptions{Tenant: tenant, PerTenantChain: true})
+		require.NoError(t, err)
+		assert.True(t, report.Valid)
+		assert.Equal(t, 2, report.EntriesChecked)
+		require.NotNil(t, report.EpochEntryID)
+		assert.Equal(t, epoch.EntryID, *report.EpochEntryID)
+	}
+
+	// Ledger complet depuis le début du test : chaîne globale puis chaînes par tenant
+	report, err := ledger.VerifyChain(ctx, db.Pool, ledger.ChainOptions{From: &from, PerTenantChain: true})
+	require.NoError(t, err)
+	assert.True(t, report.Valid, "gaps=%v forks=%v broken=%v", report.Gaps, report.Forks, report.BrokenLinks)
+	assert.Equal(t, 8, report.EntriesChecked)
+	assert.Zero(t, report.GapCount)
+	assert.Zero(t, report.ForkCount)
+}
+
+// BenchmarkPosTickets_IngestConcurrent mesure le débit d'ingestion de tickets POS
+// concurrents sur une même chaîne (sérialisée par la ligne ledger_head)
+func BenchmarkPosTickets_IngestConcurrent(b *testing.B) {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		b.Skip("TEST_DATABASE_URL not set, skipping integration benchmark")
+	}
+	ctx := context.Background()
+	db, err := storage.NewDB(ctx, dbURL, logger.New("error"))
+	require.NoError(b, err)
+	defer db.Close()
+
+	jwsService, err := crypto.NewService("/tmp/test_private_key.pem", "/tmp/test_public_key.pem", "test-kid")
+	if err != nil {
+		b.Skipf("JWS service not available: %v", err)
+	}
+
+	tenant := "bench-" + uuid.NewString()
+	defer cleanupHeadTenant(db, tenant)
+	service := newHeadTestService(db, jwsService)
+
+	var counter int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := service.Ingest(ctx, headTestTicket(tenant, atomic.AddInt64(&counter, 1))); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "tickets/s")
+}
@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLedgerMerkle_InclusionProof teste la racine de Merkle journalière signée et la
+// preuve d'inclusion d'un document vérifiée hors ligne
+func TestLedgerMerkle_InclusionProof(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+
+	// Jour improbable pour ne pas interférer avec les autres tests
+	day := time.Date(2001, 6, 15, 0, 0, 0, 0, time.UTC)
+	cleanup := func() {
+		db.Pool.Exec(ctx, "DELETE FROM ledger_merkle_roots WHERE batch_date = $1", day)
+		db.Pool.Exec(ctx, "DELETE FROM ledger WHERE timestamp >= $1 AND timestamp < $2", day, day.AddDate(0, 0, 1))
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE created_at >= $1 AND created_at < $2", day, day.AddDate(0, 0, 1))
+	}
+	cleanup()
+	defer cleanup()
+
+	var docIDs []uuid.UUID
+	var previous *string
+	for i := 0; i < 5; i++ {
+		docID := uuid.New()
+		docIDs = append(docIDs, docID)
+		hash := sha256.Sum256([]byte(docID.String()))
+		shaHex := hex.EncodeToString(hash[:])
+		createdAt := day.Add(time.Duration(i+1) * time.Hour)
+
+		_, err := db.Pool.Exec(ctx, `
+			INSERT INTO documents (id, filename, content_type, size_bytes, sha256_hex, stored_path, created_at)
+			VALUES ($1, 'merkle.pdf', 'application/pdf', 1, $2, '/tmp/merkle.pdf', $3)
+		`, docID, shaHex, createdAt)
+		require.NoError(t, err)
+
+		ledgerHash := ledger.ComputeHash(previous, shaHex)
+		_, err = db.Pool.Exec(ctx, `
+			INSERT INTO ledger (document_id, hash, previous_hash, timestamp)
+			VALUES ($1, $2, $3, $4)
+		`, docID, ledgerHash, previous, createdAt)
+		require.NoError(t, err)
+		previous = &ledgerHash
+	}
+
+	// Pas encore de racine pour ce jour
+	_, err := ledger.LoadInclusionProof(ctx, db.Pool, docIDs[2])
+	assert.True(t, errors.Is(err, ledger.ErrNoMerkleRoot))
+
+	root, err := ledger.BuildMerkleRoot(ctx, db.Pool, jwsService, day.Add(12*time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, root)
+	assert.Equal(t, "2001-06-15", root.Root.Batch)
+	assert.Equal(t, int64(5), root.Root.TreeSize)
+
+	// Une seule racine par jour
+	again, err := ledger.BuildMerkleRoot(ctx, db.Pool, jwsService, day)
+	require.NoError(t, err)
+	assert.Nil(t, again)
+
+	for i, docID := range docIDs {
+		proof, err := ledger.LoadInclusionProof(ctx, db.Pool, docID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(i), proof.LeafIndex)
+		assert.Equal(t, root.Root.RootHash, proof.Root.RootHash)
+		assert.LessOrEqual(t, len(proof.AuditPath), 3)
+
+		_, err = verify.VerifyInclusionProof(proof, jwsService)
+		assert.NoError(t, err)
+	}
+
+	// Document sans entrée dans le ledger
+	_, err = ledger.LoadInclusionProof(ctx, db.Pool, uuid.New())
+	assert.True(t, errors.Is(err, ledger.ErrNoLedgerEntry))
+}
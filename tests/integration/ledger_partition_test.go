@@ -0,0 +1,123 @@
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLedgerPartition_Lifecycle teste la conversion en ligne du ledger, puis
+// l'archivage et le ré-attachement d'une partition mensuelle
+func TestLedgerPartition_Lifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	log := logger.New("error")
+	manager := ledger.NewPartitionManager(db.Pool, *log)
+
+	// 1. Conversion (déjà faite si la base de test a été convertie par un passage précédent)
+	partitioned, err := manager.IsPartitioned(ctx)
+	require.NoError(t, err)
+	if !partitioned {
+		report, err := ledger.ConvertToPartitioned(ctx, db.Pool, ledger.ConvertOptions{BatchSize: 100}, *log)
+		require.NoError(t, err)
+		assert.True(t, report.Before.Valid)
+		assert.True(t, report.After.Valid)
+		assert.Equal(t, report.Before.EntriesChecked, report.After.EntriesChecked)
+
+		_, err = ledger.ConvertToPartitioned(ctx, db.Pool, ledger.ConvertOptions{}, *log)
+		assert.True(t, errors.Is(err, ledger.ErrLedgerPartitioned))
+	}
+	status, err := manager.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.Partitioned)
+	assert.True(t, status.NextPartitionReady)
+
+	// 2. Partition d'un mois ancien, antérieure à toutes les entrées
+	base := time.Date(2001, 1, 10, 0, 0, 0, 0, time.UTC)
+	start, _ := ledger.PartitionBounds(2001, 1)
+	var older bool
+	require.NoError(t, db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM ledger WHERE timestamp < $1)`, start).Scan(&older))
+	if older {
+		t.Skip("ledger entries older than 2001-01, skipping archive test")
+	}
+
+	tenant := "partition-" + uuid.NewString()
+	partition := ledger.PartitionName(2001, 1)
+	cleanup := func() {
+		db.Pool.Exec(ctx, "DROP TABLE IF EXISTS "+partition)
+		db.Pool.Exec(ctx, "DELETE FROM ledger_partition_archives WHERE partition_name = $1", partition)
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE tenant = $1", tenant)
+	}
+	cleanup()
+	defer cleanup()
+	require.NoError(t, manager.EnsurePartition(ctx, 2001, 1))
+
+	var previous *string
+	for i := 0; i < 3; i++ {
+		docID := uuid.New()
+		hash := sha256.Sum256([]byte(docID.String()))
+		shaHex := hex.EncodeToString(hash[:])
+		createdAt := base.Add(time.Duration(i) * time.Minute)
+
+		_, err := db.Pool.Exec(ctx, `
+			INSERT INTO documents (id, filename, content_type, size_bytes, sha256_hex, stored_path, created_at, tenant)
+			VALUES ($1, 'partition.pdf', 'application/pdf', 1, $2, '/tmp/partition.pdf', $3, $4)
+		`, docID, shaHex, createdAt, tenant)
+		require.NoError(t, err)
+
+		ledgerHash := ledger.ComputeHash(previous, shaHex)
+		_, err = db.Pool.Exec(ctx, `
+			INSERT INTO ledger (document_id, hash, previous_hash, timestamp, tenant)
+			VALUES ($1, $2, $3, $4, $5)
+		`, docID, ledgerHash, previous, createdAt, tenant)
+		require.NoError(t, err)
+		previous = &ledgerHash
+	}
+
+	// 3. Archivage : fichier vérifiable hors ligne, partition détachée
+	opts := ledger.LifecycleOptions{ArchiveDir: t.TempDir(), PerTenantChain: true}
+	manifest, err := manager.ArchivePartition(ctx, 2001, 1, opts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), manifest.EntryCount)
+	assert.Equal(t, *previous, manifest.TenantHeads[tenant])
+
+	_, manifestPath := ledger.PartitionArchivePaths(opts.ArchiveDir, partition)
+	_, report, err := ledger.VerifyPartitionArchive(manifestPath)
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+
+	var count int
+	require.NoError(t, db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM ledger WHERE tenant = $1`, tenant).Scan(&count))
+	assert.Zero(t, count)
+
+	// 4. Ré-attachement pour audit
+	_, err = manager.RestorePartition(ctx, manifestPath)
+	require.NoError(t, err)
+	require.NoError(t, db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM ledger WHERE tenant = $1`, tenant).Scan(&count))
+	assert.Equal(t, 3, count)
+
+	chain, err := ledger.VerifyChain(ctx, db.Pool, ledger.ChainOptions{Tenant: tenant, PerTenantChain: true})
+	require.NoError(t, err)
+	assert.True(t, chain.Valid)
+	assert.Equal(t, 3, chain.EntriesChecked)
+
+	archives, err := manager.ListArchives(ctx)
+	require.NoError(t, err)
+	var restored bool
+	for _, a := range archives {
+		if a.Partition == partition {
+			restored = a.RestoredAt != nil
+		}
+	}
+	assert.True(t, restored)
+}
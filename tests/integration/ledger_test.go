@@ -29,6 +29,8 @@ func TestLedger_AppendFirstHash(t *testing.T) {
 	// Nettoyer la table ledger pour le test
 	_, err = pool.Exec(ctx, "DELETE FROM ledger")
 	require.NoError(t, err)
+	_, err = pool.Exec(ctx, "DELETE FROM ledger_head")
+	require.NoError(t, err)
 
 	// Créer une transaction
 	tx, err := pool.Begin(ctx)
@@ -69,6 +71,8 @@ func TestLedger_AppendChaining(t *testing.T) {
 	// Nettoyer la table ledger
 	_, err = pool.Exec(ctx, "DELETE FROM ledger")
 	require.NoError(t, err)
+	_, err = pool.Exec(ctx, "DELETE FROM ledger_head")
+	require.NoError(t, err)
 
 	// Premier hash
 	tx1, err := pool.Begin(ctx)
@@ -114,6 +118,8 @@ func TestLedger_ExistsByDocumentID(t *testing.T) {
 	// Nettoyer
 	_, err = pool.Exec(ctx, "DELETE FROM ledger")
 	require.NoError(t, err)
+	_, err = pool.Exec(ctx, "DELETE FROM ledger_head")
+	require.NoError(t, err)
 
 	// Ajouter un document
 	tx, err := pool.Begin(ctx)
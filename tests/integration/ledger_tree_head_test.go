@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLedgerTreeHead_Consistency teste la publication des têtes signées, la preuve de
+// cohérence entre deux têtes et l'enregistrement d'une contre-signature
+func TestLedgerTreeHead_Consistency(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+
+	tenant := "treehead-" + uuid.NewString()
+	var headIDs []int64
+	defer func() {
+		cleanupHeadTenant(db, tenant)
+		db.Pool.Exec(ctx, "DELETE FROM ledger_tree_head_cosignatures WHERE tree_head_id = ANY($1)", headIDs)
+		db.Pool.Exec(ctx, "DELETE FROM ledger_tree_heads WHERE id = ANY($1)", headIDs)
+	}()
+	db.SetLedgerService(ledger.NewServiceWithOptions(ledger.Options{PerTenantChain: true, HashVersion: ledger.HashVersionV2}))
+
+	appendKeyRotated := func(kid string) {
+		_, err := db.AppendLedgerEvent(ctx, tenant, ledger.Event{Type: ledger.EntryTypeKeyRotated, Body: ledger.KeyRotatedBody{NewKID: kid}})
+		require.NoError(t, err)
+	}
+	publish := func() *ledger.SignedTreeHead {
+		head, err := ledger.PublishTreeHead(ctx, db.Pool, jwsService, time.Now())
+		require.NoError(t, err)
+		require.NotNil(t, head)
+		headIDs = append(headIDs, head.ID)
+		return head
+	}
+
+	appendKeyRotated("k1")
+	first := publish()
+
+	// Ledger inchangé : pas de nouvelle tête
+	unchanged, err := ledger.PublishTreeHead(ctx, db.Pool, jwsService, time.Now())
+	require.NoError(t, err)
+	assert.Nil(t, unchanged)
+
+	appendKeyRotated("k2")
+	appendKeyRotated("k3")
+	second := publish()
+	assert.Equal(t, first.Head.TreeSize+2, second.Head.TreeSize)
+
+	// Tête relue à l'identique : signature vérifiable
+	loaded, err := ledger.LoadTreeHead(ctx, db.Pool, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, second.Head, loaded.Head)
+	_, err = verify.VerifyTreeHeadSignature(loaded.Head, loaded.JWS, jwsService)
+	require.NoError(t, err)
+
+	proof, err := ledger.BuildConsistencyProof(ctx, db.Pool, first, second)
+	require.NoError(t, err)
+	assert.NoError(t, proof.Verify(first.Head, second.Head))
+	for _, chain := range proof.Chains {
+		for _, e := range chain.Entries {
+			assert.Empty(t, e.Body, "entries are anonymized")
+			assert.Nil(t, e.Tenant)
+		}
+	}
+
+	// Contre-signature d'un témoin, une seule par clé
+	witnessJWS, err := jwsService.SignReport(ledger.TreeHeadSubject, second.Head, time.Now())
+	require.NoError(t, err)
+	created, err := ledger.AddCosignature(ctx, db.Pool, second.ID, ledger.Cosignature{WitnessID: "w1", KID: "witness-kid", JWS: witnessJWS})
+	require.NoError(t, err)
+	assert.True(t, created)
+	created, err = ledger.AddCosignature(ctx, db.Pool, second.ID, ledger.Cosignature{WitnessID: "w1-bis", KID: "witness-kid", JWS: witnessJWS})
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	latest, err := ledger.LoadLatestTreeHead(ctx, db.Pool)
+	require.NoError(t, err)
+	require.Len(t, latest.Cosignatures, 1)
+	assert.Equal(t, "w1", latest.Cosignatures[0].WitnessID)
+
+	// Réécriture d'une entrée déjà couverte par une tête : la preuve ne se vérifie plus
+	_, err = db.Pool.Exec(ctx, `UPDATE ledger SET body = replace(body, 'k2', 'kX') WHERE tenant = $1`, tenant)
+	require.NoError(t, err)
+	proof, err = ledger.BuildConsistencyProof(ctx, db.Pool, first, second)
+	require.NoError(t, err)
+	assert.Error(t, proof.Verify(first.Head, second.Head))
+}
@@ -15,8 +15,10 @@ import (
 	"github.com/doreviateam/dorevia-vault/internal/handlers"
 	"github.com/doreviateam/dorevia-vault/internal/ledger"
 	"github.com/doreviateam/dorevia-vault/internal/metrics"
+	"github.com/doreviateam/dorevia-vault/internal/proof"
 	"github.com/doreviateam/dorevia-vault/internal/services"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
 	"github.com/doreviateam/dorevia-vault/pkg/logger"
 	"github.com/gofiber/fiber/v2"
 	"github.com/prometheus/client_golang/prometheus"
@@ -37,7 +39,9 @@ func setupTestDB(t *testing.T) *storage.DB {
 	db, err := storage.NewDB(ctx, dbURL, log)
 	require.NoError(t, err)
 
-	// Nettoyer la table documents avant le test
+	// Nettoyer la table documents avant le test (clé étrangère du ledger restrictive)
+	_, err = db.Pool.Exec(ctx, "DELETE FROM ledger WHERE document_id IN (SELECT id FROM documents WHERE source = 'pos')")
+	require.NoError(t, err)
 	_, err = db.Pool.Exec(ctx, "DELETE FROM documents WHERE source = 'pos'")
 	require.NoError(t, err)
 
@@ -67,7 +71,7 @@ func setupTestJWS(t *testing.T) *crypto.Service {
 }
 
 // getMetricValue récupère la valeur d'une métrique Prometheus
-func getMetricValue(metric prometheus.CounterVec, labels ...string) float64 {
+func getMetricValue(metric *prometheus.CounterVec, labels ...string) float64 {
 	metricProto := &dto.Metric{}
 	err := metric.WithLabelValues(labels...).Write(metricProto)
 	if err != nil {
@@ -149,7 +153,7 @@ func TestPosTickets_EndToEnd(t *testing.T) {
 
 	// Vérifier dans la DB
 	ctx := context.Background()
-	doc, err := repo.GetDocumentBySHA256(ctx, response.SHA256Hex)
+	doc, err := repo.GetDocumentBySHA256(ctx, response.Tenant, response.SHA256Hex)
 	require.NoError(t, err)
 	require.NotNil(t, doc)
 
@@ -450,3 +454,83 @@ func floatPtr(f float64) *float64 {
 	return &f
 }
 
+
+// TestPosTickets_VerifyIntegrity teste la vérification d'intégrité d'un ticket POS stocké en base
+func TestPosTickets_VerifyIntegrity(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	jwsService := setupTestJWS(t)
+	repo := storage.NewPostgresRepository(db.Pool, logger.New("error"))
+	posTicketsService := services.NewPosTicketsService(repo, ledger.NewService(), crypto.NewLocalSigner(jwsService))
+
+	ctx := context.Background()
+	result, err := posTicketsService.Ingest(ctx, services.PosTicketInput{
+		Tenant:       "test-tenant",
+		SourceSystem: "odoo_pos",
+		SourceModel:  "pos.order",
+		SourceID:     "POS/VERIFY/001",
+		PosSession:   stringPtr("SESSION/001"),
+		Ticket: map[string]interface{}{
+			"lines": []interface{}{
+				map[string]interface{}{"product": "Item 1", "quantity": 1, "price": 10.42},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	verification, err := verify.VerifyDocumentIntegrity(ctx, db, jwsService, result.ID)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid, verification.Errors)
+	components := make(map[string]string)
+	for _, check := range verification.Checks {
+		components[check.Component] = check.Status
+	}
+	assert.Equal(t, "ok", components["payload"])
+	assert.Equal(t, "ok", components["jws"])
+
+	// Altération du ticket en base
+	_, err = db.Pool.Exec(ctx, `
+		UPDATE documents SET payload_json = jsonb_set(payload_json, '{ticket,lines,0,price}', '1.00')
+		WHERE id = $1
+	`, result.ID)
+	require.NoError(t, err)
+
+	verification, err = verify.VerifyDocumentIntegrity(ctx, db, jwsService, result.ID)
+	require.NoError(t, err)
+	assert.False(t, verification.Valid)
+}
+
+// TestPosTickets_ProofBundle teste l'export puis la vérification hors ligne du bundle d'un ticket POS
+func TestPosTickets_ProofBundle(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	jwsService := setupTestJWS(t)
+	repo := storage.NewPostgresRepository(db.Pool, logger.New("error"))
+	posTicketsService := services.NewPosTicketsService(repo, ledger.NewService(), crypto.NewLocalSigner(jwsService))
+
+	ctx := context.Background()
+	result, err := posTicketsService.Ingest(ctx, services.PosTicketInput{
+		Tenant:       "test-tenant",
+		SourceSystem: "odoo_pos",
+		SourceModel:  "pos.order",
+		SourceID:     "POS/BUNDLE/001",
+		PosSession:   stringPtr("SESSION/001"),
+		Ticket: map[string]interface{}{
+			"lines": []interface{}{
+				map[string]interface{}{"product": "Item 1", "quantity": 2, "price": 4.5},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	bundle, err := proof.Build(ctx, db, jwsService, result.ID)
+	require.NoError(t, err)
+
+	report, err := proof.VerifyBundle(bundle, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, report.Valid, report.Errors)
+	assert.Equal(t, proof.ContentPosPayload, report.Manifest.ContentKind)
+	assert.Equal(t, result.ID.String(), report.DocumentID)
+}
@@ -0,0 +1,144 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/reconcile"
+	"github.com/doreviateam/dorevia-vault/internal/services"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReconcile_LedgerCrossChecks teste la réconciliation complète : document sans
+// contenu marqué orphelin puis démarqué après restauration, document sans preuve scellé
+// après coup, ledger_hash incohérent et preuve JWS d'un autre document signalés
+func TestReconcile_LedgerCrossChecks(t *testing.T) {
+	db := setupTestDB(t)
+	jwsService := setupTestJWS(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	db.SetBlobStore(blobstore.NewMux(blobstore.NewFileStore(dir)))
+
+	tenant := "reconcile-" + uuid.NewString()[:8]
+	t.Cleanup(func() {
+		db.Pool.Exec(ctx, "DELETE FROM ledger WHERE document_id IN (SELECT id FROM documents WHERE tenant = $1)", tenant)
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE tenant = $1", tenant)
+		db.Close()
+	})
+
+	service := services.NewIngestionService(storage.NewDocumentRepository(db, ""), ledger.NewService(), crypto.NewLocalSigner(jwsService), services.IngestionOptions{
+		JWSRequired: true,
+		Logger:      logger.New("error"),
+	})
+	store := func(name string, seal bool) *models.Document {
+		content := []byte("%PDF-1.4 " + name + " " + uuid.NewString())
+		doc := &models.Document{Filename: name + ".pdf", ContentType: "application/pdf", SizeBytes: int64(len(content)), Tenant: &tenant}
+		require.NoError(t, db.StoreDocumentWithTransaction(ctx, doc, content, ""))
+		if seal {
+			require.NoError(t, service.Seal(ctx, doc))
+		}
+		return doc
+	}
+	filePath := func(doc *models.Document) string {
+		return filepath.Join(dir, strings.TrimPrefix(doc.StoredPath, blobstore.FileScheme))
+	}
+
+	healthy := store("intact", true)
+	missing := store("disparu", true)
+	missingContent, err := os.ReadFile(filePath(missing))
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filePath(missing)))
+	unsealed := store("ancien", false)
+	mismatch := store("incoherent", true)
+	_, err = db.Pool.Exec(ctx, "UPDATE documents SET ledger_hash = $2 WHERE id = $1", mismatch.ID, sha256Of([]byte(uuid.NewString())))
+	require.NoError(t, err)
+	forged := store("echange", true)
+	_, err = db.Pool.Exec(ctx, "UPDATE documents SET evidence_jws = $2 WHERE id = $1", forged.ID, *healthy.EvidenceJWS)
+	require.NoError(t, err)
+
+	opts := reconcile.Options{
+		DryRun:   true,
+		Verifier: jwsService,
+		Sealer:   service,
+		Filter:   storage.UnsealedFilter{MissingJWS: true, MissingLedger: true},
+	}
+	documentIDs := func(issues []reconcile.DocumentIssue) []string {
+		ids := make([]string, 0, len(issues))
+		for _, issue := range issues {
+			ids = append(ids, issue.DocumentID)
+		}
+		return ids
+	}
+	// La réconciliation porte sur toute la base : seuls les documents du test sont vérifiés
+	orphanIDs := func(orphans []reconcile.OrphanDB) []string {
+		ids := make([]string, 0, len(orphans))
+		for _, orphan := range orphans {
+			ids = append(ids, orphan.DocumentID)
+		}
+		return ids
+	}
+
+	// Dry-run : détection seule
+	report, err := reconcile.Reconcile(ctx, db, opts)
+	require.NoError(t, err)
+	assert.True(t, report.HasIssues())
+	assert.Empty(t, report.SkippedChecks)
+	assert.Contains(t, orphanIDs(report.OrphanDBs), missing.ID.String())
+	assert.NotContains(t, orphanIDs(report.OrphanDBs), healthy.ID.String())
+	assert.Contains(t, documentIDs(report.UnsealedDocuments), unsealed.ID.String())
+	assert.Contains(t, documentIDs(report.LedgerHashMismatches), mismatch.ID.String())
+	assert.Contains(t, documentIDs(report.InvalidEvidence), forged.ID.String())
+	for _, id := range []uuid.UUID{healthy.ID, missing.ID} {
+		assert.NotContains(t, documentIDs(report.LedgerHashMismatches), id.String())
+		assert.NotContains(t, documentIDs(report.InvalidEvidence), id.String())
+	}
+	assert.Zero(t, report.DocumentsSealed)
+	doc, err := db.GetDocumentByID(ctx, missing.ID)
+	require.NoError(t, err)
+	assert.Nil(t, doc.OrphanedAt)
+
+	// Fix : marquage de l'orphelin et scellement après coup
+	opts.DryRun = false
+	report, err = reconcile.Reconcile(ctx, db, opts)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, report.DBsMarked, 1)
+	assert.GreaterOrEqual(t, report.DocumentsSealed, 1)
+	assert.GreaterOrEqual(t, report.Unrepairable(), 2) // ledger_hash et preuve JWS ne sont pas réparés
+
+	doc, err = db.GetDocumentByID(ctx, missing.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, doc.OrphanedAt)
+
+	doc, err = db.GetDocumentByID(ctx, unsealed.ID)
+	require.NoError(t, err)
+	require.NotNil(t, doc.EvidenceJWS)
+	require.NotNil(t, doc.LedgerHash)
+	var sealedEvents int
+	require.NoError(t, db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM ledger WHERE document_id = $1 AND entry_type = $2", unsealed.ID, ledger.EntryTypeDocumentSealed).Scan(&sealedEvents))
+	assert.Equal(t, 1, sealedEvents)
+
+	// Restauration du contenu : le marqueur est retiré
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath(missing)), 0755))
+	require.NoError(t, os.WriteFile(filePath(missing), missingContent, 0644))
+	report, err = reconcile.Reconcile(ctx, db, opts)
+	require.NoError(t, err)
+	assert.NotContains(t, orphanIDs(report.OrphanDBs), missing.ID.String())
+	assert.GreaterOrEqual(t, report.DBsRestored, 1)
+	assert.NotContains(t, documentIDs(report.UnsealedDocuments), unsealed.ID.String())
+
+	doc, err = db.GetDocumentByID(ctx, missing.ID)
+	require.NoError(t, err)
+	assert.Nil(t, doc.OrphanedAt)
+}
@@ -0,0 +1,262 @@
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/retention"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRetention_PurgeAndLegalHold teste la purge des documents échus : pierre tombale
+// avec le SHA256, entrée document.purged au ledger, contenu partagé libéré, blocage
+// légal respecté et suppression d'un document inscrit au ledger refusée
+func TestRetention_PurgeAndLegalHold(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	fs := blobstore.NewFileStore(t.TempDir())
+	db.SetBlobStore(blobstore.NewMux(fs))
+	require.NoError(t, db.SetStorageLayout(blobstore.LayoutContent))
+
+	// Source propre au test pour ne pas purger les documents des autres tests
+	source := "retention-" + uuid.NewString()[:8]
+	content := []byte("%PDF-1.4 ticket de caisse " + uuid.NewString())
+	sum := sha256.Sum256(content)
+	sha256Hex := hex.EncodeToString(sum[:])
+
+	var ids []uuid.UUID
+	t.Cleanup(func() {
+		db.Pool.Exec(ctx, "UPDATE documents SET legal_hold = false WHERE id = ANY($1)", ids)
+		db.Pool.Exec(ctx, "DELETE FROM ledger WHERE document_id = ANY($1)", ids)
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE id = ANY($1)", ids)
+		db.Pool.Exec(ctx, "DELETE FROM blob_refs WHERE sha256_hex = $1", sha256Hex)
+	})
+	store := func(age time.Duration) *models.Document {
+		doc := &models.Document{Filename: "ticket.pdf", ContentType: "application/pdf", SizeBytes: int64(len(content)), Source: &source}
+		require.NoError(t, db.StoreDocumentWithTransaction(ctx, doc, content, ""))
+		ids = append(ids, doc.ID)
+		_, err := db.Pool.Exec(ctx, "UPDATE documents SET created_at = now() - $2::interval WHERE id = $1", doc.ID, age.String())
+		require.NoError(t, err)
+		return doc
+	}
+
+	expired := store(7 * 365 * 24 * time.Hour)
+	held := store(7 * 365 * 24 * time.Hour)
+	recent := store(24 * time.Hour)
+	require.Equal(t, expired.StoredPath, recent.StoredPath, "shared content")
+
+	// Document inscrit au ledger : la suppression est refusée (clé étrangère restrictive)
+	_, err := db.AppendLedgerEvent(ctx, "", ledger.Event{
+		Type:       ledger.EntryTypeDocumentStatusChanged,
+		DocumentID: &expired.ID,
+		Body:       ledger.StatusChangedBody{Field: "dispatch_status", To: "SENT"},
+	})
+	require.NoError(t, err)
+	_, err = db.Pool.Exec(ctx, "DELETE FROM documents WHERE id = $1", expired.ID)
+	assert.Error(t, err, "ledger entries are never deleted")
+
+	// Blocage légal : ni purge ni suppression
+	_, err = db.SetLegalHold(ctx, held.ID, storage.LegalHoldUpdate{Hold: true, Reason: "contrôle fiscal", Actor: "test"})
+	require.NoError(t, err)
+	_, err = db.PurgeDocument(ctx, held.ID, "test", "test")
+	assert.ErrorIs(t, err, storage.ErrLegalHold)
+	_, err = db.Pool.Exec(ctx, "DELETE FROM documents WHERE id = $1", held.ID)
+	assert.Error(t, err, "legal hold blocks deletion")
+
+	policies, err := retention.ParsePolicies(source + "=6y")
+	require.NoError(t, err)
+	purger := retention.NewPurger(db, nil, retention.Config{Policies: policies, Logger: *logger.New("error")})
+
+	report, err := purger.Run(ctx, retention.Options{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Expired)
+	assert.Zero(t, report.Purged)
+
+	report, err = purger.Run(ctx, retention.Options{Actor: "test"})
+	require.NoError(t, err)
+	assert.Empty(t, report.Failures)
+	assert.Equal(t, 1, report.Purged)
+
+	// Pierre tombale : métadonnées et SHA256 conservés, contenu retiré
+	doc, err := db.GetDocumentByID(ctx, expired.ID)
+	require.NoError(t, err)
+	require.NotNil(t, doc.PurgedAt)
+	assert.Equal(t, sha256Hex, doc.SHA256Hex)
+	assert.Empty(t, doc.StoredPath)
+	require.NotNil(t, doc.PurgeReason)
+	assert.Equal(t, "retention "+source+"=6y", *doc.PurgeReason)
+
+	var entryType string
+	require.NoError(t, db.Pool.QueryRow(ctx, `
+		SELECT entry_type FROM ledger WHERE document_id = $1 ORDER BY timestamp DESC, id DESC LIMIT 1
+	`, expired.ID).Scan(&entryType))
+	assert.Equal(t, ledger.EntryTypeDocumentPurged, entryType)
+
+	// Contenu partagé encore référencé par le document récent : conservé
+	var refCount int
+	require.NoError(t, db.Pool.QueryRow(ctx, "SELECT ref_count FROM blob_refs WHERE ref = $1", recent.StoredPath).Scan(&refCount))
+	assert.Equal(t, 2, refCount)
+	plain, err := blobstore.ReadAll(ctx, fs, recent.StoredPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, plain)
+
+	// Levée du blocage : le document est purgé à la passe suivante
+	_, err = db.SetLegalHold(ctx, held.ID, storage.LegalHoldUpdate{Hold: false, Actor: "test"})
+	require.NoError(t, err)
+	report, err = purger.Run(ctx, retention.Options{Actor: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Purged)
+
+	_, err = db.SetLegalHold(ctx, held.ID, storage.LegalHoldUpdate{Hold: true, Reason: "trop tard"})
+	assert.ErrorIs(t, err, storage.ErrDocumentPurged)
+}
+
+// TestRetention_InvoiceDateAndFailures teste que la conservation court à partir de la date
+// de facture, et qu'une passe continue au-delà d'un lot composé uniquement d'échecs
+func TestRetention_InvoiceDateAndFailures(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	fs := blobstore.NewFileStore(t.TempDir())
+	db.SetBlobStore(blobstore.NewMux(fs))
+
+	source := "retention-" + uuid.NewString()[:8]
+	var ids []uuid.UUID
+	t.Cleanup(func() {
+		db.Pool.Exec(ctx, "DROP TRIGGER IF EXISTS trg_retention_test_failure ON documents")
+		db.Pool.Exec(ctx, "DELETE FROM ledger WHERE document_id = ANY($1)", ids)
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE id = ANY($1)", ids)
+	})
+
+	// Purge refusée pour les documents "unpurgeable.pdf" de ce test
+	_, err := db.Pool.Exec(ctx, `
+		CREATE OR REPLACE FUNCTION retention_test_failure() RETURNS trigger AS $$
+		BEGIN
+			IF NEW.purged_at IS NOT NULL AND OLD.filename = 'unpurgeable.pdf' THEN
+				RAISE EXCEPTION 'purge refused by test';
+			END IF;
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+		DROP TRIGGER IF EXISTS trg_retention_test_failure ON documents;
+		CREATE TRIGGER trg_retention_test_failure BEFORE UPDATE ON documents
+			FOR EACH ROW EXECUTE FUNCTION retention_test_failure();
+	`)
+	require.NoError(t, err)
+
+	store := func(filename string, age time.Duration, invoiceAge *time.Duration) *models.Document {
+		content := []byte("%PDF-1.4 facture " + uuid.NewString())
+		doc := &models.Document{Filename: filename, ContentType: "application/pdf", SizeBytes: int64(len(content)), Source: &source}
+		require.NoError(t, db.StoreDocumentWithTransaction(ctx, doc, content, ""))
+		ids = append(ids, doc.ID)
+		_, err := db.Pool.Exec(ctx, "UPDATE documents SET created_at = now() - $2::interval WHERE id = $1", doc.ID, age.String())
+		require.NoError(t, err)
+		if invoiceAge != nil {
+			_, err = db.Pool.Exec(ctx, "UPDATE documents SET invoice_date = (now() - $2::interval)::date WHERE id = $1", doc.ID, invoiceAge.String())
+			require.NoError(t, err)
+		}
+		return doc
+	}
+	old := 7 * 365 * 24 * time.Hour
+	recent := 24 * time.Hour
+
+	failed1 := store("unpurgeable.pdf", old+2*recent, nil)
+	failed2 := store("unpurgeable.pdf", old+recent, nil)
+	oldInvoice := store("facture.pdf", recent, &old)
+	recentInvoice := store("facture.pdf", old, &recent)
+	expired := store("facture.pdf", old, nil)
+
+	policies, err := retention.ParsePolicies(source + "=6y")
+	require.NoError(t, err)
+	purger := retention.NewPurger(db, nil, retention.Config{Policies: policies, BatchSize: 1, Logger: *logger.New("error")})
+
+	report, err := purger.Run(ctx, retention.Options{Actor: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Expired)
+	assert.Equal(t, 2, report.Purged)
+	require.Len(t, report.Failures, 2)
+	assert.Equal(t, failed1.ID.String(), report.Failures[0].DocumentID)
+	assert.Equal(t, failed2.ID.String(), report.Failures[1].DocumentID)
+
+	for _, tc := range []struct {
+		doc    *models.Document
+		purged bool
+	}{
+		{failed1, false},
+		{failed2, false},
+		{oldInvoice, true},
+		{recentInvoice, false},
+		{expired, true},
+	} {
+		doc, err := db.GetDocumentByID(ctx, tc.doc.ID)
+		require.NoError(t, err)
+		assert.Equal(t, tc.purged, doc.PurgedAt != nil, doc.ID.String())
+	}
+}
+
+// TestRetention_VerifyTombstone teste qu'une pierre tombale n'est acceptée par la
+// vérification d'intégrité que si la purge est inscrite au ledger : un purged_at posé
+// directement en base ne masque pas un contenu altéré
+func TestRetention_VerifyTombstone(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	fs := blobstore.NewFileStore(t.TempDir())
+	db.SetBlobStore(blobstore.NewMux(fs))
+
+	source := "retention-" + uuid.NewString()[:8]
+	var ids []uuid.UUID
+	t.Cleanup(func() {
+		db.Pool.Exec(ctx, "DELETE FROM ledger WHERE document_id = ANY($1)", ids)
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE id = ANY($1)", ids)
+	})
+	store := func() *models.Document {
+		content := []byte("%PDF-1.4 facture " + uuid.NewString())
+		doc := &models.Document{Filename: "facture.pdf", ContentType: "application/pdf", SizeBytes: int64(len(content)), Source: &source}
+		require.NoError(t, db.StoreDocumentWithTransaction(ctx, doc, content, ""))
+		ids = append(ids, doc.ID)
+		return doc
+	}
+	fileCheck := func(result *verify.VerificationResult) verify.Check {
+		for _, check := range result.Checks {
+			if check.Component == "file" {
+				return check
+			}
+		}
+		t.Fatalf("no file check in %+v", result.Checks)
+		return verify.Check{}
+	}
+
+	// Purge inscrite au ledger : pierre tombale acceptée
+	purged := store()
+	_, err := db.PurgeDocument(ctx, purged.ID, "test", "test")
+	require.NoError(t, err)
+	result, err := verify.VerifyDocumentIntegrity(ctx, db, nil, purged.ID)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Errors)
+	assert.Equal(t, "purged", fileCheck(result).Status)
+
+	// purged_at posé en base sans entrée document.purged : contenu altéré non masqué
+	forged := store()
+	_, err = db.Pool.Exec(ctx, "UPDATE documents SET purged_at = now(), stored_path = '' WHERE id = $1", forged.ID)
+	require.NoError(t, err)
+	result, err = verify.VerifyDocumentIntegrity(ctx, db, nil, forged.ID)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, "error", fileCheck(result).Status)
+}
@@ -0,0 +1,133 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/scrub"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestScrub_DetectsCorruption teste la vérification en arrière-plan : contenu altéré,
+// fichier manquant et payload POS modifié enregistrés, document purgé ignoré, altération
+// levée après restauration et curseur remis à zéro en fin de passe
+func TestScrub_DetectsCorruption(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	db.SetBlobStore(blobstore.NewMux(blobstore.NewFileStore(dir)))
+
+	tenant := "scrub-" + uuid.NewString()[:8]
+	var ids []uuid.UUID
+	t.Cleanup(func() {
+		db.Pool.Exec(ctx, "DELETE FROM ledger WHERE document_id = ANY($1)", ids)
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE id = ANY($1)", ids)
+		db.Pool.Exec(ctx, "DELETE FROM scrub_state")
+	})
+	// Passe complète depuis le premier document
+	_, err := db.Pool.Exec(ctx, "DELETE FROM scrub_state")
+	require.NoError(t, err)
+
+	store := func(content []byte) *models.Document {
+		doc := &models.Document{Filename: "facture.pdf", ContentType: "application/pdf", SizeBytes: int64(len(content)), Tenant: &tenant}
+		require.NoError(t, db.StoreDocumentWithTransaction(ctx, doc, content, ""))
+		ids = append(ids, doc.ID)
+		return doc
+	}
+	filePath := func(doc *models.Document) string {
+		return filepath.Join(dir, strings.TrimPrefix(doc.StoredPath, blobstore.FileScheme))
+	}
+
+	healthy := store([]byte("%PDF-1.4 intacte " + uuid.NewString()))
+	original := []byte("%PDF-1.4 altérée " + uuid.NewString())
+	altered := store(original)
+	require.NoError(t, os.WriteFile(filePath(altered), []byte("%PDF-1.4 bit flip "+uuid.NewString()), 0644))
+	missing := store([]byte("%PDF-1.4 disparue " + uuid.NewString()))
+	require.NoError(t, os.Remove(filePath(missing)))
+	purged := store([]byte("%PDF-1.4 purgée " + uuid.NewString()))
+	_, err = db.PurgeDocument(ctx, purged.ID, "test", "test")
+	require.NoError(t, err)
+
+	// Ticket POS dont le payload ne correspond plus au SHA256 enregistré
+	posID := uuid.New()
+	_, err = db.Pool.Exec(ctx, `
+		INSERT INTO documents (id, filename, content_type, size_bytes, sha256_hex, stored_path, tenant, source, payload_json)
+		VALUES ($1, 'ticket.json', 'application/json', 0, $2, '', $3, 'pos', $4)
+	`, posID, sha256Of([]byte(posID.String())), tenant, `{"ticket":{"total":12.5},"source_id":"POS-1"}`)
+	require.NoError(t, err)
+	ids = append(ids, posID)
+
+	scrubber := scrub.NewScrubber(db, scrub.Config{BatchSize: 2, Logger: *logger.New("error")})
+	report, err := scrubber.Run(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Completed)
+	assert.False(t, report.Resumed)
+
+	kinds := func() map[uuid.UUID]string {
+		rows, err := db.Pool.Query(ctx, "SELECT document_id, kind FROM scrub_corruptions WHERE document_id = ANY($1) AND resolved_at IS NULL", ids)
+		require.NoError(t, err)
+		defer rows.Close()
+		open := map[uuid.UUID]string{}
+		for rows.Next() {
+			var id uuid.UUID
+			var kind string
+			require.NoError(t, rows.Scan(&id, &kind))
+			open[id] = kind
+		}
+		require.NoError(t, rows.Err())
+		return open
+	}
+	assert.Equal(t, map[uuid.UUID]string{
+		altered.ID: models.ScrubCorruptionMismatch,
+		missing.ID: models.ScrubCorruptionMissing,
+		posID:      models.ScrubCorruptionPayload,
+	}, kinds())
+	assert.NotContains(t, kinds(), healthy.ID)
+	assert.NotContains(t, kinds(), purged.ID)
+
+	state, err := db.GetScrubState(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, state.Cursor())
+	assert.EqualValues(t, 1, state.PassesCompleted)
+	require.NotNil(t, state.LastPassCompletedAt)
+
+	// Reprise : seuls les documents après le curseur sont listés
+	require.NoError(t, db.SaveScrubCursor(ctx, altered))
+	state, err = db.GetScrubState(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, state.Cursor())
+	assert.Equal(t, altered.ID, state.Cursor().ID)
+	docs, err := db.ListScrubDocuments(ctx, state.Cursor(), 0)
+	require.NoError(t, err)
+	for _, doc := range docs {
+		assert.NotEqual(t, healthy.ID, doc.ID)
+		assert.NotEqual(t, altered.ID, doc.ID)
+	}
+
+	// Restauration depuis une sauvegarde : l'altération est levée à la passe suivante,
+	// une altération déjà ouverte n'est pas signalée à nouveau
+	_, err = db.Pool.Exec(ctx, "DELETE FROM scrub_state")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filePath(altered), original, 0644))
+	report, err = scrubber.Run(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Completed)
+	assert.GreaterOrEqual(t, report.Resolved, int64(1))
+	open := kinds()
+	assert.NotContains(t, open, altered.ID)
+	assert.Contains(t, open, missing.ID)
+
+	var resolved bool
+	require.NoError(t, db.Pool.QueryRow(ctx, "SELECT resolved_at IS NOT NULL FROM scrub_corruptions WHERE document_id = $1", altered.ID).Scan(&resolved))
+	assert.True(t, resolved)
+}
@@ -0,0 +1,299 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/handlers"
+	"github.com/doreviateam/dorevia-vault/internal/middleware"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/services"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/internal/upload"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupUploadApp crée l'application des routes d'ingestion en flux
+func setupUploadApp(t *testing.T, source string) (*fiber.App, *storage.DB) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	t.Cleanup(func() {
+		db.Pool.Exec(ctx, "DELETE FROM upload_sessions WHERE metadata->>'source' = $1", source)
+		db.Pool.Exec(ctx, "DELETE FROM ledger WHERE document_id IN (SELECT id FROM documents WHERE source = $1)", source)
+		db.Pool.Exec(ctx, "DELETE FROM documents WHERE source = $1", source)
+		db.Close()
+	})
+
+	fs := blobstore.NewFileStore(t.TempDir())
+	db.SetBlobStore(blobstore.NewMux(fs))
+
+	log := zerolog.Nop()
+	cfg := &config.Config{UploadMaxSizeMB: 16}
+	manager, err := upload.NewManager(db, upload.Config{
+		Dir:           t.TempDir(),
+		ChunkMaxBytes: 1 << 20,
+		MaxSizeBytes:  16 << 20,
+		TTL:           time.Hour,
+		Logger:        log,
+	})
+	require.NoError(t, err)
+
+	service := services.NewIngestionService(storage.NewDocumentRepository(db, ""), nil, nil, services.IngestionOptions{Logger: &log})
+
+	app := fiber.New(fiber.Config{StreamRequestBody: true, BodyLimit: 64 * 1024})
+	app.Use(middleware.BodyLimit(64*1024, "/api/v1/invoices", "/api/v1/uploads"))
+	app.Post("/api/v1/invoices", handlers.InvoicesHandler(service, cfg, &log))
+	app.Post("/api/v1/uploads", handlers.UploadCreateHandler(manager, &log))
+	app.Head("/api/v1/uploads/:id", handlers.UploadStatusHandler(manager, &log))
+	app.Get("/api/v1/uploads/:id", handlers.UploadStatusHandler(manager, &log))
+	app.Patch("/api/v1/uploads/:id", handlers.UploadChunkHandler(manager, &log))
+	app.Post("/api/v1/uploads/:id/complete", handlers.UploadCompleteHandler(manager, service, cfg, &log))
+	app.Delete("/api/v1/uploads/:id", handlers.UploadAbortHandler(manager, &log))
+	return app, db
+}
+
+func sha256Of(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// TestInvoices_RawPDF teste l'ingestion d'un PDF brut reçu en flux (au-delà de la limite
+// des corps en mémoire)
+func TestInvoices_RawPDF(t *testing.T) {
+	source := "stream-" + uuid.NewString()[:8]
+	app, db := setupUploadApp(t, source)
+
+	content := bytes.Repeat([]byte("%PDF-1.4 lot de factures scannées "), 8192)
+	req := httptest.NewRequest("POST", "/api/v1/invoices?source="+source+"&model=account.move&odoo_id=42", bytes.NewReader(content))
+	req.Header.Set("Content-Type", "application/pdf")
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	var result handlers.InvoiceResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, sha256Of(content), result.SHA256Hex)
+
+	ctx := context.Background()
+	doc, err := db.GetDocumentByID(ctx, uuid.MustParse(result.ID))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), doc.SizeBytes)
+	stored, err := db.ReadDocumentContent(ctx, doc)
+	require.NoError(t, err)
+	assert.Equal(t, content, stored)
+}
+
+// TestUploadSessions teste le protocole d'upload reprenable : morceaux vérifiés, reprise
+// à l'offset enregistré, scellement après vérification du SHA256 assemblé
+func TestUploadSessions(t *testing.T) {
+	source := "resumable-" + uuid.NewString()[:8]
+	app, db := setupUploadApp(t, source)
+
+	content := bytes.Repeat([]byte("%PDF-1.4 facture fournisseur "+uuid.NewString()), 10000)
+	chunkSize := len(content)/3 + 1
+	var chunks [][]byte
+	for offset := 0; offset < len(content); offset += chunkSize {
+		end := offset + chunkSize
+		if end > len(content) {
+			end = len(content)
+		}
+		chunks = append(chunks, content[offset:end])
+	}
+
+	do := func(method, target string, body []byte, headers map[string]string) *http.Response {
+		req := httptest.NewRequest(method, target, bytes.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+	create := func(sha256Hex string) string {
+		payload, _ := json.Marshal(handlers.UploadSessionRequest{
+			SizeBytes: int64(len(content)),
+			SHA256Hex: sha256Hex,
+			Source:    source,
+			Model:     "account.move",
+			OdooID:    7,
+		})
+		resp := do("POST", "/api/v1/uploads", payload, map[string]string{"Content-Type": "application/json"})
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		return resp.Header.Get("Location")
+	}
+	patch := func(location string, offset int, chunk []byte, checksum string) *http.Response {
+		return do("PATCH", location, chunk, map[string]string{
+			"Content-Type":              "application/offset+octet-stream",
+			handlers.HeaderUploadOffset: fmt.Sprint(offset),
+			handlers.HeaderChunkSHA256:  checksum,
+		})
+	}
+
+	location := create(sha256Of(content))
+	require.NotEmpty(t, location)
+
+	resp := patch(location, 0, chunks[0], sha256Of(chunks[0]))
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, fmt.Sprint(len(chunks[0])), resp.Header.Get(handlers.HeaderUploadOffset))
+
+	// Morceau corrompu : refusé, l'offset ne bouge pas
+	resp = patch(location, len(chunks[0]), chunks[1], sha256Of(chunks[0]))
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+
+	// Morceau à un autre offset : refusé avec l'offset courant
+	resp = patch(location, 0, chunks[1], sha256Of(chunks[1]))
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+	assert.Equal(t, fmt.Sprint(len(chunks[0])), resp.Header.Get(handlers.HeaderUploadOffset))
+
+	// Reprise : l'offset est demandé avant de continuer
+	resp = do("HEAD", location, nil, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, fmt.Sprint(len(chunks[0])), resp.Header.Get(handlers.HeaderUploadOffset))
+	assert.Equal(t, fmt.Sprint(len(content)), resp.Header.Get(handlers.HeaderUploadLength))
+
+	resp = do("POST", location+"/complete", nil, nil)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode, "incomplete upload")
+
+	offset := len(chunks[0])
+	for _, chunk := range chunks[1:] {
+		resp = patch(location, offset, chunk, sha256Of(chunk))
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		offset += len(chunk)
+	}
+
+	resp = do("POST", location+"/complete", nil, nil)
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var result handlers.InvoiceResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, sha256Of(content), result.SHA256Hex)
+
+	resp = do("GET", location, nil, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var session models.UploadSession
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&session))
+	assert.Equal(t, models.UploadStatusCompleted, session.Status)
+	require.NotNil(t, session.DocumentID)
+	assert.Equal(t, result.ID, session.DocumentID.String())
+
+	doc, err := db.GetDocumentByID(context.Background(), *session.DocumentID)
+	require.NoError(t, err)
+	stored, err := db.ReadDocumentContent(context.Background(), doc)
+	require.NoError(t, err)
+	assert.Equal(t, content, stored)
+
+	resp = do("POST", location+"/complete", nil, nil)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode, "already completed")
+
+	// SHA256 assemblé différent du SHA256 déclaré : rien n'est scellé, l'upload repart de zéro
+	location = create(sha256Of([]byte("autre document")))
+	offset = 0
+	for _, chunk := range chunks {
+		resp = patch(location, offset, chunk, sha256Of(chunk))
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		offset += len(chunk)
+	}
+	resp = do("POST", location+"/complete", nil, nil)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	resp = do("HEAD", location, nil, nil)
+	assert.Equal(t, "0", resp.Header.Get(handlers.HeaderUploadOffset))
+
+	resp = do("DELETE", location, nil, nil)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	resp = do("HEAD", location, nil, nil)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+// TestUploadManager_EncryptedChunks teste le chiffrement des morceaux d'un upload
+// reprenable et la suppression des fichiers abandonnés
+func TestUploadManager_EncryptedChunks(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	keys := crypto.NewDirKeyManager(t.TempDir(), zerolog.Nop())
+	require.NoError(t, keys.GenerateKeyPair(ctx, "kek-1"))
+	envelope, err := crypto.NewEnvelope(keys, "kek-1")
+	require.NoError(t, err)
+	db.SetEnvelope(envelope)
+
+	dir := t.TempDir()
+	manager, err := upload.NewManager(db, upload.Config{Dir: dir, TTL: time.Hour, Logger: zerolog.Nop()})
+	require.NoError(t, err)
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	content := bytes.Repeat([]byte("%PDF-1.4 facture confidentielle "), 4096)
+	half := len(content) / 2
+	s, err := manager.Create(ctx, upload.CreateRequest{SizeBytes: int64(len(content)), SHA256Hex: sha256Of(content)})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Pool.Exec(ctx, "DELETE FROM upload_sessions WHERE id = $1", s.ID) })
+	require.NotNil(t, s.WrappedKey)
+
+	_, err = manager.Append(ctx, s.ID, "", 0, sha256Of(content[:half]), bytes.NewReader(content[:half]))
+	require.NoError(t, err)
+	_, err = manager.Append(ctx, s.ID, "", int64(half), sha256Of(content[half:]), bytes.NewReader(content[half:]))
+	require.NoError(t, err)
+
+	// Aucun fichier du répertoire ne contient le clair
+	files := 0
+	require.NoError(t, filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		require.NoError(t, err)
+		if d.IsDir() {
+			return nil
+		}
+		files++
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw), "facture confidentielle", path)
+		return nil
+	}))
+	assert.Equal(t, 2, files)
+
+	sealed, assembled, err := manager.Seal(ctx, s.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, s.ID, sealed.ID)
+	plain, err := storage.ReadContent(assembled)
+	require.NoError(t, err)
+	assert.Equal(t, content, plain)
+	require.NoError(t, manager.Release(ctx, s.ID))
+
+	// Fichiers abandonnés : morceaux d'un upload supprimé et fichier temporaire d'un
+	// contenu reçu en flux, anciens ; un fichier récent est conservé
+	old := time.Now().Add(-2 * time.Hour)
+	orphanDir := filepath.Join(dir, uuid.NewString())
+	require.NoError(t, os.Mkdir(orphanDir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(orphanDir, "00000000000000000000.part"), []byte("x"), 0600))
+	require.NoError(t, os.Chtimes(orphanDir, old, old))
+	stale := filepath.Join(dir, storage.SpoolFilePrefix+"stale")
+	require.NoError(t, os.WriteFile(stale, []byte("x"), 0600))
+	require.NoError(t, os.Chtimes(stale, old, old))
+	recent := filepath.Join(dir, storage.SpoolFilePrefix+"recent")
+	require.NoError(t, os.WriteFile(recent, []byte("x"), 0600))
+	liveDir := filepath.Join(dir, s.ID.String())
+	require.NoError(t, os.Chtimes(liveDir, old, old))
+
+	_, err = manager.CleanupExpired(ctx)
+	require.NoError(t, err)
+	assert.NoDirExists(t, orphanDir)
+	assert.NoFileExists(t, stale)
+	assert.FileExists(t, recent)
+	assert.DirExists(t, liveDir, "directory of a live upload")
+}
@@ -0,0 +1,143 @@
+package unit
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/handlers"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPIKeyStore est un stockage de clés API en mémoire
+type fakeAPIKeyStore struct {
+	mu      sync.Mutex
+	keys    map[string]*auth.APIKey // key_hash -> clé
+	lookups int
+	touched map[string]time.Time
+}
+
+func newFakeAPIKeyStore() *fakeAPIKeyStore {
+	return &fakeAPIKeyStore{
+		keys:    make(map[string]*auth.APIKey),
+		touched: make(map[string]time.Time),
+	}
+}
+
+func (s *fakeAPIKeyStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (*auth.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lookups++
+	key, ok := s.keys[keyHash]
+	if !ok {
+		return nil, nil
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (s *fakeAPIKeyStore) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.touched[keyID] = usedAt
+	return nil
+}
+
+// TestGenerateAPIKey teste le format des clés générées
+func TestGenerateAPIKey(t *testing.T) {
+	plaintext, keyID, keyHash, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(plaintext, auth.APIKeyPrefix+"_"+keyID+"_"))
+	assert.Len(t, keyID, 16)
+	assert.Equal(t, auth.HashAPIKey(plaintext), keyHash)
+	assert.Equal(t, hashAPIKey(plaintext), keyHash)
+
+	other, otherID, _, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, plaintext, other)
+	assert.NotEqual(t, keyID, otherID)
+}
+
+// TestAuthService_APIKeyStore teste l'authentification via le stockage persistant et son cache
+func TestAuthService_APIKeyStore(t *testing.T) {
+	store := newFakeAPIKeyStore()
+	plaintext, keyID, keyHash, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+	store.keys[keyHash] = &auth.APIKey{
+		KeyID:     keyID,
+		KeyHash:   keyHash,
+		UserID:    "odoo-connector",
+		Role:      "operator",
+		Tenant:    "acme",
+		CreatedAt: time.Now(),
+		IsActive:  true,
+	}
+
+	service := auth.NewAuthService(auth.AuthConfig{
+		APIKeys:        make(map[string]*auth.APIKey),
+		APIKeyEnabled:  true,
+		APIKeyStore:    store,
+		APIKeyCacheTTL: time.Hour,
+		Logger:         zerolog.Nop(),
+	})
+	ctx := context.Background()
+
+	userInfo, err := service.Authenticate(ctx, "apikey "+plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, "odoo-connector", userInfo.UserID)
+	assert.Equal(t, "operator", userInfo.Role)
+	assert.Equal(t, keyID, userInfo.KeyID)
+	assert.Equal(t, "acme", userInfo.Tenant)
+	assert.Contains(t, store.touched, keyID)
+
+	// Second appel servi par le cache
+	_, err = service.Authenticate(ctx, "apikey "+plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.lookups)
+
+	// Révocation : après invalidation du cache, la clé inactive est refusée
+	store.keys[keyHash].IsActive = false
+	service.InvalidateAPIKey(keyID)
+	_, err = service.Authenticate(ctx, "apikey "+plaintext)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not active")
+	assert.Equal(t, 2, store.lookups)
+
+	// Clé inconnue
+	_, err = service.Authenticate(ctx, "apikey dvk_unknown_key")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid API key")
+}
+
+// TestAPIKeysHandlers_NoDatabase teste les endpoints d'administration sans DB
+func TestAPIKeysHandlers_NoDatabase(t *testing.T) {
+	log := zerolog.Nop()
+	app := fiber.New()
+	app.Post("/api/v1/admin/api-keys", handlers.APIKeysCreateHandler(nil, false, &log, nil))
+	app.Get("/api/v1/admin/api-keys", handlers.APIKeysListHandler(nil, &log))
+	app.Delete("/api/v1/admin/api-keys/:key_id", handlers.APIKeysRevokeHandler(nil, nil, &log, nil))
+	app.Post("/api/v1/admin/api-keys/:key_id/rotate", handlers.APIKeysRotateHandler(nil, nil, &log, nil))
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{"POST", "/api/v1/admin/api-keys"},
+		{"GET", "/api/v1/admin/api-keys"},
+		{"DELETE", "/api/v1/admin/api-keys/abc"},
+		{"POST", "/api/v1/admin/api-keys/abc/rotate"},
+	}
+	for _, r := range requests {
+		resp, err := app.Test(httptest.NewRequest(r.method, r.path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode, r.method+" "+r.path)
+	}
+}
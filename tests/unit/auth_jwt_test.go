@@ -0,0 +1,232 @@
+package unit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testJWKSServer sert un JWKS modifiable (simulation de rotation côté SSO)
+type testJWKSServer struct {
+	mu      sync.Mutex
+	keys    []auth.JWK
+	fetches int32
+	server  *httptest.Server
+}
+
+func newTestJWKSServer(t *testing.T) *testJWKSServer {
+	s := &testJWKSServer{}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.fetches, 1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(auth.JWKSet{Keys: s.keys})
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *testJWKSServer) setKeys(keys ...auth.JWK) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) auth.JWK {
+	return auth.JWK{
+		Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256",
+		N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) auth.JWK {
+	return auth.JWK{
+		Kty: "EC", Kid: kid, Use: "sig", Alg: "ES256", Crv: "P-256",
+		X: base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y: base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func signTestJWT(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":  "user-123",
+		"role": "operator",
+		"iss":  "https://sso.example.com",
+		"aud":  "dorevia-vault",
+		"iat":  time.Now().Unix(),
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// TestLoadPublicKeyPEM teste le chargement de clés publiques PEM RSA et ECDSA
+func TestLoadPublicKeyPEM(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	rsaPath := filepath.Join(dir, "rsa.pem")
+	require.NoError(t, os.WriteFile(rsaPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+
+	key, err := auth.LoadPublicKeyPEM(rsaPath)
+	require.NoError(t, err)
+	assert.IsType(t, &rsa.PublicKey{}, key)
+
+	// PKCS#1
+	pkcs1Path := filepath.Join(dir, "rsa-pkcs1.pem")
+	require.NoError(t, os.WriteFile(pkcs1Path, pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)}), 0600))
+	key, err = auth.LoadPublicKeyPEM(pkcs1Path)
+	require.NoError(t, err)
+	assert.IsType(t, &rsa.PublicKey{}, key)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err = x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	ecPath := filepath.Join(dir, "ec.pem")
+	require.NoError(t, os.WriteFile(ecPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+
+	key, err = auth.LoadPublicKeyPEM(ecPath)
+	require.NoError(t, err)
+	assert.IsType(t, &ecdsa.PublicKey{}, key)
+
+	_, err = auth.LoadPublicKeyPEM(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
+
+// TestAuthService_JWT_ES256StaticKey teste un JWT ES256 vérifié par une clé statique
+func TestAuthService_JWT_ES256StaticKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	service := auth.NewAuthService(auth.AuthConfig{
+		JWTPublicKey: &ecKey.PublicKey,
+		JWTEnabled:   true,
+		Logger:       zerolog.Nop(),
+	})
+
+	token := signTestJWT(t, jwt.SigningMethodES256, ecKey, "", validClaims())
+	userInfo, err := service.Authenticate(context.Background(), "Bearer "+token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-123", userInfo.UserID)
+
+	// Algorithme non supporté (HS256) refusé
+	hsToken := signTestJWT(t, jwt.SigningMethodHS256, []byte("secret"), "", validClaims())
+	_, err = service.Authenticate(context.Background(), "Bearer "+hsToken)
+	assert.Error(t, err)
+}
+
+// TestAuthService_JWT_JWKSRotation teste la sélection par kid et le rafraîchissement sur kid inconnu
+func TestAuthService_JWT_JWKSRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	srv := newTestJWKSServer(t)
+	srv.setKeys(rsaJWK("rsa-1", &rsaKey.PublicKey))
+
+	jwks := auth.NewJWKSCache(srv.server.URL, time.Hour, nil)
+	jwks.SetMinRefreshWait(0)
+	service := auth.NewAuthService(auth.AuthConfig{
+		JWKS:        jwks,
+		JWTEnabled:  true,
+		JWTIssuer:   "https://sso.example.com",
+		JWTAudience: "dorevia-vault",
+		Logger:      zerolog.Nop(),
+	})
+	ctx := context.Background()
+
+	// RS256 avec kid connu
+	_, err = service.Authenticate(ctx, "Bearer "+signTestJWT(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&srv.fetches))
+
+	// Second appel : servi par le cache
+	_, err = service.Authenticate(ctx, "Bearer "+signTestJWT(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&srv.fetches))
+
+	// Rotation : nouvelle clé ES256 publiée, kid inconnu => rafraîchissement
+	srv.setKeys(rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-2", &ecKey.PublicKey))
+	userInfo, err := service.Authenticate(ctx, "Bearer "+signTestJWT(t, jwt.SigningMethodES256, ecKey, "ec-2", validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, "user-123", userInfo.UserID)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&srv.fetches))
+
+	// kid inconnu même après rafraîchissement
+	_, err = service.Authenticate(ctx, "Bearer "+signTestJWT(t, jwt.SigningMethodES256, ecKey, "unknown", validClaims()))
+	assert.Error(t, err)
+
+	// kid pointant sur une clé d'un autre type que l'algorithme
+	_, err = service.Authenticate(ctx, "Bearer "+signTestJWT(t, jwt.SigningMethodES256, ecKey, "rsa-1", validClaims()))
+	assert.Error(t, err)
+}
+
+// TestAuthService_JWT_RegisteredClaims teste iss, aud, exp, nbf et la tolérance d'horloge
+func TestAuthService_JWT_RegisteredClaims(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	service := auth.NewAuthService(auth.AuthConfig{
+		JWTPublicKey: &rsaKey.PublicKey,
+		JWTEnabled:   true,
+		JWTIssuer:    "https://sso.example.com",
+		JWTAudience:  "dorevia-vault",
+		JWTClockSkew: 30 * time.Second,
+		Logger:       zerolog.Nop(),
+	})
+	ctx := context.Background()
+	authenticate := func(mutate func(jwt.MapClaims)) error {
+		claims := validClaims()
+		mutate(claims)
+		_, err := service.Authenticate(ctx, "Bearer "+signTestJWT(t, jwt.SigningMethodRS256, rsaKey, "", claims))
+		return err
+	}
+
+	assert.NoError(t, authenticate(func(c jwt.MapClaims) {}))
+	assert.Error(t, authenticate(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }))
+	assert.Error(t, authenticate(func(c jwt.MapClaims) { c["aud"] = "other-service" }))
+	assert.NoError(t, authenticate(func(c jwt.MapClaims) { c["aud"] = []string{"other-service", "dorevia-vault"} }))
+	assert.Error(t, authenticate(func(c jwt.MapClaims) { delete(c, "exp") }))
+	assert.Error(t, authenticate(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }))
+	assert.Error(t, authenticate(func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Minute).Unix() }))
+
+	// Dans la tolérance d'horloge
+	assert.NoError(t, authenticate(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-10 * time.Second).Unix() }))
+	assert.NoError(t, authenticate(func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(10 * time.Second).Unix() }))
+}
@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuthService_Authenticate_TenantRequired teste qu'avec AUTH_TENANT_REQUIRED une
+// identité sans tenant n'est acceptée que pour un administrateur, et que sans ce réglage
+// les jetons sans tenant (déploiement mono-tenant) restent acceptés
+func TestAuthService_Authenticate_TenantRequired(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	apiKey := "no-tenant-api-key-12345"
+	keyHash := hashAPIKey(apiKey)
+	newService := func(tenantRequired bool) *auth.AuthService {
+		return auth.NewAuthService(auth.AuthConfig{
+			JWTPublicKey: &privateKey.PublicKey,
+			JWTEnabled:   true,
+			APIKeys: map[string]*auth.APIKey{
+				keyHash: {KeyID: "key-no-tenant", KeyHash: keyHash, UserID: "user-789", Role: "auditor", CreatedAt: time.Now(), IsActive: true},
+			},
+			APIKeyEnabled:  true,
+			TenantRequired: tenantRequired,
+			Logger:         zerolog.Nop(),
+		})
+	}
+	ctx := context.Background()
+	sign := func(role string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"sub":  "user-123",
+			"role": role,
+			"exp":  time.Now().Add(1 * time.Hour).Unix(),
+		})
+		tokenString, err := token.SignedString(privateKey)
+		require.NoError(t, err)
+		return tokenString
+	}
+
+	// Multi-tenant strict : refusé hors administrateur
+	strict := newService(true)
+	_, err = strict.Authenticate(ctx, "Bearer "+sign("operator"))
+	assert.ErrorIs(t, err, auth.ErrTenantRequired)
+	_, err = strict.Authenticate(ctx, "apikey "+apiKey)
+	assert.ErrorIs(t, err, auth.ErrTenantRequired)
+	userInfo, err := strict.Authenticate(ctx, "Bearer "+sign("admin"))
+	require.NoError(t, err)
+	assert.Empty(t, userInfo.Tenant)
+
+	// Par défaut : jetons sans tenant acceptés
+	service := newService(false)
+	userInfo, err = service.Authenticate(ctx, "Bearer "+sign("operator"))
+	require.NoError(t, err)
+	assert.Empty(t, userInfo.Tenant)
+	userInfo, err = service.Authenticate(ctx, "apikey "+apiKey)
+	require.NoError(t, err)
+	assert.Equal(t, "auditor", userInfo.Role)
+}
+
+// TestCanAccessTenant teste qu'un utilisateur rattaché à un tenant n'accède qu'à ce tenant
+func TestCanAccessTenant(t *testing.T) {
+	acme, globex := "acme", "globex"
+	cases := []struct {
+		name     string
+		user     *auth.UserInfo
+		resource *string
+		allowed  bool
+	}{
+		{"auth disabled", nil, &acme, true},
+		{"admin without tenant", &auth.UserInfo{UserID: "u", Role: "admin"}, &acme, true},
+		{"operator without tenant", &auth.UserInfo{UserID: "u", Role: "operator"}, &acme, true},
+		{"same tenant", &auth.UserInfo{UserID: "u", Role: "operator", Tenant: acme}, &acme, true},
+		{"other tenant", &auth.UserInfo{UserID: "u", Role: "admin", Tenant: acme}, &globex, false},
+		{"untenanted resource", &auth.UserInfo{UserID: "u", Role: "operator", Tenant: acme}, nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/test", func(c *fiber.Ctx) error {
+				if tc.user != nil {
+					c.Locals("user", tc.user)
+				}
+				if !auth.CanAccessTenant(c, tc.resource) {
+					return c.SendStatus(fiber.StatusNotFound)
+				}
+				return c.SendStatus(fiber.StatusOK)
+			})
+			resp, err := app.Test(httptest.NewRequest("GET", "/test", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tc.allowed, resp.StatusCode == fiber.StatusOK)
+		})
+	}
+}
@@ -184,6 +184,77 @@ func TestAuthService_AuthenticateJWT(t *testing.T) {
 	assert.Equal(t, "user@example.com", userInfo.Email)
 }
 
+// TestAuthService_AuthenticateJWT_TenantClaim teste l'extraction du tenant (claim configurable)
+func TestAuthService_AuthenticateJWT_TenantClaim(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	sign := func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tokenString, err := token.SignedString(privateKey)
+		require.NoError(t, err)
+		return tokenString
+	}
+	ctx := context.Background()
+
+	// Claim par défaut : "tenant"
+	service := auth.NewAuthService(auth.AuthConfig{
+		JWTPublicKey: &privateKey.PublicKey,
+		JWTEnabled:   true,
+		Logger:       zerolog.Nop(),
+	})
+	userInfo, err := service.Authenticate(ctx, "Bearer "+sign(jwt.MapClaims{
+		"sub":    "user-123",
+		"role":   "operator",
+		"tenant": "acme",
+		"exp":    time.Now().Add(1 * time.Hour).Unix(),
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "acme", userInfo.Tenant)
+
+	// Claim personnalisé
+	service = auth.NewAuthService(auth.AuthConfig{
+		JWTPublicKey: &privateKey.PublicKey,
+		JWTEnabled:   true,
+		TenantClaim:  "org_id",
+		Logger:       zerolog.Nop(),
+	})
+	userInfo, err = service.Authenticate(ctx, "Bearer "+sign(jwt.MapClaims{
+		"sub":    "user-123",
+		"role":   "operator",
+		"tenant": "ignored",
+		"org_id": "globex",
+		"exp":    time.Now().Add(1 * time.Hour).Unix(),
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "globex", userInfo.Tenant)
+}
+
+// TestAuthService_AuthenticateAPIKey_Tenant teste le tenant porté par une clé API
+func TestAuthService_AuthenticateAPIKey_Tenant(t *testing.T) {
+	apiKey := "tenant-api-key-12345"
+	keyHash := hashAPIKey(apiKey)
+	service := auth.NewAuthService(auth.AuthConfig{
+		APIKeys: map[string]*auth.APIKey{
+			keyHash: {
+				KeyID:     "key-tenant",
+				KeyHash:   keyHash,
+				UserID:    "user-456",
+				Role:      "operator",
+				Tenant:    "acme",
+				CreatedAt: time.Now(),
+				IsActive:  true,
+			},
+		},
+		APIKeyEnabled: true,
+		Logger:        zerolog.Nop(),
+	})
+
+	userInfo, err := service.Authenticate(context.Background(), "apikey "+apiKey)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", userInfo.Tenant)
+}
+
 // TestAuthService_AuthenticateJWT_Invalid teste avec JWT invalide
 func TestAuthService_AuthenticateJWT_Invalid(t *testing.T) {
 	cfg := auth.AuthConfig{
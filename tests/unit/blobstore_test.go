@@ -0,0 +1,393 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/blobstore"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSignV4_Vector teste la signature SigV4 sur le cas "get-vanilla" de la suite de tests AWS
+func TestSignV4_Vector(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+
+	creds := blobstore.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	blobstore.SignV4(req, blobstore.EmptyPayloadSHA256, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+// TestFileStore teste le stockage fichier : références fs:, chemins historiques, clés invalides
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store := blobstore.NewFileStore(root)
+
+	key := blobstore.DateKey(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC), uuid.MustParse("11111111-2222-3333-4444-555555555555"), "facture.pdf")
+	assert.Equal(t, "2025/01/15/11111111-2222-3333-4444-555555555555-facture.pdf", key)
+
+	ref, err := store.Put(ctx, key, strings.NewReader("%PDF-1.4"), 8, blobstore.PutOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "fs:"+key, ref)
+	assert.True(t, store.Owns(ref))
+
+	content, err := blobstore.ReadAll(ctx, store, ref)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4", string(content))
+	info, err := store.Stat(ctx, ref)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), info.Size)
+
+	// Chemin absolu d'un document stocké avant les références
+	legacy := filepath.Join(root, "legacy.pdf")
+	require.NoError(t, os.WriteFile(legacy, []byte("ancien"), 0644))
+	assert.True(t, store.Owns(legacy))
+	content, err = blobstore.ReadAll(ctx, store, legacy)
+	require.NoError(t, err)
+	assert.Equal(t, "ancien", string(content))
+
+	// Écriture en cours ignorée par le parcours
+	require.NoError(t, os.WriteFile(filepath.Join(root, "partial.pdf.123.tmp"), []byte("x"), 0644))
+	var refs []string
+	require.NoError(t, store.Walk(ctx, func(o blobstore.ObjectInfo) error {
+		refs = append(refs, o.Ref)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{ref, "fs:legacy.pdf"}, refs)
+
+	// Taille annoncée incorrecte : rien n'est écrit
+	_, err = store.Put(ctx, "2025/01/15/short.pdf", strings.NewReader("abc"), 10, blobstore.PutOptions{})
+	assert.Error(t, err)
+	_, err = store.Stat(ctx, "fs:2025/01/15/short.pdf")
+	assert.ErrorIs(t, err, blobstore.ErrNotFound)
+
+	_, err = store.Put(ctx, "../evil.pdf", strings.NewReader("x"), 1, blobstore.PutOptions{})
+	assert.ErrorContains(t, err, "invalid storage key")
+	assert.False(t, store.Owns("s3://bucket/key"))
+
+	require.NoError(t, store.Delete(ctx, ref))
+	_, err = store.Open(ctx, ref)
+	assert.ErrorIs(t, err, blobstore.ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, ref), "deleting a missing blob is a no-op")
+}
+
+// fakeS3 simule un stockage objet compatible S3 (MinIO) : signature SigV4 vérifiée,
+// intégrité du corps (x-amz-content-sha256, Content-MD5), rétention object-lock
+type fakeS3 struct {
+	t        *testing.T
+	creds    blobstore.Credentials
+	pageSize int
+	mu       sync.Mutex
+	objects  map[string][]byte
+	headers  map[string]http.Header
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	return &fakeS3{
+		t:        t,
+		creds:    blobstore.Credentials{AccessKeyID: "minio", SecretAccessKey: "minio-secret"},
+		pageSize: 2,
+		objects:  map[string][]byte{},
+		headers:  map[string]http.Header{},
+	}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Signature recalculée sur la requête reçue
+	signedAt, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	require.NoError(f.t, err)
+	check := r.Clone(r.Context())
+	blobstore.SignV4(check, r.Header.Get("X-Amz-Content-Sha256"), f.creds, "us-east-1", "s3", signedAt)
+	if check.Header.Get("Authorization") != r.Header.Get("Authorization") {
+		f.s3Error(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "archive" {
+		f.s3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r)
+	case r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		digest := md5.Sum(body)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) ||
+			r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(digest[:]) {
+			f.s3Error(w, http.StatusBadRequest, "BadDigest")
+			return
+		}
+		if until, ok := f.retainUntil(key); ok && time.Now().Before(until) {
+			f.s3Error(w, http.StatusForbidden, "AccessDenied")
+			return
+		}
+		f.objects[key] = body
+		f.headers[key] = r.Header.Clone()
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		body, ok := f.objects[key]
+		if !ok {
+			f.s3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) retainUntil(key string) (time.Time, bool) {
+	h, ok := f.headers[key]
+	if !ok || h.Get("X-Amz-Object-Lock-Mode") != blobstore.RetentionCompliance {
+		return time.Time{}, false
+	}
+	until, err := time.Parse(time.RFC3339, h.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	return until, err == nil
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	start := 0
+	if token := r.URL.Query().Get("continuation-token"); token != "" {
+		start, _ = strconv.Atoi(token)
+	}
+	end := start + f.pageSize
+	if end > len(keys) {
+		end = len(keys)
+	}
+
+	type content struct {
+		Key          string
+		Size         int
+		LastModified string
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+		Contents              []content
+	}{IsTruncated: end < len(keys)}
+	if result.IsTruncated {
+		result.NextContinuationToken = strconv.Itoa(end)
+	}
+	for _, key := range keys[start:end] {
+		result.Contents = append(result.Contents, content{Key: key, Size: len(f.objects[key]), LastModified: "2025-01-15T10:00:00.000Z"})
+	}
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) s3Error(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	if status != http.StatusNotFound {
+		w.Write([]byte("<Error><Code>" + code + "</Code><Message>" + code + "</Message></Error>"))
+	}
+}
+
+// TestS3Store teste le stockage objet contre un S3 simulé : PUT signé avec rétention
+// WORM, lecture, parcours paginé, suppression
+func TestS3Store(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeS3(t)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := blobstore.NewS3Store(blobstore.S3Config{
+		Endpoint:       server.URL,
+		Bucket:         "archive",
+		Prefix:         "vault",
+		Credentials:    fake.creds,
+		PathStyle:      true,
+		ObjectLockMode: blobstore.RetentionCompliance,
+		RetentionDays:  3650,
+	})
+	require.NoError(t, err)
+
+	// Lecteur non relisible : copié en fichier temporaire avant l'envoi
+	key := "2025/01/15/11111111-2222-3333-4444-555555555555-facture n°1.pdf"
+	ref, err := store.Put(ctx, key, io.MultiReader(strings.NewReader("%PDF-"), strings.NewReader("1.4")), 8,
+		blobstore.PutOptions{ContentType: "application/pdf", SHA256Hex: "abc", LegalHold: true})
+	require.NoError(t, err)
+	assert.Equal(t, "s3://archive/vault/"+key, ref)
+	assert.Equal(t, ref, store.Ref(key))
+	assert.True(t, store.Owns(ref))
+	assert.False(t, store.Owns("s3://other/vault/"+key))
+	assert.False(t, store.Owns("fs:"+key))
+
+	stored := fake.headers["vault/"+key]
+	assert.Equal(t, blobstore.RetentionCompliance, stored.Get("X-Amz-Object-Lock-Mode"))
+	until, err := time.Parse(time.RFC3339, stored.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 3650), until, time.Minute)
+	assert.Equal(t, "ON", stored.Get("X-Amz-Object-Lock-Legal-Hold"))
+	assert.Equal(t, "abc", stored.Get("X-Amz-Meta-Sha256"))
+	assert.Equal(t, "application/pdf", stored.Get("Content-Type"))
+
+	content, err := blobstore.ReadAll(ctx, store, ref)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4", string(content))
+	info, err := store.Stat(ctx, ref)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), info.Size)
+	assert.Equal(t, key, info.Key)
+
+	// Objet retenu : réécriture refusée par le bucket
+	_, err = store.Put(ctx, key, bytes.NewReader([]byte("altered!")), 8, blobstore.PutOptions{})
+	assert.ErrorContains(t, err, "AccessDenied")
+
+	// Objet vide, lecteur relisible
+	_, err = store.Put(ctx, "2025/01/16/empty.pdf", bytes.NewReader(nil), 0, blobstore.PutOptions{})
+	require.NoError(t, err)
+	_, err = store.Put(ctx, "2025/01/16/other.pdf", bytes.NewReader([]byte("autre")), 5, blobstore.PutOptions{})
+	require.NoError(t, err)
+
+	var keys []string
+	require.NoError(t, store.Walk(ctx, func(o blobstore.ObjectInfo) error {
+		keys = append(keys, o.Key)
+		return nil
+	}))
+	assert.Equal(t, []string{key, "2025/01/16/empty.pdf", "2025/01/16/other.pdf"}, keys, "listing spans several pages")
+
+	require.NoError(t, store.Delete(ctx, "s3://archive/vault/2025/01/16/other.pdf"))
+	_, err = store.Open(ctx, "s3://archive/vault/2025/01/16/other.pdf")
+	assert.True(t, errors.Is(err, blobstore.ErrNotFound), "%v", err)
+	_, err = store.Stat(ctx, "s3://archive/vault/missing.pdf")
+	assert.ErrorIs(t, err, blobstore.ErrNotFound)
+
+	// Mauvais secret : signature refusée
+	wrong, err := blobstore.NewS3Store(blobstore.S3Config{Endpoint: server.URL, Bucket: "archive", PathStyle: true,
+		Credentials: blobstore.Credentials{AccessKeyID: "minio", SecretAccessKey: "wrong"}})
+	require.NoError(t, err)
+	_, err = wrong.Open(ctx, ref)
+	assert.ErrorContains(t, err, "SignatureDoesNotMatch")
+
+	_, err = blobstore.NewS3Store(blobstore.S3Config{Endpoint: server.URL, Bucket: "archive", ObjectLockMode: "FOREVER"})
+	assert.Error(t, err)
+}
+
+// TestMux teste le routage des références entre backends
+func TestMux(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeS3(t)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	fs := blobstore.NewFileStore(t.TempDir())
+	s3, err := blobstore.NewS3Store(blobstore.S3Config{Endpoint: server.URL, Bucket: "archive", Credentials: fake.creds, PathStyle: true})
+	require.NoError(t, err)
+
+	fsRef, err := fs.Put(ctx, "2024/12/31/ancien.pdf", strings.NewReader("ancien"), 6, blobstore.PutOptions{})
+	require.NoError(t, err)
+
+	// Nouvelles écritures en S3, anciens documents toujours lisibles
+	mux := blobstore.NewMux(s3, fs)
+	assert.Equal(t, "s3", mux.Name())
+	ref, err := mux.Put(ctx, "2025/01/15/nouveau.pdf", strings.NewReader("nouveau"), 7, blobstore.PutOptions{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(ref, "s3://archive/"))
+	assert.Equal(t, ref, mux.Ref("2025/01/15/nouveau.pdf"))
+
+	for expected, r := range map[string]string{"ancien": fsRef, "nouveau": ref} {
+		content, err := blobstore.ReadAll(ctx, mux, r)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+	assert.Same(t, fs, mux.Backend("fs"))
+
+	_, err = mux.Open(ctx, "s3://other-bucket/x.pdf")
+	assert.ErrorContains(t, err, "no configured storage backend")
+}
+
+// TestContentKey teste les clés de la disposition par contenu
+func TestContentKey(t *testing.T) {
+	sha := "ab12cd34ef56ab12cd34ef56ab12cd34ef56ab12cd34ef56ab12cd34ef56ab12"
+	key := blobstore.ContentKey(sha)
+	assert.Equal(t, "ab/12/"+sha, key)
+
+	parsed, ok := blobstore.ContentKeySHA256(key)
+	assert.True(t, ok)
+	assert.Equal(t, sha, parsed)
+	for _, other := range []string{
+		"2025/01/15/11111111-2222-3333-4444-555555555555-facture.pdf",
+		"ab/13/" + sha,
+		"ab/12/" + sha + ".tmp",
+		"ab/12/" + strings.ToUpper(sha),
+	} {
+		_, ok := blobstore.ContentKeySHA256(other)
+		assert.False(t, ok, other)
+	}
+
+	assert.True(t, blobstore.IsContentRef("fs:"+key, sha))
+	assert.True(t, blobstore.IsContentRef("s3://archive/vault/"+key, sha))
+	assert.False(t, blobstore.IsContentRef("fs:x"+key, sha))
+	assert.False(t, blobstore.IsContentRef("/opt/dorevia-vault/storage/2025/01/15/facture.pdf", sha))
+	assert.False(t, blobstore.IsContentRef("fs:"+key, ""))
+
+	// Contenu chiffré : un objet par document, jamais considéré comme partagé
+	id := uuid.MustParse("11111111-2222-3333-4444-555555555555")
+	docKey := blobstore.DocumentContentKey(sha, id)
+	assert.Equal(t, "ab/12/"+sha+"-"+id.String(), docKey)
+	assert.True(t, blobstore.IsDocumentContentRef("fs:"+docKey, sha, id))
+	assert.False(t, blobstore.IsDocumentContentRef("fs:"+docKey, sha, uuid.New()))
+	assert.False(t, blobstore.IsDocumentContentRef("fs:"+key, sha, id))
+	assert.False(t, blobstore.IsContentRef("fs:"+docKey, sha))
+	_, ok = blobstore.ContentKeySHA256(docKey)
+	assert.False(t, ok)
+}
+
+// TestSafeFilename teste la neutralisation des noms de fichier fournis par l'appelant
+func TestSafeFilename(t *testing.T) {
+	assert.Equal(t, "FA-2025-001.pdf", blobstore.SafeFilename("FA-2025-001.pdf"))
+	assert.Equal(t, "_.._etc_passwd", blobstore.SafeFilename("/../etc/passwd"))
+	assert.Equal(t, "_.._etc_passwd", blobstore.SafeFilename("../../etc/passwd"))
+	assert.Equal(t, "document", blobstore.SafeFilename(".."))
+	assert.Equal(t, "document", blobstore.SafeFilename(""))
+	assert.Equal(t, "FA_1_.pdf", blobstore.SafeFilename("FA\\1\".pdf"))
+
+	key := blobstore.DateKey(time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), uuid.MustParse("11111111-2222-3333-4444-555555555555"), "../../etc/passwd")
+	assert.Equal(t, "2025/01/15/11111111-2222-3333-4444-555555555555-_.._etc_passwd", key)
+	assert.Len(t, strings.Split(key, "/"), 4)
+}
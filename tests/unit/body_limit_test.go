@@ -0,0 +1,51 @@
+package unit
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/doreviateam/dorevia-vault/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBodyLimit teste la limite des corps hors routes de réception en flux
+func TestBodyLimit(t *testing.T) {
+	app := fiber.New(fiber.Config{StreamRequestBody: true, BodyLimit: 1024})
+	app.Use(middleware.BodyLimit(1024, "/api/v1/invoices"))
+
+	// Route classique : corps lu en mémoire
+	app.Post("/api/v1/pos-tickets", func(c *fiber.Ctx) error {
+		return c.SendString(string(c.Body()))
+	})
+	// Route de réception en flux : corps lu depuis le flux
+	app.Post("/api/v1/invoices", func(c *fiber.Ctx) error {
+		n, err := io.Copy(io.Discard, c.Context().RequestBodyStream())
+		if err != nil {
+			return err
+		}
+		return c.JSON(fiber.Map{"size": n})
+	})
+
+	small := bytes.Repeat([]byte("a"), 512)
+	large := bytes.Repeat([]byte("a"), 64*1024)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/pos-tickets", bytes.NewReader(small)))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, small, body)
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/api/v1/pos-tickets", bytes.NewReader(large)))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/api/v1/invoices", bytes.NewReader(large)))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, _ = io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"size":65536}`, string(body))
+}
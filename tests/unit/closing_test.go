@@ -0,0 +1,168 @@
+package unit
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/closing"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/merkle"
+	"github.com/doreviateam/dorevia-vault/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMerkle_Root teste le calcul de racine RFC 6962
+func TestMerkle_Root(t *testing.T) {
+	// Arbre vide : SHA256("")
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", hex.EncodeToString(merkle.Root(nil)))
+
+	// Feuille vide : SHA256(0x00)
+	assert.Equal(t, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d", hex.EncodeToString(merkle.Root([][]byte{{}})))
+
+	a, b, c := []byte("a"), []byte("b"), []byte("c")
+	assert.Equal(t, merkle.NodeHash(merkle.LeafHash(a), merkle.LeafHash(b)), merkle.Root([][]byte{a, b}))
+
+	// 3 feuilles : découpage à la plus grande puissance de 2 inférieure (2 + 1)
+	expected := merkle.NodeHash(merkle.NodeHash(merkle.LeafHash(a), merkle.LeafHash(b)), merkle.LeafHash(c))
+	assert.Equal(t, expected, merkle.Root([][]byte{a, b, c}))
+
+	var builder merkle.Builder
+	for _, leaf := range [][]byte{a, b, c} {
+		builder.Add(leaf)
+	}
+	assert.Equal(t, 3, builder.Size())
+	assert.Equal(t, expected, builder.Root())
+
+	// L'ordre des feuilles est significatif
+	assert.NotEqual(t, merkle.Root([][]byte{a, b}), merkle.Root([][]byte{b, a}))
+}
+
+// TestClosingPeriod teste le découpage mensuel
+func TestClosingPeriod(t *testing.T) {
+	p, err := closing.ParsePeriod("2024-02")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-02", p.String())
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), p.Start())
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), p.End())
+	assert.Len(t, p.Days(), 29)
+	assert.Equal(t, "2024-02-29", p.Days()[28])
+
+	previous, err := closing.ParsePeriod("2025-01")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-12", previous.Previous().String())
+	assert.Equal(t, "2025-01", closing.PeriodOf(time.Date(2025, 1, 31, 23, 59, 59, 0, time.UTC)).String())
+
+	_, err = closing.ParsePeriod("2024-13")
+	assert.Error(t, err)
+	_, err = closing.ParsePeriod("février")
+	assert.Error(t, err)
+}
+
+// TestPosTicketDate teste la lecture de la date métier d'un ticket POS
+func TestPosTicketDate(t *testing.T) {
+	date := services.PosTicketDate(map[string]interface{}{"date_order": "2025-03-31 23:15:00"})
+	require.NotNil(t, date)
+	assert.Equal(t, "2025-03", closing.PeriodOf(*date).String())
+
+	date = services.PosTicketDate(map[string]interface{}{"date_order": "2025-04-01T01:00:00+02:00"})
+	require.NotNil(t, date)
+	assert.Equal(t, "2025-03", closing.PeriodOf(*date).String(), "periods are UTC months")
+
+	assert.Nil(t, services.PosTicketDate(map[string]interface{}{"lines": []interface{}{}}))
+	assert.Nil(t, services.PosTicketDate(map[string]interface{}{"date_order": "hier"}))
+}
+
+// writeTestArchive écrit une archive de clôture d'un document et de son entrée de ledger
+func writeTestArchive(t *testing.T, service *crypto.Service, tamperRoot bool) string {
+	path := filepath.Join(t.TempDir(), "closing-2025-03.zip")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	period, err := closing.ParsePeriod("2025-03")
+	require.NoError(t, err)
+
+	content := []byte("%PDF-1.4 facture de mars")
+	shaHex := sha256HexOf(content)
+	docID := uuid.New().String()
+	contentFile := "documents/" + docID + "-facture.pdf"
+	createdAt := time.Date(2025, 3, 12, 10, 0, 0, 0, time.UTC)
+
+	archive := closing.NewArchiveWriter(file)
+	require.NoError(t, archive.AddFile(contentFile, content))
+	require.NoError(t, archive.AddJSON(closing.FileDocuments, []closing.ArchivedDocument{{
+		ID: docID, Filename: "facture.pdf", SizeBytes: int64(len(content)), SHA256Hex: shaHex,
+		CreatedAt: createdAt, ContentFile: contentFile,
+	}}))
+
+	anchor := ledger.ComputeHash(nil, sha256HexOf([]byte("février")))
+	entryHash := ledger.ComputeHash(&anchor, shaHex)
+	require.NoError(t, archive.AddJSON(closing.FileLedger, []closing.LedgerEntry{{
+		ChainEntry: ledger.ChainEntry{ID: 7, DocumentID: docID, Hash: entryHash, PreviousHash: &anchor, DocumentSHA: shaHex, Timestamp: createdAt},
+	}}))
+
+	leaf, _ := hex.DecodeString(shaHex)
+	root := merkle.Root([][]byte{leaf})
+	if tamperRoot {
+		root = merkle.Root([][]byte{leaf, leaf})
+	}
+	_, err = archive.Seal(service, &closing.Manifest{
+		Period:           period.String(),
+		PeriodStart:      period.Start(),
+		PeriodEnd:        period.End(),
+		DocumentCount:    1,
+		LedgerEntryCount: 1,
+		ChainAnchors:     map[string]*string{"": &anchor},
+		LastLedgerHash:   &entryHash,
+		MerkleRoot:       hex.EncodeToString(root),
+		AuditDays:        []string{},
+	})
+	require.NoError(t, err)
+	return path
+}
+
+// TestVerifyClosingArchive teste la vérification hors ligne d'une archive de clôture
+func TestVerifyClosingArchive(t *testing.T) {
+	service, _ := newTestRotatingService(t)
+
+	t.Run("valid", func(t *testing.T) {
+		report, err := closing.VerifyArchive(writeTestArchive(t, service, false), nil)
+		require.NoError(t, err)
+		assert.True(t, report.Valid, report.Errors)
+		assert.Equal(t, "2025-03", report.Period)
+		assert.Equal(t, closing.KeysFromArchive, report.KeysSource)
+
+		statuses := make(map[string]string)
+		for _, check := range report.Checks {
+			statuses[check.Component] = check.Status
+		}
+		for _, component := range []string{"signature", "files", "documents", "ledger"} {
+			assert.Equal(t, "ok", statuses[component], component)
+		}
+	})
+
+	t.Run("merkle root mismatch", func(t *testing.T) {
+		report, err := closing.VerifyArchive(writeTestArchive(t, service, true), nil)
+		require.NoError(t, err)
+		assert.False(t, report.Valid)
+	})
+
+	t.Run("untrusted signing key", func(t *testing.T) {
+		other, _ := newTestRotatingService(t)
+		jwks, err := other.CurrentJWKS()
+		require.NoError(t, err)
+		trusted, err := crypto.ParseJWKS(jwks)
+		require.NoError(t, err)
+
+		report, err := closing.VerifyArchive(writeTestArchive(t, service, false), trusted)
+		require.NoError(t, err)
+		assert.False(t, report.Valid)
+		assert.Equal(t, closing.KeysFromTrusted, report.KeysSource)
+	})
+}
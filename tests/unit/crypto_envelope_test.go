@@ -0,0 +1,145 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/doreviateam/dorevia-vault/internal/config"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestEnvelope crée un chiffrement par enveloppe sur un répertoire de KEK temporaire
+func newTestEnvelope(t *testing.T, kids ...string) (*crypto.DirKeyManager, *crypto.Envelope) {
+	t.Helper()
+	ctx := context.Background()
+	keys := crypto.NewDirKeyManager(t.TempDir(), zerolog.Nop())
+	for _, kid := range kids {
+		require.NoError(t, keys.GenerateKeyPair(ctx, kid))
+	}
+	envelope, err := crypto.NewEnvelope(keys, kids[len(kids)-1])
+	require.NoError(t, err)
+	return keys, envelope
+}
+
+// TestEnvelope_ContentRoundTrip teste le chiffrement du contenu aux limites de segment
+func TestEnvelope_ContentRoundTrip(t *testing.T) {
+	_, envelope := newTestEnvelope(t, "kek-1")
+	dek, _, _, err := envelope.NewDataKey(context.Background())
+	require.NoError(t, err)
+
+	for _, size := range []int{0, 1, 64*1024 - 1, 64 * 1024, 64*1024 + 1, 3 * 64 * 1024, 200_000} {
+		plaintext := make([]byte, size)
+		_, err := rand.Read(plaintext)
+		require.NoError(t, err)
+
+		ciphertext, err := crypto.EncryptContent(dek, plaintext)
+		require.NoError(t, err)
+		assert.Equal(t, crypto.EncryptedSize(int64(size)), int64(len(ciphertext)), "size %d", size)
+		if size >= 16 {
+			// En deçà, le clair peut apparaître par hasard dans le chiffré
+			assert.False(t, bytes.Contains(ciphertext, plaintext), "size %d", size)
+		}
+
+		decrypted, err := crypto.DecryptContent(dek, ciphertext)
+		require.NoError(t, err, "size %d", size)
+		assert.Equal(t, plaintext, decrypted, "size %d", size)
+
+		// Déchiffrement au fil de la lecture, par petits morceaux
+		r, err := crypto.NewDecryptReader(dek, bytes.NewReader(ciphertext))
+		require.NoError(t, err)
+		streamed, err := io.ReadAll(io.LimitReader(r, int64(size)+1))
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, streamed, "size %d", size)
+	}
+}
+
+// TestEnvelope_TamperDetection teste le refus d'un contenu altéré, tronqué ou d'une autre clé
+func TestEnvelope_TamperDetection(t *testing.T) {
+	_, envelope := newTestEnvelope(t, "kek-1")
+	ctx := context.Background()
+	dek, _, _, err := envelope.NewDataKey(ctx)
+	require.NoError(t, err)
+
+	plaintext := bytes.Repeat([]byte("facture "), 20_000) // 160 000 octets : 3 segments
+	ciphertext, err := crypto.EncryptContent(dek, plaintext)
+	require.NoError(t, err)
+
+	altered := append([]byte(nil), ciphertext...)
+	altered[len(altered)/2] ^= 0x01
+	_, err = crypto.DecryptContent(dek, altered)
+	assert.ErrorIs(t, err, crypto.ErrContentDecryption)
+
+	// Troncature à une limite de segment : le dernier segment manque
+	segment := 64*1024 + 16
+	_, err = crypto.DecryptContent(dek, ciphertext[:11+segment])
+	assert.ErrorIs(t, err, crypto.ErrContentDecryption)
+
+	_, err = crypto.DecryptContent(dek, ciphertext[:5])
+	assert.ErrorIs(t, err, crypto.ErrContentDecryption)
+
+	otherDEK, _, _, err := envelope.NewDataKey(ctx)
+	require.NoError(t, err)
+	_, err = crypto.DecryptContent(otherDEK, ciphertext)
+	assert.ErrorIs(t, err, crypto.ErrContentDecryption)
+}
+
+// TestEnvelope_RewrapDataKey teste la rotation de KEK : la clé de données est enveloppée
+// par la nouvelle KEK sans changer, le contenu reste lisible
+func TestEnvelope_RewrapDataKey(t *testing.T) {
+	keys, oldEnvelope := newTestEnvelope(t, "kek-1")
+	ctx := context.Background()
+
+	dek, wrapped, kekVersion, err := oldEnvelope.NewDataKey(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "kek-1", kekVersion)
+	ciphertext, err := crypto.EncryptContent(dek, []byte("%PDF-1.4 facture"))
+	require.NoError(t, err)
+
+	require.NoError(t, keys.GenerateKeyPair(ctx, "kek-2"))
+	envelope, err := crypto.NewEnvelope(keys, "kek-2")
+	require.NoError(t, err)
+
+	rewrapped, newVersion, err := envelope.RewrapDataKey(ctx, wrapped, kekVersion)
+	require.NoError(t, err)
+	assert.Equal(t, "kek-2", newVersion)
+	assert.NotEqual(t, wrapped, rewrapped)
+
+	unwrapped, err := envelope.UnwrapDataKey(ctx, rewrapped, newVersion)
+	require.NoError(t, err)
+	assert.Equal(t, dek, unwrapped)
+	content, err := crypto.DecryptContent(unwrapped, ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4 facture", string(content))
+
+	// Clé enveloppée par une autre KEK que celle indiquée
+	_, err = envelope.UnwrapDataKey(ctx, rewrapped, "kek-1")
+	assert.Error(t, err)
+}
+
+// TestEnvelopeFromConfig teste la configuration du chiffrement (DOCUMENT_ENCRYPTION_*)
+func TestEnvelopeFromConfig(t *testing.T) {
+	envelope, err := crypto.EnvelopeFromConfig(config.Config{}, zerolog.Nop())
+	require.NoError(t, err)
+	assert.Nil(t, envelope, "disabled by default")
+
+	_, err = crypto.EnvelopeFromConfig(config.Config{DocumentEncryptionEnabled: true}, zerolog.Nop())
+	assert.Error(t, err, "keys dir or Vault required")
+
+	dir := t.TempDir()
+	keys := crypto.NewDirKeyManager(dir, zerolog.Nop())
+	require.NoError(t, keys.GenerateKeyPair(context.Background(), "kek-2026"))
+	require.NoError(t, keys.SaveCurrentKID(context.Background(), "kek-2026"))
+
+	envelope, err = crypto.EnvelopeFromConfig(config.Config{DocumentEncryptionEnabled: true, DocumentEncryptionKeysDir: dir}, zerolog.Nop())
+	require.NoError(t, err)
+	assert.Equal(t, "kek-2026", envelope.KEKVersion(), "current KID by default")
+
+	_, err = crypto.EnvelopeFromConfig(config.Config{DocumentEncryptionEnabled: true, DocumentEncryptionKeysDir: dir, DocumentEncryptionKEKID: "missing"}, zerolog.Nop())
+	assert.Error(t, err)
+}
@@ -0,0 +1,297 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/handlers"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRotatingService crée un service JWS multi-KID sur un répertoire temporaire
+func newTestRotatingService(t *testing.T) (*crypto.Service, *crypto.DirKeyManager) {
+	ctx := context.Background()
+	keyManager := crypto.NewDirKeyManager(t.TempDir(), zerolog.Nop())
+	require.NoError(t, keyManager.GenerateKeyPair(ctx, "key-1"))
+
+	rotation, err := crypto.NewKeyRotation(crypto.RotationConfig{
+		KeyManager: keyManager,
+		CurrentKID: "key-1",
+		Logger:     zerolog.Nop(),
+	})
+	require.NoError(t, err)
+
+	service, err := crypto.NewServiceWithRotation(rotation)
+	require.NoError(t, err)
+	return service, keyManager
+}
+
+func jwsKID(t *testing.T, jws string) string {
+	token, _, err := jwt.NewParser().ParseUnverified(jws, jwt.MapClaims{})
+	require.NoError(t, err)
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
+
+func jwksKIDs(t *testing.T, jwks []byte) []string {
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+		} `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(jwks, &set))
+	kids := make([]string, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		kids = append(kids, k.Kid)
+	}
+	return kids
+}
+
+// TestDirKeyManager teste génération, listing et persistance du KID courant
+func TestDirKeyManager(t *testing.T) {
+	ctx := context.Background()
+	keyManager := crypto.NewDirKeyManager(t.TempDir(), zerolog.Nop())
+
+	require.NoError(t, keyManager.GenerateKeyPair(ctx, "key-b"))
+	require.NoError(t, keyManager.GenerateKeyPair(ctx, "key-a"))
+	assert.Error(t, keyManager.GenerateKeyPair(ctx, "key-a"), "existing key must not be overwritten")
+	assert.Error(t, keyManager.GenerateKeyPair(ctx, "../escape"))
+
+	kids, err := keyManager.ListKIDs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key-a", "key-b"}, kids)
+
+	privateKey, err := keyManager.GetPrivateKey(ctx, "key-a")
+	require.NoError(t, err)
+	publicKey, err := keyManager.GetPublicKey(ctx, "key-a")
+	require.NoError(t, err)
+	assert.Equal(t, privateKey.PublicKey.N, publicKey.N)
+
+	current, err := keyManager.LoadCurrentKID(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, current)
+	require.NoError(t, keyManager.SaveCurrentKID(ctx, "key-b"))
+	current, err = keyManager.LoadCurrentKID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "key-b", current)
+}
+
+// TestService_RotationKeepsOldEvidenceVerifiable teste la vérification par kid après rotation
+func TestService_RotationKeepsOldEvidenceVerifiable(t *testing.T) {
+	ctx := context.Background()
+	service, keyManager := newTestRotatingService(t)
+	now := time.Now()
+
+	before, err := service.SignEvidence("doc-1", "abc123", now)
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", jwsKID(t, before))
+
+	require.NoError(t, keyManager.GenerateKeyPair(ctx, "key-2"))
+	require.NoError(t, service.Rotation().Rotate("key-2"))
+	assert.Equal(t, "key-2", service.GetKID())
+
+	// Rotation persistée pour le prochain démarrage
+	current, err := keyManager.LoadCurrentKID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "key-2", current)
+
+	after, err := service.SignEvidence("doc-2", "def456", now)
+	require.NoError(t, err)
+	assert.Equal(t, "key-2", jwsKID(t, after))
+
+	// Les deux preuves restent vérifiables
+	evidence, err := service.VerifyEvidence(before)
+	require.NoError(t, err)
+	assert.Equal(t, "doc-1", evidence.DocumentID)
+	evidence, err = service.VerifyEvidence(after)
+	require.NoError(t, err)
+	assert.Equal(t, "doc-2", evidence.DocumentID)
+
+	// Le JWKS publie la clé courante en tête, puis la clé retirée
+	jwks, err := service.CurrentJWKS()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key-2", "key-1"}, jwksKIDs(t, jwks))
+
+	// Un kid inconnu est refusé
+	other, _ := newTestRotatingService(t)
+	foreign, err := other.SignEvidence("doc-3", "aaa", now)
+	require.NoError(t, err)
+	_, err = service.VerifyEvidence(strings.Replace(foreign, foreign[:strings.Index(foreign, ".")], jwtHeaderWithKID(t, "unknown"), 1))
+	assert.Error(t, err)
+
+	// Rotation vers la clé courante ou une clé absente refusée
+	assert.Error(t, service.Rotation().Rotate("key-2"))
+	assert.Error(t, service.Rotation().Rotate("missing"))
+}
+
+// TestService_KidlessEvidenceUsesLegacyKey teste qu'un JWS sans kid (antérieur à la
+// rotation multi-KID) reste vérifiable avec la clé d'origine après une rotation
+func TestService_KidlessEvidenceUsesLegacyKey(t *testing.T) {
+	ctx := context.Background()
+	service, keyManager := newTestRotatingService(t)
+
+	legacyKey, err := keyManager.GetPrivateKey(ctx, "key-1")
+	require.NoError(t, err)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"document_id": "doc-legacy",
+		"sha256":      "abc123",
+		"timestamp":   time.Now().UTC().Format(time.RFC3339),
+	})
+	kidless, err := token.SignedString(legacyKey)
+	require.NoError(t, err)
+	assert.Empty(t, jwsKID(t, kidless))
+
+	require.NoError(t, keyManager.GenerateKeyPair(ctx, "key-2"))
+	require.NoError(t, service.Rotation().Rotate("key-2"))
+
+	evidence, err := service.VerifyEvidence(kidless)
+	require.NoError(t, err)
+	assert.Equal(t, "doc-legacy", evidence.DocumentID)
+
+	// Un JWS sans kid signé par la clé courante n'est pas une preuve d'origine
+	currentKey, err := keyManager.GetPrivateKey(ctx, "key-2")
+	require.NoError(t, err)
+	forged, err := token.SignedString(currentKey)
+	require.NoError(t, err)
+	_, err = service.VerifyEvidence(forged)
+	assert.Error(t, err)
+}
+
+// TestDirKeyManager_CreatedAtPersisted teste que la date de création d'une clé ne dépend
+// ni du redémarrage ni de la date de modification des fichiers
+func TestDirKeyManager_CreatedAtPersisted(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	keyManager := crypto.NewDirKeyManager(dir, zerolog.Nop())
+	require.NoError(t, keyManager.GenerateKeyPair(ctx, "key-1"))
+
+	createdAt, err := keyManager.GetKeyCreatedAt(ctx, "key-1")
+	require.NoError(t, err)
+
+	// Fichiers copiés ou restaurés : la date enregistrée est conservée
+	touched := time.Now().Add(48 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "key-1", "public.pem"), touched, touched))
+	reloaded, err := crypto.NewDirKeyManager(dir, zerolog.Nop()).GetKeyCreatedAt(ctx, "key-1")
+	require.NoError(t, err)
+	assert.True(t, createdAt.Equal(reloaded))
+
+	// Clé sans date enregistrée (cmd/keygen) : la date est fixée au premier chargement
+	require.NoError(t, os.Remove(filepath.Join(dir, "key-1", "created_at")))
+	first, err := keyManager.GetKeyCreatedAt(ctx, "key-1")
+	require.NoError(t, err)
+	assert.True(t, touched.Equal(first))
+	assert.FileExists(t, filepath.Join(dir, "key-1", "created_at"))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "key-1", "public.pem"), time.Now(), time.Now()))
+	second, err := keyManager.GetKeyCreatedAt(ctx, "key-1")
+	require.NoError(t, err)
+	assert.True(t, first.Equal(second))
+
+	rotation, err := crypto.NewKeyRotation(crypto.RotationConfig{
+		KeyManager: keyManager,
+		CurrentKID: "key-1",
+		Logger:     zerolog.Nop(),
+	})
+	require.NoError(t, err)
+	assert.True(t, rotation.GetNextRotationDate().Equal(first.Add(90*24*time.Hour)))
+}
+
+// TestKeyRotation_RequiresKeyMetadata teste le refus d'un KeyManager sans date de création
+func TestKeyRotation_RequiresKeyMetadata(t *testing.T) {
+	ctx := context.Background()
+	keyManager := crypto.NewDirKeyManager(t.TempDir(), zerolog.Nop())
+	require.NoError(t, keyManager.GenerateKeyPair(ctx, "key-1"))
+
+	_, err := crypto.NewKeyRotation(crypto.RotationConfig{
+		KeyManager: struct{ crypto.KeyManager }{keyManager},
+		CurrentKID: "key-1",
+		Logger:     zerolog.Nop(),
+	})
+	assert.Error(t, err)
+}
+
+func jwtHeaderWithKID(t *testing.T, kid string) string {
+	token := jwt.New(jwt.SigningMethodRS256)
+	token.Header["kid"] = kid
+	signingString, err := token.SigningString()
+	require.NoError(t, err)
+	return signingString[:strings.Index(signingString, ".")]
+}
+
+// TestKeysRotateHandler_Guards teste qu'un administrateur restreint à un tenant ne peut
+// pas changer la clé de signature commune à tous les tenants
+func TestKeysRotateHandler_Guards(t *testing.T) {
+	log := zerolog.Nop()
+	service, _ := newTestRotatingService(t)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &auth.UserInfo{UserID: "admin-acme", Role: string(auth.RoleAdmin), Tenant: "acme"})
+		return c.Next()
+	})
+	app.Post("/api/v1/admin/keys/rotate", handlers.KeysRotateHandler(service, nil, &log, nil))
+	resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/admin/keys/rotate", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "key-1", service.GetKID())
+}
+
+// TestKeysRotateHandler teste l'endpoint d'administration de rotation
+func TestKeysRotateHandler(t *testing.T) {
+	log := zerolog.Nop()
+
+	// Sans rotation configurée
+	app := fiber.New()
+	app.Post("/api/v1/admin/keys/rotate", handlers.KeysRotateHandler(nil, nil, &log, nil))
+	resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/admin/keys/rotate", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	service, _ := newTestRotatingService(t)
+	before, err := service.SignEvidence("doc-1", "abc123", time.Now())
+	require.NoError(t, err)
+
+	app = fiber.New()
+	app.Post("/api/v1/admin/keys/rotate", handlers.KeysRotateHandler(service, nil, &log, nil))
+
+	// Sans kid : génération d'une nouvelle clé
+	resp, err = app.Test(httptest.NewRequest("POST", "/api/v1/admin/keys/rotate", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &result))
+	assert.Equal(t, "key-1", result["previous_kid"])
+	assert.Equal(t, true, result["generated"])
+	assert.Equal(t, result["current_kid"], service.GetKID())
+
+	_, err = service.VerifyEvidence(before)
+	assert.NoError(t, err)
+
+	// kid invalide
+	req := httptest.NewRequest("POST", "/api/v1/admin/keys/rotate", strings.NewReader(`{"kid":"../etc"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	// Retour à une clé existante
+	req = httptest.NewRequest("POST", "/api/v1/admin/keys/rotate", strings.NewReader(`{"kid":"key-1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "key-1", service.GetKID())
+}
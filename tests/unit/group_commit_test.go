@@ -0,0 +1,27 @@
+package unit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/doreviateam/dorevia-vault/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGroupCommitRepository_Closed teste qu'un document soumis après l'arrêt du
+// group commit est refusé sans attendre
+func TestGroupCommitRepository_Closed(t *testing.T) {
+	repo := storage.NewGroupCommitRepository(storage.NewPostgresRepository(nil, logger.New("error")), storage.GroupCommitOptions{
+		Window:   time.Millisecond,
+		MaxBatch: 10,
+	})
+	repo.Close()
+	repo.Close() // idempotent
+
+	err := repo.InsertDocumentWithEvidence(context.Background(), &models.Document{ID: uuid.New()}, "", nil)
+	assert.ErrorIs(t, err, storage.ErrGroupCommitClosed)
+}
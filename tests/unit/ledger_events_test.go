@@ -0,0 +1,159 @@
+package unit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appendEvent ajoute un événement au format version à la suite de entries
+func appendEvent(t *testing.T, entries []ledger.ChainEntry, version int, entryType, documentID string, body interface{}) []ledger.ChainEntry {
+	t.Helper()
+	var prev *string
+	var seq int64 = 1
+	ts := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		h := last.Hash
+		prev = &h
+		if last.Seq != nil {
+			seq = *last.Seq + 1
+		} else {
+			seq = int64(len(entries) + 1)
+		}
+		ts = last.Timestamp.Add(time.Second)
+	}
+	canonical, err := ledger.CanonicalBody(body)
+	require.NoError(t, err)
+
+	e := ledger.ChainEntry{
+		ID:           int64(len(entries) + 1),
+		DocumentID:   documentID,
+		PreviousHash: prev,
+		Timestamp:    ts,
+		Seq:          &seq,
+		HashVersion:  version,
+		EntryType:    entryType,
+		Body:         canonical,
+	}
+	e.Hash, err = ledger.EntryHash(e)
+	require.NoError(t, err)
+	return append(entries, e)
+}
+
+// TestCanonicalBody teste la sérialisation canonique du corps des événements
+func TestCanonicalBody(t *testing.T) {
+	body, err := ledger.CanonicalBody(map[string]interface{}{"b": 2, "a": "x<y", "c": map[string]int{"z": 1, "y": 2}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"x<y","b":2,"c":{"y":2,"z":1}}`, body)
+
+	// Champs d'une structure triés comme les clés d'une map
+	from := "PENDING"
+	body, err = ledger.CanonicalBody(ledger.StatusChangedBody{Field: "dispatch_status", From: &from, To: "SENT"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"field":"dispatch_status","from":"PENDING","to":"SENT"}`, body)
+
+	// Nombres conservés sans passage par float64
+	body, err = ledger.CanonicalBody(map[string]int64{"n": 9007199254740993})
+	require.NoError(t, err)
+	assert.Equal(t, `{"n":9007199254740993}`, body)
+
+	body, err = ledger.CanonicalBody(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "{}", body)
+}
+
+// TestEventDigest teste l'encodage canonique du condensat d'un événement
+func TestEventDigest(t *testing.T) {
+	expected := sha256.Sum256([]byte("dorevia-ledger-event\nkey.rotated\n\n{\"new_kid\":\"k2\"}"))
+	assert.Equal(t, hex.EncodeToString(expected[:]), ledger.EventDigest(ledger.EntryTypeKeyRotated, "", `{"new_kid":"k2"}`))
+}
+
+// TestVerifyChainEntries_Events teste une chaîne mêlant documents et événements, en v1 puis en v2
+func TestVerifyChainEntries_Events(t *testing.T) {
+	docID := uuid.New().String()
+	entries := buildChain(2, nil)
+	entries = appendEvent(t, entries, ledger.HashVersionV1, ledger.EntryTypeDocumentStatusChanged, entries[0].DocumentID,
+		ledger.StatusChangedBody{Field: "dispatch_status", To: "SENT"})
+	entries = appendV2(entries, 2, 4)
+	entries = appendEvent(t, entries, ledger.HashVersionV2, ledger.EntryTypeKeyRotated, "",
+		ledger.KeyRotatedBody{NewKID: "key-2025-03", PreviousKID: "key-2025-01"})
+	entries = appendEvent(t, entries, ledger.HashVersionV2, ledger.EntryTypeDocumentPurged, docID,
+		ledger.DocumentPurgedBody{SHA256Hex: entries[3].DocumentSHA, Reason: "retention"})
+
+	report := ledger.VerifyChainEntries(entries, nil)
+	assert.True(t, report.Valid)
+	assert.Equal(t, 7, report.EntriesChecked)
+
+	tamper := map[string]func(e *ledger.ChainEntry){
+		"body":        func(e *ledger.ChainEntry) { e.Body = `{"new_kid":"forged"}` },
+		"entry_type":  func(e *ledger.ChainEntry) { e.EntryType = ledger.EntryTypePeriodClosed },
+		"document_id": func(e *ledger.ChainEntry) { e.DocumentID = docID },
+		"timestamp":   func(e *ledger.ChainEntry) { e.Timestamp = e.Timestamp.Add(time.Minute) },
+	}
+	for field, fn := range tamper {
+		t.Run(field, func(t *testing.T) {
+			forged := append([]ledger.ChainEntry(nil), entries...)
+			fn(&forged[5])
+
+			report := ledger.VerifyChainEntries(forged, nil)
+			assert.False(t, report.Valid)
+			require.NotNil(t, report.FirstBrokenLink)
+			assert.Equal(t, forged[5].ID, report.FirstBrokenLink.EntryID)
+		})
+	}
+
+	// Type inconnu : hash non recalculable
+	unknown := append([]ledger.ChainEntry(nil), entries...)
+	unknown[2].EntryType = "document.unknown"
+	assert.False(t, ledger.VerifyChainEntries(unknown, nil).Valid)
+}
+
+// TestEntryHash_AnonymizedEvent teste le recalcul du hash d'un événement voisin
+// dans une preuve, dont le corps est remplacé par son condensat
+func TestEntryHash_AnonymizedEvent(t *testing.T) {
+	docID := uuid.New().String()
+	for _, version := range []int{ledger.HashVersionV1, ledger.HashVersionV2} {
+		entries := appendEvent(t, nil, version, ledger.EntryTypeDocumentStatusChanged, docID,
+			ledger.StatusChangedBody{Field: "odoo_state", To: "paid"})
+		e := entries[0]
+		e.ContentDigest = ledger.EventDigest(e.EntryType, e.DocumentID, e.Body)
+		e.DocumentID = ""
+		e.Body = ""
+
+		hash, err := ledger.EntryHash(e)
+		require.NoError(t, err)
+		assert.Equal(t, entries[0].Hash, hash, "v%d", version)
+	}
+}
+
+// TestValidDispatchTransition teste les transitions autorisées de dispatch_status
+func TestValidDispatchTransition(t *testing.T) {
+	allowed := [][2]string{
+		{storage.DispatchStatusPending, storage.DispatchStatusSent},
+		{storage.DispatchStatusSent, storage.DispatchStatusAck},
+		{storage.DispatchStatusSent, storage.DispatchStatusRejected},
+		{storage.DispatchStatusRejected, storage.DispatchStatusSent},
+	}
+	for _, tr := range allowed {
+		assert.True(t, storage.ValidDispatchTransition(tr[0], tr[1]), "%s -> %s", tr[0], tr[1])
+	}
+
+	refused := [][2]string{
+		{storage.DispatchStatusPending, storage.DispatchStatusAck},
+		{storage.DispatchStatusAck, storage.DispatchStatusRejected},
+		{storage.DispatchStatusAck, storage.DispatchStatusSent},
+		{storage.DispatchStatusSent, storage.DispatchStatusPending},
+		{storage.DispatchStatusPending, "UNKNOWN"},
+	}
+	for _, tr := range refused {
+		assert.False(t, storage.ValidDispatchTransition(tr[0], tr[1]), "%s -> %s", tr[0], tr[1])
+	}
+}
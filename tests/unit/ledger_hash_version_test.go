@@ -0,0 +1,156 @@
+package unit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appendV2 ajoute n entrées v2 à la suite de entries (numérotées à partir de firstSeq)
+func appendV2(entries []ledger.ChainEntry, n int, firstSeq int64) []ledger.ChainEntry {
+	var prev *string
+	if len(entries) > 0 {
+		h := entries[len(entries)-1].Hash
+		prev = &h
+	}
+	base := time.Date(2025, 2, 1, 8, 0, 0, 123456000, time.UTC)
+	for i := 0; i < n; i++ {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("document-v2-%d", i)))
+		shaHex := hex.EncodeToString(sum[:])
+		docID := uuid.New().String()
+		jws := fmt.Sprintf("header.payload-%d.signature", i)
+		seq := firstSeq + int64(i)
+		ts := base.Add(time.Duration(i) * time.Second)
+
+		hash := ledger.ComputeHashV2(prev, seq, ts, ledger.ContentDigestV2(docID, shaHex, &jws))
+		entries = append(entries, ledger.ChainEntry{
+			ID:           int64(len(entries) + 1),
+			DocumentID:   docID,
+			Hash:         hash,
+			PreviousHash: prev,
+			DocumentSHA:  shaHex,
+			Timestamp:    ts,
+			Seq:          &seq,
+			HashVersion:  ledger.HashVersionV2,
+			EvidenceJWS:  &jws,
+		})
+		h := hash
+		prev = &h
+	}
+	return entries
+}
+
+// TestComputeHashV2_CanonicalEncoding teste l'encodage canonique du format v2
+func TestComputeHashV2_CanonicalEncoding(t *testing.T) {
+	jws := "a.b.c"
+	jwsHash := sha256.Sum256([]byte(jws))
+	content := sha256.Sum256([]byte("dorevia-ledger-content/v2\ndoc-1\nabc123\n" + hex.EncodeToString(jwsHash[:])))
+	contentHex := hex.EncodeToString(content[:])
+	assert.Equal(t, contentHex, ledger.ContentDigestV2("doc-1", "abc123", &jws))
+
+	prev := "prev123"
+	ts := time.Date(2025, 3, 4, 5, 6, 7, 890000000, time.FixedZone("CET", 3600))
+	expected := sha256.Sum256([]byte("dorevia-ledger/v2\n42\n2025-03-04T04:06:07.890000Z\n" + contentHex + "\nprev123"))
+	assert.Equal(t, hex.EncodeToString(expected[:]), ledger.ComputeHashV2(&prev, 42, ts, contentHex))
+
+	// Première entrée : previous_hash vide
+	first := sha256.Sum256([]byte("dorevia-ledger/v2\n1\n2025-03-04T04:06:07.890000Z\n" + contentHex + "\n"))
+	assert.Equal(t, hex.EncodeToString(first[:]), ledger.ComputeHashV2(nil, 1, ts, contentHex))
+}
+
+// TestVerifyChainEntries_MixedVersions teste une chaîne v1 prolongée par des entrées v2
+func TestVerifyChainEntries_MixedVersions(t *testing.T) {
+	entries := appendV2(buildChain(3, nil), 3, 1)
+
+	report := ledger.VerifyChainEntries(entries, nil)
+	assert.True(t, report.Valid)
+	assert.Equal(t, 6, report.EntriesChecked)
+	assert.Equal(t, entries[5].Hash, report.HeadHash)
+}
+
+// TestVerifyChainEntries_V2Tampering teste que le hash v2 couvre l'horodatage,
+// le JWS, le document et le numéro de séquence
+func TestVerifyChainEntries_V2Tampering(t *testing.T) {
+	tamper := map[string]func(e *ledger.ChainEntry){
+		"timestamp": func(e *ledger.ChainEntry) { e.Timestamp = e.Timestamp.Add(-time.Hour) },
+		"jws": func(e *ledger.ChainEntry) {
+			jws := "forged.jws.token"
+			e.EvidenceJWS = &jws
+		},
+		"document_id": func(e *ledger.ChainEntry) { e.DocumentID = uuid.New().String() },
+		"seq": func(e *ledger.ChainEntry) {
+			seq := *e.Seq + 10
+			e.Seq = &seq
+		},
+	}
+	for field, fn := range tamper {
+		t.Run(field, func(t *testing.T) {
+			entries := appendV2(nil, 3, 1)
+			fn(&entries[1])
+
+			report := ledger.VerifyChainEntries(entries, nil)
+			assert.False(t, report.Valid)
+			require.NotNil(t, report.FirstBrokenLink)
+			assert.Equal(t, entries[1].ID, report.FirstBrokenLink.EntryID)
+		})
+	}
+
+	// En v1, les mêmes modifications ne sont pas détectées
+	entries := buildChain(3, nil)
+	entries[1].Timestamp = entries[1].Timestamp.Add(-time.Hour)
+	assert.True(t, ledger.VerifyChainEntries(entries, nil).Valid)
+}
+
+// TestVerifyChainEntries_VersionDowngrade teste le refus d'une entrée v1 après une entrée v2
+func TestVerifyChainEntries_VersionDowngrade(t *testing.T) {
+	entries := appendV2(nil, 2, 1)
+	prev := entries[1].Hash
+	sum := sha256.Sum256([]byte("downgraded"))
+	shaHex := hex.EncodeToString(sum[:])
+	seq := int64(3)
+	entries = append(entries, ledger.ChainEntry{
+		ID:           3,
+		DocumentID:   uuid.New().String(),
+		Hash:         ledger.ComputeHash(&prev, shaHex),
+		PreviousHash: &prev,
+		DocumentSHA:  shaHex,
+		Timestamp:    entries[1].Timestamp.Add(time.Second),
+		Seq:          &seq,
+		HashVersion:  ledger.HashVersionV1,
+	})
+
+	report := ledger.VerifyChainEntries(entries, nil)
+	assert.False(t, report.Valid)
+	require.NotNil(t, report.FirstBrokenLink)
+	assert.Equal(t, ledger.ChainIssueVersionDowngrade, report.FirstBrokenLink.Type)
+	assert.Equal(t, int64(3), report.FirstBrokenLink.EntryID)
+}
+
+// TestEntryHash_V2ContentDigest teste le recalcul du hash d'une entrée v2 anonymisée
+// (voisine dans une preuve) à partir du condensat de son contenu
+func TestEntryHash_V2ContentDigest(t *testing.T) {
+	entries := appendV2(nil, 1, 7)
+	e := entries[0]
+	e.ContentDigest = ledger.ContentDigestV2(e.DocumentID, e.DocumentSHA, e.EvidenceJWS)
+	e.DocumentID = ""
+	e.EvidenceJWS = nil
+
+	hash, err := ledger.EntryHash(e)
+	require.NoError(t, err)
+	assert.Equal(t, e.Hash, hash)
+
+	// Sans seq ni format connu, le hash n'est pas recalculable
+	e.Seq = nil
+	_, err = ledger.EntryHash(e)
+	assert.Error(t, err)
+	e.HashVersion = 9
+	_, err = ledger.EntryHash(e)
+	assert.Error(t, err)
+}
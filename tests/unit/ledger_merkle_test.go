@@ -0,0 +1,170 @@
+package unit
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/merkle"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMerkle_InclusionProof teste les chemins d'audit RFC 6962 pour toutes les
+// positions d'arbres de 1 à 17 feuilles
+func TestMerkle_InclusionProof(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		var builder merkle.Builder
+		for i := 0; i < size; i++ {
+			builder.Add([]byte(fmt.Sprintf("leaf-%d", i)))
+		}
+		root := builder.Root()
+		hashes := builder.LeafHashes()
+
+		for index := 0; index < size; index++ {
+			path, err := builder.Proof(index)
+			require.NoError(t, err)
+			assert.NoError(t, merkle.VerifyInclusion(hashes[index], int64(index), int64(size), path, root), "size=%d index=%d", size, index)
+
+			// Mauvaise position, mauvaise feuille, chemin tronqué ou allongé : refusé
+			if size > 1 {
+				other := (index + 1) % size
+				assert.Error(t, merkle.VerifyInclusion(hashes[index], int64(other), int64(size), path, root))
+				assert.Error(t, merkle.VerifyInclusion(hashes[other], int64(index), int64(size), path, root))
+				assert.Error(t, merkle.VerifyInclusion(hashes[index], int64(index), int64(size), path[1:], root))
+			}
+			assert.Error(t, merkle.VerifyInclusion(hashes[index], int64(index), int64(size), append(path, root), root))
+		}
+	}
+
+	var builder merkle.Builder
+	builder.Add([]byte("a"))
+	_, err := builder.Proof(1)
+	assert.Error(t, err)
+	assert.Error(t, merkle.VerifyInclusion(merkle.LeafHash([]byte("a")), 1, 1, nil, builder.Root()))
+}
+
+// newTestInclusionProof construit la preuve d'inclusion de la feuille index d'un lot
+// de size entrées, racine signée par service
+func newTestInclusionProof(t *testing.T, signer ledger.ReportSigner, size, index int) *ledger.InclusionProof {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	root := ledger.MerkleRoot{
+		Batch:          "2026-10-16",
+		TreeSize:       int64(size),
+		FirstEntryID:   100,
+		LastEntryID:    int64(100 + size - 1),
+		FirstTimestamp: now,
+		LastTimestamp:  now.Add(time.Duration(size) * time.Second),
+	}
+
+	var builder merkle.Builder
+	var previous *string
+	proof := &ledger.InclusionProof{LeafIndex: int64(index), Root: root}
+	for i := 0; i < size; i++ {
+		docID := uuid.New().String()
+		sha := sha256HexOf([]byte(docID))
+		hash := ledger.ComputeHash(previous, sha)
+		previous = &hash
+		leaf, err := ledger.MerkleLeaf(docID, sha, hash)
+		require.NoError(t, err)
+		builder.Add(leaf)
+		if i == index {
+			proof.DocumentID, proof.DocumentSHA, proof.LedgerHash, proof.LedgerEntryID = docID, sha, hash, int64(100+i)
+		}
+	}
+	path, err := builder.Proof(index)
+	require.NoError(t, err)
+	for _, h := range path {
+		proof.AuditPath = append(proof.AuditPath, hex.EncodeToString(h))
+	}
+	proof.Root.RootHash = hex.EncodeToString(builder.Root())
+
+	proof.RootJWS, err = signer.SignReport(ledger.MerkleRootSubjectPrefix+proof.Root.Batch, proof.Root, now)
+	require.NoError(t, err)
+	return proof
+}
+
+// TestMerkleLeaf teste le format des feuilles du ledger
+func TestMerkleLeaf(t *testing.T) {
+	docID := uuid.New()
+	sha := sha256HexOf([]byte("document"))
+	hash := ledger.ComputeHash(nil, sha)
+
+	leaf, err := ledger.MerkleLeaf(docID.String(), sha, hash)
+	require.NoError(t, err)
+	assert.Len(t, leaf, 80)
+	assert.Equal(t, docID[:], leaf[:16])
+
+	_, err = ledger.MerkleLeaf("not-a-uuid", sha, hash)
+	assert.Error(t, err)
+	_, err = ledger.MerkleLeaf(docID.String(), "", hash)
+	assert.Error(t, err)
+	_, err = ledger.MerkleLeaf(docID.String(), sha, "abcd")
+	assert.Error(t, err)
+}
+
+// TestVerifyInclusionProof teste la vérification hors ligne d'une preuve d'inclusion
+func TestVerifyInclusionProof(t *testing.T) {
+	service, _ := newTestRotatingService(t)
+	jwks, err := service.CurrentJWKS()
+	require.NoError(t, err)
+	keys, err := crypto.ParseJWKS(jwks)
+	require.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		for _, index := range []int{0, 6, 12} {
+			proof := newTestInclusionProof(t, service, 13, index)
+			assert.LessOrEqual(t, len(proof.AuditPath), 4)
+			evidence, err := verify.VerifyInclusionProof(proof, keys)
+			require.NoError(t, err)
+			assert.Equal(t, ledger.MerkleRootSubjectPrefix+"2026-10-16", evidence.DocumentID)
+		}
+
+		// Arbre à une seule feuille : chemin vide
+		proof := newTestInclusionProof(t, service, 1, 0)
+		assert.Empty(t, proof.AuditPath)
+		_, err := verify.VerifyInclusionProof(proof, service)
+		assert.NoError(t, err)
+	})
+
+	t.Run("tampered document", func(t *testing.T) {
+		proof := newTestInclusionProof(t, service, 8, 3)
+		proof.DocumentSHA = sha256HexOf([]byte("autre document"))
+		_, err := verify.VerifyInclusionProof(proof, keys)
+		assert.ErrorContains(t, err, "invalid inclusion proof")
+	})
+
+	t.Run("tampered audit path", func(t *testing.T) {
+		proof := newTestInclusionProof(t, service, 8, 3)
+		proof.AuditPath[1] = sha256HexOf([]byte("x"))
+		_, err := verify.VerifyInclusionProof(proof, keys)
+		assert.ErrorContains(t, err, "invalid inclusion proof")
+	})
+
+	t.Run("tampered root", func(t *testing.T) {
+		proof := newTestInclusionProof(t, service, 8, 3)
+		proof.Root.TreeSize = 9
+		_, err := verify.VerifyInclusionProof(proof, keys)
+		assert.ErrorContains(t, err, "does not match")
+	})
+
+	t.Run("root signed for another batch", func(t *testing.T) {
+		proof := newTestInclusionProof(t, service, 8, 3)
+		proof.RootJWS, err = service.SignReport(ledger.MerkleRootSubjectPrefix+"2026-10-15", proof.Root, time.Now())
+		require.NoError(t, err)
+		_, err := verify.VerifyInclusionProof(proof, keys)
+		assert.ErrorContains(t, err, "does not match")
+	})
+
+	t.Run("untrusted key", func(t *testing.T) {
+		other, _ := newTestRotatingService(t)
+		proof := newTestInclusionProof(t, other, 8, 3)
+		_, err := verify.VerifyInclusionProof(proof, keys)
+		assert.ErrorContains(t, err, "signature")
+	})
+}
@@ -0,0 +1,133 @@
+package unit
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePartitionArchive écrit une archive de partition (JSON lines compressé) et son
+// manifeste dans dir, comme ArchivePartition, et retourne le chemin du manifeste
+func writePartitionArchive(t *testing.T, dir string, entries []ledger.ChainEntry) string {
+	t.Helper()
+	archivePath, manifestPath := ledger.PartitionArchivePaths(dir, "ledger_2025_01")
+
+	file, err := os.Create(archivePath)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(file)
+	encoder := json.NewEncoder(gz)
+	for _, e := range entries {
+		require.NoError(t, encoder.Encode(ledger.ArchivedEntry{ChainEntry: e}))
+	}
+	require.NoError(t, gz.Close())
+	require.NoError(t, file.Close())
+
+	data, err := os.ReadFile(archivePath)
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+
+	start, end := ledger.PartitionBounds(2025, 1)
+	last := entries[len(entries)-1]
+	manifest := ledger.PartitionArchiveManifest{
+		Partition:    "ledger_2025_01",
+		PeriodStart:  start,
+		PeriodEnd:    end,
+		File:         filepath.Base(archivePath),
+		SHA256Hex:    hex.EncodeToString(sum[:]),
+		EntryCount:   int64(len(entries)),
+		FirstEntryID: entries[0].ID,
+		LastEntryID:  last.ID,
+		HeadHash:     last.Hash,
+		TenantHeads:  map[string]string{"": last.Hash},
+		ArchivedAt:   time.Now().UTC(),
+	}
+	manifestJSON, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(manifestPath, manifestJSON, 0644))
+	return manifestPath
+}
+
+// TestPartitionBounds teste les bornes UTC d'une partition mensuelle
+func TestPartitionBounds(t *testing.T) {
+	start, end := ledger.PartitionBounds(2024, 12)
+	assert.Equal(t, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), end)
+	assert.Equal(t, "ledger_2024_12", ledger.PartitionName(2024, 12))
+}
+
+// TestVerifyPartitionArchive_Valid teste la vérification d'une archive intacte
+// (tranche de chaîne ancrée sur une partition précédente)
+func TestVerifyPartitionArchive_Valid(t *testing.T) {
+	anchor := "previous-partition-head"
+	entries := buildChain(5, &anchor)
+	manifestPath := writePartitionArchive(t, t.TempDir(), entries)
+
+	manifest, report, err := ledger.VerifyPartitionArchive(manifestPath)
+	require.NoError(t, err)
+	assert.True(t, report.Valid)
+	assert.Equal(t, 5, report.EntriesChecked)
+	assert.Equal(t, int64(5), manifest.EntryCount)
+	assert.Equal(t, entries[4].Hash, manifest.HeadHash)
+}
+
+// TestVerifyPartitionArchive_TamperedFile teste la détection d'un fichier modifié
+func TestVerifyPartitionArchive_TamperedFile(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := writePartitionArchive(t, dir, buildChain(3, nil))
+
+	// Fichier remplacé par une autre chaîne : l'empreinte ne correspond plus
+	archivePath, _ := ledger.PartitionArchivePaths(dir, "ledger_2025_01")
+	other := buildChain(3, nil)
+	file, err := os.Create(archivePath)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(file)
+	for _, e := range other {
+		require.NoError(t, json.NewEncoder(gz).Encode(ledger.ArchivedEntry{ChainEntry: e}))
+	}
+	require.NoError(t, gz.Close())
+	require.NoError(t, file.Close())
+
+	_, _, err = ledger.VerifyPartitionArchive(manifestPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sha256")
+}
+
+// TestVerifyPartitionArchive_BrokenChain teste la détection d'une chaîne rompue
+// dans une archive dont le manifeste est cohérent
+func TestVerifyPartitionArchive_BrokenChain(t *testing.T) {
+	entries := buildChain(4, nil)
+	entries[2].DocumentSHA = "tampered"
+	manifestPath := writePartitionArchive(t, t.TempDir(), entries)
+
+	_, report, err := ledger.VerifyPartitionArchive(manifestPath)
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.NotZero(t, report.BrokenLinkCount)
+}
+
+// TestVerifyPartitionArchive_ManifestMismatch teste un manifeste incohérent avec le fichier
+func TestVerifyPartitionArchive_ManifestMismatch(t *testing.T) {
+	manifestPath := writePartitionArchive(t, t.TempDir(), buildChain(3, nil))
+
+	data, err := os.ReadFile(manifestPath)
+	require.NoError(t, err)
+	var manifest ledger.PartitionArchiveManifest
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	manifest.EntryCount = 2
+	data, err = json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(manifestPath, data, 0644))
+
+	_, _, err = ledger.VerifyPartitionArchive(manifestPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "entry count")
+}
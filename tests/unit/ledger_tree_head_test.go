@@ -0,0 +1,158 @@
+package unit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chainHead retourne la tête d'une chaîne après ses n premières entrées
+func chainHead(chainID string, entries []ledger.ChainEntry, n int) ledger.ChainHead {
+	if n == 0 {
+		return ledger.ChainHead{ChainID: chainID}
+	}
+	return ledger.ChainHead{ChainID: chainID, Seq: int64(n), Hash: entries[n-1].Hash}
+}
+
+// TestComputeTreeHeadHash teste l'encodage canonique du hash des têtes de chaîne
+func TestComputeTreeHeadHash(t *testing.T) {
+	chains := []ledger.ChainHead{
+		{ChainID: "tenant:b", Seq: 2, Hash: "bb"},
+		{ChainID: "tenant:a", Seq: 5, Hash: "aa"},
+	}
+	expected := sha256.Sum256([]byte("dorevia-tree-head/v1\ntenant:a 5 aa\ntenant:b 2 bb\n"))
+	assert.Equal(t, hex.EncodeToString(expected[:]), ledger.ComputeTreeHeadHash(chains))
+
+	// Indépendant de l'ordre, sensible au moindre champ
+	assert.Equal(t, ledger.ComputeTreeHeadHash(chains), ledger.ComputeTreeHeadHash([]ledger.ChainHead{chains[1], chains[0]}))
+	chains[0].Seq = 3
+	assert.NotEqual(t, hex.EncodeToString(expected[:]), ledger.ComputeTreeHeadHash(chains))
+
+	head := ledger.NewTreeHead(chains, time.Date(2026, 10, 16, 12, 0, 0, 123456789, time.FixedZone("CEST", 2*3600)))
+	assert.Equal(t, int64(8), head.TreeSize)
+	assert.Equal(t, "tenant:a", head.Chains[0].ChainID)
+	assert.Equal(t, ledger.ComputeTreeHeadHash(chains), head.HeadHash)
+	assert.Equal(t, time.Date(2026, 10, 16, 10, 0, 0, 123456000, time.UTC), head.Timestamp)
+}
+
+// TestConsistencyProof_Verify teste la vérification de cohérence entre deux têtes du ledger
+func TestConsistencyProof_Verify(t *testing.T) {
+	global := appendV2(nil, 6, 1)
+	other := buildChain(2, nil)
+	ts := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	prev := ledger.NewTreeHead([]ledger.ChainHead{chainHead("global", global, 3), chainHead("tenant:b", other, 2)}, ts)
+	next := ledger.NewTreeHead([]ledger.ChainHead{chainHead("global", global, 6), chainHead("tenant:b", other, 2)}, ts.Add(time.Minute))
+	proof := func() *ledger.ConsistencyProof {
+		return &ledger.ConsistencyProof{From: 1, To: 2, Chains: []ledger.ChainConsistency{
+			{ChainID: "global", FromSeq: 3, FromHash: global[2].Hash, ToSeq: 6, ToHash: global[5].Hash,
+				Entries: append([]ledger.ChainEntry(nil), global[3:6]...)},
+			{ChainID: "tenant:b", FromSeq: 2, FromHash: other[1].Hash, ToSeq: 2, ToHash: other[1].Hash},
+		}}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, proof().Verify(prev, next))
+		assert.NoError(t, (&ledger.ConsistencyProof{}).Verify(prev, prev))
+
+		// Une nouvelle chaîne n'a pas d'historique témoigné
+		withNew := ledger.NewTreeHead(append(next.Chains, ledger.ChainHead{ChainID: "tenant:c", Seq: 1, Hash: "cc"}), ts)
+		assert.NoError(t, proof().Verify(prev, withNew))
+	})
+
+	t.Run("rewritten history", func(t *testing.T) {
+		// Chaîne reconstruite depuis l'origine : mêmes seq, autres hash
+		forged := appendV2(nil, 6, 1)
+		forgedHead := ledger.NewTreeHead([]ledger.ChainHead{chainHead("global", forged, 6), chainHead("tenant:b", other, 2)}, ts)
+		p := proof()
+		p.Chains[0].Entries = forged[3:6]
+		assert.ErrorContains(t, p.Verify(prev, forgedHead), "does not extend previous head")
+	})
+
+	t.Run("tampered entry", func(t *testing.T) {
+		p := proof()
+		p.Chains[0].Entries[1].DocumentSHA = sha256HexOf([]byte("autre document"))
+		assert.ErrorContains(t, p.Verify(prev, next), "does not extend previous head")
+	})
+
+	t.Run("missing entries", func(t *testing.T) {
+		p := proof()
+		p.Chains[0].Entries = p.Chains[0].Entries[:2]
+		assert.ErrorContains(t, p.Verify(prev, next), "2 entries provided, 3 expected")
+		assert.ErrorContains(t, (&ledger.ConsistencyProof{}).Verify(prev, next), "no consistency entries")
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		back := ledger.NewTreeHead([]ledger.ChainHead{chainHead("global", global, 2), chainHead("tenant:b", other, 2)}, ts)
+		assert.ErrorContains(t, proof().Verify(prev, back), "rolled back")
+	})
+
+	t.Run("same size, other hash", func(t *testing.T) {
+		forked := ledger.NewTreeHead([]ledger.ChainHead{chainHead("global", global, 3), {ChainID: "tenant:b", Seq: 2, Hash: other[0].Hash}}, ts)
+		assert.ErrorContains(t, proof().Verify(prev, forked), "chain tenant:b rewritten at seq 2")
+	})
+
+	t.Run("chain dropped", func(t *testing.T) {
+		dropped := ledger.NewTreeHead([]ledger.ChainHead{chainHead("global", global, 6)}, ts)
+		assert.ErrorContains(t, proof().Verify(prev, dropped), "missing from new tree head")
+	})
+
+	t.Run("head hash mismatch", func(t *testing.T) {
+		forged := next
+		forged.Chains = append([]ledger.ChainHead(nil), next.Chains...)
+		forged.Chains[1].Hash = other[0].Hash
+		assert.ErrorContains(t, proof().Verify(prev, forged), "new tree head hash")
+	})
+}
+
+// TestVerifyTreeHeadSignature teste la vérification de la signature d'une tête (coffre ou témoin)
+func TestVerifyTreeHeadSignature(t *testing.T) {
+	service, _ := newTestRotatingService(t)
+	jwks, err := service.CurrentJWKS()
+	require.NoError(t, err)
+	keys, err := crypto.ParseJWKS(jwks)
+	require.NoError(t, err)
+
+	chains := buildChain(3, nil)
+	head := ledger.NewTreeHead([]ledger.ChainHead{chainHead("global", chains, 3)}, time.Now())
+	jws, err := service.SignReport(ledger.TreeHeadSubject, head, time.Now())
+	require.NoError(t, err)
+
+	evidence, err := verify.VerifyTreeHeadSignature(head, jws, keys)
+	require.NoError(t, err)
+	assert.Equal(t, ledger.TreeHeadSubject, evidence.DocumentID)
+
+	// Tête modifiée après signature
+	forged := head
+	forged.TreeSize++
+	_, err = verify.VerifyTreeHeadSignature(forged, jws, keys)
+	assert.ErrorContains(t, err, "does not match the head")
+
+	// Autre sujet (racine de Merkle, verdict de chaîne...)
+	other, err := service.SignReport(ledger.ChainVerdictSubject, head, time.Now())
+	require.NoError(t, err)
+	_, err = verify.VerifyTreeHeadSignature(head, other, keys)
+	assert.ErrorContains(t, err, "does not match the head")
+
+	// Tête signée dont le hash ne correspond pas aux chaînes
+	inconsistent := head
+	inconsistent.HeadHash = chains[0].Hash
+	jws, err = service.SignReport(ledger.TreeHeadSubject, inconsistent, time.Now())
+	require.NoError(t, err)
+	_, err = verify.VerifyTreeHeadSignature(inconsistent, jws, keys)
+	assert.ErrorContains(t, err, "tree head hash")
+
+	// Clé inconnue
+	stranger, _ := newTestRotatingService(t)
+	jws, err = stranger.SignReport(ledger.TreeHeadSubject, head, time.Now())
+	require.NoError(t, err)
+	_, err = verify.VerifyTreeHeadSignature(head, jws, keys)
+	assert.ErrorContains(t, err, "invalid tree head signature")
+}
@@ -0,0 +1,351 @@
+package unit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildChain construit une chaîne valide de n entrées à partir d'un ancrage optionnel
+func buildChain(n int, anchor *string) []ledger.ChainEntry {
+	entries := make([]ledger.ChainEntry, 0, n)
+	prev := anchor
+	base := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("document-%d", i)))
+		shaHex := hex.EncodeToString(sum[:])
+		hash := ledger.ComputeHash(prev, shaHex)
+		entries = append(entries, ledger.ChainEntry{
+			ID:           int64(i + 1),
+			DocumentID:   uuid.New().String(),
+			Hash:         hash,
+			PreviousHash: prev,
+			DocumentSHA:  shaHex,
+			Timestamp:    base.Add(time.Duration(i) * time.Second),
+		})
+		h := hash
+		prev = &h
+	}
+	return entries
+}
+
+// TestComputeHash teste le calcul du hash chaîné
+func TestComputeHash(t *testing.T) {
+	shaHex := "abc123def456"
+
+	first := sha256.Sum256([]byte(shaHex))
+	assert.Equal(t, hex.EncodeToString(first[:]), ledger.ComputeHash(nil, shaHex))
+
+	prev := "prev123"
+	chained := sha256.Sum256([]byte(prev + shaHex))
+	assert.Equal(t, hex.EncodeToString(chained[:]), ledger.ComputeHash(&prev, shaHex))
+}
+
+// TestVerifyChainEntries_Valid teste une chaîne intacte
+func TestVerifyChainEntries_Valid(t *testing.T) {
+	entries := buildChain(5, nil)
+
+	report := ledger.VerifyChainEntries(entries, nil)
+	assert.True(t, report.Valid)
+	assert.Equal(t, 5, report.EntriesChecked)
+	assert.Equal(t, int64(1), report.FirstEntryID)
+	assert.Equal(t, int64(5), report.LastEntryID)
+	assert.Equal(t, entries[4].Hash, report.HeadHash)
+	assert.Nil(t, report.FirstBrokenLink)
+	assert.Empty(t, report.Gaps)
+	assert.Empty(t, report.Forks)
+}
+
+// TestVerifyChainEntries_Empty teste un ledger vide
+func TestVerifyChainEntries_Empty(t *testing.T) {
+	report := ledger.VerifyChainEntries(nil, nil)
+	assert.True(t, report.Valid)
+	assert.Zero(t, report.EntriesChecked)
+}
+
+// TestVerifyChainEntries_BrokenLink teste la détection d'un hash altéré
+func TestVerifyChainEntries_BrokenLink(t *testing.T) {
+	entries := buildChain(5, nil)
+	entries[2].DocumentSHA = "tampered"
+
+	report := ledger.VerifyChainEntries(entries, nil)
+	assert.False(t, report.Valid)
+	require.NotNil(t, report.FirstBrokenLink)
+	assert.Equal(t, ledger.ChainIssueBrokenLink, report.FirstBrokenLink.Type)
+	assert.Equal(t, int64(3), report.FirstBrokenLink.EntryID)
+	assert.Equal(t, entries[2].Hash, report.FirstBrokenLink.Actual)
+	assert.Equal(t, 1, report.BrokenLinkCount)
+	assert.Zero(t, report.GapCount)
+}
+
+// TestVerifyChainEntries_MissingDocument teste une entrée dont le document est introuvable
+func TestVerifyChainEntries_MissingDocument(t *testing.T) {
+	entries := buildChain(3, nil)
+	entries[1].DocumentSHA = ""
+
+	report := ledger.VerifyChainEntries(entries, nil)
+	assert.False(t, report.Valid)
+	require.NotNil(t, report.FirstBrokenLink)
+	assert.Equal(t, ledger.ChainIssueMissingDocument, report.FirstBrokenLink.Type)
+}
+
+// TestVerifyChainEntries_Gap teste la détection d'une entrée supprimée
+func TestVerifyChainEntries_Gap(t *testing.T) {
+	entries := buildChain(5, nil)
+	// Supprimer l'entrée #3
+	entries = append(entries[:2], entries[3:]...)
+
+	report := ledger.VerifyChainEntries(entries, nil)
+	assert.False(t, report.Valid)
+	assert.Equal(t, 1, report.GapCount)
+	require.Len(t, report.Gaps, 1)
+	assert.Equal(t, int64(4), report.Gaps[0].EntryID)
+	assert.Equal(t, entries[1].Hash, report.Gaps[0].Expected)
+	// Les hash restent recalculables : pas de maillon rompu
+	assert.Zero(t, report.BrokenLinkCount)
+}
+
+// TestVerifyChainEntries_Sequence teste le contrôle des numéros de séquence (ledger_head)
+func TestVerifyChainEntries_Sequence(t *testing.T) {
+	entries := buildChain(5, nil)
+	// Les deux premières entrées sont antérieures à ledger_head (sans seq)
+	for i := 2; i < len(entries); i++ {
+		seq := int64(i + 1)
+		entries[i].Seq = &seq
+	}
+	report := ledger.VerifyChainEntries(entries, nil)
+	assert.True(t, report.Valid)
+
+	// Numéro sauté alors que les hash se suivent
+	skipped := int64(7)
+	entries[4].Seq = &skipped
+	report = ledger.VerifyChainEntries(entries, nil)
+	assert.False(t, report.Valid)
+	assert.Equal(t, 1, report.GapCount)
+	require.Len(t, report.Gaps, 1)
+	assert.Equal(t, ledger.ChainIssueSequenceGap, report.Gaps[0].Type)
+	assert.Equal(t, "5", report.Gaps[0].Expected)
+	assert.Equal(t, "7", report.Gaps[0].Actual)
+	assert.Zero(t, report.BrokenLinkCount)
+}
+
+// TestVerifyChainEntries_Fork teste la détection de deux entrées partageant un previous_hash
+func TestVerifyChainEntries_Fork(t *testing.T) {
+	entries := buildChain(3, nil)
+
+	// Entrée concurrente chaînée sur la même entrée que #3
+	sum := sha256.Sum256([]byte("concurrent"))
+	shaHex := hex.EncodeToString(sum[:])
+	forked := ledger.ChainEntry{
+		ID:           4,
+		DocumentID:   uuid.New().String(),
+		Hash:         ledger.ComputeHash(entries[2].PreviousHash, shaHex),
+		PreviousHash: entries[2].PreviousHash,
+		DocumentSHA:  shaHex,
+		Timestamp:    entries[2].Timestamp.Add(time.Millisecond),
+	}
+	entries = append(entries, forked)
+
+	report := ledger.VerifyChainEntries(entries, nil)
+	assert.False(t, report.Valid)
+	assert.Equal(t, 1, report.ForkCount)
+	require.Len(t, report.Forks, 1)
+	assert.Equal(t, int64(4), report.Forks[0].EntryID)
+	assert.Contains(t, report.Forks[0].Message, "entry 3")
+	assert.Zero(t, report.BrokenLinkCount)
+}
+
+// TestVerifyChainEntries_ForkWindow teste qu'une bifurcation sur une entrée sortie de la
+// fenêtre mémorisée (ForkWindow) reste détectée, comme trou
+func TestVerifyChainEntries_ForkWindow(t *testing.T) {
+	entries := buildChain(ledger.ForkWindow+10, nil)
+
+	sum := sha256.Sum256([]byte("late-fork"))
+	shaHex := hex.EncodeToString(sum[:])
+	forked := ledger.ChainEntry{
+		ID:           int64(len(entries) + 1),
+		DocumentID:   uuid.New().String(),
+		Hash:         ledger.ComputeHash(entries[1].PreviousHash, shaHex),
+		PreviousHash: entries[1].PreviousHash,
+		DocumentSHA:  shaHex,
+		Timestamp:    entries[len(entries)-1].Timestamp.Add(time.Second),
+	}
+	entries = append(entries, forked)
+
+	report := ledger.VerifyChainEntries(entries, nil)
+	assert.False(t, report.Valid)
+	assert.Zero(t, report.ForkCount)
+	assert.Equal(t, 1, report.GapCount)
+	require.Len(t, report.Gaps, 1)
+	assert.Equal(t, forked.ID, report.Gaps[0].EntryID)
+	assert.Zero(t, report.BrokenLinkCount)
+}
+
+// TestVerifyChainEntries_Anchor teste une fenêtre ancrée sur l'entrée précédente
+func TestVerifyChainEntries_Anchor(t *testing.T) {
+	full := buildChain(6, nil)
+	window := full[3:]
+	anchor := full[2].Hash
+
+	report := ledger.VerifyChainEntries(window, &anchor)
+	assert.True(t, report.Valid)
+	assert.Equal(t, 3, report.EntriesChecked)
+	require.NotNil(t, report.AnchorHash)
+	assert.Equal(t, anchor, *report.AnchorHash)
+
+	// Mauvais ancrage : trou en tête de fenêtre
+	wrong := full[1].Hash
+	report = ledger.VerifyChainEntries(window, &wrong)
+	assert.False(t, report.Valid)
+	assert.Equal(t, 1, report.GapCount)
+}
+
+// TestChainReport_RestrictToTenant teste l'expurgation d'un rapport global pour un tenant
+func TestChainReport_RestrictToTenant(t *testing.T) {
+	acme, globex := "acme", "globex"
+	entries := buildChain(4, nil)
+	for i := range entries {
+		if i%2 == 0 {
+			entries[i].Tenant = &acme
+		} else {
+			entries[i].Tenant = &globex
+		}
+	}
+	entries[1].DocumentSHA = "tampered" // anomalie côté globex
+
+	report := ledger.VerifyChainEntries(entries, nil)
+	require.False(t, report.Valid)
+	require.NotNil(t, report.FirstBrokenLink)
+
+	report.RestrictToTenant(acme)
+	assert.Equal(t, acme, report.Tenant)
+	assert.Empty(t, report.HeadHash)
+	assert.Nil(t, report.AnchorHash)
+	assert.Nil(t, report.FirstBrokenLink)
+	assert.Empty(t, report.BrokenLinks)
+	// Le verdict reste celui de la chaîne globale
+	assert.False(t, report.Valid)
+}
+
+// TestTenantChainVerifier teste la vérification de chaînes indépendantes par tenant
+func TestTenantChainVerifier(t *testing.T) {
+	acme, globex := "acme", "globex"
+	chainA := buildChain(3, nil)
+	chainB := buildChain(2, nil)
+	for i := range chainA {
+		chainA[i].Tenant = &acme
+	}
+	for i := range chainB {
+		chainB[i].ID += 10
+		chainB[i].Tenant = &globex
+	}
+
+	// Entrées entrelacées dans l'ordre du ledger
+	interleaved := []ledger.ChainEntry{chainA[0], chainB[0], chainA[1], chainB[1], chainA[2]}
+
+	// Mode chaîne par tenant : chaque chaîne est valide
+	v := ledger.NewTenantChainVerifier(nil, true)
+	for _, e := range interleaved {
+		v.Add(e)
+	}
+	report := v.Report()
+	assert.True(t, report.Valid)
+	assert.Equal(t, 5, report.EntriesChecked)
+	assert.Empty(t, report.HeadHash) // plusieurs têtes : pas de tête unique
+
+	// Mode chaîne globale : les mêmes entrées forment une chaîne rompue
+	v = ledger.NewTenantChainVerifier(nil, false)
+	for _, e := range interleaved {
+		v.Add(e)
+	}
+	assert.False(t, v.Report().Valid)
+}
+
+// TestTenantChainVerifier_SplitAt teste un ledger passé d'une chaîne globale aux chaînes
+// par tenant : les entrées antérieures restent vérifiées dans la chaîne globale
+func TestTenantChainVerifier_SplitAt(t *testing.T) {
+	acme, globex := "acme", "globex"
+
+	// Chaîne globale entrelaçant les deux tenants
+	global := buildChain(4, nil)
+	for i := range global {
+		if i%2 == 0 {
+			global[i].Tenant = &acme
+		} else {
+			global[i].Tenant = &globex
+		}
+	}
+	epoch := ledger.ChainEpoch{EntryID: global[3].ID, Hash: &global[3].Hash}
+
+	// Chaînes par tenant démarrant sur la tête de la chaîne globale
+	chainA := buildChain(2, epoch.Hash)
+	chainB := buildChain(2, epoch.Hash)
+	for i := range chainA {
+		chainA[i].ID += 10
+		chainA[i].Tenant = &acme
+		chainB[i].ID += 20
+		chainB[i].Tenant = &globex
+	}
+	entries := append(append([]ledger.ChainEntry{}, global...), chainA[0], chainB[0], chainA[1], chainB[1])
+
+	v := ledger.NewTenantChainVerifier(nil, true)
+	v.SplitAt(epoch, nil)
+	for _, e := range entries {
+		v.Add(e)
+	}
+	report := v.Report()
+	assert.True(t, report.Valid)
+	assert.Equal(t, 8, report.EntriesChecked)
+
+	// Sans point de passage, chaque previous_hash historique d'un autre tenant est un trou
+	v = ledger.NewTenantChainVerifier(nil, true)
+	for _, e := range entries {
+		v.Add(e)
+	}
+	report = v.Report()
+	assert.False(t, report.Valid)
+	assert.Positive(t, report.GapCount)
+
+	// Une chaîne de tenant qui ne part pas de la tête globale est signalée
+	forked := buildChain(1, nil)
+	forked[0].ID = 30
+	forked[0].Tenant = &acme
+	v = ledger.NewTenantChainVerifier(nil, true)
+	v.SplitAt(epoch, nil)
+	for _, e := range append(append([]ledger.ChainEntry{}, global...), forked[0]) {
+		v.Add(e)
+	}
+	report = v.Report()
+	assert.False(t, report.Valid)
+	assert.Equal(t, 1, report.GapCount)
+}
+
+// TestParseTimeBound teste le parsing des bornes de fenêtre
+func TestParseTimeBound(t *testing.T) {
+	bound, err := ledger.ParseTimeBound("", false)
+	require.NoError(t, err)
+	assert.Nil(t, bound)
+
+	bound, err = ledger.ParseTimeBound("2025-01-15", false)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), *bound)
+
+	// Borne haute au format date : journée incluse
+	bound, err = ledger.ParseTimeBound("2025-01-15", true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC), *bound)
+
+	bound, err = ledger.ParseTimeBound("2025-01-15T10:30:00Z", true)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC), *bound)
+
+	_, err = ledger.ParseTimeBound("15/01/2025", false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,245 @@
+package unit
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/handlers"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/proof"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha256HexOf(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// newTestProofEntries construit trois maillons consécutifs autour du document
+func newTestProofEntries(docID uuid.UUID, shaHex string) *ledger.ProofEntries {
+	now := time.Now().UTC()
+	previousHash := ledger.ComputeHash(nil, sha256HexOf([]byte("previous")))
+	entryHash := ledger.ComputeHash(&previousHash, shaHex)
+	nextHash := ledger.ComputeHash(&entryHash, sha256HexOf([]byte("next")))
+
+	return &ledger.ProofEntries{
+		Previous: &ledger.ChainEntry{ID: 1, Hash: previousHash, DocumentSHA: sha256HexOf([]byte("previous")), Timestamp: now},
+		Entry:    ledger.ChainEntry{ID: 2, DocumentID: docID.String(), Hash: entryHash, PreviousHash: &previousHash, DocumentSHA: shaHex, Timestamp: now},
+		Next:     &ledger.ChainEntry{ID: 3, Hash: nextHash, PreviousHash: &entryHash, DocumentSHA: sha256HexOf([]byte("next")), Timestamp: now},
+	}
+}
+
+// newTestBundle scelle un bundle pour un document fichier signé et chaîné
+func newTestBundle(t *testing.T, service *crypto.Service) []byte {
+	content := []byte("%PDF-1.4 facture de test")
+	doc := &models.Document{
+		ID:          uuid.New(),
+		Filename:    "../facture.pdf",
+		ContentType: "application/pdf",
+		SizeBytes:   int64(len(content)),
+		SHA256Hex:   sha256HexOf(content),
+		StoredPath:  "/opt/dorevia-vault/storage/facture.pdf",
+		CreatedAt:   time.Now(),
+	}
+	jws, err := service.SignEvidence(doc.ID.String(), doc.SHA256Hex, doc.CreatedAt)
+	require.NoError(t, err)
+	doc.EvidenceJWS = &jws
+	entries := newTestProofEntries(doc.ID, doc.SHA256Hex)
+	doc.LedgerHash = &entries.Entry.Hash
+
+	bundle, err := proof.Seal(service, proof.Contents{Document: doc, Content: content, Ledger: entries})
+	require.NoError(t, err)
+	return bundle
+}
+
+// rewriteBundle réécrit un bundle en appliquant mutate sur ses fichiers
+func rewriteBundle(t *testing.T, bundle []byte, mutate func(files map[string][]byte)) []byte {
+	zr, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	require.NoError(t, err)
+	files := make(map[string][]byte)
+	var names []string
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = content
+		names = append(names, f.Name)
+	}
+	mutate(files)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		if content, ok := files[name]; ok {
+			w, err := zw.Create(name)
+			require.NoError(t, err)
+			_, err = w.Write(content)
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func checkStatuses(report *proof.Report) map[string]string {
+	statuses := make(map[string]string)
+	for _, check := range report.Checks {
+		statuses[check.Component] = check.Status
+	}
+	return statuses
+}
+
+// TestKeySet_JWKSRoundTrip teste l'export JWKS par KID et la vérification avec un KeySet
+func TestKeySet_JWKSRoundTrip(t *testing.T) {
+	service, _ := newTestRotatingService(t)
+
+	jws, err := service.SignEvidence("doc-1", sha256HexOf([]byte("x")), time.Now())
+	require.NoError(t, err)
+	kid, err := crypto.PeekKID(jws)
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", kid)
+
+	jwks, err := service.JWKSForKIDs("key-1", "", "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key-1"}, jwksKIDs(t, jwks))
+
+	keys, err := crypto.ParseJWKS(jwks)
+	require.NoError(t, err)
+	evidence, err := keys.VerifyEvidence(jws)
+	require.NoError(t, err)
+	assert.Equal(t, "doc-1", evidence.DocumentID)
+	assert.Equal(t, "key-1", evidence.KID)
+
+	_, err = service.JWKSForKIDs("unknown")
+	assert.Error(t, err)
+	_, err = crypto.ParseJWKS([]byte(`{"keys":[]}`))
+	assert.Error(t, err)
+}
+
+// TestProofEntries_Verify teste le recalcul des maillons encadrant un document
+func TestProofEntries_Verify(t *testing.T) {
+	docID := uuid.New()
+	shaHex := sha256HexOf([]byte("document"))
+
+	t.Run("valid", func(t *testing.T) {
+		report := newTestProofEntries(docID, shaHex).Verify()
+		assert.True(t, report.Valid)
+		assert.Equal(t, 3, report.EntriesChecked)
+	})
+
+	t.Run("tampered document hash", func(t *testing.T) {
+		entries := newTestProofEntries(docID, shaHex)
+		entries.Entry.DocumentSHA = sha256HexOf([]byte("autre document"))
+		assert.False(t, entries.Verify().Valid)
+	})
+
+	t.Run("missing previous entry", func(t *testing.T) {
+		entries := newTestProofEntries(docID, shaHex)
+		entries.Previous = nil
+		report := entries.Verify()
+		assert.False(t, report.Valid)
+		assert.Equal(t, 1, report.GapCount)
+	})
+
+	t.Run("first entry of the chain", func(t *testing.T) {
+		hash := ledger.ComputeHash(nil, shaHex)
+		entries := &ledger.ProofEntries{Entry: ledger.ChainEntry{ID: 1, Hash: hash, DocumentSHA: shaHex}}
+		assert.True(t, entries.Verify().Valid)
+	})
+}
+
+// TestVerifyBundle teste la vérification hors ligne d'un bundle de preuve
+func TestVerifyBundle(t *testing.T) {
+	service, _ := newTestRotatingService(t)
+	bundle := newTestBundle(t, service)
+
+	t.Run("valid with bundle keys", func(t *testing.T) {
+		report, err := proof.VerifyBundle(bundle, nil, nil)
+		require.NoError(t, err)
+		assert.True(t, report.Valid, report.Errors)
+		assert.Equal(t, proof.KeysFromBundle, report.KeysSource)
+		assert.Equal(t, "document/facture.pdf", report.Manifest.ContentFile)
+
+		statuses := checkStatuses(report)
+		for _, component := range []string{"signature", "files", "file", "jws", "ledger"} {
+			assert.Equal(t, "ok", statuses[component], component)
+		}
+	})
+
+	t.Run("valid with trusted keys", func(t *testing.T) {
+		jwks, err := service.CurrentJWKS()
+		require.NoError(t, err)
+		trusted, err := crypto.ParseJWKS(jwks)
+		require.NoError(t, err)
+
+		report, err := proof.VerifyBundle(bundle, trusted, nil)
+		require.NoError(t, err)
+		assert.True(t, report.Valid, report.Errors)
+		assert.Equal(t, proof.KeysFromTrusted, report.KeysSource)
+	})
+
+	t.Run("untrusted signing key", func(t *testing.T) {
+		other, _ := newTestRotatingService(t)
+		jwks, err := other.CurrentJWKS()
+		require.NoError(t, err)
+		trusted, err := crypto.ParseJWKS(jwks)
+		require.NoError(t, err)
+
+		report, err := proof.VerifyBundle(bundle, trusted, nil)
+		require.NoError(t, err)
+		assert.False(t, report.Valid)
+		assert.Equal(t, "error", checkStatuses(report)["signature"])
+	})
+
+	t.Run("tampered document", func(t *testing.T) {
+		tampered := rewriteBundle(t, bundle, func(files map[string][]byte) {
+			files["document/facture.pdf"] = []byte("%PDF-1.4 facture modifiée")
+		})
+		report, err := proof.VerifyBundle(tampered, nil, nil)
+		require.NoError(t, err)
+		assert.False(t, report.Valid)
+		assert.Equal(t, "error", checkStatuses(report)["files"])
+	})
+
+	t.Run("missing ledger file", func(t *testing.T) {
+		tampered := rewriteBundle(t, bundle, func(files map[string][]byte) {
+			delete(files, proof.FileLedger)
+		})
+		report, err := proof.VerifyBundle(tampered, nil, nil)
+		require.NoError(t, err)
+		assert.False(t, report.Valid)
+		assert.Equal(t, "missing", checkStatuses(report)["files"])
+	})
+
+	t.Run("not a zip", func(t *testing.T) {
+		_, err := proof.VerifyBundle([]byte("not a zip"), nil, nil)
+		assert.Error(t, err)
+	})
+}
+
+// TestProofBundleHandler_NoDatabase teste la réponse sans base configurée
+func TestProofBundleHandler_NoDatabase(t *testing.T) {
+	service, _ := newTestRotatingService(t)
+	log := zerolog.Nop()
+
+	app := fiber.New()
+	app.Get("/api/v1/documents/:id/proof-bundle", handlers.ProofBundleHandler(nil, service, &log, nil))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/documents/"+uuid.New().String()+"/proof-bundle", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+}
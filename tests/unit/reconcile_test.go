@@ -4,13 +4,19 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/doreviateam/dorevia-vault/internal/auth"
+	"github.com/doreviateam/dorevia-vault/internal/handlers"
 	"github.com/doreviateam/dorevia-vault/internal/reconcile"
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -267,3 +273,58 @@ func TestReconciliationReport_Timestamp(t *testing.T) {
 	assert.True(t, diff < 1*time.Second)
 }
 
+
+// TestReconciliationReport_Issues teste le décompte des anomalies et de celles qui
+// subsistent après réparation
+func TestReconciliationReport_Issues(t *testing.T) {
+	report := &reconcile.ReconciliationReport{}
+	assert.False(t, report.HasIssues())
+	assert.Zero(t, report.Unrepairable())
+
+	// Documents sans preuve : réparables par scellement après coup
+	report.UnsealedCount = 3
+	report.DocumentsSealed = 2
+	assert.True(t, report.HasIssues())
+	assert.Equal(t, 1, report.Unrepairable())
+
+	// Ledger et preuves incohérents : jamais réparés automatiquement
+	report.DocumentsSealed = 3
+	report.DanglingLedgerCount = 1
+	report.LedgerHashMismatchCount = 2
+	report.InvalidEvidenceCount = 1
+	assert.Equal(t, 4, report.Unrepairable())
+
+	// Orphelins : anomalies traitées par marquage ou suppression
+	orphans := &reconcile.ReconciliationReport{OrphanDBs: []reconcile.OrphanDB{{DocumentID: uuid.NewString()}}}
+	assert.True(t, orphans.HasIssues())
+	assert.Zero(t, orphans.Unrepairable())
+}
+
+// TestReconcileHandler_Guards teste les refus de l'endpoint de réconciliation : base non
+// configurée et appelant rattaché à un tenant
+func TestReconcileHandler_Guards(t *testing.T) {
+	log := zerolog.Nop()
+
+	app := fiber.New()
+	app.Post("/reconcile", handlers.ReconcileHandler(nil, reconcile.Options{}, nil, &log, nil))
+	resp, err := app.Test(httptest.NewRequest("POST", "/reconcile", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	app = fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", &auth.UserInfo{UserID: "admin-acme", Role: string(auth.RoleAdmin), Tenant: "acme"})
+		return c.Next()
+	})
+	app.Post("/reconcile", handlers.ReconcileHandler(&storage.DB{}, reconcile.Options{}, nil, &log, nil))
+	resp, err = app.Test(httptest.NewRequest("POST", "/reconcile", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
+
+// TestReconcilePermission teste que la réconciliation est réservée aux administrateurs
+func TestReconcilePermission(t *testing.T) {
+	permission, err := auth.GetRequiredPermission("/api/v1/admin/reconcile")
+	require.NoError(t, err)
+	assert.Equal(t, auth.PermissionReconcile, permission)
+}
@@ -0,0 +1,86 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/retention"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParsePolicies teste la lecture de RETENTION_POLICIES et le tri par précision
+func TestParsePolicies(t *testing.T) {
+	policies, err := retention.ParsePolicies(" *=10y, pos=6y ,*/account.move=8y, sales/account.move=10y,stock=30d")
+	require.NoError(t, err)
+	require.Len(t, policies, 5)
+
+	var rules []string
+	for _, p := range policies {
+		rules = append(rules, p.String())
+	}
+	assert.Equal(t, []string{"sales/account.move=10y", "pos=6y", "stock=30d", "*/account.move=8y", "*=10y"}, rules)
+
+	empty, err := retention.ParsePolicies("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+// TestParsePolicies_Invalid teste le refus des règles mal formées
+func TestParsePolicies_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"pos",
+		"pos=",
+		"pos=6",
+		"pos=6w",
+		"pos=0y",
+		"pos=-1y",
+		"=10y",
+		"/account.move=10y",
+		"pos=6y,pos=10y",
+	} {
+		_, err := retention.ParsePolicies(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+// TestPolicyFor teste le choix de la règle la plus précise
+func TestPolicyFor(t *testing.T) {
+	policies, err := retention.ParsePolicies("*=10y,pos=6y,sales/account.move=10y,*/stock.picking=5y")
+	require.NoError(t, err)
+
+	cases := []struct {
+		source, model, want string
+	}{
+		{"sales", "account.move", "sales/account.move=10y"},
+		{"pos", "pos.order", "pos=6y"},
+		{"pos", "stock.picking", "pos=6y"},
+		{"stock", "stock.picking", "*/stock.picking=5y"},
+		{"purchase", "", "*=10y"},
+	}
+	for _, c := range cases {
+		p := retention.PolicyFor(policies, c.source, c.model)
+		require.NotNil(t, p, c.source)
+		assert.Equal(t, c.want, p.String(), "%s/%s", c.source, c.model)
+	}
+
+	policies, err = retention.ParsePolicies("pos=6y")
+	require.NoError(t, err)
+	assert.Nil(t, retention.PolicyFor(policies, "sales", "account.move"), "kept indefinitely")
+}
+
+// TestPolicyCutoff teste la date d'échéance d'une règle
+func TestPolicyCutoff(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	policies, err := retention.ParsePolicies("sales=10y,pos=6m,stock=30d")
+	require.NoError(t, err)
+
+	want := map[string]time.Time{
+		"sales=10y": time.Date(2016, 10, 17, 12, 0, 0, 0, time.UTC),
+		"pos=6m":    time.Date(2026, 4, 17, 12, 0, 0, 0, time.UTC),
+		"stock=30d": time.Date(2026, 9, 17, 12, 0, 0, 0, time.UTC),
+	}
+	for _, p := range policies {
+		assert.Equal(t, want[p.String()], p.Cutoff(now), p.String())
+	}
+}
@@ -0,0 +1,79 @@
+package unit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/doreviateam/dorevia-vault/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSpoolContent teste l'écriture en flux dans un fichier temporaire chiffré avec calcul
+// du SHA256
+func TestSpoolContent(t *testing.T) {
+	data := bytes.Repeat([]byte("%PDF-1.4 facture fournisseur scannée "), 4096)
+	sum := sha256.Sum256(data)
+
+	dir := filepath.Join(t.TempDir(), "spool")
+	content, err := storage.SpoolContent(bytes.NewReader(data), dir, int64(len(data)))
+	require.NoError(t, err)
+	defer content.Remove()
+
+	assert.Equal(t, int64(len(data)), content.Size())
+	assert.Equal(t, hex.EncodeToString(sum[:]), content.SHA256Hex())
+
+	// Répertoire privé, fichier jamais écrit en clair
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	raw, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "facture fournisseur")
+
+	// Le contenu est relu à chaque ouverture
+	for i := 0; i < 2; i++ {
+		plain, err := storage.ReadContent(content)
+		require.NoError(t, err)
+		assert.Equal(t, data, plain)
+	}
+}
+
+// TestSpoolContent_TooLarge teste le refus d'un contenu au-delà de la taille maximale
+func TestSpoolContent_TooLarge(t *testing.T) {
+	dir := t.TempDir()
+	_, err := storage.SpoolContent(bytes.NewReader(make([]byte, 1025)), dir, 1024)
+	assert.ErrorIs(t, err, storage.ErrContentTooLarge)
+
+	// Le fichier temporaire est supprimé
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// TestBytesContent teste le contenu déjà en mémoire
+func TestBytesContent(t *testing.T) {
+	data := []byte("ticket de caisse")
+	sum := sha256.Sum256(data)
+
+	content := storage.BytesContent(data)
+	assert.Equal(t, int64(len(data)), content.Size())
+	assert.Equal(t, hex.EncodeToString(sum[:]), content.SHA256Hex())
+
+	r, err := content.Open()
+	require.NoError(t, err)
+	defer r.Close()
+	_, seekable := r.(io.Seeker)
+	assert.True(t, seekable, "S3 uploads need a seekable reader")
+	plain, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, data, plain)
+}
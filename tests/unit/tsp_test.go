@@ -0,0 +1,249 @@
+package unit
+
+import (
+	"context"
+	stdcrypto "crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/proof"
+	"github.com/doreviateam/dorevia-vault/internal/tsp"
+	"github.com/doreviateam/dorevia-vault/internal/tsp/tsptest"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTSA(t *testing.T) *tsptest.Server {
+	server, err := tsptest.NewServer()
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestTSPClient_Timestamp teste l'horodatage auprès de la TSA locale
+func TestTSPClient_Timestamp(t *testing.T) {
+	server := newTestTSA(t)
+	digest := sha256.Sum256([]byte("ledger head"))
+
+	t.Run("granted", func(t *testing.T) {
+		client := tsp.NewClient(server.URL, 5*time.Second, server.Roots())
+		token, err := client.Timestamp(context.Background(), digest[:])
+		require.NoError(t, err)
+
+		assert.WithinDuration(t, time.Now(), token.GenTime, 5*time.Second)
+		assert.True(t, token.Policy.Equal(tsptest.Policy))
+		assert.Equal(t, server.Cert.Raw, token.Signer.Raw)
+		assert.NoError(t, token.VerifyImprint(stdcrypto.SHA256, digest[:]))
+
+		other := sha256.Sum256([]byte("other head"))
+		assert.ErrorIs(t, token.VerifyImprint(stdcrypto.SHA256, other[:]), tsp.ErrImprintMismatch)
+
+		// Le jeton stocké se relit et se revérifie
+		parsed, err := tsp.ParseToken(token.Raw)
+		require.NoError(t, err)
+		assert.Equal(t, token.SerialNumber, parsed.SerialNumber)
+		assert.NoError(t, parsed.VerifyCertificate(server.Roots()))
+	})
+
+	t.Run("untrusted authority", func(t *testing.T) {
+		other, err := tsptest.NewAuthority()
+		require.NoError(t, err)
+		client := tsp.NewClient(server.URL, 5*time.Second, other.Roots())
+		_, err = client.Timestamp(context.Background(), digest[:])
+		assert.Error(t, err)
+	})
+
+	t.Run("system roots", func(t *testing.T) {
+		// Sans autorités configurées, la TSA de test n'est pas reconnue par le système
+		client := tsp.NewClient(server.URL, 5*time.Second, nil)
+		_, err := client.Timestamp(context.Background(), digest[:])
+		assert.ErrorContains(t, err, "untrusted timestamp authority certificate")
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		server.SetReject(true)
+		defer server.SetReject(false)
+		client := tsp.NewClient(server.URL, 5*time.Second, nil)
+		_, err := client.Timestamp(context.Background(), digest[:])
+		assert.ErrorContains(t, err, "rejected")
+	})
+
+	t.Run("invalid digest", func(t *testing.T) {
+		client := tsp.NewClient(server.URL, 5*time.Second, nil)
+		_, err := client.Timestamp(context.Background(), []byte("short"))
+		assert.Error(t, err)
+	})
+}
+
+// TestTSPToken_Tampered teste le refus d'un jeton modifié
+func TestTSPToken_Tampered(t *testing.T) {
+	authority, err := tsptest.NewAuthority()
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("ledger head"))
+	token, err := authority.Issue(&tsp.Request{HashAlgorithm: stdcrypto.SHA256, HashedMessage: digest[:], CertReq: true}, time.Now())
+	require.NoError(t, err)
+
+	_, err = tsp.ParseToken(token)
+	require.NoError(t, err)
+
+	// Modifier l'empreinte horodatée invalide la signature de la TSA
+	tampered := append([]byte(nil), token...)
+	i := indexOf(tampered, digest[:])
+	require.GreaterOrEqual(t, i, 0)
+	tampered[i] ^= 0xff
+	_, err = tsp.ParseToken(tampered)
+	assert.Error(t, err)
+
+	// Sans certificat joint, le signataire est inconnu
+	noCert, err := authority.Issue(&tsp.Request{HashAlgorithm: stdcrypto.SHA256, HashedMessage: digest[:]}, time.Now())
+	require.NoError(t, err)
+	_, err = tsp.ParseToken(noCert)
+	assert.ErrorContains(t, err, "certificate")
+}
+
+func indexOf(data, sub []byte) int {
+	for i := 0; i+len(sub) <= len(data); i++ {
+		if string(data[i:i+len(sub)]) == string(sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// newTestAnchorProof horodate la tête d'une chaîne entry -> 2 entrées suivantes
+func newTestAnchorProof(t *testing.T, authority *tsptest.Authority, entry ledger.ChainEntry) *ledger.AnchorProof {
+	now := time.Now().UTC()
+	firstSHA := sha256HexOf([]byte("suivant 1"))
+	firstHash := ledger.ComputeHash(&entry.Hash, firstSHA)
+	secondSHA := sha256HexOf([]byte("suivant 2"))
+	secondHash := ledger.ComputeHash(&firstHash, secondSHA)
+
+	digest, err := hex.DecodeString(secondHash)
+	require.NoError(t, err)
+	token, err := authority.Issue(&tsp.Request{HashAlgorithm: stdcrypto.SHA256, HashedMessage: digest, CertReq: true}, now)
+	require.NoError(t, err)
+
+	return &ledger.AnchorProof{
+		Anchor: ledger.Anchor{ID: 1, LedgerEntryID: entry.ID + 2, LedgerHash: secondHash, LedgerTimestamp: now, TSAURL: "http://tsa.test", Token: token, GenTime: now},
+		Path: []ledger.ChainEntry{
+			{ID: entry.ID + 1, Hash: firstHash, PreviousHash: &entry.Hash, DocumentSHA: firstSHA, Timestamp: now},
+			{ID: entry.ID + 2, Hash: secondHash, PreviousHash: &firstHash, DocumentSHA: secondSHA, Timestamp: now},
+		},
+	}
+}
+
+// TestAnchorProof_Verify teste la vérification d'un ancrage horodaté du ledger
+func TestAnchorProof_Verify(t *testing.T) {
+	authority, err := tsptest.NewAuthority()
+	require.NoError(t, err)
+	entries := newTestProofEntries(uuid.New(), sha256HexOf([]byte("document")))
+	entry := entries.Entry
+
+	t.Run("valid", func(t *testing.T) {
+		anchor := newTestAnchorProof(t, authority, entry)
+		token, err := anchor.Verify(entry, authority.Roots())
+		require.NoError(t, err)
+		assert.Equal(t, authority.Cert.Raw, token.Signer.Raw)
+
+		check, info := verify.CheckAnchor(anchor, entry, authority.Roots())
+		assert.Equal(t, "ok", check.Status, check.Message)
+		assert.Equal(t, "timestamp", check.Component)
+		assert.Equal(t, 2, info.PathLength)
+		assert.NotEmpty(t, info.GenTime)
+	})
+
+	t.Run("anchor on the entry itself", func(t *testing.T) {
+		digest, _ := hex.DecodeString(entry.Hash)
+		token, err := authority.Issue(&tsp.Request{HashAlgorithm: stdcrypto.SHA256, HashedMessage: digest, CertReq: true}, time.Now())
+		require.NoError(t, err)
+		anchor := &ledger.AnchorProof{Anchor: ledger.Anchor{LedgerHash: entry.Hash, Token: token}}
+		_, err = anchor.Verify(entry, authority.Roots())
+		assert.NoError(t, err)
+	})
+
+	t.Run("broken path", func(t *testing.T) {
+		anchor := newTestAnchorProof(t, authority, entry)
+		anchor.Path[0].DocumentSHA = sha256HexOf([]byte("autre"))
+		_, err := anchor.Verify(entry, nil)
+		assert.ErrorContains(t, err, "path")
+	})
+
+	t.Run("token for another hash", func(t *testing.T) {
+		anchor := newTestAnchorProof(t, authority, entry)
+		other := newTestAnchorProof(t, authority, ledger.ChainEntry{ID: 10, Hash: ledger.ComputeHash(nil, sha256HexOf([]byte("x")))})
+		anchor.Anchor.Token = other.Anchor.Token
+		_, err := anchor.Verify(entry, nil)
+		assert.ErrorIs(t, err, tsp.ErrImprintMismatch)
+	})
+
+	t.Run("untrusted authority", func(t *testing.T) {
+		other, err := tsptest.NewAuthority()
+		require.NoError(t, err)
+		anchor := newTestAnchorProof(t, authority, entry)
+		check, _ := verify.CheckAnchor(anchor, entry, other.Roots())
+		assert.Equal(t, "error", check.Status)
+	})
+
+	t.Run("system roots", func(t *testing.T) {
+		// Sans autorités configurées, une chaîne inconnue du système n'est pas "ok"
+		anchor := newTestAnchorProof(t, authority, entry)
+		_, err := anchor.Verify(entry, nil)
+		assert.ErrorContains(t, err, "untrusted timestamp authority certificate")
+		check, _ := verify.CheckAnchor(anchor, entry, nil)
+		assert.Equal(t, "error", check.Status)
+	})
+}
+
+// TestVerifyBundle_Anchor teste l'horodatage joint au bundle de preuve
+func TestVerifyBundle_Anchor(t *testing.T) {
+	service, _ := newTestRotatingService(t)
+	authority, err := tsptest.NewAuthority()
+	require.NoError(t, err)
+
+	content := []byte("%PDF-1.4 facture horodatée")
+	doc := &models.Document{
+		ID:          uuid.New(),
+		Filename:    "facture.pdf",
+		ContentType: "application/pdf",
+		SizeBytes:   int64(len(content)),
+		SHA256Hex:   sha256HexOf(content),
+		StoredPath:  "/opt/dorevia-vault/storage/facture.pdf",
+		CreatedAt:   time.Now(),
+	}
+	jws, err := service.SignEvidence(doc.ID.String(), doc.SHA256Hex, doc.CreatedAt)
+	require.NoError(t, err)
+	doc.EvidenceJWS = &jws
+	entries := newTestProofEntries(doc.ID, doc.SHA256Hex)
+	doc.LedgerHash = &entries.Entry.Hash
+	anchor := newTestAnchorProof(t, authority, entries.Entry)
+
+	bundle, err := proof.Seal(service, proof.Contents{Document: doc, Content: content, Ledger: entries, Anchor: anchor})
+	require.NoError(t, err)
+
+	report, err := proof.VerifyBundle(bundle, nil, authority.Roots())
+	require.NoError(t, err)
+	assert.True(t, report.Valid, report.Errors)
+	assert.Equal(t, "ok", checkStatuses(report)["timestamp"])
+	require.NotNil(t, report.Manifest.AnchoredAt)
+
+	other, err := tsptest.NewAuthority()
+	require.NoError(t, err)
+	report, err = proof.VerifyBundle(bundle, nil, other.Roots())
+	require.NoError(t, err)
+	assert.False(t, report.Valid)
+	assert.Equal(t, "error", checkStatuses(report)["timestamp"])
+
+	// Sans ancrage : simple avertissement
+	unanchored, err := proof.Seal(service, proof.Contents{Document: doc, Content: content, Ledger: entries})
+	require.NoError(t, err)
+	report, err = proof.VerifyBundle(unanchored, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, report.Valid, report.Errors)
+	assert.Equal(t, "warn", checkStatuses(report)["timestamp"])
+}
@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"mime/multipart"
 	"net/http/httptest"
-	"os"
-	"path/filepath"
 	"testing"
 
 	"github.com/doreviateam/dorevia-vault/internal/handlers"
@@ -17,7 +15,7 @@ import (
 // TestUploadHandlerWithoutDB teste le handler sans DB configurée
 func TestUploadHandlerWithoutDB(t *testing.T) {
 	app := fiber.New()
-	app.Post("/upload", handlers.UploadHandler(nil, "/tmp"))
+	app.Post("/upload", handlers.UploadHandler(nil))
 
 	// Créer un fichier multipart
 	body := &bytes.Buffer{}
@@ -42,7 +40,7 @@ func TestUploadHandlerWithoutDB(t *testing.T) {
 func TestUploadHandlerNoFile(t *testing.T) {
 	// Ce test nécessiterait un mock de la DB, on teste juste la validation de base
 	app := fiber.New()
-	app.Post("/upload", handlers.UploadHandler(nil, "/tmp"))
+	app.Post("/upload", handlers.UploadHandler(nil))
 
 	req := httptest.NewRequest("POST", "/upload", nil)
 	resp, err := app.Test(req)
@@ -51,29 +49,3 @@ func TestUploadHandlerNoFile(t *testing.T) {
 	// Soit 503 (DB not configured) soit 400 (No file provided)
 	assert.Contains(t, []int{400, 503}, resp.StatusCode)
 }
-
-// TestUploadHandlerInvalidStorageDir teste avec un répertoire invalide
-func TestUploadHandlerInvalidStorageDir(t *testing.T) {
-	// Créer un répertoire temporaire pour le test
-	tmpDir := filepath.Join(os.TempDir(), "vault-test")
-	defer os.RemoveAll(tmpDir)
-
-	app := fiber.New()
-	app.Post("/upload", handlers.UploadHandler(nil, "/invalid/path/that/does/not/exist"))
-
-	// Créer un fichier multipart
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, _ := writer.CreateFormFile("file", "test.txt")
-	part.Write([]byte("test content"))
-	writer.Close()
-
-	req := httptest.NewRequest("POST", "/upload", body)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	resp, err := app.Test(req)
-
-	assert.NoError(t, err)
-	// Devrait retourner 503 (DB not configured) ou 500 (storage error)
-	assert.Contains(t, []int{500, 503}, resp.StatusCode)
-}
-
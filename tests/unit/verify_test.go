@@ -4,12 +4,15 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/services"
 	"github.com/doreviateam/dorevia-vault/internal/verify"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -49,6 +52,22 @@ func TestCheck_Structure(t *testing.T) {
 	assert.NotEmpty(t, check.Message)
 }
 
+// TestCheck_Failed teste que seuls "error" et "missing" sont des échecs : une pierre
+// tombale ("purged") ou un statut inconnu n'invalident pas la vérification
+func TestCheck_Failed(t *testing.T) {
+	for status, failed := range map[string]bool{
+		"ok":       false,
+		"warn":     false,
+		"degraded": false,
+		"purged":   false,
+		"future":   false,
+		"error":    true,
+		"missing":  true,
+	} {
+		assert.Equal(t, failed, verify.Check{Component: "file", Status: status}.Failed(), status)
+	}
+}
+
 // TestVerificationResult_InvalidDocument teste un résultat avec document invalide
 func TestVerificationResult_InvalidDocument(t *testing.T) {
 	result := &verify.VerificationResult{
@@ -247,3 +266,114 @@ func TestVerifyDocumentIntegrity_ContextTimeout(t *testing.T) {
 	}
 }
 
+// TestVerifyEvidenceJWS teste la vérification de la preuve JWS d'un document
+func TestVerifyEvidenceJWS(t *testing.T) {
+	service, keyManager := newTestRotatingService(t)
+	signedAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	newDoc := func() *models.Document {
+		hash := sha256.Sum256([]byte(uuid.NewString()))
+		doc := &models.Document{ID: uuid.New(), SHA256Hex: hex.EncodeToString(hash[:])}
+		jws, err := service.SignEvidence(doc.ID.String(), doc.SHA256Hex, signedAt)
+		require.NoError(t, err)
+		doc.EvidenceJWS = &jws
+		return doc
+	}
+
+	// Preuve valide, y compris après rotation de la clé
+	doc := newDoc()
+	require.NoError(t, keyManager.GenerateKeyPair(context.Background(), "key-2"))
+	require.NoError(t, service.Rotation().Rotate("key-2"))
+	check, evidence := verify.VerifyEvidenceJWS(service, doc)
+	assert.Equal(t, "jws", check.Component)
+	assert.Equal(t, "ok", check.Status)
+	assert.Equal(t, "key-1", evidence.KID)
+	assert.Equal(t, "2025-03-01T10:00:00Z", evidence.SignedAt)
+
+	// Preuves échangées entre deux documents
+	other := newDoc()
+	swapped := *doc
+	swapped.EvidenceJWS = other.EvidenceJWS
+	check, evidence = verify.VerifyEvidenceJWS(service, &swapped)
+	assert.Equal(t, "error", check.Status)
+	assert.Contains(t, check.Message, "document_id mismatch")
+	assert.Equal(t, "error", evidence.Status)
+
+	// SHA256 modifié en base
+	tampered := *doc
+	tampered.SHA256Hex = other.SHA256Hex
+	check, _ = verify.VerifyEvidenceJWS(service, &tampered)
+	assert.Equal(t, "error", check.Status)
+	assert.Contains(t, check.Message, "sha256 mismatch")
+
+	// Signature altérée
+	forged := *doc
+	bad := *doc.EvidenceJWS + "x"
+	forged.EvidenceJWS = &bad
+	check, _ = verify.VerifyEvidenceJWS(service, &forged)
+	assert.Equal(t, "error", check.Status)
+
+	// Mode dégradé : document stocké sans preuve
+	degraded := *doc
+	empty := ""
+	degraded.EvidenceJWS = &empty
+	check, evidence = verify.VerifyEvidenceJWS(service, &degraded)
+	assert.Equal(t, "degraded", check.Status)
+	assert.Equal(t, "degraded", evidence.Status)
+	degraded.EvidenceJWS = nil
+	check, _ = verify.VerifyEvidenceJWS(service, &degraded)
+	assert.Equal(t, "degraded", check.Status)
+
+	// Service JWS absent : preuve non vérifiée
+	check, evidence = verify.VerifyEvidenceJWS(nil, doc)
+	assert.Equal(t, "warn", check.Status)
+	assert.Equal(t, "unverified", evidence.Status)
+}
+
+// TestVerifyPosPayload teste la vérification d'un ticket POS stocké en base (sans fichier)
+func TestVerifyPosPayload(t *testing.T) {
+	session := "POS/2025/0042"
+	ticket := map[string]interface{}{
+		"lines": []interface{}{
+			map[string]interface{}{"product": "Café", "qty": 2.0, "price": 1.5},
+		},
+		"total": 3.0,
+	}
+	sha256Hex, err := services.ComputePosTicketHash(ticket, "pos.order,1234", &session)
+	require.NoError(t, err)
+
+	// payload_json tel que relu depuis JSONB : clés réordonnées, espaces, champs nuls
+	payload := []byte(`{"ticket": {"total": 3, "lines": [{"qty": 2, "price": 1.5, "product": "Café"}]},
+		"tenant": "acme", "cashier": null, "source_id": "pos.order,1234", "pos_session": "POS/2025/0042"}`)
+	doc := &models.Document{ID: uuid.New(), SHA256Hex: sha256Hex, PayloadJSON: payload}
+
+	check := verify.VerifyPosPayload(doc)
+	assert.Equal(t, "payload", check.Component)
+	assert.Equal(t, "ok", check.Status, check.Message)
+
+	// Ticket modifié en base
+	var tampered map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &tampered))
+	tampered["ticket"].(map[string]interface{})["total"] = 30
+	doc.PayloadJSON, err = json.Marshal(tampered)
+	require.NoError(t, err)
+	check = verify.VerifyPosPayload(doc)
+	assert.Equal(t, "error", check.Status)
+	assert.Contains(t, check.Message, "SHA256 mismatch")
+
+	// Session sans valeur : le champ nul est ignoré comme à l'ingestion
+	sha256NoSession, err := services.ComputePosTicketHash(ticket, "pos.order,1234", nil)
+	require.NoError(t, err)
+	doc = &models.Document{
+		ID:          uuid.New(),
+		SHA256Hex:   sha256NoSession,
+		PayloadJSON: []byte(`{"source_id":"pos.order,1234","pos_session":null,"ticket":{"total":3,"lines":[{"product":"Café","qty":2,"price":1.5}]}}`),
+	}
+	assert.Equal(t, "ok", verify.VerifyPosPayload(doc).Status)
+
+	// Payload invalide
+	doc.PayloadJSON = []byte(`{"source_id":"pos.order,1234"}`)
+	assert.Equal(t, "error", verify.VerifyPosPayload(doc).Status)
+	doc.PayloadJSON = []byte(`not json`)
+	assert.Equal(t, "error", verify.VerifyPosPayload(doc).Status)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/doreviateam/dorevia-vault/internal/config"
 	"github.com/doreviateam/dorevia-vault/internal/handlers"
 	"github.com/doreviateam/dorevia-vault/internal/models"
+	"github.com/doreviateam/dorevia-vault/internal/services"
 	"github.com/doreviateam/dorevia-vault/internal/storage"
 	"github.com/doreviateam/dorevia-vault/internal/webhooks"
 	"github.com/gofiber/fiber/v2"
@@ -103,7 +104,11 @@ func TestInvoicesHandler_WebhookEmitted(t *testing.T) {
 		FacturXValidationEnabled: false,
 	}
 	log := zerolog.Nop()
-	handler := handlers.InvoicesHandler(db, "/tmp/test-storage", nil, cfg, &log, nil, mockWebhookManager)
+	service := services.NewIngestionService(storage.NewDocumentRepository(db, "/tmp/test-storage"), nil, nil, services.IngestionOptions{
+		WebhookManager: mockWebhookManager,
+		Logger:         &log,
+	})
+	handler := handlers.InvoicesHandler(service, cfg, &log)
 
 	// Créer l'app Fiber
 	app := fiber.New()
@@ -151,7 +156,8 @@ func TestInvoicesHandler_WebhookNotEmittedIfNil(t *testing.T) {
 		FacturXValidationEnabled: false,
 	}
 	log := zerolog.Nop()
-	handler := handlers.InvoicesHandler(db, "/tmp/test-storage", nil, cfg, &log, nil, nil)
+	service := services.NewIngestionService(storage.NewDocumentRepository(db, "/tmp/test-storage"), nil, nil, services.IngestionOptions{Logger: &log})
+	handler := handlers.InvoicesHandler(service, cfg, &log)
 
 	// Créer l'app Fiber
 	app := fiber.New()
@@ -0,0 +1,156 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/doreviateam/dorevia-vault/internal/crypto"
+	"github.com/doreviateam/dorevia-vault/internal/ledger"
+	"github.com/doreviateam/dorevia-vault/internal/verify"
+	"github.com/doreviateam/dorevia-vault/internal/witness"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVault simule les endpoints des têtes signées d'un coffre
+type fakeVault struct {
+	t       *testing.T
+	service *crypto.Service
+	mu      sync.Mutex
+	entries []ledger.ChainEntry
+	heads   []ledger.SignedTreeHead
+	cosigns map[int64]string // tree_head_id -> JWS du témoin
+}
+
+// publish signe la tête de la chaîne après ses n premières entrées
+func (v *fakeVault) publish(n int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	head := ledger.NewTreeHead([]ledger.ChainHead{chainHead(ledger.GlobalChainID, v.entries, n)}, time.Now())
+	jws, err := v.service.SignReport(ledger.TreeHeadSubject, head, time.Now())
+	require.NoError(v.t, err)
+	v.heads = append(v.heads, ledger.SignedTreeHead{ID: int64(len(v.heads) + 1), Head: head, JWS: jws, Cosignatures: []ledger.Cosignature{}})
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if r.Header.Get("Authorization") != "ApiKey witness-key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.URL.Path == "/jwks.json":
+		jwks, _ := v.service.CurrentJWKS()
+		w.Write(jwks)
+	case r.URL.Path == "/api/v1/ledger/tree-heads/latest":
+		json.NewEncoder(w).Encode(v.heads[len(v.heads)-1])
+	case r.URL.Path == "/api/v1/ledger/consistency":
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		prev, next := v.heads[from-1].Head.Chains[0], v.heads[to-1].Head.Chains[0]
+		json.NewEncoder(w).Encode(ledger.ConsistencyProof{From: from, To: to, Chains: []ledger.ChainConsistency{{
+			ChainID: prev.ChainID, FromSeq: prev.Seq, FromHash: prev.Hash, ToSeq: next.Seq, ToHash: next.Hash,
+			Entries: v.entries[prev.Seq:next.Seq],
+		}}})
+	case r.Method == http.MethodPost:
+		var req struct {
+			WitnessID string `json:"witness_id"`
+			JWS       string `json:"jws"`
+		}
+		require.NoError(v.t, json.NewDecoder(r.Body).Decode(&req))
+		id, _ := strconv.ParseInt(filepath.Base(filepath.Dir(r.URL.Path)), 10, 64)
+		v.cosigns[id] = req.JWS
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// TestWitness_Check teste le suivi d'un coffre : contre-signature des têtes cohérentes,
+// refus d'une tête dont l'historique a été réécrit
+func TestWitness_Check(t *testing.T) {
+	vaultService, _ := newTestRotatingService(t)
+	witnessService, _ := newTestRotatingService(t)
+	vault := &fakeVault{t: t, service: vaultService, entries: appendV2(nil, 5, 1), cosigns: map[int64]string{}}
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	vaults, err := witness.ParseVaults("main="+server.URL, "witness-key")
+	require.NoError(t, err)
+	stateDir := t.TempDir()
+	w := witness.New(witness.Config{WitnessID: "w1", Signer: witnessService, StateDir: stateDir, Logger: zerolog.Nop()})
+	ctx := context.Background()
+
+	// Premier passage : tête acceptée et contre-signée
+	vault.publish(2)
+	head, err := w.Check(ctx, vaults[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), head.ID)
+	witnessJWKS, err := witnessService.CurrentJWKS()
+	require.NoError(t, err)
+	witnessKeys, err := crypto.ParseJWKS(witnessJWKS)
+	require.NoError(t, err)
+	_, err = verify.VerifyTreeHeadSignature(vault.heads[0].Head, vault.cosigns[1], witnessKeys)
+	assert.NoError(t, err)
+
+	// Ledger prolongé : cohérence vérifiée, nouvelle tête contre-signée
+	vault.publish(5)
+	head, err = w.Check(ctx, vaults[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), head.ID)
+	assert.Contains(t, vault.cosigns, int64(2))
+
+	// Tête inchangée : rien à contre-signer
+	delete(vault.cosigns, 2)
+	_, err = w.Check(ctx, vaults[0])
+	require.NoError(t, err)
+	assert.NotContains(t, vault.cosigns, int64(2))
+
+	// Historique réécrit par un opérateur : tête signée par le coffre mais incohérente
+	vault.entries = appendV2(nil, 7, 1)
+	vault.publish(7)
+	_, err = w.Check(ctx, vaults[0])
+	assert.True(t, errors.Is(err, witness.ErrInconsistent), "%v", err)
+	assert.NotContains(t, vault.cosigns, int64(3))
+
+	// La référence du témoin n'avance pas
+	data, err := os.ReadFile(filepath.Join(stateDir, "main.json"))
+	require.NoError(t, err)
+	var state witness.State
+	require.NoError(t, json.Unmarshal(data, &state))
+	assert.Equal(t, int64(2), state.TreeHeadID)
+
+	// Table des têtes réécrite : la tête déjà témoignée a changé
+	vault.heads = vault.heads[:2]
+	vault.heads[1].Head.Chains = []ledger.ChainHead{chainHead(ledger.GlobalChainID, vault.entries, 5)}
+	vault.heads[1].Head.HeadHash = ledger.ComputeTreeHeadHash(vault.heads[1].Head.Chains)
+	vault.heads[1].JWS, err = vaultService.SignReport(ledger.TreeHeadSubject, vault.heads[1].Head, time.Now())
+	require.NoError(t, err)
+	_, err = w.Check(ctx, vaults[0])
+	assert.True(t, errors.Is(err, witness.ErrInconsistent), "%v", err)
+}
+
+// TestParseVaults teste le parsing de la liste des coffres suivis
+func TestParseVaults(t *testing.T) {
+	vaults, err := witness.ParseVaults("prod=https://vault.example.com/, https://backup.example.com:8443", "k")
+	require.NoError(t, err)
+	require.Len(t, vaults, 2)
+	assert.Equal(t, witness.Vault{Name: "prod", URL: "https://vault.example.com", APIKey: "k"}, vaults[0])
+	assert.Equal(t, "backup.example.com_8443", vaults[1].Name)
+
+	for _, value := range []string{"", "ftp://vault", "prod=https://a, prod=https://b", "not a url"} {
+		_, err := witness.ParseVaults(value, "")
+		assert.Error(t, err, value)
+	}
+}